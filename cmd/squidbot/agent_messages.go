@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/grixate/squidbot/internal/agent"
+	"github.com/grixate/squidbot/internal/app"
+)
+
+// readAgentMessages loads prompts for `agent --messages-file`. A file whose
+// first non-space byte is '[' is a JSON array of strings. Otherwise each
+// non-blank line is one prompt: a JSON string literal, a JSON object with a
+// "content" field, or plain text.
+func readAgentMessages(path string) ([]string, error) {
+	var (
+		raw []byte
+		err error
+	)
+	if path == "-" {
+		raw, err = io.ReadAll(os.Stdin)
+	} else {
+		raw, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseAgentMessages(raw)
+}
+
+func parseAgentMessages(raw []byte) ([]string, error) {
+	trimmed := bytes.TrimSpace(raw)
+	if bytes.HasPrefix(trimmed, []byte("[")) {
+		var messages []string
+		if err := json.Unmarshal(trimmed, &messages); err != nil {
+			return nil, fmt.Errorf("parse messages array: %w", err)
+		}
+		return nonEmptyMessages(messages), nil
+	}
+	var messages []string
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "{"):
+			var item struct {
+				Content string `json:"content"`
+			}
+			if err := json.Unmarshal([]byte(line), &item); err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			messages = append(messages, item.Content)
+		case strings.HasPrefix(line, `"`):
+			var item string
+			if err := json.Unmarshal([]byte(line), &item); err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			messages = append(messages, item)
+		default:
+			messages = append(messages, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nonEmptyMessages(messages), nil
+}
+
+func nonEmptyMessages(messages []string) []string {
+	out := make([]string, 0, len(messages))
+	for _, message := range messages {
+		if strings.TrimSpace(message) != "" {
+			out = append(out, message)
+		}
+	}
+	return out
+}
+
+// askCLI sends one message on the cli channel and writes the reply to out.
+func askCLI(ctx context.Context, runtime *app.Runtime, sessionID, content string, stream bool, out io.Writer) error {
+	inbound := agent.InboundMessage{
+		SessionID: sessionID,
+		Channel:   "cli",
+		ChatID:    "direct",
+		SenderID:  "user",
+		Content:   content,
+		CreatedAt: time.Now().UTC(),
+	}
+	if stream {
+		var final string
+		err := runtime.Engine.AskStream(ctx, inbound, agent.StreamSinkFunc(func(ctx context.Context, event agent.StreamEvent) error {
+			switch event.Type {
+			case "assistant_delta":
+				fmt.Fprint(out, event.Delta)
+			case "tool_call_started", "tool_call_end":
+				fmt.Fprintln(out, toolStatusLine(out, event))
+			case "final":
+				final = event.Content
+			case "error":
+				return errors.New(event.Error)
+			}
+			return nil
+		}))
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(final) != "" {
+			fmt.Fprintln(out)
+		}
+		return nil
+	}
+	resp, err := runtime.Engine.Ask(ctx, inbound)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, resp)
+	return nil
+}
+
+// toolStatusLine renders a tool progress event as one status line, dimmed
+// when out is a terminal.
+func toolStatusLine(out io.Writer, event agent.StreamEvent) string {
+	var line string
+	switch {
+	case event.Type == "tool_call_started":
+		line = "→ " + event.ToolName
+	case event.Error != "":
+		line = "✗ " + event.ToolName + ": " + firstLine(event.Error)
+	default:
+		line = "✓ " + event.ToolName
+		if preview := firstLine(event.Content); preview != "" {
+			line += ": " + preview
+		}
+	}
+	if file, ok := out.(*os.File); ok && isTerminal(file) {
+		return "\x1b[2m" + line + "\x1b[0m"
+	}
+	return line
+}
+
+func firstLine(text string) string {
+	text = strings.TrimSpace(text)
+	if idx := strings.IndexByte(text, '\n'); idx >= 0 {
+		text = strings.TrimSpace(text[:idx]) + " …"
+	}
+	return text
+}
+
+// runAgentMessages asks each message in order on one session. It stops at the
+// first failure unless continueOnError is set, and returns an error if any
+// message failed.
+func runAgentMessages(ctx context.Context, runtime *app.Runtime, sessionID string, messages []string, stream, continueOnError bool, out, errOut io.Writer) error {
+	failed := 0
+	for idx, message := range messages {
+		if idx > 0 {
+			fmt.Fprintln(out)
+		}
+		fmt.Fprintf(out, "--- [%d/%d] ---\n", idx+1, len(messages))
+		if err := askCLI(ctx, runtime, sessionID, message, stream, out); err != nil {
+			failed++
+			fmt.Fprintf(errOut, "message %d failed: %v\n", idx+1, err)
+			if !continueOnError {
+				return fmt.Errorf("message %d of %d failed: %w", idx+1, len(messages), err)
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d messages failed", failed, len(messages))
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/grixate/squidbot/internal/agent"
+	"github.com/grixate/squidbot/internal/config"
+)
+
+const gatewayManageTimeout = 3 * time.Second
+
+// errGatewayManageDisabled reports that the config has no metrics HTTP server
+// to reach a running gateway through.
+var errGatewayManageDisabled = errors.New("runtime.metricsHttp is not enabled")
+
+// gatewayManageGet fetches path from the running gateway's operator
+// endpoints and decodes the JSON reply into out. A wildcard listen address is
+// reached on loopback. Requests time out after three seconds unless ctx
+// already has a deadline.
+func gatewayManageGet(ctx context.Context, cfg config.Config, path string, out any) error {
+	return gatewayManageRequest(ctx, cfg, http.MethodGet, path, out)
+}
+
+// gatewayManagePost is gatewayManageGet for operator actions.
+func gatewayManagePost(ctx context.Context, cfg config.Config, path string, out any) error {
+	return gatewayManageRequest(ctx, cfg, http.MethodPost, path, out)
+}
+
+func gatewayManageRequest(ctx context.Context, cfg config.Config, method, path string, out any) error {
+	metrics := cfg.Runtime.MetricsHTTP
+	listenAddr := strings.TrimSpace(metrics.ListenAddr)
+	if !(cfg.Features.MetricsHTTP || metrics.Enabled) || listenAddr == "" {
+		return errGatewayManageDisabled
+	}
+	host, port, err := net.SplitHostPort(listenAddr)
+	if err != nil {
+		return fmt.Errorf("runtime.metricsHttp.listenAddr %q: %w", listenAddr, err)
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "127.0.0.1"
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, gatewayManageTimeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, "http://"+net.JoinHostPort(host, port)+path, nil)
+	if err != nil {
+		return err
+	}
+	token := strings.TrimSpace(metrics.ManageToken)
+	if token == "" {
+		token = strings.TrimSpace(metrics.AuthToken)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		if message := strings.TrimSpace(string(body)); message != "" {
+			return fmt.Errorf("gateway returned %s: %s", resp.Status, message)
+		}
+		return fmt.Errorf("gateway returned %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// gatewayNotRunning reports whether err means no gateway is there to take a
+// request, as opposed to one that answered with an error.
+func gatewayNotRunning(err error) bool {
+	return errors.Is(err, errGatewayManageDisabled) || errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// gatewayHeartbeatRun asks the running gateway to run a heartbeat profile and
+// returns the agent's reply. The wait covers one agent turn.
+func gatewayHeartbeatRun(ctx context.Context, cfg config.Config, profile string) (string, error) {
+	turnTimeout := time.Duration(cfg.Agents.Defaults.TurnTimeoutSec) * time.Second
+	if turnTimeout <= 0 {
+		turnTimeout = 120 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, turnTimeout+gatewayManageTimeout)
+	defer cancel()
+	var payload struct {
+		Status   string `json:"status"`
+		Response string `json:"response"`
+		Error    string `json:"error"`
+	}
+	if err := gatewayManagePost(ctx, cfg, "/api/manage/heartbeat/run?profile="+url.QueryEscape(profile), &payload); err != nil {
+		return "", err
+	}
+	if payload.Status != "ok" {
+		return "", fmt.Errorf("heartbeat %s failed on the gateway: %s", profile, payload.Error)
+	}
+	return payload.Response, nil
+}
+
+// liveSessionStatuses asks the running gateway for its sessions, which marks
+// the ones with a live actor. The CLI cannot tell on its own: the gateway
+// holds the store open while it runs.
+func liveSessionStatuses(ctx context.Context, cfg config.Config) ([]agent.SessionStatus, error) {
+	var payload struct {
+		Sessions []agent.SessionStatus `json:"sessions"`
+	}
+	if err := gatewayManageGet(ctx, cfg, "/api/manage/sessions", &payload); err != nil {
+		return nil, err
+	}
+	return payload.Sessions, nil
+}
@@ -2,15 +2,20 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log"
+	"maps"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -20,13 +25,18 @@ import (
 	"github.com/grixate/squidbot/internal/agent"
 	"github.com/grixate/squidbot/internal/app"
 	"github.com/grixate/squidbot/internal/budget"
+	"github.com/grixate/squidbot/internal/buildinfo"
 	"github.com/grixate/squidbot/internal/config"
 	"github.com/grixate/squidbot/internal/cron"
+	"github.com/grixate/squidbot/internal/heartbeat"
 	"github.com/grixate/squidbot/internal/memory"
+	"github.com/grixate/squidbot/internal/mission"
 	"github.com/grixate/squidbot/internal/plugins"
+	"github.com/grixate/squidbot/internal/provider"
 	"github.com/grixate/squidbot/internal/skills"
 	storepkg "github.com/grixate/squidbot/internal/storage/bbolt"
 	"github.com/grixate/squidbot/internal/subagent"
+	"github.com/grixate/squidbot/internal/tools"
 )
 
 const squidbotRomanBanner = "                                 o8o        .o8   .o8                     .\n" +
@@ -51,12 +61,38 @@ func main() {
 
 func newRootCmd(logger *log.Logger) *cobra.Command {
 	var configPath string
+	var configDir string
+	var profile string
 	root := &cobra.Command{
 		Use:   "squidbot",
 		Short: "squidbot - Go-native personal AI assistant",
-		RunE:  func(cmd *cobra.Command, args []string) error { return cmd.Help() },
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if strings.TrimSpace(configDir) != "" {
+				if err := os.Setenv("SQUIDBOT_HOME", configDir); err != nil {
+					return err
+				}
+			}
+			if strings.TrimSpace(profile) == "" {
+				return nil
+			}
+			if strings.TrimSpace(configPath) != "" {
+				return fmt.Errorf("--profile and --config cannot be used together")
+			}
+			profiles, err := config.LoadProfiles("")
+			if err != nil {
+				return err
+			}
+			path, err := profiles.Resolve(profile)
+			if err != nil {
+				return err
+			}
+			return os.Setenv("SQUIDBOT_CONFIG", path)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error { return cmd.Help() },
 	}
 	root.PersistentFlags().StringVar(&configPath, "config", "", "config file path")
+	root.PersistentFlags().StringVar(&profile, "profile", "", "named profile from profiles.json selecting the config file")
+	root.PersistentFlags().StringVar(&configDir, "config-dir", "", "squidbot home directory for config and data (overrides SQUIDBOT_HOME)")
 
 	root.AddCommand(onboardCmd(configPath))
 	root.AddCommand(statusCmd(configPath))
@@ -64,10 +100,21 @@ func newRootCmd(logger *log.Logger) *cobra.Command {
 	root.AddCommand(gatewayCmd(configPath, logger))
 	root.AddCommand(telegramCmd(configPath))
 	root.AddCommand(cronCmd(configPath, logger))
-	root.AddCommand(subagentsCmd(configPath))
+	root.AddCommand(heartbeatCmd(configPath, logger))
+	root.AddCommand(subagentsCmd(configPath, logger))
 	root.AddCommand(skillsCmd(configPath))
 	root.AddCommand(budgetCmd(configPath))
+	root.AddCommand(providersCmd(configPath))
 	root.AddCommand(doctorCmd(configPath))
+	root.AddCommand(authCmd(configPath))
+	root.AddCommand(memoryCmd(configPath))
+	root.AddCommand(refreshCmd(configPath))
+	root.AddCommand(sessionsCmd(configPath))
+	root.AddCommand(tasksCmd(configPath))
+	root.AddCommand(toolsCmd(configPath))
+	root.AddCommand(profileCmd())
+	root.AddCommand(configCmd(configPath))
+	root.AddCommand(versionCmd())
 	return root
 }
 
@@ -87,6 +134,12 @@ func loadCfg(path string) (config.Config, error) {
 	if err != nil {
 		return cfg, err
 	}
+	return withRuntimePaths(cfg), nil
+}
+
+// withRuntimePaths fills in the workspace, database, index, and skills paths
+// a loaded config leaves unset.
+func withRuntimePaths(cfg config.Config) config.Config {
 	cfg.Agents.Defaults.Workspace = config.WorkspacePath(cfg)
 	if cfg.Storage.DBPath == "" {
 		cfg.Storage.DBPath = config.DataRoot() + "/squidbot.db"
@@ -97,7 +150,7 @@ func loadCfg(path string) (config.Config, error) {
 	if len(cfg.Skills.Paths) == 0 {
 		cfg.Skills.Paths = []string{filepath.Join(cfg.Agents.Defaults.Workspace, "skills")}
 	}
-	return cfg, nil
+	return cfg
 }
 
 func onboardCmd(configPath string) *cobra.Command {
@@ -107,17 +160,23 @@ func onboardCmd(configPath string) *cobra.Command {
 	var model string
 	var nonInteractive bool
 	var verifyGeminiCLI bool
+	var geminiVerifyAttempts int
+	var geminiVerifyTimeout time.Duration
 	var telegramEnabled bool
 	var telegramToken string
 	var telegramAllowFrom []string
 	var channelEnabledIDs []string
 	var channelEndpoints []string
 	var channelAuthTokens []string
+	var fromEnv bool
 
 	cmd := &cobra.Command{
 		Use:   "onboard",
 		Short: "Initialize squidbot config and workspace",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if fromEnv {
+				return onboardFromEnv(cmd, configPath)
+			}
 			printBanner(cmd.OutOrStdout())
 			cfg, err := loadCfg(configPath)
 			if err != nil {
@@ -131,6 +190,8 @@ func onboardCmd(configPath string) *cobra.Command {
 				Model:                model,
 				NonInteractive:       nonInteractive,
 				VerifyGeminiCLI:      verifyGeminiCLI,
+				GeminiVerifyAttempts: geminiVerifyAttempts,
+				GeminiVerifyTimeout:  geminiVerifyTimeout,
 				TelegramEnabledSet:   cmd.Flags().Changed("telegram-enabled"),
 				TelegramEnabled:      telegramEnabled,
 				TelegramTokenSet:     cmd.Flags().Changed("telegram-token"),
@@ -157,6 +218,8 @@ func onboardCmd(configPath string) *cobra.Command {
 			fmt.Fprintf(cmd.OutOrStdout(), "Active provider: %s\n", result.Provider)
 			if result.GeminiCLIVerifyRan && result.GeminiCLIVerified {
 				fmt.Fprintln(cmd.OutOrStdout(), "Gemini CLI verification passed")
+			} else if result.GeminiCLIVerifyRan {
+				fmt.Fprintf(cmd.OutOrStdout(), "Gemini CLI verification failed (reason: %s)\n", result.GeminiCLIFailureReason)
 			}
 			for _, warning := range result.Warnings {
 				fmt.Fprintf(cmd.OutOrStdout(), "Warning: %s\n", warning)
@@ -171,17 +234,246 @@ func onboardCmd(configPath string) *cobra.Command {
 	cmd.Flags().StringVar(&model, "model", "", "Provider model")
 	cmd.Flags().BoolVar(&nonInteractive, "non-interactive", false, "Disable prompts and require explicit inputs")
 	cmd.Flags().BoolVar(&verifyGeminiCLI, "verify-gemini-cli", false, "Verify Gemini CLI connectivity during onboarding")
+	cmd.Flags().IntVar(&geminiVerifyAttempts, "gemini-verify-attempts", 3, "Gemini CLI verification attempts for network or timeout failures")
+	cmd.Flags().DurationVar(&geminiVerifyTimeout, "gemini-verify-timeout", 30*time.Second, "Timeout for each Gemini CLI verification attempt")
 	cmd.Flags().BoolVar(&telegramEnabled, "telegram-enabled", false, "Enable Telegram channel")
 	cmd.Flags().StringVar(&telegramToken, "telegram-token", "", "Telegram bot token")
 	cmd.Flags().StringSliceVar(&telegramAllowFrom, "telegram-allow-from", nil, "Telegram allow list entry (repeatable or comma-separated)")
 	cmd.Flags().StringSliceVar(&channelEnabledIDs, "channel-enable", nil, "Enable channel id (repeatable)")
 	cmd.Flags().StringSliceVar(&channelEndpoints, "channel-endpoint", nil, "Channel endpoint in id=url form (repeatable)")
 	cmd.Flags().StringSliceVar(&channelAuthTokens, "channel-auth-token", nil, "Channel auth token in id=token form (repeatable)")
+	cmd.Flags().BoolVar(&fromEnv, "from-env", false, "Write the config from SQUIDBOT_* environment variables and defaults without prompting")
 	return cmd
 }
 
+// onboardFromEnv writes the config Load produces from the existing file,
+// SQUIDBOT_* environment variables, and defaults, with no prompts. Running it
+// again with the same environment writes the same file.
+func onboardFromEnv(cmd *cobra.Command, configPath string) error {
+	for _, name := range []string{"provider", "api-key", "api-base", "model", "non-interactive", "verify-gemini-cli", "telegram-enabled", "telegram-token", "telegram-allow-from", "channel-enable", "channel-endpoint", "channel-auth-token"} {
+		if cmd.Flags().Changed(name) {
+			return fmt.Errorf("--from-env cannot be combined with --%s; set the matching SQUIDBOT_* variable instead", name)
+		}
+	}
+	path := resolvedConfigPath(configPath)
+	_, statErr := os.Stat(path)
+	hadFile := statErr == nil
+	cfg, envPaths, err := config.LoadWithEnvSources(configPath)
+	if err != nil {
+		return err
+	}
+	cfg = withRuntimePaths(cfg)
+	if err := config.ValidateActiveProvider(cfg); err != nil {
+		return fmt.Errorf("provider setup incomplete: %w. Set SQUIDBOT_PROVIDER_ACTIVE and the provider's SQUIDBOT_* variables", err)
+	}
+	if err := config.Save(configPath, cfg); err != nil {
+		return err
+	}
+	if err := config.EnsureFilesystem(cfg); err != nil {
+		return err
+	}
+	out := cmd.OutOrStdout()
+	name, _ := cfg.PrimaryProvider()
+	fmt.Fprintf(out, "Saved config at %s\n", path)
+	fmt.Fprintf(out, "Workspace ready at %s\n", config.WorkspacePath(cfg))
+	fmt.Fprintf(out, "Active provider: %s\n", name)
+	if len(envPaths) == 0 {
+		fmt.Fprintln(out, "No settings came from the environment.")
+	} else {
+		fmt.Fprintf(out, "From environment (%d):\n", len(envPaths))
+		for _, setting := range envPaths {
+			fmt.Fprintf(out, "  %s\n", setting)
+		}
+	}
+	if hadFile {
+		fmt.Fprintf(out, "All other settings kept from %s or defaults.\n", path)
+	} else {
+		fmt.Fprintln(out, "All other settings use defaults.")
+	}
+	return nil
+}
+
+// statusReport is the `status` output. Its JSON field names are a stable
+// interface for scripts; the text output is printed from the same values.
+type statusReport struct {
+	Paths            config.Status          `json:"paths"`
+	Model            string                 `json:"model"`
+	DetectedProvider string                 `json:"detected_provider,omitempty"`
+	ActiveProvider   string                 `json:"active_provider"`
+	ProviderReady    bool                   `json:"provider_ready"`
+	ProviderError    string                 `json:"provider_error,omitempty"`
+	StorageBackend   string                 `json:"storage_backend"`
+	TelegramEnabled  bool                   `json:"telegram_enabled"`
+	Features         statusFeatures         `json:"features"`
+	ToolPolicy       statusToolPolicy       `json:"tool_policy"`
+	Runtime          statusRuntime          `json:"runtime"`
+	Throughput       *providerThroughputRow `json:"provider_throughput_7d,omitempty"`
+}
+
+type statusFeatures struct {
+	Streaming      bool `json:"streaming"`
+	ChannelsWave1  bool `json:"channels_wave1"`
+	SemanticMemory bool `json:"semantic_memory"`
+	Plugins        bool `json:"plugins"`
+	MetricsHTTP    bool `json:"metrics_http"`
+}
+
+type statusToolPolicy struct {
+	ExecEnabled   bool `json:"exec_enabled"`
+	ParentWrite   bool `json:"parent_write"`
+	SubagentWrite bool `json:"subagent_write"`
+}
+
+type statusRuntime struct {
+	Plugins struct {
+		Enabled       bool `json:"enabled"`
+		Paths         int  `json:"paths"`
+		TimeoutSec    int  `json:"timeout_sec"`
+		MaxConcurrent int  `json:"max_concurrent"`
+		MaxProcesses  int  `json:"max_processes"`
+	} `json:"plugins"`
+	Federation struct {
+		Enabled      bool   `json:"enabled"`
+		NodeID       string `json:"node_id"`
+		Listen       string `json:"listen"`
+		Peers        int    `json:"peers"`
+		AllowFrom    int    `json:"allow_from"`
+		Retries      int    `json:"retries"`
+		BackoffMS    int    `json:"backoff_ms"`
+		AutoFallback bool   `json:"auto_fallback"`
+	} `json:"federation"`
+	MetricsHTTP struct {
+		Enabled       bool   `json:"enabled"`
+		Listen        string `json:"listen"`
+		LocalhostOnly bool   `json:"localhost_only"`
+		AuthTokenSet  bool   `json:"auth_token_set"`
+	} `json:"metrics_http"`
+	SemanticMemory struct {
+		Enabled        bool `json:"enabled"`
+		TopKCandidates int  `json:"top_k_candidates"`
+		RerankTopK     int  `json:"rerank_top_k"`
+	} `json:"semantic_memory"`
+	Skills struct {
+		Enabled    bool `json:"enabled"`
+		Paths      int  `json:"paths"`
+		MaxActive  int  `json:"max_active"`
+		AllowZip   bool `json:"allow_zip"`
+		RefreshSec int  `json:"refresh_sec"`
+	} `json:"skills"`
+}
+
+func buildStatusReport(cfg config.Config) statusReport {
+	report := statusReport{
+		Paths:           config.BuildStatus(cfg),
+		Model:           cfg.Agents.Defaults.Model,
+		ActiveProvider:  cfg.Providers.Active,
+		ProviderReady:   true,
+		StorageBackend:  cfg.Storage.Backend,
+		TelegramEnabled: cfg.Channels.Telegram.Enabled,
+		Features: statusFeatures{
+			Streaming:      cfg.Features.Streaming,
+			ChannelsWave1:  cfg.Features.ChannelsWave1,
+			SemanticMemory: cfg.Features.SemanticMemory,
+			Plugins:        cfg.Features.Plugins,
+			MetricsHTTP:    cfg.Features.MetricsHTTP,
+		},
+		ToolPolicy: statusToolPolicy{
+			ExecEnabled:   cfg.Tools.Exec.Enabled,
+			ParentWrite:   cfg.Tools.Filesystem.ParentWriteEnabled,
+			SubagentWrite: cfg.Tools.Filesystem.SubagentWriteEnabled,
+		},
+	}
+	report.DetectedProvider, _ = cfg.PrimaryProvider()
+	if err := config.ValidateActiveProvider(cfg); err != nil {
+		report.ProviderReady = false
+		report.ProviderError = err.Error()
+	}
+	rt := &report.Runtime
+	rt.Plugins.Enabled = cfg.Runtime.Plugins.Enabled
+	rt.Plugins.Paths = len(cfg.Runtime.Plugins.Paths)
+	rt.Plugins.TimeoutSec = cfg.Runtime.Plugins.DefaultTimeoutSec
+	rt.Plugins.MaxConcurrent = cfg.Runtime.Plugins.MaxConcurrent
+	rt.Plugins.MaxProcesses = cfg.Runtime.Plugins.MaxProcesses
+	rt.Federation.Enabled = cfg.Runtime.Federation.Enabled
+	rt.Federation.NodeID = strings.TrimSpace(cfg.Runtime.Federation.NodeID)
+	rt.Federation.Listen = cfg.Runtime.Federation.ListenAddr
+	rt.Federation.Peers = len(cfg.Runtime.Federation.Peers)
+	rt.Federation.AllowFrom = len(cfg.Runtime.Federation.AllowFromNodeIDs)
+	rt.Federation.Retries = cfg.Runtime.Federation.MaxRetries
+	rt.Federation.BackoffMS = cfg.Runtime.Federation.RetryBackoffMs
+	rt.Federation.AutoFallback = cfg.Runtime.Federation.AutoFallback
+	rt.MetricsHTTP.Enabled = cfg.Runtime.MetricsHTTP.Enabled
+	rt.MetricsHTTP.Listen = cfg.Runtime.MetricsHTTP.ListenAddr
+	rt.MetricsHTTP.LocalhostOnly = cfg.Runtime.MetricsHTTP.LocalhostOnly
+	rt.MetricsHTTP.AuthTokenSet = strings.TrimSpace(cfg.Runtime.MetricsHTTP.AuthToken) != ""
+	rt.SemanticMemory.Enabled = cfg.Memory.Semantic.Enabled
+	rt.SemanticMemory.TopKCandidates = cfg.Memory.Semantic.TopKCandidates
+	rt.SemanticMemory.RerankTopK = cfg.Memory.Semantic.RerankTopK
+	rt.Skills.Enabled = cfg.Skills.Enabled
+	rt.Skills.Paths = len(cfg.Skills.Paths)
+	rt.Skills.MaxActive = cfg.Skills.MaxActive
+	rt.Skills.AllowZip = cfg.Skills.AllowZip
+	rt.Skills.RefreshSec = cfg.Skills.RefreshIntervalSec
+	if store, err := storepkg.Open(cfg.Storage.DBPath); err == nil {
+		rows, err := providerThroughput(context.Background(), store, 7)
+		_ = store.Close()
+		if err == nil && len(rows) > 0 {
+			report.Throughput = &rows[0]
+		}
+	}
+	return report
+}
+
+func printStatusReport(w io.Writer, report statusReport) {
+	st := report.Paths
+	fmt.Fprintf(w, "Config: %s [%v]\n", st.ConfigPath, st.ConfigOK)
+	fmt.Fprintf(w, "Workspace: %s [%v]\n", st.Workspace, st.WorkspaceOK)
+	fmt.Fprintf(w, "Data root: %s [%v]\n", st.DataRoot, st.DataRootOK)
+	fmt.Fprintf(w, "Model: %s\n", report.Model)
+	if report.DetectedProvider != "" {
+		fmt.Fprintf(w, "Detected provider: %s\n", report.DetectedProvider)
+	}
+	fmt.Fprintf(w, "Active provider: %s\n", report.ActiveProvider)
+	if !report.ProviderReady {
+		fmt.Fprintf(w, "Provider ready: false (%s)\n", report.ProviderError)
+	} else {
+		fmt.Fprintln(w, "Provider ready: true")
+	}
+	fmt.Fprintf(w, "Storage backend: %s\n", report.StorageBackend)
+	fmt.Fprintf(w, "Telegram enabled: %v\n", report.TelegramEnabled)
+	f := report.Features
+	fmt.Fprintf(w, "Feature flags: streaming=%v channelsWave1=%v semanticMemory=%v plugins=%v metricsHttp=%v\n",
+		f.Streaming, f.ChannelsWave1, f.SemanticMemory, f.Plugins, f.MetricsHTTP)
+	fmt.Fprintf(w, "Tool policy: execEnabled=%v parentWrite=%v subagentWrite=%v\n",
+		report.ToolPolicy.ExecEnabled, report.ToolPolicy.ParentWrite, report.ToolPolicy.SubagentWrite)
+	rt := report.Runtime
+	fmt.Fprintf(w, "Plugins runtime: enabled=%v paths=%d timeoutSec=%d maxConcurrent=%d maxProcesses=%d\n",
+		rt.Plugins.Enabled, rt.Plugins.Paths, rt.Plugins.TimeoutSec, rt.Plugins.MaxConcurrent, rt.Plugins.MaxProcesses)
+	fmt.Fprintf(w, "Federation runtime: enabled=%v nodeId=%s listen=%s peers=%d allowFrom=%d retries=%d backoffMs=%d autoFallback=%v\n",
+		rt.Federation.Enabled,
+		rt.Federation.NodeID,
+		rt.Federation.Listen,
+		rt.Federation.Peers,
+		rt.Federation.AllowFrom,
+		rt.Federation.Retries,
+		rt.Federation.BackoffMS,
+		rt.Federation.AutoFallback,
+	)
+	fmt.Fprintf(w, "Metrics HTTP: enabled=%v listen=%s localhostOnly=%v authTokenSet=%v\n",
+		rt.MetricsHTTP.Enabled, rt.MetricsHTTP.Listen, rt.MetricsHTTP.LocalhostOnly, rt.MetricsHTTP.AuthTokenSet)
+	fmt.Fprintf(w, "Semantic memory: enabled=%v topKCandidates=%d rerankTopK=%d\n",
+		rt.SemanticMemory.Enabled, rt.SemanticMemory.TopKCandidates, rt.SemanticMemory.RerankTopK)
+	fmt.Fprintf(w, "Skills runtime: enabled=%v paths=%d maxActive=%d allowZip=%v refreshSec=%d\n",
+		rt.Skills.Enabled, rt.Skills.Paths, rt.Skills.MaxActive, rt.Skills.AllowZip, rt.Skills.RefreshSec)
+	if row := report.Throughput; row != nil {
+		fmt.Fprintf(w, "Provider throughput (7d): fastest=%s avgTokPerSec=%.1f maxTokPerSec=%.1f calls=%d\n",
+			row.Label, row.AvgTokensPerSec, row.MaxTokensPerSec, row.Calls)
+	}
+}
+
 func statusCmd(configPath string) *cobra.Command {
-	return &cobra.Command{
+	var asJSON bool
+	cmd := &cobra.Command{
 		Use:   "status",
 		Short: "Show squidbot status",
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -189,53 +481,32 @@ func statusCmd(configPath string) *cobra.Command {
 			if err != nil {
 				return err
 			}
-			st := config.BuildStatus(cfg)
-			fmt.Printf("Config: %s [%v]\n", st.ConfigPath, st.ConfigOK)
-			fmt.Printf("Workspace: %s [%v]\n", st.Workspace, st.WorkspaceOK)
-			fmt.Printf("Data root: %s [%v]\n", st.DataRoot, st.DataRootOK)
-			fmt.Printf("Model: %s\n", cfg.Agents.Defaults.Model)
-			if providerName, _ := cfg.PrimaryProvider(); providerName != "" {
-				fmt.Printf("Detected provider: %s\n", providerName)
+			report := buildStatusReport(cfg)
+			if asJSON {
+				raw, err := json.MarshalIndent(report, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), string(raw))
+				return nil
 			}
-			fmt.Printf("Active provider: %s\n", cfg.Providers.Active)
-			if err := config.ValidateActiveProvider(cfg); err != nil {
-				fmt.Printf("Provider ready: false (%v)\n", err)
-			} else {
-				fmt.Println("Provider ready: true")
-			}
-			fmt.Printf("Storage backend: %s\n", cfg.Storage.Backend)
-			fmt.Printf("Telegram enabled: %v\n", cfg.Channels.Telegram.Enabled)
-			fmt.Printf("Feature flags: streaming=%v channelsWave1=%v semanticMemory=%v plugins=%v metricsHttp=%v\n",
-				cfg.Features.Streaming, cfg.Features.ChannelsWave1, cfg.Features.SemanticMemory, cfg.Features.Plugins, cfg.Features.MetricsHTTP)
-			fmt.Printf("Tool policy: execEnabled=%v parentWrite=%v subagentWrite=%v\n",
-				cfg.Tools.Exec.Enabled, cfg.Tools.Filesystem.ParentWriteEnabled, cfg.Tools.Filesystem.SubagentWriteEnabled)
-			fmt.Printf("Plugins runtime: enabled=%v paths=%d timeoutSec=%d maxConcurrent=%d maxProcesses=%d\n",
-				cfg.Runtime.Plugins.Enabled, len(cfg.Runtime.Plugins.Paths), cfg.Runtime.Plugins.DefaultTimeoutSec, cfg.Runtime.Plugins.MaxConcurrent, cfg.Runtime.Plugins.MaxProcesses)
-			fmt.Printf("Federation runtime: enabled=%v nodeId=%s listen=%s peers=%d allowFrom=%d retries=%d backoffMs=%d autoFallback=%v\n",
-				cfg.Runtime.Federation.Enabled,
-				strings.TrimSpace(cfg.Runtime.Federation.NodeID),
-				cfg.Runtime.Federation.ListenAddr,
-				len(cfg.Runtime.Federation.Peers),
-				len(cfg.Runtime.Federation.AllowFromNodeIDs),
-				cfg.Runtime.Federation.MaxRetries,
-				cfg.Runtime.Federation.RetryBackoffMs,
-				cfg.Runtime.Federation.AutoFallback,
-			)
-			fmt.Printf("Metrics HTTP: enabled=%v listen=%s localhostOnly=%v authTokenSet=%v\n",
-				cfg.Runtime.MetricsHTTP.Enabled, cfg.Runtime.MetricsHTTP.ListenAddr, cfg.Runtime.MetricsHTTP.LocalhostOnly, strings.TrimSpace(cfg.Runtime.MetricsHTTP.AuthToken) != "")
-			fmt.Printf("Semantic memory: enabled=%v topKCandidates=%d rerankTopK=%d\n",
-				cfg.Memory.Semantic.Enabled, cfg.Memory.Semantic.TopKCandidates, cfg.Memory.Semantic.RerankTopK)
-			fmt.Printf("Skills runtime: enabled=%v paths=%d maxActive=%d allowZip=%v refreshSec=%d\n",
-				cfg.Skills.Enabled, len(cfg.Skills.Paths), cfg.Skills.MaxActive, cfg.Skills.AllowZip, cfg.Skills.RefreshIntervalSec)
+			printStatusReport(cmd.OutOrStdout(), report)
 			return nil
 		},
 	}
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Emit JSON output")
+	return cmd
 }
 
 func agentCmd(configPath string, logger *log.Logger) *cobra.Command {
 	var message string
 	var sessionID string
 	var stream bool
+	var historyFile string
+	var noHistory bool
+	var messagesFile string
+	var continueOnError bool
+	var dryTools bool
 	cmd := &cobra.Command{
 		Use:   "agent",
 		Short: "Chat with squidbot directly",
@@ -244,6 +515,9 @@ func agentCmd(configPath string, logger *log.Logger) *cobra.Command {
 			if err != nil {
 				return err
 			}
+			if strings.TrimSpace(message) != "" && strings.TrimSpace(messagesFile) != "" {
+				return fmt.Errorf("--message and --messages-file cannot be combined")
+			}
 			if err := config.ValidateActiveProvider(cfg); err != nil {
 				return fmt.Errorf("provider setup incomplete: %w. Run `squidbot onboard`", err)
 			}
@@ -255,52 +529,36 @@ func agentCmd(configPath string, logger *log.Logger) *cobra.Command {
 				return err
 			}
 			defer runtime.Shutdown()
+			runtime.Engine.SetDryTools(dryTools)
 
 			if strings.TrimSpace(sessionID) == "" {
 				sessionID = "cli:default"
 			}
 
-			if strings.TrimSpace(message) != "" {
-				inbound := agent.InboundMessage{
-					SessionID: sessionID,
-					RequestID: "",
-					Channel:   "cli",
-					ChatID:    "direct",
-					SenderID:  "user",
-					Content:   message,
-					CreatedAt: time.Now().UTC(),
-				}
-				if stream {
-					var final string
-					err := runtime.Engine.AskStream(context.Background(), inbound, agent.StreamSinkFunc(func(ctx context.Context, event agent.StreamEvent) error {
-						switch event.Type {
-						case "assistant_delta":
-							fmt.Print(event.Delta)
-						case "final":
-							final = event.Content
-						case "error":
-							return errors.New(event.Error)
-						}
-						return nil
-					}))
-					if err != nil {
-						return err
-					}
-					if strings.TrimSpace(final) != "" {
-						fmt.Println()
-					}
-					return nil
-				}
-				resp, err := runtime.Engine.Ask(context.Background(), inbound)
+			if strings.TrimSpace(messagesFile) != "" {
+				messages, err := readAgentMessages(strings.TrimSpace(messagesFile))
 				if err != nil {
 					return err
 				}
-				fmt.Println(resp)
-				return nil
+				if len(messages) == 0 {
+					return fmt.Errorf("no messages found in %s", messagesFile)
+				}
+				return runAgentMessages(context.Background(), runtime, sessionID, messages, stream, continueOnError, cmd.OutOrStdout(), cmd.ErrOrStderr())
+			}
+			if strings.TrimSpace(message) != "" {
+				return askCLI(context.Background(), runtime, sessionID, message, stream, cmd.OutOrStdout())
 			}
 
-			fmt.Println("Interactive mode (Ctrl+C to exit)")
-			reader := bufio.NewReader(os.Stdin)
+			historyPath := strings.TrimSpace(historyFile)
+			if historyPath == "" {
+				historyPath = defaultReplHistoryPath()
+			}
+			if noHistory {
+				historyPath = ""
+			}
+			history := loadReplHistory(historyPath)
+			input := newLineReader(os.Stdin, os.Stdout, history)
+			fmt.Println("Interactive mode (/help for commands, /exit to quit)")
 			stopAsync := make(chan struct{})
 			go func() {
 				for {
@@ -319,14 +577,16 @@ func agentCmd(configPath string, logger *log.Logger) *cobra.Command {
 						if strings.TrimSpace(msgSession) != "" && msgSession != sessionID {
 							continue
 						}
-						fmt.Printf("\n\n[async]\n%s\n\nYou: ", msg.Content)
+						input.Notify("[async]\n" + msg.Content)
 					}
 				}
 			}()
 			defer close(stopAsync)
 			for {
-				fmt.Print("You: ")
-				line, err := reader.ReadString('\n')
+				line, err := input.ReadLine(replPrompt)
+				if errors.Is(err, io.EOF) {
+					return nil
+				}
 				if err != nil {
 					return err
 				}
@@ -334,6 +594,19 @@ func agentCmd(configPath string, logger *log.Logger) *cobra.Command {
 				if line == "" {
 					continue
 				}
+				history.Add(line)
+				switch parseReplCommand(line) {
+				case replExit:
+					return nil
+				case replHelp:
+					fmt.Println(replHelpText)
+					continue
+				case replShowHistory:
+					for _, entry := range history.Recent(replHistoryShowEntries) {
+						fmt.Println(entry)
+					}
+					continue
+				}
 				resp, err := runtime.Engine.Ask(context.Background(), agent.InboundMessage{
 					SessionID: sessionID,
 					Channel:   "cli",
@@ -353,10 +626,17 @@ func agentCmd(configPath string, logger *log.Logger) *cobra.Command {
 	cmd.Flags().StringVarP(&message, "message", "m", "", "Message to send")
 	cmd.Flags().StringVarP(&sessionID, "session", "s", "cli:default", "Session ID")
 	cmd.Flags().BoolVar(&stream, "stream", false, "Stream response chunks")
+	cmd.Flags().StringVar(&historyFile, "history-file", "", "interactive input history file (default <data>/agent_history)")
+	cmd.Flags().BoolVar(&noHistory, "no-history", false, "do not persist interactive input history")
+	cmd.Flags().StringVar(&messagesFile, "messages-file", "", "send each prompt in this file (one per line, JSON lines, or a JSON array; - for stdin) on the same session")
+	cmd.Flags().BoolVar(&continueOnError, "continue-on-error", false, "with --messages-file, keep going after a failed message")
+	cmd.Flags().BoolVar(&dryTools, "dry-tools", false, "use the real provider but return stubbed results instead of running tools (calls are logged to stderr)")
 	return cmd
 }
 
 func gatewayCmd(configPath string, logger *log.Logger) *cobra.Command {
+	var configCheck bool
+	var disabledChannels []string
 	cmd := &cobra.Command{
 		Use:   "gateway",
 		Short: "Start squidbot gateway (telegram + cron + heartbeat)",
@@ -376,17 +656,42 @@ func gatewayCmd(configPath string, logger *log.Logger) *cobra.Command {
 				return err
 			}
 			defer runtime.Shutdown()
+			if configCheck {
+				if err := runtime.ConfigCheck(); err != nil {
+					return fmt.Errorf("config check failed:\n%w", err)
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), "config check passed")
+				return nil
+			}
 
 			ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 			defer cancel()
 
+			runtime.DisabledChannels = disabledChannels
 			fmt.Println("squidbot gateway started")
+			printGatewayChannels(cmd.OutOrStdout(), runtime.GatewayChannels())
 			return runtime.StartGateway(ctx)
 		},
 	}
+	cmd.Flags().BoolVar(&configCheck, "config-check", false, "Validate config and build the runtime, then exit without serving")
+	cmd.Flags().StringArrayVar(&disabledChannels, "disable-channel", nil, "Do not start this channel for this run (repeatable); config on disk is unchanged")
 	return cmd
 }
 
+func printGatewayChannels(w io.Writer, plan app.GatewayChannelPlan) {
+	started := "none"
+	if len(plan.Started) > 0 {
+		started = strings.Join(plan.Started, ", ")
+	}
+	fmt.Fprintf(w, "Channels started: %s\n", started)
+	if len(plan.Suppressed) > 0 {
+		fmt.Fprintf(w, "Channels suppressed by --disable-channel: %s\n", strings.Join(plan.Suppressed, ", "))
+	}
+	for _, id := range plan.Unknown {
+		fmt.Fprintf(w, "warning: --disable-channel %s matches no enabled channel\n", id)
+	}
+}
+
 func telegramCmd(configPath string) *cobra.Command {
 	root := &cobra.Command{Use: "telegram", Short: "Telegram channel commands"}
 	root.AddCommand(&cobra.Command{
@@ -407,6 +712,44 @@ func telegramCmd(configPath string) *cobra.Command {
 	return root
 }
 
+// cronRecurrenceFlags turns the --daily, --weekly, and --monthly shortcuts
+// into a Recurrence, or returns nil when none is set. The shortcuts exclude
+// each other and the other schedule flags.
+func cronRecurrenceFlags(cmd *cobra.Command, daily, weekly, monthly bool, at, weekday string, dayOfMonth int) (*cron.Recurrence, error) {
+	periods := make([]string, 0, 1)
+	for period, set := range map[string]bool{cron.RecurDaily: daily, cron.RecurWeekly: weekly, cron.RecurMonthly: monthly} {
+		if set {
+			periods = append(periods, period)
+		}
+	}
+	if len(periods) == 0 {
+		for _, flag := range []string{"time", "weekday", "day-of-month"} {
+			if cmd.Flags().Changed(flag) {
+				return nil, fmt.Errorf("--%s requires --daily, --weekly, or --monthly", flag)
+			}
+		}
+		return nil, nil
+	}
+	if len(periods) > 1 {
+		return nil, fmt.Errorf("use only one of --daily, --weekly, or --monthly")
+	}
+	for _, flag := range []string{"every", "cron", "at"} {
+		if cmd.Flags().Changed(flag) {
+			return nil, fmt.Errorf("--%s cannot be combined with --%s", flag, periods[0])
+		}
+	}
+	if cmd.Flags().Changed("weekday") && periods[0] != cron.RecurWeekly {
+		return nil, fmt.Errorf("--weekday requires --weekly")
+	}
+	if cmd.Flags().Changed("day-of-month") && periods[0] != cron.RecurMonthly {
+		return nil, fmt.Errorf("--day-of-month requires --monthly")
+	}
+	if cmd.Flags().Changed("day-of-month") && dayOfMonth == 0 {
+		return nil, fmt.Errorf("day of month 0 must be between 1 and 31")
+	}
+	return &cron.Recurrence{Period: periods[0], Time: at, Weekday: weekday, DayOfMonth: dayOfMonth}, nil
+}
+
 func cronCmd(configPath string, logger *log.Logger) *cobra.Command {
 	root := &cobra.Command{Use: "cron", Short: "Manage scheduled jobs"}
 	var includeDisabled bool
@@ -446,8 +789,12 @@ func cronCmd(configPath string, logger *log.Logger) *cobra.Command {
 
 	var name, msg, cronExpr, at string
 	var every int64
-	var deliver bool
+	var deliver, asSubagent bool
 	var to, channel string
+	var timeoutSec int
+	var daily, weekly, monthly bool
+	var recurTime, weekday string
+	var dayOfMonth int
 	add := &cobra.Command{
 		Use:   "add",
 		Short: "Add a scheduled job",
@@ -468,7 +815,23 @@ func cronCmd(configPath string, logger *log.Logger) *cobra.Command {
 				Enabled: true,
 				Payload: cron.JobPayload{Message: msg, Deliver: deliver, Channel: channel, To: to},
 			}
+			if asSubagent {
+				job.Payload.Kind = cron.PayloadSubagent
+				job.Payload.TimeoutSec = timeoutSec
+			} else if cmd.Flags().Changed("timeout") {
+				return fmt.Errorf("--timeout requires --as-subagent")
+			}
+			recurrence, err := cronRecurrenceFlags(cmd, daily, weekly, monthly, recurTime, weekday, dayOfMonth)
+			if err != nil {
+				return err
+			}
 			switch {
+			case recurrence != nil:
+				expr, exprErr := recurrence.CronExpr()
+				if exprErr != nil {
+					return exprErr
+				}
+				job.Schedule = cron.JobSchedule{Kind: cron.ScheduleCron, Expr: expr}
 			case every > 0:
 				job.Schedule = cron.JobSchedule{Kind: cron.ScheduleEvery, Every: every * 1000}
 			case strings.TrimSpace(cronExpr) != "":
@@ -480,12 +843,21 @@ func cronCmd(configPath string, logger *log.Logger) *cobra.Command {
 				}
 				job.Schedule = cron.JobSchedule{Kind: cron.ScheduleAt, At: &parsed}
 			default:
-				return fmt.Errorf("provide --every, --cron, or --at")
+				return fmt.Errorf("provide --every, --cron, --at, --daily, --weekly, or --monthly")
+			}
+			if err := cron.ValidateSchedule(job.Schedule); err != nil {
+				return err
+			}
+			if err := cron.ValidatePayload(job.Payload); err != nil {
+				return err
 			}
 			if err := service.Put(context.Background(), job); err != nil {
 				return err
 			}
 			fmt.Printf("Added job %s (%s)\n", job.Name, job.ID)
+			if recurrence != nil {
+				fmt.Printf("Schedule: cron %q\n", job.Schedule.Expr)
+			}
 			return nil
 		},
 	}
@@ -494,9 +866,17 @@ func cronCmd(configPath string, logger *log.Logger) *cobra.Command {
 	add.Flags().Int64VarP(&every, "every", "e", 0, "Run every N seconds")
 	add.Flags().StringVarP(&cronExpr, "cron", "c", "", "Cron expression")
 	add.Flags().StringVar(&at, "at", "", "Run once at RFC3339 time")
+	add.Flags().BoolVar(&daily, "daily", false, "Run every day at --time")
+	add.Flags().BoolVar(&weekly, "weekly", false, "Run every week on --weekday at --time")
+	add.Flags().BoolVar(&monthly, "monthly", false, "Run every month on --day-of-month at --time")
+	add.Flags().StringVar(&recurTime, "time", "", "Time of day as HH:MM (with --daily, --weekly, or --monthly)")
+	add.Flags().StringVar(&weekday, "weekday", "", "Day of the week for --weekly, e.g. monday or mon (default monday)")
+	add.Flags().IntVar(&dayOfMonth, "day-of-month", 0, "Day of the month (1-31) for --monthly (default 1)")
 	add.Flags().BoolVarP(&deliver, "deliver", "d", false, "Deliver response to channel")
 	add.Flags().StringVar(&channel, "channel", "telegram", "Delivery channel")
 	add.Flags().StringVar(&to, "to", "", "Delivery target chat ID")
+	add.Flags().BoolVar(&asSubagent, "as-subagent", false, "Run the message as a background subagent task")
+	add.Flags().IntVar(&timeoutSec, "timeout", 0, "Subagent run timeout in seconds (with --as-subagent)")
 	_ = add.MarkFlagRequired("name")
 	_ = add.MarkFlagRequired("message")
 	root.AddCommand(add)
@@ -566,18 +946,236 @@ func cronCmd(configPath string, logger *log.Logger) *cobra.Command {
 	run.Flags().BoolVarP(&force, "force", "f", false, "Run even if disabled")
 	root.AddCommand(run)
 
+	var exportOut string
+	export := &cobra.Command{
+		Use:   "export",
+		Short: "Export all jobs as JSON",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadCfg(configPath)
+			if err != nil {
+				return err
+			}
+			store, err := storepkg.Open(cfg.Storage.DBPath)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+			file, err := cron.NewService(store, nil, nil).Export(context.Background())
+			if err != nil {
+				return err
+			}
+			raw, err := json.MarshalIndent(file, "", "  ")
+			if err != nil {
+				return err
+			}
+			raw = append(raw, '\n')
+			if strings.TrimSpace(exportOut) == "" {
+				_, err = cmd.OutOrStdout().Write(raw)
+				return err
+			}
+			if err := os.WriteFile(exportOut, raw, 0o600); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Exported %d jobs to %s\n", len(file.Jobs), exportOut)
+			return nil
+		},
+	}
+	export.Flags().StringVarP(&exportOut, "out", "o", "", "Write to this file instead of stdout")
+	root.AddCommand(export)
+
+	var replace bool
+	importCmd := &cobra.Command{
+		Use:   "import <file|->",
+		Short: "Import jobs from a cron export file",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var raw []byte
+			var err error
+			if args[0] == "-" {
+				raw, err = io.ReadAll(cmd.InOrStdin())
+			} else {
+				raw, err = os.ReadFile(args[0])
+			}
+			if err != nil {
+				return err
+			}
+			var file cron.ExportFile
+			if err := json.Unmarshal(raw, &file); err != nil {
+				return fmt.Errorf("invalid cron export: %w", err)
+			}
+			cfg, err := loadCfg(configPath)
+			if err != nil {
+				return err
+			}
+			store, err := storepkg.Open(cfg.Storage.DBPath)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+			result, err := cron.NewService(store, nil, nil).Import(context.Background(), file, replace)
+			for _, warning := range result.Warnings {
+				fmt.Fprintf(cmd.ErrOrStderr(), "warning: %s\n", warning)
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Imported %d jobs", result.Imported)
+			if replace {
+				fmt.Fprintf(cmd.OutOrStdout(), ", removed %d", result.Removed)
+			}
+			fmt.Fprintln(cmd.OutOrStdout())
+			return nil
+		},
+	}
+	importCmd.Flags().BoolVar(&replace, "replace", false, "Remove existing jobs that are not in the file")
+	root.AddCommand(importCmd)
+
+	return root
+}
+
+func heartbeatCmd(configPath string, logger *log.Logger) *cobra.Command {
+	root := &cobra.Command{Use: "heartbeat", Short: "Inspect and run heartbeat profiles"}
+	root.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List heartbeat profiles",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadCfg(configPath)
+			if err != nil {
+				return err
+			}
+			for _, profile := range app.HeartbeatProfiles(cfg) {
+				every := "manual"
+				if profile.Interval > 0 {
+					every = profile.Interval.String()
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "%s\t%s\t%s\n", profile.Name, profile.File, every)
+			}
+			return nil
+		},
+	})
+
+	root.AddCommand(&cobra.Command{
+		Use:   "run [profile]",
+		Short: "Run a heartbeat profile now (default: default)",
+		Long:  "Run a heartbeat profile now (default: default). A running gateway runs it through its operator endpoint; without one, the profile runs in this process.",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadCfg(configPath)
+			if err != nil {
+				return err
+			}
+			profile := heartbeat.DefaultProfile
+			if len(args) == 1 {
+				profile = strings.ToLower(strings.TrimSpace(args[0]))
+			}
+			response, err := gatewayHeartbeatRun(cmd.Context(), cfg, profile)
+			if err == nil {
+				fmt.Fprintln(cmd.OutOrStdout(), strings.TrimSpace(response))
+				return nil
+			}
+			if !gatewayNotRunning(err) {
+				return err
+			}
+			if err := config.ValidateActiveProvider(cfg); err != nil {
+				return fmt.Errorf("provider setup incomplete: %w. Run `squidbot onboard`", err)
+			}
+			runtime, err := app.BuildRuntime(cfg, logger)
+			if err != nil {
+				return err
+			}
+			defer runtime.Shutdown()
+			response, err = runtime.TriggerHeartbeat(cmd.Context(), profile)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), strings.TrimSpace(response))
+			return nil
+		},
+	})
+
+	var limit int
+	runs := &cobra.Command{
+		Use:   "runs",
+		Short: "List recent heartbeat runs",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadCfg(configPath)
+			if err != nil {
+				return err
+			}
+			store, err := storepkg.Open(cfg.Storage.DBPath)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+			records, err := store.ListHeartbeatRuns(cmd.Context(), limit)
+			if err != nil {
+				return err
+			}
+			if len(records) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "No heartbeat runs")
+				return nil
+			}
+			for _, run := range records {
+				profile := run.Profile
+				if profile == "" {
+					profile = heartbeat.DefaultProfile
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "%s\t%s\t%s\t%s\t%s\n", run.StartedAt.Format(time.RFC3339), profile, run.TriggeredBy, run.Status, run.Preview)
+			}
+			return nil
+		},
+	}
+	runs.Flags().IntVar(&limit, "limit", 20, "Maximum runs to list")
+	root.AddCommand(runs)
 	return root
 }
 
-func subagentsCmd(configPath string) *cobra.Command {
+// subagentRunsInWindow keeps the runs active at some point in [from, to]:
+// created no later than to and, unless still open, finished no earlier than
+// from. A zero bound is open.
+func subagentRunsInWindow(runs []subagent.Run, from, to time.Time) []subagent.Run {
+	if from.IsZero() && to.IsZero() {
+		return runs
+	}
+	out := make([]subagent.Run, 0, len(runs))
+	for _, run := range runs {
+		if !to.IsZero() && run.CreatedAt.After(to) {
+			continue
+		}
+		if !from.IsZero() && run.FinishedAt != nil && run.FinishedAt.Before(from) {
+			continue
+		}
+		out = append(out, run)
+	}
+	return out
+}
+
+func subagentsCmd(configPath string, logger *log.Logger) *cobra.Command {
 	root := &cobra.Command{Use: "subagents", Short: "Inspect and manage subagent runs"}
 	var sessionID string
 	var status string
 	var limit int
+	var since string
+	var until string
 	list := &cobra.Command{
 		Use:   "list",
 		Short: "List subagent runs",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			var from, to time.Time
+			if strings.TrimSpace(since) != "" {
+				parsed, err := time.Parse(time.RFC3339, strings.TrimSpace(since))
+				if err != nil {
+					return fmt.Errorf("invalid --since: %w", err)
+				}
+				from = parsed
+			}
+			if strings.TrimSpace(until) != "" {
+				parsed, err := time.Parse(time.RFC3339, strings.TrimSpace(until))
+				if err != nil {
+					return fmt.Errorf("invalid --until: %w", err)
+				}
+				to = parsed
+			}
 			cfg, err := loadCfg(configPath)
 			if err != nil {
 				return err
@@ -588,21 +1186,36 @@ func subagentsCmd(configPath string) *cobra.Command {
 			}
 			defer store.Close()
 			ctx := context.Background()
+			// The window is applied before the limit, so fetch everything
+			// when one is set.
+			fetchLimit := limit
+			if !from.IsZero() || !to.IsZero() {
+				fetchLimit = 0
+			}
 			var runs []subagent.Run
 			if strings.TrimSpace(status) != "" {
-				runs, err = store.ListSubagentRunsByStatus(ctx, subagent.Status(strings.TrimSpace(strings.ToLower(status))), limit)
+				runs, err = store.ListSubagentRunsByStatus(ctx, subagent.Status(strings.TrimSpace(strings.ToLower(status))), fetchLimit)
 			} else {
-				runs, err = store.ListSubagentRunsBySession(ctx, strings.TrimSpace(sessionID), limit)
+				runs, err = store.ListSubagentRunsBySession(ctx, strings.TrimSpace(sessionID), fetchLimit)
 			}
 			if err != nil {
 				return err
 			}
+			runs = subagentRunsInWindow(runs, from, to)
+			if limit > 0 && len(runs) > limit {
+				runs = runs[:limit]
+			}
+			out := cmd.OutOrStdout()
 			if len(runs) == 0 {
-				fmt.Println("No subagent runs")
+				fmt.Fprintln(out, "No subagent runs")
 				return nil
 			}
 			for _, run := range runs {
-				fmt.Printf("%s\t%s\tattempt %d/%d\t%s\n", run.ID, run.Status, run.Attempt, run.MaxAttempts, run.Task)
+				finished := "-"
+				if run.FinishedAt != nil {
+					finished = run.FinishedAt.UTC().Format(time.RFC3339)
+				}
+				fmt.Fprintf(out, "%s\t%s\tattempt %d/%d\t%s\t%s\t%s\n", run.ID, run.Status, run.Attempt, run.MaxAttempts, run.CreatedAt.UTC().Format(time.RFC3339), finished, run.Task)
 			}
 			return nil
 		},
@@ -610,6 +1223,8 @@ func subagentsCmd(configPath string) *cobra.Command {
 	list.Flags().StringVar(&sessionID, "session", "", "Filter by session ID")
 	list.Flags().StringVar(&status, "status", "", "Filter by status (queued|running|succeeded|failed|timed_out|cancelled)")
 	list.Flags().IntVar(&limit, "limit", 50, "Max number of runs to return")
+	list.Flags().StringVar(&since, "since", "", "Only runs still open or finished at or after this time (RFC3339)")
+	list.Flags().StringVar(&until, "until", "", "Only runs created at or before this time (RFC3339)")
 	root.AddCommand(list)
 
 	show := &cobra.Command{
@@ -640,9 +1255,10 @@ func subagentsCmd(configPath string) *cobra.Command {
 	}
 	root.AddCommand(show)
 
-	cancel := &cobra.Command{
-		Use:   "cancel <run_id>",
-		Short: "Cancel a queued or running subagent run",
+	var transcriptRaw bool
+	transcript := &cobra.Command{
+		Use:   "transcript <run_id>",
+		Short: "Print the messages and tool calls of a subagent run",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			cfg, err := loadCfg(configPath)
@@ -654,12 +1270,51 @@ func subagentsCmd(configPath string) *cobra.Command {
 				return err
 			}
 			defer store.Close()
-			ctx := context.Background()
-			run, err := store.GetSubagentRun(ctx, args[0])
+			run, err := store.GetSubagentRun(context.Background(), args[0])
 			if err != nil {
 				return err
 			}
-			if run.Status.Terminal() {
+			path := subagent.TranscriptPath(run)
+			if path == "" {
+				return fmt.Errorf("run %s kept no artifacts (runtime.subagents.artifactRetentionDays is 0)", run.ID)
+			}
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					return fmt.Errorf("run %s has no transcript yet", run.ID)
+				}
+				return err
+			}
+			if transcriptRaw {
+				_, err = cmd.OutOrStdout().Write(raw)
+				return err
+			}
+			return renderSubagentTranscript(cmd.OutOrStdout(), raw)
+		},
+	}
+	transcript.Flags().BoolVar(&transcriptRaw, "raw", false, "Print the transcript.jsonl file as stored")
+	root.AddCommand(transcript)
+
+	cancel := &cobra.Command{
+		Use:   "cancel <run_id>",
+		Short: "Cancel a queued or running subagent run",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadCfg(configPath)
+			if err != nil {
+				return err
+			}
+			store, err := storepkg.Open(cfg.Storage.DBPath)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+			ctx := context.Background()
+			run, err := store.GetSubagentRun(ctx, args[0])
+			if err != nil {
+				return err
+			}
+			if run.Status.Terminal() {
 				fmt.Printf("Run %s already terminal: %s\n", run.ID, run.Status)
 				return nil
 			}
@@ -688,9 +1343,154 @@ func subagentsCmd(configPath string) *cobra.Command {
 	}
 	root.AddCommand(cancel)
 
+	var retrySession string
+	var retryNoWait bool
+	retry := &cobra.Command{
+		Use:   "retry <run_id>",
+		Short: "Re-run a failed or timed out subagent run",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadCfg(configPath)
+			if err != nil {
+				return err
+			}
+			if err := config.ValidateActiveProvider(cfg); err != nil {
+				return fmt.Errorf("provider setup incomplete: %w. Run `squidbot onboard`", err)
+			}
+			runtime, err := app.BuildRuntime(cfg, logger)
+			if err != nil {
+				return err
+			}
+			defer runtime.Shutdown()
+			ctx := context.Background()
+			run, err := runtime.Engine.RetrySubagentRun(ctx, args[0], retrySession)
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "Run %s queued as retry of %s\n", run.ID, run.RetryOf)
+			if retryNoWait {
+				return nil
+			}
+			run, err = runtime.Engine.WaitSubagentRun(ctx, run.ID, time.Duration(run.TimeoutSec*run.MaxAttempts+30)*time.Second)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "Run %s %s\n", run.ID, run.Status)
+			if run.Result != nil && strings.TrimSpace(run.Result.Summary) != "" {
+				fmt.Fprintln(out, run.Result.Summary)
+			} else if run.Error != "" {
+				fmt.Fprintln(out, run.Error)
+			}
+			return nil
+		},
+	}
+	retry.Flags().StringVar(&retrySession, "session", "", "Only retry runs that belong to this session ID")
+	retry.Flags().BoolVar(&retryNoWait, "no-wait", false, "Leave the new run queued for the gateway instead of running it here")
+	root.AddCommand(retry)
+
+	var olderThan string
+	var pruneStatuses string
+	prune := &cobra.Command{
+		Use:   "prune",
+		Short: "Delete old terminal subagent runs and their artifacts",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			age, err := parseAge(olderThan)
+			if err != nil {
+				return err
+			}
+			statuses := []subagent.Status{}
+			for _, raw := range strings.Split(pruneStatuses, ",") {
+				status := subagent.Status(strings.TrimSpace(strings.ToLower(raw)))
+				if status == "" {
+					continue
+				}
+				if !status.Terminal() {
+					return fmt.Errorf("status %q is not terminal (use succeeded|failed|timed_out|cancelled)", status)
+				}
+				statuses = append(statuses, status)
+			}
+			cfg, err := loadCfg(configPath)
+			if err != nil {
+				return err
+			}
+			store, err := storepkg.Open(cfg.Storage.DBPath)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+			pruned, err := store.PruneSubagentRuns(context.Background(), time.Now().UTC().Add(-age), statuses)
+			if err != nil {
+				return err
+			}
+			artifactRoot := filepath.Join(config.WorkspacePath(cfg), ".squidbot", "subagents")
+			removedDirs := 0
+			for _, run := range pruned {
+				dir := filepath.Clean(strings.TrimSpace(run.ArtifactDir))
+				if run.ArtifactDir == "" || !strings.HasPrefix(dir, artifactRoot+string(os.PathSeparator)) {
+					continue
+				}
+				if err := os.RemoveAll(dir); err == nil {
+					removedDirs++
+				}
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Pruned %d subagent run(s), removed %d artifact dir(s)\n", len(pruned), removedDirs)
+			return nil
+		},
+	}
+	prune.Flags().StringVar(&olderThan, "older-than", "30d", "Minimum age of finished runs to prune (e.g. 30d, 12h)")
+	prune.Flags().StringVar(&pruneStatuses, "status", "", "Comma-separated terminal statuses to prune (default: all terminal)")
+	root.AddCommand(prune)
+
 	return root
 }
 
+// renderSubagentTranscript prints a transcript.jsonl file one message per
+// block, with tool calls listed under the assistant message that made them.
+func renderSubagentTranscript(out io.Writer, raw []byte) error {
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var message provider.Message
+		if err := json.Unmarshal(scanner.Bytes(), &message); err != nil {
+			return fmt.Errorf("transcript line %d: %w", line, err)
+		}
+		heading := message.Role
+		if message.Role == "tool" && message.Name != "" {
+			heading += " " + message.Name
+		}
+		fmt.Fprintf(out, "[%s]\n", heading)
+		if content := strings.TrimSpace(message.Content); content != "" {
+			fmt.Fprintln(out, content)
+		}
+		for _, call := range message.ToolCalls {
+			fmt.Fprintf(out, "→ %s %s\n", call.Name, string(call.Arguments))
+		}
+		fmt.Fprintln(out)
+	}
+	return scanner.Err()
+}
+
+// parseAge accepts Go durations plus a day suffix such as "30d".
+func parseAge(value string) (time.Duration, error) {
+	value = strings.TrimSpace(strings.ToLower(value))
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age %q", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	age, err := time.ParseDuration(value)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("invalid age %q (use e.g. 30d or 12h)", value)
+	}
+	return age, nil
+}
+
 func skillsCmd(configPath string) *cobra.Command {
 	root := &cobra.Command{Use: "skills", Short: "Inspect and validate skill runtime state"}
 	var channel string
@@ -716,13 +1516,14 @@ func skillsCmd(configPath string) *cobra.Command {
 				fmt.Fprintln(cmd.OutOrStdout(), string(raw))
 				return nil
 			}
+			overrides := loadSkillOverrides(cmd.Context(), cfg)
 			for _, item := range snapshot.Skills {
 				decision := "enabled"
-				allowed, denied := skillsPolicyOutcome(cfg, channel, item)
-				if !allowed {
-					decision = denied
+				outcome := skillsPolicyOutcome(cfg, channel, item, overrides)
+				if !outcome.Allowed {
+					decision = outcome.Reason
 				}
-				fmt.Fprintf(cmd.OutOrStdout(), "%s\t%s\t%s\tvalid=%v\ttags=%s\t%s\n", item.ID, item.Name, item.SourceKind, item.Valid, strings.Join(item.Tags, ","), decision)
+				fmt.Fprintf(cmd.OutOrStdout(), "%s\t%s\t%s\tvalid=%v\ttags=%s\t%s\tsource=%s\n", item.ID, item.Name, item.SourceKind, item.Valid, strings.Join(item.Tags, ","), decision, outcome.Source)
 				fmt.Fprintf(cmd.OutOrStdout(), "  %s\n", item.Path)
 			}
 			if len(snapshot.Warnings) > 0 {
@@ -762,12 +1563,13 @@ func skillsCmd(configPath string) *cobra.Command {
 				if strings.ToLower(item.ID) != id {
 					continue
 				}
-				allowed, denied := skillsPolicyOutcome(cfg, channel, item)
+				outcome := skillsPolicyOutcome(cfg, channel, item, loadSkillOverrides(cmd.Context(), cfg))
 				payload := map[string]any{
 					"skill":           item,
 					"channel":         channel,
-					"policy_allowed":  allowed,
-					"policy_decision": denied,
+					"policy_allowed":  outcome.Allowed,
+					"policy_decision": outcome.Reason,
+					"policy_source":   outcome.Source,
 				}
 				if strings.TrimSpace(showQuery) != "" {
 					activation, err := runtime.Activate(cmd.Context(), skills.ActivationRequest{
@@ -833,6 +1635,76 @@ func skillsCmd(configPath string) *cobra.Command {
 	show.Flags().StringSliceVar(&showMentions, "mention", nil, "Explicit skill mentions for activation diagnostics (repeatable)")
 	root.AddCommand(show)
 
+	var rankQuery, rankChannel, rankSessionID string
+	var rankSubagent, rankJSON bool
+	rankMentions := []string{}
+	rank := &cobra.Command{
+		Use:   "rank",
+		Short: "Rank every skill against one query with its activation score breakdown",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if strings.TrimSpace(rankQuery) == "" {
+				return fmt.Errorf("--query is required")
+			}
+			cfg, err := loadCfg(configPath)
+			if err != nil {
+				return err
+			}
+			runtime := skills.NewManager(cfg, log.Default())
+			if err := runtime.Discover(cmd.Context()); err != nil {
+				return err
+			}
+			overrides := loadSkillOverrides(cmd.Context(), cfg)
+			activation, err := runtime.Activate(cmd.Context(), skills.ActivationRequest{
+				Query:            rankQuery,
+				Channel:          rankChannel,
+				SessionID:        strings.TrimSpace(rankSessionID),
+				IsSubagent:       rankSubagent,
+				ExplicitMentions: rankMentions,
+				Overrides:        overrides,
+			})
+			if err != nil {
+				return err
+			}
+			rows := skillRankRows(cfg, rankChannel, runtime.Snapshot().Skills, activation, overrides)
+			out := cmd.OutOrStdout()
+			if rankJSON {
+				raw, err := json.MarshalIndent(map[string]any{
+					"query":     rankQuery,
+					"channel":   rankChannel,
+					"subagent":  rankSubagent,
+					"threshold": max(cfg.Skills.MatchThreshold, 1),
+					"ranked":    rows,
+					"errors":    activation.Errors,
+					"warnings":  activation.Warnings,
+				}, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(out, string(raw))
+				return nil
+			}
+			fmt.Fprintf(out, "Query: %q (threshold %d, max active %d)\n", rankQuery, max(cfg.Skills.MatchThreshold, 1), max(cfg.Skills.MaxActive, 1))
+			fmt.Fprintf(out, "%-24s  %-10s  %-18s  %6s  %-20s  %s\n", "ID", "STATUS", "REASON", "SCORE", "MATCHED BY", "BREAKDOWN")
+			for _, row := range rows {
+				fmt.Fprintf(out, "%-24s  %-10s  %-18s  %6d  %-20s  %s\n", row.ID, row.Status, valueOrDash(row.Reason), row.Score, valueOrDash(strings.Join(row.MatchedBy, ",")), valueOrDash(formatScoreBreakdown(row.Breakdown)))
+			}
+			for _, message := range activation.Errors {
+				fmt.Fprintf(out, "error: %s\n", message)
+			}
+			for _, message := range activation.Warnings {
+				fmt.Fprintf(out, "warning: %s\n", message)
+			}
+			return nil
+		},
+	}
+	rank.Flags().StringVar(&rankQuery, "query", "", "Query to score every skill against")
+	rank.Flags().StringVar(&rankChannel, "channel", "", "Channel id for policy visibility")
+	rank.Flags().StringVar(&rankSessionID, "session", "", "Session id used for activation diagnostics")
+	rank.Flags().BoolVar(&rankSubagent, "subagent", false, "Evaluate routing as subagent context")
+	rank.Flags().StringSliceVar(&rankMentions, "mention", nil, "Explicit skill mentions (repeatable)")
+	rank.Flags().BoolVar(&rankJSON, "json", false, "Emit JSON output")
+	root.AddCommand(rank)
+
 	var strict bool
 	checkJSON := false
 	check := &cobra.Command{
@@ -887,77 +1759,311 @@ func skillsCmd(configPath string) *cobra.Command {
 	check.Flags().BoolVar(&checkJSON, "json", false, "Emit JSON output")
 	root.AddCommand(check)
 
+	var reloadLocal bool
 	reload := &cobra.Command{
 		Use:   "reload",
-		Short: "Force a skill index refresh",
+		Short: "Force a skill index refresh in the running gateway",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			cfg, err := loadCfg(configPath)
 			if err != nil {
 				return err
 			}
-			runtime := skills.NewManager(cfg, log.Default())
-			snapshot, err := runtime.Reload(cmd.Context())
-			if err != nil {
-				return err
+			var summary app.SkillsReloadSummary
+			where := "gateway"
+			err = errGatewayManageDisabled
+			if !reloadLocal {
+				err = gatewayManagePost(cmd.Context(), cfg, "/api/manage/skills/reload", &summary)
 			}
-			valid := 0
-			invalid := 0
-			for _, item := range snapshot.Skills {
-				if item.Valid {
-					valid++
-				} else {
-					invalid++
+			if err != nil {
+				if !reloadLocal {
+					fmt.Fprintf(cmd.ErrOrStderr(), "Running gateway not reached (%v); checked the skill index locally instead.\n", err)
 				}
+				snapshot, err := skills.NewManager(cfg, log.Default()).Reload(cmd.Context())
+				if err != nil {
+					return err
+				}
+				valid, invalid := snapshot.Counts()
+				summary = app.SkillsReloadSummary{Total: len(snapshot.Skills), Valid: valid, Invalid: invalid, Warnings: snapshot.Warnings}
+				where = "local"
 			}
-			fmt.Fprintf(cmd.OutOrStdout(), "Skills reloaded: total=%d valid=%d invalid=%d warnings=%d\n", len(snapshot.Skills), valid, invalid, len(snapshot.Warnings))
+			fmt.Fprintf(cmd.OutOrStdout(), "Skills reloaded (%s): total=%d valid=%d invalid=%d warnings=%d\n", where, summary.Total, summary.Valid, summary.Invalid, len(summary.Warnings))
 			return nil
 		},
 	}
+	reload.Flags().BoolVar(&reloadLocal, "local", false, "Only rebuild the index from disk; do not contact the gateway")
 	root.AddCommand(reload)
 
+	for _, enable := range []bool{true, false} {
+		root.AddCommand(skillsOverrideCmd(configPath, enable))
+	}
+	root.AddCommand(skillsPolicyCmd(configPath))
+
 	return root
 }
 
-func skillsPolicyOutcome(cfg config.Config, channel string, skill skills.SkillDescriptor) (bool, string) {
-	channel = strings.ToLower(strings.TrimSpace(channel))
-	matchAny := func(tokens []string) bool {
-		if len(tokens) == 0 {
-			return false
-		}
-		needle := strings.ToLower(skill.ID)
-		name := strings.ToLower(skill.Name)
-		for _, token := range tokens {
-			token = strings.ToLower(strings.TrimSpace(token))
-			if token == "" {
-				continue
+// skillsPolicyCmd builds `skills policy export|import`, which move the config
+// allow and deny lists together with stored overrides between instances.
+func skillsPolicyCmd(configPath string) *cobra.Command {
+	root := &cobra.Command{Use: "policy", Short: "Export or import the effective skills policy"}
+
+	var exportOut string
+	export := &cobra.Command{
+		Use:   "export",
+		Short: "Export config policy and stored overrides as JSON",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadCfg(configPath)
+			if err != nil {
+				return err
+			}
+			store, err := storepkg.Open(cfg.Storage.DBPath)
+			if err != nil {
+				return err
+			}
+			overrides := skills.LoadOverrides(cmd.Context(), store)
+			_ = store.Close()
+			file := skills.ExportPolicy(cfg, overrides)
+			raw, err := json.MarshalIndent(file, "", "  ")
+			if err != nil {
+				return err
+			}
+			raw = append(raw, '\n')
+			if strings.TrimSpace(exportOut) == "" {
+				_, err = cmd.OutOrStdout().Write(raw)
+				return err
+			}
+			if err := os.WriteFile(exportOut, raw, 0o600); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Exported skills policy with %d overrides to %s\n", len(file.Overrides), exportOut)
+			return nil
+		},
+	}
+	export.Flags().StringVarP(&exportOut, "out", "o", "", "Write to this file instead of stdout")
+	root.AddCommand(export)
+
+	importCmd := &cobra.Command{
+		Use:   "import <file|->",
+		Short: "Replace config policy and stored overrides from an export file",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var raw []byte
+			var err error
+			if args[0] == "-" {
+				raw, err = io.ReadAll(cmd.InOrStdin())
+			} else {
+				raw, err = os.ReadFile(args[0])
+			}
+			if err != nil {
+				return err
+			}
+			var file skills.PolicyExport
+			if err := json.Unmarshal(raw, &file); err != nil {
+				return fmt.Errorf("invalid skills policy export: %w", err)
+			}
+			policy, overrides, err := file.Normalize()
+			if err != nil {
+				return err
+			}
+			effective, err := loadCfg(configPath)
+			if err != nil {
+				return err
+			}
+			runtime := skills.NewManager(effective, log.Default())
+			if err := runtime.Discover(cmd.Context()); err != nil {
+				return err
+			}
+			for _, unknown := range file.UnknownSkills(runtime.Snapshot().Skills) {
+				fmt.Fprintf(cmd.ErrOrStderr(), "warning: %s matches no discovered skill\n", unknown)
+			}
+			// Load again without the data-path defaults loadCfg fills in or
+			// the environment overrides so only the policy changes in the
+			// saved file.
+			cfg, err := config.LoadFile(configPath)
+			if err != nil {
+				return err
+			}
+			cfg.Skills.Policy = policy
+			store, err := storepkg.Open(effective.Storage.DBPath)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+			if err := skills.SaveOverrides(cmd.Context(), store, overrides); err != nil {
+				return err
+			}
+			if err := config.Save(configPath, cfg); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Imported skills policy: %d allow, %d deny, %d channel rules, %d overrides\n", len(policy.Allow), len(policy.Deny), len(policy.Channels), len(overrides.Overrides))
+			return nil
+		},
+	}
+	root.AddCommand(importCmd)
+	return root
+}
+
+// skillsOverrideCmd builds `skills enable` or `skills disable`, which store a
+// per-skill override checked before the config allow and deny lists.
+func skillsOverrideCmd(configPath string, enable bool) *cobra.Command {
+	use, short := "disable", "Disable a skill without editing config"
+	if enable {
+		use, short = "enable", "Enable a skill without editing config"
+	}
+	var channel string
+	var reset bool
+	cmd := &cobra.Command{
+		Use:   use + " <skill_id>",
+		Short: short,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadCfg(configPath)
+			if err != nil {
+				return err
+			}
+			id := strings.ToLower(strings.TrimSpace(args[0]))
+			runtime := skills.NewManager(cfg, log.Default())
+			if err := runtime.Discover(cmd.Context()); err != nil {
+				return err
+			}
+			found := false
+			for _, item := range runtime.Snapshot().Skills {
+				if strings.ToLower(item.ID) == id {
+					found = true
+					break
+				}
+			}
+			if !found && !reset {
+				return fmt.Errorf("skill %q not found", args[0])
 			}
-			if token == needle || token == name {
-				return true
+			store, err := storepkg.Open(cfg.Storage.DBPath)
+			if err != nil {
+				return err
 			}
-			for _, alias := range skill.Aliases {
-				if token == strings.ToLower(alias) {
-					return true
+			defer store.Close()
+			scope := "all channels"
+			if strings.TrimSpace(channel) != "" {
+				scope = "channel " + strings.ToLower(strings.TrimSpace(channel))
+			}
+			overrides := skills.LoadOverrides(cmd.Context(), store)
+			if reset {
+				if !overrides.Clear(id, channel) {
+					fmt.Fprintf(cmd.OutOrStdout(), "No override stored for %s on %s\n", id, scope)
+					return nil
+				}
+				if err := skills.SaveOverrides(cmd.Context(), store, overrides); err != nil {
+					return err
 				}
+				fmt.Fprintf(cmd.OutOrStdout(), "Cleared override for %s on %s; config policy applies\n", id, scope)
+				return nil
 			}
-		}
-		return false
+			overrides.Set(id, channel, enable)
+			if err := skills.SaveOverrides(cmd.Context(), store, overrides); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Skill %s %sd on %s\n", id, use, scope)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&channel, "channel", "", "Limit the override to one channel")
+	cmd.Flags().BoolVar(&reset, "reset", false, "Remove the stored override instead of setting one")
+	return cmd
+}
+
+type skillRankRow struct {
+	ID        string                `json:"id"`
+	Name      string                `json:"name"`
+	Status    string                `json:"status"`
+	Reason    string                `json:"reason,omitempty"`
+	Score     int                   `json:"score"`
+	Explicit  bool                  `json:"explicit,omitempty"`
+	MatchedBy []string              `json:"matched_by"`
+	Breakdown skills.ScoreBreakdown `json:"breakdown"`
+}
+
+// skillRankRows lists every discovered skill for `skills rank`: the scored
+// skills from the activation diagnostics, then the rest as unmatched,
+// denied by policy, or invalid. Rows are sorted by score, then ID.
+func skillRankRows(cfg config.Config, channel string, discovered []skills.SkillDescriptor, activation skills.ActivationResult, overrides skills.OverrideSet) []skillRankRow {
+	rows := make([]skillRankRow, 0, len(discovered))
+	seen := map[string]struct{}{}
+	for _, ranked := range activation.Diagnostics.Ranked {
+		seen[strings.ToLower(ranked.ID)] = struct{}{}
+		rows = append(rows, skillRankRow{
+			ID:        ranked.ID,
+			Name:      ranked.Name,
+			Status:    ranked.Status,
+			Reason:    ranked.Reason,
+			Score:     ranked.Score,
+			Explicit:  ranked.Explicit,
+			MatchedBy: append([]string{}, ranked.MatchedBy...),
+			Breakdown: ranked.Breakdown,
+		})
 	}
-	if len(cfg.Skills.Policy.Allow) > 0 && !matchAny(cfg.Skills.Policy.Allow) {
-		return false, "denied_by_global_allowlist"
+	for _, skip := range activation.Skipped {
+		if _, ok := seen[strings.ToLower(skip.ID)]; ok {
+			continue
+		}
+		seen[strings.ToLower(skip.ID)] = struct{}{}
+		rows = append(rows, skillRankRow{ID: skip.ID, Name: skip.Name, Status: "skipped", Reason: skip.Reason, Score: skip.Score, MatchedBy: []string{}})
 	}
-	if matchAny(cfg.Skills.Policy.Deny) {
-		return false, "denied_by_global"
+	for _, item := range discovered {
+		if _, ok := seen[strings.ToLower(item.ID)]; ok {
+			continue
+		}
+		row := skillRankRow{ID: item.ID, Name: item.Name, Status: "unmatched", MatchedBy: []string{}}
+		if !item.Valid {
+			row.Status = "invalid"
+		} else if outcome := skillsPolicyOutcome(cfg, channel, item, overrides); !outcome.Allowed {
+			row.Status = "denied"
+			row.Reason = outcome.Reason
+		}
+		rows = append(rows, row)
 	}
-	channelPolicy, ok := cfg.Skills.Policy.Channels[channel]
-	if ok {
-		if len(channelPolicy.Allow) > 0 && !matchAny(channelPolicy.Allow) {
-			return false, "denied_by_channel_allowlist"
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].Score != rows[j].Score {
+			return rows[i].Score > rows[j].Score
 		}
-		if matchAny(channelPolicy.Deny) {
-			return false, "denied_by_channel"
+		return rows[i].ID < rows[j].ID
+	})
+	return rows
+}
+
+// formatScoreBreakdown renders the non-zero parts of a skill score.
+func formatScoreBreakdown(b skills.ScoreBreakdown) string {
+	parts := make([]string, 0, 6)
+	for _, part := range []struct {
+		label string
+		value int
+	}{
+		{"explicit", b.ExplicitBonus},
+		{"id", b.IDBonus},
+		{"name", b.NameBonus},
+		{"alias", b.AliasBonus},
+		{"tags", b.TagBonus},
+		{"description", b.DescriptionBonus},
+		{"examples", b.ExampleBonus},
+	} {
+		if part.value != 0 {
+			parts = append(parts, fmt.Sprintf("%s=%d", part.label, part.value))
 		}
 	}
-	return true, "allowed"
+	return strings.Join(parts, " ")
+}
+
+func skillsPolicyOutcome(cfg config.Config, channel string, skill skills.SkillDescriptor, overrides skills.OverrideSet) skills.PolicyDecision {
+	return skills.EvaluatePolicy(cfg, channel, skill, overrides)
+}
+
+// loadSkillOverrides reads stored skill overrides for display. When the store
+// cannot be opened (for example while the gateway holds it) config policy is
+// shown on its own.
+func loadSkillOverrides(ctx context.Context, cfg config.Config) skills.OverrideSet {
+	store, err := storepkg.Open(cfg.Storage.DBPath)
+	if err != nil {
+		return skills.OverrideSet{}
+	}
+	defer store.Close()
+	return skills.LoadOverrides(ctx, store)
 }
 
 func budgetCmd(configPath string) *cobra.Command {
@@ -1076,10 +2182,19 @@ func budgetCmd(configPath string) *cobra.Command {
 		},
 	})
 
-	root.AddCommand(&cobra.Command{
-		Use:   "enable",
-		Short: "Enable token safety enforcement",
+	var resetScope, resetSession, resetRun string
+	reset := &cobra.Command{
+		Use:   "reset",
+		Short: "Zero a usage counter and clear its stale reservations",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			id := resetRun
+			if strings.EqualFold(strings.TrimSpace(resetScope), "session") {
+				id = resetSession
+			}
+			key, err := budget.ScopeKey(resetScope, id)
+			if err != nil {
+				return err
+			}
 			cfg, err := loadCfg(configPath)
 			if err != nil {
 				return err
@@ -1089,81 +2204,1527 @@ func budgetCmd(configPath string) *cobra.Command {
 				return err
 			}
 			defer store.Close()
-			ctx := context.Background()
-			settings := effectiveTokenSafetySettings(ctx, cfg, store)
-			settings.Enabled = true
-			if err := putTokenSafetyOverride(ctx, store, settings); err != nil {
+			result, err := store.ResetBudgetCounter(context.Background(), key)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Budget counter %s reset (was used=%d reserved=%d; cleared %d reservations)\n",
+				result.Scope, result.Previous.TotalTokens, result.Previous.ReservedTokens, result.ClearedReservations)
+			return nil
+		},
+	}
+	reset.Flags().StringVar(&resetScope, "scope", "", "Scope: global|session|subagent, or session:<id>|subagent:<run>")
+	reset.Flags().StringVar(&resetSession, "session", "", "Session ID for --scope session")
+	reset.Flags().StringVar(&resetRun, "run", "", "Subagent run ID for --scope subagent")
+	_ = reset.MarkFlagRequired("scope")
+	root.AddCommand(reset)
+
+	root.AddCommand(&cobra.Command{
+		Use:   "enable",
+		Short: "Enable token safety enforcement",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadCfg(configPath)
+			if err != nil {
+				return err
+			}
+			store, err := storepkg.Open(cfg.Storage.DBPath)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+			ctx := context.Background()
+			settings := effectiveTokenSafetySettings(ctx, cfg, store)
+			settings.Enabled = true
+			if err := putTokenSafetyOverride(ctx, store, settings); err != nil {
+				return err
+			}
+			fmt.Println("Token safety enabled")
+			return nil
+		},
+	})
+
+	root.AddCommand(&cobra.Command{
+		Use:   "disable",
+		Short: "Disable token safety enforcement",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadCfg(configPath)
+			if err != nil {
+				return err
+			}
+			store, err := storepkg.Open(cfg.Storage.DBPath)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+			ctx := context.Background()
+			settings := effectiveTokenSafetySettings(ctx, cfg, store)
+			settings.Enabled = false
+			if err := putTokenSafetyOverride(ctx, store, settings); err != nil {
+				return err
+			}
+			fmt.Println("Token safety disabled")
+			return nil
+		},
+	})
+
+	var dailyTurns int
+	var avgTokens uint64
+	var days int
+	var sessions int
+	var outputShare float64
+	var model string
+	var simulateJSON bool
+	simulate := &cobra.Command{
+		Use:   "simulate",
+		Short: "Project token usage and cost against the configured limits",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if dailyTurns <= 0 || avgTokens == 0 {
+				return fmt.Errorf("--daily-turns and --avg-tokens must be positive")
+			}
+			cfg, err := loadCfg(configPath)
+			if err != nil {
+				return err
+			}
+			store, err := storepkg.Open(cfg.Storage.DBPath)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+			settings := effectiveTokenSafetySettings(context.Background(), cfg, store)
+			if strings.TrimSpace(model) == "" {
+				model = cfg.Agents.Defaults.Model
+			}
+			input := budget.SimulationInput{
+				DailyTurns:       dailyTurns,
+				AvgTokensPerTurn: avgTokens,
+				Days:             days,
+				Sessions:         sessions,
+				OutputShare:      outputShare,
+			}
+			if price, ok := budget.LookupPrice(pricingTable(cfg), model); ok {
+				input.Price = &price
+			}
+			result := budget.Simulate(settings, input)
+			out := cmd.OutOrStdout()
+			if simulateJSON {
+				raw, err := json.MarshalIndent(map[string]any{"model": model, "simulation": result}, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(out, string(raw))
+				return nil
+			}
+			fmt.Fprintf(out, "Projected usage: %d tokens/day, %d tokens over %d day(s)\n", result.DailyTokens, result.TotalTokens, max(days, 1))
+			for _, scope := range result.Scopes {
+				if scope.HardLimitTokens == 0 {
+					fmt.Fprintf(out, "  %-8s projected %d tokens (no limit)\n", scope.Scope, scope.ProjectedTokens)
+					continue
+				}
+				status := "within limits"
+				switch {
+				case scope.HardReached:
+					status = fmt.Sprintf("hard limit reached on day %d", scope.HardReachedDay)
+				case scope.SoftReached:
+					status = fmt.Sprintf("soft threshold (%d%%) reached on day %d", scope.SoftThresholdPct, scope.SoftReachedDay)
+				}
+				fmt.Fprintf(out, "  %-8s projected %d / %d tokens (%.1f%%): %s\n", scope.Scope, scope.ProjectedTokens, scope.HardLimitTokens, scope.UsedPct, status)
+			}
+			if result.EstimatedCostUSD != nil {
+				fmt.Fprintf(out, "Estimated cost for %s: $%.2f/day, $%.2f total\n", model, *result.DailyCostUSD, *result.EstimatedCostUSD)
+			} else {
+				fmt.Fprintf(out, "No pricing configured for %s (set runtime.tokenSafety.pricing)\n", model)
+			}
+			return nil
+		},
+	}
+	simulate.Flags().IntVar(&dailyTurns, "daily-turns", 0, "Agent turns per day")
+	simulate.Flags().Uint64Var(&avgTokens, "avg-tokens", 0, "Average total tokens per turn")
+	simulate.Flags().IntVar(&days, "days", 30, "Number of days to project")
+	simulate.Flags().IntVar(&sessions, "sessions", 1, "Number of sessions the turns are spread across")
+	simulate.Flags().Float64Var(&outputShare, "output-share", 0.25, "Fraction of tokens that are completion tokens (for cost)")
+	simulate.Flags().StringVar(&model, "model", "", "Model used for pricing (defaults to agents.defaults.model)")
+	simulate.Flags().BoolVar(&simulateJSON, "json", false, "Output as JSON")
+	_ = simulate.MarkFlagRequired("daily-turns")
+	_ = simulate.MarkFlagRequired("avg-tokens")
+	root.AddCommand(simulate)
+
+	var reportDays int
+	var reportFrom string
+	var reportTo string
+	var reportJSON bool
+	var reportCSV bool
+	report := &cobra.Command{
+		Use:   "report",
+		Short: "Show per-day token usage with totals and a daily average",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if reportJSON && reportCSV {
+				return fmt.Errorf("--json and --csv cannot be combined")
+			}
+			to := time.Now().UTC()
+			if strings.TrimSpace(reportTo) != "" {
+				parsed, err := time.Parse(time.RFC3339, strings.TrimSpace(reportTo))
+				if err != nil {
+					return fmt.Errorf("invalid --to: %w", err)
+				}
+				to = parsed
+			}
+			from := to.AddDate(0, 0, -(max(reportDays, 1) - 1))
+			if strings.TrimSpace(reportFrom) != "" {
+				parsed, err := time.Parse(time.RFC3339, strings.TrimSpace(reportFrom))
+				if err != nil {
+					return fmt.Errorf("invalid --from: %w", err)
+				}
+				from = parsed
+			}
+			cfg, err := loadCfg(configPath)
+			if err != nil {
+				return err
+			}
+			store, err := storepkg.Open(cfg.Storage.DBPath)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+			usage, err := store.ListUsageDays(context.Background())
+			if err != nil {
+				return err
+			}
+			result, err := budget.BuildUsageReport(usage, from, to)
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			switch {
+			case reportJSON:
+				raw, err := json.MarshalIndent(result, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(out, string(raw))
+			case reportCSV:
+				writer := csv.NewWriter(out)
+				_ = writer.Write([]string{"day", "prompt_tokens", "completion_tokens", "total_tokens"})
+				for _, day := range append(result.Days, result.Totals) {
+					_ = writer.Write([]string{day.Day, strconv.FormatUint(day.PromptTokens, 10), strconv.FormatUint(day.CompletionTokens, 10), strconv.FormatUint(day.TotalTokens, 10)})
+				}
+				writer.Flush()
+				return writer.Error()
+			default:
+				fmt.Fprintf(out, "%-10s  %12s  %12s  %12s\n", "DAY", "PROMPT", "COMPLETION", "TOTAL")
+				for _, day := range append(result.Days, result.Totals) {
+					fmt.Fprintf(out, "%-10s  %12d  %12d  %12d\n", day.Day, day.PromptTokens, day.CompletionTokens, day.TotalTokens)
+				}
+				avg := result.DailyAverage
+				fmt.Fprintf(out, "%-10s  %12.1f  %12.1f  %12.1f\n", "average", avg.PromptTokens, avg.CompletionTokens, avg.TotalTokens)
+				if len(result.TokenByProvider) > 0 {
+					labels := make([]string, 0, len(result.TokenByProvider))
+					for label := range result.TokenByProvider {
+						labels = append(labels, label)
+					}
+					sort.Strings(labels)
+					fmt.Fprintln(out, "\nBy provider:")
+					for _, label := range labels {
+						share := result.TokenByProvider[label]
+						fmt.Fprintf(out, "  %-32s  %12d  %12d  %12d\n", label, share.PromptTokens, share.CompletionTokens, share.TotalTokens)
+					}
+				}
+			}
+			return nil
+		},
+	}
+	report.Flags().IntVar(&reportDays, "days", 30, "Number of days ending at --to to include")
+	report.Flags().StringVar(&reportFrom, "from", "", "Start of the range (RFC3339); overrides --days")
+	report.Flags().StringVar(&reportTo, "to", "", "End of the range (RFC3339, default now)")
+	report.Flags().BoolVar(&reportJSON, "json", false, "Output as JSON")
+	report.Flags().BoolVar(&reportCSV, "csv", false, "Output as CSV")
+	root.AddCommand(report)
+	return root
+}
+
+func providersCmd(configPath string) *cobra.Command {
+	root := &cobra.Command{Use: "providers", Aliases: []string{"provider"}, Short: "Inspect configured providers and their performance"}
+	var listJSON bool
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List configured providers with model, API base, and key status",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadCfg(configPath)
+			if err != nil {
+				return err
+			}
+			rows := configuredProviders(cfg)
+			out := cmd.OutOrStdout()
+			if listJSON {
+				raw, err := json.MarshalIndent(rows, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(out, string(raw))
+				return nil
+			}
+			if len(rows) == 0 {
+				fmt.Fprintln(out, "No providers configured. Run `squidbot onboard`.")
+				return nil
+			}
+			fmt.Fprintf(out, "%-2s %-16s  %-32s  %-40s  %s\n", "", "PROVIDER", "MODEL", "API BASE", "KEY")
+			for _, row := range rows {
+				marker := ""
+				if row.Active {
+					marker = "*"
+				}
+				key := "missing"
+				if row.KeySet {
+					key = "set"
+				} else if !row.KeyRequired {
+					key = "not required"
+				}
+				fmt.Fprintf(out, "%-2s %-16s  %-32s  %-40s  %s\n", marker, row.Name, valueOrDash(row.Model), valueOrDash(row.APIBase), key)
+			}
+			return nil
+		},
+	}
+	list.Flags().BoolVar(&listJSON, "json", false, "Output as JSON")
+	root.AddCommand(list)
+
+	var testTimeout time.Duration
+	test := &cobra.Command{
+		Use:   "test <name>",
+		Short: "Send a tiny chat to a provider and report latency",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadCfg(configPath)
+			if err != nil {
+				return err
+			}
+			result := liveProviderCheck(cmd.Context(), cfg, args[0], testTimeout)
+			out := cmd.OutOrStdout()
+			if result.Err != nil {
+				fmt.Fprintf(out, "%s (%s): FAIL after %dms: %v\n", result.Name, valueOrDash(result.Model), result.Latency.Milliseconds(), result.Err)
+				return fmt.Errorf("provider %s check failed", result.Name)
+			}
+			fmt.Fprintf(out, "%s (%s): OK in %dms, reply %q\n", result.Name, result.Model, result.Latency.Milliseconds(), result.Reply)
+			return nil
+		},
+	}
+	test.Flags().DurationVar(&testTimeout, "timeout", 30*time.Second, "Give up on the provider after this long")
+	root.AddCommand(test)
+
+	var days int
+	var asJSON bool
+	throughput := &cobra.Command{
+		Use:   "throughput",
+		Short: "Show completion tokens per second by provider and model",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadCfg(configPath)
+			if err != nil {
+				return err
+			}
+			store, err := storepkg.Open(cfg.Storage.DBPath)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+			rows, err := providerThroughput(context.Background(), store, days)
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			if asJSON {
+				raw, err := json.MarshalIndent(rows, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(out, string(raw))
+				return nil
+			}
+			if len(rows) == 0 {
+				fmt.Fprintln(out, "No timed provider calls recorded.")
+				return nil
+			}
+			fmt.Fprintf(out, "%-32s  %8s  %10s  %10s  %12s\n", "PROVIDER/MODEL", "CALLS", "AVG TOK/S", "MAX TOK/S", "AVG LATENCY")
+			for _, row := range rows {
+				fmt.Fprintf(out, "%-32s  %8d  %10.1f  %10.1f  %10dms\n", row.Label, row.Calls, row.AvgTokensPerSec, row.MaxTokensPerSec, row.AvgLatencyMS)
+			}
+			return nil
+		},
+	}
+	throughput.Flags().IntVar(&days, "days", 7, "Number of days, ending today, to include")
+	throughput.Flags().BoolVar(&asJSON, "json", false, "Output as JSON")
+	root.AddCommand(throughput)
+	return root
+}
+
+type configuredProviderRow struct {
+	Name        string `json:"name"`
+	Active      bool   `json:"active"`
+	Model       string `json:"model,omitempty"`
+	APIBase     string `json:"api_base,omitempty"`
+	KeySet      bool   `json:"key_set"`
+	KeyRequired bool   `json:"key_required"`
+}
+
+// configuredProviders lists the registry entries and any non-empty legacy
+// provider blocks, sorted by name. Model and API base fall back to the
+// catalog defaults so the row shows what a request would actually use.
+func configuredProviders(cfg config.Config) []configuredProviderRow {
+	activeName, _ := cfg.PrimaryProvider()
+	names := map[string]struct{}{}
+	for name := range cfg.Providers.Registry {
+		if normalized, ok := config.NormalizeProviderName(name); ok {
+			names[normalized] = struct{}{}
+		}
+	}
+	for _, name := range config.SupportedProviders() {
+		if p, ok := cfg.ProviderByName(name); ok && (strings.TrimSpace(p.APIKey) != "" || strings.TrimSpace(p.APIBase) != "" || strings.TrimSpace(p.Model) != "") {
+			names[name] = struct{}{}
+		}
+	}
+	if activeName != "" {
+		names[activeName] = struct{}{}
+	}
+	rows := make([]configuredProviderRow, 0, len(names))
+	for name := range names {
+		p, _ := cfg.ProviderByName(name)
+		requiresKey, _, _ := config.ProviderRequirements(name)
+		row := configuredProviderRow{
+			Name:        name,
+			Active:      name == activeName,
+			Model:       strings.TrimSpace(p.Model),
+			APIBase:     strings.TrimSpace(p.APIBase),
+			KeySet:      strings.TrimSpace(p.APIKey) != "",
+			KeyRequired: requiresKey,
+		}
+		if row.Model == "" {
+			row.Model = config.ProviderDefaultModel(name)
+		}
+		if row.APIBase == "" {
+			row.APIBase = config.ProviderDefaultAPIBase(name)
+		}
+		rows = append(rows, row)
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Name < rows[j].Name })
+	return rows
+}
+
+func valueOrDash(value string) string {
+	if strings.TrimSpace(value) == "" {
+		return "-"
+	}
+	return value
+}
+
+type providerCheckResult struct {
+	Name    string
+	Model   string
+	Reply   string
+	Latency time.Duration
+	Err     error
+}
+
+// liveProviderCheck sends a one-line chat to the named provider, as if it
+// were active, and times the round trip. A provider without a model of its
+// own is tried with its catalog default, the model `providers list` shows.
+func liveProviderCheck(ctx context.Context, cfg config.Config, name string, timeout time.Duration) providerCheckResult {
+	result := providerCheckResult{Name: strings.TrimSpace(name)}
+	normalized, ok := config.NormalizeProviderName(name)
+	if !ok {
+		result.Err = fmt.Errorf("unknown provider %q; supported: %s", result.Name, strings.Join(config.SupportedProviders(), ", "))
+		return result
+	}
+	result.Name = normalized
+	p, exists := cfg.ProviderByName(normalized)
+	if !exists {
+		result.Err = fmt.Errorf("provider %s is not configured", normalized)
+		return result
+	}
+	cfg.Providers.Active = normalized
+	if strings.TrimSpace(p.Model) == "" {
+		if model := config.ProviderDefaultModel(normalized); model != "" {
+			p.Model = model
+			cfg.Providers.Registry = maps.Clone(cfg.Providers.Registry)
+			cfg.SetProviderByName(normalized, p)
+		}
+	}
+	client, model, err := provider.FromConfig(cfg)
+	result.Model = model
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	started := time.Now()
+	resp, err := client.Chat(ctx, provider.ChatRequest{
+		Model:     model,
+		Messages:  []provider.Message{{Role: "user", Content: "Reply with OK"}},
+		MaxTokens: 16,
+	})
+	result.Latency = time.Since(started)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Reply = strings.TrimSpace(resp.Content)
+	return result
+}
+
+type providerThroughputRow struct {
+	Label            string  `json:"label"`
+	Calls            uint64  `json:"calls"`
+	CompletionTokens uint64  `json:"completion_tokens"`
+	AvgTokensPerSec  float64 `json:"avg_tokens_per_sec"`
+	MaxTokensPerSec  float64 `json:"max_tokens_per_sec"`
+	AvgLatencyMS     uint64  `json:"avg_latency_ms"`
+}
+
+// providerThroughput aggregates the timed provider calls of the last days
+// days per provider/model label, fastest average first.
+func providerThroughput(ctx context.Context, store *storepkg.Store, days int) ([]providerThroughputRow, error) {
+	usage, err := store.ListUsageDays(ctx)
+	if err != nil {
+		return nil, err
+	}
+	to := time.Now().UTC()
+	report, err := budget.BuildUsageReport(usage, to.AddDate(0, 0, -(max(days, 1)-1)), to)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]providerThroughputRow, 0, len(report.TokenByProvider))
+	for label, share := range report.TokenByProvider {
+		if share.Calls == 0 {
+			continue
+		}
+		rows = append(rows, providerThroughputRow{
+			Label:            label,
+			Calls:            share.Calls,
+			CompletionTokens: share.TimedTokens,
+			AvgTokensPerSec:  share.TokensPerSec(),
+			MaxTokensPerSec:  share.MaxTokensPerSec,
+			AvgLatencyMS:     share.LatencyMS / share.Calls,
+		})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].AvgTokensPerSec != rows[j].AvgTokensPerSec {
+			return rows[i].AvgTokensPerSec > rows[j].AvgTokensPerSec
+		}
+		return rows[i].Label < rows[j].Label
+	})
+	return rows, nil
+}
+
+// pricingTable merges configured model prices over the built-in defaults.
+func pricingTable(cfg config.Config) map[string]budget.Price {
+	table := make(map[string]budget.Price, len(budget.DefaultPricing)+len(cfg.Runtime.TokenSafety.Pricing))
+	for name, price := range budget.DefaultPricing {
+		table[name] = price
+	}
+	for name, price := range cfg.Runtime.TokenSafety.Pricing {
+		table[strings.ToLower(strings.TrimSpace(name))] = budget.Price{InputPerMillion: price.InputPerMillion, OutputPerMillion: price.OutputPerMillion}
+	}
+	return table
+}
+
+func authCmd(configPath string) *cobra.Command {
+	root := &cobra.Command{Use: "auth", Short: "Manage local authentication settings"}
+
+	var password string
+	var current string
+	var force bool
+	setPassword := &cobra.Command{
+		Use:   "set-password",
+		Short: "Set the management password hash in config",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.LoadFile(configPath)
+			if err != nil {
+				return err
+			}
+			if strings.TrimSpace(cfg.Auth.PasswordHash) != "" && !force {
+				if !config.VerifyPassword(cfg.Auth.PasswordHash, current) {
+					return fmt.Errorf("current password is incorrect (use --current or --force)")
+				}
+			}
+			if !cmd.Flags().Changed("password") {
+				line, readErr := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+				if readErr != nil && !errors.Is(readErr, io.EOF) {
+					return readErr
+				}
+				password = strings.TrimRight(line, "\r\n")
+			}
+			if err := config.SetPassword(&cfg, password, time.Now().UTC()); err != nil {
+				return err
+			}
+			if err := config.Save(configPath, cfg); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Password updated in %s\n", resolvedConfigPath(configPath))
+			return nil
+		},
+	}
+	setPassword.Flags().StringVar(&password, "password", "", "New password (read from stdin when omitted)")
+	setPassword.Flags().StringVar(&current, "current", "", "Current password")
+	setPassword.Flags().BoolVar(&force, "force", false, "Skip current password verification")
+	root.AddCommand(setPassword)
+	return root
+}
+
+func tokenSafetySettingsFromConfig(cfg config.Config) budget.Settings {
+	return budget.Settings{
+		Enabled:                     cfg.Runtime.TokenSafety.Enabled,
+		Mode:                        budget.Mode(budget.NormalizeMode(cfg.Runtime.TokenSafety.Mode)),
+		GlobalHardLimitTokens:       cfg.Runtime.TokenSafety.GlobalHardLimitTokens,
+		GlobalSoftThresholdPct:      cfg.Runtime.TokenSafety.GlobalSoftThresholdPct,
+		SessionHardLimitTokens:      cfg.Runtime.TokenSafety.SessionHardLimitTokens,
+		SessionSoftThresholdPct:     cfg.Runtime.TokenSafety.SessionSoftThresholdPct,
+		SubagentRunHardLimitTokens:  cfg.Runtime.TokenSafety.SubagentRunHardLimitTokens,
+		SubagentRunSoftThresholdPct: cfg.Runtime.TokenSafety.SubagentRunSoftThresholdPct,
+		EstimateOnMissingUsage:      cfg.Runtime.TokenSafety.EstimateOnMissingUsage,
+		EstimateCharsPerToken:       cfg.Runtime.TokenSafety.EstimateCharsPerToken,
+		TrustedWriters:              append([]string(nil), cfg.Runtime.TokenSafety.TrustedWriters...),
+		ReservationTTLSec:           300,
+	}.Normalized()
+}
+
+func effectiveTokenSafetySettings(ctx context.Context, cfg config.Config, store *storepkg.Store) budget.Settings {
+	settings := tokenSafetySettingsFromConfig(cfg)
+	if store == nil {
+		return settings
+	}
+	override, err := store.GetTokenSafetyOverride(ctx)
+	if err == nil {
+		return override.Settings.Normalized()
+	}
+	return settings
+}
+
+func putTokenSafetyOverride(ctx context.Context, store *storepkg.Store, settings budget.Settings) error {
+	if store == nil {
+		return fmt.Errorf("store is required")
+	}
+	return store.PutTokenSafetyOverride(ctx, budget.TokenSafetyOverride{
+		Settings:  settings.Normalized(),
+		UpdatedAt: time.Now().UTC(),
+		Version:   1,
+	})
+}
+
+func sessionsCmd(configPath string) *cobra.Command {
+	root := &cobra.Command{Use: "sessions", Short: "Inspect and control agent sessions"}
+	setLock := func(use, short string, locked bool) *cobra.Command {
+		return &cobra.Command{
+			Use:   use + " <session-id>",
+			Short: short,
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				cfg, err := loadCfg(configPath)
+				if err != nil {
+					return err
+				}
+				store, err := storepkg.Open(cfg.Storage.DBPath)
+				if err != nil {
+					return err
+				}
+				defer store.Close()
+				sessionID := strings.TrimSpace(args[0])
+				if err := agent.SetSessionToolsLocked(context.Background(), store, sessionID, locked); err != nil {
+					return err
+				}
+				state := "unlocked"
+				if locked {
+					state = "locked (read-only tools only)"
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "Tools %s for session %s\n", state, sessionID)
+				return nil
+			},
+		}
+	}
+	root.AddCommand(setLock("lock-tools", "Restrict a session to read-only tools", true))
+	root.AddCommand(setLock("unlock-tools", "Restore the full tool set for a session", false))
+
+	var listJSON bool
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List sessions with their last activity and whether an actor is live",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadCfg(configPath)
+			if err != nil {
+				return err
+			}
+			statuses, liveErr := liveSessionStatuses(cmd.Context(), cfg)
+			if liveErr != nil {
+				store, err := storepkg.Open(cfg.Storage.DBPath)
+				if err != nil {
+					return err
+				}
+				defer store.Close()
+				records, err := store.ListSessions(context.Background())
+				if err != nil {
+					return err
+				}
+				statuses = agent.SessionStatuses(records, nil)
+				fmt.Fprintf(cmd.ErrOrStderr(), "Live state unavailable (%v); showing stored sessions only.\n", liveErr)
+			}
+			if listJSON {
+				raw, err := json.MarshalIndent(statuses, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), string(raw))
+				return nil
+			}
+			if len(statuses) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "No sessions.")
+				return nil
+			}
+			for _, status := range statuses {
+				title, _ := status.Meta["title"].(string)
+				if strings.TrimSpace(title) == "" {
+					title = "(untitled)"
+				}
+				channel, _ := status.Meta["last_channel"].(string)
+				state := "idle"
+				if status.Live {
+					state = "live"
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "%s\t%s\t%s\t%s\t%s\n", status.SessionID, status.LastActive.Format(time.RFC3339), state, channel, title)
+			}
+			return nil
+		},
+	}
+	list.Flags().BoolVar(&listJSON, "json", false, "Print sessions as JSON")
+	root.AddCommand(list)
+
+	var showJSON bool
+	show := &cobra.Command{
+		Use:   "show <session-id>",
+		Short: "Show a session's title, channel, tool lock, and skill focus",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadCfg(configPath)
+			if err != nil {
+				return err
+			}
+			store, err := storepkg.Open(cfg.Storage.DBPath)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+			ctx := context.Background()
+			sessionID := strings.TrimSpace(args[0])
+			meta, err := store.GetSessionMeta(ctx, sessionID)
+			if err != nil {
+				return err
+			}
+			locked := agent.SessionToolsLocked(ctx, store, sessionID)
+			focus := agent.SessionFocus(ctx, store, sessionID)
+			if showJSON {
+				raw, err := json.MarshalIndent(map[string]any{
+					"session_id":   sessionID,
+					"meta":         meta,
+					"tools_locked": locked,
+					"focus_skill":  focus,
+				}, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), string(raw))
+				return nil
+			}
+			title, _ := meta["title"].(string)
+			if strings.TrimSpace(title) == "" {
+				title = "(untitled)"
+			}
+			channel, _ := meta["last_channel"].(string)
+			chatID, _ := meta["last_chat_id"].(string)
+			if focus == "" {
+				focus = "(none)"
+			}
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "Session: %s\n", sessionID)
+			fmt.Fprintf(out, "Title: %s\n", title)
+			fmt.Fprintf(out, "Last channel: %s %s\n", channel, chatID)
+			fmt.Fprintf(out, "Tools locked: %v\n", locked)
+			fmt.Fprintf(out, "Focused skill: %s\n", focus)
+			return nil
+		},
+	}
+	show.Flags().BoolVar(&showJSON, "json", false, "Print the session as JSON")
+	root.AddCommand(show)
+
+	var exportFormat string
+	var exportOut string
+	export := &cobra.Command{
+		Use:   "export <session-id>",
+		Short: "Export a session transcript as JSON or markdown",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, err := agent.NormalizeTranscriptFormat(exportFormat)
+			if err != nil {
+				return err
+			}
+			cfg, err := loadCfg(configPath)
+			if err != nil {
+				return err
+			}
+			store, err := storepkg.Open(cfg.Storage.DBPath)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+			transcript, err := agent.LoadTranscript(context.Background(), store, strings.TrimSpace(args[0]))
+			if err != nil {
+				return err
+			}
+			if len(transcript.Turns) == 0 {
+				return fmt.Errorf("session %s has no stored turns", transcript.SessionID)
+			}
+			raw, err := agent.RenderTranscript(transcript, format)
+			if err != nil {
+				return err
+			}
+			if strings.TrimSpace(exportOut) == "" {
+				_, err = cmd.OutOrStdout().Write(raw)
+				return err
+			}
+			if err := os.WriteFile(exportOut, raw, 0o600); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Exported %d turns to %s\n", len(transcript.Turns), exportOut)
+			return nil
+		},
+	}
+	export.Flags().StringVar(&exportFormat, "format", agent.TranscriptJSON, "Transcript format: json or markdown")
+	export.Flags().StringVarP(&exportOut, "out", "o", "", "Write to this file instead of stdout")
+	root.AddCommand(export)
+	return root
+}
+
+func tasksCmd(configPath string) *cobra.Command {
+	root := &cobra.Command{Use: "tasks", Short: "Inspect and manage mission tasks"}
+	policy := &cobra.Command{Use: "policy", Short: "Inspect and edit the task automation policy"}
+	printPolicy := func(cmd *cobra.Command, current mission.TaskAutomationPolicy) error {
+		enabled := make([]string, 0, 4)
+		for _, source := range mission.AutomationSources() {
+			if current.EnabledForSource(source) {
+				enabled = append(enabled, string(source))
+			}
+		}
+		payload := map[string]any{
+			"defaultColumn":   current.DefaultColumnID,
+			"dedupeWindow":    current.DedupeWindow().String(),
+			"dedupeFields":    current.DedupeFields,
+			"dedupeNormalize": current.DedupeNormalization,
+			"dedupeNotes":     current.DedupeNotes,
+			"enabledSources":  enabled,
+			"updatedAt":       current.UpdatedAt,
+		}
+		raw, err := json.MarshalIndent(payload, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), string(raw))
+		return nil
+	}
+	policy.AddCommand(&cobra.Command{
+		Use:   "show",
+		Short: "Show the task automation policy",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadCfg(configPath)
+			if err != nil {
+				return err
+			}
+			store, err := storepkg.Open(cfg.Storage.DBPath)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+			current, err := store.GetTaskAutomationPolicy(context.Background())
+			if err != nil {
+				return err
+			}
+			return printPolicy(cmd, current)
+		},
+	})
+
+	var defaultColumn string
+	var dedupeWindow time.Duration
+	var enableSources []string
+	var dedupeFields string
+	var dedupeNormalize string
+	var dedupeNotes string
+	set := &cobra.Command{
+		Use:   "set",
+		Short: "Update the task automation policy",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadCfg(configPath)
+			if err != nil {
+				return err
+			}
+			store, err := storepkg.Open(cfg.Storage.DBPath)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+			ctx := context.Background()
+			current, err := store.GetTaskAutomationPolicy(ctx)
+			if err != nil {
+				return err
+			}
+			if cmd.Flags().Changed("default-column") {
+				columnID := strings.TrimSpace(defaultColumn)
+				columns, err := store.ListMissionColumns(ctx)
+				if err != nil {
+					return err
+				}
+				if len(columns) == 0 {
+					columns = mission.DefaultColumns(time.Now().UTC())
+				}
+				known := make([]string, 0, len(columns))
+				found := false
+				for _, column := range columns {
+					known = append(known, column.ID)
+					if column.ID == columnID {
+						found = true
+					}
+				}
+				if !found {
+					return fmt.Errorf("unknown column %q (available: %s)", columnID, strings.Join(known, ", "))
+				}
+				current.DefaultColumnID = columnID
+			}
+			if cmd.Flags().Changed("dedupe-window") {
+				if dedupeWindow < time.Second {
+					return fmt.Errorf("dedupe window must be at least 1s")
+				}
+				current.DedupeWindowSec = int(dedupeWindow.Seconds())
+			}
+			if cmd.Flags().Changed("enable-source") {
+				sources := make([]mission.TaskSourceType, 0, len(enableSources))
+				for _, source := range enableSources {
+					if source = strings.ToLower(strings.TrimSpace(source)); source != "" {
+						sources = append(sources, mission.TaskSourceType(source))
+					}
+				}
+				if err := current.SetEnabledSources(sources); err != nil {
+					return err
+				}
+			}
+			if cmd.Flags().Changed("dedupe-fields") {
+				current.DedupeFields = dedupeFields
+			}
+			if cmd.Flags().Changed("dedupe-normalize") {
+				current.DedupeNormalization = dedupeNormalize
+			}
+			if cmd.Flags().Changed("dedupe-notes") {
+				current.DedupeNotes = dedupeNotes
+			}
+			if err := current.NormalizeDedupe(); err != nil {
+				return err
+			}
+			current.UpdatedAt = time.Now().UTC()
+			if err := store.PutTaskAutomationPolicy(ctx, current); err != nil {
+				return err
+			}
+			return printPolicy(cmd, current)
+		},
+	}
+	set.Flags().StringVar(&defaultColumn, "default-column", "", "Column id for auto-created tasks")
+	set.Flags().DurationVar(&dedupeWindow, "dedupe-window", 0, "Window for merging duplicate auto-created tasks (e.g. 6h)")
+	set.Flags().StringSliceVar(&enableSources, "enable-source", nil, "Sources allowed to auto-create tasks; others are disabled (chat,heartbeat,cron,subagent)")
+	set.Flags().StringVar(&dedupeFields, "dedupe-fields", "", "Fields that identify duplicates: title|title_assignee|title_column")
+	set.Flags().StringVar(&dedupeNormalize, "dedupe-normalize", "", "Title normalization: strict (trim only), basic (case/whitespace), loose (also punctuation)")
+	set.Flags().StringVar(&dedupeNotes, "dedupe-notes", "", "How duplicate notes merge: append|replace|ignore")
+	policy.AddCommand(set)
+	root.AddCommand(policy)
+
+	var listColumn, listCursor string
+	var listLimit int
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List mission tasks one page at a time, oldest first",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadCfg(configPath)
+			if err != nil {
+				return err
+			}
+			store, err := storepkg.Open(cfg.Storage.DBPath)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+			page, err := store.ListMissionTasksPage(cmd.Context(), mission.TaskPageQuery{ColumnID: listColumn, Limit: listLimit, Cursor: listCursor})
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			if len(page.Tasks) == 0 {
+				fmt.Fprintln(out, "No tasks")
+				return nil
+			}
+			for _, task := range page.Tasks {
+				due := "-"
+				if task.DueAt != nil {
+					due = task.DueAt.Format(time.RFC3339)
+				}
+				fmt.Fprintf(out, "%s\t%s\t%s\t%s\t%s\n", task.ID, task.ColumnID, task.Priority, due, task.Title)
+			}
+			if page.NextCursor != "" {
+				fmt.Fprintf(cmd.ErrOrStderr(), "More tasks: rerun with --cursor %s\n", page.NextCursor)
+			}
+			return nil
+		},
+	}
+	list.Flags().StringVar(&listColumn, "column", "", "Only list tasks in this column")
+	list.Flags().IntVar(&listLimit, "limit", mission.DefaultTaskPageSize, "Tasks per page (at most 500)")
+	list.Flags().StringVar(&listCursor, "cursor", "", "Continue after the cursor printed by the previous page")
+	root.AddCommand(list)
+	return root
+}
+
+func configCmd(configPath string) *cobra.Command {
+	root := &cobra.Command{Use: "config", Short: "Manage the config file"}
+	var dryRun bool
+	var asJSON bool
+	migrate := &cobra.Command{
+		Use:   "migrate",
+		Short: "Rewrite the config file in the current schema version",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := resolvedConfigPath(configPath)
+			report, err := config.Migrate(path, dryRun)
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				raw, err := json.MarshalIndent(report, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), string(raw))
+				return nil
+			}
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "Config: %s (version %d -> %d)\n", report.Path, report.FromVersion, report.ToVersion)
+			for _, step := range report.Steps {
+				fmt.Fprintf(out, "Applied %s\n", step)
+			}
+			if len(report.Changed) == 0 {
+				fmt.Fprintln(out, "No settings changed")
+			} else {
+				fmt.Fprintln(out, "Changed settings:")
+				for _, change := range report.Changed {
+					fmt.Fprintf(out, "- %s\n", change)
+				}
+			}
+			if report.Rewritten {
+				fmt.Fprintf(out, "Rewrote %s (previous file saved as %s.bak)\n", report.Path, report.Path)
+			} else {
+				fmt.Fprintln(out, "Dry run: file not written")
+			}
+			return nil
+		},
+	}
+	migrate.Flags().BoolVar(&dryRun, "dry-run", false, "Report changes without rewriting the file")
+	migrate.Flags().BoolVar(&asJSON, "json", false, "Emit the migration report as JSON")
+	root.AddCommand(migrate)
+	return root
+}
+
+func versionCmd() *cobra.Command {
+	var asJSON bool
+	cmd := &cobra.Command{
+		Use:   "version",
+		Short: "Show build version and compiled-in capabilities",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			info := buildinfo.Get()
+			providers := config.SupportedProviders()
+			channels := config.SupportedChannels()
+			toolNames := tools.BuiltinToolNames()
+			if asJSON {
+				payload := map[string]any{
+					"version":    info.Version,
+					"commit":     info.Commit,
+					"date":       info.Date,
+					"go_version": info.GoVersion,
+					"platform":   info.Platform,
+					"modified":   info.Modified,
+					"providers":  providers,
+					"channels":   channels,
+					"tools":      toolNames,
+				}
+				raw, err := json.MarshalIndent(payload, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), string(raw))
+				return nil
+			}
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "squidbot %s\n", info.Version)
+			commit := info.Commit
+			if commit == "" {
+				commit = "unknown"
+			}
+			if info.Modified {
+				commit += " (modified)"
+			}
+			fmt.Fprintf(out, "Commit: %s\n", commit)
+			if info.Date != "" {
+				fmt.Fprintf(out, "Built: %s\n", info.Date)
+			}
+			fmt.Fprintf(out, "Go: %s %s\n", info.GoVersion, info.Platform)
+			fmt.Fprintf(out, "Providers: %s\n", strings.Join(providers, ", "))
+			fmt.Fprintf(out, "Channels: %s\n", strings.Join(channels, ", "))
+			fmt.Fprintf(out, "Tools: %s\n", strings.Join(toolNames, ", "))
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print version info as JSON")
+	return cmd
+}
+
+func profileCmd() *cobra.Command {
+	root := &cobra.Command{Use: "profile", Short: "Manage named config profiles"}
+	root.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List configured profiles",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			profiles, err := config.LoadProfiles("")
+			if err != nil {
 				return err
 			}
-			fmt.Println("Token safety enabled")
+			names := profiles.Names()
+			if len(names) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "No profiles configured")
+				return nil
+			}
+			for _, name := range names {
+				path := profiles.Profiles[name]
+				status := ""
+				if _, err := os.Stat(path); err != nil {
+					status = " (missing)"
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "%s\t%s%s\n", name, path, status)
+			}
+			return nil
+		},
+	})
+	root.AddCommand(&cobra.Command{
+		Use:   "add <name> <config-path>",
+		Short: "Add or update a profile",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			profiles, err := config.LoadProfiles("")
+			if err != nil {
+				return err
+			}
+			if err := profiles.Add(args[0], args[1]); err != nil {
+				return err
+			}
+			if err := config.SaveProfiles("", profiles); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Profile %s -> %s\n", strings.TrimSpace(args[0]), profiles.Profiles[strings.TrimSpace(args[0])])
+			return nil
+		},
+	})
+	root.AddCommand(&cobra.Command{
+		Use:   "remove <name>",
+		Short: "Remove a profile",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			profiles, err := config.LoadProfiles("")
+			if err != nil {
+				return err
+			}
+			if !profiles.Remove(args[0]) {
+				return fmt.Errorf("unknown profile %q", args[0])
+			}
+			if err := config.SaveProfiles("", profiles); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Profile %s removed\n", strings.TrimSpace(args[0]))
 			return nil
 		},
 	})
+	return root
+}
 
+func memoryCmd(configPath string) *cobra.Command {
+	root := &cobra.Command{Use: "memory", Short: "Inspect and repair the memory index"}
 	root.AddCommand(&cobra.Command{
-		Use:   "disable",
-		Short: "Disable token safety enforcement",
+		Use:   "doctor",
+		Short: "Check memory index integrity",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			cfg, err := loadCfg(configPath)
 			if err != nil {
 				return err
 			}
-			store, err := storepkg.Open(cfg.Storage.DBPath)
+			mem := memory.NewManager(cfg)
+			defer mem.Close()
+			if !mem.Enabled() {
+				fmt.Println("Memory is disabled")
+				return nil
+			}
+			health, err := mem.CheckIndex(cmd.Context())
 			if err != nil {
 				return err
 			}
-			defer store.Close()
-			ctx := context.Background()
-			settings := effectiveTokenSafetySettings(ctx, cfg, store)
-			settings.Enabled = false
-			if err := putTokenSafetyOverride(ctx, store, settings); err != nil {
+			fmt.Printf("Index: %s\n", health.Path)
+			fmt.Printf("Integrity: %s\n", health.Integrity)
+			fmt.Printf("Chunks: %d fts=%v ftsRows=%d\n", health.Chunks, health.FTS, health.FTSRows)
+			if health.OK() {
+				fmt.Println("Memory index healthy")
+				return nil
+			}
+			fmt.Println("Memory index issues:")
+			for _, problem := range health.Problems {
+				fmt.Println("-", problem)
+			}
+			fmt.Println("Run `squidbot memory rebuild` to recreate the index from workspace files.")
+			return fmt.Errorf("memory index checks failed")
+		},
+	})
+	root.AddCommand(&cobra.Command{
+		Use:   "rebuild",
+		Short: "Drop and recreate the memory index from workspace sources",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadCfg(configPath)
+			if err != nil {
 				return err
 			}
-			fmt.Println("Token safety disabled")
+			mem := memory.NewManager(cfg)
+			defer mem.Close()
+			if !mem.Enabled() {
+				return fmt.Errorf("memory is disabled")
+			}
+			if err := mem.RebuildIndex(cmd.Context()); err != nil {
+				return err
+			}
+			health, err := mem.CheckIndex(cmd.Context())
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Memory index rebuilt: %s (%d chunks)\n", health.Path, health.Chunks)
 			return nil
 		},
 	})
+
+	var searchLimit int
+	search := &cobra.Command{
+		Use:   "search <query>",
+		Short: "Search the memory index",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadCfg(configPath)
+			if err != nil {
+				return err
+			}
+			mem := memory.NewManager(cfg)
+			defer mem.Close()
+			if !mem.Enabled() {
+				return fmt.Errorf("memory is disabled")
+			}
+			chunks, err := mem.Search(cmd.Context(), strings.Join(args, " "), searchLimit)
+			if err != nil {
+				return err
+			}
+			if len(chunks) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "No memory matches")
+				return nil
+			}
+			for _, chunk := range chunks {
+				fmt.Fprintf(cmd.OutOrStdout(), "%s (%s, score %.3f)\n  %s\n", chunk.Path, chunk.Kind, chunk.Score, strings.Join(strings.Fields(chunk.Content), " "))
+			}
+			return nil
+		},
+	}
+	search.Flags().IntVar(&searchLimit, "limit", 0, "Maximum results (default memory.topK)")
+	root.AddCommand(search)
+
+	var exportOut string
+	export := &cobra.Command{
+		Use:   "export",
+		Short: "Export memory files and the index as a tar.gz archive",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadCfg(configPath)
+			if err != nil {
+				return err
+			}
+			mem := memory.NewManager(cfg)
+			defer mem.Close()
+			if strings.TrimSpace(exportOut) == "" {
+				_, err = mem.Export(cmd.Context(), cmd.OutOrStdout())
+				return err
+			}
+			file, err := os.OpenFile(exportOut, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+			if err != nil {
+				return err
+			}
+			manifest, err := mem.Export(cmd.Context(), file)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Exported %d files and %d chunks to %s\n", manifest.Files, manifest.Chunks, exportOut)
+			return nil
+		},
+	}
+	export.Flags().StringVarP(&exportOut, "out", "o", "", "Write to this file instead of stdout")
+	root.AddCommand(export)
+
+	var force bool
+	importCmd := &cobra.Command{
+		Use:   "import <file|->",
+		Short: "Restore memory files and the index from an export archive",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadCfg(configPath)
+			if err != nil {
+				return err
+			}
+			var in io.Reader = cmd.InOrStdin()
+			if args[0] != "-" {
+				file, err := os.Open(args[0])
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				in = file
+			}
+			mem := memory.NewManager(cfg)
+			defer mem.Close()
+			result, err := mem.Import(cmd.Context(), in, force)
+			if errors.Is(err, memory.ErrWorkspaceNotEmpty) {
+				return fmt.Errorf("%w: %s already has memory; pass --force to overwrite", err, config.WorkspacePath(cfg))
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Imported %d files and %d chunks\n", result.Files, result.Chunks)
+			return nil
+		},
+	}
+	importCmd.Flags().BoolVar(&force, "force", false, "Overwrite memory in a workspace that is not empty")
+	root.AddCommand(importCmd)
 	return root
 }
 
-func tokenSafetySettingsFromConfig(cfg config.Config) budget.Settings {
-	return budget.Settings{
-		Enabled:                     cfg.Runtime.TokenSafety.Enabled,
-		Mode:                        budget.Mode(budget.NormalizeMode(cfg.Runtime.TokenSafety.Mode)),
-		GlobalHardLimitTokens:       cfg.Runtime.TokenSafety.GlobalHardLimitTokens,
-		GlobalSoftThresholdPct:      cfg.Runtime.TokenSafety.GlobalSoftThresholdPct,
-		SessionHardLimitTokens:      cfg.Runtime.TokenSafety.SessionHardLimitTokens,
-		SessionSoftThresholdPct:     cfg.Runtime.TokenSafety.SessionSoftThresholdPct,
-		SubagentRunHardLimitTokens:  cfg.Runtime.TokenSafety.SubagentRunHardLimitTokens,
-		SubagentRunSoftThresholdPct: cfg.Runtime.TokenSafety.SubagentRunSoftThresholdPct,
-		EstimateOnMissingUsage:      cfg.Runtime.TokenSafety.EstimateOnMissingUsage,
-		EstimateCharsPerToken:       cfg.Runtime.TokenSafety.EstimateCharsPerToken,
-		TrustedWriters:              append([]string(nil), cfg.Runtime.TokenSafety.TrustedWriters...),
-		ReservationTTLSec:           300,
-	}.Normalized()
+// refreshSummary is the combined result of `squidbot refresh`.
+type refreshSummary struct {
+	SkillsTotal    int      `json:"skills_total"`
+	SkillsValid    int      `json:"skills_valid"`
+	SkillsInvalid  int      `json:"skills_invalid"`
+	SkillsWarnings int      `json:"skills_warnings"`
+	MemoryEnabled  bool     `json:"memory_enabled"`
+	MemoryChunks   int      `json:"memory_chunks"`
+	PluginsEnabled bool     `json:"plugins_enabled"`
+	PluginsFound   int      `json:"plugins_found"`
+	PluginTools    int      `json:"plugin_tools"`
+	Errors         []string `json:"errors,omitempty"`
 }
 
-func effectiveTokenSafetySettings(ctx context.Context, cfg config.Config, store *storepkg.Store) budget.Settings {
-	settings := tokenSafetySettingsFromConfig(cfg)
-	if store == nil {
-		return settings
+func refreshCmd(configPath string) *cobra.Command {
+	var asJSON bool
+	cmd := &cobra.Command{
+		Use:   "refresh",
+		Short: "Reload skills, re-sync memory, and re-discover plugins",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadCfg(configPath)
+			if err != nil {
+				return err
+			}
+			ctx := cmd.Context()
+			summary := refreshSummary{}
+			var errs []error
+			fail := func(step string, err error) {
+				errs = append(errs, fmt.Errorf("%s: %w", step, err))
+				summary.Errors = append(summary.Errors, step+": "+err.Error())
+			}
+
+			snapshot, err := skills.NewManager(cfg, log.Default()).Reload(ctx)
+			if err != nil {
+				fail("skills", err)
+			}
+			summary.SkillsTotal = len(snapshot.Skills)
+			summary.SkillsWarnings = len(snapshot.Warnings)
+			for _, item := range snapshot.Skills {
+				if item.Valid {
+					summary.SkillsValid++
+				} else {
+					summary.SkillsInvalid++
+				}
+			}
+
+			mem := memory.NewManager(cfg)
+			summary.MemoryEnabled = mem.Enabled()
+			if mem.Enabled() {
+				if err := mem.Sync(ctx); err != nil {
+					fail("memory", err)
+				} else if health, err := mem.CheckIndex(ctx); err != nil {
+					fail("memory", err)
+				} else {
+					summary.MemoryChunks = health.Chunks
+				}
+			}
+			_ = mem.Close()
+
+			pluginRuntime := plugins.NewManager(cfg, log.Default())
+			summary.PluginsEnabled = cfg.Features.Plugins || cfg.Runtime.Plugins.Enabled
+			if err := pluginRuntime.Discover(ctx); err != nil {
+				fail("plugins", err)
+			}
+			found := map[string]struct{}{}
+			for _, tool := range pluginRuntime.Tools() {
+				found[tool.PluginName] = struct{}{}
+				summary.PluginTools++
+			}
+			summary.PluginsFound = len(found)
+			_ = pluginRuntime.Close()
+
+			out := cmd.OutOrStdout()
+			if asJSON {
+				raw, err := json.MarshalIndent(summary, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(out, string(raw))
+			} else {
+				fmt.Fprintf(out, "Skills: total=%d valid=%d invalid=%d warnings=%d\n", summary.SkillsTotal, summary.SkillsValid, summary.SkillsInvalid, summary.SkillsWarnings)
+				if summary.MemoryEnabled {
+					fmt.Fprintf(out, "Memory: chunks=%d\n", summary.MemoryChunks)
+				} else {
+					fmt.Fprintln(out, "Memory: disabled")
+				}
+				if summary.PluginsEnabled {
+					fmt.Fprintf(out, "Plugins: found=%d tools=%d\n", summary.PluginsFound, summary.PluginTools)
+				} else {
+					fmt.Fprintln(out, "Plugins: disabled")
+				}
+			}
+			return errors.Join(errs...)
+		},
 	}
-	override, err := store.GetTokenSafetyOverride(ctx)
-	if err == nil {
-		return override.Settings.Normalized()
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Output as JSON")
+	return cmd
+}
+
+func toolsCmd(configPath string) *cobra.Command {
+	root := &cobra.Command{Use: "tools", Short: "Inspect the tools available to the agent"}
+	var asJSON bool
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List effective tools with their parameter schemas",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadCfg(configPath)
+			if err != nil {
+				return err
+			}
+			store, err := storepkg.Open(cfg.Storage.DBPath)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+			engine, err := agent.NewEngine(cfg, nil, "", store, nil, log.New(io.Discard, "", 0))
+			if err != nil {
+				return err
+			}
+			defer engine.Close()
+			defs, err := engine.ToolDefinitions(agent.InboundMessage{
+				SessionID: "cli:default",
+				Channel:   "cli",
+				ChatID:    "direct",
+				SenderID:  "user",
+				CreatedAt: time.Now().UTC(),
+			})
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				payload := make([]map[string]any, 0, len(defs))
+				for _, def := range defs {
+					payload = append(payload, map[string]any{
+						"name":        def.Name,
+						"description": def.Description,
+						"parameters":  def.Schema,
+					})
+				}
+				raw, err := json.MarshalIndent(payload, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), string(raw))
+				return nil
+			}
+			for _, def := range defs {
+				fmt.Fprintf(cmd.OutOrStdout(), "%s\t%s\n", def.Name, def.Description)
+			}
+			return nil
+		},
 	}
-	return settings
+	list.Flags().BoolVar(&asJSON, "json", false, "Print tool names, descriptions, and parameter schemas as JSON")
+	root.AddCommand(list)
+
+	var logSession, logTool string
+	var logLimit int
+	var logJSON bool
+	logCmd := &cobra.Command{
+		Use:   "log",
+		Short: "Show recorded tool calls, newest first",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadCfg(configPath)
+			if err != nil {
+				return err
+			}
+			store, err := storepkg.Open(cfg.Storage.DBPath)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+			events, err := store.QueryToolEvents(context.Background(), logSession, logTool, logLimit)
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			if logJSON {
+				raw, err := json.MarshalIndent(events, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(out, string(raw))
+				return nil
+			}
+			if len(events) == 0 {
+				fmt.Fprintln(out, "No tool calls recorded")
+				return nil
+			}
+			for _, event := range events {
+				fmt.Fprintf(out, "%s\t%s\t%s\t%s\n", event.CreatedAt.Local().Format("2006-01-02 15:04:05"), event.ToolName, event.SessionID, toolLogPreview(event.Output, 80))
+			}
+			return nil
+		},
+	}
+	logCmd.Flags().StringVar(&logSession, "session", "", "Only calls from this session ID")
+	logCmd.Flags().StringVar(&logTool, "tool", "", "Only calls to this tool")
+	logCmd.Flags().IntVar(&logLimit, "limit", 50, "Max number of calls to show")
+	logCmd.Flags().BoolVar(&logJSON, "json", false, "Print full input and output as JSON")
+	root.AddCommand(logCmd)
+	return root
 }
 
-func putTokenSafetyOverride(ctx context.Context, store *storepkg.Store, settings budget.Settings) error {
-	if store == nil {
-		return fmt.Errorf("store is required")
+// toolLogPreview flattens output to one line and cuts it to maxRunes.
+func toolLogPreview(output string, maxRunes int) string {
+	preview := strings.Join(strings.Fields(output), " ")
+	if runes := []rune(preview); len(runes) > maxRunes {
+		return string(runes[:maxRunes-3]) + "..."
 	}
-	return store.PutTokenSafetyOverride(ctx, budget.TokenSafetyOverride{
-		Settings:  settings.Normalized(),
-		UpdatedAt: time.Now().UTC(),
-		Version:   1,
-	})
+	return preview
 }
 
 func doctorCmd(configPath string) *cobra.Command {
@@ -1246,6 +3807,22 @@ func doctorCmd(configPath string) *cobra.Command {
 					problems = append(problems, "semantic memory rerankTopK must be > 0")
 				}
 			}
+			for _, problem := range agent.ValidatePostProcessors(cfg.Agents.PostProcessors.Default) {
+				problems = append(problems, "agents.postProcessors.default: "+problem)
+			}
+			for channel, steps := range cfg.Agents.PostProcessors.Channels {
+				for _, problem := range agent.ValidatePostProcessors(steps) {
+					problems = append(problems, fmt.Sprintf("agents.postProcessors.channels[%s]: %s", channel, problem))
+				}
+			}
+			if cfg.Runtime.AgentAPI.Enabled {
+				if strings.TrimSpace(cfg.Runtime.AgentAPI.ListenAddr) == "" {
+					problems = append(problems, "agent api enabled but runtime.agentApi.listenAddr missing")
+				}
+				if strings.TrimSpace(cfg.Runtime.AgentAPI.AuthToken) == "" {
+					problems = append(problems, "agent api enabled but runtime.agentApi.authToken missing")
+				}
+			}
 			if cfg.Runtime.Federation.Enabled {
 				if strings.TrimSpace(cfg.Runtime.Federation.ListenAddr) == "" {
 					problems = append(problems, "federation enabled but runtime.federation.listenAddr missing")
@@ -1283,9 +3860,24 @@ func doctorCmd(configPath string) *cobra.Command {
 			if _, readErr := os.ReadFile(filepath.Join(workspace, "HEARTBEAT.md")); readErr != nil {
 				problems = append(problems, "heartbeat file not readable: "+readErr.Error())
 			}
+			for _, profile := range app.HeartbeatProfiles(cfg) {
+				if profile.File == heartbeat.DefaultFile {
+					continue
+				}
+				if _, readErr := os.ReadFile(filepath.Join(workspace, profile.File)); readErr != nil {
+					problems = append(problems, fmt.Sprintf("heartbeat profile %q file not readable: %v", profile.Name, readErr))
+				}
+			}
 			mem := memory.NewManager(cfg)
+			defer mem.Close()
 			if err := mem.EnsureIndex(cmd.Context()); err != nil {
 				problems = append(problems, "memory index unavailable: "+err.Error())
+			} else if health, err := mem.CheckIndex(cmd.Context()); err != nil {
+				problems = append(problems, "memory index check failed: "+err.Error())
+			} else {
+				for _, problem := range health.Problems {
+					problems = append(problems, "memory index: "+problem+" (run `squidbot memory rebuild`)")
+				}
 			}
 			if cfg.Features.Plugins || cfg.Runtime.Plugins.Enabled {
 				pluginRuntime := plugins.NewManager(cfg, log.Default())
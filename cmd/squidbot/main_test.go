@@ -3,8 +3,12 @@ package main
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"io"
 	"log"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -12,8 +16,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/grixate/squidbot/internal/agent"
 	"github.com/grixate/squidbot/internal/budget"
+	"github.com/grixate/squidbot/internal/buildinfo"
 	"github.com/grixate/squidbot/internal/config"
+	"github.com/grixate/squidbot/internal/cron"
 	storepkg "github.com/grixate/squidbot/internal/storage/bbolt"
 	"github.com/grixate/squidbot/internal/subagent"
 )
@@ -91,6 +98,49 @@ func TestCronRunCommandRequiresProviderSetup(t *testing.T) {
 	}
 }
 
+func TestCronAddRecurrenceShortcuts(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg := baseTestConfig(t)
+	configPath := writeTestConfig(t, cfg)
+	run := func(args ...string) error {
+		cmd := cronCmd(configPath, log.New(io.Discard, "", 0))
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		cmd.SetOut(io.Discard)
+		cmd.SetErr(io.Discard)
+		cmd.SetArgs(append([]string{"add", "--name", "standup", "--message", "post the standup"}, args...))
+		return cmd.Execute()
+	}
+
+	if err := run("--weekly", "--weekday", "mon", "--time", "09:00"); err != nil {
+		t.Fatal(err)
+	}
+	for want, args := range map[string][]string{
+		"requires --daily":   {"--time", "09:00"},
+		"invalid time":       {"--daily", "--time", "9am"},
+		"use only one":       {"--daily", "--weekly", "--time", "09:00"},
+		"cannot be combined": {"--monthly", "--time", "09:00", "--cron", "0 9 * * *"},
+		"requires --weekly":  {"--daily", "--time", "09:00", "--weekday", "fri"},
+	} {
+		if err := run(args...); err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected error containing %q for %v, got %v", want, args, err)
+		}
+	}
+
+	store, err := storepkg.Open(cfg.Storage.DBPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	jobs, err := cron.NewService(store, nil, nil).List(context.Background(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || jobs[0].Schedule.Kind != cron.ScheduleCron || jobs[0].Schedule.Expr != "0 9 * * 1" {
+		t.Fatalf("unexpected jobs: %+v", jobs)
+	}
+}
+
 func TestOnboardStatusDoctorCommandsRemainRunnable(t *testing.T) {
 	t.Setenv("HOME", t.TempDir())
 	cfg := baseTestConfig(t)
@@ -308,7 +358,7 @@ func TestSubagentsCancelWritesExternalCancelSignal(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	cmd := subagentsCmd(configPath)
+	cmd := subagentsCmd(configPath, log.New(io.Discard, "", 0))
 	cmd.SilenceUsage = true
 	cmd.SilenceErrors = true
 	cmd.SetOut(io.Discard)
@@ -335,6 +385,314 @@ func TestSubagentsCancelWritesExternalCancelSignal(t *testing.T) {
 	}
 }
 
+func TestSubagentsListFiltersByTimeWindow(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg := baseTestConfig(t)
+	configPath := writeTestConfig(t, cfg)
+	store, err := storepkg.Open(cfg.Storage.DBPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(minutes int) *time.Time {
+		value := base.Add(time.Duration(minutes) * time.Minute)
+		return &value
+	}
+	runs := []subagent.Run{
+		{ID: "run-old", Status: subagent.StatusFailed, CreatedAt: *at(-180), FinishedAt: at(-170)},
+		{ID: "run-recent", Status: subagent.StatusFailed, CreatedAt: *at(-90), FinishedAt: at(-30)},
+		{ID: "run-open", Status: subagent.StatusRunning, CreatedAt: *at(-120)},
+		{ID: "run-late", Status: subagent.StatusFailed, CreatedAt: *at(30), FinishedAt: at(40)},
+	}
+	for _, run := range runs {
+		run.SessionID = "cli:default"
+		run.MaxAttempts = 1
+		if err := store.PutSubagentRun(ctx, run); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	list := func(args ...string) string {
+		t.Helper()
+		var out bytes.Buffer
+		cmd := subagentsCmd(configPath, log.New(io.Discard, "", 0))
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		cmd.SetOut(&out)
+		cmd.SetErr(io.Discard)
+		cmd.SetArgs(append([]string{"list"}, args...))
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("list %v failed: %v", args, err)
+		}
+		return out.String()
+	}
+
+	output := list("--since", "2026-03-01T11:00:00Z", "--until", "2026-03-01T12:00:00Z")
+	if !strings.Contains(output, "run-recent") || !strings.Contains(output, "run-open") {
+		t.Fatalf("expected runs active in the window, got:\n%s", output)
+	}
+	if strings.Contains(output, "run-old") || strings.Contains(output, "run-late") {
+		t.Fatalf("expected runs outside the window to be dropped, got:\n%s", output)
+	}
+	if !strings.Contains(output, "2026-03-01T10:30:00Z\t2026-03-01T11:30:00Z") {
+		t.Fatalf("expected created and finished timestamps, got:\n%s", output)
+	}
+
+	output = list("--status", "failed", "--since", "2026-03-01T11:00:00Z", "--limit", "1")
+	if strings.TrimSpace(output) == "" || strings.Count(strings.TrimSpace(output), "\n") != 0 || !strings.Contains(output, "run-late") {
+		t.Fatalf("expected only the newest failed run in the window, got:\n%s", output)
+	}
+
+	cmd := subagentsCmd(configPath, log.New(io.Discard, "", 0))
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"list", "--since", "yesterday"})
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "--since") {
+		t.Fatalf("expected invalid --since error, got %v", err)
+	}
+}
+
+func TestSubagentsRetryRerunsFailedRun(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg := baseTestConfig(t)
+	cfg.Providers.Active = config.ProviderMock
+	configPath := writeTestConfig(t, cfg)
+	store, err := storepkg.Open(cfg.Storage.DBPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	now := time.Now().UTC()
+	for _, run := range []subagent.Run{
+		{ID: "run-failed", SessionID: "cli:default", Channel: "cli", ChatID: "direct", Task: "summarise notes", Status: subagent.StatusFailed, CreatedAt: now, FinishedAt: &now, TimeoutSec: 30, MaxAttempts: 1, Attempt: 1, Context: subagent.ContextPacket{Mode: subagent.ContextModeMinimal}},
+		{ID: "run-busy", SessionID: "cli:default", Channel: "cli", ChatID: "direct", Task: "still going", Status: subagent.StatusRunning, CreatedAt: now, StartedAt: &now, TimeoutSec: 30, MaxAttempts: 1},
+	} {
+		if err := store.PutSubagentRun(ctx, run); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	execute := func(args ...string) (string, error) {
+		cmd := subagentsCmd(configPath, log.New(io.Discard, "", 0))
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetErr(io.Discard)
+		cmd.SetArgs(args)
+		err := cmd.Execute()
+		return out.String(), err
+	}
+	if _, err := execute("retry", "run-busy"); err == nil || !strings.Contains(err.Error(), "only failed or timed out") {
+		t.Fatalf("expected running run to be refused, got %v", err)
+	}
+	if _, err := execute("retry", "run-failed", "--session", "telegram:42"); err == nil || !strings.Contains(err.Error(), "does not belong to session") {
+		t.Fatalf("expected session mismatch to be refused, got %v", err)
+	}
+	text, err := execute("retry", "run-failed", "--session", "cli:default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(text, "as retry of run-failed") || !strings.Contains(text, string(subagent.StatusSucceeded)) {
+		t.Fatalf("unexpected output:\n%s", text)
+	}
+
+	store, err = storepkg.Open(cfg.Storage.DBPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	runs, err := store.ListSubagentRunsBySession(ctx, "cli:default", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var retried *subagent.Run
+	for idx := range runs {
+		if runs[idx].RetryOf == "run-failed" {
+			retried = &runs[idx]
+		}
+	}
+	if retried == nil {
+		t.Fatalf("expected a run linked to run-failed, got %+v", runs)
+	}
+	if retried.Task != "summarise notes" || retried.Status != subagent.StatusSucceeded {
+		t.Fatalf("unexpected retried run: %+v", retried)
+	}
+}
+
+func TestSubagentsTranscriptRendersToolCalls(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg := baseTestConfig(t)
+	configPath := writeTestConfig(t, cfg)
+	artifactDir := filepath.Join(t.TempDir(), "run-1")
+	if err := os.MkdirAll(artifactDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	lines := `{"role":"user","content":"inspect the workspace"}
+{"role":"assistant","tool_calls":[{"id":"ls-1","name":"list_dir","arguments":{"path":"."}}]}
+{"role":"tool","content":"notes.md","name":"list_dir","tool_call_id":"ls-1"}
+`
+	if err := os.WriteFile(filepath.Join(artifactDir, subagent.TranscriptFile), []byte(lines), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	store, err := storepkg.Open(cfg.Storage.DBPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC()
+	if err := store.PutSubagentRun(context.Background(), subagent.Run{ID: "run-1", Task: "inspect the workspace", Status: subagent.StatusFailed, CreatedAt: now, ArtifactDir: artifactDir}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := subagentsCmd(configPath, log.New(io.Discard, "", 0))
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"transcript", "run-1"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	want := "[user]\ninspect the workspace\n\n[assistant]\n→ list_dir {\"path\":\".\"}\n\n[tool list_dir]\nnotes.md\n\n"
+	if out.String() != want {
+		t.Fatalf("unexpected transcript output:\n%s", out.String())
+	}
+}
+
+func TestSessionsShowReportsFocusedSkill(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg := baseTestConfig(t)
+	configPath := writeTestConfig(t, cfg)
+	store, err := storepkg.Open(cfg.Storage.DBPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := store.SaveSessionMeta(ctx, "telegram:42", map[string]any{"title": "Client intake", "last_channel": "telegram", "last_chat_id": "42"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := agent.SetSessionFocus(ctx, store, "telegram:42", "intake"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := sessionsCmd(configPath)
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"show", "telegram:42"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Title: Client intake", "Last channel: telegram 42", "Tools locked: false", "Focused skill: intake"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("expected %q in output:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestSessionsListReportsLiveActors(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg := baseTestConfig(t)
+	store, err := storepkg.Open(cfg.Storage.DBPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveSessionMeta(context.Background(), "telegram:42", map[string]any{"title": "Client intake", "last_channel": "telegram"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+	run := func(configPath string) (string, string) {
+		cmd := sessionsCmd(configPath)
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		var out, errOut bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetErr(&errOut)
+		cmd.SetArgs([]string{"list"})
+		if err := cmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+		return out.String(), errOut.String()
+	}
+
+	out, errOut := run(writeTestConfig(t, cfg))
+	if !strings.Contains(out, "telegram:42\t") || !strings.Contains(out, "\tidle\ttelegram\tClient intake") || !strings.Contains(errOut, "Live state unavailable") {
+		t.Fatalf("expected stored session marked idle, got %q / %q", out, errOut)
+	}
+
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/manage/sessions" || r.Header.Get("Authorization") != "Bearer manage-token" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"sessions": []agent.SessionStatus{
+			{SessionRecord: agent.SessionRecord{SessionID: "telegram:42", Meta: map[string]any{"last_channel": "telegram"}}, LastActive: time.Now().UTC(), Live: true},
+		}})
+	}))
+	defer gateway.Close()
+	cfg.Runtime.MetricsHTTP.Enabled = true
+	cfg.Runtime.MetricsHTTP.ListenAddr = strings.TrimPrefix(gateway.URL, "http://")
+	cfg.Runtime.MetricsHTTP.AuthToken = "manage-token"
+	out, errOut = run(writeTestConfig(t, cfg))
+	if !strings.Contains(out, "\tlive\ttelegram\t(untitled)") || errOut != "" {
+		t.Fatalf("expected live session from the gateway, got %q / %q", out, errOut)
+	}
+}
+
+func TestHeartbeatRunUsesRunningGateway(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg := baseTestConfig(t)
+	var gotProfile string
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/manage/heartbeat/run" || r.Header.Get("Authorization") != "Bearer manage-token" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		gotProfile = r.URL.Query().Get("profile")
+		_ = json.NewEncoder(w).Encode(map[string]any{"profile": gotProfile, "status": "ok", "response": "HEARTBEAT_OK"})
+	}))
+	defer gateway.Close()
+	cfg.Runtime.MetricsHTTP.Enabled = true
+	cfg.Runtime.MetricsHTTP.ListenAddr = strings.TrimPrefix(gateway.URL, "http://")
+	cfg.Runtime.MetricsHTTP.ManageToken = "manage-token"
+
+	cmd := heartbeatCmd(writeTestConfig(t, cfg), log.New(io.Discard, "", 0))
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"run", "Evening"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if gotProfile != "evening" || strings.TrimSpace(out.String()) != "HEARTBEAT_OK" {
+		t.Fatalf("expected the gateway to run the evening profile, got %q / %q", gotProfile, out.String())
+	}
+	if gatewayNotRunning(errors.New("gateway returned 404 Not Found")) {
+		t.Fatal("expected a gateway error reply not to trigger a local run")
+	}
+}
+
 func TestBudgetCommandsPersistOverride(t *testing.T) {
 	t.Setenv("HOME", t.TempDir())
 	cfg := baseTestConfig(t)
@@ -377,6 +735,113 @@ func TestBudgetCommandsPersistOverride(t *testing.T) {
 	}
 }
 
+func TestBudgetResetCommandZeroesScope(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg := baseTestConfig(t)
+	configPath := writeTestConfig(t, cfg)
+
+	store, err := storepkg.Open(cfg.Storage.DBPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := store.AddBudgetUsage(ctx, "session:telegram:42", 10, 5, 15); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.ReserveBudget(ctx, "session:telegram:42", 20, 60); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	run := func(args ...string) (string, error) {
+		cmd := budgetCmd(configPath)
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetErr(io.Discard)
+		cmd.SetArgs(append([]string{"reset"}, args...))
+		err := cmd.Execute()
+		return out.String(), err
+	}
+	if _, err := run("--scope", "session"); err == nil {
+		t.Fatal("expected session scope without --session to fail")
+	}
+	out, err := run("--scope", "session", "--session", "telegram:42")
+	if err != nil {
+		t.Fatalf("budget reset failed: %v", err)
+	}
+	if !strings.Contains(out, "session:telegram:42 reset (was used=15 reserved=20; cleared 1 reservations)") {
+		t.Fatalf("unexpected output %q", out)
+	}
+
+	store, err = storepkg.Open(cfg.Storage.DBPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	counter, err := store.GetBudgetCounter(ctx, "session:telegram:42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counter.TotalTokens != 0 || counter.ReservedTokens != 0 {
+		t.Fatalf("expected zeroed counter, got %+v", counter)
+	}
+}
+
+func TestStatusJSONMatchesTextOutput(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg := baseTestConfig(t)
+	cfg.Tools.Exec.Enabled = true
+	cfg.Memory.Semantic.RerankTopK = 5
+	configPath := writeTestConfig(t, cfg)
+
+	run := func(args ...string) string {
+		cmd := statusCmd(configPath)
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetErr(io.Discard)
+		cmd.SetArgs(args)
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("status %v failed: %v", args, err)
+		}
+		return out.String()
+	}
+
+	var report map[string]any
+	if err := json.Unmarshal([]byte(run("--json")), &report); err != nil {
+		t.Fatalf("status --json is not JSON: %v", err)
+	}
+	for _, key := range []string{"paths", "model", "active_provider", "provider_ready", "storage_backend", "features", "tool_policy", "runtime"} {
+		if _, ok := report[key]; !ok {
+			t.Fatalf("expected %q in status JSON, got %v", key, report)
+		}
+	}
+	if report["provider_ready"] != false || report["provider_error"] == "" {
+		t.Fatalf("expected provider not ready with a reason, got %v / %v", report["provider_ready"], report["provider_error"])
+	}
+	if report["tool_policy"].(map[string]any)["exec_enabled"] != true {
+		t.Fatalf("expected exec_enabled in tool policy, got %v", report["tool_policy"])
+	}
+	semantic := report["runtime"].(map[string]any)["semantic_memory"].(map[string]any)
+	if semantic["rerank_top_k"] != float64(5) {
+		t.Fatalf("expected rerank_top_k 5, got %v", semantic["rerank_top_k"])
+	}
+
+	text := run()
+	for _, want := range []string{
+		"Provider ready: false (" + report["provider_error"].(string) + ")",
+		"Tool policy: execEnabled=true",
+		"rerankTopK=5",
+	} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %q in status text, got:\n%s", want, text)
+		}
+	}
+}
+
 func TestOnboardCommandDoesNotExposeWebMode(t *testing.T) {
 	cmd := onboardCmd("")
 	if cmd.Flags().Lookup("mode") != nil {
@@ -434,4 +899,880 @@ func TestSkillsCommandsListAndCheck(t *testing.T) {
 	if !strings.Contains(showOut.String(), "\"activation\"") || !strings.Contains(showOut.String(), "\"breakdown\"") {
 		t.Fatalf("expected activation breakdown in show output, got: %s", showOut.String())
 	}
+
+	disableCmd := skillsCmd(configPath)
+	disableCmd.SilenceUsage = true
+	disableCmd.SilenceErrors = true
+	disableCmd.SetOut(io.Discard)
+	disableCmd.SetErr(io.Discard)
+	disableCmd.SetArgs([]string{"disable", "planner", "--channel", "telegram"})
+	if err := disableCmd.Execute(); err != nil {
+		t.Fatalf("skills disable failed: %v", err)
+	}
+	listCmd := skillsCmd(configPath)
+	listCmd.SilenceUsage = true
+	listCmd.SilenceErrors = true
+	var listOut bytes.Buffer
+	listCmd.SetOut(&listOut)
+	listCmd.SetErr(io.Discard)
+	listCmd.SetArgs([]string{"list", "--channel", "telegram"})
+	if err := listCmd.Execute(); err != nil {
+		t.Fatalf("skills list failed: %v", err)
+	}
+	if !strings.Contains(listOut.String(), "disabled_by_override\tsource=override") {
+		t.Fatalf("expected stored override in list output, got: %s", listOut.String())
+	}
+}
+
+func TestSkillsRankCommandListsEverySkill(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg := baseTestConfig(t)
+	workspace := cfg.Agents.Defaults.Workspace
+	cfg.Skills.Paths = []string{filepath.Join(workspace, "skills")}
+	for name, body := range map[string]string{
+		"planner": "# Planner\nCreates practical execution plans.",
+		"writer":  "# Writer\nDrafts release notes.",
+	} {
+		if err := os.MkdirAll(filepath.Join(workspace, "skills", name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(workspace, "skills", name, "SKILL.md"), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	configPath := writeTestConfig(t, cfg)
+
+	cmd := skillsCmd(configPath)
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"rank", "--query", "ask the planner for execution plans", "--json"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("skills rank failed: %v", err)
+	}
+	var payload struct {
+		Ranked []skillRankRow `json:"ranked"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &payload); err != nil {
+		t.Fatalf("invalid json %q: %v", out.String(), err)
+	}
+	if len(payload.Ranked) != 2 {
+		t.Fatalf("expected both skills ranked, got %+v", payload.Ranked)
+	}
+	top, rest := payload.Ranked[0], payload.Ranked[1]
+	if top.ID != "planner" || top.Status != "activated" || top.Score == 0 || top.Breakdown.NameBonus == 0 {
+		t.Fatalf("expected planner activated with a name bonus, got %+v", top)
+	}
+	if rest.ID != "writer" || rest.Status != "unmatched" || rest.Score != 0 {
+		t.Fatalf("expected writer unmatched, got %+v", rest)
+	}
+
+	table := skillsCmd(configPath)
+	table.SilenceUsage = true
+	table.SilenceErrors = true
+	out.Reset()
+	table.SetOut(&out)
+	table.SetErr(io.Discard)
+	table.SetArgs([]string{"rank", "--query", "ask the planner for execution plans"})
+	if err := table.Execute(); err != nil {
+		t.Fatalf("skills rank failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "name=120") || strings.Index(out.String(), "planner") > strings.Index(out.String(), "writer") {
+		t.Fatalf("unexpected rank table:\n%s", out.String())
+	}
+}
+
+func TestSkillsPolicyExportImportRoundTrip(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	newInstance := func() string {
+		cfg := baseTestConfig(t)
+		workspace := cfg.Agents.Defaults.Workspace
+		cfg.Skills.Paths = []string{filepath.Join(workspace, "skills")}
+		if err := os.MkdirAll(filepath.Join(workspace, "skills", "planner"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(workspace, "skills", "planner", "SKILL.md"), []byte("# Planner\nCreates plans"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return writeTestConfig(t, cfg)
+	}
+	run := func(configPath string, stdout, stderr io.Writer, args ...string) error {
+		cmd := skillsCmd(configPath)
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		cmd.SetOut(stdout)
+		cmd.SetErr(stderr)
+		cmd.SetArgs(args)
+		return cmd.Execute()
+	}
+
+	source := newInstance()
+	cfg, err := config.Load(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Skills.Policy.Deny = []string{"ghost"}
+	cfg.Skills.Policy.Channels = map[string]config.SkillsChannelPolicyConfig{"slack": {Allow: []string{"planner"}}}
+	if err := config.Save(source, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := run(source, io.Discard, io.Discard, "disable", "planner", "--channel", "telegram"); err != nil {
+		t.Fatal(err)
+	}
+	exportPath := filepath.Join(t.TempDir(), "policy.json")
+	if err := run(source, io.Discard, io.Discard, "policy", "export", "--out", exportPath); err != nil {
+		t.Fatalf("policy export failed: %v", err)
+	}
+
+	target := newInstance()
+	t.Setenv("SQUIDBOT_OPENAI_API_KEY", "sk-env-only")
+	var stderr bytes.Buffer
+	if err := run(target, io.Discard, &stderr, "policy", "import", exportPath); err != nil {
+		t.Fatalf("policy import failed: %v", err)
+	}
+	if data, err := os.ReadFile(target); err != nil || strings.Contains(string(data), "sk-env-only") {
+		t.Fatalf("expected the env secret to stay out of the imported config (err=%v)", err)
+	}
+	if !strings.Contains(stderr.String(), "policy.deny: ghost matches no discovered skill") {
+		t.Fatalf("expected unknown skill warning, got %q", stderr.String())
+	}
+	imported, err := config.Load(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imported.Skills.Policy.Deny) != 1 || imported.Skills.Policy.Deny[0] != "ghost" || len(imported.Skills.Policy.Channels["slack"].Allow) != 1 {
+		t.Fatalf("expected policy copied into target config, got %+v", imported.Skills.Policy)
+	}
+	var listOut bytes.Buffer
+	if err := run(target, &listOut, io.Discard, "list", "--channel", "telegram"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(listOut.String(), "disabled_by_override\tsource=override") {
+		t.Fatalf("expected imported override in list output, got: %s", listOut.String())
+	}
+}
+
+func TestAuthSetPasswordRequiresCurrentUnlessForced(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	configPath := writeTestConfig(t, baseTestConfig(t))
+
+	run := func(args []string, stdin string) error {
+		cmd := authCmd(configPath)
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		cmd.SetOut(io.Discard)
+		cmd.SetErr(io.Discard)
+		cmd.SetIn(strings.NewReader(stdin))
+		cmd.SetArgs(args)
+		return cmd.Execute()
+	}
+
+	if err := run([]string{"set-password"}, "first-password\n"); err != nil {
+		t.Fatalf("initial set-password failed: %v", err)
+	}
+	if err := run([]string{"set-password", "--password", "second-password", "--current", "wrong"}, ""); err == nil {
+		t.Fatal("expected wrong current password to be rejected")
+	}
+	if err := run([]string{"set-password", "--password", "second-password", "--current", "first-password"}, ""); err != nil {
+		t.Fatalf("rotation with current password failed: %v", err)
+	}
+	if err := run([]string{"set-password", "--password", "third-password", "--force"}, ""); err != nil {
+		t.Fatalf("forced rotation failed: %v", err)
+	}
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !config.VerifyPassword(cfg.Auth.PasswordHash, "third-password") {
+		t.Fatal("expected persisted hash to match the forced password")
+	}
+}
+
+func TestAuthSetPasswordKeepsEnvSecretsOutOfConfig(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	configPath := writeTestConfig(t, baseTestConfig(t))
+	t.Setenv("SQUIDBOT_OPENAI_API_KEY", "sk-env-only")
+	t.Setenv("SQUIDBOT_TELEGRAM_TOKEN", "123:env-only")
+
+	cmd := authCmd(configPath)
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"set-password", "--password", "first-password"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("set-password failed: %v", err)
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "sk-env-only") || strings.Contains(string(data), "123:env-only") {
+		t.Fatalf("expected env secrets to stay out of the saved config, got %s", data)
+	}
+	cfg, err := config.LoadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !config.VerifyPassword(cfg.Auth.PasswordHash, "first-password") {
+		t.Fatal("expected the password hash to be saved")
+	}
+}
+
+func TestToolsListJSONReflectsConfig(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg := baseTestConfig(t)
+	cfg.Tools.Filesystem.ParentWriteEnabled = false
+	configPath := writeTestConfig(t, cfg)
+
+	cmd := toolsCmd(configPath)
+	cmd.SilenceUsage = true
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"list", "--json"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	var payload []struct {
+		Name        string         `json:"name"`
+		Description string         `json:"description"`
+		Parameters  map[string]any `json:"parameters"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &payload); err != nil {
+		t.Fatalf("invalid json output: %v\n%s", err, out.String())
+	}
+	names := map[string]bool{}
+	for _, item := range payload {
+		names[item.Name] = true
+		if item.Parameters == nil {
+			t.Fatalf("tool %q missing parameter schema", item.Name)
+		}
+	}
+	if !names["read_file"] || !names["web_fetch"] {
+		t.Fatalf("expected core tools, got %v", names)
+	}
+	if names["write_file"] {
+		t.Fatal("write_file should be hidden when parent writes are disabled")
+	}
+}
+
+func TestToolsLogFiltersBySessionAndTool(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg := baseTestConfig(t)
+	configPath := writeTestConfig(t, cfg)
+
+	store, err := storepkg.Open(cfg.Storage.DBPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for _, event := range []agent.ToolEvent{
+		{SessionID: "cli:a", ToolName: "exec", Input: `{"command":"ls"}`, Output: "file1\nfile2"},
+		{SessionID: "cli:a", ToolName: "write_file", Input: `{"path":"notes.md"}`, Output: strings.Repeat("x", 200)},
+		{SessionID: "cli:b", ToolName: "exec", Input: `{"command":"pwd"}`, Output: "/tmp"},
+	} {
+		if err := store.AppendToolEvent(ctx, event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.Close()
+
+	run := func(args ...string) string {
+		cmd := toolsCmd(configPath)
+		cmd.SilenceUsage = true
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetArgs(append([]string{"log"}, args...))
+		if err := cmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+		return out.String()
+	}
+
+	text := run("--session", "cli:a")
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "write_file") || !strings.Contains(lines[1], "file1 file2") {
+		t.Fatalf("unexpected session log:\n%s", text)
+	}
+	if !strings.HasSuffix(lines[0], "...") || len(lines[0]) > 160 {
+		t.Fatalf("expected truncated output preview, got %q", lines[0])
+	}
+
+	var events []agent.ToolEvent
+	if err := json.Unmarshal([]byte(run("--tool", "exec", "--limit", "1", "--json")), &events); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].SessionID != "cli:b" || events[0].Input != `{"command":"pwd"}` {
+		t.Fatalf("unexpected json log: %+v", events)
+	}
+}
+
+func TestTasksPolicySetValidatesAndPersists(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg := baseTestConfig(t)
+	configPath := writeTestConfig(t, cfg)
+
+	run := func(args ...string) error {
+		cmd := tasksCmd(configPath)
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		cmd.SetOut(io.Discard)
+		cmd.SetErr(io.Discard)
+		cmd.SetArgs(args)
+		return cmd.Execute()
+	}
+
+	if err := run("policy", "set", "--default-column", "nowhere"); err == nil {
+		t.Fatal("expected unknown column to be rejected")
+	}
+	if err := run("policy", "set", "--enable-source", "chat,email"); err == nil {
+		t.Fatal("expected unknown source to be rejected")
+	}
+	if err := run("policy", "set", "--default-column", "in_progress", "--dedupe-window", "2h", "--enable-source", "chat,cron"); err != nil {
+		t.Fatal(err)
+	}
+	if err := run("policy", "show"); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := storepkg.Open(cfg.Storage.DBPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	policy, err := store.GetTaskAutomationPolicy(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy.DefaultColumnID != "in_progress" || policy.DedupeWindowSec != 7200 {
+		t.Fatalf("unexpected policy: %+v", policy)
+	}
+	if !policy.EnableChat || !policy.EnableCron || policy.EnableHeartbeat || policy.EnableSubagent {
+		t.Fatalf("unexpected source toggles: %+v", policy)
+	}
+}
+
+func TestProfileFlagResolvesConfigPath(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("SQUIDBOT_HOME", "")
+	t.Setenv("SQUIDBOT_CONFIG", "")
+	configPath := writeTestConfig(t, baseTestConfig(t))
+
+	run := func(args ...string) error {
+		root := newRootCmd(log.New(io.Discard, "", 0))
+		root.SilenceUsage = true
+		root.SilenceErrors = true
+		root.SetOut(io.Discard)
+		root.SetErr(io.Discard)
+		root.SetArgs(args)
+		return root.Execute()
+	}
+
+	if err := run("profile", "add", "work", configPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := run("--profile", "personal", "profile", "list"); err == nil {
+		t.Fatal("expected unknown profile to fail")
+	}
+	if err := run("--profile", "work", "profile", "list"); err != nil {
+		t.Fatal(err)
+	}
+	if got := config.ConfigPath(); got != configPath {
+		t.Fatalf("expected profile to select %s, got %s", configPath, got)
+	}
+	if err := run("profile", "remove", "work"); err != nil {
+		t.Fatal(err)
+	}
+	profiles, err := config.LoadProfiles("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(profiles.Profiles) != 0 {
+		t.Fatalf("expected profile to be removed, got %v", profiles.Profiles)
+	}
+}
+
+func TestGatewayConfigCheckValidatesWithoutServing(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg := baseTestConfig(t)
+	cfg.Providers.Active = "openai"
+	cfg.Providers.OpenAI.APIKey = "sk-test"
+	cfg.Providers.OpenAI.Model = "gpt-4o-mini"
+	cfg.Runtime.AgentAPI.Enabled = true
+	cfg.Runtime.AgentAPI.ListenAddr = "not-an-addr"
+
+	run := func(cfg config.Config) (string, error) {
+		cmd := gatewayCmd(writeTestConfig(t, cfg), log.New(io.Discard, "", 0))
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetErr(io.Discard)
+		cmd.SetArgs([]string{"--config-check"})
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	_, err := run(cfg)
+	if err == nil {
+		t.Fatal("expected config check to fail")
+	}
+	for _, want := range []string{"runtime.agentApi listenAddr", "authToken missing"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %q in error, got %v", want, err)
+		}
+	}
+
+	cfg.Runtime.AgentAPI.ListenAddr = "127.0.0.1:0"
+	cfg.Runtime.AgentAPI.AuthToken = "secret"
+	out, err := run(cfg)
+	if err != nil {
+		t.Fatalf("expected config check to pass, got %v", err)
+	}
+	if !strings.Contains(out, "config check passed") {
+		t.Fatalf("unexpected output: %q", out)
+	}
+}
+
+func TestVersionCommandJSON(t *testing.T) {
+	restore := buildinfo.Version
+	buildinfo.Version = "v9.9.9-test"
+	defer func() { buildinfo.Version = restore }()
+
+	var out bytes.Buffer
+	root := newRootCmd(log.New(io.Discard, "", 0))
+	root.SetOut(&out)
+	root.SetErr(io.Discard)
+	root.SetArgs([]string{"version", "--json"})
+	if err := root.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	var payload struct {
+		Version   string   `json:"version"`
+		GoVersion string   `json:"go_version"`
+		Providers []string `json:"providers"`
+		Tools     []string `json:"tools"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &payload); err != nil {
+		t.Fatalf("expected JSON output, got %q: %v", out.String(), err)
+	}
+	if payload.Version != "v9.9.9-test" || payload.GoVersion == "" {
+		t.Fatalf("unexpected version payload: %+v", payload)
+	}
+	if len(payload.Providers) == 0 || len(payload.Tools) == 0 {
+		t.Fatalf("expected capability lists, got %+v", payload)
+	}
+}
+
+func TestReplHistoryPersistsAndEditorRecallsEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent_history")
+	history := loadReplHistory(path)
+	history.Add("first")
+	history.Add("second")
+	history.Add("second")
+	history.Add("  ")
+
+	reloaded := loadReplHistory(path)
+	if !reflect.DeepEqual(reloaded.entries, []string{"first", "second"}) {
+		t.Fatalf("unexpected persisted history: %#v", reloaded.entries)
+	}
+
+	// Up, up, down recalls "second"; then type "!" and submit.
+	editor := func(input string, history *replHistory) *terminalLineReader {
+		return newTerminalLineReader(struct {
+			io.Reader
+			io.Writer
+		}{strings.NewReader(input), io.Discard}, history)
+	}
+	line, err := editor("\x1b[A\x1b[A\x1b[B!\r", reloaded).ReadLine(replPrompt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line != "second!" {
+		t.Fatalf("expected recalled and edited line, got %q", line)
+	}
+	if reloaded.Len() != 3 || reloaded.At(0) != "second!" {
+		t.Fatalf("expected the submitted line in history, got %#v", reloaded.entries)
+	}
+
+	if line, err := editor("ab\x7fc\x1b[Dx\r", nil).ReadLine(replPrompt); err != nil || line != "axc" {
+		t.Fatalf("expected backspace and cursor edits to produce axc, got %q (%v)", line, err)
+	}
+	for _, key := range []string{"\x04", "\x03"} {
+		if _, err := editor(key, nil).ReadLine(replPrompt); !errors.Is(err, io.EOF) {
+			t.Fatalf("expected %q on an empty line to return EOF, got %v", key, err)
+		}
+	}
+
+	for input, want := range map[string]replAction{"/exit": replExit, " /QUIT ": replExit, "/help": replHelp, "/history": replShowHistory, "/lock-tools": replSend, "hello": replSend} {
+		if got := parseReplCommand(input); got != want {
+			t.Fatalf("parseReplCommand(%q) = %v, want %v", input, got, want)
+		}
+	}
+}
+
+func TestParseAgentMessagesFormats(t *testing.T) {
+	cases := map[string][]string{
+		"first\n\nsecond\n":                                  {"first", "second"},
+		`["one", "two\nlines", " "]`:                         {"one", "two\nlines"},
+		"{\"content\":\"json line\"}\n\"quoted\\nprompt\"\n": {"json line", "quoted\nprompt"},
+	}
+	for input, want := range cases {
+		got, err := parseAgentMessages([]byte(input))
+		if err != nil {
+			t.Fatalf("parse %q: %v", input, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("parse %q: expected %q, got %q", input, want, got)
+		}
+	}
+	if _, err := parseAgentMessages([]byte("{not json}\n")); err == nil {
+		t.Fatal("expected malformed JSON line to fail")
+	}
+}
+
+func TestAgentCommandRunsMessagesFileOnOneSession(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg := baseTestConfig(t)
+	cfg.Providers.Active = config.ProviderMock
+	configPath := writeTestConfig(t, cfg)
+	messagesPath := filepath.Join(t.TempDir(), "prompts.txt")
+	if err := os.WriteFile(messagesPath, []byte("first prompt\nsecond prompt\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := agentCmd(configPath, log.New(io.Discard, "", 0))
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"--messages-file", messagesPath, "--session", "cli:batch"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	text := out.String()
+	if !strings.Contains(text, "--- [1/2] ---\necho: first prompt") || !strings.Contains(text, "--- [2/2] ---\necho: second prompt") {
+		t.Fatalf("unexpected output:\n%s", text)
+	}
+
+	store, err := storepkg.Open(cfg.Storage.DBPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	turns, err := store.SessionTurns(context.Background(), "cli:batch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(turns) != 4 {
+		t.Fatalf("expected both exchanges on one session, got %d turns", len(turns))
+	}
+}
+
+func TestProvidersThroughputCommandRanksByAverage(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg := baseTestConfig(t)
+	configPath := writeTestConfig(t, cfg)
+	store, err := storepkg.Open(cfg.Storage.DBPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := time.Now().UTC().Format("2006-01-02")
+	if err := store.RecordProviderThroughput(context.Background(), day, "slow/model", 50, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RecordProviderThroughput(context.Background(), day, "fast/model", 400, 2*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	cmd := providersCmd(configPath)
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"throughput", "--json"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	var rows []providerThroughputRow
+	if err := json.Unmarshal(out.Bytes(), &rows); err != nil {
+		t.Fatalf("invalid json %q: %v", out.String(), err)
+	}
+	if len(rows) != 2 || rows[0].Label != "fast/model" || rows[0].AvgTokensPerSec != 200 || rows[1].AvgLatencyMS != 1000 {
+		t.Fatalf("unexpected throughput rows: %+v", rows)
+	}
+}
+
+func TestRefreshCommandReportsCombinedSummary(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg := baseTestConfig(t)
+	cfg.Memory.IndexPath = filepath.Join(t.TempDir(), "memory_index.db")
+	configPath := writeTestConfig(t, cfg)
+	memoryDir := filepath.Join(cfg.Agents.Defaults.Workspace, "memory")
+	if err := os.MkdirAll(memoryDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(memoryDir, "MEMORY.md"), []byte("The squid prefers short answers."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := refreshCmd(configPath)
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"--json"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	var summary refreshSummary
+	if err := json.Unmarshal(out.Bytes(), &summary); err != nil {
+		t.Fatalf("invalid json %q: %v", out.String(), err)
+	}
+	if !summary.MemoryEnabled || summary.MemoryChunks == 0 || summary.PluginsEnabled || len(summary.Errors) != 0 {
+		t.Fatalf("unexpected refresh summary: %+v", summary)
+	}
+}
+
+func TestToolStatusLineRendersPreview(t *testing.T) {
+	var out bytes.Buffer
+	if line := toolStatusLine(&out, agent.StreamEvent{Type: "tool_call_started", ToolName: "exec"}); line != "→ exec" {
+		t.Fatalf("unexpected start line %q", line)
+	}
+	if line := toolStatusLine(&out, agent.StreamEvent{Type: "tool_call_end", ToolName: "read_file", Content: "first\nsecond"}); line != "✓ read_file: first …" {
+		t.Fatalf("unexpected end line %q", line)
+	}
+	if line := toolStatusLine(&out, agent.StreamEvent{Type: "tool_call_end", ToolName: "exec", Error: "boom"}); line != "✗ exec: boom" {
+		t.Fatalf("unexpected error line %q", line)
+	}
+}
+
+func TestBudgetReportCommandOutputsCSV(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg := baseTestConfig(t)
+	configPath := writeTestConfig(t, cfg)
+	store, err := storepkg.Open(cfg.Storage.DBPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RecordUsageDay(context.Background(), "2026-04-02", "mock/mock", 30, 10, 40, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	cmd := budgetCmd(configPath)
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"report", "--from", "2026-04-01T00:00:00Z", "--to", "2026-04-03T00:00:00Z", "--csv"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	want := "day,prompt_tokens,completion_tokens,total_tokens\n" +
+		"2026-04-01,0,0,0\n" +
+		"2026-04-02,30,10,40\n" +
+		"2026-04-03,0,0,0\n" +
+		"total,30,10,40\n"
+	if out.String() != want {
+		t.Fatalf("unexpected csv:\n%s", out.String())
+	}
+}
+
+func TestMemoryExportImportRestoresSearchableIndex(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	run := func(configPath string, args ...string) (string, error) {
+		t.Helper()
+		cmd := memoryCmd(configPath)
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetErr(io.Discard)
+		cmd.SetArgs(args)
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	source := baseTestConfig(t)
+	source.Memory.IndexPath = filepath.Join(t.TempDir(), "memory_index.db")
+	dailyDir := filepath.Join(config.WorkspacePath(source), "memory", "daily")
+	if err := os.MkdirAll(dailyDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dailyDir, "2026-03-01.md"), []byte("# 2026-03-01\n- discussed the kraken migration plan\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sourcePath := writeTestConfig(t, source)
+	if _, err := run(sourcePath, "rebuild"); err != nil {
+		t.Fatalf("rebuild failed: %v", err)
+	}
+	archive := filepath.Join(t.TempDir(), "memory.tar.gz")
+	out, err := run(sourcePath, "export", "-o", archive)
+	if err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	if !strings.Contains(out, "1 files and 1 chunks") {
+		t.Fatalf("unexpected export output: %q", out)
+	}
+
+	target := baseTestConfig(t)
+	target.Memory.IndexPath = filepath.Join(t.TempDir(), "memory_index.db")
+	if err := config.EnsureFilesystem(target); err != nil {
+		t.Fatal(err)
+	}
+	targetPath := writeTestConfig(t, target)
+	if _, err := run(targetPath, "import", archive); err != nil {
+		t.Fatalf("import into fresh workspace failed: %v", err)
+	}
+	out, err = run(targetPath, "search", "kraken")
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if !strings.Contains(out, "kraken migration plan") || !strings.Contains(out, filepath.Join(config.WorkspacePath(target), "memory", "daily", "2026-03-01.md")) {
+		t.Fatalf("expected restored chunk at the new workspace path, got %q", out)
+	}
+
+	if _, err := run(targetPath, "import", archive); err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Fatalf("expected import into non-empty workspace to require --force, got %v", err)
+	}
+	if _, err := run(targetPath, "import", "--force", archive); err != nil {
+		t.Fatalf("forced import failed: %v", err)
+	}
+}
+
+func TestOnboardFromEnvWritesConfigAndReportsSources(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("SQUIDBOT_PROVIDER_ACTIVE", "anthropic")
+	t.Setenv("SQUIDBOT_ANTHROPIC_API_KEY", "sk-env")
+	configPath := filepath.Join(t.TempDir(), "config.json")
+
+	run := func(args ...string) (string, error) {
+		onboard := onboardCmd(configPath)
+		onboard.SilenceUsage = true
+		onboard.SilenceErrors = true
+		var out bytes.Buffer
+		onboard.SetOut(&out)
+		onboard.SetErr(io.Discard)
+		onboard.SetArgs(args)
+		err := onboard.Execute()
+		return out.String(), err
+	}
+
+	for _, wantRest := range []string{"All other settings use defaults.", "All other settings kept from"} {
+		out, err := run("--from-env")
+		if err != nil {
+			t.Fatalf("onboard --from-env: %v", err)
+		}
+		if !strings.Contains(out, "  providers.active\n") || !strings.Contains(out, "  providers.anthropic.apiKey\n") || !strings.Contains(out, wantRest) {
+			t.Fatalf("expected env sources in output, got %q", out)
+		}
+		if strings.Contains(out, "sk-env") {
+			t.Fatalf("expected secrets to stay out of the output, got %q", out)
+		}
+	}
+	t.Setenv("SQUIDBOT_ANTHROPIC_API_KEY", "")
+	loaded, err := config.Load(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Providers.Active != config.ProviderAnthropic || loaded.Providers.Anthropic.APIKey != "sk-env" {
+		t.Fatalf("expected env values persisted, got %+v", loaded.Providers.Anthropic)
+	}
+	if _, err := os.Stat(config.WorkspacePath(loaded)); err != nil {
+		t.Fatalf("expected workspace to exist: %v", err)
+	}
+
+	if _, err := run("--from-env", "--provider", "gemini"); err == nil || !strings.Contains(err.Error(), "--provider") {
+		t.Fatalf("expected flag conflict error, got %v", err)
+	}
+	t.Setenv("SQUIDBOT_PROVIDER_ACTIVE", "openai")
+	if _, err := run("--from-env"); err == nil || !strings.Contains(err.Error(), "provider setup incomplete") {
+		t.Fatalf("expected provider validation error, got %v", err)
+	}
+}
+
+func TestProviderListAndTestCommands(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"choices":[{"message":{"role":"assistant","content":"OK"},"finish_reason":"stop"}],"usage":{"prompt_tokens":3,"completion_tokens":1,"total_tokens":4}}`)
+	}))
+	defer server.Close()
+
+	cfg := baseTestConfig(t)
+	cfg.Providers.Active = config.ProviderOpenAI
+	cfg.SetProviderByName(config.ProviderOpenAI, config.ProviderConfig{APIKey: "sk-test", Model: "gpt-test"})
+	cfg.SetProviderByName(config.ProviderOllama, config.ProviderConfig{APIBase: server.URL, Model: "llama-test"})
+	cfg.SetProviderByName(config.ProviderLMStudio, config.ProviderConfig{APIBase: server.URL})
+	configPath := writeTestConfig(t, cfg)
+
+	run := func(args ...string) (string, error) {
+		cmd := providersCmd(configPath)
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetErr(io.Discard)
+		cmd.SetArgs(args)
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	out, err := run("list", "--json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rows []configuredProviderRow
+	if err := json.Unmarshal([]byte(out), &rows); err != nil {
+		t.Fatalf("invalid json %q: %v", out, err)
+	}
+	byName := map[string]configuredProviderRow{}
+	for _, row := range rows {
+		byName[row.Name] = row
+	}
+	if row := byName[config.ProviderOpenAI]; !row.Active || !row.KeySet || row.Model != "gpt-test" || row.APIBase != "https://api.openai.com/v1" {
+		t.Fatalf("unexpected openai row: %+v", row)
+	}
+	if row := byName[config.ProviderOllama]; row.Active || row.KeySet || row.KeyRequired || row.APIBase != server.URL {
+		t.Fatalf("unexpected ollama row: %+v", row)
+	}
+
+	out, err = run("test", "ollama")
+	if err != nil {
+		t.Fatalf("test ollama: %v (%s)", err, out)
+	}
+	if !strings.Contains(out, "ollama (llama-test): OK") || !strings.Contains(out, `"OK"`) {
+		t.Fatalf("unexpected test output %q", out)
+	}
+	lmstudio := byName[config.ProviderLMStudio]
+	out, err = run("test", "lmstudio")
+	if err != nil {
+		t.Fatalf("test lmstudio: %v (%s)", err, out)
+	}
+	if lmstudio.Model == "" || !strings.Contains(out, "lmstudio ("+lmstudio.Model+"): OK") {
+		t.Fatalf("expected the listed default model %q to be tested, got %q", lmstudio.Model, out)
+	}
+
+	server.Close()
+	out, err = run("test", "ollama", "--timeout", "2s")
+	if err == nil || !strings.Contains(out, "FAIL") {
+		t.Fatalf("expected failure against a closed server, got %q (%v)", out, err)
+	}
+	if _, err := run("test", "nope"); err == nil {
+		t.Fatal("expected unknown provider to fail")
+	}
 }
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/term"
+
+	"github.com/grixate/squidbot/internal/config"
+)
+
+const (
+	replPrompt             = "You: "
+	replHistoryMaxEntries  = 500
+	replHistoryShowEntries = 20
+)
+
+type replAction int
+
+const (
+	replSend replAction = iota
+	replExit
+	replHelp
+	replShowHistory
+)
+
+// parseReplCommand recognises the control commands handled by the
+// interactive CLI itself. Everything else, including engine commands such as
+// /lock-tools, is sent to the agent.
+func parseReplCommand(line string) replAction {
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "/exit", "/quit":
+		return replExit
+	case "/help":
+		return replHelp
+	case "/history":
+		return replShowHistory
+	}
+	return replSend
+}
+
+const replHelpText = `Commands:
+  /exit, /quit    leave interactive mode
+  /help           show this help
+  /history        show recent input history
+  /lock-tools     restrict this session to read-only tools
+  /unlock-tools   restore the full tool set
+  /retitle        regenerate this session's title
+Use the up/down arrows to recall previous input; Ctrl+D exits.`
+
+func defaultReplHistoryPath() string {
+	return filepath.Join(config.DataRoot(), "agent_history")
+}
+
+// replHistory keeps interactive input in memory and, when path is set,
+// appends each entry to a history file shared across sessions.
+type replHistory struct {
+	path    string
+	entries []string
+}
+
+func loadReplHistory(path string) *replHistory {
+	history := &replHistory{path: strings.TrimSpace(path)}
+	if history.path == "" {
+		return history
+	}
+	raw, err := os.ReadFile(history.path)
+	if err != nil {
+		return history
+	}
+	for _, line := range strings.Split(string(raw), "\n") {
+		if strings.TrimSpace(line) != "" {
+			history.entries = append(history.entries, line)
+		}
+	}
+	if len(history.entries) > replHistoryMaxEntries {
+		history.entries = history.entries[len(history.entries)-replHistoryMaxEntries:]
+		_ = history.rewrite()
+	}
+	return history
+}
+
+func (h *replHistory) Add(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" || (len(h.entries) > 0 && h.entries[len(h.entries)-1] == line) {
+		return
+	}
+	h.entries = append(h.entries, line)
+	if len(h.entries) > replHistoryMaxEntries {
+		h.entries = h.entries[len(h.entries)-replHistoryMaxEntries:]
+	}
+	if h.path == "" {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0o755); err != nil {
+		return
+	}
+	file, err := os.OpenFile(h.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	_, _ = fmt.Fprintln(file, line)
+}
+
+// Len and At let the terminal line editor recall entries, most recent first.
+func (h *replHistory) Len() int { return len(h.entries) }
+
+func (h *replHistory) At(idx int) string { return h.entries[len(h.entries)-1-idx] }
+
+func (h *replHistory) Recent(n int) []string {
+	if n <= 0 || n > len(h.entries) {
+		n = len(h.entries)
+	}
+	return h.entries[len(h.entries)-n:]
+}
+
+func (h *replHistory) rewrite() error {
+	data := strings.Join(h.entries, "\n")
+	if data != "" {
+		data += "\n"
+	}
+	return os.WriteFile(h.path, []byte(data), 0o600)
+}
+
+// lineReader reads interactive input and prints asynchronous notices
+// without corrupting a partially typed line.
+type lineReader interface {
+	ReadLine(prompt string) (string, error)
+	Notify(text string)
+}
+
+func newLineReader(in *os.File, out io.Writer, history *replHistory) lineReader {
+	plain := &plainLineReader{reader: bufio.NewReader(in), out: out}
+	if !isTerminal(in) {
+		return plain
+	}
+	reader := newTerminalLineReader(struct {
+		io.Reader
+		io.Writer
+	}{in, out}, history)
+	reader.fd = int(in.Fd())
+	reader.fallback = plain
+	return reader
+}
+
+func isTerminal(file *os.File) bool {
+	return term.IsTerminal(int(file.Fd()))
+}
+
+type plainLineReader struct {
+	reader  *bufio.Reader
+	out     io.Writer
+	mu      sync.Mutex
+	reading bool
+	prompt  string
+}
+
+func (r *plainLineReader) ReadLine(prompt string) (string, error) {
+	r.mu.Lock()
+	r.reading = true
+	r.prompt = prompt
+	fmt.Fprint(r.out, prompt)
+	r.mu.Unlock()
+	line, err := r.reader.ReadString('\n')
+	r.mu.Lock()
+	r.reading = false
+	r.mu.Unlock()
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (r *plainLineReader) Notify(text string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fmt.Fprintf(r.out, "\n\n%s\n\n", text)
+	if r.reading {
+		fmt.Fprint(r.out, r.prompt)
+	}
+}
+
+// terminalLineReader edits TTY input with golang.org/x/term, which handles
+// cursor movement, history recall on the arrow keys, and redrawing the
+// prompt around notices. The terminal is only in raw mode while a line is
+// being read, so replies print normally in between. Ctrl+C and Ctrl+D on an
+// empty line return io.EOF.
+type terminalLineReader struct {
+	terminal *term.Terminal
+	out      io.Writer
+	// fd is the input terminal switched to raw mode per line; -1 skips it.
+	fd       int
+	fallback lineReader
+
+	mu      sync.Mutex
+	reading bool
+}
+
+func newTerminalLineReader(rw io.ReadWriter, history *replHistory) *terminalLineReader {
+	terminal := term.NewTerminal(rw, "")
+	if history != nil {
+		terminal.History = history
+	}
+	return &terminalLineReader{terminal: terminal, out: rw, fd: -1}
+}
+
+func (r *terminalLineReader) ReadLine(prompt string) (string, error) {
+	if r.fd >= 0 {
+		state, err := term.MakeRaw(r.fd)
+		if err != nil {
+			if r.fallback != nil {
+				return r.fallback.ReadLine(prompt)
+			}
+			return "", err
+		}
+		defer term.Restore(r.fd, state)
+		if width, height, err := term.GetSize(r.fd); err == nil {
+			_ = r.terminal.SetSize(width, height)
+		}
+	}
+	r.terminal.SetPrompt(prompt)
+	r.mu.Lock()
+	r.reading = true
+	r.mu.Unlock()
+	line, err := r.terminal.ReadLine()
+	r.mu.Lock()
+	r.reading = false
+	r.mu.Unlock()
+	if errors.Is(err, term.ErrPasteIndicator) {
+		err = nil
+	}
+	return line, err
+}
+
+func (r *terminalLineReader) Notify(text string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.reading {
+		fmt.Fprintf(r.out, "\n%s\n", text)
+		return
+	}
+	fmt.Fprintf(r.terminal, "%s\n\n", text)
+}
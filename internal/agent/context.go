@@ -22,19 +22,31 @@ const (
 )
 
 func buildSystemPrompt(cfg config.Config, userMessage string) string {
-	return buildSystemPromptWithSkills(cfg, userMessage, nil)
+	return buildSystemPromptWithSkills(cfg, nil, promptContext{}, userMessage, nil)
 }
 
-func buildSystemPromptWithSkills(cfg config.Config, userMessage string, activation *skills.ActivationResult) string {
+// buildSystemPromptWithSkills assembles the system prompt as a single
+// string, as buildSystemPromptSections describes.
+func buildSystemPromptWithSkills(cfg config.Config, mem *memory.Manager, pc promptContext, userMessage string, activation *skills.ActivationResult) string {
+	stable, turn := buildSystemPromptSections(cfg, mem, pc, userMessage, activation)
+	return stable + "\n" + turn
+}
+
+// buildSystemPromptSections assembles the system prompt in two parts. stable
+// holds what stays the same from turn to turn, the bootstrap files and
+// curated memory, so providers can cache it as a prefix; turn holds the
+// current time, retrieved memory and skill contracts. mem is the engine's
+// pooled memory manager; when nil a short-lived one is used. The bootstrap
+// files are expanded as templates with the variables for pc.
+func buildSystemPromptSections(cfg config.Config, mem *memory.Manager, pc promptContext, userMessage string, activation *skills.ActivationResult) (stable, turn string) {
 	workspace := config.WorkspacePath(cfg)
+	now := time.Now()
+	vars := promptVars(cfg, pc, now)
 	parts := []string{
 		"# squidbot",
 		"",
 		"You are squidbot, a practical AI assistant with tool access.",
 		"",
-		"## Current Time",
-		time.Now().Format("2006-01-02 15:04:05 (Monday)"),
-		"",
 		"## Workspace",
 		workspace,
 		"",
@@ -46,15 +58,27 @@ func buildSystemPromptWithSkills(cfg config.Config, userMessage string, activati
 		if err != nil {
 			continue
 		}
-		parts = append(parts, fmt.Sprintf("## %s\n\n%s", name, truncateText(string(content), maxBootstrapSectionChars)))
+		rendered := renderPromptTemplate(name, string(content), vars, pc)
+		parts = append(parts, fmt.Sprintf("## %s\n\n%s", name, truncateText(rendered, maxBootstrapSectionChars)))
 	}
 
 	memoryPath := filepath.Join(workspace, "memory", "MEMORY.md")
 	if memoryBytes, err := os.ReadFile(memoryPath); err == nil {
 		parts = append(parts, "## Curated Memory\n\n"+truncateText(string(memoryBytes), maxBootstrapSectionChars))
 	}
+	stable = strings.Join(parts, "\n")
 
-	memoryManager := memory.NewManager(cfg)
+	parts = []string{
+		"## Current Time",
+		now.In(promptLocation(cfg)).Format("2006-01-02 15:04:05 (Monday)"),
+		"",
+	}
+
+	memoryManager := mem
+	if memoryManager == nil {
+		memoryManager = memory.NewManager(cfg)
+		defer memoryManager.Close()
+	}
 	if memoryManager.Enabled() {
 		ctx := context.Background()
 		_ = memoryManager.Sync(ctx)
@@ -68,13 +92,8 @@ func buildSystemPromptWithSkills(cfg config.Config, userMessage string, activati
 			parts = append(parts, "## Retrieved Memory\n\n"+strings.Join(lines, "\n"))
 		}
 
-		recentDaily, err := memoryManager.RecentDaily(ctx, minInt(4, cfg.Memory.TopK))
-		if err == nil && len(recentDaily) > 0 {
-			lines := make([]string, 0, len(recentDaily))
-			for _, chunk := range recentDaily {
-				lines = append(lines, fmt.Sprintf("- %s: %s", shortPath(workspace, chunk.Path), truncateText(chunk.Content, maxMemorySnippetChars)))
-			}
-			parts = append(parts, "## Recent Daily Memory\n\n"+strings.Join(lines, "\n"))
+		if section := renderRecentDailySection(ctx, cfg, memoryManager); section != "" {
+			parts = append(parts, section)
 		}
 	}
 
@@ -82,7 +101,38 @@ func buildSystemPromptWithSkills(cfg config.Config, userMessage string, activati
 		parts = append(parts, section)
 	}
 
-	return strings.Join(parts, "\n")
+	return stable, strings.Join(parts, "\n")
+}
+
+// renderRecentDailySection summarises the last memory.injectRecentDays days
+// of daily logs, one line per entry, within memory.injectMaxChars.
+func renderRecentDailySection(ctx context.Context, cfg config.Config, mem *memory.Manager) string {
+	days := cfg.Memory.InjectRecentDays
+	if days <= 0 {
+		return ""
+	}
+	maxChars := cfg.Memory.InjectMaxChars
+	if maxChars <= 0 {
+		maxChars = 1200
+	}
+	chunks, err := mem.RecentDailySince(ctx, days, days*4)
+	if err != nil || len(chunks) == 0 {
+		return ""
+	}
+	lines := make([]string, 0, len(chunks))
+	used := 0
+	for _, chunk := range chunks {
+		line := fmt.Sprintf("- %s: %s", chunk.Day, truncateText(strings.Join(strings.Fields(chunk.Content), " "), maxMemorySnippetChars))
+		if used+len(line) > maxChars {
+			break
+		}
+		lines = append(lines, line)
+		used += len(line) + 1
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return "## Recent Daily Memory\n\n" + strings.Join(lines, "\n")
 }
 
 func renderSkillContractsSection(cfg config.Config, workspace string, activation *skills.ActivationResult) string {
@@ -137,9 +187,15 @@ func renderSkillContractsSection(cfg config.Config, workspace string, activation
 	return "## Skill Contracts\n\n" + strings.Join(lines, "\n\n")
 }
 
-func buildMessages(systemPrompt string, history []provider.Message, userMessage string) []provider.Message {
-	messages := make([]provider.Message, 0, len(history)+2)
-	messages = append(messages, provider.Message{Role: "system", Content: systemPrompt})
+// buildMessages lays out a turn's request. The stable system prompt comes
+// first on its own, so a provider cache breakpoint after it survives the
+// per-turn part that follows.
+func buildMessages(stablePrompt, turnPrompt string, history []provider.Message, userMessage string) []provider.Message {
+	messages := make([]provider.Message, 0, len(history)+3)
+	messages = append(messages, provider.Message{Role: "system", Content: stablePrompt})
+	if strings.TrimSpace(turnPrompt) != "" {
+		messages = append(messages, provider.Message{Role: "system", Content: turnPrompt})
+	}
 	messages = append(messages, history...)
 	messages = append(messages, provider.Message{Role: "user", Content: userMessage})
 	return messages
@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/grixate/squidbot/internal/config"
 	"github.com/grixate/squidbot/internal/skills"
@@ -40,6 +41,38 @@ func TestBuildSystemPromptIncludesSkillsAndMemorySections(t *testing.T) {
 	}
 }
 
+func TestBuildSystemPromptInjectsRecentDailyMemory(t *testing.T) {
+	workspace := t.TempDir()
+	today := time.Now().UTC().Format("2006-01-02")
+	stale := time.Now().UTC().AddDate(0, 0, -10).Format("2006-01-02")
+	mustWrite(t, filepath.Join(workspace, "memory", "daily", today+".md"), "# "+today+"\nDiscussed the quarterly roadmap with Sam.")
+	mustWrite(t, filepath.Join(workspace, "memory", "daily", stale+".md"), "# "+stale+"\nOld note about the migration.")
+
+	cfg := config.Default()
+	cfg.Agents.Defaults.Workspace = workspace
+	cfg.Memory.IndexPath = filepath.Join(t.TempDir(), "memory_index.db")
+	cfg.Memory.InjectRecentDays = 2
+
+	prompt := buildSystemPrompt(cfg, "unrelated question")
+	section := prompt[strings.Index(prompt, "## Recent Daily Memory"):]
+	if !strings.Contains(section, today+": # "+today+" Discussed the quarterly roadmap") {
+		t.Fatalf("expected today's entry on one line, got:\n%s", section)
+	}
+	if strings.Contains(section, "migration") {
+		t.Fatalf("expected entries older than injectRecentDays to be left out, got:\n%s", section)
+	}
+
+	cfg.Memory.InjectMaxChars = 20
+	if prompt := buildSystemPrompt(cfg, "unrelated question"); strings.Contains(prompt, "## Recent Daily Memory") {
+		t.Fatal("expected section to be dropped when no entry fits injectMaxChars")
+	}
+	cfg.Memory.InjectMaxChars = 0
+	cfg.Memory.InjectRecentDays = 0
+	if prompt := buildSystemPrompt(cfg, "unrelated question"); strings.Contains(prompt, "## Recent Daily Memory") {
+		t.Fatal("expected injectRecentDays=0 to turn the section off")
+	}
+}
+
 func TestBuildSystemPromptTruncatesLargeBootstrapFiles(t *testing.T) {
 	workspace := t.TempDir()
 	huge := strings.Repeat("x", maxBootstrapSectionChars+500)
@@ -82,7 +115,7 @@ func TestBuildSystemPromptWithSkillsUsesActivatedOnly(t *testing.T) {
 		},
 		Diagnostics: skills.ActivationDiagnostics{Matched: 3, Activated: 1, Skipped: 2},
 	}
-	prompt := buildSystemPromptWithSkills(cfg, "plan", &activation)
+	prompt := buildSystemPromptWithSkills(cfg, nil, promptContext{}, "plan", &activation)
 	if !strings.Contains(prompt, "Planner [planner]") {
 		t.Fatalf("expected activated skill in prompt, got:\n%s", prompt)
 	}
@@ -91,6 +124,37 @@ func TestBuildSystemPromptWithSkillsUsesActivatedOnly(t *testing.T) {
 	}
 }
 
+func TestBuildSystemPromptExpandsTemplateVariables(t *testing.T) {
+	workspace := t.TempDir()
+	mustWrite(t, filepath.Join(workspace, "USER.md"), "Team: {{.Team}}\nToday is {{.Date}} ({{.Timezone}}) on {{.Channel}} in {{.SessionID}}.\nMissing: [{{.Nope}}]")
+	mustWrite(t, filepath.Join(workspace, "TOOLS.md"), "Literal {{ braces")
+
+	cfg := config.Default()
+	cfg.Agents.Defaults.Workspace = workspace
+	cfg.Agents.Defaults.Timezone = "Asia/Tokyo"
+	cfg.Agents.Defaults.PromptVars = map[string]string{"Team": "platform", "Date": "ignored"}
+	cfg.Memory.Enabled = false
+
+	var warnings []string
+	pc := promptContext{Channel: "telegram", SessionID: "telegram:42", Warn: func(message string) { warnings = append(warnings, message) }}
+	prompt := buildSystemPromptWithSkills(cfg, nil, pc, "hello", &skills.ActivationResult{})
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	today := time.Now().In(tokyo).Format("2006-01-02")
+	for _, want := range []string{
+		"Team: platform",
+		"Today is " + today + " (Asia/Tokyo) on telegram in telegram:42.",
+		"Missing: []",
+		"Literal {{ braces",
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Fatalf("expected %q in prompt, got:\n%s", want, prompt)
+		}
+	}
+	if len(warnings) != 2 || !strings.Contains(warnings[0], "USER.md references unknown variables: Nope") || !strings.Contains(warnings[1], "TOOLS.md left unexpanded") {
+		t.Fatalf("unexpected warnings: %#v", warnings)
+	}
+}
+
 func mustWrite(t *testing.T, path, content string) {
 	t.Helper()
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
@@ -100,3 +164,24 @@ func mustWrite(t *testing.T, path, content string) {
 		t.Fatal(err)
 	}
 }
+
+func TestBuildSystemPromptSectionsKeepTimeOutOfStablePart(t *testing.T) {
+	workspace := t.TempDir()
+	mustWrite(t, filepath.Join(workspace, "AGENTS.md"), "# Agent")
+
+	cfg := config.Default()
+	cfg.Agents.Defaults.Workspace = workspace
+	cfg.Memory.Enabled = false
+
+	stable, turn := buildSystemPromptSections(cfg, nil, promptContext{}, "hello", nil)
+	if strings.Contains(stable, "## Current Time") || !strings.Contains(stable, "## AGENTS.md") {
+		t.Fatalf("expected only turn-invariant sections in the stable part, got:\n%s", stable)
+	}
+	if !strings.HasPrefix(turn, "## Current Time") {
+		t.Fatalf("expected the current time in the per-turn part, got:\n%s", turn)
+	}
+	messages := buildMessages(stable, turn, nil, "hello")
+	if len(messages) != 3 || messages[0].Content != stable || messages[1].Content != turn {
+		t.Fatalf("expected stable and per-turn system messages, got %+v", messages)
+	}
+}
@@ -0,0 +1,148 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/grixate/squidbot/internal/skills"
+	"github.com/grixate/squidbot/internal/tools"
+)
+
+const ToolLockNamespace = "session_tool_lock"
+
+// SessionFocusKey is the session meta key holding the skill a session is
+// pinned to with /focus.
+const SessionFocusKey = "focus_skill"
+
+var readOnlyToolNames = []string{
+	"read_file",
+	"list_dir",
+	"web_search",
+	"web_fetch",
+	"subagent_status",
+	"subagent_result",
+	"budget_status",
+	"federation_peers",
+	"search_history",
+}
+
+// SetSessionToolsLocked persists whether a session is restricted to read-only tools.
+func SetSessionToolsLocked(ctx context.Context, store KVStore, sessionID string, locked bool) error {
+	value := []byte("0")
+	if locked {
+		value = []byte("1")
+	}
+	return store.PutKV(ctx, ToolLockNamespace, strings.TrimSpace(sessionID), value)
+}
+
+func SessionToolsLocked(ctx context.Context, store KVStore, sessionID string) bool {
+	sessionID = strings.TrimSpace(sessionID)
+	if store == nil || sessionID == "" {
+		return false
+	}
+	value, err := store.GetKV(ctx, ToolLockNamespace, sessionID)
+	if err != nil {
+		return false
+	}
+	return string(value) == "1"
+}
+
+// SetSessionFocus pins a session to one skill; an empty skillID clears it.
+func SetSessionFocus(ctx context.Context, store ConversationStore, sessionID, skillID string) error {
+	return store.SaveSessionMeta(ctx, strings.TrimSpace(sessionID), map[string]any{SessionFocusKey: strings.TrimSpace(skillID)})
+}
+
+// SessionFocus returns the skill a session is pinned to, or "".
+func SessionFocus(ctx context.Context, store ConversationStore, sessionID string) string {
+	sessionID = strings.TrimSpace(sessionID)
+	if store == nil || sessionID == "" {
+		return ""
+	}
+	meta, err := store.GetSessionMeta(ctx, sessionID)
+	if err != nil {
+		return ""
+	}
+	focus, _ := meta[SessionFocusKey].(string)
+	return strings.TrimSpace(focus)
+}
+
+func readOnlyRegistry(registry *tools.Registry) *tools.Registry {
+	out := tools.NewRegistry()
+	for _, name := range readOnlyToolNames {
+		if tool, ok := registry.Get(name); ok {
+			out.Register(tool)
+		}
+	}
+	return out
+}
+
+// handleControlCommand intercepts session control commands such as
+// /lock-tools and /focus before they reach the model.
+func (e *Engine) handleControlCommand(ctx context.Context, msg InboundMessage) (string, bool, error) {
+	command := strings.ToLower(strings.TrimSpace(msg.Content))
+	if name, arg, _ := strings.Cut(command, " "); name == "/focus" {
+		return e.focusSession(ctx, msg.SessionID, strings.TrimSpace(arg))
+	}
+	switch command {
+	case "/lock-tools":
+		if err := SetSessionToolsLocked(ctx, e.store, msg.SessionID, true); err != nil {
+			return "", true, err
+		}
+		return "Tools locked for this session. Only read-only tools are available until you send /unlock-tools.", true, nil
+	case "/unlock-tools":
+		if err := SetSessionToolsLocked(ctx, e.store, msg.SessionID, false); err != nil {
+			return "", true, err
+		}
+		return "Tools unlocked for this session.", true, nil
+	case "/retitle":
+		title, err := e.RefreshSessionTitle(ctx, msg.SessionID)
+		if err != nil {
+			return "", true, err
+		}
+		return fmt.Sprintf("Session title: %s", title), true, nil
+	case "/unfocus":
+		if err := SetSessionFocus(ctx, e.store, msg.SessionID, ""); err != nil {
+			return "", true, err
+		}
+		return "Skill focus cleared. All skills are available again.", true, nil
+	}
+	return "", false, nil
+}
+
+// focusSession handles /focus: with no argument it reports the current
+// focus, otherwise it pins the session to the named skill.
+func (e *Engine) focusSession(ctx context.Context, sessionID, mention string) (string, bool, error) {
+	if mention == "" {
+		if focus := SessionFocus(ctx, e.store, sessionID); focus != "" {
+			return fmt.Sprintf("Focused on skill %s. Send /unfocus to clear it.", focus), true, nil
+		}
+		return "No skill focus. Send /focus <skill-id> to pin this session to one skill.", true, nil
+	}
+	if e.skills == nil || !e.currentConfig().Skills.Enabled {
+		return "Skills are disabled, so there is nothing to focus on.", true, nil
+	}
+	needle := strings.TrimPrefix(mention, "$")
+	for _, skill := range e.skills.Snapshot().Skills {
+		if !skill.Valid || !skillMatchesMention(skill, needle) {
+			continue
+		}
+		if err := SetSessionFocus(ctx, e.store, sessionID, skill.ID); err != nil {
+			return "", true, err
+		}
+		return fmt.Sprintf("Focused on skill %s. Other skills are disabled for this session until you send /unfocus.", skill.ID), true, nil
+	}
+	return fmt.Sprintf("Unknown skill %q. Run `squidbot skills list` to see available skills.", mention), true, nil
+}
+
+func skillMatchesMention(skill skills.SkillDescriptor, mention string) bool {
+	if strings.EqualFold(skill.ID, mention) || strings.EqualFold(skill.Name, mention) {
+		return true
+	}
+	for _, alias := range skill.Aliases {
+		if strings.EqualFold(alias, mention) {
+			return true
+		}
+	}
+	return false
+}
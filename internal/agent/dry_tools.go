@@ -0,0 +1,43 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/grixate/squidbot/internal/tools"
+)
+
+// SetDryTools makes every tool call return a canned result instead of
+// running. The model still sees the full tool surface and the arguments are
+// still validated, so a turn exercises the real tool loop without side
+// effects.
+func (e *Engine) SetDryTools(enabled bool) {
+	e.dryTools.Store(enabled)
+}
+
+// stubTool keeps a tool's name, description and schema and replaces its
+// execution with an echo of the arguments.
+type stubTool struct {
+	tools.Tool
+	log func(name string, args json.RawMessage)
+}
+
+func (t stubTool) Execute(_ context.Context, args json.RawMessage) (tools.ToolResult, error) {
+	t.log(t.Name(), args)
+	return tools.ToolResult{
+		Text:     "stubbed: " + string(args),
+		Metadata: map[string]any{"dry_tools": true},
+	}, nil
+}
+
+func (e *Engine) stubRegistry(registry *tools.Registry) *tools.Registry {
+	out := tools.NewRegistry()
+	logCall := func(name string, args json.RawMessage) {
+		e.log.Printf("event=tool_stubbed tool=%s args=%s", name, truncateText(string(args), 500))
+	}
+	for _, name := range registry.Names() {
+		tool, _ := registry.Get(name)
+		out.Register(stubTool{Tool: tool, log: logCall})
+	}
+	return out
+}
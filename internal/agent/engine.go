@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha1"
 	"encoding/hex"
@@ -11,9 +12,11 @@ import (
 	mrand "math/rand"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/oklog/ulid/v2"
@@ -54,6 +57,7 @@ type Engine struct {
 	log                 *log.Logger
 	actors              *actor.System
 	outbound            chan OutboundMessage
+	outboundLog         *outboundLog
 	policy              *tools.PathPolicy
 	memory              *memory.Manager
 	plugins             plugins.Runtime
@@ -63,17 +67,49 @@ type Engine struct {
 	federationClient    *federation.Client
 	fedCancelMu         sync.Mutex
 	fedCancels          map[string]context.CancelFunc
+	fedRuns             sync.WaitGroup
 	ulidMu              sync.Mutex
 	stateMu             sync.RWMutex
 	tokenSafetyMu       sync.Mutex
 	tokenSafetyCached   budget.Settings
 	tokenSafetyCachedAt time.Time
 	tokenSafetyCacheTTL time.Duration
+	turnSlots           chan struct{}
 	entropy             *ulid.MonotonicEntropy
+	dryTools            atomic.Bool
+	deferredMu          sync.Mutex
+	deferred            []OutboundMessage
+	deferredTimer       *time.Timer
+	// deferredStop is set by StartDeferredDelivery and closed by Close. Held
+	// messages are only released while it is open, because only the gateway
+	// consumes Outbound.
+	deferredStop chan struct{}
+	// promptWarnings holds prompt template warnings already logged, so a
+	// broken workspace file is reported once rather than on every turn.
+	promptWarnings sync.Map
+	// streamFallbacks holds the providers whose streaming is turned off in
+	// config and whose buffered fallback has already been logged.
+	streamFallbacks sync.Map
+	// searchFallbacks holds the unknown web_search providers whose fallback
+	// to brave has already been logged.
+	searchFallbacks sync.Map
+	// summaryBackoff holds, per session, the history summary failures that
+	// hold off the next attempt.
+	summaryBackoff sync.Map
+	// skillsDigest fingerprints the last skill index seen by RefreshSkills.
+	skillsDigestMu sync.Mutex
+	skillsDigest   string
+	// abortCtx is cancelled by AbortTurns to cut off turns still running at
+	// shutdown.
+	abortCtx    context.Context
+	abortCancel context.CancelFunc
 }
 
 type processRequest struct {
 	Msg InboundMessage
+	// Sink, when set, receives tool_call_started and tool_call_end events
+	// from the tool loop around each call that runs.
+	Sink StreamSink
 }
 
 func NewEngine(cfg config.Config, providerClient provider.LLMProvider, model string, store Store, metrics *telemetry.Metrics, logger *log.Logger) (*Engine, error) {
@@ -95,6 +131,7 @@ func NewEngine(cfg config.Config, providerClient provider.LLMProvider, model str
 		metrics:             metrics,
 		log:                 logger,
 		outbound:            make(chan OutboundMessage, 512),
+		outboundLog:         newOutboundLog(outboundLogCapacity),
 		policy:              policy,
 		memory:              memory.NewManager(cfg),
 		budgetGuard:         budget.NewGuard(store, metrics),
@@ -103,6 +140,10 @@ func NewEngine(cfg config.Config, providerClient provider.LLMProvider, model str
 		tokenSafetyCacheTTL: 2 * time.Second,
 		entropy:             ulid.Monotonic(mrand.New(mrand.NewSource(time.Now().UnixNano())), 0),
 	}
+	engine.abortCtx, engine.abortCancel = context.WithCancel(context.Background())
+	if cfg.Runtime.MaxConcurrentTurns > 0 {
+		engine.turnSlots = make(chan struct{}, cfg.Runtime.MaxConcurrentTurns)
+	}
 	pluginRuntime := plugins.NewManager(cfg, logger)
 	if err := pluginRuntime.Discover(context.Background()); err != nil {
 		return nil, fmt.Errorf("plugin discovery failed: %w", err)
@@ -113,8 +154,10 @@ func NewEngine(cfg config.Config, providerClient provider.LLMProvider, model str
 		return nil, fmt.Errorf("skills discovery failed: %w", err)
 	}
 	engine.skills = skillsRuntime
+	engine.skillsDigest = skillsDigest(skillsRuntime.Snapshot())
 	system := actor.NewSystem(engine.newSessionHandler, cfg.Runtime.MailboxSize, cfg.Runtime.ActorIdleTTL.Duration)
 	system.SetActorHooks(func() { engine.metrics.ActiveActors.Add(1) }, func() { engine.metrics.ActiveActors.Add(-1) })
+	system.SetEvictHook(func(string) { engine.metrics.SessionsEvicted.Add(1) })
 	engine.actors = system
 	subCfg := cfg.Runtime.Subagents
 	engine.subagents = subagent.NewManager(subagent.Options{
@@ -126,11 +169,18 @@ func NewEngine(cfg config.Config, providerClient provider.LLMProvider, model str
 		RetryBackoff:     time.Duration(subCfg.RetryBackoffSec) * time.Second,
 		MaxDepth:         subCfg.MaxDepth,
 		NotifyOnComplete: subCfg.NotifyOnComplete,
+		FailInterrupted:  !subCfg.ResumeOnStartup,
+		LoopThreshold:    subCfg.LoopThreshold,
+		LoopWindow:       time.Duration(subCfg.LoopWindowSec) * time.Second,
+		StreamProgress:   subCfg.StreamProgress,
+		ProgressInterval: time.Duration(subCfg.ProgressIntervalSec) * time.Second,
+		Progress:         engine.relaySubagentProgress,
 		NextID:           engine.nextID,
 	}, store, engine.runSubtask, engine.notifySubagentCompletion, metrics)
 	if err := engine.subagents.Start(context.Background()); err != nil {
 		return nil, err
 	}
+	engine.restoreDeferredOutbound()
 	return engine, nil
 }
 
@@ -143,13 +193,58 @@ func (e *Engine) EmitOutbound(channel, chatID, content string, metadata map[stri
 }
 
 func (e *Engine) Close() error {
+	e.AbortTurns()
+	e.deferredMu.Lock()
+	if e.deferredTimer != nil {
+		e.deferredTimer.Stop()
+	}
+	if e.deferredStop != nil {
+		close(e.deferredStop)
+		e.deferredStop = nil
+	}
+	e.deferredMu.Unlock()
 	if e.subagents != nil {
 		e.subagents.Stop()
 	}
+	e.fedCancelMu.Lock()
+	for _, cancel := range e.fedCancels {
+		cancel()
+	}
+	e.fedCancelMu.Unlock()
+	e.fedRuns.Wait()
 	if e.plugins != nil {
 		_ = e.plugins.Close()
 	}
-	return e.actors.Stop()
+	err := e.actors.Stop()
+	if e.memory != nil {
+		_ = e.memory.Close()
+	}
+	return err
+}
+
+// BeginDrain stops subagent workers from starting queued runs, ahead of a
+// graceful shutdown.
+func (e *Engine) BeginDrain() {
+	e.subagents.Drain()
+}
+
+// ActiveWork reports the turns and subagent runs currently executing.
+func (e *Engine) ActiveWork() (turns int, subagents int) {
+	return int(e.metrics.ActiveTurns.Load()), e.subagents.ActiveRuns()
+}
+
+// LiveSessions returns the sessions that currently have an actor, with the
+// time each was last active. Idle actors drop out after runtime.actorIdleTtl.
+func (e *Engine) LiveSessions() map[string]time.Time {
+	return e.actors.Live()
+}
+
+// AbortTurns cancels every turn still running. Close calls it, so a turn in
+// progress never holds up shutdown.
+func (e *Engine) AbortTurns() {
+	if e.abortCancel != nil {
+		e.abortCancel()
+	}
 }
 
 func (e *Engine) Submit(ctx context.Context, msg InboundMessage) (Ack, error) {
@@ -163,7 +258,9 @@ func (e *Engine) Submit(ctx context.Context, msg InboundMessage) (Ack, error) {
 		msg.SessionID = msg.Channel + ":" + msg.ChatID
 	}
 	msg.Metadata = ensureTraceMetadata(msg.Metadata, msg.RequestID)
-	_, err := e.actors.Submit(ctx, msg.SessionID, processRequest{Msg: msg}, false)
+	// The turn runs after Submit returns, so it must outlive the caller's
+	// context; AbortTurns still cancels it.
+	_, err := e.actors.Submit(context.WithoutCancel(ctx), msg.SessionID, processRequest{Msg: msg}, false)
 	if err != nil {
 		return Ack{}, err
 	}
@@ -172,6 +269,10 @@ func (e *Engine) Submit(ctx context.Context, msg InboundMessage) (Ack, error) {
 }
 
 func (e *Engine) Ask(ctx context.Context, msg InboundMessage) (string, error) {
+	return e.ask(ctx, msg, nil)
+}
+
+func (e *Engine) ask(ctx context.Context, msg InboundMessage, sink StreamSink) (string, error) {
 	if msg.RequestID == "" {
 		msg.RequestID = e.nextID()
 	}
@@ -182,7 +283,7 @@ func (e *Engine) Ask(ctx context.Context, msg InboundMessage) (string, error) {
 		msg.SessionID = msg.Channel + ":" + msg.ChatID
 	}
 	msg.Metadata = ensureTraceMetadata(msg.Metadata, msg.RequestID)
-	res, err := e.actors.Submit(ctx, msg.SessionID, processRequest{Msg: msg}, true)
+	res, err := e.actors.Submit(ctx, msg.SessionID, processRequest{Msg: msg, Sink: sink}, true)
 	if err != nil {
 		return "", err
 	}
@@ -207,71 +308,50 @@ func (e *Engine) AskStream(ctx context.Context, msg InboundMessage, sink StreamS
 	cfg := e.currentConfig()
 	providerClient, model := e.currentProviderModel()
 	if providerClient.Capabilities().SupportsStream {
-		history, err := e.store.Window(ctx, msg.SessionID, 50)
+		history, err := e.sessionHistory(ctx, msg.SessionID)
 		if err == nil {
 			skillActivation, skillErr := e.activateSkills(ctx, msg.Content, msg.Channel, msg.SessionID, false, nil)
 			if skillErr != nil {
 				_ = sink.OnEvent(ctx, StreamEvent{Type: "error", Error: skillErr.Error(), Done: true})
 				return skillErr
 			}
-			systemPrompt := buildSystemPromptWithSkills(cfg, msg.Content, &skillActivation)
-			messages := buildMessages(systemPrompt, history, msg.Content)
-			events, errs := providerClient.Stream(ctx, provider.ChatRequest{
-				Messages:    messages,
-				Model:       model,
-				MaxTokens:   cfg.Agents.Defaults.MaxTokens,
-				Temperature: cfg.Agents.Defaults.Temperature,
-			})
-			var final strings.Builder
-			for events != nil || errs != nil {
-				select {
-				case event, ok := <-events:
-					if !ok {
-						events = nil
-						continue
-					}
-					if event.ToolCall != nil {
-						_ = sink.OnEvent(ctx, StreamEvent{
-							Type:       "tool_call_started",
-							ToolName:   event.ToolCall.Name,
-							ToolCallID: event.ToolCall.ID,
-						})
-						continue
-					}
-					if strings.TrimSpace(event.DeltaContent) != "" {
-						final.WriteString(event.DeltaContent)
-						if err := sink.OnEvent(ctx, StreamEvent{Type: "assistant_delta", Delta: event.DeltaContent}); err != nil {
-							return err
-						}
-					}
-				case streamErr, ok := <-errs:
-					if !ok {
-						errs = nil
-						continue
-					}
-					if streamErr != nil {
-						_ = sink.OnEvent(ctx, StreamEvent{Type: "error", Error: streamErr.Error(), Done: true})
-						return streamErr
-					}
-				}
+			stablePrompt, turnPrompt := buildSystemPromptSections(cfg, e.memory, e.promptContext(msg.Channel, msg.ChatID, msg.SessionID), msg.Content, &skillActivation)
+			messages := buildMessages(stablePrompt, turnPrompt, history, msg.Content)
+			release, err := e.acquireTurnSlot(ctx)
+			if err != nil {
+				_ = sink.OnEvent(ctx, StreamEvent{Type: "error", Error: err.Error(), Done: true})
+				return err
 			}
-			finalContent := strings.TrimSpace(final.String())
+			defer release()
+			providerName, providerCfg := cfg.PrimaryProvider()
+			streamed, err := e.streamReply(ctx, providerClient, providerName, providerCfg.StreamingEnabled(), provider.ChatRequest{
+				Messages:      messages,
+				Model:         model,
+				MaxTokens:     cfg.Agents.Defaults.MaxTokens,
+				Temperature:   cfg.Agents.Defaults.Temperature,
+				PromptCaching: cfg.Agents.Defaults.PromptCaching,
+			}, sink)
+			if err != nil {
+				return err
+			}
+			finalContent := strings.TrimSpace(streamed)
 			if finalContent == "" {
 				finalContent = "I've completed processing but have no response to provide."
 			}
+			finalContent = applyPostProcessors(finalContent, postProcessorsFor(cfg.Agents.PostProcessors, msg.Channel))
 			_ = e.store.AppendTurn(ctx, Turn{SessionID: msg.SessionID, Role: "user", Content: msg.Content})
 			_ = e.store.AppendTurn(ctx, Turn{SessionID: msg.SessionID, Role: "assistant", Content: finalContent})
 			_ = e.store.SaveSessionMeta(ctx, msg.SessionID, map[string]interface{}{"last_channel": msg.Channel, "last_chat_id": msg.ChatID})
-			if msg.Channel != "cli" {
+			if !IsReservedChannel(msg.Channel) {
 				traceID, _ := msg.Metadata["trace_id"].(string)
-				e.send(msg.Channel, msg.ChatID, finalContent, map[string]interface{}{"session_id": msg.SessionID, "trace_id": traceID})
+				e.sendReply(msg.Channel, msg.ChatID, finalContent, map[string]interface{}{"session_id": msg.SessionID, "trace_id": traceID})
 			}
 			e.appendDailyMemory(ctx, msg, finalContent)
 			return sink.OnEvent(ctx, StreamEvent{Type: "final", Content: finalContent, Done: true})
 		}
 	}
 
-	response, err := e.Ask(ctx, msg)
+	response, err := e.ask(ctx, msg, sink)
 	if err != nil {
 		_ = sink.OnEvent(ctx, StreamEvent{Type: "error", Error: err.Error(), Done: true})
 		return err
@@ -287,6 +367,18 @@ func (e *Engine) AskStream(ctx context.Context, msg InboundMessage, sink StreamS
 	return sink.OnEvent(ctx, StreamEvent{Type: "final", Content: response, Done: true})
 }
 
+const toolEventPreviewChars = 200
+
+// emitToolEvent reports tool progress to a streaming caller. It runs on the
+// session actor between tool calls, so events arrive in loop order; sink
+// errors are ignored so a gone client cannot fail the turn.
+func emitToolEvent(ctx context.Context, sink StreamSink, event StreamEvent) {
+	if sink == nil {
+		return
+	}
+	_ = sink.OnEvent(ctx, event)
+}
+
 func streamChunks(content string, chunkSize int) []string {
 	if chunkSize <= 0 {
 		chunkSize = 80
@@ -309,12 +401,18 @@ func (e *Engine) activateSkills(ctx context.Context, query, channel, sessionID s
 	if e == nil || e.skills == nil {
 		return skills.ActivationResult{}, nil
 	}
+	focus := ""
+	if !isSubagent {
+		focus = SessionFocus(ctx, e.store, sessionID)
+	}
 	result, err := e.skills.Activate(ctx, skills.ActivationRequest{
 		Query:            query,
 		Channel:          channel,
 		SessionID:        sessionID,
 		ExplicitMentions: explicitMentions,
 		IsSubagent:       isSubagent,
+		Overrides:        skills.LoadOverrides(ctx, e.store),
+		Focus:            focus,
 	})
 	if err != nil {
 		return skills.ActivationResult{}, err
@@ -334,8 +432,8 @@ func (e *Engine) activateSkills(ctx context.Context, query, channel, sessionID s
 	for _, item := range result.Activated {
 		activatedIDs = append(activatedIDs, item.Skill.Descriptor.ID)
 	}
-	e.log.Printf("event=skills_router channel=%s session_id=%s subagent=%v query_hash=%s explicit=%d activated=%v skipped=%d",
-		channel, sessionID, isSubagent, result.Diagnostics.QueryHash, len(result.Diagnostics.Explicit), activatedIDs, len(result.Skipped))
+	e.log.Printf("event=skills_router channel=%s session_id=%s subagent=%v query_hash=%s explicit=%d activated=%v skipped=%d focus=%s",
+		channel, sessionID, isSubagent, result.Diagnostics.QueryHash, len(result.Diagnostics.Explicit), activatedIDs, len(result.Skipped), focus)
 	return result, nil
 }
 
@@ -387,11 +485,29 @@ func (e *Engine) send(channel, chatID, content string, metadata map[string]inter
 	for k, v := range metadata {
 		msg.Metadata[k] = v
 	}
+	outboundID := e.nextID()
+	if isInternalChannel(channel) {
+		e.recordOutbound(outboundID, msg, OutboundSuppressed)
+		return
+	}
+	msg.Metadata["outbound_id"] = outboundID
+	if e.deferOutbound(outboundID, msg) {
+		return
+	}
+	e.recordOutbound(outboundID, msg, e.enqueueOutbound(msg))
+}
+
+// enqueueOutbound hands msg to the outbound channel without blocking and
+// returns the resulting outbound status.
+func (e *Engine) enqueueOutbound(msg OutboundMessage) string {
 	select {
 	case e.outbound <- msg:
 		e.metrics.OutboundCount.Add(1)
+		return OutboundQueued
 	default:
-		e.log.Printf("outbound channel full; dropping message channel=%s chat_id=%s", channel, chatID)
+		e.metrics.OutboundDropped.Add(1)
+		e.log.Printf("outbound channel full; dropping message channel=%s chat_id=%s", msg.Channel, msg.ChatID)
+		return OutboundDropped
 	}
 }
 
@@ -429,13 +545,9 @@ func (e *Engine) currentProviderModel() (provider.LLMProvider, string) {
 
 func (e *Engine) newSessionHandler(sessionID string) (actor.SessionHandler, error) {
 	h := &sessionHandler{engine: e, sessionID: sessionID}
-	if checkpoint, err := e.store.LoadCheckpoint(context.Background(), sessionID); err == nil && len(checkpoint) > 0 {
-		var restored struct {
-			LastRequestID string `json:"last_request_id"`
-		}
-		if json.Unmarshal(checkpoint, &restored) == nil {
-			h.lastRequestID = restored.LastRequestID
-		}
+	if restored, ok := e.loadCheckpoint(context.Background(), sessionID); ok {
+		h.lastRequestID = restored.LastRequestID
+		e.metrics.SessionsResurrected.Add(1)
 	}
 	return h, nil
 }
@@ -451,19 +563,41 @@ func (h *sessionHandler) Handle(ctx context.Context, payload interface{}) (inter
 	if !ok {
 		return nil, fmt.Errorf("invalid payload type %T", payload)
 	}
-	response, err := h.process(ctx, req.Msg)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(h.engine.abortCtx, cancel)
+	defer stop()
+	response, err := h.process(ctx, req.Msg, req.Sink)
 	if err != nil {
 		return nil, err
 	}
 	h.lastRequestID = req.Msg.RequestID
-	checkpoint, _ := json.Marshal(map[string]interface{}{"last_request_id": h.lastRequestID, "updated_at": time.Now().UTC()})
-	_ = h.engine.store.SaveCheckpoint(context.Background(), h.sessionID, checkpoint)
+	_ = h.engine.updateCheckpoint(context.Background(), h.sessionID, func(c *sessionCheckpoint) {
+		c.LastRequestID = h.lastRequestID
+	})
 	return response, nil
 }
 
-func (h *sessionHandler) Close() error { return nil }
+// Close runs when the session actor is evicted after runtime.actorIdleTtl or
+// stopped at shutdown, and archives the transcript when archiveOnIdle is set.
+func (h *sessionHandler) Close() error {
+	if !h.engine.currentConfig().Runtime.ArchiveOnIdle {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	path, err := h.engine.ArchiveSession(ctx, h.sessionID)
+	if err != nil {
+		h.engine.log.Printf("event=session_archive_failed session_id=%s err=%v", h.sessionID, err)
+		return nil
+	}
+	if path != "" {
+		h.engine.metrics.SessionsArchived.Add(1)
+	}
+	return nil
+}
 
-func (h *sessionHandler) process(ctx context.Context, msg InboundMessage) (string, error) {
+func (h *sessionHandler) process(ctx context.Context, msg InboundMessage, sink StreamSink) (string, error) {
 	h.engine.metrics.ActiveTurns.Add(1)
 	defer h.engine.metrics.ActiveTurns.Add(-1)
 
@@ -476,7 +610,18 @@ func (h *sessionHandler) process(ctx context.Context, msg InboundMessage) (strin
 	turnCtx, cancel := context.WithTimeout(ctx, turnTimeout)
 	defer cancel()
 
-	history, err := h.engine.store.Window(turnCtx, h.sessionID, 50)
+	if reply, handled, err := h.engine.handleControlCommand(turnCtx, msg); handled {
+		if err != nil {
+			return "", err
+		}
+		_ = h.engine.store.SaveSessionMeta(turnCtx, h.sessionID, map[string]interface{}{"last_channel": msg.Channel, "last_chat_id": msg.ChatID})
+		if !IsReservedChannel(msg.Channel) {
+			h.engine.sendReply(msg.Channel, msg.ChatID, reply, map[string]interface{}{"session_id": msg.SessionID, "trace_id": traceID})
+		}
+		return reply, nil
+	}
+
+	history, err := h.engine.sessionHistory(turnCtx, h.sessionID)
 	if err != nil {
 		return "", err
 	}
@@ -486,13 +631,13 @@ func (h *sessionHandler) process(ctx context.Context, msg InboundMessage) (strin
 		_ = h.engine.store.AppendTurn(turnCtx, Turn{SessionID: h.sessionID, Role: "user", Content: msg.Content})
 		_ = h.engine.store.AppendTurn(turnCtx, Turn{SessionID: h.sessionID, Role: "assistant", Content: finalContent})
 		_ = h.engine.store.SaveSessionMeta(turnCtx, h.sessionID, map[string]interface{}{"last_channel": msg.Channel, "last_chat_id": msg.ChatID})
-		if msg.Channel != "cli" {
-			h.engine.send(msg.Channel, msg.ChatID, finalContent, map[string]interface{}{"session_id": msg.SessionID, "trace_id": traceID})
+		if !IsReservedChannel(msg.Channel) {
+			h.engine.sendReply(msg.Channel, msg.ChatID, finalContent, map[string]interface{}{"session_id": msg.SessionID, "trace_id": traceID})
 		}
 		return finalContent, nil
 	}
-	systemPrompt := buildSystemPromptWithSkills(cfg, msg.Content, &skillActivation)
-	messages := buildMessages(systemPrompt, history, msg.Content)
+	stablePrompt, turnPrompt := buildSystemPromptSections(cfg, h.engine.memory, h.engine.promptContext(msg.Channel, msg.ChatID, h.sessionID), msg.Content, &skillActivation)
+	messages := buildMessages(stablePrompt, turnPrompt, history, msg.Content)
 	registry, err := h.engine.buildRegistry(msg)
 	if err != nil {
 		return "", err
@@ -504,8 +649,15 @@ func (h *sessionHandler) process(ctx context.Context, msg InboundMessage) (strin
 	}
 	finalContent := ""
 	budgetWarnings := []string{}
+	lastContent := ""
+	pendingTools := []string{}
+	timedOut := false
 
 	for i := 0; i < maxHops; i++ {
+		if turnDeadlineExceeded(ctx, turnCtx) {
+			timedOut = true
+			break
+		}
 		settings := h.engine.effectiveTokenSafety(turnCtx)
 		scopeLimits := []budget.ScopeLimit{
 			{Key: "global", HardLimitTokens: settings.GlobalHardLimitTokens, SoftThresholdPct: settings.GlobalSoftThresholdPct},
@@ -517,28 +669,32 @@ func (h *sessionHandler) process(ctx context.Context, msg InboundMessage) (strin
 				SoftThresholdPct: settings.SessionSoftThresholdPct,
 			})
 		}
-		plannedTokens := uint64(max(cfg.Agents.Defaults.MaxTokens, 1))
-		preflight, preflightErr := h.engine.budgetGuard.Preflight(turnCtx, settings, scopeLimits, plannedTokens)
-		if preflightErr != nil {
+		providerClient, model := h.engine.currentProviderModel()
+		response, preflight, chatErr := h.engine.chatWithRetry(turnCtx, cfg, providerClient, providerCall{
+			settings: settings,
+			scopes:   scopeLimits,
+			planned:  uint64(max(cfg.Agents.Defaults.MaxTokens, 1)),
+			req: provider.ChatRequest{
+				Messages:      messages,
+				Tools:         registry.Definitions(),
+				Model:         model,
+				MaxTokens:     cfg.Agents.Defaults.MaxTokens,
+				Temperature:   cfg.Agents.Defaults.Temperature,
+				PromptCaching: cfg.Agents.Defaults.PromptCaching,
+			},
+			turnSlot: true,
+		})
+		h.engine.recordUsageTokens(response.Usage)
+		if chatErr != nil {
 			var limitErr *budget.LimitError
-			if errors.As(preflightErr, &limitErr) {
+			if errors.As(chatErr, &limitErr) {
 				finalContent = h.engine.formatBudgetLimitMessage(limitErr)
 				break
 			}
-			return "", preflightErr
-		}
-		h.engine.metrics.ProviderCalls.Add(1)
-		providerClient, model := h.engine.currentProviderModel()
-		response, chatErr := providerClient.Chat(turnCtx, provider.ChatRequest{
-			Messages:    messages,
-			Tools:       registry.Definitions(),
-			Model:       model,
-			MaxTokens:   cfg.Agents.Defaults.MaxTokens,
-			Temperature: cfg.Agents.Defaults.Temperature,
-		})
-		if chatErr != nil {
-			h.engine.budgetGuard.Abort(turnCtx, preflight)
-			h.engine.metrics.ProviderErrors.Add(1)
+			if turnDeadlineExceeded(ctx, turnCtx) {
+				timedOut = true
+				break
+			}
 			return "", chatErr
 		}
 		commit, commitErr := h.engine.budgetGuard.Commit(turnCtx, settings, scopeLimits, preflight, budget.Usage{
@@ -550,10 +706,12 @@ func (h *sessionHandler) process(ctx context.Context, msg InboundMessage) (strin
 		if commitErr != nil {
 			h.engine.log.Printf("failed to commit token budget usage: %v", commitErr)
 		}
-		h.engine.recordUsageDay(turnCtx,
+		h.engine.recordUsageDay(turnCtx, response.Model,
 			uint64(max(response.Usage.PromptTokens, 0)),
 			uint64(max(response.Usage.CompletionTokens, 0)),
 			commit.TotalTokens,
+			uint64(max(response.Usage.CacheReadTokens, 0)),
+			uint64(max(response.Usage.CacheWriteTokens, 0)),
 		)
 		for _, warning := range commit.Warnings {
 			budgetWarnings = append(budgetWarnings,
@@ -561,19 +719,37 @@ func (h *sessionHandler) process(ctx context.Context, msg InboundMessage) (strin
 		}
 
 		if response.HasToolCalls() {
+			if content := strings.TrimSpace(response.Content); content != "" {
+				lastContent = content
+			}
+			pendingTools = pendingTools[:0]
+			for _, tc := range response.ToolCalls {
+				pendingTools = append(pendingTools, tc.Name)
+			}
 			messages = append(messages, provider.Message{Role: "assistant", Content: response.Content, ToolCalls: response.ToolCalls})
 			for _, tc := range response.ToolCalls {
 				h.engine.metrics.ToolCalls.Add(1)
-				toolTimeout := time.Duration(cfg.Agents.Defaults.ToolTimeoutSec) * time.Second
-				if toolTimeout <= 0 {
-					toolTimeout = 60 * time.Second
-				}
-				toolCtx, toolCancel := context.WithTimeout(turnCtx, toolTimeout)
-				result, toolErr := registry.Execute(toolCtx, tc.Name, tc.Arguments)
-				toolCancel()
-				if toolErr != nil {
-					h.engine.metrics.ToolErrors.Add(1)
-					result = tools.ToolResult{Text: toolErr.Error()}
+				result, allowed := h.engine.consumeToolQuota(turnCtx, cfg, tc.Name)
+				if allowed {
+					emitToolEvent(turnCtx, sink, StreamEvent{
+						Type:       "tool_call_started",
+						ToolName:   tc.Name,
+						ToolCallID: tc.ID,
+						Metadata:   map[string]any{"arguments": truncateText(string(tc.Arguments), toolEventPreviewChars)},
+					})
+					toolCtx, toolCancel := context.WithTimeout(turnCtx, toolTimeout(cfg, tc.Name))
+					var toolErr error
+					result, toolErr = registry.Execute(toolCtx, tc.Name, tc.Arguments)
+					toolCancel()
+					end := StreamEvent{Type: "tool_call_end", ToolName: tc.Name, ToolCallID: tc.ID}
+					if toolErr != nil {
+						h.engine.metrics.ToolErrors.Add(1)
+						h.engine.recordToolArgumentError(toolErr)
+						result = tools.ToolResult{Text: toolErr.Error()}
+						end.Error = toolErr.Error()
+					}
+					end.Content = truncateText(result.Text, toolEventPreviewChars)
+					emitToolEvent(turnCtx, sink, end)
 				}
 				toolMeta := map[string]any{"trace_id": traceID}
 				for key, value := range result.Metadata {
@@ -605,12 +781,21 @@ func (h *sessionHandler) process(ctx context.Context, msg InboundMessage) (strin
 		break
 	}
 
+	if timedOut {
+		h.engine.log.Printf("event=turn_timeout session_id=%s trace_id=%s timeout=%s", h.sessionID, traceID, turnTimeout)
+		finalContent = partialTurnContent(lastContent, pendingTools, msg.Content)
+		// The turn context is already expired; persist the partial result on a fresh deadline.
+		var persistCancel context.CancelFunc
+		turnCtx, persistCancel = context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		defer persistCancel()
+	}
 	if strings.TrimSpace(finalContent) == "" {
 		finalContent = "I've completed processing but have no response to provide."
 	}
 	if len(budgetWarnings) > 0 {
 		finalContent = strings.TrimSpace(finalContent) + "\n\n[Token safety]\n- " + strings.Join(budgetWarnings, "\n- ")
 	}
+	finalContent = applyPostProcessors(finalContent, postProcessorsFor(cfg.Agents.PostProcessors, msg.Channel))
 
 	if err := h.engine.store.AppendTurn(turnCtx, Turn{SessionID: h.sessionID, Role: "user", Content: msg.Content}); err != nil {
 		h.engine.log.Printf("failed to persist user turn: %v", err)
@@ -620,13 +805,108 @@ func (h *sessionHandler) process(ctx context.Context, msg InboundMessage) (strin
 	}
 	_ = h.engine.store.SaveSessionMeta(turnCtx, h.sessionID, map[string]interface{}{"last_channel": msg.Channel, "last_chat_id": msg.ChatID})
 
-	if msg.Channel != "cli" {
-		h.engine.send(msg.Channel, msg.ChatID, finalContent, map[string]interface{}{"session_id": msg.SessionID, "trace_id": traceID})
+	if !IsReservedChannel(msg.Channel) {
+		h.engine.sendReply(msg.Channel, msg.ChatID, finalContent, map[string]interface{}{"session_id": msg.SessionID, "trace_id": traceID})
+	}
+	if !isInternalChannel(msg.Channel) {
+		h.engine.ensureSessionTitle(turnCtx, h.sessionID, msg.Content)
 	}
 	h.engine.appendDailyMemory(turnCtx, msg, finalContent)
 	return finalContent, nil
 }
 
+// webSearchOptions resolves the web_search backend settings. An unknown
+// provider falls back to brave and is logged once per name.
+func (e *Engine) webSearchOptions(cfg config.Config) tools.WebSearchOptions {
+	search := cfg.Tools.Web.Search
+	name := tools.NormalizeSearchProvider(search.Provider)
+	if !tools.KnownSearchProvider(search.Provider) {
+		if _, logged := e.searchFallbacks.LoadOrStore(search.Provider, struct{}{}); !logged {
+			e.log.Printf("WARNING event=web_search_fallback provider=%q fallback=%s; set tools.web.search.provider to one of %s", search.Provider, name, strings.Join(tools.SearchProviders, ", "))
+		}
+	}
+	opts := tools.WebSearchOptions{
+		Provider:   name,
+		APIKey:     search.APIKey,
+		BaseURL:    search.BaseURL,
+		MaxResults: search.MaxResults,
+	}
+	for key, entry := range search.Providers {
+		if tools.NormalizeSearchProvider(key) != name || !tools.KnownSearchProvider(key) {
+			continue
+		}
+		if strings.TrimSpace(entry.APIKey) != "" {
+			opts.APIKey = entry.APIKey
+		}
+		if strings.TrimSpace(entry.BaseURL) != "" {
+			opts.BaseURL = entry.BaseURL
+		}
+	}
+	return opts
+}
+
+func webFetchPolicy(cfg config.Config) tools.WebFetchPolicy {
+	return tools.WebFetchPolicy{
+		AllowHosts:      cfg.Tools.Web.Fetch.AllowHosts,
+		DenyHosts:       cfg.Tools.Web.Fetch.DenyHosts,
+		BlockPrivateIPs: cfg.Tools.Web.Fetch.BlockPrivateIPs,
+	}
+}
+
+// acquireTurnSlot bounds concurrent provider calls across sessions when
+// runtime.maxConcurrentTurns is set, queuing callers until a slot frees up.
+func (e *Engine) acquireTurnSlot(ctx context.Context) (func(), error) {
+	if e.turnSlots == nil {
+		return func() {}, nil
+	}
+	release := func() { <-e.turnSlots }
+	select {
+	case e.turnSlots <- struct{}{}:
+		return release, nil
+	default:
+	}
+	e.metrics.TurnsQueued.Add(1)
+	e.metrics.TurnsWaiting.Add(1)
+	defer e.metrics.TurnsWaiting.Add(-1)
+	select {
+	case e.turnSlots <- struct{}{}:
+		return release, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// toolTimeout returns the per-tool override from tools.timeouts, falling back
+// to agents.defaults.toolTimeoutSec.
+func toolTimeout(cfg config.Config, name string) time.Duration {
+	if seconds, ok := cfg.Tools.Timeouts[name]; ok && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if cfg.Agents.Defaults.ToolTimeoutSec > 0 {
+		return time.Duration(cfg.Agents.Defaults.ToolTimeoutSec) * time.Second
+	}
+	return 60 * time.Second
+}
+
+func turnDeadlineExceeded(parent, turnCtx context.Context) bool {
+	return parent.Err() == nil && errors.Is(turnCtx.Err(), context.DeadlineExceeded)
+}
+
+func partialTurnContent(lastContent string, pendingTools []string, request string) string {
+	lastContent = strings.TrimSpace(lastContent)
+	if lastContent != "" {
+		return lastContent + "\n\n[Timed out before finishing; the result above may be incomplete.]"
+	}
+	task := strings.TrimSpace(request)
+	if runes := []rune(task); len(runes) > 120 {
+		task = string(runes[:117]) + "..."
+	}
+	if len(pendingTools) > 0 {
+		return fmt.Sprintf("I timed out while working on %q (last step: %s). Please retry or narrow the request.", task, strings.Join(pendingTools, ", "))
+	}
+	return fmt.Sprintf("I timed out while working on %q. Please retry or narrow the request.", task)
+}
+
 func (e *Engine) appendDailyMemory(ctx context.Context, msg InboundMessage, response string) {
 	if e.memory == nil || !e.memory.Enabled() {
 		return
@@ -652,7 +932,7 @@ func (e *Engine) appendDailyMemory(ctx context.Context, msg InboundMessage, resp
 	}
 }
 
-func (e *Engine) RecordHeartbeat(ctx context.Context, prompt, response string) {
+func (e *Engine) RecordHeartbeat(ctx context.Context, sessionID, prompt, response string) {
 	if e.memory == nil || !e.memory.Enabled() {
 		return
 	}
@@ -667,7 +947,7 @@ func (e *Engine) RecordHeartbeat(ctx context.Context, prompt, response string) {
 	if err := e.memory.AppendDailyLog(ctx, memory.DailyEntry{
 		Time:      time.Now().UTC(),
 		Source:    "heartbeat",
-		SessionID: "system:heartbeat",
+		SessionID: sessionID,
 		Intent:    intent,
 		Outcome:   outcome,
 		FollowUp:  suggestsFollowUp(response),
@@ -676,6 +956,99 @@ func (e *Engine) RecordHeartbeat(ctx context.Context, prompt, response string) {
 	}
 }
 
+// SearchMemory queries the memory index with per-result score components.
+// It returns nothing when memory is disabled.
+func (e *Engine) SearchMemory(ctx context.Context, query string, limit int) ([]memory.SearchResult, error) {
+	if e.memory == nil || !e.memory.Enabled() {
+		return nil, nil
+	}
+	return e.memory.SearchExplain(ctx, query, limit)
+}
+
+// RollupDailyMemory digests completed daily logs into MEMORY.md using the
+// active provider.
+func (e *Engine) RollupDailyMemory(ctx context.Context) (memory.RollupResult, error) {
+	if e.memory == nil || !e.memory.Enabled() {
+		return memory.RollupResult{}, nil
+	}
+	return e.memory.RollupDaily(ctx, func(ctx context.Context, day, content string) (string, error) {
+		resp, err := e.chatInternal(ctx, "", provider.ChatRequest{
+			Messages: []provider.Message{
+				{Role: "system", Content: "You condense an assistant's daily activity log into a digest for long-term memory. Reply with 3-6 short bullet points covering decisions, user preferences, completed work, and open follow-ups. Omit greetings and routine chatter."},
+				{Role: "user", Content: "Daily log for " + day + ":\n\n" + content},
+			},
+			MaxTokens:   512,
+			Temperature: 0.2,
+		})
+		if err != nil {
+			return "", err
+		}
+		return resp.Content, nil
+	})
+}
+
+// ReapSubagentArtifacts removes artifact directories of subagent and
+// federated runs that finished more than
+// Runtime.Subagents.ArtifactRetentionDays ago. A retention of zero or less
+// leaves existing artifacts alone.
+func (e *Engine) ReapSubagentArtifacts(ctx context.Context, now time.Time) (int, error) {
+	cfg := e.currentConfig()
+	days := cfg.Runtime.Subagents.ArtifactRetentionDays
+	if days <= 0 {
+		return 0, nil
+	}
+	cutoff := now.Add(-time.Duration(days) * 24 * time.Hour)
+	count := 0
+	if e.subagents != nil {
+		root := filepath.Join(config.WorkspacePath(cfg), ".squidbot", "subagents")
+		reaped, err := e.subagents.ReapArtifacts(ctx, root, cutoff)
+		count += len(reaped)
+		if err != nil {
+			return count, err
+		}
+	}
+	reaped, err := e.reapFederationArtifacts(ctx, cfg, cutoff)
+	return count + reaped, err
+}
+
+// reapFederationArtifacts removes the artifact directories of terminal
+// federated runs that finished before cutoff.
+func (e *Engine) reapFederationArtifacts(ctx context.Context, cfg config.Config, cutoff time.Time) (int, error) {
+	if e.store == nil {
+		return 0, nil
+	}
+	root := filepath.Join(config.WorkspacePath(cfg), ".squidbot", "federation")
+	if _, err := os.Stat(root); err != nil {
+		return 0, nil
+	}
+	runs, err := e.store.ListFederationRuns(ctx, "", "", 0)
+	if err != nil {
+		return 0, err
+	}
+	reaped := 0
+	for _, run := range runs {
+		if !run.Status.Terminal() || strings.TrimSpace(run.ID) == "" {
+			continue
+		}
+		finished := run.CreatedAt
+		if run.FinishedAt != nil {
+			finished = *run.FinishedAt
+		}
+		if !finished.Before(cutoff) {
+			continue
+		}
+		dir := filepath.Join(root, filepath.Base(run.ID))
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			return reaped, err
+		}
+		reaped++
+	}
+	return reaped, nil
+}
+
 func suggestsFollowUp(content string) bool {
 	lower := strings.ToLower(content)
 	markers := []string{
@@ -704,19 +1077,19 @@ func (e *Engine) buildRegistry(msg InboundMessage) (*tools.Registry, error) {
 		registry.Register(tools.NewEditFileTool(e.policy))
 	}
 	registry.Register(tools.NewListDirTool(e.policy))
-	registry.Register(tools.NewExecToolWithPolicy(e.policy, time.Duration(cfg.Agents.Defaults.ToolTimeoutSec)*time.Second, tools.ExecPolicy{
+	registry.Register(tools.NewExecToolWithPolicy(e.policy, toolTimeout(cfg, "exec"), tools.ExecPolicy{
 		Enabled:         cfg.Tools.Exec.Enabled,
 		AllowedCommands: cfg.Tools.Exec.AllowedCommands,
 		BlockedCommands: cfg.Tools.Exec.BlockedCommands,
 	}))
-	registry.Register(tools.NewWebSearchTool(cfg.Tools.Web.Search.APIKey, cfg.Tools.Web.Search.MaxResults))
-	registry.Register(tools.NewWebFetchTool(50000))
+	registry.Register(tools.NewWebSearchToolWithOptions(e.webSearchOptions(cfg)))
+	registry.Register(tools.NewWebFetchToolWithPolicy(50000, webFetchPolicy(cfg)))
 
-	messageTool := tools.NewMessageTool(func(ctx context.Context, channel, chatID, content string) error {
+	messageTool := tools.NewMessageToolWithTargets(func(ctx context.Context, channel, chatID, content string) error {
 		traceID, _ := msg.Metadata["trace_id"].(string)
 		e.send(channel, chatID, content, map[string]interface{}{"session_id": msg.SessionID, "source": "tool:message", "trace_id": traceID})
 		return nil
-	})
+	}, cfg.Tools.Message.AllowTargets)
 	messageTool.SetContext(msg.Channel, msg.ChatID, msg.SessionID)
 	registry.Register(messageTool)
 
@@ -739,6 +1112,10 @@ func (e *Engine) buildRegistry(msg InboundMessage) (*tools.Registry, error) {
 	cancelTool.SetContext(msg.SessionID)
 	registry.Register(cancelTool)
 
+	searchHistoryTool := tools.NewSearchHistoryTool(e.searchHistory)
+	searchHistoryTool.SetContext(msg.SessionID)
+	registry.Register(searchHistoryTool)
+
 	federationPeersTool := tools.NewFederationPeersTool(func(ctx context.Context, req tools.FederationPeersRequest) (tools.FederationPeersResponse, error) {
 		peers, err := e.federationPeersStatus(ctx)
 		if err != nil {
@@ -768,6 +1145,10 @@ func (e *Engine) buildRegistry(msg InboundMessage) (*tools.Registry, error) {
 	budgetSetEstimationTool.SetContext(msg.SessionID, msg.Channel, msg.SenderID)
 	registry.Register(budgetSetEstimationTool)
 
+	budgetResetTool := tools.NewBudgetResetTool(e.budgetReset)
+	budgetResetTool.SetContext(msg.SessionID, msg.Channel, msg.SenderID)
+	registry.Register(budgetResetTool)
+
 	createTaskTool := tools.NewCreateTaskTool(e.createMissionTask)
 	createTaskTool.SetContext(msg.SessionID, msg.Channel, msg.ChatID, msg.RequestID, msg.SenderID)
 	registry.Register(createTaskTool)
@@ -794,9 +1175,27 @@ func (e *Engine) buildRegistry(msg InboundMessage) (*tools.Registry, error) {
 		}
 	}
 
+	if SessionToolsLocked(context.Background(), e.store, msg.SessionID) {
+		registry = readOnlyRegistry(registry)
+	}
+	if e.dryTools.Load() {
+		registry = e.stubRegistry(registry)
+	}
 	return registry, nil
 }
 
+// ToolDefinitions returns the effective tool surface for a message, sorted by
+// name, as the model would see it.
+func (e *Engine) ToolDefinitions(msg InboundMessage) ([]provider.ToolDefinition, error) {
+	registry, err := e.buildRegistry(msg)
+	if err != nil {
+		return nil, err
+	}
+	defs := registry.Definitions()
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Name < defs[j].Name })
+	return defs, nil
+}
+
 func subagentDepthFromMetadata(metadata map[string]any) int {
 	if len(metadata) == 0 {
 		return 0
@@ -851,42 +1250,8 @@ func (e *Engine) spawnLocalSubtask(ctx context.Context, req tools.SpawnRequest)
 	if e.subagents == nil {
 		return tools.SpawnResponse{}, fmt.Errorf("subagent manager is not configured")
 	}
-	cfg := e.currentConfig()
-	workspace := config.WorkspacePath(cfg)
-	taskID := e.nextID()
-	label := strings.TrimSpace(req.Label)
-	if label == "" {
-		label = req.Task
-		if len(label) > 40 {
-			label = label[:40] + "..."
-		}
-	}
-
-	packet, err := e.buildSubagentContextPacket(ctx, req)
-	if err != nil {
-		return tools.SpawnResponse{}, err
-	}
-	artifactDir := filepath.Join(workspace, ".squidbot", "subagents", taskID)
-	if err := os.MkdirAll(artifactDir, 0o755); err != nil {
-		return tools.SpawnResponse{}, err
-	}
-	run, err := e.subagents.Enqueue(ctx, subagent.Request{
-		ID:               taskID,
-		SessionID:        req.SessionID,
-		Channel:          req.Channel,
-		ChatID:           req.ChatID,
-		SenderID:         req.SenderID,
-		Task:             req.Task,
-		Label:            label,
-		ContextMode:      req.ContextMode,
-		Attachments:      req.Attachments,
-		TimeoutSec:       req.TimeoutSec,
-		MaxAttempts:      req.MaxAttempts,
-		Depth:            req.Depth + 1,
-		NotifyOnComplete: cfg.Runtime.Subagents.NotifyOnComplete,
-		ArtifactDir:      artifactDir,
-		Context:          packet,
-	})
+	label := subtaskLabel(req)
+	run, err := e.enqueueLocalSubtask(ctx, req, e.currentConfig().Runtime.Subagents.NotifyOnComplete, false)
 	if err != nil {
 		return tools.SpawnResponse{}, err
 	}
@@ -918,6 +1283,72 @@ func (e *Engine) spawnLocalSubtask(ctx context.Context, req tools.SpawnRequest)
 	}, nil
 }
 
+// enqueueLocalSubtask builds the context packet and artifact dir for req and
+// enqueues it one level below req.Depth.
+func (e *Engine) enqueueLocalSubtask(ctx context.Context, req tools.SpawnRequest, notify, recurring bool) (subagent.Run, error) {
+	cfg := e.currentConfig()
+	taskID := e.nextID()
+	packet, err := e.buildSubagentContextPacket(ctx, req)
+	if err != nil {
+		return subagent.Run{}, err
+	}
+	artifactDir, err := subagentArtifactDir(cfg, taskID)
+	if err != nil {
+		return subagent.Run{}, err
+	}
+	return e.subagents.Enqueue(ctx, subagent.Request{
+		ID:               taskID,
+		SessionID:        req.SessionID,
+		Channel:          req.Channel,
+		ChatID:           req.ChatID,
+		SenderID:         req.SenderID,
+		Task:             req.Task,
+		Label:            subtaskLabel(req),
+		ContextMode:      packet.Mode,
+		Attachments:      req.Attachments,
+		TimeoutSec:       req.TimeoutSec,
+		MaxAttempts:      req.MaxAttempts,
+		Depth:            req.Depth + 1,
+		NotifyOnComplete: notify,
+		ArtifactDir:      artifactDir,
+		Context:          packet,
+		Recurring:        recurring,
+	})
+}
+
+func subtaskLabel(req tools.SpawnRequest) string {
+	label := strings.TrimSpace(req.Label)
+	if label == "" {
+		label = req.Task
+		if len(label) > 40 {
+			label = label[:40] + "..."
+		}
+	}
+	return label
+}
+
+// SpawnScheduledSubagent enqueues a cron job's task as a background subagent
+// run in the job's cron session. When channel and chatID are both set the run
+// reports its result there on completion.
+func (e *Engine) SpawnScheduledSubagent(ctx context.Context, jobID, jobName, task string, timeoutSec int, channel, chatID string) (subagent.Run, error) {
+	if e.subagents == nil {
+		return subagent.Run{}, fmt.Errorf("subagent manager is not configured")
+	}
+	notify := strings.TrimSpace(channel) != "" && strings.TrimSpace(chatID) != ""
+	if !notify {
+		channel, chatID = ChannelCron, jobID
+	}
+	return e.enqueueLocalSubtask(ctx, tools.SpawnRequest{
+		Task:       task,
+		Label:      jobName,
+		TimeoutSec: timeoutSec,
+		SessionID:  "cron:" + jobID,
+		Channel:    channel,
+		ChatID:     chatID,
+		SenderID:   "cron",
+	}, notify, true)
+}
+
 func (e *Engine) waitSubtasks(ctx context.Context, req tools.SubagentWaitRequest) (tools.SubagentWaitResponse, error) {
 	if e.subagents == nil {
 		return tools.SubagentWaitResponse{}, fmt.Errorf("subagent manager is not configured")
@@ -953,6 +1384,21 @@ func (e *Engine) statusSubtask(ctx context.Context, req tools.SubagentStatusRequ
 	return tools.SubagentStatusResponse{Run: run}, nil
 }
 
+func (e *Engine) searchHistory(ctx context.Context, req tools.SearchHistoryRequest) ([]tools.HistoryMatch, error) {
+	if strings.TrimSpace(req.SessionID) == "" {
+		return nil, fmt.Errorf("session is required")
+	}
+	turns, err := e.store.SearchTurns(ctx, req.SessionID, req.Query, req.Limit)
+	if err != nil {
+		return nil, err
+	}
+	matches := make([]tools.HistoryMatch, 0, len(turns))
+	for _, turn := range turns {
+		matches = append(matches, tools.HistoryMatch{Role: turn.Role, Content: turn.Content, CreatedAt: turn.CreatedAt})
+	}
+	return matches, nil
+}
+
 func (e *Engine) resultSubtask(ctx context.Context, req tools.SubagentResultRequest) (tools.SubagentResultResponse, error) {
 	if e.subagents == nil {
 		return tools.SubagentResultResponse{}, fmt.Errorf("subagent manager is not configured")
@@ -972,6 +1418,10 @@ func (e *Engine) resultSubtask(ctx context.Context, req tools.SubagentResultRequ
 		result.Summary = run.Result.Summary
 		result.Output = run.Result.Output
 		result.ArtifactPaths = run.Result.ArtifactPaths
+	} else if path := subagent.TranscriptPath(run); path != "" {
+		if _, err := os.Stat(path); err == nil {
+			result.ArtifactPaths = []string{path}
+		}
 	}
 	return result, nil
 }
@@ -994,6 +1444,57 @@ func (e *Engine) cancelSubtask(ctx context.Context, req tools.SubagentCancelRequ
 	return tools.SubagentCancelResponse{RunID: run.ID, Status: string(run.Status)}, nil
 }
 
+// RetrySubagentRun re-enqueues a failed or timed out subagent run under a new
+// run ID. When sessionID is set the run must belong to that session.
+func (e *Engine) RetrySubagentRun(ctx context.Context, runID, sessionID string) (subagent.Run, error) {
+	if e.subagents == nil {
+		return subagent.Run{}, fmt.Errorf("subagent manager is not configured")
+	}
+	original, err := e.subagents.Status(ctx, runID)
+	if err != nil {
+		return subagent.Run{}, err
+	}
+	if err := ensureSubagentRunAccess(sessionID, original); err != nil {
+		return subagent.Run{}, err
+	}
+	taskID := e.nextID()
+	artifactDir, err := subagentArtifactDir(e.currentConfig(), taskID)
+	if err != nil {
+		return subagent.Run{}, err
+	}
+	return e.subagents.Retry(ctx, original, taskID, artifactDir)
+}
+
+// WaitSubagentRun blocks until the run finishes or timeout elapses.
+func (e *Engine) WaitSubagentRun(ctx context.Context, runID string, timeout time.Duration) (subagent.Run, error) {
+	if e.subagents == nil {
+		return subagent.Run{}, fmt.Errorf("subagent manager is not configured")
+	}
+	runs, err := e.subagents.Wait(ctx, []string{runID}, timeout)
+	if len(runs) == 0 {
+		return subagent.Run{}, err
+	}
+	return runs[0], err
+}
+
+func subagentArtifactDir(cfg config.Config, taskID string) (string, error) {
+	return runArtifactDir(cfg, "subagents", taskID)
+}
+
+// runArtifactDir creates workspace/.squidbot/<kind>/<id>. It returns "" when
+// Runtime.Subagents.ArtifactRetentionDays is zero, which disables artifacts
+// for subagent and federated runs alike.
+func runArtifactDir(cfg config.Config, kind, id string) (string, error) {
+	if cfg.Runtime.Subagents.ArtifactRetentionDays == 0 {
+		return "", nil
+	}
+	dir := filepath.Join(config.WorkspacePath(cfg), ".squidbot", kind, id)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
 func ensureSubagentRunAccess(sessionID string, run subagent.Run) error {
 	sessionID = strings.TrimSpace(sessionID)
 	if sessionID == "" {
@@ -1104,10 +1605,46 @@ func (e *Engine) budgetSetEstimation(ctx context.Context, req tools.BudgetSetEst
 	return tools.BudgetSetEstimationResponse{Settings: settings}, nil
 }
 
+func (e *Engine) budgetReset(ctx context.Context, req tools.BudgetResetRequest) (tools.BudgetResetResponse, error) {
+	if err := e.assertTrustedBudgetWriter(ctx, req.Channel, req.SenderID); err != nil {
+		return tools.BudgetResetResponse{}, err
+	}
+	result, err := e.store.ResetBudgetCounter(ctx, req.Scope)
+	if err != nil {
+		return tools.BudgetResetResponse{}, err
+	}
+	e.log.Printf("event=budget_counter_reset scope=%s channel=%s sender_id=%s previous_total=%d cleared_reservations=%d", result.Scope, req.Channel, req.SenderID, result.Previous.TotalTokens, result.ClearedReservations)
+	return tools.BudgetResetResponse{Result: result}, nil
+}
+
+// TokenSafetySettings returns the effective token safety settings: the
+// stored override when one exists, otherwise runtime.tokenSafety.
+func (e *Engine) TokenSafetySettings(ctx context.Context) budget.Settings {
+	return e.effectiveTokenSafety(ctx)
+}
+
+// BudgetStatus reports the global counter and, when given, the counters of
+// one session and one subagent run against the effective limits.
+func (e *Engine) BudgetStatus(ctx context.Context, sessionID, runID string) (tools.BudgetStatusResponse, error) {
+	return e.budgetStatus(ctx, tools.BudgetStatusRequest{SessionID: sessionID, RunID: runID})
+}
+
+// UpdateTokenSafety applies mutate to the effective settings and stores the
+// result as the override, like the budget CLI. It skips the trusted-writer
+// check, so callers must authorize the request themselves; the manage API
+// only calls it when a manage token is configured.
+func (e *Engine) UpdateTokenSafety(ctx context.Context, mutate func(current *budget.Settings) error) (budget.Settings, error) {
+	return e.storeTokenSafetySettings(ctx, mutate)
+}
+
 func (e *Engine) updateTokenSafetySettings(ctx context.Context, channel, senderID string, mutate func(current *budget.Settings) error) (budget.Settings, error) {
 	if err := e.assertTrustedBudgetWriter(ctx, channel, senderID); err != nil {
 		return budget.Settings{}, err
 	}
+	return e.storeTokenSafetySettings(ctx, mutate)
+}
+
+func (e *Engine) storeTokenSafetySettings(ctx context.Context, mutate func(current *budget.Settings) error) (budget.Settings, error) {
 	current := e.effectiveTokenSafety(ctx)
 	if mutate != nil {
 		if err := mutate(&current); err != nil {
@@ -1224,11 +1761,11 @@ func tokenScopeLimit(scope string, settings budget.Settings) (hard uint64, soft
 }
 
 func (e *Engine) buildSubagentContextPacket(ctx context.Context, req tools.SpawnRequest) (subagent.ContextPacket, error) {
+	cfg := e.currentConfig()
 	mode := req.ContextMode
 	if mode == "" {
-		mode = subagent.ContextModeMinimal
+		mode = subagent.NormalizeContextMode(cfg.Runtime.Subagents.DefaultContextMode)
 	}
-	cfg := e.currentConfig()
 	workspace := config.WorkspacePath(cfg)
 	skillActivation, skillErr := e.activateSkills(ctx, req.Task, req.Channel, req.SessionID, true, nil)
 	if skillErr != nil {
@@ -1243,7 +1780,7 @@ func (e *Engine) buildSubagentContextPacket(ctx context.Context, req tools.Spawn
 		packet.SystemPrompt += " Use the supplied parent session context."
 	}
 	if mode == subagent.ContextModeSessionMemory {
-		packet.SystemPrompt = buildSystemPromptWithSkills(cfg, req.Task, &skillActivation)
+		packet.SystemPrompt = buildSystemPromptWithSkills(cfg, e.memory, e.promptContext(req.Channel, req.ChatID, req.SessionID), req.Task, &skillActivation)
 	} else if section := renderSkillContractsSection(cfg, workspace, &skillActivation); strings.TrimSpace(section) != "" {
 		packet.SystemPrompt = strings.TrimSpace(packet.SystemPrompt) + "\n\n" + section
 	}
@@ -1259,6 +1796,9 @@ func (e *Engine) buildSubagentContextPacket(ctx context.Context, req tools.Spawn
 			return subagent.ContextPacket{}, err
 		}
 		packet.Attachments = append(packet.Attachments, resolved)
+		if content, ok := readInlineAttachment(resolved, cfg.Runtime.Subagents.InlineAttachmentMaxBytes); ok {
+			packet.InlineAttachments = append(packet.InlineAttachments, subagent.InlineAttachment{Path: resolved, Content: content})
+		}
 	}
 	if mode == subagent.ContextModeSessionMemory && e.memory != nil && e.memory.Enabled() {
 		chunks, err := e.memory.Search(ctx, req.Task, minInt(6, cfg.Memory.TopK))
@@ -1269,18 +1809,47 @@ func (e *Engine) buildSubagentContextPacket(ctx context.Context, req tools.Spawn
 		}
 	}
 	checksumPayload, _ := json.Marshal(map[string]any{
-		"mode":            packet.Mode,
-		"system_prompt":   packet.SystemPrompt,
-		"history_len":     len(packet.History),
-		"memory_snippets": packet.MemorySnippets,
-		"attachments":     packet.Attachments,
-		"task":            strings.TrimSpace(req.Task),
+		"mode":               packet.Mode,
+		"system_prompt":      packet.SystemPrompt,
+		"history_len":        len(packet.History),
+		"memory_snippets":    packet.MemorySnippets,
+		"attachments":        packet.Attachments,
+		"inline_attachments": packet.InlineAttachments,
+		"task":               strings.TrimSpace(req.Task),
 	})
 	sum := sha1.Sum(checksumPayload)
 	packet.Checksum = hex.EncodeToString(sum[:])
 	return packet, nil
 }
 
+// relaySubagentProgress sends a condensed progress line to the chat that
+// spawned the run.
+func (e *Engine) relaySubagentProgress(run subagent.Run, progress subagent.Progress) {
+	if strings.TrimSpace(run.Channel) == "" || strings.TrimSpace(run.ChatID) == "" {
+		return
+	}
+	if e.currentConfig().SuppressesSubagentNotifications(run.Channel) {
+		return
+	}
+	label := strings.TrimSpace(run.Label)
+	if label == "" {
+		label = truncateText(run.Task, 60)
+	}
+	status := progress.Status
+	if progress.Tool != "" {
+		status = status + ": " + progress.Tool
+	}
+	content := fmt.Sprintf("[Subagent %s] step %d/%d, %s", label, progress.Hop, progress.MaxHops, status)
+	e.send(run.Channel, run.ChatID, content, map[string]interface{}{
+		"session_id":     run.SessionID,
+		"source":         "subagent_progress",
+		"run_id":         run.ID,
+		"hop":            progress.Hop,
+		"tool":           progress.Tool,
+		"subagent_depth": run.Depth,
+	})
+}
+
 func (e *Engine) notifySubagentCompletion(run subagent.Run) {
 	if strings.TrimSpace(run.Channel) == "" || strings.TrimSpace(run.ChatID) == "" {
 		return
@@ -1299,13 +1868,19 @@ func (e *Engine) notifySubagentCompletion(run subagent.Run) {
 	if strings.TrimSpace(run.Error) != "" {
 		lines = append(lines, "", "Error:", run.Error)
 	}
-	e.send(run.Channel, run.ChatID, strings.Join(lines, "\n"), map[string]interface{}{
-		"session_id":     run.SessionID,
-		"source":         "subagent",
-		"run_id":         run.ID,
-		"status":         run.Status,
-		"subagent_depth": run.Depth,
-	})
+	// Suppressed channels still keep the run record, and reinjection below
+	// lets the parent turn pick up the result.
+	if cfg.SuppressesSubagentNotifications(run.Channel) {
+		e.metrics.SubagentNotifySuppressed.Add(1)
+	} else {
+		e.send(run.Channel, run.ChatID, strings.Join(lines, "\n"), map[string]interface{}{
+			"session_id":     run.SessionID,
+			"source":         "subagent",
+			"run_id":         run.ID,
+			"status":         run.Status,
+			"subagent_depth": run.Depth,
+		})
+	}
 	if cfg.Runtime.Subagents.ReinjectCompletion {
 		_, err := e.Submit(context.Background(), InboundMessage{
 			RequestID: e.nextID(),
@@ -1328,7 +1903,7 @@ func (e *Engine) notifySubagentCompletion(run subagent.Run) {
 	}
 }
 
-func (e *Engine) runSubtask(ctx context.Context, run subagent.Run) (subagent.Result, error) {
+func (e *Engine) runSubtask(ctx context.Context, run subagent.Run) (result subagent.Result, err error) {
 	cfg := e.currentConfig()
 	activation, activationErr := e.activateSkills(ctx, run.Task, run.Channel, run.SessionID, true, nil)
 	if activationErr != nil {
@@ -1351,10 +1926,23 @@ func (e *Engine) runSubtask(ctx context.Context, run subagent.Run) (subagent.Res
 	if len(run.Context.MemorySnippets) > 0 {
 		messages = append(messages, provider.Message{Role: "user", Content: "Relevant memory:\n- " + strings.Join(run.Context.MemorySnippets, "\n- ")})
 	}
-	if len(run.Context.Attachments) > 0 {
-		messages = append(messages, provider.Message{Role: "user", Content: "Attachment paths available in workspace:\n- " + strings.Join(run.Context.Attachments, "\n- ")})
-	}
+	messages = append(messages, subagentAttachmentMessages(run.Context, cfg.Runtime.Subagents.InlineAttachmentMaxBytes > 0)...)
 	messages = append(messages, provider.Message{Role: "user", Content: run.Task})
+	// The transcript is written however the run ends, so failed runs can be
+	// inspected without re-running them.
+	defer func() {
+		path := subagent.TranscriptPath(run)
+		if path == "" {
+			return
+		}
+		if writeErr := writeSubagentTranscript(path, messages); writeErr != nil {
+			e.log.Printf("failed to write subagent transcript run_id=%s: %v", run.ID, writeErr)
+			return
+		}
+		if err == nil {
+			result.ArtifactPaths = append(result.ArtifactPaths, path)
+		}
+	}()
 	registry := tools.NewRegistry()
 	registry.Register(tools.NewReadFileTool(e.policy))
 	if cfg.Tools.Filesystem.SubagentWriteEnabled || cfg.Runtime.Subagents.AllowWrites {
@@ -1362,13 +1950,13 @@ func (e *Engine) runSubtask(ctx context.Context, run subagent.Run) (subagent.Res
 		registry.Register(tools.NewEditFileTool(e.policy))
 	}
 	registry.Register(tools.NewListDirTool(e.policy))
-	registry.Register(tools.NewExecToolWithPolicy(e.policy, time.Duration(cfg.Agents.Defaults.ToolTimeoutSec)*time.Second, tools.ExecPolicy{
+	registry.Register(tools.NewExecToolWithPolicy(e.policy, toolTimeout(cfg, "exec"), tools.ExecPolicy{
 		Enabled:         cfg.Tools.Exec.Enabled,
 		AllowedCommands: cfg.Tools.Exec.AllowedCommands,
 		BlockedCommands: cfg.Tools.Exec.BlockedCommands,
 	}))
-	registry.Register(tools.NewWebSearchTool(cfg.Tools.Web.Search.APIKey, cfg.Tools.Web.Search.MaxResults))
-	registry.Register(tools.NewWebFetchTool(30000))
+	registry.Register(tools.NewWebSearchToolWithOptions(e.webSearchOptions(cfg)))
+	registry.Register(tools.NewWebFetchToolWithPolicy(30000, webFetchPolicy(cfg)))
 
 	maxHops := cfg.Agents.Defaults.MaxToolIterations
 	if maxHops <= 0 {
@@ -1377,6 +1965,7 @@ func (e *Engine) runSubtask(ctx context.Context, run subagent.Run) (subagent.Res
 	finalContent := ""
 	budgetWarnings := []string{}
 	for i := 0; i < maxHops; i++ {
+		e.subagents.ReportProgress(run, subagent.Progress{Hop: i + 1, MaxHops: maxHops, Status: "thinking"})
 		settings := e.effectiveTokenSafety(ctx)
 		scopeLimits := []budget.ScopeLimit{
 			{Key: "global", HardLimitTokens: settings.GlobalHardLimitTokens, SoftThresholdPct: settings.GlobalSoftThresholdPct},
@@ -1393,28 +1982,28 @@ func (e *Engine) runSubtask(ctx context.Context, run subagent.Run) (subagent.Res
 			HardLimitTokens:  settings.SubagentRunHardLimitTokens,
 			SoftThresholdPct: settings.SubagentRunSoftThresholdPct,
 		})
-		preflight, preflightErr := e.budgetGuard.Preflight(ctx, settings, scopeLimits, uint64(max(cfg.Agents.Defaults.MaxTokens, 1)))
-		if preflightErr != nil {
-			var limitErr *budget.LimitError
-			if errors.As(preflightErr, &limitErr) {
-				return subagent.Result{}, fmt.Errorf("budget_exhausted: %w", limitErr)
-			}
-			return subagent.Result{}, preflightErr
-		}
-		e.metrics.ProviderCalls.Add(1)
 		providerClient, model := e.currentProviderModel()
-		resp, err := providerClient.Chat(ctx, provider.ChatRequest{
-			Messages:    messages,
-			Tools:       registry.Definitions(),
-			Model:       model,
-			MaxTokens:   cfg.Agents.Defaults.MaxTokens,
-			Temperature: cfg.Agents.Defaults.Temperature,
+		resp, preflight, err := e.chatWithRetry(ctx, cfg, providerClient, providerCall{
+			settings: settings,
+			scopes:   scopeLimits,
+			planned:  uint64(max(cfg.Agents.Defaults.MaxTokens, 1)),
+			req: provider.ChatRequest{
+				Messages:      messages,
+				Tools:         registry.Definitions(),
+				Model:         model,
+				MaxTokens:     cfg.Agents.Defaults.MaxTokens,
+				Temperature:   cfg.Agents.Defaults.Temperature,
+				PromptCaching: cfg.Agents.Defaults.PromptCaching,
+			},
 		})
 		if err != nil {
-			e.budgetGuard.Abort(ctx, preflight)
-			e.metrics.ProviderErrors.Add(1)
+			var limitErr *budget.LimitError
+			if errors.As(err, &limitErr) {
+				return subagent.Result{}, fmt.Errorf("budget_exhausted: %w", limitErr)
+			}
 			return subagent.Result{}, err
 		}
+		e.recordUsageTokens(resp.Usage)
 		commit, commitErr := e.budgetGuard.Commit(ctx, settings, scopeLimits, preflight, budget.Usage{
 			PromptTokens:     resp.Usage.PromptTokens,
 			CompletionTokens: resp.Usage.CompletionTokens,
@@ -1424,10 +2013,12 @@ func (e *Engine) runSubtask(ctx context.Context, run subagent.Run) (subagent.Res
 		if commitErr != nil {
 			e.log.Printf("failed to commit subagent token budget usage: %v", commitErr)
 		}
-		e.recordUsageDay(ctx,
+		e.recordUsageDay(ctx, resp.Model,
 			uint64(max(resp.Usage.PromptTokens, 0)),
 			uint64(max(resp.Usage.CompletionTokens, 0)),
 			commit.TotalTokens,
+			uint64(max(resp.Usage.CacheReadTokens, 0)),
+			uint64(max(resp.Usage.CacheWriteTokens, 0)),
 		)
 		for _, warning := range commit.Warnings {
 			budgetWarnings = append(budgetWarnings, fmt.Sprintf("%s at %d%% of hard limit", warning.Scope, warning.ThresholdPct))
@@ -1436,15 +2027,24 @@ func (e *Engine) runSubtask(ctx context.Context, run subagent.Run) (subagent.Res
 			messages = append(messages, provider.Message{Role: "assistant", Content: resp.Content, ToolCalls: resp.ToolCalls})
 			for _, tc := range resp.ToolCalls {
 				e.metrics.ToolCalls.Add(1)
-				result, toolErr := registry.Execute(ctx, tc.Name, tc.Arguments)
-				if toolErr != nil {
-					e.metrics.ToolErrors.Add(1)
-					result = tools.ToolResult{Text: toolErr.Error()}
+				e.subagents.ReportProgress(run, subagent.Progress{Hop: i + 1, MaxHops: maxHops, Tool: tc.Name, Status: "running tool"})
+				result, allowed := e.consumeToolQuota(ctx, cfg, tc.Name)
+				if allowed {
+					toolCtx, toolCancel := context.WithTimeout(ctx, toolTimeout(cfg, tc.Name))
+					var toolErr error
+					result, toolErr = registry.Execute(toolCtx, tc.Name, tc.Arguments)
+					toolCancel()
+					if toolErr != nil {
+						e.metrics.ToolErrors.Add(1)
+						e.recordToolArgumentError(toolErr)
+						result = tools.ToolResult{Text: toolErr.Error()}
+					}
 				}
 				messages = append(messages, provider.Message{Role: "tool", ToolCallID: tc.ID, Name: tc.Name, Content: result.Text})
 			}
 			continue
 		}
+		messages = append(messages, provider.Message{Role: "assistant", Content: resp.Content})
 		finalContent = strings.TrimSpace(resp.Content)
 		if finalContent == "" {
 			finalContent = "Task completed."
@@ -1481,6 +2081,22 @@ func (e *Engine) runSubtask(ctx context.Context, run subagent.Run) (subagent.Res
 	return subagent.Result{Summary: summary, Output: finalContent, ArtifactPaths: artifactPaths}, nil
 }
 
+// writeSubagentTranscript writes one JSON message per line, replacing the
+// transcript of any earlier attempt.
+func writeSubagentTranscript(path string, messages []provider.Message) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, message := range messages {
+		if err := encoder.Encode(message); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
 func (e *Engine) createMissionTask(ctx context.Context, req tools.CreateTaskRequest) (tools.TaskResult, error) {
 	title := strings.TrimSpace(req.Title)
 	if title == "" {
@@ -1518,7 +2134,6 @@ func (e *Engine) createMissionTask(ctx context.Context, req tools.CreateTaskRequ
 	}
 
 	now := time.Now().UTC()
-	normalized := mission.NormalizeTaskTitle(title)
 	all, err := e.store.ListMissionTasks(ctx)
 	if err != nil {
 		return tools.TaskResult{}, err
@@ -1526,7 +2141,7 @@ func (e *Engine) createMissionTask(ctx context.Context, req tools.CreateTaskRequ
 
 	dedupeWindow := policy.DedupeWindow()
 	for _, candidate := range all {
-		if mission.NormalizeTaskTitle(candidate.Title) != normalized {
+		if !policy.DedupeMatches(candidate, title, req.Assignee, columnID) {
 			continue
 		}
 		if candidate.Source.Type != source.Type {
@@ -1549,13 +2164,7 @@ func (e *Engine) createMissionTask(ctx context.Context, req tools.CreateTaskRequ
 		if strings.TrimSpace(req.Assignee) != "" {
 			candidate.Assignee = strings.TrimSpace(req.Assignee)
 		}
-		if strings.TrimSpace(req.Notes) != "" {
-			if strings.TrimSpace(candidate.Notes) == "" {
-				candidate.Notes = strings.TrimSpace(req.Notes)
-			} else {
-				candidate.Notes = strings.TrimSpace(candidate.Notes) + "\n" + strings.TrimSpace(req.Notes)
-			}
-		}
+		candidate.Notes = policy.MergeDedupeNotes(candidate.Notes, req.Notes)
 		if req.DueAt != nil {
 			due := req.DueAt.UTC()
 			candidate.DueAt = &due
@@ -1730,12 +2339,12 @@ func sourceTypeFromContext(channel, sessionID, trigger string) mission.TaskSourc
 		return mission.TaskSourceSubagent
 	}
 	switch strings.TrimSpace(strings.ToLower(channel)) {
-	case "system":
-		if strings.TrimSpace(strings.ToLower(sessionID)) == "system:heartbeat" {
+	case ChannelSystem:
+		if isHeartbeatSession(sessionID) {
 			return mission.TaskSourceHeartbeat
 		}
 		return mission.TaskSourceSystem
-	case "cron":
+	case ChannelCron:
 		return mission.TaskSourceCron
 	case "api":
 		return mission.TaskSourceAPI
@@ -1758,7 +2367,48 @@ func (e *Engine) formatBudgetLimitMessage(limitErr *budget.LimitError) string {
 	)
 }
 
-func (e *Engine) recordUsageDay(ctx context.Context, promptTokens, completionTokens, totalTokens uint64) {
+func (e *Engine) recordToolArgumentError(err error) {
+	var argErr *tools.ArgumentError
+	if errors.As(err, &argErr) {
+		e.metrics.ToolArgumentErrors.Add(1)
+	}
+}
+
+// recordUsageTokens adds the prompt-cache and reasoning token counts of a
+// provider response to the metrics.
+func (e *Engine) recordUsageTokens(usage provider.Usage) {
+	if usage.CacheReadTokens > 0 {
+		e.metrics.PromptCacheReadTokens.Add(uint64(usage.CacheReadTokens))
+	}
+	if usage.CacheWriteTokens > 0 {
+		e.metrics.PromptCacheWriteTokens.Add(uint64(usage.CacheWriteTokens))
+	}
+	if usage.ReasoningTokens > 0 {
+		e.metrics.ReasoningTokens.Add(uint64(usage.ReasoningTokens))
+	}
+}
+
+// usageLabel names the provider and the model that served a call, for
+// per-provider usage attribution. An empty model falls back to the
+// configured one.
+func (e *Engine) usageLabel(model string) string {
+	name, _ := e.currentConfig().PrimaryProvider()
+	if strings.TrimSpace(model) == "" {
+		_, model = e.currentProviderModel()
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = "unknown"
+	}
+	if strings.TrimSpace(model) == "" {
+		return name
+	}
+	return name + "/" + strings.TrimSpace(model)
+}
+
+// recordUsageDay adds a call's token counts to the day's usage for the
+// provider and the model that served it.
+func (e *Engine) recordUsageDay(ctx context.Context, model string, promptTokens, completionTokens, totalTokens, cacheReadTokens, cacheWriteTokens uint64) {
 	if promptTokens == 0 && completionTokens == 0 && totalTokens == 0 {
 		return
 	}
@@ -1766,9 +2416,12 @@ func (e *Engine) recordUsageDay(ctx context.Context, promptTokens, completionTok
 	if err := e.store.RecordUsageDay(
 		ctx,
 		day,
+		e.usageLabel(model),
 		promptTokens,
 		completionTokens,
 		totalTokens,
+		cacheReadTokens,
+		cacheWriteTokens,
 	); err != nil {
 		e.log.Printf("failed to record token usage: %v", err)
 	}
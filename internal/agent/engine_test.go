@@ -3,9 +3,14 @@ package agent_test
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"log"
+	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -13,6 +18,7 @@ import (
 	"github.com/grixate/squidbot/internal/config"
 	"github.com/grixate/squidbot/internal/provider"
 	storepkg "github.com/grixate/squidbot/internal/storage/bbolt"
+	"github.com/grixate/squidbot/internal/telemetry"
 )
 
 type fakeProvider struct {
@@ -75,3 +81,1315 @@ func TestEngineToolLoop(t *testing.T) {
 		t.Fatalf("unexpected response: %s", resp)
 	}
 }
+
+type slowToolChainProvider struct {
+	calls int
+}
+
+func (p *slowToolChainProvider) Capabilities() provider.ProviderCapabilities {
+	return provider.ProviderCapabilities{SupportsTools: true}
+}
+
+func (p *slowToolChainProvider) Stream(ctx context.Context, req provider.ChatRequest) (<-chan provider.StreamEvent, <-chan error) {
+	events := make(chan provider.StreamEvent)
+	errs := make(chan error, 1)
+	close(events)
+	close(errs)
+	return events, errs
+}
+
+func (p *slowToolChainProvider) Chat(ctx context.Context, req provider.ChatRequest) (provider.ChatResponse, error) {
+	p.calls++
+	if p.calls == 1 {
+		args, _ := json.Marshal(map[string]string{"path": "."})
+		return provider.ChatResponse{
+			Content:   "Found three candidate files so far.",
+			ToolCalls: []provider.ToolCall{{ID: "1", Name: "list_dir", Arguments: args}},
+		}, nil
+	}
+	<-ctx.Done()
+	return provider.ChatResponse{}, ctx.Err()
+}
+
+func TestEngineReturnsPartialResultOnTurnTimeout(t *testing.T) {
+	workspace := t.TempDir()
+	cfg := config.Default()
+	cfg.Agents.Defaults.Workspace = workspace
+	cfg.Agents.Defaults.MaxToolIterations = 5
+	cfg.Agents.Defaults.ToolTimeoutSec = 5
+	cfg.Agents.Defaults.TurnTimeoutSec = 1
+
+	store, err := storepkg.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	engine, err := agent.NewEngine(cfg, &slowToolChainProvider{}, "test-model", store, nil, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	resp, err := engine.Ask(context.Background(), agent.InboundMessage{
+		SessionID: "cli:timeout",
+		Channel:   "cli",
+		ChatID:    "direct",
+		SenderID:  "user",
+		Content:   "survey the workspace",
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		t.Fatalf("expected partial result instead of error, got %v", err)
+	}
+	if !strings.Contains(resp, "Found three candidate files so far.") || !strings.Contains(resp, "Timed out") {
+		t.Fatalf("unexpected partial response: %s", resp)
+	}
+
+	history, err := store.Window(context.Background(), "cli:timeout", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[1].Content != resp {
+		t.Fatalf("expected partial result to be persisted, got %+v", history)
+	}
+}
+
+type toolRecordingProvider struct {
+	mu    sync.Mutex
+	tools [][]string
+}
+
+func (p *toolRecordingProvider) Capabilities() provider.ProviderCapabilities {
+	return provider.ProviderCapabilities{SupportsTools: true}
+}
+
+func (p *toolRecordingProvider) Stream(ctx context.Context, req provider.ChatRequest) (<-chan provider.StreamEvent, <-chan error) {
+	events := make(chan provider.StreamEvent)
+	errs := make(chan error, 1)
+	close(events)
+	close(errs)
+	return events, errs
+}
+
+func (p *toolRecordingProvider) Chat(ctx context.Context, req provider.ChatRequest) (provider.ChatResponse, error) {
+	names := make([]string, 0, len(req.Tools))
+	for _, def := range req.Tools {
+		names = append(names, def.Name)
+	}
+	p.mu.Lock()
+	p.tools = append(p.tools, names)
+	p.mu.Unlock()
+	return provider.ChatResponse{Content: "ok"}, nil
+}
+
+func (p *toolRecordingProvider) lastTools() map[string]bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := map[string]bool{}
+	if len(p.tools) == 0 {
+		return out
+	}
+	for _, name := range p.tools[len(p.tools)-1] {
+		out[name] = true
+	}
+	return out
+}
+
+func TestEngineLockToolsRestrictsSessionToReadOnlyTools(t *testing.T) {
+	cfg := config.Default()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	cfg.Tools.Exec.Enabled = true
+
+	store, err := storepkg.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	recorder := &toolRecordingProvider{}
+	engine, err := agent.NewEngine(cfg, recorder, "test-model", store, nil, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	ask := func(content string) string {
+		t.Helper()
+		resp, err := engine.Ask(context.Background(), agent.InboundMessage{
+			SessionID: "cli:lock",
+			Channel:   "cli",
+			ChatID:    "direct",
+			SenderID:  "user",
+			Content:   content,
+			CreatedAt: time.Now().UTC(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	if resp := ask("/lock-tools"); !strings.Contains(resp, "locked") {
+		t.Fatalf("unexpected lock reply: %s", resp)
+	}
+	ask("brainstorm with me")
+	locked := recorder.lastTools()
+	if !locked["read_file"] || locked["exec"] || locked["spawn"] || locked["message"] {
+		t.Fatalf("expected only read-only tools while locked, got %v", locked)
+	}
+	if !agent.SessionToolsLocked(context.Background(), store, "cli:lock") {
+		t.Fatal("expected lock to be persisted")
+	}
+
+	ask("/unlock-tools")
+	ask("now do things")
+	if unlocked := recorder.lastTools(); !unlocked["exec"] {
+		t.Fatalf("expected full tool set after unlock, got %v", unlocked)
+	}
+}
+
+type promptRecordingProvider struct {
+	mu      sync.Mutex
+	prompts []string
+}
+
+func (p *promptRecordingProvider) Capabilities() provider.ProviderCapabilities {
+	return provider.ProviderCapabilities{SupportsTools: true}
+}
+
+func (p *promptRecordingProvider) Stream(ctx context.Context, req provider.ChatRequest) (<-chan provider.StreamEvent, <-chan error) {
+	events := make(chan provider.StreamEvent)
+	errs := make(chan error, 1)
+	close(events)
+	close(errs)
+	return events, errs
+}
+
+func (p *promptRecordingProvider) Chat(ctx context.Context, req provider.ChatRequest) (provider.ChatResponse, error) {
+	var system []string
+	for _, message := range req.Messages {
+		if message.Role == "system" {
+			system = append(system, message.Content)
+		}
+	}
+	p.mu.Lock()
+	p.prompts = append(p.prompts, strings.Join(system, "\n"))
+	p.mu.Unlock()
+	return provider.ChatResponse{Content: "ok"}, nil
+}
+
+func (p *promptRecordingProvider) lastPrompt() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.prompts) == 0 {
+		return ""
+	}
+	return p.prompts[len(p.prompts)-1]
+}
+
+func TestEngineFocusPinsSessionToOneSkill(t *testing.T) {
+	workspace := t.TempDir()
+	for name, body := range map[string]string{
+		"intake":  "# Intake\nRun the client intake interview.",
+		"planner": "# Planner\nCreates practical execution plans.",
+	} {
+		path := filepath.Join(workspace, "skills", name, "SKILL.md")
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := config.Default()
+	cfg.Agents.Defaults.Workspace = workspace
+	cfg.Skills.Paths = []string{filepath.Join(workspace, "skills")}
+
+	store, err := storepkg.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	recorder := &promptRecordingProvider{}
+	engine, err := agent.NewEngine(cfg, recorder, "test-model", store, nil, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	ask := func(content string) string {
+		t.Helper()
+		resp, err := engine.Ask(context.Background(), agent.InboundMessage{SessionID: "cli:focus", Channel: "cli", ChatID: "direct", Content: content, CreatedAt: time.Now().UTC()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	if resp := ask("/focus nope"); !strings.Contains(resp, "Unknown skill") {
+		t.Fatalf("unexpected reply for unknown skill: %s", resp)
+	}
+	if resp := ask("/focus intake"); !strings.Contains(resp, "Focused on skill intake") {
+		t.Fatalf("unexpected focus reply: %s", resp)
+	}
+	if got := agent.SessionFocus(context.Background(), store, "cli:focus"); got != "intake" {
+		t.Fatalf("expected focus stored in session meta, got %q", got)
+	}
+	ask("use $planner to create practical execution plans")
+	if prompt := recorder.lastPrompt(); !strings.Contains(prompt, "intake/SKILL.md") || strings.Contains(prompt, "planner/SKILL.md") {
+		t.Fatalf("expected only the focused skill in the prompt, got:\n%s", prompt)
+	}
+
+	ask("/unfocus")
+	ask("use $planner to create practical execution plans")
+	if prompt := recorder.lastPrompt(); !strings.Contains(prompt, "planner/SKILL.md") {
+		t.Fatalf("expected normal routing after unfocus, got:\n%s", prompt)
+	}
+}
+
+type concurrencyProbeProvider struct {
+	mu      sync.Mutex
+	active  int
+	maxSeen int
+}
+
+func (p *concurrencyProbeProvider) Capabilities() provider.ProviderCapabilities {
+	return provider.ProviderCapabilities{SupportsTools: true}
+}
+
+func (p *concurrencyProbeProvider) Stream(ctx context.Context, req provider.ChatRequest) (<-chan provider.StreamEvent, <-chan error) {
+	events := make(chan provider.StreamEvent)
+	errs := make(chan error, 1)
+	close(events)
+	close(errs)
+	return events, errs
+}
+
+func (p *concurrencyProbeProvider) Chat(ctx context.Context, req provider.ChatRequest) (provider.ChatResponse, error) {
+	p.mu.Lock()
+	p.active++
+	p.maxSeen = max(p.maxSeen, p.active)
+	p.mu.Unlock()
+	time.Sleep(50 * time.Millisecond)
+	p.mu.Lock()
+	p.active--
+	p.mu.Unlock()
+	return provider.ChatResponse{Content: "done"}, nil
+}
+
+func TestEngineMaxConcurrentTurnsQueuesProviderCalls(t *testing.T) {
+	cfg := config.Default()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	cfg.Runtime.MaxConcurrentTurns = 1
+
+	store, err := storepkg.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	probe := &concurrencyProbeProvider{}
+	metrics := &telemetry.Metrics{}
+	engine, err := agent.NewEngine(cfg, probe, "test-model", store, metrics, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := engine.Ask(context.Background(), agent.InboundMessage{
+				SessionID: fmt.Sprintf("cli:burst-%d", i),
+				Channel:   "cli",
+				ChatID:    "direct",
+				SenderID:  "user",
+				Content:   "hello",
+				CreatedAt: time.Now().UTC(),
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if probe.maxSeen != 1 {
+		t.Fatalf("expected provider calls to be serialized, saw %d in flight", probe.maxSeen)
+	}
+	if metrics.TurnsQueued.Load() == 0 {
+		t.Fatal("expected queued turns to be counted")
+	}
+	if metrics.TurnsWaiting.Load() != 0 {
+		t.Fatalf("expected no waiting turns after completion, got %d", metrics.TurnsWaiting.Load())
+	}
+}
+
+func TestEngineSystemChannelRunsTurnWithoutDelivery(t *testing.T) {
+	cfg := config.Default()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	store, err := storepkg.Open(filepath.Join(t.TempDir(), "system.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	engine, err := agent.NewEngine(cfg, &fakeProvider{}, "test-model", store, nil, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	for _, channel := range []string{agent.ChannelSystem, "telegram"} {
+		resp, err := engine.Ask(context.Background(), agent.InboundMessage{
+			SessionID: channel + ":maintenance",
+			Channel:   channel,
+			ChatID:    "internal",
+			SenderID:  "automation",
+			Content:   "tidy up",
+			CreatedAt: time.Now().UTC(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp != "done" {
+			t.Fatalf("expected full turn on %s, got %q", channel, resp)
+		}
+	}
+	engine.EmitOutbound(agent.ChannelCron, "job", "ignored", nil)
+
+	delivered := []string{}
+	for {
+		select {
+		case msg := <-engine.Outbound():
+			delivered = append(delivered, msg.Channel)
+			continue
+		default:
+		}
+		break
+	}
+	if len(delivered) != 1 || delivered[0] != "telegram" {
+		t.Fatalf("expected only the telegram reply to be delivered, got %v", delivered)
+	}
+	turns, err := store.Window(context.Background(), "system:maintenance", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(turns) == 0 {
+		t.Fatal("expected system turn to be recorded")
+	}
+}
+
+func TestEngineTitlesSessionFromFirstMessage(t *testing.T) {
+	cfg := config.Default()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	cfg.Runtime.SessionTitles.MaxChars = 24
+	store, err := storepkg.Open(filepath.Join(t.TempDir(), "titles.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	engine, err := agent.NewEngine(cfg, &fakeProvider{}, "test-model", store, nil, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	ask := func(content, chatID string) {
+		t.Helper()
+		if _, err := engine.Ask(context.Background(), agent.InboundMessage{
+			SessionID: "telegram:42",
+			Channel:   "telegram",
+			ChatID:    chatID,
+			SenderID:  "user",
+			Content:   content,
+			CreatedAt: time.Now().UTC(),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ask("Plan the   garden irrigation schedule for spring", "42")
+	ask("something else entirely", "43")
+
+	meta, err := store.GetSessionMeta(context.Background(), "telegram:42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta["title"] != "Plan the garden…" || meta["title_source"] != "heuristic" {
+		t.Fatalf("expected heuristic title from first message, got %+v", meta)
+	}
+	if meta["last_chat_id"] != "43" {
+		t.Fatalf("expected later meta writes to merge, got %+v", meta)
+	}
+
+	title, err := engine.RefreshSessionTitle(context.Background(), "telegram:42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title != "Plan the garden…" {
+		t.Fatalf("unexpected refreshed title %q", title)
+	}
+}
+
+func TestEngineTitlesSessionWithProvider(t *testing.T) {
+	cfg := config.Default()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	cfg.Runtime.SessionTitles.UseProvider = true
+	store, err := storepkg.Open(filepath.Join(t.TempDir(), "titles.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	fake := &fakeProvider{}
+	engine, err := agent.NewEngine(cfg, fake, "test-model", store, nil, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	if _, err := engine.Ask(context.Background(), agent.InboundMessage{SessionID: "cli:titled", Channel: "cli", ChatID: "direct", Content: "hello", CreatedAt: time.Now().UTC()}); err != nil {
+		t.Fatal(err)
+	}
+	meta, err := store.GetSessionMeta(context.Background(), "cli:titled")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta["title"] != "done" || meta["title_source"] != "provider" {
+		t.Fatalf("expected provider title, got %+v", meta)
+	}
+	if fake.calls != 3 {
+		t.Fatalf("expected one extra provider call for the title, got %d calls", fake.calls)
+	}
+}
+
+type retiredModelProvider struct {
+	models []string
+}
+
+func (p *retiredModelProvider) Capabilities() provider.ProviderCapabilities {
+	return provider.ProviderCapabilities{SupportsTools: true}
+}
+
+func (p *retiredModelProvider) Stream(ctx context.Context, req provider.ChatRequest) (<-chan provider.StreamEvent, <-chan error) {
+	events := make(chan provider.StreamEvent)
+	errs := make(chan error, 1)
+	close(events)
+	close(errs)
+	return events, errs
+}
+
+func (p *retiredModelProvider) Chat(ctx context.Context, req provider.ChatRequest) (provider.ChatResponse, error) {
+	p.models = append(p.models, req.Model)
+	if req.Model != config.ProviderDefaultModel(config.ProviderOllama) {
+		return provider.ChatResponse{}, &provider.HTTPError{StatusCode: 404, Body: map[string]any{"error": map[string]any{"type": "not_found_error", "message": "model 'retired' not found"}}}
+	}
+	return provider.ChatResponse{Content: "fallback ok", Usage: provider.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}}, nil
+}
+
+func TestEngineFallsBackToProviderDefaultModel(t *testing.T) {
+	cfg := config.Default()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	cfg.Providers.Active = config.ProviderOllama
+	cfg.Runtime.SessionTitles.Enabled = false
+	store, err := storepkg.Open(filepath.Join(t.TempDir(), "fallback.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	ask := func(engine *agent.Engine) (string, error) {
+		return engine.Ask(context.Background(), agent.InboundMessage{SessionID: "cli:fallback", Channel: "cli", ChatID: "direct", Content: "hi", CreatedAt: time.Now().UTC()})
+	}
+
+	strict := &retiredModelProvider{}
+	engine, err := agent.NewEngine(cfg, strict, "retired", store, nil, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ask(engine); err == nil {
+		t.Fatal("expected model error without fallback enabled")
+	}
+	engine.Close()
+
+	cfg.Agents.Defaults.FallbackToProviderDefaultModel = true
+	fallback := &retiredModelProvider{}
+	metrics := &telemetry.Metrics{}
+	engine, err = agent.NewEngine(cfg, fallback, "retired", store, metrics, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+	for i := 0; i < 2; i++ {
+		resp, err := ask(engine)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp != "fallback ok" {
+			t.Fatalf("unexpected response %q", resp)
+		}
+	}
+	want := []string{"retired", "llama3.1:8b", "retired", "llama3.1:8b"}
+	if strings.Join(fallback.models, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected each turn to try the configured model first, got %v", fallback.models)
+	}
+	if metrics.ModelFallbacks.Load() != 2 {
+		t.Fatalf("expected one fallback per turn, got %d", metrics.ModelFallbacks.Load())
+	}
+	days, err := store.ListUsageDays(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(days) != 1 {
+		t.Fatalf("expected one usage day, got %+v", days)
+	}
+	served := days[0].ByProvider["ollama/llama3.1:8b"]
+	if served.PromptTokens != 20 || served.CompletionTokens != 10 {
+		t.Fatalf("expected usage recorded under the fallback model, got %+v", days[0].ByProvider)
+	}
+	if served.Calls != 2 {
+		t.Fatalf("expected throughput recorded under the fallback model, got %+v", days[0].ByProvider)
+	}
+	if _, ok := days[0].ByProvider["ollama/retired"]; ok {
+		t.Fatalf("expected nothing recorded under the missing model, got %+v", days[0].ByProvider)
+	}
+}
+
+func TestEngineRunsToolLoopWithMockProvider(t *testing.T) {
+	cfg := config.Default()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	cfg.Providers.Active = config.ProviderMock
+	cfg.Providers.Mock.Script = []config.MockScriptStep{
+		{ToolCalls: []config.MockToolCall{{Name: "list_dir", Arguments: json.RawMessage(`{"path":"."}`)}}},
+	}
+	client, model, err := provider.FromConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := storepkg.Open(filepath.Join(t.TempDir(), "mock.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	metrics := &telemetry.Metrics{}
+	engine, err := agent.NewEngine(cfg, client, model, store, metrics, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	resp, err := engine.Ask(context.Background(), agent.InboundMessage{SessionID: "cli:mock", Channel: "cli", ChatID: "direct", Content: "what is here?", CreatedAt: time.Now().UTC()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp != "echo: what is here?" {
+		t.Fatalf("unexpected response %q", resp)
+	}
+	if metrics.ToolCalls.Load() != 1 || metrics.ProviderCalls.Load() != 2 {
+		t.Fatalf("expected one tool round trip, got tools=%d provider=%d", metrics.ToolCalls.Load(), metrics.ProviderCalls.Load())
+	}
+}
+
+func TestEngineBlocksToolsOverDailyQuota(t *testing.T) {
+	cfg := config.Default()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	cfg.Providers.Active = config.ProviderMock
+	cfg.Tools.DailyLimits = map[string]int{"list_dir": 1}
+	listDir := config.MockToolCall{Name: "list_dir", Arguments: json.RawMessage(`{"path":"."}`)}
+	cfg.Providers.Mock.Script = []config.MockScriptStep{{ToolCalls: []config.MockToolCall{listDir, listDir}}}
+	client, model, err := provider.FromConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := storepkg.Open(filepath.Join(t.TempDir(), "quota.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	metrics := &telemetry.Metrics{}
+	engine, err := agent.NewEngine(cfg, client, model, store, metrics, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	if _, err := engine.Ask(context.Background(), agent.InboundMessage{SessionID: "cli:quota", Channel: "cli", ChatID: "direct", Content: "list twice", CreatedAt: time.Now().UTC()}); err != nil {
+		t.Fatal(err)
+	}
+	if metrics.ToolCalls.Load() != 2 || metrics.ToolQuotaBlocked.Load() != 1 {
+		t.Fatalf("expected one of two calls blocked, got calls=%d blocked=%d", metrics.ToolCalls.Load(), metrics.ToolQuotaBlocked.Load())
+	}
+	usage, err := store.ListToolUsage(context.Background(), time.Now().UTC().Format("2006-01-02"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(usage) != 1 || usage[0].Tool != "list_dir" || usage[0].Calls != 1 || usage[0].Blocked != 1 {
+		t.Fatalf("unexpected tool usage: %+v", usage)
+	}
+}
+
+type toolResultRecorder struct {
+	calls   int
+	results []string
+}
+
+func (p *toolResultRecorder) Capabilities() provider.ProviderCapabilities {
+	return provider.ProviderCapabilities{SupportsTools: true}
+}
+
+func (p *toolResultRecorder) Stream(ctx context.Context, req provider.ChatRequest) (<-chan provider.StreamEvent, <-chan error) {
+	events := make(chan provider.StreamEvent)
+	errs := make(chan error, 1)
+	close(events)
+	close(errs)
+	return events, errs
+}
+
+func (p *toolResultRecorder) Chat(ctx context.Context, req provider.ChatRequest) (provider.ChatResponse, error) {
+	p.calls++
+	if p.calls == 1 {
+		args, _ := json.Marshal(map[string]string{"path": "notes.txt", "content": "hello"})
+		return provider.ChatResponse{ToolCalls: []provider.ToolCall{{ID: "1", Name: "write_file", Arguments: args}}}, nil
+	}
+	for _, message := range req.Messages {
+		if message.Role == "tool" {
+			p.results = append(p.results, message.Content)
+		}
+	}
+	return provider.ChatResponse{Content: "done"}, nil
+}
+
+func TestEngineDryToolsStubsToolExecution(t *testing.T) {
+	workspace := t.TempDir()
+	cfg := config.Default()
+	cfg.Agents.Defaults.Workspace = workspace
+	cfg.Tools.Filesystem.ParentWriteEnabled = true
+	store, err := storepkg.Open(filepath.Join(t.TempDir(), "dry.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	recorder := &toolResultRecorder{}
+	engine, err := agent.NewEngine(cfg, recorder, "test-model", store, nil, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+	engine.SetDryTools(true)
+
+	if _, err := engine.Ask(context.Background(), agent.InboundMessage{SessionID: "cli:dry", Channel: "cli", ChatID: "direct", Content: "write a note", CreatedAt: time.Now().UTC()}); err != nil {
+		t.Fatal(err)
+	}
+	if len(recorder.results) != 1 || !strings.HasPrefix(recorder.results[0], "stubbed: ") || !strings.Contains(recorder.results[0], `"notes.txt"`) {
+		t.Fatalf("expected a stubbed tool result, got %q", recorder.results)
+	}
+	if _, err := os.Stat(filepath.Join(workspace, "notes.txt")); !os.IsNotExist(err) {
+		t.Fatalf("expected write_file not to run, stat err=%v", err)
+	}
+}
+
+func TestEngineArchivesSessionTranscriptWhenActorStops(t *testing.T) {
+	for _, format := range []string{agent.TranscriptJSON, agent.TranscriptMarkdown} {
+		t.Run(format, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.Agents.Defaults.Workspace = t.TempDir()
+			cfg.Runtime.ArchiveOnIdle = true
+			cfg.Runtime.ArchiveDir = t.TempDir()
+			cfg.Runtime.ArchiveFormat = format
+			store, err := storepkg.Open(filepath.Join(t.TempDir(), "archive.db"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer store.Close()
+			engine, err := agent.NewEngine(cfg, &fakeProvider{}, "test-model", store, nil, log.New(io.Discard, "", 0))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := engine.Ask(context.Background(), agent.InboundMessage{SessionID: "telegram:7", Channel: "telegram", ChatID: "7", Content: "archive me", CreatedAt: time.Now().UTC()}); err != nil {
+				t.Fatal(err)
+			}
+			if err := engine.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			matches, err := filepath.Glob(filepath.Join(cfg.Runtime.ArchiveDir, "telegram_7-*"))
+			if err != nil || len(matches) != 1 {
+				t.Fatalf("expected one archive file, got %v (err=%v)", matches, err)
+			}
+			raw, err := os.ReadFile(matches[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if format == agent.TranscriptMarkdown {
+				if !strings.Contains(string(raw), "## user") || !strings.Contains(string(raw), "archive me") {
+					t.Fatalf("unexpected markdown transcript:\n%s", raw)
+				}
+				return
+			}
+			var transcript agent.Transcript
+			if err := json.Unmarshal(raw, &transcript); err != nil {
+				t.Fatal(err)
+			}
+			if transcript.SessionID != "telegram:7" || len(transcript.Turns) != 2 || transcript.Title != "archive me" {
+				t.Fatalf("unexpected transcript %+v", transcript)
+			}
+		})
+	}
+}
+
+func TestTranscriptFileNameKeepsSessionsAndArchivesApart(t *testing.T) {
+	at := time.Date(2026, 3, 2, 7, 4, 5, 0, time.UTC)
+	colon := agent.TranscriptFileName("telegram:7", agent.TranscriptJSON, at)
+	slash := agent.TranscriptFileName("telegram/7", agent.TranscriptJSON, at)
+	if colon == slash {
+		t.Fatalf("expected distinct names for IDs that sanitize alike, got %q", colon)
+	}
+	if !strings.HasPrefix(colon, "telegram_7-") || !strings.HasSuffix(colon, "-20260302T070405Z.json") {
+		t.Fatalf("unexpected file name %q", colon)
+	}
+	later := agent.TranscriptFileName("telegram:7", agent.TranscriptMarkdown, at.Add(time.Second))
+	if later == colon || !strings.HasSuffix(later, "-20260302T070406Z.md") {
+		t.Fatalf("expected a new file per archive, got %q", later)
+	}
+}
+
+func TestQuietHoursUntilHandlesWindowsPastMidnight(t *testing.T) {
+	quiet := config.QuietHoursConfig{Enabled: true, Start: "22:00", End: "07:00", Timezone: "UTC"}
+	cases := []struct {
+		now   string
+		quiet bool
+		until string
+	}{
+		{now: "2026-03-01T23:30:00Z", quiet: true, until: "2026-03-02T07:00:00Z"},
+		{now: "2026-03-02T03:00:00Z", quiet: true, until: "2026-03-02T07:00:00Z"},
+		{now: "2026-03-02T07:00:00Z", quiet: false},
+		{now: "2026-03-02T12:00:00Z", quiet: false},
+	}
+	for _, tc := range cases {
+		now, _ := time.Parse(time.RFC3339, tc.now)
+		until, inQuiet, err := agent.QuietHoursUntil(quiet, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if inQuiet != tc.quiet || (tc.quiet && until.UTC().Format(time.RFC3339) != tc.until) {
+			t.Fatalf("%s: expected quiet=%v until %s, got %v until %s", tc.now, tc.quiet, tc.until, inQuiet, until.UTC().Format(time.RFC3339))
+		}
+	}
+	if _, _, err := agent.QuietHoursUntil(config.QuietHoursConfig{Enabled: true, Start: "25:00", End: "07:00"}, time.Now()); err == nil {
+		t.Fatal("expected an invalid start time to be rejected")
+	}
+}
+
+func TestEngineQuietHoursDefersProactiveMessages(t *testing.T) {
+	cfg := config.Default()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	now := time.Now().UTC()
+	cfg.Runtime.QuietHours = config.QuietHoursConfig{
+		Enabled:  true,
+		Start:    now.Add(-time.Hour).Format("15:04"),
+		End:      now.Add(time.Hour).Format("15:04"),
+		Timezone: "UTC",
+	}
+	dbPath := filepath.Join(t.TempDir(), "quiet.db")
+	store, err := storepkg.Open(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine, err := agent.NewEngine(cfg, &fakeProvider{}, "test-model", store, nil, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine.StartDeferredDelivery()
+	engine.EmitOutbound("telegram", "42", "reminder", map[string]interface{}{"source": "cron"})
+	engine.EmitOutbound("telegram", "42", "reply", nil)
+	engine.EmitOutbound("telegram", "42", "in-turn", map[string]interface{}{"source": "tool:message", "session_id": "telegram:42"})
+	engine.EmitOutbound("telegram", "42", "heartbeat", map[string]interface{}{"source": "tool:message", "session_id": "system:heartbeat"})
+
+	for _, want := range []string{"reply", "in-turn"} {
+		select {
+		case msg := <-engine.Outbound():
+			if msg.Content != want {
+				t.Fatalf("expected %q to be delivered, got %q", want, msg.Content)
+			}
+		default:
+			t.Fatalf("expected %q to be delivered during quiet hours", want)
+		}
+	}
+	select {
+	case msg := <-engine.Outbound():
+		t.Fatalf("expected proactive messages to be held, got %q", msg.Content)
+	default:
+	}
+	if held := engine.DeferredOutbound(); held != 2 {
+		t.Fatalf("expected two held messages, got %d", held)
+	}
+	if recent := engine.RecentOutbound(4, "", ""); recent[0].Status != agent.OutboundDeferred || recent[3].Status != agent.OutboundDeferred {
+		t.Fatalf("expected the held messages to be logged as deferred, got %+v", recent)
+	}
+	engine.Close()
+
+	// Held messages survive a restart. A runtime without delivery keeps
+	// them stored; the gateway sends them once quiet hours are over.
+	cfg.Runtime.QuietHours.Enabled = false
+	cli, err := agent.NewEngine(cfg, &fakeProvider{}, "test-model", store, nil, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if released := cli.FlushDeferredOutbound(); released != 0 {
+		t.Fatalf("expected nothing released without delivery, got %d", released)
+	}
+	cli.Close()
+
+	restarted, err := agent.NewEngine(cfg, &fakeProvider{}, "test-model", store, nil, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	defer restarted.Close()
+	if held := restarted.DeferredOutbound(); held != 2 {
+		t.Fatalf("expected both held messages to survive, got %d", held)
+	}
+	restarted.StartDeferredDelivery()
+	for _, want := range []string{"reminder", "heartbeat"} {
+		select {
+		case msg := <-restarted.Outbound():
+			if msg.Content != want {
+				t.Fatalf("expected the held %q, got %q", want, msg.Content)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("expected the held message to be delivered after quiet hours")
+		}
+	}
+	if held := restarted.DeferredOutbound(); held != 0 {
+		t.Fatalf("expected the queue to be empty after delivery, got %d", held)
+	}
+}
+
+// flakyProvider fails its first failures calls with status, then replies.
+type flakyProvider struct {
+	mu       sync.Mutex
+	calls    int
+	failures int
+	status   int
+}
+
+func (f *flakyProvider) Capabilities() provider.ProviderCapabilities {
+	return provider.ProviderCapabilities{SupportsTools: true}
+}
+
+func (f *flakyProvider) Stream(ctx context.Context, req provider.ChatRequest) (<-chan provider.StreamEvent, <-chan error) {
+	events := make(chan provider.StreamEvent)
+	errs := make(chan error, 1)
+	close(events)
+	close(errs)
+	return events, errs
+}
+
+func (f *flakyProvider) Chat(ctx context.Context, req provider.ChatRequest) (provider.ChatResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.calls <= f.failures {
+		return provider.ChatResponse{}, &provider.HTTPError{StatusCode: f.status, Body: map[string]any{"error": "try later"}}
+	}
+	return provider.ChatResponse{Content: "recovered", Usage: provider.Usage{PromptTokens: 10, CompletionTokens: 2, TotalTokens: 12}}, nil
+}
+
+func TestEngineRetriesTransientProviderErrors(t *testing.T) {
+	cases := []struct {
+		name      string
+		status    int
+		failures  int
+		wantCalls int
+		wantErr   bool
+	}{
+		{name: "rate limited", status: 429, failures: 2, wantCalls: 3},
+		{name: "retries exhausted", status: 503, failures: 5, wantCalls: 3, wantErr: true},
+		{name: "permanent", status: 401, failures: 1, wantCalls: 1, wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.Agents.Defaults.Workspace = t.TempDir()
+			cfg.Agents.Defaults.RetryMax = 2
+			cfg.Agents.Defaults.RetryBackoffMs = 1
+			store, err := storepkg.Open(filepath.Join(t.TempDir(), "retry.db"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer store.Close()
+			flaky := &flakyProvider{failures: tc.failures, status: tc.status}
+			metrics := &telemetry.Metrics{}
+			engine, err := agent.NewEngine(cfg, flaky, "test-model", store, metrics, log.New(io.Discard, "", 0))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer engine.Close()
+
+			reply, err := engine.Ask(context.Background(), agent.InboundMessage{
+				SessionID: "cli:retry",
+				Channel:   "cli",
+				ChatID:    "direct",
+				SenderID:  "user",
+				Content:   "hello",
+				CreatedAt: time.Now().UTC(),
+			})
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("expected error=%v, got reply %q err %v", tc.wantErr, reply, err)
+			}
+			if !tc.wantErr && reply != "recovered" {
+				t.Fatalf("expected the retried reply, got %q", reply)
+			}
+			if flaky.calls != tc.wantCalls {
+				t.Fatalf("expected %d provider calls, got %d", tc.wantCalls, flaky.calls)
+			}
+			if retries := metrics.ProviderRetries.Load(); retries != uint64(tc.wantCalls-1) {
+				t.Fatalf("expected %d retries, got %d", tc.wantCalls-1, retries)
+			}
+			reservations, err := store.ListBudgetReservations(context.Background(), "global", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(reservations) != tc.wantCalls {
+				t.Fatalf("expected one reservation per attempt, got %d", len(reservations))
+			}
+			for _, reservation := range reservations {
+				if !reservation.Finalized && !reservation.Cancelled {
+					t.Fatalf("reservation %s leaked: %+v", reservation.ID, reservation)
+				}
+			}
+		})
+	}
+}
+
+func TestEngineAskStreamEmitsToolCallEvents(t *testing.T) {
+	cfg := config.Default()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	store, err := storepkg.Open(filepath.Join(t.TempDir(), "stream.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	engine, err := agent.NewEngine(cfg, &fakeProvider{}, "test-model", store, nil, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	var events []agent.StreamEvent
+	err = engine.AskStream(context.Background(), agent.InboundMessage{
+		SessionID: "cli:stream",
+		Channel:   "cli",
+		ChatID:    "direct",
+		SenderID:  "user",
+		Content:   "list files",
+		CreatedAt: time.Now().UTC(),
+	}, agent.StreamSinkFunc(func(ctx context.Context, event agent.StreamEvent) error {
+		events = append(events, event)
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	types := make([]string, 0, len(events))
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	want := []string{"tool_call_started", "tool_call_end", "assistant_delta", "final"}
+	if strings.Join(types, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected events %v, got %v", want, types)
+	}
+	if events[0].ToolName != "list_dir" || events[1].ToolName != "list_dir" || events[1].ToolCallID != "1" {
+		t.Fatalf("expected list_dir tool events, got %+v", events[:2])
+	}
+}
+
+func TestEngineAskStreamSkipsToolEventsForBlockedCalls(t *testing.T) {
+	cfg := config.Default()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	cfg.Tools.DailyLimits = map[string]int{"list_dir": 1}
+	store, err := storepkg.Open(filepath.Join(t.TempDir(), "stream.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	fake := &fakeProvider{}
+	engine, err := agent.NewEngine(cfg, fake, "test-model", store, nil, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	msg := agent.InboundMessage{SessionID: "cli:stream", Channel: "cli", ChatID: "direct", SenderID: "user", Content: "list files", CreatedAt: time.Now().UTC()}
+	if _, err := engine.Ask(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	fake.calls = 0
+	var types []string
+	err = engine.AskStream(context.Background(), msg, agent.StreamSinkFunc(func(ctx context.Context, event agent.StreamEvent) error {
+		types = append(types, event.Type)
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"assistant_delta", "final"}; strings.Join(types, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected no tool events for a quota-blocked call, got %v", types)
+	}
+}
+
+// nonStreamingProvider advertises streaming but rejects every Stream call the
+// way an endpoint that refuses stream=true does; streaming must be turned off
+// in its config.
+type nonStreamingProvider struct {
+	mu      sync.Mutex
+	streams int
+	chats   int
+}
+
+func (p *nonStreamingProvider) Capabilities() provider.ProviderCapabilities {
+	return provider.ProviderCapabilities{SupportsStream: true}
+}
+
+func (p *nonStreamingProvider) Stream(ctx context.Context, req provider.ChatRequest) (<-chan provider.StreamEvent, <-chan error) {
+	p.mu.Lock()
+	p.streams++
+	p.mu.Unlock()
+	events := make(chan provider.StreamEvent)
+	errs := make(chan error, 1)
+	close(events)
+	errs <- &provider.HTTPError{StatusCode: 400, Body: map[string]any{"error": "stream is not supported"}}
+	close(errs)
+	return events, errs
+}
+
+func (p *nonStreamingProvider) Chat(ctx context.Context, req provider.ChatRequest) (provider.ChatResponse, error) {
+	p.mu.Lock()
+	p.chats++
+	p.mu.Unlock()
+	return provider.ChatResponse{Content: "buffered reply"}, nil
+}
+
+func TestEngineAskStreamBuffersWhenProviderStreamingDisabled(t *testing.T) {
+	cfg := config.Default()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	streaming := false
+	cfg.Providers.Active = config.ProviderOpenAI
+	cfg.Providers.OpenAI = config.ProviderConfig{APIKey: "key", Model: "test-model", Streaming: &streaming}
+	store, err := storepkg.Open(filepath.Join(t.TempDir(), "stream.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	var logs strings.Builder
+	client := &nonStreamingProvider{}
+	engine, err := agent.NewEngine(cfg, client, "test-model", store, nil, log.New(&logs, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	for i := 0; i < 2; i++ {
+		var events []agent.StreamEvent
+		err = engine.AskStream(context.Background(), agent.InboundMessage{
+			SessionID: "cli:fallback",
+			Channel:   "cli",
+			ChatID:    "direct",
+			SenderID:  "user",
+			Content:   "hello",
+		}, agent.StreamSinkFunc(func(ctx context.Context, event agent.StreamEvent) error {
+			events = append(events, event)
+			return nil
+		}))
+		if err != nil {
+			t.Fatalf("turn %d: %v", i, err)
+		}
+		if len(events) != 1 || events[0].Type != "final" || events[0].Content != "buffered reply" {
+			t.Fatalf("turn %d: expected a single final event, got %+v", i, events)
+		}
+	}
+	if client.streams != 0 || client.chats != 2 {
+		t.Fatalf("expected no stream calls and one chat per turn, got streams=%d chats=%d", client.streams, client.chats)
+	}
+	if got := strings.Count(logs.String(), "event=stream_fallback"); got != 1 {
+		t.Fatalf("expected the fallback to be logged once, got %d in %q", got, logs.String())
+	}
+}
+
+func TestEngineAskStreamAppliesPostProcessors(t *testing.T) {
+	cfg := config.Default()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	cfg.Agents.PostProcessors = config.PostProcessorsConfig{Default: []config.PostProcessorConfig{{Type: "append", Text: " -- bot"}}}
+	streaming := false
+	cfg.Providers.Active = config.ProviderOpenAI
+	cfg.Providers.OpenAI = config.ProviderConfig{APIKey: "key", Model: "test-model", Streaming: &streaming}
+	store, err := storepkg.Open(filepath.Join(t.TempDir(), "stream.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	engine, err := agent.NewEngine(cfg, &nonStreamingProvider{}, "test-model", store, nil, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	var final string
+	err = engine.AskStream(context.Background(), agent.InboundMessage{SessionID: "cli:post", Channel: "cli", ChatID: "direct", SenderID: "user", Content: "hello"}, agent.StreamSinkFunc(func(ctx context.Context, event agent.StreamEvent) error {
+		if event.Type == "final" {
+			final = event.Content
+		}
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if final != "buffered reply -- bot" {
+		t.Fatalf("expected the post-processed reply in the final event, got %q", final)
+	}
+	history, err := store.Window(context.Background(), "cli:post", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[1].Content != "buffered reply -- bot" {
+		t.Fatalf("expected the post-processed reply stored, got %+v", history)
+	}
+}
+
+// summaryRecordingProvider answers summary requests with a fixed summary and
+// records the messages of every other call.
+type summaryRecordingProvider struct {
+	mu        sync.Mutex
+	summaries []string
+	turns     [][]provider.Message
+	fail      bool
+}
+
+func (p *summaryRecordingProvider) Capabilities() provider.ProviderCapabilities {
+	return provider.ProviderCapabilities{SupportsTools: true}
+}
+
+func (p *summaryRecordingProvider) Stream(ctx context.Context, req provider.ChatRequest) (<-chan provider.StreamEvent, <-chan error) {
+	events := make(chan provider.StreamEvent)
+	errs := make(chan error, 1)
+	close(events)
+	close(errs)
+	return events, errs
+}
+
+func (p *summaryRecordingProvider) Chat(ctx context.Context, req provider.ChatRequest) (provider.ChatResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if strings.HasPrefix(req.Messages[0].Content, "Summarize the conversation") {
+		p.summaries = append(p.summaries, req.Messages[1].Content)
+		if p.fail {
+			return provider.ChatResponse{}, errors.New("summary unavailable")
+		}
+		return provider.ChatResponse{Content: fmt.Sprintf("summary #%d", len(p.summaries))}, nil
+	}
+	p.turns = append(p.turns, req.Messages)
+	return provider.ChatResponse{Content: "ok"}, nil
+}
+
+func TestEngineBacksOffAfterSummaryFailure(t *testing.T) {
+	cfg := config.Default()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	cfg.Agents.Defaults.RetryMax = 0
+	cfg.Runtime.SessionTitles.Enabled = false
+	cfg.Runtime.HistorySummary = config.HistorySummaryConfig{Enabled: true, MaxTurns: 2, KeepRecent: 2}
+	store, err := storepkg.Open(filepath.Join(t.TempDir(), "summary.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	client := &summaryRecordingProvider{fail: true}
+	engine, err := agent.NewEngine(cfg, client, "test-model", store, nil, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	for _, content := range []string{"one", "two", "three", "four"} {
+		if _, err := engine.Ask(context.Background(), agent.InboundMessage{SessionID: "cli:backoff", Channel: "cli", ChatID: "direct", SenderID: "user", Content: content}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(client.summaries) != 1 {
+		t.Fatalf("expected one summary attempt before the backoff ends, got %d", len(client.summaries))
+	}
+	if len(client.turns) != 4 {
+		t.Fatalf("expected every turn answered despite the failed summary, got %d", len(client.turns))
+	}
+}
+
+func TestEngineSummarizesLongHistory(t *testing.T) {
+	cfg := config.Default()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	cfg.Runtime.SessionTitles.Enabled = false
+	cfg.Runtime.HistorySummary = config.HistorySummaryConfig{Enabled: true, MaxTurns: 6, KeepRecent: 2}
+	store, err := storepkg.Open(filepath.Join(t.TempDir(), "summary.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	const sessionID = "cli:summary"
+	started := time.Now().UTC().Add(-time.Hour)
+	for i := 0; i < 8; i++ {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		if err := store.AppendTurn(context.Background(), agent.Turn{SessionID: sessionID, Role: role, Content: fmt.Sprintf("old message %d", i), CreatedAt: started.Add(time.Duration(i) * time.Minute)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	client := &summaryRecordingProvider{}
+	engine, err := agent.NewEngine(cfg, client, "test-model", store, nil, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	ask := func(content string) {
+		t.Helper()
+		if _, err := engine.Ask(context.Background(), agent.InboundMessage{SessionID: sessionID, Channel: "cli", ChatID: "direct", SenderID: "user", Content: content}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ask("what next?")
+	if len(client.summaries) != 1 || !strings.Contains(client.summaries[0], "old message 0") || strings.Contains(client.summaries[0], "old message 6") {
+		t.Fatalf("expected the six oldest turns to be summarized, got %q", client.summaries)
+	}
+	messages := client.turns[0]
+	if messages[2].Role != "system" || !strings.Contains(messages[2].Content, "summary #1") {
+		t.Fatalf("expected the summary note after the system prompt, got %+v", messages[2])
+	}
+	var history []string
+	for _, message := range messages[3:] {
+		history = append(history, message.Content)
+	}
+	if want := "old message 6|old message 7|what next?"; strings.Join(history, "|") != want {
+		t.Fatalf("expected recent turns %q, got %q", want, strings.Join(history, "|"))
+	}
+
+	raw, err := store.LoadCheckpoint(context.Background(), sessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var checkpoint map[string]any
+	if err := json.Unmarshal(raw, &checkpoint); err != nil {
+		t.Fatal(err)
+	}
+	if checkpoint["summary"] != "summary #1" || checkpoint["last_request_id"] == "" {
+		t.Fatalf("expected the summary and request id in the checkpoint, got %v", checkpoint)
+	}
+
+	ask("and then?")
+	if len(client.summaries) != 1 {
+		t.Fatalf("expected no new summary while under the limit, got %d", len(client.summaries))
+	}
+	if got := len(client.turns[1]); got != 8 {
+		t.Fatalf("expected both system prompt parts, summary, four stored turns, and the new message, got %d messages", got)
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"path/filepath"
 	"sort"
 	"strings"
 	"time"
@@ -30,7 +29,7 @@ func (e *Engine) federationNodeID(cfg config.Config) string {
 }
 
 func toFederationContext(packet subagent.ContextPacket) federation.ContextPacket {
-	return federation.ContextPacket{
+	out := federation.ContextPacket{
 		Mode:           string(packet.Mode),
 		SystemPrompt:   packet.SystemPrompt,
 		History:        packet.History,
@@ -39,10 +38,14 @@ func toFederationContext(packet subagent.ContextPacket) federation.ContextPacket
 		CreatedAt:      packet.CreatedAt,
 		Checksum:       packet.Checksum,
 	}
+	for _, item := range packet.InlineAttachments {
+		out.InlineAttachments = append(out.InlineAttachments, federation.InlineAttachment{Path: item.Path, Content: item.Content})
+	}
+	return out
 }
 
 func toSubagentContext(packet federation.ContextPacket) subagent.ContextPacket {
-	return subagent.ContextPacket{
+	out := subagent.ContextPacket{
 		Mode:           subagent.NormalizeContextMode(packet.Mode),
 		SystemPrompt:   packet.SystemPrompt,
 		History:        packet.History,
@@ -51,6 +54,10 @@ func toSubagentContext(packet federation.ContextPacket) subagent.ContextPacket {
 		CreatedAt:      packet.CreatedAt,
 		Checksum:       packet.Checksum,
 	}
+	for _, item := range packet.InlineAttachments {
+		out.InlineAttachments = append(out.InlineAttachments, subagent.InlineAttachment{Path: item.Path, Content: item.Content})
+	}
+	return out
 }
 
 func mapDelegationStatus(status federation.DelegationStatus) subagent.Status {
@@ -451,7 +458,11 @@ func (e *Engine) FederationSubmit(ctx context.Context, req federation.Delegation
 		})
 	}
 	e.metrics.DelegationsSubmitted.Add(1)
-	go e.executeFederationRun(run.ID)
+	e.fedRuns.Add(1)
+	go func() {
+		defer e.fedRuns.Done()
+		e.executeFederationRun(run.ID)
+	}()
 	return run, nil
 }
 
@@ -483,8 +494,7 @@ func (e *Engine) executeFederationRun(runID string) {
 		delete(e.fedCancels, run.ID)
 		e.fedCancelMu.Unlock()
 	}()
-	artifactDir := filepath.Join(config.WorkspacePath(e.currentConfig()), ".squidbot", "federation", run.ID)
-	_ = os.MkdirAll(artifactDir, 0o755)
+	artifactDir, _ := runArtifactDir(e.currentConfig(), "federation", run.ID)
 	result, runErr := e.runSubtask(runCtx, subagent.Run{
 		ID:          "federated-" + run.ID,
 		SessionID:   run.SessionID,
@@ -4,6 +4,7 @@ import (
 	"context"
 	"io"
 	"log"
+	"os"
 	"path/filepath"
 	"testing"
 	"time"
@@ -53,12 +54,15 @@ func (p *federationBlockingProvider) Chat(ctx context.Context, req provider.Chat
 	return provider.ChatResponse{}, ctx.Err()
 }
 
-func newFederationTestEngine(t *testing.T, providerClient provider.LLMProvider) (*agent.Engine, *storepkg.Store, *telemetry.Metrics) {
+func newFederationTestEngine(t *testing.T, providerClient provider.LLMProvider, mutate ...func(*config.Config)) (*agent.Engine, *storepkg.Store, *telemetry.Metrics) {
 	t.Helper()
 	cfg := config.Default()
 	cfg.Agents.Defaults.Workspace = t.TempDir()
 	cfg.Runtime.Subagents.DefaultTimeoutSec = 2
 	cfg.Runtime.Subagents.MaxAttempts = 1
+	for _, fn := range mutate {
+		fn(&cfg)
+	}
 	store, err := storepkg.Open(filepath.Join(t.TempDir(), "test.db"))
 	if err != nil {
 		t.Fatal(err)
@@ -156,3 +160,86 @@ func TestFederationCancelTransitionsRun(t *testing.T) {
 		t.Fatalf("expected terminal cancelled status, got %s", waited.Status)
 	}
 }
+
+func TestFederationArtifactsFollowSubagentRetention(t *testing.T) {
+	var workspace string
+	engine, store, _ := newFederationTestEngine(t, &federationInstantProvider{}, func(cfg *config.Config) {
+		cfg.Runtime.Subagents.ArtifactRetentionDays = 0
+		workspace = cfg.Agents.Defaults.Workspace
+	})
+	run, err := engine.FederationSubmit(context.Background(), federation.DelegationRequest{
+		Task:    "summarize session",
+		Context: federation.ContextPacket{Mode: "minimal", CreatedAt: time.Now().UTC()},
+	}, "origin-c", "idem-key-c")
+	if err != nil {
+		t.Fatalf("submit failed: %v", err)
+	}
+	waitForFederationTerminal(t, store, run.ID, 3*time.Second)
+	if _, err := os.Stat(filepath.Join(workspace, ".squidbot", "federation", run.ID)); !os.IsNotExist(err) {
+		t.Fatalf("expected no artifact dir with retention 0, stat err=%v", err)
+	}
+}
+
+func TestReapSubagentArtifactsRemovesExpiredFederationDirs(t *testing.T) {
+	var workspace string
+	engine, store, _ := newFederationTestEngine(t, &federationInstantProvider{}, func(cfg *config.Config) {
+		cfg.Runtime.Subagents.ArtifactRetentionDays = 1
+		workspace = cfg.Agents.Defaults.Workspace
+	})
+	ctx := context.Background()
+	now := time.Now().UTC()
+	old := now.Add(-72 * time.Hour)
+	recent := now.Add(-time.Hour)
+	runs := []federation.DelegationRun{
+		{ID: "fed-old", Task: "a", Status: federation.StatusSucceeded, CreatedAt: old, FinishedAt: &old},
+		{ID: "fed-recent", Task: "b", Status: federation.StatusSucceeded, CreatedAt: recent, FinishedAt: &recent},
+		{ID: "fed-running", Task: "c", Status: federation.StatusRunning, CreatedAt: old},
+	}
+	root := filepath.Join(workspace, ".squidbot", "federation")
+	for _, run := range runs {
+		if err := store.PutFederationRun(ctx, run); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.MkdirAll(filepath.Join(root, run.ID), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reaped, err := engine.ReapSubagentArtifacts(ctx, now)
+	if err != nil {
+		t.Fatalf("reap failed: %v", err)
+	}
+	if reaped != 1 {
+		t.Fatalf("expected one reaped dir, got %d", reaped)
+	}
+	if _, err := os.Stat(filepath.Join(root, "fed-old")); !os.IsNotExist(err) {
+		t.Fatalf("expected expired dir removed, stat err=%v", err)
+	}
+	for _, id := range []string{"fed-recent", "fed-running"} {
+		if _, err := os.Stat(filepath.Join(root, id)); err != nil {
+			t.Fatalf("expected %s kept: %v", id, err)
+		}
+	}
+}
+
+func TestReapSubagentArtifactsKeepsEverythingByDefault(t *testing.T) {
+	var workspace string
+	engine, store, _ := newFederationTestEngine(t, &federationInstantProvider{}, func(cfg *config.Config) {
+		workspace = cfg.Agents.Defaults.Workspace
+	})
+	ctx := context.Background()
+	old := time.Now().UTC().Add(-365 * 24 * time.Hour)
+	if err := store.PutFederationRun(ctx, federation.DelegationRun{ID: "fed-old", Task: "a", Status: federation.StatusSucceeded, CreatedAt: old, FinishedAt: &old}); err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(workspace, ".squidbot", "federation", "fed-old")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	reaped, err := engine.ReapSubagentArtifacts(ctx, time.Now().UTC())
+	if err != nil || reaped != 0 {
+		t.Fatalf("expected the default retention to keep artifacts, reaped=%d err=%v", reaped, err)
+	}
+	if _, err := os.Stat(dir); err != nil {
+		t.Fatalf("expected artifact dir kept: %v", err)
+	}
+}
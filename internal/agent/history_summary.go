@@ -0,0 +1,220 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/grixate/squidbot/internal/provider"
+)
+
+const (
+	historyWindowTurns        = 50
+	historySummaryTimeout     = 60 * time.Second
+	historySummaryTurnChars   = 2000
+	historySummaryPrompt      = "Summarize the conversation below so you can continue it later without the original messages. Keep facts, decisions, names, numbers, open tasks, and the user's stated preferences. If an earlier summary is given, fold it in. Reply with the summary only."
+	historySummaryNotePrefix  = "Summary of earlier conversation in this session:\n"
+	historySummaryMaxTokens   = 1024
+	historySummaryTemperature = 0.2
+	historySummaryBackoff     = time.Minute
+	historySummaryMaxBackoff  = time.Hour
+)
+
+// summaryFailure records a session's consecutive history summary failures.
+type summaryFailure struct {
+	count   int
+	retryAt time.Time
+}
+
+// sessionCheckpoint is the actor checkpoint stored per session. Summary and
+// SummaryThrough hold the conversation summary and the ID of the last turn
+// it covers.
+type sessionCheckpoint struct {
+	LastRequestID  string    `json:"last_request_id,omitempty"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	Summary        string    `json:"summary,omitempty"`
+	SummaryThrough string    `json:"summary_through,omitempty"`
+}
+
+func (e *Engine) loadCheckpoint(ctx context.Context, sessionID string) (sessionCheckpoint, bool) {
+	var checkpoint sessionCheckpoint
+	raw, err := e.store.LoadCheckpoint(ctx, sessionID)
+	if err != nil || len(raw) == 0 || json.Unmarshal(raw, &checkpoint) != nil {
+		return sessionCheckpoint{}, false
+	}
+	return checkpoint, true
+}
+
+// updateCheckpoint applies mutate to the stored checkpoint and saves it,
+// keeping the fields mutate leaves alone.
+func (e *Engine) updateCheckpoint(ctx context.Context, sessionID string, mutate func(*sessionCheckpoint)) error {
+	checkpoint, _ := e.loadCheckpoint(ctx, sessionID)
+	mutate(&checkpoint)
+	checkpoint.UpdatedAt = time.Now().UTC()
+	raw, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	return e.store.SaveCheckpoint(ctx, sessionID, raw)
+}
+
+// sessionHistory returns the history fed to the provider for a turn. With
+// runtime.historySummary off it is the last 50 turns. With it on, turns
+// covered by the stored summary are replaced by one system note holding it,
+// and the summary is first brought up to date when the rest of the history
+// has grown past the configured limits. A failed summary call keeps the
+// previous summary, logs the error, and holds off the next attempt for that
+// session, starting at one minute and doubling up to an hour.
+func (e *Engine) sessionHistory(ctx context.Context, sessionID string) ([]provider.Message, error) {
+	settings := e.currentConfig().Runtime.HistorySummary
+	if !settings.Enabled {
+		return e.store.Window(ctx, sessionID, historyWindowTurns)
+	}
+	turns, err := e.store.SessionTurns(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	checkpoint, _ := e.loadCheckpoint(ctx, sessionID)
+	recent := turnsAfter(turns, checkpoint.SummaryThrough)
+	if cut := historySummaryCut(recent, settings.MaxTurns, settings.MaxChars, settings.KeepRecent); cut > 0 && e.summaryDue(sessionID) {
+		summary, err := e.summarizeTurns(ctx, sessionID, checkpoint.Summary, recent[:cut])
+		if err != nil {
+			wait := e.recordSummaryFailure(sessionID)
+			e.log.Printf("event=history_summary_failed session_id=%s retry_in=%s err=%v", sessionID, wait, err)
+		} else {
+			e.summaryBackoff.Delete(sessionID)
+			through := recent[cut-1].ID
+			if err := e.updateCheckpoint(ctx, sessionID, func(c *sessionCheckpoint) {
+				c.Summary = summary
+				c.SummaryThrough = through
+			}); err != nil {
+				e.log.Printf("event=history_summary_failed session_id=%s err=%v", sessionID, err)
+			} else {
+				checkpoint.Summary = summary
+				recent = recent[cut:]
+			}
+		}
+	}
+	if len(recent) > historyWindowTurns {
+		recent = recent[len(recent)-historyWindowTurns:]
+	}
+	messages := make([]provider.Message, 0, len(recent)+1)
+	if strings.TrimSpace(checkpoint.Summary) != "" {
+		messages = append(messages, provider.Message{Role: "system", Content: historySummaryNotePrefix + checkpoint.Summary})
+	}
+	for _, turn := range recent {
+		messages = append(messages, provider.Message{
+			Role:       turn.Role,
+			Content:    turn.Content,
+			Name:       turn.Name,
+			ToolCallID: turn.ToolCallID,
+			ToolCalls:  turn.ToolCalls,
+		})
+	}
+	return messages, nil
+}
+
+// turnsAfter returns the turns following the one with ID through, or all
+// turns when through is empty or no longer present.
+func turnsAfter(turns []Turn, through string) []Turn {
+	if through == "" {
+		return turns
+	}
+	for idx, turn := range turns {
+		if turn.ID == through {
+			return turns[idx+1:]
+		}
+	}
+	return turns
+}
+
+// historySummaryCut returns how many leading turns to summarize, or 0 when
+// turns are within the limits. The kept tail starts at a user turn so tool
+// calls and their results stay together.
+func historySummaryCut(turns []Turn, maxTurns, maxChars, keepRecent int) int {
+	chars := 0
+	for _, turn := range turns {
+		chars += len(turn.Content)
+	}
+	overTurns := maxTurns > 0 && len(turns) > maxTurns
+	overChars := maxChars > 0 && chars > maxChars
+	if !overTurns && !overChars {
+		return 0
+	}
+	cut := len(turns) - max(keepRecent, 0)
+	for cut > 0 && cut < len(turns) && turns[cut].Role != "user" {
+		cut++
+	}
+	if cut >= len(turns) {
+		return 0
+	}
+	return cut
+}
+
+// summaryDue reports whether sessionID is past its summary backoff.
+func (e *Engine) summaryDue(sessionID string) bool {
+	value, ok := e.summaryBackoff.Load(sessionID)
+	return !ok || !time.Now().Before(value.(summaryFailure).retryAt)
+}
+
+// recordSummaryFailure extends sessionID's summary backoff and returns it.
+func (e *Engine) recordSummaryFailure(sessionID string) time.Duration {
+	failure := summaryFailure{}
+	if value, ok := e.summaryBackoff.Load(sessionID); ok {
+		failure = value.(summaryFailure)
+	}
+	failure.count++
+	wait := historySummaryBackoff
+	for i := 1; i < failure.count && wait < historySummaryMaxBackoff; i++ {
+		wait *= 2
+	}
+	wait = min(wait, historySummaryMaxBackoff)
+	failure.retryAt = time.Now().Add(wait)
+	e.summaryBackoff.Store(sessionID, failure)
+	return wait
+}
+
+func (e *Engine) summarizeTurns(ctx context.Context, sessionID, previous string, turns []Turn) (string, error) {
+	var transcript strings.Builder
+	if strings.TrimSpace(previous) != "" {
+		transcript.WriteString("Earlier summary:\n")
+		transcript.WriteString(strings.TrimSpace(previous))
+		transcript.WriteString("\n\nConversation:\n")
+	}
+	for _, turn := range turns {
+		content := truncateText(turn.Content, historySummaryTurnChars)
+		if content == "" {
+			for _, call := range turn.ToolCalls {
+				content += fmt.Sprintf("[called %s]", call.Name)
+			}
+		}
+		if content == "" {
+			continue
+		}
+		role := turn.Role
+		if turn.Name != "" {
+			role += " (" + turn.Name + ")"
+		}
+		fmt.Fprintf(&transcript, "%s: %s\n", role, content)
+	}
+	callCtx, cancel := context.WithTimeout(ctx, historySummaryTimeout)
+	defer cancel()
+	resp, err := e.chatInternal(callCtx, sessionID, provider.ChatRequest{
+		Messages: []provider.Message{
+			{Role: "system", Content: historySummaryPrompt},
+			{Role: "user", Content: transcript.String()},
+		},
+		MaxTokens:   historySummaryMaxTokens,
+		Temperature: historySummaryTemperature,
+	})
+	if err != nil {
+		return "", err
+	}
+	summary := strings.TrimSpace(resp.Content)
+	if summary == "" {
+		return "", fmt.Errorf("provider returned an empty summary")
+	}
+	return summary, nil
+}
@@ -0,0 +1,59 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/grixate/squidbot/internal/config"
+	"github.com/grixate/squidbot/internal/provider"
+)
+
+// chatWithModelFallback sends req and, when the provider reports the model as
+// missing and agents.defaults.fallbackToProviderDefaultModel is set, retries
+// once with the provider's catalog default model. The fallback applies to this
+// call only; the configured model is tried again on the next one.
+func (e *Engine) chatWithModelFallback(ctx context.Context, client provider.LLMProvider, req provider.ChatRequest) (provider.ChatResponse, error) {
+	resp, err := e.timedChat(ctx, client, req)
+	if err == nil || !provider.IsModelNotFound(err) {
+		return resp, err
+	}
+	cfg := e.currentConfig()
+	if !cfg.Agents.Defaults.FallbackToProviderDefaultModel {
+		return resp, err
+	}
+	providerName, _ := cfg.PrimaryProvider()
+	fallback := config.ProviderDefaultModel(providerName)
+	if fallback == "" || strings.EqualFold(fallback, req.Model) {
+		return resp, err
+	}
+	e.log.Printf("WARNING event=model_fallback provider=%s model=%q fallback=%q err=%v; update agents.defaults.model or the provider model in config", providerName, req.Model, fallback, err)
+	e.metrics.ModelFallbacks.Add(1)
+	e.metrics.ProviderCalls.Add(1)
+	req.Model = fallback
+	return e.timedChat(ctx, client, req)
+}
+
+// timedChat sends req, records its wall time in the latency histogram, and
+// records the call's completion throughput, in tokens per second, in the
+// metrics and the day's usage for the model that served it. The response
+// names req.Model as that model unless the provider reported one.
+func (e *Engine) timedChat(ctx context.Context, client provider.LLMProvider, req provider.ChatRequest) (provider.ChatResponse, error) {
+	started := time.Now()
+	resp, err := client.Chat(ctx, req)
+	latency := time.Since(started)
+	if err == nil && resp.Model == "" {
+		resp.Model = req.Model
+	}
+	e.metrics.ProviderLatency.Observe(latency)
+	if err != nil || resp.Usage.CompletionTokens <= 0 {
+		return resp, err
+	}
+	completion := uint64(resp.Usage.CompletionTokens)
+	e.metrics.RecordProviderThroughput(completion, latency)
+	day := time.Now().UTC().Format("2006-01-02")
+	if recordErr := e.store.RecordProviderThroughput(ctx, day, e.usageLabel(resp.Model), completion, latency); recordErr != nil {
+		e.log.Printf("failed to record provider throughput: %v", recordErr)
+	}
+	return resp, nil
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"os"
 	"path/filepath"
 	"regexp"
 	"sort"
@@ -18,6 +19,8 @@ import (
 	"github.com/grixate/squidbot/internal/config"
 	"github.com/grixate/squidbot/internal/provider"
 	storepkg "github.com/grixate/squidbot/internal/storage/bbolt"
+	"github.com/grixate/squidbot/internal/subagent"
+	"github.com/grixate/squidbot/internal/telemetry"
 )
 
 type fanoutProvider struct {
@@ -188,3 +191,274 @@ func TestEngineFanOutFanInParallelSubagents(t *testing.T) {
 		t.Fatalf("fanout took too long (%s), expected <= %s for parallel execution", elapsed, maxAllowed)
 	}
 }
+
+func TestEngineSuppressesSubagentNotificationsPerChannel(t *testing.T) {
+	cfg := config.Default()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	cfg.Agents.Defaults.MaxToolIterations = 10
+	cfg.Runtime.Subagents.DefaultTimeoutSec = 20
+	cfg.Runtime.Subagents.MaxAttempts = 1
+	cfg.Runtime.Subagents.NotifyOnComplete = true
+	cfg.Channels.CLI.SuppressSubagentNotifications = true
+
+	store, err := storepkg.Open(filepath.Join(t.TempDir(), "suppress.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	metrics := &telemetry.Metrics{}
+	engine, err := agent.NewEngine(cfg, &fanoutProvider{delay: 10 * time.Millisecond}, "test-model", store, metrics, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := engine.Ask(ctx, agent.InboundMessage{SessionID: "cli:quiet", Channel: "cli", ChatID: "direct", SenderID: "user", Content: "fan out", CreatedAt: time.Now().UTC()}); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for metrics.SubagentNotifySuppressed.Load() < 8 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := metrics.SubagentNotifySuppressed.Load(); got != 8 {
+		t.Fatalf("expected 8 suppressed completions, got %d", got)
+	}
+	for {
+		select {
+		case msg := <-engine.Outbound():
+			if source, _ := msg.Metadata["source"].(string); strings.HasPrefix(source, "subagent") {
+				t.Fatalf("expected no subagent messages on a suppressed channel, got %+v", msg)
+			}
+			continue
+		default:
+		}
+		break
+	}
+	runs, err := store.ListSubagentRunsBySession(context.Background(), "cli:quiet", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 8 {
+		t.Fatalf("expected suppressed runs to stay recorded, got %d", len(runs))
+	}
+}
+
+type contextModeProvider struct {
+	mu          sync.Mutex
+	spawnArgs   map[string]any
+	parentCalls int
+	subPrompts  []string
+}
+
+func (p *contextModeProvider) Capabilities() provider.ProviderCapabilities {
+	return provider.ProviderCapabilities{SupportsTools: true}
+}
+
+func (p *contextModeProvider) Stream(ctx context.Context, req provider.ChatRequest) (<-chan provider.StreamEvent, <-chan error) {
+	events := make(chan provider.StreamEvent)
+	errs := make(chan error, 1)
+	close(events)
+	close(errs)
+	return events, errs
+}
+
+func (p *contextModeProvider) Chat(ctx context.Context, req provider.ChatRequest) (provider.ChatResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if isSubagentRequest(req.Messages) {
+		p.subPrompts = append(p.subPrompts, req.Messages[0].Content)
+		return provider.ChatResponse{Content: "sub done"}, nil
+	}
+	p.parentCalls++
+	if p.parentCalls == 1 {
+		args, _ := json.Marshal(p.spawnArgs)
+		return provider.ChatResponse{ToolCalls: []provider.ToolCall{{ID: "spawn-1", Name: "spawn", Arguments: args}}}, nil
+	}
+	return provider.ChatResponse{Content: "parent done"}, nil
+}
+
+func TestEngineSpawnUsesDefaultContextMode(t *testing.T) {
+	cases := []struct {
+		name        string
+		spawnArgs   map[string]any
+		wantSession bool
+	}{
+		{"default applies when omitted", map[string]any{"task": "summarise", "wait": true}, true},
+		{"explicit mode overrides default", map[string]any{"task": "summarise", "wait": true, "context_mode": "minimal"}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.Agents.Defaults.Workspace = t.TempDir()
+			cfg.Runtime.Subagents.MaxAttempts = 1
+			cfg.Runtime.Subagents.NotifyOnComplete = false
+			cfg.Runtime.Subagents.DefaultContextMode = "session"
+			store, err := storepkg.Open(filepath.Join(t.TempDir(), "mode.db"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer store.Close()
+			stub := &contextModeProvider{spawnArgs: tc.spawnArgs}
+			engine, err := agent.NewEngine(cfg, stub, "test-model", store, nil, log.New(io.Discard, "", 0))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer engine.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if _, err := engine.Ask(ctx, agent.InboundMessage{SessionID: "cli:mode", Channel: "cli", ChatID: "direct", Content: "delegate", CreatedAt: time.Now().UTC()}); err != nil {
+				t.Fatal(err)
+			}
+			stub.mu.Lock()
+			defer stub.mu.Unlock()
+			if len(stub.subPrompts) != 1 {
+				t.Fatalf("expected one subagent call, got %d", len(stub.subPrompts))
+			}
+			if got := strings.Contains(stub.subPrompts[0], "parent session context"); got != tc.wantSession {
+				t.Fatalf("expected session context=%v, prompt: %q", tc.wantSession, stub.subPrompts[0])
+			}
+		})
+	}
+}
+
+type transcriptProvider struct {
+	mu          sync.Mutex
+	parentCalls int
+}
+
+func (p *transcriptProvider) Capabilities() provider.ProviderCapabilities {
+	return provider.ProviderCapabilities{SupportsTools: true}
+}
+
+func (p *transcriptProvider) Stream(ctx context.Context, req provider.ChatRequest) (<-chan provider.StreamEvent, <-chan error) {
+	events := make(chan provider.StreamEvent)
+	errs := make(chan error, 1)
+	close(events)
+	close(errs)
+	return events, errs
+}
+
+func (p *transcriptProvider) Chat(ctx context.Context, req provider.ChatRequest) (provider.ChatResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if isSubagentRequest(req.Messages) {
+		if req.Messages[len(req.Messages)-1].Role == "tool" {
+			return provider.ChatResponse{}, fmt.Errorf("subagent went off the rails")
+		}
+		return provider.ChatResponse{ToolCalls: []provider.ToolCall{{ID: "ls-1", Name: "list_dir", Arguments: json.RawMessage(`{"path":"."}`)}}}, nil
+	}
+	p.parentCalls++
+	if p.parentCalls == 1 {
+		args, _ := json.Marshal(map[string]any{"task": "inspect the workspace", "wait": true})
+		return provider.ChatResponse{ToolCalls: []provider.ToolCall{{ID: "spawn-1", Name: "spawn", Arguments: args}}}, nil
+	}
+	return provider.ChatResponse{Content: "parent done"}, nil
+}
+
+func TestEngineWritesTranscriptForFailedSubagentRun(t *testing.T) {
+	cfg := config.Default()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	cfg.Runtime.Subagents.MaxAttempts = 1
+	cfg.Runtime.Subagents.NotifyOnComplete = false
+	cfg.Agents.Defaults.RetryMax = 0
+	store, err := storepkg.Open(filepath.Join(t.TempDir(), "transcript.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	engine, err := agent.NewEngine(cfg, &transcriptProvider{}, "test-model", store, nil, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := engine.Ask(ctx, agent.InboundMessage{SessionID: "cli:transcript", Channel: "cli", ChatID: "direct", Content: "delegate", CreatedAt: time.Now().UTC()}); err != nil {
+		t.Fatal(err)
+	}
+	runs, err := store.ListSubagentRunsBySession(ctx, "cli:transcript", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 1 || runs[0].Status != subagent.StatusFailed {
+		t.Fatalf("expected one failed run, got %+v", runs)
+	}
+	raw, err := os.ReadFile(subagent.TranscriptPath(runs[0]))
+	if err != nil {
+		t.Fatalf("expected transcript for failed run: %v", err)
+	}
+	var roles []string
+	sawCall := false
+	for _, line := range strings.Split(strings.TrimSpace(string(raw)), "\n") {
+		var message provider.Message
+		if err := json.Unmarshal([]byte(line), &message); err != nil {
+			t.Fatalf("invalid transcript line %q: %v", line, err)
+		}
+		roles = append(roles, message.Role)
+		for _, call := range message.ToolCalls {
+			sawCall = sawCall || call.Name == "list_dir"
+		}
+	}
+	if got := strings.Join(roles, ","); got != "system,user,assistant,tool" || !sawCall {
+		t.Fatalf("unexpected transcript roles %q (tool call seen: %v)", got, sawCall)
+	}
+}
+
+func TestEngineScheduledSubagentRunsRepeatInCronSession(t *testing.T) {
+	cfg := config.Default()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	cfg.Runtime.Subagents.MaxAttempts = 1
+	cfg.Runtime.Subagents.LoopThreshold = 2
+	store, err := storepkg.Open(filepath.Join(t.TempDir(), "cron.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	engine, err := agent.NewEngine(cfg, &fanoutProvider{}, "test-model", store, nil, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	// A nightly job repeats the same context on purpose, so it must not trip
+	// the loop guard.
+	var run subagent.Run
+	for i := 0; i < 3; i++ {
+		run, err = engine.SpawnScheduledSubagent(ctx, "job-1", "nightly", "research topic", 45, "", "")
+		if err != nil {
+			t.Fatalf("spawn %d: %v", i, err)
+		}
+	}
+	if run.SessionID != "cron:job-1" || run.Channel != agent.ChannelCron || run.TimeoutSec != 45 || run.NotifyOnComplete {
+		t.Fatalf("unexpected scheduled run: %+v", run)
+	}
+	done, err := engine.WaitSubagentRun(ctx, run.ID, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if done.Status != subagent.StatusSucceeded || done.Result == nil || !strings.Contains(done.Result.Summary, "research topic") {
+		t.Fatalf("unexpected scheduled run result: %+v", done)
+	}
+
+	run, err = engine.SpawnScheduledSubagent(ctx, "job-2", "report", "write report", 0, "telegram", "42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !run.NotifyOnComplete || run.Channel != "telegram" || run.ChatID != "42" {
+		t.Fatalf("expected delivery target on run, got %+v", run)
+	}
+	select {
+	case msg := <-engine.Outbound():
+		if msg.Channel != "telegram" || msg.ChatID != "42" || !strings.Contains(msg.Content, "[Subagent completed]") {
+			t.Fatalf("unexpected completion notice: %+v", msg)
+		}
+	case <-ctx.Done():
+		t.Fatal("expected a completion notice")
+	}
+}
@@ -0,0 +1,125 @@
+package agent
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	outboundLogCapacity     = 200
+	outboundLogContentChars = 280
+
+	OutboundQueued     = "queued"
+	OutboundDelivered  = "delivered"
+	OutboundFailed     = "failed"
+	OutboundDropped    = "dropped"
+	OutboundSuppressed = "suppressed"
+	OutboundDeferred   = "deferred"
+)
+
+// OutboundRecord is one entry in the engine's recent outbound log. Status
+// starts as queued (or dropped/suppressed when the message never reached the
+// outbound channel, or deferred while quiet hours hold it) and is updated once
+// the runtime reports delivery.
+type OutboundRecord struct {
+	ID        string    `json:"id"`
+	Channel   string    `json:"channel"`
+	ChatID    string    `json:"chat_id"`
+	TraceID   string    `json:"trace_id,omitempty"`
+	Content   string    `json:"content"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// outboundLog is a fixed-size ring buffer of recent outbound records.
+type outboundLog struct {
+	mu      sync.Mutex
+	entries []OutboundRecord
+	next    int
+	full    bool
+}
+
+func newOutboundLog(capacity int) *outboundLog {
+	return &outboundLog{entries: make([]OutboundRecord, capacity)}
+}
+
+func (l *outboundLog) add(record OutboundRecord) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries[l.next] = record
+	l.next = (l.next + 1) % len(l.entries)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+func (l *outboundLog) update(id, status, errText string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i := range l.entries {
+		if l.entries[i].ID == id && id != "" {
+			l.entries[i].Status = status
+			l.entries[i].Error = errText
+			l.entries[i].UpdatedAt = time.Now().UTC()
+			return true
+		}
+	}
+	return false
+}
+
+// recent returns up to limit records on channel with status, newest first.
+// An empty channel or status matches any.
+func (l *outboundLog) recent(limit int, channel, status string) []OutboundRecord {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	size := l.next
+	if l.full {
+		size = len(l.entries)
+	}
+	if limit <= 0 || limit > size {
+		limit = size
+	}
+	out := make([]OutboundRecord, 0, limit)
+	for i := 1; i <= size && len(out) < limit; i++ {
+		record := l.entries[(l.next-i+len(l.entries))%len(l.entries)]
+		if (channel == "" || record.Channel == channel) && (status == "" || record.Status == status) {
+			out = append(out, record)
+		}
+	}
+	return out
+}
+
+func (e *Engine) recordOutbound(id string, msg OutboundMessage, status string) {
+	traceID, _ := msg.Metadata["trace_id"].(string)
+	now := time.Now().UTC()
+	e.outboundLog.add(OutboundRecord{
+		ID:        id,
+		Channel:   msg.Channel,
+		ChatID:    msg.ChatID,
+		TraceID:   traceID,
+		Content:   truncateText(msg.Content, outboundLogContentChars),
+		Status:    status,
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+}
+
+// RecentOutbound returns up to limit recent outbound messages on channel with
+// status, newest first. An empty channel or status matches any.
+func (e *Engine) RecentOutbound(limit int, channel, status string) []OutboundRecord {
+	return e.outboundLog.recent(limit, channel, status)
+}
+
+// ReportOutboundDelivery records the result of handing msg to its channel
+// adapter. Messages without an outbound_id, or ones that have already left
+// the ring buffer, are ignored.
+func (e *Engine) ReportOutboundDelivery(msg OutboundMessage, err error) {
+	id, _ := msg.Metadata["outbound_id"].(string)
+	if err != nil {
+		e.outboundLog.update(id, OutboundFailed, err.Error())
+		return
+	}
+	e.outboundLog.update(id, OutboundDelivered, "")
+}
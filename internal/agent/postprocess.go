@@ -0,0 +1,125 @@
+package agent
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/grixate/squidbot/internal/config"
+)
+
+const (
+	PostProcessAppend       = "append"
+	PostProcessPrepend      = "prepend"
+	PostProcessRewriteLinks = "rewrite_links"
+	PostProcessMaxLength    = "max_length"
+)
+
+var postProcessLinkPattern = regexp.MustCompile(`https?://[^\s<>()\[\]"'` + "`" + `]+`)
+
+// ValidatePostProcessors reports configuration errors in a pipeline.
+func ValidatePostProcessors(steps []config.PostProcessorConfig) []string {
+	problems := []string{}
+	for idx, step := range steps {
+		switch strings.ToLower(strings.TrimSpace(step.Type)) {
+		case PostProcessAppend, PostProcessPrepend:
+			if step.Text == "" {
+				problems = append(problems, fmt.Sprintf("step %d (%s) requires text", idx+1, step.Type))
+			}
+		case PostProcessRewriteLinks:
+			if !strings.Contains(step.Template, "{url}") {
+				problems = append(problems, fmt.Sprintf("step %d (rewrite_links) template must contain {url}", idx+1))
+			}
+		case PostProcessMaxLength:
+			if step.MaxChars <= 0 {
+				problems = append(problems, fmt.Sprintf("step %d (max_length) requires maxChars > 0", idx+1))
+			}
+		default:
+			problems = append(problems, fmt.Sprintf("step %d has unknown type %q", idx+1, step.Type))
+		}
+	}
+	return problems
+}
+
+// postProcessorsFor returns the pipeline for a channel, falling back to the
+// default pipeline.
+func postProcessorsFor(cfg config.PostProcessorsConfig, channel string) []config.PostProcessorConfig {
+	channel = strings.TrimSpace(channel)
+	for name, steps := range cfg.Channels {
+		if strings.EqualFold(strings.TrimSpace(name), channel) {
+			return steps
+		}
+	}
+	return cfg.Default
+}
+
+// applyPostProcessors runs each step in order. Unknown or misconfigured steps
+// are skipped so a bad entry never drops a response.
+func applyPostProcessors(content string, steps []config.PostProcessorConfig) string {
+	for _, step := range steps {
+		switch strings.ToLower(strings.TrimSpace(step.Type)) {
+		case PostProcessAppend:
+			content = appendText(content, step.Text)
+		case PostProcessPrepend:
+			content = prependText(content, step.Text)
+		case PostProcessRewriteLinks:
+			content = rewriteLinks(content, step.Template)
+		case PostProcessMaxLength:
+			content = capLength(content, step.MaxChars, step.Suffix)
+		}
+	}
+	return content
+}
+
+func appendText(content, text string) string {
+	if text == "" {
+		return content
+	}
+	return content + text
+}
+
+func prependText(content, text string) string {
+	if text == "" {
+		return content
+	}
+	return text + content
+}
+
+// rewriteLinks routes every http(s) link through template, substituting the
+// query-escaped original for {url}. Links already pointing at the template's
+// prefix are left alone so the step is idempotent.
+func rewriteLinks(content, template string) string {
+	if !strings.Contains(template, "{url}") {
+		return content
+	}
+	prefix := template[:strings.Index(template, "{url}")]
+	return postProcessLinkPattern.ReplaceAllStringFunc(content, func(link string) string {
+		trimmed := strings.TrimRight(link, ".,;:!?")
+		trailing := link[len(trimmed):]
+		if prefix != "" && strings.HasPrefix(trimmed, prefix) {
+			return link
+		}
+		return strings.ReplaceAll(template, "{url}", url.QueryEscape(trimmed)) + trailing
+	})
+}
+
+// capLength limits content to maxChars runes including suffix.
+func capLength(content string, maxChars int, suffix string) string {
+	if maxChars <= 0 {
+		return content
+	}
+	runes := []rune(content)
+	if len(runes) <= maxChars {
+		return content
+	}
+	if suffix == "" {
+		suffix = "…"
+	}
+	suffixRunes := []rune(suffix)
+	keep := maxChars - len(suffixRunes)
+	if keep <= 0 {
+		return string(runes[:maxChars])
+	}
+	return strings.TrimRight(string(runes[:keep]), " \t\n") + suffix
+}
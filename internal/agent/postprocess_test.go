@@ -0,0 +1,67 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/grixate/squidbot/internal/config"
+)
+
+func TestAppendAndPrependText(t *testing.T) {
+	if got := appendText("hello", "\n-- squidbot"); got != "hello\n-- squidbot" {
+		t.Fatalf("unexpected append: %q", got)
+	}
+	if got := prependText("hello", "[bot] "); got != "[bot] hello" {
+		t.Fatalf("unexpected prepend: %q", got)
+	}
+}
+
+func TestRewriteLinks(t *testing.T) {
+	template := "https://t.example/r?u={url}"
+	got := rewriteLinks("See https://go.dev/doc?a=1, and (https://example.com).", template)
+	want := "See https://t.example/r?u=https%3A%2F%2Fgo.dev%2Fdoc%3Fa%3D1, and (https://t.example/r?u=https%3A%2F%2Fexample.com)."
+	if got != want {
+		t.Fatalf("unexpected rewrite:\n got %q\nwant %q", got, want)
+	}
+	if again := rewriteLinks(got, template); again != got {
+		t.Fatalf("rewrite should be idempotent, got %q", again)
+	}
+	if unchanged := rewriteLinks("https://go.dev", "https://t.example/r"); unchanged != "https://go.dev" {
+		t.Fatalf("template without {url} must be a no-op, got %q", unchanged)
+	}
+}
+
+func TestCapLength(t *testing.T) {
+	if got := capLength("short", 10, ""); got != "short" {
+		t.Fatalf("unexpected cap: %q", got)
+	}
+	if got := capLength("héllo wörld again", 10, ""); got != "héllo wör…" {
+		t.Fatalf("unexpected rune-aware cap: %q", got)
+	}
+	if got := capLength("abcdefghij", 6, " [more]"); got != "abcdef" {
+		t.Fatalf("suffix longer than limit should hard cut, got %q", got)
+	}
+}
+
+func TestApplyPostProcessorsUsesChannelPipeline(t *testing.T) {
+	cfg := config.PostProcessorsConfig{
+		Default: []config.PostProcessorConfig{{Type: "append", Text: " -- bot"}},
+		Channels: map[string][]config.PostProcessorConfig{
+			"Telegram": {
+				{Type: "max_length", MaxChars: 8},
+				{Type: "prepend", Text: "> "},
+				{Type: "unknown"},
+			},
+		},
+	}
+	if got := applyPostProcessors("hello", postProcessorsFor(cfg, "cli")); got != "hello -- bot" {
+		t.Fatalf("unexpected default pipeline result: %q", got)
+	}
+	if got := applyPostProcessors("hello world", postProcessorsFor(cfg, "telegram")); got != "> hello w…" {
+		t.Fatalf("unexpected channel pipeline result: %q", got)
+	}
+	problems := ValidatePostProcessors(cfg.Channels["Telegram"])
+	if len(problems) != 1 || !strings.Contains(problems[0], "unknown") {
+		t.Fatalf("expected unknown type problem, got %v", problems)
+	}
+}
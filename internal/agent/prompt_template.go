@@ -0,0 +1,155 @@
+package agent
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/grixate/squidbot/internal/config"
+)
+
+// promptContext carries the per-turn values that workspace prompt files can
+// reference as template variables. Warn, when set, receives notes about
+// unknown variables and templates that fail to render.
+type promptContext struct {
+	Channel   string
+	ChatID    string
+	SessionID string
+	Warn      func(string)
+}
+
+func (pc promptContext) warn(message string) {
+	if pc.Warn != nil {
+		pc.Warn(message)
+	}
+}
+
+// promptContext returns the template context for a turn, logging each
+// distinct warning once per engine.
+func (e *Engine) promptContext(channel, chatID, sessionID string) promptContext {
+	return promptContext{
+		Channel:   channel,
+		ChatID:    chatID,
+		SessionID: sessionID,
+		Warn: func(message string) {
+			if _, logged := e.promptWarnings.LoadOrStore(message, struct{}{}); !logged {
+				e.log.Printf("event=prompt_template_warning %s", message)
+			}
+		},
+	}
+}
+
+// promptLocation returns the zone named by agents.defaults.timezone, or the
+// local zone when it is unset or invalid.
+func promptLocation(cfg config.Config) *time.Location {
+	name := strings.TrimSpace(cfg.Agents.Defaults.Timezone)
+	if name == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// promptVars returns the variables available to prompt templates: the
+// agents.defaults.promptVars map overlaid by the built-in values, which win
+// on a name clash.
+func promptVars(cfg config.Config, pc promptContext, now time.Time) map[string]string {
+	loc := promptLocation(cfg)
+	local := now.In(loc)
+	vars := make(map[string]string, len(cfg.Agents.Defaults.PromptVars)+8)
+	for name, value := range cfg.Agents.Defaults.PromptVars {
+		vars[strings.TrimSpace(name)] = value
+	}
+	vars["Date"] = local.Format("2006-01-02")
+	vars["Time"] = local.Format("15:04")
+	vars["Weekday"] = local.Weekday().String()
+	vars["Timezone"] = loc.String()
+	vars["Channel"] = pc.Channel
+	vars["ChatID"] = pc.ChatID
+	vars["SessionID"] = pc.SessionID
+	vars["Workspace"] = config.WorkspacePath(cfg)
+	return vars
+}
+
+// renderPromptTemplate expands {{.Name}} references in text from vars.
+// Unknown variables render empty and are reported through pc; text that does
+// not parse or execute as a template is returned unchanged with a warning.
+func renderPromptTemplate(name, text string, vars map[string]string, pc promptContext) string {
+	if !strings.Contains(text, "{{") {
+		return text
+	}
+	tmpl, err := template.New(name).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		pc.warn(fmt.Sprintf("prompt template %s left unexpanded: %v", name, err))
+		return text
+	}
+	if unknown := unknownTemplateFields(tmpl.Tree, vars); len(unknown) > 0 {
+		pc.warn(fmt.Sprintf("prompt template %s references unknown variables: %s", name, strings.Join(unknown, ", ")))
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, vars); err != nil {
+		pc.warn(fmt.Sprintf("prompt template %s left unexpanded: %v", name, err))
+		return text
+	}
+	return out.String()
+}
+
+// unknownTemplateFields lists the top-level .Name references in tree that
+// vars does not define, sorted and deduplicated.
+func unknownTemplateFields(tree *parse.Tree, vars map[string]string) []string {
+	seen := map[string]struct{}{}
+	var walk func(node parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.IfNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				for _, arg := range cmd.Args {
+					walk(arg)
+				}
+			}
+		case *parse.FieldNode:
+			if _, ok := vars[n.Ident[0]]; !ok {
+				seen[n.Ident[0]] = struct{}{}
+			}
+		}
+	}
+	if tree != nil {
+		walk(tree.Root)
+	}
+	unknown := make([]string, 0, len(seen))
+	for name := range seen {
+		unknown = append(unknown, name)
+	}
+	sort.Strings(unknown)
+	return unknown
+}
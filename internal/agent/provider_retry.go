@@ -0,0 +1,138 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/grixate/squidbot/internal/budget"
+	"github.com/grixate/squidbot/internal/config"
+	"github.com/grixate/squidbot/internal/provider"
+)
+
+const maxProviderRetryBackoff = 30 * time.Second
+
+// providerCall is one budget-guarded provider request.
+type providerCall struct {
+	settings budget.Settings
+	scopes   []budget.ScopeLimit
+	planned  uint64
+	req      provider.ChatRequest
+	// turnSlot holds a maxConcurrentTurns slot for the duration of each
+	// attempt; it is released while waiting to retry.
+	turnSlot bool
+}
+
+// chatWithRetry reserves budget, sends the request, and retries transient
+// provider errors up to agents.defaults.retryMax times with exponential
+// backoff. Each failed attempt releases its reservation before the next
+// preflight, and a retry is skipped when its wait would outlast ctx. The
+// returned preflight belongs to the successful attempt.
+func (e *Engine) chatWithRetry(ctx context.Context, cfg config.Config, client provider.LLMProvider, call providerCall) (provider.ChatResponse, budget.PreflightResult, error) {
+	retryMax := max(cfg.Agents.Defaults.RetryMax, 0)
+	for attempt := 0; ; attempt++ {
+		preflight, err := e.budgetGuard.Preflight(ctx, call.settings, call.scopes, call.planned)
+		if err != nil {
+			return provider.ChatResponse{}, budget.PreflightResult{}, err
+		}
+		release := func() {}
+		if call.turnSlot {
+			release, err = e.acquireTurnSlot(ctx)
+			if err != nil {
+				e.budgetGuard.Abort(context.WithoutCancel(ctx), preflight)
+				return provider.ChatResponse{}, budget.PreflightResult{}, err
+			}
+		}
+		e.metrics.ProviderCalls.Add(1)
+		resp, err := e.chatWithModelFallback(ctx, client, call.req)
+		release()
+		if err == nil {
+			return resp, preflight, nil
+		}
+		e.budgetGuard.Abort(context.WithoutCancel(ctx), preflight)
+		e.metrics.ProviderErrors.Add(1)
+		if attempt >= retryMax || !provider.IsRetryable(err) {
+			return resp, budget.PreflightResult{}, err
+		}
+		wait := providerRetryBackoff(cfg, attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= wait {
+			return resp, budget.PreflightResult{}, err
+		}
+		e.metrics.ProviderRetries.Add(1)
+		e.log.Printf("INFO event=provider_retry attempt=%d/%d wait=%s err=%v", attempt+1, retryMax, wait, err)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return resp, budget.PreflightResult{}, err
+		case <-timer.C:
+		}
+	}
+}
+
+// chatInternal sends a request the engine makes on its own behalf, such as a
+// history summary, session title, or memory rollup, through the same budget
+// guard, retries, and turn slots as a chat turn, and records its usage. A
+// non-empty sessionID adds that session's budget scope. req.Model defaults to
+// the active model.
+func (e *Engine) chatInternal(ctx context.Context, sessionID string, req provider.ChatRequest) (provider.ChatResponse, error) {
+	client, model := e.currentProviderModel()
+	if client == nil {
+		return provider.ChatResponse{}, fmt.Errorf("no provider configured")
+	}
+	if req.Model == "" {
+		req.Model = model
+	}
+	cfg := e.currentConfig()
+	settings := e.effectiveTokenSafety(ctx)
+	scopeLimits := []budget.ScopeLimit{
+		{Key: "global", HardLimitTokens: settings.GlobalHardLimitTokens, SoftThresholdPct: settings.GlobalSoftThresholdPct},
+	}
+	if sessionID = strings.TrimSpace(sessionID); sessionID != "" {
+		scopeLimits = append(scopeLimits, budget.ScopeLimit{
+			Key:              "session:" + sessionID,
+			HardLimitTokens:  settings.SessionHardLimitTokens,
+			SoftThresholdPct: settings.SessionSoftThresholdPct,
+		})
+	}
+	resp, preflight, err := e.chatWithRetry(ctx, cfg, client, providerCall{
+		settings: settings,
+		scopes:   scopeLimits,
+		planned:  uint64(max(req.MaxTokens, 1)),
+		req:      req,
+		turnSlot: true,
+	})
+	if err != nil {
+		return resp, err
+	}
+	e.recordUsageTokens(resp.Usage)
+	commit, commitErr := e.budgetGuard.Commit(ctx, settings, scopeLimits, preflight, budget.Usage{
+		PromptTokens:     resp.Usage.PromptTokens,
+		CompletionTokens: resp.Usage.CompletionTokens,
+		TotalTokens:      resp.Usage.TotalTokens,
+		OutputChars:      len(resp.Content),
+	})
+	if commitErr != nil {
+		e.log.Printf("failed to commit token budget usage: %v", commitErr)
+	}
+	e.recordUsageDay(ctx, resp.Model,
+		uint64(max(resp.Usage.PromptTokens, 0)),
+		uint64(max(resp.Usage.CompletionTokens, 0)),
+		commit.TotalTokens,
+		uint64(max(resp.Usage.CacheReadTokens, 0)),
+		uint64(max(resp.Usage.CacheWriteTokens, 0)),
+	)
+	return resp, nil
+}
+
+func providerRetryBackoff(cfg config.Config, attempt int) time.Duration {
+	wait := time.Duration(max(cfg.Agents.Defaults.RetryBackoffMs, 1)) * time.Millisecond
+	for i := 0; i < attempt && wait < maxProviderRetryBackoff; i++ {
+		wait *= 2
+	}
+	if wait > maxProviderRetryBackoff {
+		wait = maxProviderRetryBackoff
+	}
+	return wait
+}
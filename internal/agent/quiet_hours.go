@@ -0,0 +1,221 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/grixate/squidbot/internal/config"
+)
+
+const (
+	quietHoursNamespace = "quiet_hours"
+	quietHoursQueueKey  = "deferred"
+)
+
+// QuietHoursUntil reports whether now falls inside the configured quiet
+// hours and, if so, when they end. A disabled window is never quiet.
+func QuietHoursUntil(quiet config.QuietHoursConfig, now time.Time) (time.Time, bool, error) {
+	if !quiet.Enabled {
+		return time.Time{}, false, nil
+	}
+	start, err := parseClock("runtime.quietHours.start", quiet.Start)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	end, err := parseClock("runtime.quietHours.end", quiet.End)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	if start == end {
+		return time.Time{}, false, fmt.Errorf("runtime.quietHours.start and end must differ")
+	}
+	loc := time.Local
+	if name := strings.TrimSpace(quiet.Timezone); name != "" {
+		loc, err = time.LoadLocation(name)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid runtime.quietHours.timezone %q: %w", name, err)
+		}
+	}
+	local := now.In(loc)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	at := func(day time.Time, minutes int) time.Time {
+		return time.Date(day.Year(), day.Month(), day.Day(), minutes/60, minutes%60, 0, 0, loc)
+	}
+	current := local.Hour()*60 + local.Minute()
+	switch {
+	case start < end:
+		if current >= start && current < end {
+			return at(midnight, end), true, nil
+		}
+	case current >= start:
+		return at(midnight.AddDate(0, 0, 1), end), true, nil
+	case current < end:
+		return at(midnight, end), true, nil
+	}
+	return time.Time{}, false, nil
+}
+
+func parseClock(name, value string) (int, error) {
+	parsed, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q (want HH:MM)", name, value)
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+// isProactiveOutbound reports whether a message was sent on the engine's own
+// initiative rather than in reply to a user turn. Replies carry no source.
+// The message tool counts as a reply inside a user turn, but heartbeat and
+// cron turns can only reach anyone through it, so there it is proactive.
+func isProactiveOutbound(metadata map[string]any) bool {
+	source, _ := metadata["source"].(string)
+	source = strings.TrimSpace(source)
+	if source != "tool:message" {
+		return source != ""
+	}
+	sessionID, _ := metadata["session_id"].(string)
+	sessionID = strings.ToLower(strings.TrimSpace(sessionID))
+	return strings.HasPrefix(sessionID, "system:") || strings.HasPrefix(sessionID, "cron:")
+}
+
+// deferOutbound holds msg until quiet hours end. It reports false when the
+// message should go out now.
+func (e *Engine) deferOutbound(outboundID string, msg OutboundMessage) bool {
+	if !isProactiveOutbound(msg.Metadata) {
+		return false
+	}
+	until, quiet, err := QuietHoursUntil(e.currentConfig().Runtime.QuietHours, time.Now())
+	if err != nil {
+		e.log.Printf("quiet hours ignored: %v", err)
+		return false
+	}
+	if !quiet {
+		return false
+	}
+	e.deferredMu.Lock()
+	e.deferred = append(e.deferred, msg)
+	e.persistDeferredLocked()
+	if e.deferredStop != nil {
+		e.armDeferredLocked(time.Until(until))
+	}
+	e.deferredMu.Unlock()
+	e.metrics.OutboundDeferred.Add(1)
+	e.recordOutbound(outboundID, msg, OutboundDeferred)
+	e.log.Printf("event=outbound_deferred channel=%s chat_id=%s until=%s", msg.Channel, msg.ChatID, until.UTC().Format(time.RFC3339))
+	return true
+}
+
+// FlushDeferredOutbound hands every held message to the outbound channel,
+// unless quiet hours are still in effect, in which case it waits for them to
+// end. It waits for room in the channel rather than dropping messages, and
+// does nothing before StartDeferredDelivery. It returns how many messages
+// were released.
+func (e *Engine) FlushDeferredOutbound() int {
+	until, quiet, err := QuietHoursUntil(e.currentConfig().Runtime.QuietHours, time.Now())
+	if err != nil {
+		quiet = false
+	}
+	e.deferredMu.Lock()
+	stop := e.deferredStop
+	if stop == nil {
+		e.deferredMu.Unlock()
+		return 0
+	}
+	if quiet {
+		if len(e.deferred) > 0 {
+			e.armDeferredLocked(time.Until(until))
+		}
+		e.deferredMu.Unlock()
+		return 0
+	}
+	held := e.deferred
+	e.deferred = nil
+	e.deferredMu.Unlock()
+	for i, msg := range held {
+		select {
+		case e.outbound <- msg:
+			e.metrics.OutboundCount.Add(1)
+		case <-stop:
+			// Shutting down: keep the rest for the next gateway.
+			e.deferredMu.Lock()
+			e.deferred = append(held[i:], e.deferred...)
+			e.persistDeferredLocked()
+			e.deferredMu.Unlock()
+			return i
+		}
+		id, _ := msg.Metadata["outbound_id"].(string)
+		if !e.outboundLog.update(id, OutboundQueued, "") {
+			e.recordOutbound(id, msg, OutboundQueued)
+		}
+	}
+	e.deferredMu.Lock()
+	e.persistDeferredLocked()
+	e.deferredMu.Unlock()
+	return len(held)
+}
+
+// StartDeferredDelivery begins releasing held messages, including any kept
+// from before a restart, once quiet hours allow. Only the gateway calls it:
+// other runtimes have no consumer for Outbound, so they keep held messages
+// stored for the next gateway run instead.
+func (e *Engine) StartDeferredDelivery() {
+	e.deferredMu.Lock()
+	defer e.deferredMu.Unlock()
+	if e.deferredStop != nil {
+		return
+	}
+	e.deferredStop = make(chan struct{})
+	e.armDeferredLocked(0)
+}
+
+// DeferredOutbound returns the number of messages held for quiet hours.
+func (e *Engine) DeferredOutbound() int {
+	e.deferredMu.Lock()
+	defer e.deferredMu.Unlock()
+	return len(e.deferred)
+}
+
+// restoreDeferredOutbound reloads messages held before a restart, so that
+// messages deferred later are stored alongside them. Delivery waits for
+// StartDeferredDelivery.
+func (e *Engine) restoreDeferredOutbound() {
+	raw, err := e.store.GetKV(context.Background(), quietHoursNamespace, quietHoursQueueKey)
+	if err != nil || len(raw) == 0 {
+		return
+	}
+	var held []OutboundMessage
+	if err := json.Unmarshal(raw, &held); err != nil {
+		e.log.Printf("failed to load deferred outbound messages: %v", err)
+		return
+	}
+	if len(held) == 0 {
+		return
+	}
+	e.deferredMu.Lock()
+	e.deferred = append(held, e.deferred...)
+	e.deferredMu.Unlock()
+}
+
+func (e *Engine) armDeferredLocked(wait time.Duration) {
+	if e.deferredTimer != nil {
+		e.deferredTimer.Stop()
+	}
+	if wait < 0 {
+		wait = 0
+	}
+	e.deferredTimer = time.AfterFunc(wait, func() { e.FlushDeferredOutbound() })
+}
+
+func (e *Engine) persistDeferredLocked() {
+	raw, err := json.Marshal(e.deferred)
+	if err != nil {
+		e.log.Printf("failed to encode deferred outbound messages: %v", err)
+		return
+	}
+	if err := e.store.PutKV(context.Background(), quietHoursNamespace, quietHoursQueueKey, raw); err != nil {
+		e.log.Printf("failed to persist deferred outbound messages: %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package agent
+
+import "strings"
+
+// Reserved channel names. None of them maps to a channel adapter, so replies
+// on these channels are never delivered:
+//
+//   - cli: replies are returned to the caller (Ask/AskStream) directly.
+//   - system: internal automations (heartbeat, rollups, self-maintenance).
+//     The full turn runs and is recorded, but nothing is sent.
+//   - cron: scheduled jobs; delivery only happens when the job payload names
+//     a target channel explicitly.
+const (
+	ChannelCLI    = "cli"
+	ChannelSystem = "system"
+	ChannelCron   = "cron"
+)
+
+// heartbeatSessionID is the system session the default heartbeat profile
+// runs in.
+const heartbeatSessionID = "system:heartbeat"
+
+// HeartbeatSessionID returns the system session a heartbeat profile runs in,
+// so each profile keeps its own history. The default profile keeps the
+// original system:heartbeat session.
+func HeartbeatSessionID(profile string) string {
+	profile = strings.ToLower(strings.TrimSpace(profile))
+	if profile == "" || profile == "default" {
+		return heartbeatSessionID
+	}
+	return heartbeatSessionID + ":" + profile
+}
+
+// isHeartbeatSession reports whether sessionID belongs to a heartbeat profile.
+func isHeartbeatSession(sessionID string) bool {
+	sessionID = strings.ToLower(strings.TrimSpace(sessionID))
+	return sessionID == heartbeatSessionID || strings.HasPrefix(sessionID, heartbeatSessionID+":")
+}
+
+// IsReservedChannel reports whether channel is one of the reserved names.
+func IsReservedChannel(channel string) bool {
+	switch strings.ToLower(strings.TrimSpace(channel)) {
+	case ChannelCLI, ChannelSystem, ChannelCron:
+		return true
+	}
+	return false
+}
+
+// isInternalChannel reports whether outbound messages addressed to channel
+// must be dropped. CLI is excluded because the interactive REPL reads async
+// notices from the outbound stream.
+func isInternalChannel(channel string) bool {
+	switch strings.ToLower(strings.TrimSpace(channel)) {
+	case ChannelSystem, ChannelCron:
+		return true
+	}
+	return false
+}
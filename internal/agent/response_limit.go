@@ -0,0 +1,112 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const (
+	// ResponseOverflowSplit sends an over-long reply as several messages.
+	ResponseOverflowSplit = "split"
+	// ResponseOverflowTruncate cuts an over-long reply and notes how much was
+	// left out.
+	ResponseOverflowTruncate = "truncate"
+)
+
+// sendReply delivers a turn's reply to a chat channel, applying
+// agents.defaults.maxResponseChars. Every part carries the same metadata.
+func (e *Engine) sendReply(channel, chatID, content string, metadata map[string]interface{}) {
+	defaults := e.currentConfig().Agents.Defaults
+	parts := limitResponse(content, defaults.MaxResponseChars, defaults.ResponseOverflow)
+	for idx, part := range parts {
+		partMeta := make(map[string]interface{}, len(metadata)+2)
+		for k, v := range metadata {
+			partMeta[k] = v
+		}
+		if len(parts) > 1 {
+			partMeta["part"] = idx + 1
+			partMeta["parts"] = len(parts)
+		}
+		e.send(channel, chatID, part, partMeta)
+	}
+}
+
+// limitResponse returns content as the messages to send under a limit of
+// maxChars runes. A limit of zero or less returns content unchanged.
+func limitResponse(content string, maxChars int, overflow string) []string {
+	if maxChars <= 0 || len([]rune(content)) <= maxChars {
+		return []string{content}
+	}
+	if strings.EqualFold(strings.TrimSpace(overflow), ResponseOverflowTruncate) {
+		return []string{truncateResponse(content, maxChars)}
+	}
+	return splitResponse(content, maxChars)
+}
+
+// splitResponse breaks content into parts of at most maxChars runes,
+// preferring paragraph breaks, then sentence ends, then spaces, and cutting
+// mid-word only when a part has none of them.
+func splitResponse(content string, maxChars int) []string {
+	remaining := []rune(strings.TrimSpace(content))
+	parts := make([]string, 0, len(remaining)/maxChars+1)
+	for len(remaining) > maxChars {
+		cut := responseBreak(remaining[:maxChars+1])
+		part := strings.TrimSpace(string(remaining[:cut]))
+		if part != "" {
+			parts = append(parts, part)
+		}
+		remaining = []rune(strings.TrimLeftFunc(string(remaining[cut:]), unicode.IsSpace))
+	}
+	if len(remaining) > 0 {
+		parts = append(parts, string(remaining))
+	}
+	return parts
+}
+
+// responseBreak returns where to end a part inside window, whose last rune
+// would overflow it. Breaks in the first third are ignored so parts do not
+// come out tiny.
+func responseBreak(window []rune) int {
+	limit := len(window) - 1
+	floor := limit / 3
+	if idx := strings.LastIndex(string(window[:limit]), "\n\n"); idx >= 0 {
+		if cut := len([]rune(string(window[:limit])[:idx])); cut > floor {
+			return cut
+		}
+	}
+	for i := limit; i > floor; i-- {
+		if !unicode.IsSpace(window[i]) {
+			continue
+		}
+		switch window[i-1] {
+		case '.', '!', '?', '\n':
+			return i
+		}
+	}
+	for i := limit; i > floor; i-- {
+		if unicode.IsSpace(window[i]) {
+			return i
+		}
+	}
+	return limit
+}
+
+// truncateResponse cuts content to at most maxChars runes, marker included,
+// ending on a space when one is near the cut.
+func truncateResponse(content string, maxChars int) string {
+	runes := []rune(content)
+	marker := fmt.Sprintf(" [+%d chars omitted]", len(runes))
+	keep := maxChars - len([]rune(marker))
+	if keep <= 0 {
+		return string(runes[:maxChars])
+	}
+	for i := keep; i > keep/2; i-- {
+		if unicode.IsSpace(runes[i]) {
+			keep = i
+			break
+		}
+	}
+	kept := strings.TrimRightFunc(string(runes[:keep]), unicode.IsSpace)
+	return fmt.Sprintf("%s [+%d chars omitted]", kept, len(runes)-len([]rune(kept)))
+}
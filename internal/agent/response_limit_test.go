@@ -0,0 +1,47 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestLimitResponseSplitsOnSentences(t *testing.T) {
+	content := "First sentence is here. Second one follows it! Third asks a question? Fourth closes."
+	parts := limitResponse(content, 40, "")
+	want := []string{"First sentence is here.", "Second one follows it!", "Third asks a question? Fourth closes."}
+	if strings.Join(parts, "|") != strings.Join(want, "|") {
+		t.Fatalf("expected %q, got %q", want, parts)
+	}
+	for _, part := range parts {
+		if len([]rune(part)) > 40 {
+			t.Fatalf("part %q exceeds the limit", part)
+		}
+	}
+	if got := limitResponse(content, 0, ""); len(got) != 1 || got[0] != content {
+		t.Fatalf("expected no limit to keep the reply whole, got %q", got)
+	}
+	long := strings.Repeat("x", 25)
+	if got := limitResponse(long, 10, ResponseOverflowSplit); strings.Join(got, "") != long || len(got) != 3 {
+		t.Fatalf("expected a word with no breaks to be cut hard, got %q", got)
+	}
+}
+
+func TestLimitResponseTruncatesWithMarker(t *testing.T) {
+	content := strings.Repeat("word ", 40)
+	got := limitResponse(content, 60, ResponseOverflowTruncate)
+	if len(got) != 1 {
+		t.Fatalf("expected one message, got %q", got)
+	}
+	if len([]rune(got[0])) > 60 {
+		t.Fatalf("truncated reply %q exceeds the limit", got[0])
+	}
+	kept, marker, ok := strings.Cut(got[0], " [+")
+	if !ok || !strings.HasSuffix(marker, " chars omitted]") || strings.HasSuffix(kept, "wor") {
+		t.Fatalf("unexpected truncated reply %q", got[0])
+	}
+	omitted := len([]rune(content)) - len([]rune(kept))
+	if !strings.Contains(got[0], fmt.Sprintf("[+%d chars omitted]", omitted)) {
+		t.Fatalf("expected %d omitted chars in %q", omitted, got[0])
+	}
+}
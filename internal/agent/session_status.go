@@ -0,0 +1,45 @@
+package agent
+
+import (
+	"sort"
+	"time"
+)
+
+// SessionStatus is a stored session joined with the live actor set. A
+// session that is not live has been evicted after runtime.actorIdleTtl, or
+// was never started by the running process; its next message resumes it from
+// the checkpoint.
+type SessionStatus struct {
+	SessionRecord
+	LastActive time.Time `json:"last_active"`
+	Live       bool      `json:"live"`
+}
+
+// SessionStatuses merges records with live, as returned by LiveSessions,
+// most recently active first. Live sessions without stored metadata are
+// included.
+func SessionStatuses(records []SessionRecord, live map[string]time.Time) []SessionStatus {
+	statuses := make([]SessionStatus, 0, len(records))
+	seen := make(map[string]struct{}, len(records))
+	for _, record := range records {
+		status := SessionStatus{SessionRecord: record, LastActive: record.UpdatedAt}
+		if lastSeen, ok := live[record.SessionID]; ok {
+			status.Live = true
+			if lastSeen.After(status.LastActive) {
+				status.LastActive = lastSeen
+			}
+		}
+		seen[record.SessionID] = struct{}{}
+		statuses = append(statuses, status)
+	}
+	for sessionID, lastSeen := range live {
+		if _, ok := seen[sessionID]; ok {
+			continue
+		}
+		statuses = append(statuses, SessionStatus{SessionRecord: SessionRecord{SessionID: sessionID}, LastActive: lastSeen, Live: true})
+	}
+	sort.SliceStable(statuses, func(i, j int) bool {
+		return statuses[i].LastActive.After(statuses[j].LastActive)
+	})
+	return statuses
+}
@@ -0,0 +1,128 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/grixate/squidbot/internal/config"
+	"github.com/grixate/squidbot/internal/provider"
+)
+
+const (
+	sessionTitleKey        = "title"
+	sessionTitleSourceKey  = "title_source"
+	sessionTitleUpdatedKey = "titled_at"
+	sessionTitleTimeout    = 20 * time.Second
+	sessionTitleInputChars = 2000
+)
+
+// ensureSessionTitle stores a title for the session after its first exchange.
+// Existing titles are left alone; use RefreshSessionTitle to replace one.
+func (e *Engine) ensureSessionTitle(ctx context.Context, sessionID, userMessage string) {
+	cfg := e.currentConfig()
+	if !cfg.Runtime.SessionTitles.Enabled || strings.TrimSpace(userMessage) == "" {
+		return
+	}
+	meta, err := e.store.GetSessionMeta(ctx, sessionID)
+	if err != nil {
+		return
+	}
+	if existing, _ := meta[sessionTitleKey].(string); strings.TrimSpace(existing) != "" {
+		return
+	}
+	if _, err := e.saveSessionTitle(ctx, cfg, sessionID, userMessage, userMessage); err != nil {
+		e.log.Printf("failed to store session title: %v", err)
+	}
+}
+
+// RefreshSessionTitle regenerates the title from the session's recent user
+// messages and stores it, replacing any existing title.
+func (e *Engine) RefreshSessionTitle(ctx context.Context, sessionID string) (string, error) {
+	sessionID = strings.TrimSpace(sessionID)
+	if sessionID == "" {
+		return "", fmt.Errorf("session id is required")
+	}
+	history, err := e.store.Window(ctx, sessionID, 50)
+	if err != nil {
+		return "", err
+	}
+	userMessages := make([]string, 0, len(history))
+	for _, message := range history {
+		if message.Role == "user" && strings.TrimSpace(message.Content) != "" {
+			userMessages = append(userMessages, strings.TrimSpace(message.Content))
+		}
+	}
+	if len(userMessages) == 0 {
+		return "", fmt.Errorf("session %s has no user messages to title", sessionID)
+	}
+	return e.saveSessionTitle(ctx, e.currentConfig(), sessionID, userMessages[0], strings.Join(userMessages, "\n"))
+}
+
+func (e *Engine) saveSessionTitle(ctx context.Context, cfg config.Config, sessionID, firstMessage, conversation string) (string, error) {
+	title, source := e.generateSessionTitle(ctx, cfg, sessionID, firstMessage, conversation)
+	if title == "" {
+		return "", fmt.Errorf("could not derive a title for session %s", sessionID)
+	}
+	err := e.store.SaveSessionMeta(ctx, sessionID, map[string]any{
+		sessionTitleKey:        title,
+		sessionTitleSourceKey:  source,
+		sessionTitleUpdatedKey: time.Now().UTC(),
+	})
+	return title, err
+}
+
+// generateSessionTitle asks the active provider for a title when
+// sessionTitles.useProvider is set, falling back to truncating the first
+// user message if the call fails or returns nothing usable.
+func (e *Engine) generateSessionTitle(ctx context.Context, cfg config.Config, sessionID, firstMessage, conversation string) (string, string) {
+	maxChars := cfg.Runtime.SessionTitles.MaxChars
+	if maxChars <= 0 {
+		maxChars = 60
+	}
+	if cfg.Runtime.SessionTitles.UseProvider {
+		if title := e.providerSessionTitle(ctx, sessionID, conversation, maxChars); title != "" {
+			return title, "provider"
+		}
+	}
+	return heuristicSessionTitle(firstMessage, maxChars), "heuristic"
+}
+
+func (e *Engine) providerSessionTitle(ctx context.Context, sessionID, conversation string, maxChars int) string {
+	callCtx, cancel := context.WithTimeout(ctx, sessionTitleTimeout)
+	defer cancel()
+	resp, err := e.chatInternal(callCtx, sessionID, provider.ChatRequest{
+		Messages: []provider.Message{
+			{Role: "system", Content: fmt.Sprintf("Write a short title (at most %d characters) for a conversation that starts with the user messages below. Reply with the title only, without quotes or trailing punctuation.", maxChars)},
+			{Role: "user", Content: truncateText(conversation, sessionTitleInputChars)},
+		},
+		MaxTokens:   32,
+		Temperature: 0.2,
+	})
+	if err != nil {
+		e.log.Printf("session title provider call failed: %v", err)
+		return ""
+	}
+	title := strings.TrimSpace(resp.Content)
+	if idx := strings.IndexByte(title, '\n'); idx >= 0 {
+		title = title[:idx]
+	}
+	title = strings.Trim(strings.TrimSpace(title), "\"'`*#. ")
+	return heuristicSessionTitle(title, maxChars)
+}
+
+// heuristicSessionTitle collapses whitespace in text and cuts it to maxChars
+// runes, preferring a word boundary.
+func heuristicSessionTitle(text string, maxChars int) string {
+	title := strings.Join(strings.Fields(text), " ")
+	runes := []rune(title)
+	if len(runes) <= maxChars {
+		return title
+	}
+	cut := string(runes[:maxChars])
+	if idx := strings.LastIndexByte(cut, ' '); idx > len(cut)/2 {
+		cut = cut[:idx]
+	}
+	return strings.TrimRight(cut, " ,.;:-") + "…"
+}
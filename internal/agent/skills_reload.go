@@ -0,0 +1,55 @@
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/grixate/squidbot/internal/skills"
+)
+
+// ReloadSkills rediscovers skills in the live runtime, so the next turn
+// activates against the files on disk, and logs a summary.
+func (e *Engine) ReloadSkills(ctx context.Context) (skills.IndexSnapshot, error) {
+	snapshot, _, err := e.refreshSkills(ctx, true)
+	return snapshot, err
+}
+
+// RefreshSkills rediscovers skills and logs a summary only when the index
+// changed. The gateway calls it every skills.refreshIntervalSec.
+func (e *Engine) RefreshSkills(ctx context.Context) (bool, error) {
+	_, changed, err := e.refreshSkills(ctx, false)
+	return changed, err
+}
+
+func (e *Engine) refreshSkills(ctx context.Context, forced bool) (skills.IndexSnapshot, bool, error) {
+	if e.skills == nil {
+		return skills.IndexSnapshot{}, false, nil
+	}
+	snapshot, err := e.skills.Reload(ctx)
+	if err != nil {
+		return skills.IndexSnapshot{}, false, err
+	}
+	digest := skillsDigest(snapshot)
+	e.skillsDigestMu.Lock()
+	changed := digest != e.skillsDigest
+	e.skillsDigest = digest
+	e.skillsDigestMu.Unlock()
+	if forced || changed {
+		valid, invalid := snapshot.Counts()
+		e.log.Printf("event=skills_reloaded forced=%v changed=%v total=%d valid=%d invalid=%d warnings=%d", forced, changed, len(snapshot.Skills), valid, invalid, len(snapshot.Warnings))
+	}
+	return snapshot, changed, nil
+}
+
+// skillsDigest fingerprints the discovered descriptors, so a refresh can
+// tell an edited, added, or removed skill from an unchanged index.
+func skillsDigest(snapshot skills.IndexSnapshot) string {
+	raw, _ := json.Marshal(struct {
+		Skills   []skills.SkillDescriptor
+		Warnings []string
+	}{snapshot.Skills, snapshot.Warnings})
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
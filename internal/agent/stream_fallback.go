@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"context"
+	"strings"
+
+	"github.com/grixate/squidbot/internal/provider"
+)
+
+// streamReply sends req through client.Stream, forwarding text deltas to
+// sink, and returns the streamed text. A provider whose
+// config sets streaming to false gets one buffered Chat instead, whose reply
+// reaches the caller only in the final event; the switch is logged once per
+// provider. Provider errors are reported to sink; sink errors are returned as
+// they are.
+func (e *Engine) streamReply(ctx context.Context, client provider.LLMProvider, providerName string, streaming bool, req provider.ChatRequest, sink StreamSink) (string, error) {
+	if !streaming {
+		if _, logged := e.streamFallbacks.LoadOrStore(providerName, struct{}{}); !logged {
+			e.log.Printf("event=stream_fallback provider=%s reason=config", providerName)
+		}
+		return e.bufferedReply(ctx, client, req, sink)
+	}
+	events, errs := client.Stream(ctx, req)
+	var final strings.Builder
+	for events != nil || errs != nil {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			// Tools are not offered on this path, so a tool call never runs
+			// and gets no tool events.
+			if event.DeltaContent != "" {
+				final.WriteString(event.DeltaContent)
+				if err := sink.OnEvent(ctx, StreamEvent{Type: "assistant_delta", Delta: event.DeltaContent}); err != nil {
+					return "", err
+				}
+			}
+		case streamErr, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			if streamErr != nil {
+				_ = sink.OnEvent(ctx, StreamEvent{Type: "error", Error: streamErr.Error(), Done: true})
+				return "", streamErr
+			}
+		}
+	}
+	return final.String(), nil
+}
+
+func (e *Engine) bufferedReply(ctx context.Context, client provider.LLMProvider, req provider.ChatRequest, sink StreamSink) (string, error) {
+	resp, err := client.Chat(ctx, req)
+	if err != nil {
+		_ = sink.OnEvent(ctx, StreamEvent{Type: "error", Error: err.Error(), Done: true})
+		return "", err
+	}
+	return resp.Content, nil
+}
@@ -0,0 +1,56 @@
+package agent
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/grixate/squidbot/internal/provider"
+	"github.com/grixate/squidbot/internal/subagent"
+)
+
+// readInlineAttachment returns the content of path when it is a text file of
+// at most maxBytes, so it can travel inside a subagent context packet.
+func readInlineAttachment(path string, maxBytes int) (string, bool) {
+	if maxBytes <= 0 {
+		return "", false
+	}
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() || info.Size() > int64(maxBytes) {
+		return "", false
+	}
+	content, err := os.ReadFile(path)
+	if err != nil || len(content) > maxBytes || !utf8.Valid(content) || bytes.IndexByte(content, 0) >= 0 {
+		return "", false
+	}
+	return string(content), true
+}
+
+// subagentAttachmentMessages renders a packet's attachments for the
+// subagent: one message per inlined file, then the remaining paths. inlining
+// reports whether inlining is enabled, which adds a note that the listed
+// files were left out for size or content.
+func subagentAttachmentMessages(packet subagent.ContextPacket, inlining bool) []provider.Message {
+	messages := make([]provider.Message, 0, len(packet.InlineAttachments)+1)
+	inlined := make(map[string]struct{}, len(packet.InlineAttachments))
+	for _, item := range packet.InlineAttachments {
+		inlined[item.Path] = struct{}{}
+		messages = append(messages, provider.Message{Role: "user", Content: fmt.Sprintf("Attachment %s:\n```\n%s\n```", item.Path, strings.TrimRight(item.Content, "\n"))})
+	}
+	paths := make([]string, 0, len(packet.Attachments))
+	for _, path := range packet.Attachments {
+		if _, ok := inlined[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return messages
+	}
+	header := "Attachment paths available in workspace:"
+	if inlining || len(packet.InlineAttachments) > 0 {
+		header = "Attachment paths available in workspace (too large or not text to inline; read them with read_file):"
+	}
+	return append(messages, provider.Message{Role: "user", Content: header + "\n- " + strings.Join(paths, "\n- ")})
+}
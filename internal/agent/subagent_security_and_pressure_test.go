@@ -28,6 +28,7 @@ type scriptedParentProvider struct {
 	subDelay      time.Duration
 	spawnCount    int
 	cancelRunID   string
+	subMessages   [][]provider.Message
 }
 
 func (p *scriptedParentProvider) Capabilities() provider.ProviderCapabilities {
@@ -52,6 +53,7 @@ func (p *scriptedParentProvider) Chat(ctx context.Context, req provider.ChatRequ
 		p.mu.Lock()
 		call := p.subagentCalls
 		p.subagentCalls++
+		p.subMessages = append(p.subMessages, req.Messages)
 		p.mu.Unlock()
 		if p.mode == "write_block" && call%2 == 0 {
 			args, _ := json.Marshal(map[string]any{"path": "proof.txt", "content": "blocked"})
@@ -72,6 +74,12 @@ func (p *scriptedParentProvider) Chat(ctx context.Context, req provider.ChatRequ
 			return provider.ChatResponse{ToolCalls: []provider.ToolCall{{ID: "s1", Name: "spawn", Arguments: args}}}, nil
 		}
 		return provider.ChatResponse{Content: "done"}, nil
+	case "attachment_inline":
+		if call%2 == 0 {
+			args, _ := json.Marshal(map[string]any{"task": "read attachments", "attachments": []string{"notes.txt", "big.txt"}, "wait": true})
+			return provider.ChatResponse{ToolCalls: []provider.ToolCall{{ID: "s1", Name: "spawn", Arguments: args}}}, nil
+		}
+		return provider.ChatResponse{Content: "done"}, nil
 	case "write_block":
 		if call == 0 {
 			args, _ := json.Marshal(map[string]any{"task": "attempt write", "wait": true})
@@ -131,6 +139,91 @@ func TestSubagentSecurityRejectsAttachmentOutsideWorkspace(t *testing.T) {
 	}
 }
 
+func TestSubagentInlinesSmallAttachments(t *testing.T) {
+	workspace := t.TempDir()
+	cfg := config.Default()
+	cfg.Agents.Defaults.Workspace = workspace
+	cfg.Runtime.Subagents.NotifyOnComplete = false
+	cfg.Runtime.Subagents.InlineAttachmentMaxBytes = 64
+	if err := os.WriteFile(filepath.Join(workspace, "notes.txt"), []byte("first draft\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(workspace, "big.txt"), []byte(strings.Repeat("x", 65)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := storepkg.Open(filepath.Join(t.TempDir(), "inline.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	providerStub := &scriptedParentProvider{mode: "attachment_inline"}
+	engine, err := agent.NewEngine(cfg, providerStub, "test-model", store, nil, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	ask := func() subagent.Run {
+		t.Helper()
+		if _, err := engine.Ask(context.Background(), agent.InboundMessage{SessionID: "cli:inline", Channel: "cli", ChatID: "direct", SenderID: "user", Content: "spawn", CreatedAt: time.Now().UTC()}); err != nil {
+			t.Fatal(err)
+		}
+		runs, err := store.ListSubagentRunsBySession(context.Background(), "cli:inline", 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(runs) == 0 {
+			t.Fatal("expected a subagent run")
+		}
+		latest := runs[0]
+		for _, run := range runs[1:] {
+			if run.CreatedAt.After(latest.CreatedAt) {
+				latest = run
+			}
+		}
+		return latest
+	}
+
+	first := ask()
+	if len(first.Context.Attachments) != 2 {
+		t.Fatalf("expected both attachment paths to be kept, got %#v", first.Context.Attachments)
+	}
+	if len(first.Context.InlineAttachments) != 1 || first.Context.InlineAttachments[0].Content != "first draft\n" {
+		t.Fatalf("expected only notes.txt to be inlined, got %#v", first.Context.InlineAttachments)
+	}
+	providerStub.mu.Lock()
+	messages := providerStub.subMessages[0]
+	providerStub.mu.Unlock()
+	var inlined, listed string
+	for _, message := range messages {
+		switch {
+		case strings.HasPrefix(message.Content, "Attachment "+first.Context.InlineAttachments[0].Path+":"):
+			inlined = message.Content
+		case strings.HasPrefix(message.Content, "Attachment paths available in workspace"):
+			listed = message.Content
+		}
+	}
+	if !strings.Contains(inlined, "first draft") {
+		t.Fatalf("expected inlined content in subagent messages, got %#v", messages)
+	}
+	if !strings.Contains(listed, "big.txt") || strings.Contains(listed, "notes.txt") || !strings.Contains(listed, "read_file") {
+		t.Fatalf("expected only big.txt listed by path with a read_file note, got %q", listed)
+	}
+
+	if err := os.WriteFile(filepath.Join(workspace, "notes.txt"), []byte("second draft\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	second := ask()
+	if second.ID == first.ID {
+		t.Fatal("expected a second run")
+	}
+	if second.Context.Checksum == first.Context.Checksum {
+		t.Fatal("expected checksum to change with inlined content")
+	}
+}
+
 func TestSubagentSecurityDisablesWriteToolsAndKeepsArtifactsInTree(t *testing.T) {
 	workspace := t.TempDir()
 	cfg := config.Default()
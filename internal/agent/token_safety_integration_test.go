@@ -61,6 +61,12 @@ func (p *tokenSafetyProvider) Chat(ctx context.Context, req provider.ChatRequest
 		default:
 			return provider.ChatResponse{Content: "done", Usage: provider.Usage{TotalTokens: 1}}, nil
 		}
+	case "trusted_reset_session":
+		if call == 0 {
+			args, _ := json.Marshal(map[string]any{"scope": "session"})
+			return provider.ChatResponse{ToolCalls: []provider.ToolCall{{ID: "rs-1", Name: "budget_reset", Arguments: args}}, Usage: provider.Usage{TotalTokens: 1}}, nil
+		}
+		return provider.ChatResponse{Content: "done", Usage: provider.Usage{TotalTokens: 1}}, nil
 	case "untrusted_set_enabled":
 		if call == 0 {
 			args, _ := json.Marshal(map[string]any{"enabled": false})
@@ -137,6 +143,53 @@ func TestBudgetToolsTrustedWriterCanUpdateAndReadStatus(t *testing.T) {
 	}
 }
 
+func TestBudgetResetToolClearsSessionCounter(t *testing.T) {
+	workspace := t.TempDir()
+	cfg := config.Default()
+	cfg.Agents.Defaults.Workspace = workspace
+	cfg.Agents.Defaults.MaxTokens = 1
+	cfg.Runtime.TokenSafety.SessionHardLimitTokens = 1000
+	store, err := storepkg.Open(filepath.Join(t.TempDir(), "token-reset.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+	if err := store.AddBudgetUsage(ctx, "session:cli:budget3", 400, 100, 500); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.ReserveBudget(ctx, "session:cli:budget3", 400, 3600); err != nil {
+		t.Fatal(err)
+	}
+	engine, err := agent.NewEngine(cfg, &tokenSafetyProvider{mode: "trusted_reset_session"}, "test-model", store, nil, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	resp, err := engine.Ask(ctx, agent.InboundMessage{
+		SessionID: "cli:budget3",
+		Channel:   "cli",
+		ChatID:    "direct",
+		SenderID:  "user",
+		Content:   "reset my budget",
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(resp, "done") {
+		t.Fatalf("unexpected response: %q", resp)
+	}
+	counter, err := store.GetBudgetCounter(ctx, "session:cli:budget3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counter.ReservedTokens != 0 || counter.TotalTokens >= 500 {
+		t.Fatalf("expected session counter to be reset, got %+v", counter)
+	}
+}
+
 func TestBudgetToolsRejectUntrustedWriter(t *testing.T) {
 	workspace := t.TempDir()
 	cfg := config.Default()
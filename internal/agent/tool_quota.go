@@ -0,0 +1,39 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/grixate/squidbot/internal/config"
+	"github.com/grixate/squidbot/internal/tools"
+)
+
+// consumeToolQuota counts a call against the tool's daily limit. When the
+// limit is already reached it returns the result to hand back to the model
+// in place of running the tool. Store failures are logged and the call is
+// allowed, so a broken counter never disables a tool.
+func (e *Engine) consumeToolQuota(ctx context.Context, cfg config.Config, name string) (tools.ToolResult, bool) {
+	limit := cfg.ToolDailyLimit(name)
+	if limit <= 0 {
+		return tools.ToolResult{}, true
+	}
+	day := time.Now().UTC().Format("2006-01-02")
+	usage, allowed, err := e.store.ConsumeToolQuota(ctx, day, name, limit)
+	if err != nil {
+		e.log.Printf("tool quota check failed for %s: %v", name, err)
+		return tools.ToolResult{}, true
+	}
+	if allowed {
+		return tools.ToolResult{}, true
+	}
+	e.metrics.ToolQuotaBlocked.Add(1)
+	return tools.ToolResult{
+		Text: fmt.Sprintf("tool quota exceeded: %s has reached its daily limit of %d calls; it resets at 00:00 UTC. Continue without it.", name, limit),
+		Metadata: map[string]any{
+			"quota_exceeded": true,
+			"quota_limit":    limit,
+			"quota_blocked":  usage.Blocked,
+		},
+	}, false
+}
@@ -0,0 +1,43 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/grixate/squidbot/internal/config"
+)
+
+func TestToolTimeoutUsesPerToolOverride(t *testing.T) {
+	cfg := config.Default()
+	cfg.Agents.Defaults.ToolTimeoutSec = 45
+	cfg.Tools.Timeouts = map[string]int{"exec": 300, "read_file": 5, "web_fetch": 0}
+
+	cases := map[string]time.Duration{
+		"exec":      300 * time.Second,
+		"read_file": 5 * time.Second,
+		"web_fetch": 45 * time.Second,
+		"list_dir":  45 * time.Second,
+	}
+	for name, want := range cases {
+		if got := toolTimeout(cfg, name); got != want {
+			t.Fatalf("%s: expected %s, got %s", name, want, got)
+		}
+	}
+
+	cfg.Agents.Defaults.ToolTimeoutSec = 0
+	if got := toolTimeout(cfg, "list_dir"); got != 60*time.Second {
+		t.Fatalf("expected 60s fallback, got %s", got)
+	}
+}
+
+func TestPartialTurnContentShortensRequestByRune(t *testing.T) {
+	got := partialTurnContent("", []string{"web_fetch"}, strings.Repeat("é", 150))
+	if !utf8.ValidString(got) {
+		t.Fatalf("expected valid UTF-8, got %q", got)
+	}
+	if !strings.Contains(got, strings.Repeat("é", 117)+"...") || strings.Contains(got, strings.Repeat("é", 118)) {
+		t.Fatalf("expected the request cut to 117 runes, got %q", got)
+	}
+}
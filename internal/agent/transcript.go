@@ -0,0 +1,141 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/grixate/squidbot/internal/config"
+)
+
+const (
+	TranscriptJSON     = "json"
+	TranscriptMarkdown = "markdown"
+)
+
+// Transcript is a session's full stored conversation plus its metadata.
+type Transcript struct {
+	SessionID  string         `json:"session_id"`
+	Title      string         `json:"title,omitempty"`
+	Meta       map[string]any `json:"meta,omitempty"`
+	ExportedAt time.Time      `json:"exported_at"`
+	Turns      []Turn         `json:"turns"`
+}
+
+// LoadTranscript reads every turn and the metadata of a session.
+func LoadTranscript(ctx context.Context, store ConversationStore, sessionID string) (Transcript, error) {
+	turns, err := store.SessionTurns(ctx, sessionID)
+	if err != nil {
+		return Transcript{}, err
+	}
+	meta, err := store.GetSessionMeta(ctx, sessionID)
+	if err != nil {
+		return Transcript{}, err
+	}
+	title, _ := meta[sessionTitleKey].(string)
+	return Transcript{SessionID: sessionID, Title: title, Meta: meta, ExportedAt: time.Now().UTC(), Turns: turns}, nil
+}
+
+// NormalizeTranscriptFormat maps user input to json or markdown ("md" is
+// accepted); anything else is an error.
+func NormalizeTranscriptFormat(format string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", TranscriptJSON:
+		return TranscriptJSON, nil
+	case TranscriptMarkdown, "md":
+		return TranscriptMarkdown, nil
+	}
+	return "", fmt.Errorf("unsupported transcript format %q (use json or markdown)", format)
+}
+
+// RenderTranscript encodes t as indented JSON or as markdown.
+func RenderTranscript(t Transcript, format string) ([]byte, error) {
+	format, err := NormalizeTranscriptFormat(format)
+	if err != nil {
+		return nil, err
+	}
+	if format == TranscriptJSON {
+		raw, err := json.MarshalIndent(t, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(raw, '\n'), nil
+	}
+	var out bytes.Buffer
+	heading := t.SessionID
+	if strings.TrimSpace(t.Title) != "" {
+		heading = t.Title
+	}
+	fmt.Fprintf(&out, "# %s\n\n", heading)
+	fmt.Fprintf(&out, "- Session: `%s`\n- Exported: %s\n- Turns: %d\n", t.SessionID, t.ExportedAt.Format(time.RFC3339), len(t.Turns))
+	for _, turn := range t.Turns {
+		fmt.Fprintf(&out, "\n## %s · %s\n\n%s\n", turn.Role, turn.CreatedAt.UTC().Format(time.RFC3339), strings.TrimSpace(turn.Content))
+	}
+	return out.Bytes(), nil
+}
+
+// TranscriptFileName returns a filesystem-safe file name for a session
+// archived at the given time. The sanitized session ID is followed by a short
+// hash of the raw ID, so IDs that sanitize alike do not collide, and by the
+// UTC timestamp, so each archive keeps its own file.
+func TranscriptFileName(sessionID, format string, at time.Time) string {
+	ext := ".json"
+	if format == TranscriptMarkdown {
+		ext = ".md"
+	}
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		}
+		return '_'
+	}, sessionID)
+	if strings.Trim(name, "._") == "" {
+		name = "session"
+	}
+	sum := sha256.Sum256([]byte(sessionID))
+	return fmt.Sprintf("%s-%s-%s%s", name, hex.EncodeToString(sum[:4]), at.UTC().Format("20060102T150405Z"), ext)
+}
+
+// ArchiveSession writes the session transcript to a new file in the archive
+// directory; earlier archives of the session are kept. Sessions without turns
+// are skipped and return an empty path.
+func (e *Engine) ArchiveSession(ctx context.Context, sessionID string) (string, error) {
+	cfg := e.currentConfig()
+	format, err := NormalizeTranscriptFormat(cfg.Runtime.ArchiveFormat)
+	if err != nil {
+		return "", err
+	}
+	transcript, err := LoadTranscript(ctx, e.store, sessionID)
+	if err != nil {
+		return "", err
+	}
+	if len(transcript.Turns) == 0 {
+		return "", nil
+	}
+	raw, err := RenderTranscript(transcript, format)
+	if err != nil {
+		return "", err
+	}
+	dir := config.ArchivePath(cfg)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, TranscriptFileName(sessionID, format, transcript.ExportedAt))
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o600); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return "", err
+	}
+	return path, nil
+}
@@ -99,10 +99,22 @@ type ToolEvent struct {
 	Version   int             `json:"version"`
 }
 
+// SessionRecord is the stored metadata for one session, such as the last
+// delivery target and the generated title.
+type SessionRecord struct {
+	SessionID string         `json:"session_id"`
+	Meta      map[string]any `json:"meta"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	Version   int            `json:"version"`
+}
+
 type ConversationStore interface {
 	AppendTurn(ctx context.Context, turn Turn) error
 	Window(ctx context.Context, sessionID string, limit int) ([]provider.Message, error)
+	SessionTurns(ctx context.Context, sessionID string) ([]Turn, error)
+	SearchTurns(ctx context.Context, sessionID, query string, limit int) ([]Turn, error)
 	SaveSessionMeta(ctx context.Context, sessionID string, meta map[string]any) error
+	GetSessionMeta(ctx context.Context, sessionID string) (map[string]any, error)
 }
 
 type KVStore interface {
@@ -127,7 +139,8 @@ type MissionStore interface {
 	ListMissionTasks(ctx context.Context) ([]mission.Task, error)
 	ReplaceMissionColumns(ctx context.Context, columns []mission.Column) error
 	ListMissionColumns(ctx context.Context) ([]mission.Column, error)
-	RecordUsageDay(ctx context.Context, day string, promptTokens, completionTokens, totalTokens uint64) error
+	RecordUsageDay(ctx context.Context, day, label string, promptTokens, completionTokens, totalTokens, cacheReadTokens, cacheWriteTokens uint64) error
+	RecordProviderThroughput(ctx context.Context, day, label string, completionTokens uint64, latency time.Duration) error
 	GetTaskAutomationPolicy(ctx context.Context) (mission.TaskAutomationPolicy, error)
 }
 
@@ -148,6 +161,9 @@ type BudgetStore interface {
 	FinalizeBudgetReservation(ctx context.Context, reservationID string, actualTotal uint64) error
 	CancelBudgetReservation(ctx context.Context, reservationID string) error
 	ListBudgetReservations(ctx context.Context, scope string, limit int) ([]budget.Reservation, error)
+	ResetBudgetCounter(ctx context.Context, scope string) (budget.ResetResult, error)
+	ConsumeToolQuota(ctx context.Context, day, tool string, limit int) (budget.ToolUsage, bool, error)
+	ListToolUsage(ctx context.Context, day string) ([]budget.ToolUsage, error)
 }
 
 type FederationStore interface {
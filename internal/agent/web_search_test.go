@@ -0,0 +1,44 @@
+package agent
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/grixate/squidbot/internal/config"
+)
+
+func TestWebSearchOptionsUsesProviderEntryAndLogsFallback(t *testing.T) {
+	var logs bytes.Buffer
+	engine := &Engine{log: log.New(&logs, "", 0)}
+	cfg := config.Default()
+	cfg.Tools.Web.Search.Provider = "searxng"
+	cfg.Tools.Web.Search.APIKey = "brave-key"
+	cfg.Tools.Web.Search.Providers = map[string]config.WebSearchProviderConfig{
+		"searxng": {BaseURL: "http://127.0.0.1:8888"},
+		"tavily":  {APIKey: "tvly-key"},
+	}
+	opts := engine.webSearchOptions(cfg)
+	if opts.Provider != "searxng" || opts.BaseURL != "http://127.0.0.1:8888" || opts.APIKey != "brave-key" {
+		t.Fatalf("unexpected searxng options %+v", opts)
+	}
+	if logs.Len() != 0 {
+		t.Fatalf("expected no fallback log for a known provider, got %q", logs.String())
+	}
+
+	cfg.Tools.Web.Search.Provider = "tavily"
+	if opts := engine.webSearchOptions(cfg); opts.APIKey != "tvly-key" || opts.BaseURL != "" {
+		t.Fatalf("unexpected tavily options %+v", opts)
+	}
+
+	cfg.Tools.Web.Search.Provider = "bing"
+	for i := 0; i < 2; i++ {
+		if opts := engine.webSearchOptions(cfg); opts.Provider != "brave" || opts.APIKey != "brave-key" {
+			t.Fatalf("expected brave fallback, got %+v", opts)
+		}
+	}
+	if got := strings.Count(logs.String(), "event=web_search_fallback"); got != 1 || !strings.Contains(logs.String(), `provider="bing"`) {
+		t.Fatalf("expected one fallback warning, got %q", logs.String())
+	}
+}
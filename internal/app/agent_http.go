@@ -0,0 +1,258 @@
+package app
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+
+	"github.com/grixate/squidbot/internal/agent"
+)
+
+const (
+	agentAPIChannel         = "api"
+	agentAPIMaxPending      = 256
+	agentAPIMaxResults      = 1024
+	agentAPIResultRetention = time.Hour
+)
+
+type agentAPIMessageRequest struct {
+	SessionID string         `json:"session_id"`
+	ChatID    string         `json:"chat_id"`
+	SenderID  string         `json:"sender_id"`
+	Content   string         `json:"content"`
+	Async     bool           `json:"async"`
+	Metadata  map[string]any `json:"metadata,omitempty"`
+}
+
+type agentAPIResult struct {
+	RequestID  string     `json:"request_id"`
+	SessionID  string     `json:"session_id"`
+	Status     string     `json:"status"`
+	Content    string     `json:"content,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+type agentAPIResults struct {
+	mu      sync.Mutex
+	entries map[string]agentAPIResult
+}
+
+func (r *Runtime) startAgentAPI(ctx context.Context) {
+	if r == nil || r.Engine == nil {
+		return
+	}
+	cfg := r.Config.Runtime.AgentAPI
+	if !cfg.Enabled {
+		return
+	}
+	listenAddr := strings.TrimSpace(cfg.ListenAddr)
+	if listenAddr == "" {
+		return
+	}
+	if strings.TrimSpace(cfg.AuthToken) == "" {
+		r.log.Printf("agent api disabled: runtime.agentApi.authToken is required")
+		return
+	}
+	r.agentAPISrv = &http.Server{Addr: listenAddr, Handler: r.agentAPIHandler(ctx)}
+	go func() {
+		<-ctx.Done()
+		_ = r.agentAPISrv.Shutdown(context.Background())
+	}()
+	go func() {
+		if err := r.agentAPISrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			r.log.Printf("agent api stopped: %v", err)
+		}
+	}()
+}
+
+func (r *Runtime) agentAPIHandler(ctx context.Context) http.Handler {
+	results := &agentAPIResults{entries: map[string]agentAPIResult{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/agent/message", func(w http.ResponseWriter, req *http.Request) {
+		r.handleAgentAPIMessage(ctx, results, w, req)
+	})
+	mux.HandleFunc("/api/agent/messages/", func(w http.ResponseWriter, req *http.Request) {
+		r.handleAgentAPIResult(results, w, req)
+	})
+	return mux
+}
+
+func (r *Runtime) agentAPIAuth(req *http.Request) (int, string) {
+	expected := strings.TrimSpace(r.Config.Runtime.AgentAPI.AuthToken)
+	if expected == "" {
+		return http.StatusNotFound, "agent api disabled"
+	}
+	authz := strings.TrimSpace(req.Header.Get("Authorization"))
+	if !strings.HasPrefix(authz, "Bearer ") {
+		return http.StatusUnauthorized, "missing bearer token"
+	}
+	token := strings.TrimSpace(strings.TrimPrefix(authz, "Bearer "))
+	if subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+		return http.StatusUnauthorized, "invalid token"
+	}
+	return 0, ""
+}
+
+func (r *Runtime) handleAgentAPIMessage(ctx context.Context, results *agentAPIResults, w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if status, msg := r.agentAPIAuth(req); status != 0 {
+		http.Error(w, msg, status)
+		return
+	}
+	var payload agentAPIMessageRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, req.Body, 1<<20)).Decode(&payload); err != nil {
+		http.Error(w, "invalid json body", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(payload.Content) == "" {
+		http.Error(w, "content is required", http.StatusBadRequest)
+		return
+	}
+	chatID := strings.TrimSpace(payload.ChatID)
+	if chatID == "" {
+		chatID = "default"
+	}
+	senderID := strings.TrimSpace(payload.SenderID)
+	if senderID == "" {
+		senderID = "api"
+	}
+	// API callers stay in api: sessions; any other ID is nested under the
+	// prefix so a caller cannot append turns to another channel's session.
+	sessionID := strings.TrimSpace(payload.SessionID)
+	if sessionID == "" {
+		sessionID = chatID
+	}
+	if !strings.HasPrefix(sessionID, agentAPIChannel+":") {
+		sessionID = agentAPIChannel + ":" + sessionID
+	}
+	now := time.Now().UTC()
+	msg := agent.InboundMessage{
+		SessionID: sessionID,
+		RequestID: "api-" + ulid.Make().String(),
+		Channel:   agentAPIChannel,
+		ChatID:    chatID,
+		SenderID:  senderID,
+		Content:   payload.Content,
+		Metadata:  payload.Metadata,
+		CreatedAt: now,
+	}
+	if err := r.admitInbound(msg); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	timeout := time.Duration(max(r.Config.Runtime.AgentAPI.RequestTimeoutSec, 1)) * time.Second
+
+	if !payload.Async {
+		askCtx, cancel := context.WithTimeout(req.Context(), timeout)
+		defer cancel()
+		content, err := r.Engine.Ask(askCtx, msg)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		finished := time.Now().UTC()
+		writeFederationJSON(w, http.StatusOK, agentAPIResult{
+			RequestID:  msg.RequestID,
+			SessionID:  sessionID,
+			Status:     "done",
+			Content:    content,
+			CreatedAt:  now,
+			FinishedAt: &finished,
+		})
+		return
+	}
+
+	pending := agentAPIResult{RequestID: msg.RequestID, SessionID: sessionID, Status: "pending", CreatedAt: now}
+	if !results.put(pending, true) {
+		http.Error(w, "too many pending requests", http.StatusTooManyRequests)
+		return
+	}
+	go func() {
+		askCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		content, err := r.Engine.Ask(askCtx, msg)
+		finished := time.Now().UTC()
+		result := pending
+		result.FinishedAt = &finished
+		if err != nil {
+			result.Status = "error"
+			result.Error = err.Error()
+		} else {
+			result.Status = "done"
+			result.Content = content
+		}
+		results.put(result, false)
+	}()
+	writeFederationJSON(w, http.StatusAccepted, pending)
+}
+
+func (r *Runtime) handleAgentAPIResult(results *agentAPIResults, w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if status, msg := r.agentAPIAuth(req); status != 0 {
+		http.Error(w, msg, status)
+		return
+	}
+	requestID := strings.Trim(strings.TrimPrefix(req.URL.Path, "/api/agent/messages/"), "/")
+	result, ok := results.get(requestID)
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+	writeFederationJSON(w, http.StatusOK, result)
+}
+
+// put stores a result, pruning finished entries past retention. New entries
+// are refused while agentAPIMaxPending requests are still running; finished
+// results waiting to be polled do not count. Past agentAPIMaxResults stored
+// entries, the oldest finished results are evicted to make room.
+func (s *agentAPIResults) put(result agentAPIResult, isNew bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now().UTC()
+	pending := 0
+	var finished []agentAPIResult
+	for id, entry := range s.entries {
+		if entry.FinishedAt == nil {
+			pending++
+			continue
+		}
+		if now.Sub(*entry.FinishedAt) > agentAPIResultRetention {
+			delete(s.entries, id)
+			continue
+		}
+		finished = append(finished, entry)
+	}
+	if isNew && pending >= agentAPIMaxPending {
+		return false
+	}
+	if isNew && len(s.entries) >= agentAPIMaxResults {
+		sort.Slice(finished, func(i, j int) bool { return finished[i].FinishedAt.Before(*finished[j].FinishedAt) })
+		for _, entry := range finished[:min(len(s.entries)-agentAPIMaxResults+1, len(finished))] {
+			delete(s.entries, entry.RequestID)
+		}
+	}
+	s.entries[result.RequestID] = result
+	return true
+}
+
+func (s *agentAPIResults) get(requestID string) (agentAPIResult, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result, ok := s.entries[requestID]
+	return result, ok
+}
@@ -0,0 +1,178 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grixate/squidbot/internal/agent"
+	"github.com/grixate/squidbot/internal/config"
+	"github.com/grixate/squidbot/internal/provider"
+	storepkg "github.com/grixate/squidbot/internal/storage/bbolt"
+)
+
+type echoProvider struct{}
+
+func (echoProvider) Capabilities() provider.ProviderCapabilities {
+	return provider.ProviderCapabilities{SupportsTools: true}
+}
+
+func (echoProvider) Stream(ctx context.Context, req provider.ChatRequest) (<-chan provider.StreamEvent, <-chan error) {
+	events := make(chan provider.StreamEvent)
+	errs := make(chan error, 1)
+	close(events)
+	close(errs)
+	return events, errs
+}
+
+func (echoProvider) Chat(ctx context.Context, req provider.ChatRequest) (provider.ChatResponse, error) {
+	last := req.Messages[len(req.Messages)-1]
+	return provider.ChatResponse{Content: "echo: " + last.Content}, nil
+}
+
+func TestAgentAPIMessageSyncAndAsync(t *testing.T) {
+	cfg := config.Default()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	cfg.Runtime.AgentAPI.Enabled = true
+	cfg.Runtime.AgentAPI.AuthToken = "secret"
+
+	store, err := storepkg.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	logger := log.New(io.Discard, "", 0)
+	engine, err := agent.NewEngine(cfg, echoProvider{}, "test-model", store, nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	runtime := &Runtime{Config: cfg, Store: store, Engine: engine, log: logger}
+	server := httptest.NewServer(runtime.agentAPIHandler(context.Background()))
+	defer server.Close()
+
+	post := func(token string, body map[string]any) *http.Response {
+		t.Helper()
+		raw, _ := json.Marshal(body)
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/api/agent/message", bytes.NewReader(raw))
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := post("wrong", map[string]any{"content": "hi"})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected unauthorized, got %d", resp.StatusCode)
+	}
+
+	resp = post("secret", map[string]any{"content": "hello"})
+	var syncResult agentAPIResult
+	_ = json.NewDecoder(resp.Body).Decode(&syncResult)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || syncResult.Status != "done" || syncResult.Content != "echo: hello" {
+		t.Fatalf("unexpected sync result: %d %+v", resp.StatusCode, syncResult)
+	}
+
+	resp = post("secret", map[string]any{"content": "other channel", "session_id": "telegram:123"})
+	var scoped agentAPIResult
+	_ = json.NewDecoder(resp.Body).Decode(&scoped)
+	resp.Body.Close()
+	if scoped.SessionID != "api:telegram:123" {
+		t.Fatalf("expected the session kept under api:, got %q", scoped.SessionID)
+	}
+	if turns, err := store.Window(context.Background(), "telegram:123", 10); err != nil || len(turns) != 0 {
+		t.Fatalf("expected no turns in the telegram session, got %d (err=%v)", len(turns), err)
+	}
+
+	resp = post("secret", map[string]any{"content": "later", "async": true, "session_id": "api:async"})
+	var pending agentAPIResult
+	_ = json.NewDecoder(resp.Body).Decode(&pending)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted || pending.RequestID == "" {
+		t.Fatalf("unexpected async ack: %d %+v", resp.StatusCode, pending)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/agent/messages/"+pending.RequestID, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var polled agentAPIResult
+		_ = json.NewDecoder(resp.Body).Decode(&polled)
+		resp.Body.Close()
+		if polled.Status == "done" {
+			if polled.Content != "echo: later" {
+				t.Fatalf("unexpected async content: %+v", polled)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("async request did not finish: %+v", polled)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestAgentAPIResultsLimitCountsOnlyPendingRequests(t *testing.T) {
+	results := &agentAPIResults{entries: map[string]agentAPIResult{}}
+	finished := time.Now().UTC()
+	for i := 0; i < agentAPIMaxPending; i++ {
+		entry := agentAPIResult{RequestID: fmt.Sprintf("done-%d", i), Status: "done", FinishedAt: &finished}
+		if !results.put(entry, true) {
+			t.Fatalf("expected finished result %d to be stored", i)
+		}
+	}
+	for i := 0; i < agentAPIMaxPending; i++ {
+		if !results.put(agentAPIResult{RequestID: fmt.Sprintf("pending-%d", i), Status: "pending"}, true) {
+			t.Fatalf("expected pending request %d to be admitted despite unread results", i)
+		}
+	}
+	if results.put(agentAPIResult{RequestID: "one-too-many", Status: "pending"}, true) {
+		t.Fatal("expected the pending limit to refuse a new request")
+	}
+	done := results.entries["pending-0"]
+	done.Status, done.FinishedAt = "done", &finished
+	results.put(done, false)
+	if !results.put(agentAPIResult{RequestID: "after-finish", Status: "pending"}, true) {
+		t.Fatal("expected a finished request to free a pending slot")
+	}
+}
+
+func TestAgentAPIResultsEvictOldestFinishedPastCap(t *testing.T) {
+	results := &agentAPIResults{entries: map[string]agentAPIResult{}}
+	base := time.Now().UTC().Add(-time.Minute)
+	for i := 0; i < agentAPIMaxResults; i++ {
+		finished := base.Add(time.Duration(i) * time.Millisecond)
+		if !results.put(agentAPIResult{RequestID: fmt.Sprintf("done-%d", i), Status: "done", FinishedAt: &finished}, true) {
+			t.Fatalf("expected finished result %d to be stored", i)
+		}
+	}
+	if !results.put(agentAPIResult{RequestID: "pending", Status: "pending"}, true) {
+		t.Fatal("expected a new request to be admitted at the cap")
+	}
+	if len(results.entries) != agentAPIMaxResults {
+		t.Fatalf("expected the store capped at %d entries, got %d", agentAPIMaxResults, len(results.entries))
+	}
+	if _, ok := results.get("done-0"); ok {
+		t.Fatal("expected the oldest finished result evicted")
+	}
+	if _, ok := results.get("done-1"); !ok {
+		t.Fatal("expected newer finished results kept")
+	}
+}
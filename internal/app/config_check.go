@@ -0,0 +1,141 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/grixate/squidbot/internal/agent"
+	"github.com/grixate/squidbot/internal/tools"
+)
+
+// ConfigCheck validates the settings StartGateway would otherwise only log
+// and skip at serve time, without binding listeners or starting channels.
+func (r *Runtime) ConfigCheck() error {
+	cfg := r.Config
+	var errs []error
+	checkAddr := func(name, addr string) {
+		addr = strings.TrimSpace(addr)
+		if addr == "" {
+			errs = append(errs, fmt.Errorf("%s enabled but listenAddr missing", name))
+			return
+		}
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			errs = append(errs, fmt.Errorf("%s listenAddr %q invalid: %w", name, addr, err))
+		}
+	}
+
+	if cfg.Features.MetricsHTTP || cfg.Runtime.MetricsHTTP.Enabled {
+		checkAddr("runtime.metricsHttp", cfg.Runtime.MetricsHTTP.ListenAddr)
+	}
+	if cfg.Runtime.AgentAPI.Enabled {
+		checkAddr("runtime.agentApi", cfg.Runtime.AgentAPI.ListenAddr)
+		if strings.TrimSpace(cfg.Runtime.AgentAPI.AuthToken) == "" {
+			errs = append(errs, errors.New("runtime.agentApi enabled but authToken missing"))
+		}
+	}
+	if cfg.Runtime.Federation.Enabled {
+		checkAddr("runtime.federation", cfg.Runtime.Federation.ListenAddr)
+		for _, peer := range cfg.Runtime.Federation.Peers {
+			if !peer.Enabled {
+				continue
+			}
+			if strings.TrimSpace(peer.ID) == "" {
+				errs = append(errs, errors.New("federation peer enabled but id missing"))
+				continue
+			}
+			if strings.TrimSpace(peer.BaseURL) == "" {
+				errs = append(errs, fmt.Errorf("federation peer %q enabled but baseUrl missing", peer.ID))
+			}
+		}
+	}
+	switch mode := strings.ToLower(strings.TrimSpace(cfg.Runtime.Subagents.DefaultContextMode)); mode {
+	case "", "minimal", "session", "session_memory":
+	default:
+		errs = append(errs, fmt.Errorf("runtime.subagents.defaultContextMode %q must be minimal, session, or session_memory", cfg.Runtime.Subagents.DefaultContextMode))
+	}
+	if name := strings.TrimSpace(cfg.Agents.Defaults.Timezone); name != "" {
+		if _, err := time.LoadLocation(name); err != nil {
+			errs = append(errs, fmt.Errorf("invalid agents.defaults.timezone %q: %w", name, err))
+		}
+	}
+	if summary := cfg.Runtime.HistorySummary; summary.Enabled {
+		if summary.MaxTurns <= 0 && summary.MaxChars <= 0 {
+			errs = append(errs, errors.New("runtime.historySummary is enabled but neither maxTurns nor maxChars is set"))
+		}
+		if summary.KeepRecent < 0 {
+			errs = append(errs, errors.New("runtime.historySummary.keepRecent must not be negative"))
+		}
+	}
+	if cfg.Agents.Defaults.MaxResponseChars < 0 {
+		errs = append(errs, fmt.Errorf("agents.defaults.maxResponseChars must not be negative"))
+	}
+	switch mode := strings.ToLower(strings.TrimSpace(cfg.Agents.Defaults.ResponseOverflow)); mode {
+	case "", agent.ResponseOverflowSplit, agent.ResponseOverflowTruncate:
+	default:
+		errs = append(errs, fmt.Errorf("agents.defaults.responseOverflow %q must be split or truncate", cfg.Agents.Defaults.ResponseOverflow))
+	}
+	if _, _, err := agent.QuietHoursUntil(cfg.Runtime.QuietHours, time.Now()); err != nil {
+		errs = append(errs, err)
+	}
+	for name, entries := range map[string][]string{
+		"runtime.globalAllowFrom": cfg.Runtime.GlobalAllowFrom,
+		"runtime.globalDenyFrom":  cfg.Runtime.GlobalDenyFrom,
+	} {
+		for _, entry := range entries {
+			channel, sender, ok := strings.Cut(strings.TrimSpace(entry), ":")
+			if !ok || strings.TrimSpace(channel) == "" || strings.TrimSpace(sender) == "" {
+				errs = append(errs, fmt.Errorf("%s entry %q must be channel:sender", name, entry))
+			}
+		}
+	}
+	for _, entry := range cfg.Tools.Message.AllowTargets {
+		channel, chatID, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || strings.TrimSpace(channel) == "" || strings.TrimSpace(chatID) == "" {
+			errs = append(errs, fmt.Errorf("tools.message.allowTargets entry %q must be channel:chat_id", entry))
+		}
+	}
+	for name, entries := range map[string][]string{
+		"tools.exec.allowedCommands": cfg.Tools.Exec.AllowedCommands,
+		"tools.exec.blockedCommands": cfg.Tools.Exec.BlockedCommands,
+	} {
+		for _, problem := range tools.ValidateExecPatterns(entries) {
+			errs = append(errs, fmt.Errorf("%s: %s", name, problem))
+		}
+	}
+	if !tools.KnownSearchProvider(cfg.Tools.Web.Search.Provider) {
+		errs = append(errs, fmt.Errorf("tools.web.search.provider %q must be one of %s", cfg.Tools.Web.Search.Provider, strings.Join(tools.SearchProviders, ", ")))
+	}
+	for name := range cfg.Tools.Web.Search.Providers {
+		if strings.TrimSpace(name) == "" || !tools.KnownSearchProvider(name) {
+			errs = append(errs, fmt.Errorf("tools.web.search.providers[%s]: unknown provider, must be one of %s", name, strings.Join(tools.SearchProviders, ", ")))
+		}
+	}
+	errs = append(errs, validateHeartbeatProfiles(cfg)...)
+	if cfg.Memory.Enabled && cfg.Memory.DailyRollup.Enabled {
+		if _, _, err := parseRollupTime(cfg.Memory.DailyRollup.Time); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, problem := range agent.ValidatePostProcessors(cfg.Agents.PostProcessors.Default) {
+		errs = append(errs, fmt.Errorf("agents.postProcessors.default: %s", problem))
+	}
+	for channel, steps := range cfg.Agents.PostProcessors.Channels {
+		for _, problem := range agent.ValidatePostProcessors(steps) {
+			errs = append(errs, fmt.Errorf("agents.postProcessors.channels[%s]: %s", channel, problem))
+		}
+	}
+	for id, channel := range cfg.Channels.Registry {
+		if channel.Enabled && agent.IsReservedChannel(id) {
+			errs = append(errs, fmt.Errorf("channels.registry[%s]: %q is a reserved channel name", id, id))
+		}
+	}
+	for id, channel := range cfg.Channels.Plugins {
+		if channel.Enabled && agent.IsReservedChannel(id) {
+			errs = append(errs, fmt.Errorf("channels.plugins[%s]: %q is a reserved channel name", id, id))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,41 @@
+package app
+
+import (
+	"errors"
+	"time"
+)
+
+var errGatewayDraining = errors.New("gateway is shutting down")
+
+const drainPollInterval = 100 * time.Millisecond
+
+// drain runs when the gateway is asked to stop. It refuses new inbound
+// messages, stops the schedulers and subagent workers from starting more
+// work, and waits up to runtime.shutdownGraceSec for running turns and
+// subagent runs to finish. Turns still running after that are cancelled;
+// subagent runs are cancelled when the engine closes.
+func (r *Runtime) drain() {
+	r.draining.Store(true)
+	r.Engine.BeginDrain()
+	schedulersStopped := make(chan struct{})
+	go func() {
+		defer close(schedulersStopped)
+		r.Cron.Stop()
+		r.Heartbeat.Stop()
+	}()
+
+	started := time.Now()
+	deadline := started.Add(time.Duration(max(r.Config.Runtime.ShutdownGraceSec, 0)) * time.Second)
+	startTurns, startSubagents := r.Engine.ActiveWork()
+	turns, subagents := startTurns, startSubagents
+	for (turns > 0 || subagents > 0) && time.Now().Before(deadline) {
+		time.Sleep(drainPollInterval)
+		turns, subagents = r.Engine.ActiveWork()
+	}
+	if turns > 0 {
+		r.Engine.AbortTurns()
+	}
+	<-schedulersStopped
+	r.log.Printf("event=gateway_drain drained_turns=%d drained_subagents=%d forced_turns=%d forced_subagents=%d waited=%s",
+		max(startTurns-turns, 0), max(startSubagents-subagents, 0), turns, subagents, time.Since(started).Round(time.Millisecond))
+}
@@ -0,0 +1,106 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grixate/squidbot/internal/config"
+	"github.com/grixate/squidbot/internal/heartbeat"
+)
+
+// HeartbeatProfiles returns every heartbeat profile in name order: the
+// default one, which runs HEARTBEAT.md every runtime.heartbeatIntervalSec,
+// and runtime.heartbeatProfiles, where a "default" entry replaces it.
+func HeartbeatProfiles(cfg config.Config) []heartbeat.Profile {
+	interval := time.Duration(cfg.Runtime.HeartbeatIntervalSec) * time.Second
+	if interval <= 0 {
+		interval = heartbeat.DefaultInterval
+	}
+	byName := map[string]heartbeat.Profile{
+		heartbeat.DefaultProfile: {Name: heartbeat.DefaultProfile, File: heartbeat.DefaultFile, Interval: interval},
+	}
+	for name, profile := range cfg.Runtime.HeartbeatProfiles {
+		name = strings.ToLower(strings.TrimSpace(name))
+		byName[name] = heartbeat.Profile{
+			Name:     name,
+			File:     strings.TrimSpace(profile.File),
+			Interval: time.Duration(max(profile.IntervalSec, 0)) * time.Second,
+		}
+	}
+	profiles := make([]heartbeat.Profile, 0, len(byName))
+	for _, profile := range byName {
+		profiles = append(profiles, profile)
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name < profiles[j].Name })
+	return profiles
+}
+
+// validateHeartbeatProfiles reports profiles without a name or file, with a
+// file outside the workspace, or with a negative interval.
+func validateHeartbeatProfiles(cfg config.Config) []error {
+	var errs []error
+	names := make([]string, 0, len(cfg.Runtime.HeartbeatProfiles))
+	for name := range cfg.Runtime.HeartbeatProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		profile := cfg.Runtime.HeartbeatProfiles[name]
+		if strings.TrimSpace(name) == "" {
+			errs = append(errs, errors.New("runtime.heartbeatProfiles has an empty profile name"))
+			continue
+		}
+		file := strings.TrimSpace(profile.File)
+		switch {
+		case file == "":
+			errs = append(errs, fmt.Errorf("runtime.heartbeatProfiles.%s: file is required", name))
+		case !filepath.IsLocal(file):
+			errs = append(errs, fmt.Errorf("runtime.heartbeatProfiles.%s: file %q must be a path inside the workspace", name, profile.File))
+		}
+		if profile.IntervalSec < 0 {
+			errs = append(errs, fmt.Errorf("runtime.heartbeatProfiles.%s: intervalSec must not be negative", name))
+		}
+	}
+	return errs
+}
+
+// TriggerHeartbeat runs the named heartbeat profile now; an empty name runs
+// the default profile.
+func (r *Runtime) TriggerHeartbeat(ctx context.Context, profile string) (string, error) {
+	if r.Heartbeat == nil {
+		return "", errors.New("heartbeat unavailable")
+	}
+	return r.Heartbeat.TriggerProfile(ctx, profile)
+}
+
+func (r *Runtime) handleManageHeartbeatRun(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if r.Heartbeat == nil {
+		http.Error(w, "heartbeat unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	profile := strings.ToLower(strings.TrimSpace(req.URL.Query().Get("profile")))
+	if profile == "" {
+		profile = heartbeat.DefaultProfile
+	}
+	response, err := r.TriggerHeartbeat(req.Context(), profile)
+	if errors.Is(err, heartbeat.ErrUnknownProfile) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	payload := map[string]any{"profile": profile, "status": "ok", "response": strings.TrimSpace(response)}
+	if err != nil {
+		payload["status"] = "error"
+		payload["error"] = err.Error()
+	}
+	writeFederationJSON(w, http.StatusOK, payload)
+}
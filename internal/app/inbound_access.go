@@ -0,0 +1,111 @@
+package app
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/grixate/squidbot/internal/agent"
+	"github.com/grixate/squidbot/internal/config"
+)
+
+var errInboundDenied = errors.New("sender is not allowed to message this agent")
+
+// inboundAccess decides whether a sender may reach the engine. Entries in
+// runtime.globalDenyFrom always reject. Otherwise, when the channel's
+// allowFrom or the runtime.globalAllowFrom entries naming this channel have
+// entries, the sender must match one of them; with neither set every sender
+// is allowed, so a global entry for one channel never locks out another. The
+// returned reason is empty when the message is allowed.
+func inboundAccess(cfg config.Config, msg agent.InboundMessage) string {
+	channel := strings.ToLower(strings.TrimSpace(msg.Channel))
+	senders := inboundSenderKeys(msg)
+	if matchesGlobalSender(cfg.Runtime.GlobalDenyFrom, channel, senders) {
+		return "global_deny"
+	}
+	channelAllow := cfg.ChannelAllowFrom(channel)
+	globalAllow := globalEntriesFor(cfg.Runtime.GlobalAllowFrom, channel)
+	if len(channelAllow) == 0 && len(globalAllow) == 0 {
+		return ""
+	}
+	if matchesGlobalSender(globalAllow, channel, senders) {
+		return ""
+	}
+	for _, entry := range channelAllow {
+		entry = normalizeSenderKey(entry)
+		for _, sender := range senders {
+			if entry == "*" || entry == sender {
+				return ""
+			}
+		}
+	}
+	if len(channelAllow) > 0 {
+		return "channel_allow_list"
+	}
+	return "global_allow_list"
+}
+
+// inboundSenderKeys returns the identifiers a sender can be listed under:
+// the sender id and, when the channel reports one, the username.
+func inboundSenderKeys(msg agent.InboundMessage) []string {
+	keys := make([]string, 0, 2)
+	if sender := normalizeSenderKey(msg.SenderID); sender != "" {
+		keys = append(keys, sender)
+	}
+	if username, _ := msg.Metadata["sender_username"].(string); normalizeSenderKey(username) != "" {
+		keys = append(keys, normalizeSenderKey(username))
+	}
+	return keys
+}
+
+// globalEntriesFor returns the "channel:sender" entries that name channel.
+func globalEntriesFor(entries []string, channel string) []string {
+	var out []string
+	for _, entry := range entries {
+		entryChannel, _, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if ok && strings.ToLower(strings.TrimSpace(entryChannel)) == channel {
+			out = append(out, entry)
+		}
+	}
+	return out
+}
+
+func matchesGlobalSender(entries []string, channel string, senders []string) bool {
+	for _, entry := range entries {
+		entryChannel, entrySender, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || strings.ToLower(strings.TrimSpace(entryChannel)) != channel {
+			continue
+		}
+		entrySender = normalizeSenderKey(entrySender)
+		if entrySender == "*" {
+			return true
+		}
+		for _, sender := range senders {
+			if entrySender == sender {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func normalizeSenderKey(value string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(value), "@"))
+}
+
+// admitInbound logs and counts a rejected message and returns
+// errInboundDenied; allowed messages return nil. Every message is refused
+// with errGatewayDraining once shutdown begins.
+func (r *Runtime) admitInbound(msg agent.InboundMessage) error {
+	if r.draining.Load() {
+		return errGatewayDraining
+	}
+	reason := inboundAccess(r.Config, msg)
+	if reason == "" {
+		return nil
+	}
+	if r.Metrics != nil {
+		r.Metrics.InboundRejected.Add(1)
+	}
+	r.log.Printf("event=inbound_rejected channel=%s sender_id=%s chat_id=%s reason=%s", msg.Channel, msg.SenderID, msg.ChatID, reason)
+	return errInboundDenied
+}
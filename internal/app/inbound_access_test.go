@@ -0,0 +1,88 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grixate/squidbot/internal/agent"
+	"github.com/grixate/squidbot/internal/config"
+	storepkg "github.com/grixate/squidbot/internal/storage/bbolt"
+	"github.com/grixate/squidbot/internal/telemetry"
+)
+
+func TestInboundAccess(t *testing.T) {
+	cfg := config.Default()
+	cfg.Channels.Registry = map[string]config.GenericChannelConfig{
+		"slack": {Enabled: true, AllowFrom: []string{"U1"}},
+	}
+	cfg.Runtime.GlobalAllowFrom = []string{"discord:42", "webchat:*"}
+	cfg.Runtime.GlobalDenyFrom = []string{"slack:U1", "telegram:@mallory"}
+
+	cases := []struct {
+		name   string
+		msg    agent.InboundMessage
+		reason string
+	}{
+		{"global deny beats channel allow", agent.InboundMessage{Channel: "slack", SenderID: "U1"}, "global_deny"},
+		{"deny matches telegram username", agent.InboundMessage{Channel: "telegram", SenderID: "7", Metadata: map[string]any{"sender_username": "Mallory"}}, "global_deny"},
+		{"not on channel list", agent.InboundMessage{Channel: "slack", SenderID: "U2"}, "channel_allow_list"},
+		{"global allow entry", agent.InboundMessage{Channel: "discord", SenderID: "42"}, ""},
+		{"not on global allow list", agent.InboundMessage{Channel: "discord", SenderID: "43"}, "global_allow_list"},
+		{"channel wildcard", agent.InboundMessage{Channel: "webchat", SenderID: "anyone"}, ""},
+		{"channel without lists stays open", agent.InboundMessage{Channel: "matrix", SenderID: "anyone"}, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := inboundAccess(cfg, tc.msg); got != tc.reason {
+				t.Fatalf("expected reason %q, got %q", tc.reason, got)
+			}
+		})
+	}
+
+	open := config.Default()
+	if got := inboundAccess(open, agent.InboundMessage{Channel: "discord", SenderID: "43"}); got != "" {
+		t.Fatalf("expected senders allowed without any lists, got %q", got)
+	}
+}
+
+func TestChannelAskRejectsDeniedSenderBeforeEngine(t *testing.T) {
+	cfg := config.Default()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	cfg.Runtime.GlobalDenyFrom = []string{"webchat:blocked"}
+
+	store, err := storepkg.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	logger := log.New(io.Discard, "", 0)
+	metrics := &telemetry.Metrics{}
+	engine, err := agent.NewEngine(cfg, echoProvider{}, "test-model", store, metrics, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+	runtime := &Runtime{Config: cfg, Store: store, Engine: engine, Metrics: metrics, log: logger}
+
+	ask := runtime.channelAsk("webchat")
+	_, err = ask(context.Background(), agent.InboundMessage{ChatID: "c1", SenderID: "blocked", Content: "hi", CreatedAt: time.Now().UTC()})
+	if !errors.Is(err, errInboundDenied) {
+		t.Fatalf("expected denied error, got %v", err)
+	}
+	if metrics.InboundRejected.Load() != 1 || metrics.ProviderCalls.Load() != 0 || metrics.InboundCount.Load() != 0 {
+		t.Fatalf("expected rejection before the engine, got rejected=%d provider=%d inbound=%d", metrics.InboundRejected.Load(), metrics.ProviderCalls.Load(), metrics.InboundCount.Load())
+	}
+
+	reply, err := ask(context.Background(), agent.InboundMessage{ChatID: "c1", SenderID: "friend", Content: "hi", CreatedAt: time.Now().UTC()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply != "echo: hi" {
+		t.Fatalf("unexpected reply %q", reply)
+	}
+}
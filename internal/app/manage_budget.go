@@ -0,0 +1,138 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/grixate/squidbot/internal/budget"
+)
+
+// manageBudgetScope is one usage counter checked against its limits.
+type manageBudgetScope struct {
+	Scope            string `json:"scope"`
+	Used             uint64 `json:"used"`
+	Reserved         uint64 `json:"reserved"`
+	HardLimit        uint64 `json:"hard_limit"`
+	SoftThresholdPct int    `json:"soft_threshold_pct"`
+	SoftWarning      bool   `json:"soft_warning"`
+	HardExceeded     bool   `json:"hard_exceeded"`
+}
+
+// manageBudgetUpdate is a PUT body. Omitted fields keep their current value.
+type manageBudgetUpdate struct {
+	Enabled                     *bool   `json:"enabled"`
+	Mode                        *string `json:"mode"`
+	GlobalHardLimitTokens       *uint64 `json:"global_hard_limit_tokens"`
+	GlobalSoftThresholdPct      *int    `json:"global_soft_threshold_pct"`
+	SessionHardLimitTokens      *uint64 `json:"session_hard_limit_tokens"`
+	SessionSoftThresholdPct     *int    `json:"session_soft_threshold_pct"`
+	SubagentRunHardLimitTokens  *uint64 `json:"subagent_run_hard_limit_tokens"`
+	SubagentRunSoftThresholdPct *int    `json:"subagent_run_soft_threshold_pct"`
+}
+
+func (u manageBudgetUpdate) validate() error {
+	if u.Mode != nil {
+		switch strings.ToLower(strings.TrimSpace(*u.Mode)) {
+		case string(budget.ModeHybrid), string(budget.ModeSoft), string(budget.ModeHard):
+		default:
+			return fmt.Errorf("mode %q must be hybrid, soft, or hard", *u.Mode)
+		}
+	}
+	for name, pct := range map[string]*int{
+		"global_soft_threshold_pct":       u.GlobalSoftThresholdPct,
+		"session_soft_threshold_pct":      u.SessionSoftThresholdPct,
+		"subagent_run_soft_threshold_pct": u.SubagentRunSoftThresholdPct,
+	} {
+		if pct != nil && (*pct < 0 || *pct > 100) {
+			return fmt.Errorf("%s must be between 0 and 100", name)
+		}
+	}
+	return nil
+}
+
+func (u manageBudgetUpdate) apply(current *budget.Settings) {
+	if u.Enabled != nil {
+		current.Enabled = *u.Enabled
+	}
+	if u.Mode != nil {
+		current.Mode = budget.Mode(budget.NormalizeMode(*u.Mode))
+	}
+	if u.GlobalHardLimitTokens != nil {
+		current.GlobalHardLimitTokens = *u.GlobalHardLimitTokens
+	}
+	if u.GlobalSoftThresholdPct != nil {
+		current.GlobalSoftThresholdPct = *u.GlobalSoftThresholdPct
+	}
+	if u.SessionHardLimitTokens != nil {
+		current.SessionHardLimitTokens = *u.SessionHardLimitTokens
+	}
+	if u.SessionSoftThresholdPct != nil {
+		current.SessionSoftThresholdPct = *u.SessionSoftThresholdPct
+	}
+	if u.SubagentRunHardLimitTokens != nil {
+		current.SubagentRunHardLimitTokens = *u.SubagentRunHardLimitTokens
+	}
+	if u.SubagentRunSoftThresholdPct != nil {
+		current.SubagentRunSoftThresholdPct = *u.SubagentRunSoftThresholdPct
+	}
+}
+
+// handleManageBudget serves the effective token safety settings with their
+// usage counters, and on PUT stores an updated override the way the budget
+// CLI does. session and run query parameters add those counters. PUT relies
+// on manageAuthorizer, which refuses writes unless a manage token is set,
+// because the engine skips its trusted-writer check here.
+func (r *Runtime) handleManageBudget(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodPut {
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if r.Engine == nil {
+		http.Error(w, "engine unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	ctx := req.Context()
+	if req.Method == http.MethodPut {
+		var update manageBudgetUpdate
+		decoder := json.NewDecoder(http.MaxBytesReader(w, req.Body, 1<<20))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&update); err != nil {
+			http.Error(w, "invalid json body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := update.validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		settings, err := r.Engine.UpdateTokenSafety(ctx, func(current *budget.Settings) error {
+			update.apply(current)
+			return nil
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		r.log.Printf("event=budget_settings_updated source=manage enabled=%v mode=%s", settings.Enabled, settings.Mode)
+	}
+	status, err := r.Engine.BudgetStatus(ctx, strings.TrimSpace(req.URL.Query().Get("session")), strings.TrimSpace(req.URL.Query().Get("run")))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	scopes := make([]manageBudgetScope, 0, len(status.Scopes))
+	for _, scope := range status.Scopes {
+		scopes = append(scopes, manageBudgetScope{
+			Scope:            scope.Scope,
+			Used:             scope.Used,
+			Reserved:         scope.Reserved,
+			HardLimit:        scope.HardLimit,
+			SoftThresholdPct: scope.WarningLevel,
+			SoftWarning:      scope.SoftWarning,
+			HardExceeded:     scope.HardExceeded,
+		})
+	}
+	writeFederationJSON(w, http.StatusOK, map[string]any{"settings": status.Settings, "usage": scopes})
+}
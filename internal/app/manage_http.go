@@ -0,0 +1,401 @@
+package app
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grixate/squidbot/internal/agent"
+	"github.com/grixate/squidbot/internal/config"
+	"github.com/grixate/squidbot/internal/mission"
+	"github.com/grixate/squidbot/internal/telemetry"
+)
+
+const manageOutboundDefaultLimit = 50
+
+// manageRoutesServed reports whether the manage routes may be mounted. Without
+// a manage token they are only served to loopback clients, either because the
+// listener is bound to loopback or because localhostOnly is set.
+func manageRoutesServed(cfg config.MetricsHTTPRuntimeConfig) bool {
+	if strings.TrimSpace(cfg.ManageToken) != "" || cfg.LocalhostOnly {
+		return true
+	}
+	host, _, err := net.SplitHostPort(strings.TrimSpace(cfg.ListenAddr))
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// manageAuthorizer guards the manage routes. With a manage token every
+// request must carry it as its bearer token, and the metrics token does not
+// grant access. Without one only GET is served, under the metrics checks, and
+// everything that changes state is refused with 403.
+func manageAuthorizer(cfg config.MetricsHTTPRuntimeConfig) func(http.ResponseWriter, *http.Request) bool {
+	manageToken := strings.TrimSpace(cfg.ManageToken)
+	metricsAuthorize := metricsAuthorizer(cfg)
+	localhostOnly := cfg.LocalhostOnly
+	return func(w http.ResponseWriter, req *http.Request) bool {
+		if manageToken == "" {
+			if req.Method != http.MethodGet && req.Method != http.MethodHead {
+				http.Error(w, "runtime.metricsHttp.manageToken is not configured", http.StatusForbidden)
+				return false
+			}
+			return metricsAuthorize(w, req)
+		}
+		if localhostOnly && !loopbackPeer(req) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return false
+		}
+		if req.Header.Get("Authorization") != "Bearer "+manageToken {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return false
+		}
+		return true
+	}
+}
+
+// registerManageRoutes adds operator endpoints to the metrics server behind
+// authorize. Only the heavy routes (memory/search, tasks, skills/reload) share
+// a concurrency cap so dashboard load cannot starve the engine; cheap reads
+// stay uncapped so slow searches never lock out status checks. heartbeat/run
+// is also uncapped: it holds its request for a whole agent turn, which already
+// waits for an engine turn slot.
+func (r *Runtime) registerManageRoutes(mux *http.ServeMux, authorize func(http.ResponseWriter, *http.Request) bool) {
+	r.mountManageRoutes(mux, authorize, newManageLimiter(r.Config.Runtime.MetricsHTTP.ManageMaxConcurrent, r.Metrics))
+}
+
+func (r *Runtime) mountManageRoutes(mux *http.ServeMux, authorize func(http.ResponseWriter, *http.Request) bool, limit *manageLimiter) {
+	mux.HandleFunc("/api/manage/outbound/recent", func(w http.ResponseWriter, req *http.Request) {
+		if !authorize(w, req) {
+			return
+		}
+		r.handleManageOutboundRecent(w, req)
+	})
+	mux.HandleFunc("/api/manage/memory/search", func(w http.ResponseWriter, req *http.Request) {
+		if !authorize(w, req) {
+			return
+		}
+		limit.serve(w, req, r.handleManageMemorySearch)
+	})
+	mux.HandleFunc("/api/manage/sessions", func(w http.ResponseWriter, req *http.Request) {
+		if !authorize(w, req) {
+			return
+		}
+		r.handleManageSessions(w, req)
+	})
+	mux.HandleFunc("/api/manage/sessions/tools-lock", func(w http.ResponseWriter, req *http.Request) {
+		if !authorize(w, req) {
+			return
+		}
+		r.handleManageSessionToolsLock(w, req)
+	})
+	mux.HandleFunc("/api/manage/tools", func(w http.ResponseWriter, req *http.Request) {
+		if !authorize(w, req) {
+			return
+		}
+		r.handleManageTools(w, req)
+	})
+	mux.HandleFunc("/api/manage/budget", func(w http.ResponseWriter, req *http.Request) {
+		if !authorize(w, req) {
+			return
+		}
+		r.handleManageBudget(w, req)
+	})
+	mux.HandleFunc("/api/manage/tasks", func(w http.ResponseWriter, req *http.Request) {
+		if !authorize(w, req) {
+			return
+		}
+		limit.serve(w, req, r.handleManageTasks)
+	})
+	mux.HandleFunc("/api/manage/tasks/overview", func(w http.ResponseWriter, req *http.Request) {
+		if !authorize(w, req) {
+			return
+		}
+		r.handleManageTasksOverview(w, req)
+	})
+	mux.HandleFunc("/api/manage/heartbeat/run", func(w http.ResponseWriter, req *http.Request) {
+		if !authorize(w, req) {
+			return
+		}
+		r.handleManageHeartbeatRun(w, req)
+	})
+	mux.HandleFunc("/api/manage/skills/reload", func(w http.ResponseWriter, req *http.Request) {
+		if !authorize(w, req) {
+			return
+		}
+		limit.serve(w, req, r.handleManageSkillsReload)
+	})
+}
+
+// manageLimiter is a semaphore over manage handlers. Requests that find every
+// slot taken are refused with 429 instead of queueing.
+type manageLimiter struct {
+	slots   chan struct{}
+	metrics *telemetry.Metrics
+}
+
+func newManageLimiter(maxConcurrent int, metrics *telemetry.Metrics) *manageLimiter {
+	limiter := &manageLimiter{metrics: metrics}
+	if maxConcurrent > 0 {
+		limiter.slots = make(chan struct{}, maxConcurrent)
+	}
+	return limiter
+}
+
+func (l *manageLimiter) serve(w http.ResponseWriter, req *http.Request, handler http.HandlerFunc) {
+	if l.slots == nil {
+		handler(w, req)
+		return
+	}
+	select {
+	case l.slots <- struct{}{}:
+		defer func() { <-l.slots }()
+		handler(w, req)
+	default:
+		if l.metrics != nil {
+			l.metrics.ManageRejected.Add(1)
+		}
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "too many concurrent management requests", http.StatusTooManyRequests)
+	}
+}
+
+func (r *Runtime) handleManageOutboundRecent(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if r.Engine == nil {
+		http.Error(w, "engine unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	limit := manageOutboundDefaultLimit
+	if raw := strings.TrimSpace(req.URL.Query().Get("limit")); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+	channel := strings.TrimSpace(req.URL.Query().Get("channel"))
+	status := strings.TrimSpace(req.URL.Query().Get("status"))
+	records := r.Engine.RecentOutbound(limit, channel, status)
+	writeFederationJSON(w, http.StatusOK, map[string]any{"messages": records})
+}
+
+func (r *Runtime) handleManageSessions(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if r.Engine == nil || r.Store == nil {
+		http.Error(w, "engine unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	records, err := r.Store.ListSessions(req.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeFederationJSON(w, http.StatusOK, map[string]any{"sessions": agent.SessionStatuses(records, r.Engine.LiveSessions())})
+}
+
+// handleManageSessionToolsLock reports whether the session named by the
+// session query parameter is restricted to read-only tools, and on PUT sets
+// it from a {"locked": bool} body, as /lock-tools and /unlock-tools do in
+// chat.
+func (r *Runtime) handleManageSessionToolsLock(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodPut {
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if r.Store == nil {
+		http.Error(w, "store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	sessionID := strings.TrimSpace(req.URL.Query().Get("session"))
+	if sessionID == "" {
+		http.Error(w, "session is required", http.StatusBadRequest)
+		return
+	}
+	ctx := req.Context()
+	if req.Method == http.MethodPut {
+		var update struct {
+			Locked *bool `json:"locked"`
+		}
+		decoder := json.NewDecoder(http.MaxBytesReader(w, req.Body, 1<<10))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&update); err != nil {
+			http.Error(w, "invalid json body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if update.Locked == nil {
+			http.Error(w, "locked is required", http.StatusBadRequest)
+			return
+		}
+		if err := agent.SetSessionToolsLocked(ctx, r.Store, sessionID, *update.Locked); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		r.log.Printf("event=session_tools_lock source=manage session=%s locked=%v", sessionID, *update.Locked)
+	}
+	writeFederationJSON(w, http.StatusOK, map[string]any{"session": sessionID, "locked": agent.SessionToolsLocked(ctx, r.Store, sessionID)})
+}
+
+// handleManageTools serves the effective tool registry, as `squidbot tools
+// list --json` prints it, for a turn on channel (default cli).
+func (r *Runtime) handleManageTools(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if r.Engine == nil {
+		http.Error(w, "engine unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	channel := strings.ToLower(strings.TrimSpace(req.URL.Query().Get("channel")))
+	if channel == "" {
+		channel = agent.ChannelCLI
+	}
+	defs, err := r.Engine.ToolDefinitions(agent.InboundMessage{
+		SessionID: channel + ":direct",
+		Channel:   channel,
+		ChatID:    "direct",
+		SenderID:  "user",
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	tools := make([]map[string]any, 0, len(defs))
+	for _, def := range defs {
+		tools = append(tools, map[string]any{
+			"name":        def.Name,
+			"description": def.Description,
+			"parameters":  def.Schema,
+		})
+	}
+	writeFederationJSON(w, http.StatusOK, map[string]any{"channel": channel, "tools": tools})
+}
+
+// handleManageTasks serves one page of mission tasks. limit caps the page
+// (default 100, at most 500), column filters by column ID, and cursor takes
+// the nextCursor of the previous page.
+func (r *Runtime) handleManageTasks(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if r.Store == nil {
+		http.Error(w, "store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	query := mission.TaskPageQuery{
+		ColumnID: strings.TrimSpace(req.URL.Query().Get("column")),
+		Cursor:   strings.TrimSpace(req.URL.Query().Get("cursor")),
+	}
+	if raw := strings.TrimSpace(req.URL.Query().Get("limit")); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		query.Limit = parsed
+	}
+	page, err := r.Store.ListMissionTasksPage(req.Context(), query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeFederationJSON(w, http.StatusOK, page)
+}
+
+// handleManageTasksOverview serves board counts: tasks per column, open
+// tasks, and open tasks due within a day or overdue.
+func (r *Runtime) handleManageTasksOverview(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if r.Store == nil {
+		http.Error(w, "store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	counts, err := r.Store.MissionTaskCounts(req.Context(), time.Now().UTC(), mission.DueSoonWindow)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeFederationJSON(w, http.StatusOK, counts)
+}
+
+// manageMemoryHit is one memory search result. The score breakdown is only
+// filled in when the caller asks for it with explain=1.
+type manageMemoryHit struct {
+	ID        string   `json:"id"`
+	Path      string   `json:"path"`
+	Kind      string   `json:"kind"`
+	Day       string   `json:"day,omitempty"`
+	Content   string   `json:"content"`
+	Score     float64  `json:"score"`
+	Retrieval string   `json:"retrieval,omitempty"`
+	Lexical   *float64 `json:"lexical,omitempty"`
+	Recency   *float64 `json:"recency,omitempty"`
+	Semantic  *float64 `json:"semantic,omitempty"`
+	Reranked  *bool    `json:"reranked,omitempty"`
+}
+
+func (r *Runtime) handleManageMemorySearch(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if r.Engine == nil {
+		http.Error(w, "engine unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	query := strings.TrimSpace(req.URL.Query().Get("q"))
+	if query == "" {
+		http.Error(w, "q is required", http.StatusBadRequest)
+		return
+	}
+	limit := 0
+	if raw := strings.TrimSpace(req.URL.Query().Get("limit")); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+	explain, _ := strconv.ParseBool(strings.TrimSpace(req.URL.Query().Get("explain")))
+	results, err := r.Engine.SearchMemory(req.Context(), query, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	hits := make([]manageMemoryHit, 0, len(results))
+	for _, result := range results {
+		hit := manageMemoryHit{ID: result.ID, Path: result.Path, Kind: result.Kind, Day: result.Day, Content: result.Content, Score: result.Score}
+		if explain {
+			hit.Retrieval = result.Retrieval
+			hit.Lexical = &result.Lexical
+			hit.Recency = &result.Recency
+			hit.Semantic = &result.Semantic
+			hit.Reranked = &result.Reranked
+		}
+		hits = append(hits, hit)
+	}
+	writeFederationJSON(w, http.StatusOK, map[string]any{"query": query, "results": hits})
+}
@@ -0,0 +1,770 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grixate/squidbot/internal/agent"
+	"github.com/grixate/squidbot/internal/budget"
+	"github.com/grixate/squidbot/internal/config"
+	"github.com/grixate/squidbot/internal/heartbeat"
+	"github.com/grixate/squidbot/internal/memory"
+	storepkg "github.com/grixate/squidbot/internal/storage/bbolt"
+	"github.com/grixate/squidbot/internal/telemetry"
+)
+
+func TestManageOutboundRecentReportsDeliveryStatus(t *testing.T) {
+	cfg := config.Default()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	store, err := storepkg.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	logger := log.New(io.Discard, "", 0)
+	engine, err := agent.NewEngine(cfg, echoProvider{}, "test-model", store, nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	engine.EmitOutbound("telegram", "42", "first reply", nil)
+	engine.EmitOutbound(agent.ChannelSystem, "internal", "heartbeat note", nil)
+	engine.EmitOutbound("slack", "C1", "second reply", nil)
+	engine.ReportOutboundDelivery(<-engine.Outbound(), errors.New("chat not found"))
+	engine.ReportOutboundDelivery(<-engine.Outbound(), nil)
+
+	runtime := &Runtime{Config: cfg, Store: store, Engine: engine, log: logger}
+	mux := http.NewServeMux()
+	runtime.registerManageRoutes(mux, func(http.ResponseWriter, *http.Request) bool { return true })
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	fetch := func(query string) []agent.OutboundRecord {
+		t.Helper()
+		resp, err := http.Get(server.URL + "/api/manage/outbound/recent" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("unexpected status %d", resp.StatusCode)
+		}
+		var body struct {
+			Messages []agent.OutboundRecord `json:"messages"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		return body.Messages
+	}
+
+	records := fetch("")
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records, got %+v", records)
+	}
+	if records[0].Channel != "slack" || records[0].Status != agent.OutboundDelivered {
+		t.Fatalf("expected newest slack record delivered, got %+v", records[0])
+	}
+	if records[1].Status != agent.OutboundSuppressed {
+		t.Fatalf("expected system record suppressed, got %+v", records[1])
+	}
+	if records[2].Status != agent.OutboundFailed || records[2].Error != "chat not found" {
+		t.Fatalf("expected telegram record failed, got %+v", records[2])
+	}
+
+	failed := fetch("?status=failed&limit=10")
+	if len(failed) != 1 || failed[0].ChatID != "42" {
+		t.Fatalf("expected status filter to apply, got %+v", failed)
+	}
+	// The oldest record matches, so the filter must run before the limit.
+	if oldest := fetch("?channel=telegram&limit=1"); len(oldest) != 1 || oldest[0].ChatID != "42" {
+		t.Fatalf("expected the limit to count matching records only, got %+v", oldest)
+	}
+}
+
+func TestManageLimiterRejectsBeyondCap(t *testing.T) {
+	metrics := &telemetry.Metrics{}
+	limiter := newManageLimiter(1, metrics)
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	slow := func(w http.ResponseWriter, req *http.Request) {
+		close(entered)
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		limiter.serve(w, req, slow)
+	}))
+	defer server.Close()
+
+	done := make(chan int, 1)
+	go func() {
+		resp, err := http.Get(server.URL)
+		if err != nil {
+			done <- 0
+			return
+		}
+		resp.Body.Close()
+		done <- resp.StatusCode
+	}()
+	select {
+	case <-entered:
+	case <-time.After(2 * time.Second):
+		t.Fatal("first request never reached the handler")
+	}
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") == "" {
+		t.Fatalf("expected 429 with Retry-After, got %d", resp.StatusCode)
+	}
+	if metrics.ManageRejected.Load() != 1 {
+		t.Fatalf("expected one rejection counted, got %d", metrics.ManageRejected.Load())
+	}
+	close(release)
+	if status := <-done; status != http.StatusOK {
+		t.Fatalf("expected first request to succeed, got %d", status)
+	}
+}
+
+func TestManageAuthorizerRequiresTokenForWrites(t *testing.T) {
+	check := func(cfg config.MetricsHTTPRuntimeConfig, method, remote, auth string) int {
+		req := httptest.NewRequest(method, "/api/manage/budget", nil)
+		req.RemoteAddr = remote
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		if manageAuthorizer(cfg)(rec, req) {
+			return http.StatusOK
+		}
+		return rec.Code
+	}
+	open := config.MetricsHTTPRuntimeConfig{ListenAddr: "0.0.0.0:19090"}
+	if status := check(open, http.MethodGet, "10.0.0.5:4000", ""); status != http.StatusOK {
+		t.Fatalf("expected reads without a manage token, got %d", status)
+	}
+	for _, method := range []string{http.MethodPut, http.MethodPost} {
+		if status := check(open, method, "127.0.0.1:4000", ""); status != http.StatusForbidden {
+			t.Fatalf("expected %s refused without a manage token, got %d", method, status)
+		}
+	}
+
+	withToken := config.MetricsHTTPRuntimeConfig{ListenAddr: "0.0.0.0:19090", AuthToken: "scrape", ManageToken: "admin"}
+	if status := check(withToken, http.MethodPut, "10.0.0.5:4000", "Bearer scrape"); status != http.StatusUnauthorized {
+		t.Fatalf("expected the metrics token rejected, got %d", status)
+	}
+	if status := check(withToken, http.MethodGet, "10.0.0.5:4000", ""); status != http.StatusUnauthorized {
+		t.Fatalf("expected reads to need the manage token, got %d", status)
+	}
+	if status := check(withToken, http.MethodPut, "10.0.0.5:4000", "Bearer admin"); status != http.StatusOK {
+		t.Fatalf("expected the manage token accepted, got %d", status)
+	}
+}
+
+func TestManageRoutesServedOnlyToLoopbackWithoutToken(t *testing.T) {
+	cases := []struct {
+		cfg  config.MetricsHTTPRuntimeConfig
+		want bool
+	}{
+		{config.MetricsHTTPRuntimeConfig{ListenAddr: "127.0.0.1:19090"}, true},
+		{config.MetricsHTTPRuntimeConfig{ListenAddr: "localhost:19090"}, true},
+		{config.MetricsHTTPRuntimeConfig{ListenAddr: "0.0.0.0:19090", LocalhostOnly: true}, true},
+		{config.MetricsHTTPRuntimeConfig{ListenAddr: "0.0.0.0:19090"}, false},
+		{config.MetricsHTTPRuntimeConfig{ListenAddr: ":19090", AuthToken: "scrape"}, false},
+		{config.MetricsHTTPRuntimeConfig{ListenAddr: "0.0.0.0:19090", ManageToken: "admin"}, true},
+	}
+	for _, tc := range cases {
+		if got := manageRoutesServed(tc.cfg); got != tc.want {
+			t.Fatalf("manageRoutesServed(%+v) = %v, want %v", tc.cfg, got, tc.want)
+		}
+	}
+}
+
+func TestManageMemorySearchExplainsScores(t *testing.T) {
+	cfg := config.Default()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	cfg.Memory.IndexPath = filepath.Join(t.TempDir(), "memory_index.db")
+	memoryDir := filepath.Join(cfg.Agents.Defaults.Workspace, "memory")
+	if err := os.MkdirAll(filepath.Join(memoryDir, "daily"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	today := time.Now().UTC().Format("2006-01-02")
+	if err := os.WriteFile(filepath.Join(memoryDir, "daily", today+".md"), []byte("# "+today+"\n- tuned the kraken reranker\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	indexer := memory.NewManager(cfg)
+	if err := indexer.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	indexer.Close()
+
+	store, err := storepkg.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	logger := log.New(io.Discard, "", 0)
+	engine, err := agent.NewEngine(cfg, echoProvider{}, "test-model", store, nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+	runtime := &Runtime{Config: cfg, Store: store, Engine: engine, log: logger}
+	mux := http.NewServeMux()
+	runtime.registerManageRoutes(mux, func(http.ResponseWriter, *http.Request) bool { return true })
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	search := func(query string) []map[string]any {
+		t.Helper()
+		resp, err := http.Get(server.URL + "/api/manage/memory/search" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("unexpected status %d", resp.StatusCode)
+		}
+		var body struct {
+			Results []map[string]any `json:"results"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		return body.Results
+	}
+
+	plain := search("?q=kraken")
+	if len(plain) != 1 {
+		t.Fatalf("expected one hit, got %+v", plain)
+	}
+	if _, ok := plain[0]["lexical"]; ok {
+		t.Fatalf("expected no breakdown without explain, got %+v", plain[0])
+	}
+
+	explained := search("?q=kraken&explain=1")
+	if len(explained) != 1 {
+		t.Fatalf("expected one hit, got %+v", explained)
+	}
+	hit := explained[0]
+	lexical, _ := hit["lexical"].(float64)
+	recency, _ := hit["recency"].(float64)
+	semantic, _ := hit["semantic"].(float64)
+	score, _ := hit["score"].(float64)
+	if recency != 0.35 || semantic != 0 || hit["reranked"] != false {
+		t.Fatalf("unexpected breakdown: %+v", hit)
+	}
+	if diff := score - (lexical + recency + semantic); diff > 1e-9 || diff < -1e-9 {
+		t.Fatalf("expected score to equal the sum of its parts, got %+v", hit)
+	}
+}
+
+func TestManageSessionsMarksLiveActors(t *testing.T) {
+	cfg := config.Default()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	store, err := storepkg.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	logger := log.New(io.Discard, "", 0)
+	ctx := context.Background()
+	if err := store.SaveSessionMeta(ctx, "telegram:old", map[string]any{"last_channel": "telegram"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveCheckpoint(ctx, "cli:resumed", []byte(`{"last_request_id":"r1"}`)); err != nil {
+		t.Fatal(err)
+	}
+	metrics := &telemetry.Metrics{}
+	engine, err := agent.NewEngine(cfg, echoProvider{}, "test-model", store, metrics, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+	for _, sessionID := range []string{"cli:fresh", "cli:resumed"} {
+		if _, err := engine.Ask(ctx, agent.InboundMessage{SessionID: sessionID, Channel: "cli", ChatID: "direct", SenderID: "user", Content: "hello", CreatedAt: time.Now().UTC()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	runtime := &Runtime{Config: cfg, Store: store, Engine: engine, log: logger}
+	mux := http.NewServeMux()
+	runtime.registerManageRoutes(mux, func(http.ResponseWriter, *http.Request) bool { return true })
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/manage/sessions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var body struct {
+		Sessions []agent.SessionStatus `json:"sessions"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	live := map[string]bool{}
+	for _, session := range body.Sessions {
+		live[session.SessionID] = session.Live
+	}
+	if len(live) != 3 || !live["cli:fresh"] || !live["cli:resumed"] || live["telegram:old"] {
+		t.Fatalf("unexpected session states: %+v", body.Sessions)
+	}
+	if body.Sessions[len(body.Sessions)-1].SessionID != "telegram:old" {
+		t.Fatalf("expected the idle session last, got %+v", body.Sessions)
+	}
+	if got := metrics.SessionsResurrected.Load(); got != 1 {
+		t.Fatalf("expected one session resumed from its checkpoint, got %d", got)
+	}
+}
+
+func TestManageToolsListsEffectiveRegistry(t *testing.T) {
+	cfg := config.Default()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	store, err := storepkg.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	logger := log.New(io.Discard, "", 0)
+	engine, err := agent.NewEngine(cfg, echoProvider{}, "test-model", store, nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+	runtime := &Runtime{Config: cfg, Store: store, Engine: engine, log: logger}
+	mux := http.NewServeMux()
+	runtime.registerManageRoutes(mux, func(http.ResponseWriter, *http.Request) bool { return true })
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/manage/tools")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var body struct {
+		Channel string `json:"channel"`
+		Tools   []struct {
+			Name       string         `json:"name"`
+			Parameters map[string]any `json:"parameters"`
+		} `json:"tools"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Channel != "cli" || len(body.Tools) == 0 {
+		t.Fatalf("expected the cli tool registry, got %+v", body)
+	}
+	names := map[string]bool{}
+	for idx, tool := range body.Tools {
+		names[tool.Name] = true
+		if idx > 0 && body.Tools[idx-1].Name > tool.Name {
+			t.Fatalf("expected tools sorted by name, got %q before %q", body.Tools[idx-1].Name, tool.Name)
+		}
+	}
+	if !names["read_file"] || body.Tools[0].Parameters == nil {
+		t.Fatalf("expected read_file with a parameter schema, got %+v", body.Tools)
+	}
+
+	post, err := http.Post(server.URL+"/api/manage/tools", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	post.Body.Close()
+	if post.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405 for POST, got %d", post.StatusCode)
+	}
+}
+
+func TestManageSessionToolsLockToggles(t *testing.T) {
+	cfg := config.Default()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	store, err := storepkg.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	logger := log.New(io.Discard, "", 0)
+	runtime := &Runtime{Config: cfg, Store: store, log: logger}
+	mux := http.NewServeMux()
+	runtime.registerManageRoutes(mux, func(http.ResponseWriter, *http.Request) bool { return true })
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	endpoint := server.URL + "/api/manage/sessions/tools-lock?session=" + url.QueryEscape("telegram:42")
+	toggle := func(method, body string) (int, bool) {
+		t.Helper()
+		req, err := http.NewRequest(method, endpoint, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var state struct {
+			Session string `json:"session"`
+			Locked  bool   `json:"locked"`
+		}
+		if resp.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(&state); err != nil {
+				t.Fatal(err)
+			}
+			if state.Session != "telegram:42" {
+				t.Fatalf("unexpected session %q", state.Session)
+			}
+		}
+		return resp.StatusCode, state.Locked
+	}
+	if status, locked := toggle(http.MethodGet, ""); status != http.StatusOK || locked {
+		t.Fatalf("expected an unlocked session, got %d locked=%v", status, locked)
+	}
+	if status, locked := toggle(http.MethodPut, `{"locked":true}`); status != http.StatusOK || !locked {
+		t.Fatalf("expected PUT to lock, got %d locked=%v", status, locked)
+	}
+	if !agent.SessionToolsLocked(context.Background(), store, "telegram:42") {
+		t.Fatal("expected the lock to be persisted")
+	}
+	if status, locked := toggle(http.MethodPut, `{"locked":false}`); status != http.StatusOK || locked {
+		t.Fatalf("expected PUT to unlock, got %d locked=%v", status, locked)
+	}
+	if status, _ := toggle(http.MethodPut, `{}`); status != http.StatusBadRequest {
+		t.Fatalf("expected 400 without locked, got %d", status)
+	}
+	if status, _ := toggle(http.MethodPost, `{"locked":true}`); status != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405 for POST, got %d", status)
+	}
+}
+
+func TestManageBudgetReadsAndUpdatesTokenSafety(t *testing.T) {
+	cfg := config.Default()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	store, err := storepkg.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	logger := log.New(io.Discard, "", 0)
+	engine, err := agent.NewEngine(cfg, echoProvider{}, "test-model", store, nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	runtime := &Runtime{Config: cfg, Store: store, Engine: engine, log: logger}
+	serve := func(metrics config.MetricsHTTPRuntimeConfig) string {
+		mux := http.NewServeMux()
+		runtime.registerManageRoutes(mux, manageAuthorizer(metrics))
+		server := httptest.NewServer(mux)
+		t.Cleanup(server.Close)
+		return server.URL
+	}
+	withoutToken := serve(cfg.Runtime.MetricsHTTP)
+	tokenCfg := cfg.Runtime.MetricsHTTP
+	tokenCfg.ManageToken = "admin"
+	withToken := serve(tokenCfg)
+
+	type budgetBody struct {
+		Settings budget.Settings     `json:"settings"`
+		Usage    []manageBudgetScope `json:"usage"`
+	}
+	send := func(base, method, query, payload string) (int, budgetBody) {
+		t.Helper()
+		req, err := http.NewRequest(method, base+"/api/manage/budget"+query, strings.NewReader(payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if base == withToken {
+			req.Header.Set("Authorization", "Bearer admin")
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var body budgetBody
+		if resp.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return resp.StatusCode, body
+	}
+
+	status, body := send(withoutToken, http.MethodGet, "?session=cli:default", "")
+	if status != http.StatusOK || len(body.Usage) != 2 || body.Usage[0].Scope != "global" || body.Usage[1].Scope != "session:cli:default" {
+		t.Fatalf("unexpected budget response %d: %+v", status, body)
+	}
+
+	update := `{"enabled":true,"mode":"hard","global_hard_limit_tokens":5000,"global_soft_threshold_pct":80}`
+	if status, _ := send(withoutToken, http.MethodPut, "", update); status != http.StatusForbidden {
+		t.Fatalf("expected 403 without a manage token, got %d", status)
+	}
+	if got := engine.TokenSafetySettings(context.Background()); got.Mode == budget.ModeHard {
+		t.Fatalf("refused update was applied: %+v", got)
+	}
+
+	status, body = send(withToken, http.MethodPut, "", update)
+	if status != http.StatusOK {
+		t.Fatalf("unexpected update status %d", status)
+	}
+	if !body.Settings.Enabled || body.Settings.Mode != budget.ModeHard || body.Settings.GlobalHardLimitTokens != 5000 || body.Usage[0].HardLimit != 5000 {
+		t.Fatalf("update not applied: %+v", body)
+	}
+	override, err := store.GetTokenSafetyOverride(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if override.Settings.GlobalSoftThresholdPct != 80 || override.Settings.SessionHardLimitTokens != cfg.Runtime.TokenSafety.SessionHardLimitTokens {
+		t.Fatalf("unexpected stored override: %+v", override.Settings)
+	}
+	if got := engine.TokenSafetySettings(context.Background()); got.Mode != budget.ModeHard {
+		t.Fatalf("engine did not pick up the override: %+v", got)
+	}
+
+	for _, payload := range []string{`{"mode":"strict"}`, `{"global_soft_threshold_pct":150}`, `{"limit":1}`} {
+		if status, _ := send(withToken, http.MethodPut, "", payload); status != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %s, got %d", payload, status)
+		}
+	}
+	if status, _ := send(withToken, http.MethodDelete, "", ""); status != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405 for DELETE, got %d", status)
+	}
+}
+
+func TestManageHeartbeatRunTriggersProfiles(t *testing.T) {
+	cfg := config.Default()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	cfg.Runtime.HeartbeatProfiles = map[string]config.HeartbeatProfileConfig{
+		"Evening": {File: "EVENING.md"},
+		"bad":     {File: "../outside.md", IntervalSec: -1},
+	}
+	if errs := validateHeartbeatProfiles(cfg); len(errs) != 2 {
+		t.Fatalf("expected the bad profile's file and interval to be reported, got %v", errs)
+	}
+	delete(cfg.Runtime.HeartbeatProfiles, "bad")
+
+	var prompts []string
+	service := heartbeat.NewService(cfg.Agents.Defaults.Workspace, time.Hour, func(ctx context.Context, profile heartbeat.Profile, prompt string) (string, error) {
+		prompts = append(prompts, prompt)
+		return "reviewed", nil
+	}, nil)
+	for _, profile := range HeartbeatProfiles(cfg) {
+		if err := service.SetProfile(profile); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runtime := &Runtime{Config: cfg, Heartbeat: service, log: log.New(io.Discard, "", 0)}
+	mux := http.NewServeMux()
+	runtime.registerManageRoutes(mux, func(http.ResponseWriter, *http.Request) bool { return true })
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/api/manage/heartbeat/run?profile=evening", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body struct {
+		Profile  string `json:"profile"`
+		Status   string `json:"status"`
+		Response string `json:"response"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body.Profile != "evening" || body.Status != "ok" || body.Response != "reviewed" {
+		t.Fatalf("unexpected response: %+v", body)
+	}
+	if len(prompts) != 1 || !strings.Contains(prompts[0], "EVENING.md") {
+		t.Fatalf("expected the evening file in the prompt, got %v", prompts)
+	}
+
+	resp, err = http.Post(server.URL+"/api/manage/heartbeat/run?profile=nope", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown profile, got %d", resp.StatusCode)
+	}
+}
+
+func TestManageHeartbeatRunDoesNotHoldLimiterSlot(t *testing.T) {
+	cfg := config.Default()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	cfg.Runtime.MetricsHTTP.ManageMaxConcurrent = 1
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	service := heartbeat.NewService(cfg.Agents.Defaults.Workspace, time.Hour, func(ctx context.Context, profile heartbeat.Profile, prompt string) (string, error) {
+		close(entered)
+		<-release
+		return "reviewed", nil
+	}, nil)
+	metrics := &telemetry.Metrics{}
+	runtime := &Runtime{Config: cfg, Heartbeat: service, Metrics: metrics, log: log.New(io.Discard, "", 0)}
+	mux := http.NewServeMux()
+	runtime.registerManageRoutes(mux, func(http.ResponseWriter, *http.Request) bool { return true })
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	var releaseOnce sync.Once
+	defer releaseOnce.Do(func() { close(release) })
+
+	done := make(chan int, 1)
+	go func() {
+		resp, err := http.Post(server.URL+"/api/manage/heartbeat/run", "application/json", nil)
+		if err != nil {
+			done <- 0
+			return
+		}
+		resp.Body.Close()
+		done <- resp.StatusCode
+	}()
+	select {
+	case <-entered:
+	case <-time.After(2 * time.Second):
+		t.Fatal("heartbeat run never started")
+	}
+
+	resp, err := http.Get(server.URL + "/api/manage/tools")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusTooManyRequests || metrics.ManageRejected.Load() != 0 {
+		t.Fatalf("expected a running heartbeat to leave the manage slot free, got %d", resp.StatusCode)
+	}
+	releaseOnce.Do(func() { close(release) })
+	if status := <-done; status != http.StatusOK {
+		t.Fatalf("expected heartbeat run to succeed, got %d", status)
+	}
+}
+
+func TestManageLimiterCapsOnlyHeavyRoutes(t *testing.T) {
+	cfg := config.Default()
+	metrics := &telemetry.Metrics{}
+	runtime := &Runtime{Config: cfg, Metrics: metrics, log: log.New(io.Discard, "", 0)}
+	limit := newManageLimiter(1, metrics)
+	limit.slots <- struct{}{}
+	mux := http.NewServeMux()
+	runtime.mountManageRoutes(mux, func(http.ResponseWriter, *http.Request) bool { return true }, limit)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	for _, path := range []string{"outbound/recent", "sessions", "sessions/tools-lock", "tools", "budget", "tasks/overview"} {
+		resp, err := http.Get(server.URL + "/api/manage/" + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusTooManyRequests {
+			t.Fatalf("expected %s to skip the manage cap, got 429", path)
+		}
+	}
+	if got := metrics.ManageRejected.Load(); got != 0 {
+		t.Fatalf("expected no rejections for cheap routes, got %d", got)
+	}
+	for _, path := range []string{"memory/search?q=x", "tasks", "skills/reload"} {
+		resp, err := http.Get(server.URL + "/api/manage/" + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusTooManyRequests {
+			t.Fatalf("expected %s to be capped, got %d", path, resp.StatusCode)
+		}
+	}
+	if got := metrics.ManageRejected.Load(); got != 3 {
+		t.Fatalf("expected 3 rejections for heavy routes, got %d", got)
+	}
+}
+
+func TestManageSkillsReloadRefreshesLiveRuntime(t *testing.T) {
+	cfg := config.Default()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	skillsDir := filepath.Join(cfg.Agents.Defaults.Workspace, "skills")
+	cfg.Skills.Paths = []string{skillsDir}
+	store, err := storepkg.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	var logs strings.Builder
+	logger := log.New(&logs, "", 0)
+	engine, err := agent.NewEngine(cfg, echoProvider{}, "test-model", store, nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	runtime := &Runtime{Config: cfg, Store: store, Engine: engine, log: logger}
+	mux := http.NewServeMux()
+	runtime.registerManageRoutes(mux, func(http.ResponseWriter, *http.Request) bool { return true })
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	skillPath := filepath.Join(skillsDir, "planner", "SKILL.md")
+	if err := os.MkdirAll(filepath.Dir(skillPath), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(skillPath, []byte("# Planner\nCreates practical execution plans."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Post(server.URL+"/api/manage/skills/reload", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var summary SkillsReloadSummary
+	err = json.NewDecoder(resp.Body).Decode(&summary)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Total != 1 || summary.Valid != 1 || summary.Invalid != 0 {
+		t.Fatalf("expected the new skill in the live index, got %+v", summary)
+	}
+	if !strings.Contains(logs.String(), "event=skills_reloaded forced=true changed=true total=1 valid=1 invalid=0") {
+		t.Fatalf("expected a reload summary log line, got %q", logs.String())
+	}
+
+	if changed, err := engine.RefreshSkills(context.Background()); err != nil || changed {
+		t.Fatalf("expected an unchanged index after reload, got changed=%v err=%v", changed, err)
+	}
+	if err := os.RemoveAll(filepath.Dir(skillPath)); err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := engine.RefreshSkills(context.Background()); err != nil || !changed {
+		t.Fatalf("expected the removed skill to change the index, got changed=%v err=%v", changed, err)
+	}
+
+	resp, err = http.Get(server.URL + "/api/manage/skills/reload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("expected GET to be refused, got %d", resp.StatusCode)
+	}
+}
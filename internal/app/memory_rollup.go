@@ -0,0 +1,62 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const defaultDailyRollupTime = "00:30"
+
+// startDailyRollup schedules the daily-log digest into MEMORY.md once per day
+// at the configured UTC time.
+func (r *Runtime) startDailyRollup(ctx context.Context) {
+	if r == nil || r.Engine == nil || !r.Config.Memory.Enabled || !r.Config.Memory.DailyRollup.Enabled {
+		return
+	}
+	hour, minute, err := parseRollupTime(r.Config.Memory.DailyRollup.Time)
+	if err != nil {
+		r.log.Printf("memory daily rollup: %v; using %s", err, defaultDailyRollupTime)
+		hour, minute, _ = parseRollupTime(defaultDailyRollupTime)
+	}
+	go func() {
+		for {
+			timer := time.NewTimer(time.Until(nextDailyRollup(time.Now().UTC(), hour, minute)))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			result, err := r.Engine.RollupDailyMemory(ctx)
+			if err != nil {
+				r.log.Printf("memory daily rollup failed: %v", err)
+				continue
+			}
+			if len(result.Days) > 0 || result.Pending > 0 {
+				r.log.Printf("memory daily rollup: digested=%d pending=%d", len(result.Days), result.Pending)
+			}
+		}
+	}()
+}
+
+func parseRollupTime(value string) (int, int, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		value = defaultDailyRollupTime
+	}
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid memory.dailyRollup.time %q (want HH:MM)", value)
+	}
+	return parsed.Hour(), parsed.Minute(), nil
+}
+
+func nextDailyRollup(now time.Time, hour, minute int) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, time.UTC)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
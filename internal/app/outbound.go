@@ -0,0 +1,61 @@
+package app
+
+import (
+	"context"
+	"sync"
+
+	"github.com/grixate/squidbot/internal/agent"
+)
+
+// outboundQueueSize bounds how many replies wait on one channel before the
+// gateway loop blocks on it.
+const outboundQueueSize = 256
+
+// outboundQueues delivers gateway replies through one worker per channel, so
+// a channel paced by its send rate limit does not hold up the others.
+type outboundQueues struct {
+	ctx     context.Context
+	runtime *Runtime
+	mu      sync.Mutex
+	queues  map[string]chan agent.OutboundMessage
+	wg      sync.WaitGroup
+}
+
+func newOutboundQueues(ctx context.Context, runtime *Runtime) *outboundQueues {
+	return &outboundQueues{ctx: ctx, runtime: runtime, queues: map[string]chan agent.OutboundMessage{}}
+}
+
+// enqueue hands msg to its channel's worker, starting the worker on first
+// use. It blocks while the channel's queue is full.
+func (q *outboundQueues) enqueue(msg agent.OutboundMessage) {
+	q.mu.Lock()
+	queue, ok := q.queues[msg.Channel]
+	if !ok {
+		queue = make(chan agent.OutboundMessage, outboundQueueSize)
+		q.queues[msg.Channel] = queue
+		q.wg.Add(1)
+		go q.deliver(queue)
+	}
+	q.mu.Unlock()
+	select {
+	case <-q.ctx.Done():
+	case queue <- msg:
+	}
+}
+
+func (q *outboundQueues) deliver(queue chan agent.OutboundMessage) {
+	defer q.wg.Done()
+	for {
+		select {
+		case <-q.ctx.Done():
+			return
+		case msg := <-queue:
+			q.runtime.deliverOutbound(q.ctx, msg)
+		}
+	}
+}
+
+// wait blocks until every worker has stopped after ctx is cancelled.
+func (q *outboundQueues) wait() {
+	q.wg.Wait()
+}
@@ -2,13 +2,17 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net"
 	"net/http"
+	"sort"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/grixate/squidbot/internal/agent"
 	channelreg "github.com/grixate/squidbot/internal/channels"
 	"github.com/grixate/squidbot/internal/channels/telegram"
@@ -34,6 +38,13 @@ type Runtime struct {
 	done       chan struct{}
 	metricsSrv *http.Server
 	federationSrv *http.Server
+	agentAPISrv   *http.Server
+	// draining is set once shutdown begins; inbound messages are refused
+	// from then on.
+	draining atomic.Bool
+	// DisabledChannels lists channel IDs StartGateway leaves stopped for this
+	// run, whatever the config enables.
+	DisabledChannels []string
 }
 
 func BuildRuntime(cfg config.Config, logger *log.Logger) (*Runtime, error) {
@@ -58,10 +69,21 @@ func BuildRuntime(cfg config.Config, logger *log.Logger) (*Runtime, error) {
 
 	runtime := &Runtime{Config: cfg, Store: store, Engine: engine, Metrics: metrics, log: logger, done: make(chan struct{})}
 	runtime.Cron = cron.NewService(store, func(ctx context.Context, job cron.Job) (string, error) {
+		if job.Payload.Kind == cron.PayloadSubagent {
+			channel, to := "", ""
+			if job.Payload.Deliver {
+				channel, to = job.Payload.Channel, job.Payload.To
+			}
+			run, err := engine.SpawnScheduledSubagent(ctx, job.ID, job.Name, job.Payload.Message, job.Payload.TimeoutSec, channel, to)
+			if err != nil {
+				return "", err
+			}
+			return "subagent run " + run.ID + " queued", nil
+		}
 		response, err := engine.Ask(ctx, agent.InboundMessage{
 			SessionID: "cron:" + job.ID,
 			RequestID: "",
-			Channel:   "cron",
+			Channel:   agent.ChannelCron,
 			ChatID:    job.ID,
 			SenderID:  "cron",
 			Content:   job.Payload.Message,
@@ -76,28 +98,41 @@ func BuildRuntime(cfg config.Config, logger *log.Logger) (*Runtime, error) {
 		return response, nil
 	}, metrics)
 
-	runtime.Heartbeat = heartbeat.NewService(config.WorkspacePath(cfg), time.Duration(cfg.Runtime.HeartbeatIntervalSec)*time.Second, func(ctx context.Context, prompt string) (string, error) {
+	runtime.Heartbeat = heartbeat.NewService(config.WorkspacePath(cfg), time.Duration(cfg.Runtime.HeartbeatIntervalSec)*time.Second, func(ctx context.Context, profile heartbeat.Profile, prompt string) (string, error) {
+		sessionID := agent.HeartbeatSessionID(profile.Name)
 		response, err := engine.Ask(ctx, agent.InboundMessage{
-			SessionID: "system:heartbeat",
+			SessionID: sessionID,
 			RequestID: "",
-			Channel:   "system",
+			Channel:   agent.ChannelSystem,
 			ChatID:    "heartbeat",
 			SenderID:  "heartbeat",
 			Content:   prompt,
 			CreatedAt: time.Now().UTC(),
 		})
 		if err == nil {
-			engine.RecordHeartbeat(ctx, prompt, response)
+			engine.RecordHeartbeat(ctx, sessionID, prompt, response)
 		}
 		return response, err
 	}, metrics)
+	for _, profile := range HeartbeatProfiles(cfg) {
+		if err := runtime.Heartbeat.SetProfile(profile); err != nil {
+			_ = engine.Close()
+			_ = store.Close()
+			return nil, err
+		}
+	}
 	runtime.Heartbeat.SetRunObserver(func(record heartbeat.RunRecord) {
 		preview := strings.TrimSpace(record.Response)
 		if len(preview) > 280 {
 			preview = preview[:277] + "..."
 		}
+		id := "hb-" + strings.ReplaceAll(record.StartedAt.UTC().Format(time.RFC3339Nano), ":", "-")
+		if record.Profile != heartbeat.DefaultProfile {
+			id += "-" + record.Profile
+		}
 		run := mission.HeartbeatRun{
-			ID:          "hb-" + strings.ReplaceAll(record.StartedAt.UTC().Format(time.RFC3339Nano), ":", "-"),
+			ID:          id,
+			Profile:     record.Profile,
 			TriggeredBy: record.TriggeredBy,
 			Status:      record.Status,
 			Error:       record.Error,
@@ -126,6 +161,9 @@ func (r *Runtime) telegramIngress() telegram.IngressHandler {
 		if strings.TrimSpace(msg.Channel) == "" {
 			msg.Channel = "telegram"
 		}
+		if r.admitInbound(msg) != nil {
+			return nil
+		}
 		_, err := r.Engine.Submit(ctx, msg)
 		return err
 	}
@@ -140,6 +178,9 @@ func (r *Runtime) channelIngress(channelID string) channelreg.IngressHandler {
 		if strings.TrimSpace(msg.SessionID) == "" {
 			msg.SessionID = msg.Channel + ":" + msg.ChatID
 		}
+		if r.admitInbound(msg) != nil {
+			return nil
+		}
 		_, err := r.Engine.Submit(ctx, msg)
 		return err
 	}
@@ -154,6 +195,9 @@ func (r *Runtime) channelAsk(channelID string) channelreg.AskHandler {
 		if strings.TrimSpace(msg.SessionID) == "" {
 			msg.SessionID = msg.Channel + ":" + msg.ChatID
 		}
+		if err := r.admitInbound(msg); err != nil {
+			return "", err
+		}
 		return r.Engine.Ask(ctx, msg)
 	}
 }
@@ -167,45 +211,117 @@ func (r *Runtime) channelAskStream(channelID string) channelreg.AskStreamHandler
 		if strings.TrimSpace(msg.SessionID) == "" {
 			msg.SessionID = msg.Channel + ":" + msg.ChatID
 		}
+		if err := r.admitInbound(msg); err != nil {
+			return err
+		}
 		return r.Engine.AskStream(ctx, msg, sink)
 	}
 }
 
 func (r *Runtime) StartGateway(ctx context.Context) error {
+	// Replies keep flowing while the gateway drains, so delivery gets its
+	// own context that outlives the caller's.
+	deliveryCtx, cancelDelivery := context.WithCancel(context.WithoutCancel(ctx))
 	ctx, cancel := context.WithCancel(ctx)
-	r.cancel = cancel
+	r.cancel = func() {
+		cancel()
+		cancelDelivery()
+	}
 
 	r.Cron.Start()
 	r.Heartbeat.Start()
 	r.startMetricsHTTP()
 	r.startFederationHTTP(ctx)
+	r.startAgentAPI(ctx)
+	r.startDailyRollup(ctx)
+	r.startArtifactReaper(ctx)
+	r.startSkillsRefresh(ctx)
+	plan := r.GatewayChannels()
+	for _, id := range plan.Suppressed {
+		r.Channels.Remove(id)
+	}
+	r.log.Printf("event=gateway_channels started=%s suppressed=%s", strings.Join(plan.Started, ","), strings.Join(plan.Suppressed, ","))
 
 	go func() {
 		defer close(r.done)
+		outbound := newOutboundQueues(deliveryCtx, r)
+		defer outbound.wait()
 		for {
 			select {
-			case <-ctx.Done():
+			case <-deliveryCtx.Done():
 				return
 			case msg := <-r.Engine.Outbound():
+				if msg.Channel == agentAPIChannel {
+					continue
+				}
 				if r.Channels != nil {
-					if err := r.Channels.Send(ctx, msg); err != nil {
-						traceID, _ := msg.Metadata["trace_id"].(string)
-						r.log.Printf("event=channel_send_failed trace_id=%s channel=%s chat_id=%s err=%v", traceID, msg.Channel, msg.ChatID, err)
-					}
+					outbound.enqueue(msg)
 				}
 			}
 		}
 	}()
 
+	r.Engine.StartDeferredDelivery()
+
 	if r.Channels != nil {
 		r.Channels.StartAll(ctx)
 	}
 
 	<-ctx.Done()
+	r.drain()
+	cancelDelivery()
 	<-r.done
 	return nil
 }
 
+// deliverOutbound sends one gateway reply, pacing and retrying per the
+// channel's send rate limit, and reports the outcome to the engine.
+func (r *Runtime) deliverOutbound(ctx context.Context, msg agent.OutboundMessage) {
+	err := r.Channels.Send(ctx, msg)
+	if err != nil {
+		traceID, _ := msg.Metadata["trace_id"].(string)
+		r.log.Printf("event=channel_send_failed trace_id=%s channel=%s chat_id=%s err=%v", traceID, msg.Channel, msg.ChatID, err)
+	}
+	r.Engine.ReportOutboundDelivery(msg, err)
+}
+
+// GatewayChannelPlan splits the registered channels into those StartGateway
+// launches and those held back by DisabledChannels. Unknown lists disabled
+// IDs that no enabled channel matches.
+type GatewayChannelPlan struct {
+	Started    []string
+	Suppressed []string
+	Unknown    []string
+}
+
+func (r *Runtime) GatewayChannels() GatewayChannelPlan {
+	disabled := map[string]bool{}
+	for _, id := range r.DisabledChannels {
+		if id = strings.ToLower(strings.TrimSpace(id)); id != "" {
+			disabled[id] = true
+		}
+	}
+	var plan GatewayChannelPlan
+	registered := map[string]bool{}
+	for _, id := range r.Channels.IDs() {
+		registered[id] = true
+		if disabled[id] {
+			plan.Suppressed = append(plan.Suppressed, id)
+		} else {
+			plan.Started = append(plan.Started, id)
+		}
+	}
+	for id := range disabled {
+		if !registered[id] {
+			plan.Unknown = append(plan.Unknown, id)
+		}
+	}
+	sort.Strings(plan.Started)
+	sort.Strings(plan.Suppressed)
+	sort.Strings(plan.Unknown)
+	return plan
+}
+
 func (r *Runtime) Shutdown() error {
 	if r.cancel != nil {
 		r.cancel()
@@ -216,6 +332,9 @@ func (r *Runtime) Shutdown() error {
 	if r.federationSrv != nil {
 		_ = r.federationSrv.Shutdown(context.Background())
 	}
+	if r.agentAPISrv != nil {
+		_ = r.agentAPISrv.Shutdown(context.Background())
+	}
 	r.Cron.Stop()
 	r.Heartbeat.Stop()
 	if err := r.Engine.Close(); err != nil {
@@ -235,30 +354,20 @@ func (r *Runtime) startMetricsHTTP() {
 	if listenAddr == "" {
 		return
 	}
-	authToken := strings.TrimSpace(r.Config.Runtime.MetricsHTTP.AuthToken)
-	localhostOnly := r.Config.Runtime.MetricsHTTP.LocalhostOnly
+	authorize := metricsAuthorizer(r.Config.Runtime.MetricsHTTP)
 	mux := http.NewServeMux()
 	mux.HandleFunc("/metrics", func(w http.ResponseWriter, req *http.Request) {
-		if localhostOnly {
-			host, _, err := net.SplitHostPort(req.RemoteAddr)
-			if err == nil {
-				ip := net.ParseIP(host)
-				if ip == nil || !ip.IsLoopback() {
-					http.Error(w, "forbidden", http.StatusForbidden)
-					return
-				}
-			}
-		}
-		if authToken != "" {
-			token := req.Header.Get("Authorization")
-			if token != "Bearer "+authToken {
-				http.Error(w, "unauthorized", http.StatusUnauthorized)
-				return
-			}
+		if !authorize(w, req) {
+			return
 		}
 		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
 		_, _ = w.Write([]byte(telemetry.PrometheusText(r.Metrics.Snapshot())))
 	})
+	if manageRoutesServed(r.Config.Runtime.MetricsHTTP) {
+		r.registerManageRoutes(mux, manageAuthorizer(r.Config.Runtime.MetricsHTTP))
+	} else {
+		r.log.Printf("manage api disabled: runtime.metricsHttp.manageToken is required on non-loopback listener %s", listenAddr)
+	}
 	r.metricsSrv = &http.Server{Addr: listenAddr, Handler: mux}
 	go func() {
 		if err := r.metricsSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -267,6 +376,36 @@ func (r *Runtime) startMetricsHTTP() {
 	}()
 }
 
+// metricsAuthorizer applies the /metrics checks: a loopback peer when
+// localhostOnly is set, and the bearer token when one is configured.
+func metricsAuthorizer(cfg config.MetricsHTTPRuntimeConfig) func(http.ResponseWriter, *http.Request) bool {
+	authToken := strings.TrimSpace(cfg.AuthToken)
+	localhostOnly := cfg.LocalhostOnly
+	return func(w http.ResponseWriter, req *http.Request) bool {
+		if localhostOnly && !loopbackPeer(req) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return false
+		}
+		if authToken != "" {
+			token := req.Header.Get("Authorization")
+			if token != "Bearer "+authToken {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func loopbackPeer(req *http.Request) bool {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
 func (r *Runtime) registerChannels(cfg config.Config) error {
 	if r.Channels == nil {
 		return nil
@@ -399,9 +538,26 @@ func (r *Runtime) registerChannels(cfg config.Config) error {
 			r.log.Printf("register telegram channel failed: %v", err)
 		}
 	}
+	r.applySendLimits(cfg)
 	return nil
 }
 
+// applySendLimits sets each channel's outbound pacing. The registry telegram
+// entry falls back to channels.telegram.sendRateLimit when it has none.
+func (r *Runtime) applySendLimits(cfg config.Config) {
+	r.Channels.SetSendLimit("telegram", cfg.Channels.Telegram.SendRateLimit)
+	for channelID, channelCfg := range cfg.Channels.Registry {
+		if !channelCfg.Enabled {
+			continue
+		}
+		limit := channelCfg.SendRateLimit
+		if channelID == "telegram" && limit == (config.ChannelSendRateLimitConfig{}) {
+			limit = cfg.Channels.Telegram.SendRateLimit
+		}
+		r.Channels.SetSendLimit(channelID, limit)
+	}
+}
+
 func channelMeta(cfg config.GenericChannelConfig, key string) string {
 	if cfg.Metadata == nil {
 		return ""
@@ -486,5 +642,10 @@ func (a *telegramAdapter) Send(ctx context.Context, msg agent.OutboundMessage) e
 	if a.channel == nil {
 		return nil
 	}
-	return a.channel.Send(ctx, msg)
+	err := a.channel.Send(ctx, msg)
+	var apiErr *tgbotapi.Error
+	if errors.As(err, &apiErr) && (apiErr.RetryAfter > 0 || apiErr.Code == http.StatusTooManyRequests) {
+		return &channelreg.RateLimitError{RetryAfter: time.Duration(apiErr.RetryAfter) * time.Second, Err: err}
+	}
+	return err
 }
@@ -0,0 +1,138 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/grixate/squidbot/internal/agent"
+	channelreg "github.com/grixate/squidbot/internal/channels"
+	"github.com/grixate/squidbot/internal/config"
+	"github.com/grixate/squidbot/internal/cron"
+	"github.com/grixate/squidbot/internal/heartbeat"
+	"github.com/grixate/squidbot/internal/provider"
+	storepkg "github.com/grixate/squidbot/internal/storage/bbolt"
+)
+
+func TestGatewayChannelsHonorsDisabledFlag(t *testing.T) {
+	logger := log.New(io.Discard, "", 0)
+	registry := channelreg.NewRegistry(logger)
+	for _, id := range []string{"telegram", "slack", "webchat"} {
+		if err := registry.Register(channelreg.NewNoopAdapter(id, logger)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runtime := &Runtime{Channels: registry, DisabledChannels: []string{" Telegram ", "irc"}, log: logger}
+
+	plan := runtime.GatewayChannels()
+	if !reflect.DeepEqual(plan.Started, []string{"slack", "webchat"}) {
+		t.Fatalf("unexpected started channels: %v", plan.Started)
+	}
+	if !reflect.DeepEqual(plan.Suppressed, []string{"telegram"}) {
+		t.Fatalf("unexpected suppressed channels: %v", plan.Suppressed)
+	}
+	if !reflect.DeepEqual(plan.Unknown, []string{"irc"}) {
+		t.Fatalf("unexpected unknown channels: %v", plan.Unknown)
+	}
+
+	if !registry.Remove("telegram") {
+		t.Fatal("expected telegram adapter to be removed")
+	}
+	if err := registry.Send(context.Background(), agent.OutboundMessage{Channel: "telegram", ChatID: "1", Content: "hi"}); err == nil {
+		t.Fatal("expected sends to a suppressed channel to fail")
+	}
+}
+
+// blockingProvider answers after delay, or when the turn is cancelled.
+type blockingProvider struct {
+	echoProvider
+	delay time.Duration
+}
+
+func (p blockingProvider) Chat(ctx context.Context, req provider.ChatRequest) (provider.ChatResponse, error) {
+	select {
+	case <-time.After(p.delay):
+		return provider.ChatResponse{Content: "finished"}, nil
+	case <-ctx.Done():
+		return provider.ChatResponse{}, ctx.Err()
+	}
+}
+
+func TestDrainWaitsForRunningTurnsThenAborts(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		graceSec int
+		delay    time.Duration
+		forced   bool
+	}{
+		{name: "drains within grace", graceSec: 10, delay: 300 * time.Millisecond},
+		{name: "aborts after grace", graceSec: 0, delay: time.Hour, forced: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.Agents.Defaults.Workspace = t.TempDir()
+			cfg.Runtime.ShutdownGraceSec = tc.graceSec
+			store, err := storepkg.Open(filepath.Join(t.TempDir(), "test.db"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer store.Close()
+			logger := log.New(io.Discard, "", 0)
+			engine, err := agent.NewEngine(cfg, blockingProvider{delay: tc.delay}, "test-model", store, nil, logger)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer engine.Close()
+			runtime := &Runtime{
+				Config:    cfg,
+				Store:     store,
+				Engine:    engine,
+				Cron:      cron.NewService(store, nil, nil),
+				Heartbeat: heartbeat.NewService(cfg.Agents.Defaults.Workspace, time.Hour, nil, nil),
+				log:       logger,
+			}
+
+			// The channel's context ends with the gateway; the turn must not.
+			ingressCtx, cancelIngress := context.WithCancel(context.Background())
+			if _, err := engine.Submit(ingressCtx, agent.InboundMessage{Channel: "telegram", ChatID: "1", SenderID: "1", Content: "work"}); err != nil {
+				t.Fatal(err)
+			}
+			cancelIngress()
+			deadline := time.Now().Add(5 * time.Second)
+			for turns, _ := engine.ActiveWork(); turns == 0; turns, _ = engine.ActiveWork() {
+				if time.Now().After(deadline) {
+					t.Fatal("turn never started")
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			started := time.Now()
+			runtime.drain()
+			if time.Since(started) > 5*time.Second {
+				t.Fatalf("drain took %s", time.Since(started))
+			}
+			if err := runtime.admitInbound(agent.InboundMessage{Channel: "telegram", SenderID: "1"}); !errors.Is(err, errGatewayDraining) {
+				t.Fatalf("expected inbound refused while draining, got %v", err)
+			}
+			if tc.forced {
+				return
+			}
+			if turns, _ := engine.ActiveWork(); turns != 0 {
+				t.Fatalf("expected no running turns after drain, got %d", turns)
+			}
+			select {
+			case msg := <-engine.Outbound():
+				if msg.Content != "finished" {
+					t.Fatalf("unexpected reply %q", msg.Content)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("expected the drained turn to reply")
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/grixate/squidbot/internal/skills"
+)
+
+// startSkillsRefresh rediscovers skills every skills.refreshIntervalSec so
+// edited, added, and removed skill files reach the running gateway.
+func (r *Runtime) startSkillsRefresh(ctx context.Context) {
+	if r == nil || r.Engine == nil || !r.Config.Skills.Enabled {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(skills.RefreshPeriod(r.Config))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if _, err := r.Engine.RefreshSkills(ctx); err != nil {
+				r.log.Printf("skills refresh failed: %v", err)
+			}
+		}
+	}()
+}
+
+func (r *Runtime) handleManageSkillsReload(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if r.Engine == nil {
+		http.Error(w, "engine unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	snapshot, err := r.Engine.ReloadSkills(req.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeFederationJSON(w, http.StatusOK, skillsReloadSummary(snapshot))
+}
+
+// SkillsReloadSummary is the reply of POST /api/manage/skills/reload.
+type SkillsReloadSummary struct {
+	Total    int      `json:"total"`
+	Valid    int      `json:"valid"`
+	Invalid  int      `json:"invalid"`
+	Warnings []string `json:"warnings"`
+}
+
+func skillsReloadSummary(snapshot skills.IndexSnapshot) SkillsReloadSummary {
+	valid, invalid := snapshot.Counts()
+	warnings := snapshot.Warnings
+	if warnings == nil {
+		warnings = []string{}
+	}
+	return SkillsReloadSummary{Total: len(snapshot.Skills), Valid: valid, Invalid: invalid, Warnings: warnings}
+}
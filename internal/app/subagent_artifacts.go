@@ -0,0 +1,34 @@
+package app
+
+import (
+	"context"
+	"time"
+)
+
+const artifactReapInterval = time.Hour
+
+// startArtifactReaper periodically removes expired subagent and federated
+// run artifact directories according to
+// runtime.subagents.artifactRetentionDays.
+func (r *Runtime) startArtifactReaper(ctx context.Context) {
+	if r == nil || r.Engine == nil || r.Config.Runtime.Subagents.ArtifactRetentionDays <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(artifactReapInterval)
+		defer ticker.Stop()
+		for {
+			reaped, err := r.Engine.ReapSubagentArtifacts(ctx, time.Now().UTC())
+			if err != nil {
+				r.log.Printf("subagent artifact cleanup failed: %v", err)
+			} else if reaped > 0 {
+				r.log.Printf("subagent artifact cleanup: reaped=%d", reaped)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
@@ -0,0 +1,97 @@
+package budget
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/grixate/squidbot/internal/mission"
+)
+
+const reportDayLayout = "2006-01-02"
+
+type UsageReportDay struct {
+	Day              string `json:"day"`
+	PromptTokens     uint64 `json:"prompt_tokens"`
+	CompletionTokens uint64 `json:"completion_tokens"`
+	TotalTokens      uint64 `json:"total_tokens"`
+}
+
+type UsageAverage struct {
+	PromptTokens     float64 `json:"prompt_tokens"`
+	CompletionTokens float64 `json:"completion_tokens"`
+	TotalTokens      float64 `json:"total_tokens"`
+}
+
+// UsageReport is a per-day token breakdown over an inclusive range of UTC
+// days. Every day in the range appears, with zeros when nothing was used.
+type UsageReport struct {
+	From         string           `json:"from"`
+	To           string           `json:"to"`
+	Days         []UsageReportDay `json:"days"`
+	Totals       UsageReportDay   `json:"totals"`
+	DailyAverage UsageAverage     `json:"daily_average"`
+	// TokenByProvider totals the range per "provider/model" label. Usage
+	// recorded before provider attribution existed is not included.
+	TokenByProvider map[string]mission.ProviderUsage `json:"token_by_provider,omitempty"`
+}
+
+// BuildUsageReport aggregates recorded usage days between the UTC dates of
+// from and to, inclusive.
+func BuildUsageReport(usage []mission.UsageDay, from, to time.Time) (UsageReport, error) {
+	start := utcDay(from)
+	end := utcDay(to)
+	if end.Before(start) {
+		return UsageReport{}, fmt.Errorf("report range ends (%s) before it starts (%s)", end.Format(reportDayLayout), start.Format(reportDayLayout))
+	}
+	byDay := make(map[string]mission.UsageDay, len(usage))
+	for _, day := range usage {
+		byDay[day.Day] = day
+	}
+	report := UsageReport{
+		From:   start.Format(reportDayLayout),
+		To:     end.Format(reportDayLayout),
+		Totals: UsageReportDay{Day: "total"},
+	}
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		key := day.Format(reportDayLayout)
+		recorded := byDay[key]
+		row := UsageReportDay{
+			Day:              key,
+			PromptTokens:     recorded.PromptTokens,
+			CompletionTokens: recorded.CompletionTokens,
+			TotalTokens:      recorded.TotalTokens,
+		}
+		report.Days = append(report.Days, row)
+		report.Totals.PromptTokens += row.PromptTokens
+		report.Totals.CompletionTokens += row.CompletionTokens
+		report.Totals.TotalTokens += row.TotalTokens
+		for label, share := range recorded.ByProvider {
+			if report.TokenByProvider == nil {
+				report.TokenByProvider = map[string]mission.ProviderUsage{}
+			}
+			total := report.TokenByProvider[label]
+			total.PromptTokens += share.PromptTokens
+			total.CompletionTokens += share.CompletionTokens
+			total.TotalTokens += share.TotalTokens
+			total.Calls += share.Calls
+			total.TimedTokens += share.TimedTokens
+			total.LatencyMS += share.LatencyMS
+			if share.MaxTokensPerSec > total.MaxTokensPerSec {
+				total.MaxTokensPerSec = share.MaxTokensPerSec
+			}
+			report.TokenByProvider[label] = total
+		}
+	}
+	count := float64(len(report.Days))
+	report.DailyAverage = UsageAverage{
+		PromptTokens:     float64(report.Totals.PromptTokens) / count,
+		CompletionTokens: float64(report.Totals.CompletionTokens) / count,
+		TotalTokens:      float64(report.Totals.TotalTokens) / count,
+	}
+	return report, nil
+}
+
+func utcDay(value time.Time) time.Time {
+	value = value.UTC()
+	return time.Date(value.Year(), value.Month(), value.Day(), 0, 0, 0, 0, time.UTC)
+}
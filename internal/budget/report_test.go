@@ -0,0 +1,43 @@
+package budget
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grixate/squidbot/internal/mission"
+)
+
+func TestBuildUsageReportFillsMissingDays(t *testing.T) {
+	usage := []mission.UsageDay{
+		{Day: "2026-03-01", PromptTokens: 100, CompletionTokens: 50, TotalTokens: 150, ByProvider: map[string]mission.ProviderUsage{
+			"anthropic/claude-sonnet-4": {PromptTokens: 100, CompletionTokens: 50, TotalTokens: 150},
+		}},
+		{Day: "2026-03-03", PromptTokens: 20, CompletionTokens: 10, TotalTokens: 30, ByProvider: map[string]mission.ProviderUsage{
+			"ollama/llama3":             {PromptTokens: 5, CompletionTokens: 5, TotalTokens: 10},
+			"anthropic/claude-sonnet-4": {PromptTokens: 15, CompletionTokens: 5, TotalTokens: 20},
+		}},
+		{Day: "2026-02-20", PromptTokens: 999, CompletionTokens: 999, TotalTokens: 1998},
+	}
+	from := time.Date(2026, 3, 1, 18, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 3, 3, 1, 0, 0, 0, time.UTC)
+	report, err := BuildUsageReport(usage, from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Days) != 3 || report.Days[1].Day != "2026-03-02" || report.Days[1].TotalTokens != 0 {
+		t.Fatalf("expected three continuous days with a zero gap, got %+v", report.Days)
+	}
+	if report.Totals.TotalTokens != 180 || report.Totals.PromptTokens != 120 {
+		t.Fatalf("unexpected totals: %+v", report.Totals)
+	}
+	if report.TokenByProvider["anthropic/claude-sonnet-4"].TotalTokens != 170 || report.TokenByProvider["ollama/llama3"].TotalTokens != 10 {
+		t.Fatalf("unexpected provider breakdown: %+v", report.TokenByProvider)
+	}
+	if report.DailyAverage.TotalTokens != 60 {
+		t.Fatalf("unexpected daily average: %+v", report.DailyAverage)
+	}
+
+	if _, err := BuildUsageReport(usage, to, from); err == nil {
+		t.Fatal("expected reversed range to fail")
+	}
+}
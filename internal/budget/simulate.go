@@ -0,0 +1,142 @@
+package budget
+
+import (
+	"strings"
+)
+
+// Price is the cost of a model in USD per million tokens.
+type Price struct {
+	InputPerMillion  float64 `json:"input_per_million"`
+	OutputPerMillion float64 `json:"output_per_million"`
+}
+
+// DefaultPricing is a small built-in table of list prices used when the
+// config does not provide one for the model.
+var DefaultPricing = map[string]Price{
+	"claude-opus-4-1":            {InputPerMillion: 15, OutputPerMillion: 75},
+	"claude-sonnet-4":            {InputPerMillion: 3, OutputPerMillion: 15},
+	"claude-3-5-sonnet-20241022": {InputPerMillion: 3, OutputPerMillion: 15},
+	"claude-3-5-haiku-20241022":  {InputPerMillion: 0.8, OutputPerMillion: 4},
+	"gpt-4o":                     {InputPerMillion: 2.5, OutputPerMillion: 10},
+	"gpt-4o-mini":                {InputPerMillion: 0.15, OutputPerMillion: 0.6},
+}
+
+// LookupPrice finds a price for model, trying the exact name and then the
+// name without its provider prefix (e.g. "anthropic/claude-opus-4-1").
+func LookupPrice(table map[string]Price, model string) (Price, bool) {
+	model = strings.ToLower(strings.TrimSpace(model))
+	if model == "" {
+		return Price{}, false
+	}
+	candidates := []string{model}
+	if idx := strings.LastIndex(model, "/"); idx >= 0 && idx < len(model)-1 {
+		candidates = append(candidates, model[idx+1:])
+	}
+	for _, candidate := range candidates {
+		for key, price := range table {
+			if strings.ToLower(strings.TrimSpace(key)) == candidate {
+				return price, true
+			}
+		}
+	}
+	return Price{}, false
+}
+
+type SimulationInput struct {
+	DailyTurns       int
+	AvgTokensPerTurn uint64
+	Days             int
+	Sessions         int
+	OutputShare      float64
+	Price            *Price
+}
+
+type ScopeProjection struct {
+	Scope            string  `json:"scope"`
+	HardLimitTokens  uint64  `json:"hard_limit_tokens"`
+	SoftThresholdPct int     `json:"soft_threshold_pct"`
+	ProjectedTokens  uint64  `json:"projected_tokens"`
+	UsedPct          float64 `json:"used_pct"`
+	SoftReached      bool    `json:"soft_reached"`
+	HardReached      bool    `json:"hard_reached"`
+	SoftReachedDay   int     `json:"soft_reached_day,omitempty"`
+	HardReachedDay   int     `json:"hard_reached_day,omitempty"`
+}
+
+type Simulation struct {
+	DailyTokens      uint64            `json:"daily_tokens"`
+	TotalTokens      uint64            `json:"total_tokens"`
+	Scopes           []ScopeProjection `json:"scopes"`
+	DailyCostUSD     *float64          `json:"daily_cost_usd,omitempty"`
+	EstimatedCostUSD *float64          `json:"estimated_cost_usd,omitempty"`
+}
+
+// Simulate projects token usage over the requested period against the
+// configured scope limits. Global usage accumulates across all sessions,
+// session usage is split evenly across sessions, and a subagent run is
+// treated as a single turn.
+func Simulate(settings Settings, in SimulationInput) Simulation {
+	settings = settings.Normalized()
+	if in.DailyTurns < 0 {
+		in.DailyTurns = 0
+	}
+	if in.Days <= 0 {
+		in.Days = 1
+	}
+	if in.Sessions <= 0 {
+		in.Sessions = 1
+	}
+	daily := uint64(in.DailyTurns) * in.AvgTokensPerTurn
+	out := Simulation{DailyTokens: daily, TotalTokens: daily * uint64(in.Days)}
+
+	sessionDaily := daily / uint64(in.Sessions)
+	out.Scopes = []ScopeProjection{
+		projectScope("global", settings.GlobalHardLimitTokens, settings.GlobalSoftThresholdPct, daily, in.Days),
+		projectScope("session", settings.SessionHardLimitTokens, settings.SessionSoftThresholdPct, sessionDaily, in.Days),
+		projectScope("subagent", settings.SubagentRunHardLimitTokens, settings.SubagentRunSoftThresholdPct, in.AvgTokensPerTurn, 1),
+	}
+
+	if in.Price != nil {
+		share := in.OutputShare
+		if share < 0 {
+			share = 0
+		}
+		if share > 1 {
+			share = 1
+		}
+		perToken := (in.Price.InputPerMillion*(1-share) + in.Price.OutputPerMillion*share) / 1e6
+		dailyCost := float64(daily) * perToken
+		totalCost := float64(out.TotalTokens) * perToken
+		out.DailyCostUSD = &dailyCost
+		out.EstimatedCostUSD = &totalCost
+	}
+	return out
+}
+
+func projectScope(scope string, hard uint64, softPct int, perDay uint64, days int) ScopeProjection {
+	out := ScopeProjection{
+		Scope:            scope,
+		HardLimitTokens:  hard,
+		SoftThresholdPct: softPct,
+		ProjectedTokens:  perDay * uint64(days),
+	}
+	if hard == 0 {
+		return out
+	}
+	out.UsedPct = float64(out.ProjectedTokens) * 100 / float64(hard)
+	if perDay == 0 {
+		return out
+	}
+	if softPct > 0 {
+		soft := ceilDiv(hard*uint64(softPct), 100)
+		if day := int(ceilDiv(soft, perDay)); day <= days {
+			out.SoftReached = true
+			out.SoftReachedDay = day
+		}
+	}
+	if day := int(ceilDiv(hard, perDay)); day <= days {
+		out.HardReached = true
+		out.HardReachedDay = day
+	}
+	return out
+}
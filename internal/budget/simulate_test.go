@@ -0,0 +1,50 @@
+package budget
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSimulateProjectsScopesAndCost(t *testing.T) {
+	settings := Settings{
+		Enabled:                     true,
+		GlobalHardLimitTokens:       100000,
+		GlobalSoftThresholdPct:      80,
+		SessionHardLimitTokens:      50000,
+		SessionSoftThresholdPct:     80,
+		SubagentRunHardLimitTokens:  1000,
+		SubagentRunSoftThresholdPct: 50,
+	}
+	price, ok := LookupPrice(map[string]Price{"gpt-4o": {InputPerMillion: 2, OutputPerMillion: 10}}, "openai/GPT-4o")
+	if !ok {
+		t.Fatal("expected provider-prefixed model to resolve")
+	}
+	sim := Simulate(settings, SimulationInput{
+		DailyTurns:       10,
+		AvgTokensPerTurn: 2000,
+		Days:             7,
+		Sessions:         2,
+		OutputShare:      0.5,
+		Price:            &price,
+	})
+	if sim.DailyTokens != 20000 || sim.TotalTokens != 140000 {
+		t.Fatalf("unexpected totals: %+v", sim)
+	}
+
+	global := sim.Scopes[0]
+	if !global.SoftReached || global.SoftReachedDay != 4 || !global.HardReached || global.HardReachedDay != 5 {
+		t.Fatalf("unexpected global projection: %+v", global)
+	}
+	session := sim.Scopes[1]
+	if session.ProjectedTokens != 70000 || !session.SoftReached || session.SoftReachedDay != 4 || !session.HardReached || session.HardReachedDay != 5 {
+		t.Fatalf("unexpected session projection: %+v", session)
+	}
+	subagent := sim.Scopes[2]
+	if !subagent.HardReached || subagent.HardReachedDay != 1 {
+		t.Fatalf("expected a single turn to exceed the subagent run limit: %+v", subagent)
+	}
+
+	if sim.EstimatedCostUSD == nil || math.Abs(*sim.EstimatedCostUSD-0.84) > 1e-9 {
+		t.Fatalf("unexpected cost estimate: %v", sim.EstimatedCostUSD)
+	}
+}
@@ -98,6 +98,50 @@ type Counter struct {
 	UpdatedAt        time.Time `json:"updated_at"`
 }
 
+// ResetResult reports a counter cleared by a budget reset. Previous holds
+// the counter as it was, and ClearedReservations the open reservations that
+// were cancelled with it.
+type ResetResult struct {
+	Scope               string  `json:"scope"`
+	Previous            Counter `json:"previous"`
+	ClearedReservations int     `json:"cleared_reservations"`
+}
+
+// ScopeKey returns the counter key for kind ("global", "session", or
+// "subagent") and id, the session or subagent run ID. A kind already in
+// "session:<id>" or "subagent:<run>" form is accepted as is.
+func ScopeKey(kind, id string) (string, error) {
+	kind = strings.TrimSpace(kind)
+	id = strings.TrimSpace(id)
+	if prefix, rest, ok := strings.Cut(kind, ":"); ok {
+		if id != "" && id != strings.TrimSpace(rest) {
+			return "", fmt.Errorf("scope %q conflicts with id %q", kind, id)
+		}
+		kind, id = prefix, strings.TrimSpace(rest)
+	}
+	switch strings.ToLower(kind) {
+	case "global":
+		return "global", nil
+	case "session", "subagent":
+		if id == "" {
+			return "", fmt.Errorf("%s scope requires an id", strings.ToLower(kind))
+		}
+		return strings.ToLower(kind) + ":" + id, nil
+	default:
+		return "", fmt.Errorf("unsupported scope %q (use global|session|subagent)", kind)
+	}
+}
+
+// ToolUsage counts one tool's calls on one UTC day. Blocked counts calls
+// refused because the daily limit was already reached.
+type ToolUsage struct {
+	Day       string    `json:"day"`
+	Tool      string    `json:"tool"`
+	Calls     int       `json:"calls"`
+	Blocked   int       `json:"blocked"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
 type Reservation struct {
 	ID          string    `json:"id"`
 	Scope       string    `json:"scope"`
@@ -0,0 +1,59 @@
+// Package buildinfo exposes version details stamped into the binary at
+// build time, e.g.
+//
+//	go build -ldflags "-X github.com/grixate/squidbot/internal/buildinfo.Version=v1.2.3 \
+//	  -X github.com/grixate/squidbot/internal/buildinfo.Commit=$(git rev-parse --short HEAD) \
+//	  -X github.com/grixate/squidbot/internal/buildinfo.Date=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd/squidbot
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+var (
+	Version = "dev"
+	Commit  = ""
+	Date    = ""
+)
+
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	Date      string `json:"date,omitempty"`
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"`
+	Modified  bool   `json:"modified,omitempty"`
+}
+
+// Get returns the stamped build info. When no commit was stamped, the VCS
+// revision recorded by the Go toolchain is used instead.
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		Date:      Date,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.Date == "" {
+					info.Date = setting.Value
+				}
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+		if info.Version == "dev" && build.Main.Version != "" && build.Main.Version != "(devel)" {
+			info.Version = build.Main.Version
+		}
+	}
+	return info
+}
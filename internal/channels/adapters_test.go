@@ -3,11 +3,13 @@ package channels
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"strings"
 	"testing"
 	"time"
@@ -210,3 +212,165 @@ func TestWhatsAppAdapterVerification(t *testing.T) {
 		t.Fatalf("unexpected challenge body: %q", w.Body.String())
 	}
 }
+
+func TestRegistrySendRetriesRateLimitedChannel(t *testing.T) {
+	attempts := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.Header().Set("Retry-After", "0.01")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	registry := NewRegistry(log.New(io.Discard, "", 0))
+	if err := registry.Register(NewWebhookAdapter("hook", config.GenericChannelConfig{Endpoint: ts.URL}, log.New(io.Discard, "", 0))); err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+	if err := registry.Send(context.Background(), agent.OutboundMessage{Channel: "hook", ChatID: "c1", Content: "hello"}); err != nil {
+		t.Fatalf("expected retry to succeed, got %v", err)
+	}
+	if attempts != 2 {
+		t.Fatalf("expected 2 attempts, got %d", attempts)
+	}
+
+	attempts = 0
+	registry.SetSendLimit("hook", config.ChannelSendRateLimitConfig{MaxRetries: -1})
+	err := registry.Send(context.Background(), agent.OutboundMessage{Channel: "hook", ChatID: "c1", Content: "hello"})
+	var limited *RateLimitError
+	if !errors.As(err, &limited) || limited.RetryAfter != 10*time.Millisecond {
+		t.Fatalf("expected rate limit error with retry hint when retries are off, got %v", err)
+	}
+}
+
+func TestRegistryDeadLettersWebhookStillRateLimited(t *testing.T) {
+	attempts := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.Header().Set("Retry-After", "0.01")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer ts.Close()
+
+	deadLetters := t.TempDir() + "/dead.jsonl"
+	registry := NewRegistry(log.New(io.Discard, "", 0))
+	if err := registry.Register(NewWebhookAdapter("hook", config.GenericChannelConfig{
+		Endpoint: ts.URL,
+		Metadata: map[string]string{"dead_letter_path": deadLetters},
+	}, log.New(io.Discard, "", 0))); err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+	registry.SetSendLimit("hook", config.ChannelSendRateLimitConfig{MaxRetries: 1})
+	err := registry.Send(context.Background(), agent.OutboundMessage{Channel: "hook", ChatID: "c1", Content: "hello"})
+	var limited *RateLimitError
+	if !errors.As(err, &limited) || attempts != 2 {
+		t.Fatalf("expected a rate limit error after 2 attempts, got %v after %d", err, attempts)
+	}
+	raw, err := os.ReadFile(deadLetters)
+	if err != nil {
+		t.Fatalf("read dead letters: %v", err)
+	}
+	var entry webhookDeadLetter
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		t.Fatalf("decode dead letter %q: %v", raw, err)
+	}
+	if entry.ChatID != "c1" || entry.Content != "hello" || entry.Attempts != 2 || !strings.Contains(entry.Error, "429") {
+		t.Fatalf("unexpected dead letter %+v", entry)
+	}
+}
+
+func TestWebhookAdapterRetriesServerErrorsAndDeadLetters(t *testing.T) {
+	attempts := 0
+	failUntil := 2
+	var payload map[string]any
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("unexpected auth header %q", r.Header.Get("Authorization"))
+		}
+		if attempts <= failUntil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	deadLetters := t.TempDir() + "/dead.jsonl"
+	adapter := NewWebhookAdapter("hook", config.GenericChannelConfig{
+		Endpoint:      ts.URL,
+		AuthToken:     "secret",
+		SendRateLimit: config.ChannelSendRateLimitConfig{MaxRetries: 2, RetryBackoffMs: 100},
+		Metadata:      map[string]string{"dead_letter_path": deadLetters},
+	}, log.New(io.Discard, "", 0))
+	var waits []time.Duration
+	adapter.sleep = func(ctx context.Context, wait time.Duration) error {
+		waits = append(waits, wait)
+		return nil
+	}
+	msg := agent.OutboundMessage{Channel: "hook", ChatID: "c1", Content: "hello", Metadata: map[string]any{"trace_id": "t1"}}
+	if err := adapter.Send(context.Background(), msg); err != nil {
+		t.Fatalf("expected retries to succeed, got %v", err)
+	}
+	if attempts != 3 || len(waits) != 2 || waits[0] != 100*time.Millisecond || waits[1] != 200*time.Millisecond {
+		t.Fatalf("expected 3 attempts with doubling backoff, got %d attempts and waits %v", attempts, waits)
+	}
+	if payload["channel"] != "hook" || payload["chatId"] != "c1" || payload["content"] != "hello" {
+		t.Fatalf("unexpected payload %v", payload)
+	}
+	if _, err := os.Stat(deadLetters); !os.IsNotExist(err) {
+		t.Fatalf("expected no dead letter after a delivered message, got %v", err)
+	}
+
+	attempts, failUntil = 0, 10
+	if err := adapter.Send(context.Background(), msg); err == nil {
+		t.Fatal("expected send to fail once retries run out")
+	}
+	if attempts != 3 {
+		t.Fatalf("expected 3 attempts, got %d", attempts)
+	}
+	raw, err := os.ReadFile(deadLetters)
+	if err != nil {
+		t.Fatalf("read dead letters: %v", err)
+	}
+	var entry webhookDeadLetter
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		t.Fatalf("decode dead letter %q: %v", raw, err)
+	}
+	if entry.Channel != "hook" || entry.ChatID != "c1" || entry.Content != "hello" || entry.Attempts != 3 || !strings.Contains(entry.Error, "502") {
+		t.Fatalf("unexpected dead letter %+v", entry)
+	}
+
+	attempts = 0
+	ts.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadRequest)
+	})
+	if err := adapter.Send(context.Background(), msg); err == nil || attempts != 1 {
+		t.Fatalf("expected a 4xx to fail without retries, got %v after %d attempts", err, attempts)
+	}
+}
+
+func TestSendLimiterPacesBurst(t *testing.T) {
+	limiter := newSendLimiter(config.ChannelSendRateLimitConfig{PerSecond: 2, Burst: 2})
+	var waits []time.Duration
+	limiter.sleep = func(ctx context.Context, wait time.Duration) error {
+		waits = append(waits, wait)
+		return nil
+	}
+	for i := 0; i < 4; i++ {
+		if err := limiter.do(context.Background(), func() error { return nil }); err != nil {
+			t.Fatalf("send %d failed: %v", i, err)
+		}
+	}
+	if len(waits) != 2 {
+		t.Fatalf("expected the two sends past the burst to wait, got %v", waits)
+	}
+	if waits[0] < 400*time.Millisecond || waits[1] < 900*time.Millisecond {
+		t.Fatalf("expected waits of about 500ms and 1s, got %v", waits)
+	}
+}
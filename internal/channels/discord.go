@@ -82,7 +82,7 @@ func (a *DiscordAdapter) Send(ctx context.Context, msg agent.OutboundMessage) er
 	defer resp.Body.Close()
 	if resp.StatusCode >= 300 {
 		body, _ := io.ReadAll(io.LimitReader(resp.Body, 2048))
-		return fmt.Errorf("discord send failed status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(body)))
+		return rateLimitedResponse(resp, fmt.Errorf("discord send failed status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(body))))
 	}
 	return nil
 }
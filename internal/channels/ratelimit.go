@@ -0,0 +1,161 @@
+package channels
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grixate/squidbot/internal/config"
+)
+
+const (
+	defaultSendRetries      = 3
+	defaultSendRetryBackoff = time.Second
+	// maxSendRetryWait is the longest retry hint a send waits out; a channel
+	// asking for more fails the send instead of stalling its queue.
+	maxSendRetryWait = 2 * time.Minute
+)
+
+// RateLimitError reports a send the channel refused because of its own rate
+// limit. RetryAfter is the channel's hint, or zero when it gave none.
+type RateLimitError struct {
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *RateLimitError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("rate limited (retry after %s): %v", e.RetryAfter, e.Err)
+	}
+	return fmt.Sprintf("rate limited: %v", e.Err)
+}
+
+func (e *RateLimitError) Unwrap() error { return e.Err }
+
+// rateLimitedResponse wraps err in a RateLimitError when resp is a 429,
+// reading the Retry-After header as seconds or an HTTP date.
+func rateLimitedResponse(resp *http.Response, err error) error {
+	if resp == nil || resp.StatusCode != http.StatusTooManyRequests {
+		return err
+	}
+	return &RateLimitError{RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")), Err: err}
+}
+
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(at); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
+
+// sendLimiter paces sends on one channel with a token bucket and retries
+// sends the channel rejects with a RateLimitError.
+type sendLimiter struct {
+	interval   time.Duration
+	burst      float64
+	maxRetries int
+	backoff    time.Duration
+	sleep      func(ctx context.Context, wait time.Duration) error
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newSendLimiter(cfg config.ChannelSendRateLimitConfig) *sendLimiter {
+	limiter := &sendLimiter{
+		burst:      float64(max(cfg.Burst, 1)),
+		maxRetries: cfg.MaxRetries,
+		backoff:    time.Duration(cfg.RetryBackoffMs) * time.Millisecond,
+		sleep:      sleepContext,
+	}
+	if cfg.PerSecond > 0 {
+		limiter.interval = time.Duration(float64(time.Second) / cfg.PerSecond)
+	}
+	if limiter.maxRetries == 0 {
+		limiter.maxRetries = defaultSendRetries
+	}
+	if limiter.maxRetries < 0 {
+		limiter.maxRetries = 0
+	}
+	if limiter.backoff <= 0 {
+		limiter.backoff = defaultSendRetryBackoff
+	}
+	limiter.tokens = limiter.burst
+	return limiter
+}
+
+// do runs send once a token is available, retrying rate-limited attempts.
+func (l *sendLimiter) do(ctx context.Context, send func() error) error {
+	backoff := l.backoff
+	for attempt := 0; ; attempt++ {
+		if err := l.wait(ctx); err != nil {
+			return err
+		}
+		err := send()
+		var limited *RateLimitError
+		if err == nil || !errors.As(err, &limited) || attempt >= l.maxRetries {
+			return err
+		}
+		wait := limited.RetryAfter
+		if wait <= 0 {
+			wait = backoff
+			backoff *= 2
+		}
+		if wait > maxSendRetryWait {
+			return err
+		}
+		if sleepErr := l.sleep(ctx, wait); sleepErr != nil {
+			return err
+		}
+	}
+}
+
+// wait blocks until the bucket holds a token and takes it. Without pacing it
+// returns at once.
+func (l *sendLimiter) wait(ctx context.Context) error {
+	if l.interval <= 0 {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	if !l.last.IsZero() {
+		l.tokens += float64(now.Sub(l.last)) / float64(l.interval)
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+	l.tokens--
+	deficit := -l.tokens
+	l.mu.Unlock()
+	if deficit <= 0 {
+		return nil
+	}
+	return l.sleep(ctx, time.Duration(deficit*float64(l.interval)))
+}
+
+func sleepContext(ctx context.Context, wait time.Duration) error {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -2,11 +2,13 @@ package channels
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
 
 	"github.com/grixate/squidbot/internal/agent"
+	"github.com/grixate/squidbot/internal/config"
 )
 
 type Adapter interface {
@@ -20,8 +22,17 @@ type StreamingAdapter interface {
 	SendStream(ctx context.Context, stream agent.OutboundStream) error
 }
 
+// DeadLetterer is implemented by adapters that record messages they could
+// not deliver. Adapters dead-letter their own failures, but hand rate-limited
+// sends back to the registry for retrying, so the registry dead-letters those
+// once its retries run out.
+type DeadLetterer interface {
+	DeadLetter(msg agent.OutboundMessage, attempts int, err error)
+}
+
 type Registry struct {
 	adapters map[string]Adapter
+	limiters map[string]*sendLimiter
 	log      *log.Logger
 }
 
@@ -29,7 +40,7 @@ func NewRegistry(logger *log.Logger) *Registry {
 	if logger == nil {
 		logger = log.Default()
 	}
-	return &Registry{adapters: map[string]Adapter{}, log: logger}
+	return &Registry{adapters: map[string]Adapter{}, limiters: map[string]*sendLimiter{}, log: logger}
 }
 
 func (r *Registry) Register(adapter Adapter) error {
@@ -47,9 +58,38 @@ func (r *Registry) Register(adapter Adapter) error {
 		return fmt.Errorf("channel adapter %q already registered", id)
 	}
 	r.adapters[id] = adapter
+	r.limiters[id] = newSendLimiter(config.ChannelSendRateLimitConfig{})
 	return nil
 }
 
+// SetSendLimit replaces the pacing and retry settings for a registered
+// channel. Channels without one still retry rate-limited sends with the
+// defaults.
+func (r *Registry) SetSendLimit(id string, limit config.ChannelSendRateLimitConfig) {
+	if r == nil {
+		return
+	}
+	id = strings.ToLower(strings.TrimSpace(id))
+	if _, ok := r.adapters[id]; ok {
+		r.limiters[id] = newSendLimiter(limit)
+	}
+}
+
+// Remove drops a registered adapter so it is neither started nor used for
+// sends. It reports whether the adapter was registered.
+func (r *Registry) Remove(id string) bool {
+	if r == nil {
+		return false
+	}
+	id = strings.ToLower(strings.TrimSpace(id))
+	if _, ok := r.adapters[id]; !ok {
+		return false
+	}
+	delete(r.adapters, id)
+	delete(r.limiters, id)
+	return true
+}
+
 func (r *Registry) StartAll(ctx context.Context) {
 	if r == nil {
 		return
@@ -74,7 +114,18 @@ func (r *Registry) Send(ctx context.Context, msg agent.OutboundMessage) error {
 	if !ok {
 		return fmt.Errorf("channel %q is not configured", id)
 	}
-	return adapter.Send(ctx, msg)
+	attempts := 0
+	err := r.limiters[id].do(ctx, func() error {
+		attempts++
+		return adapter.Send(ctx, msg)
+	})
+	var limited *RateLimitError
+	if errors.As(err, &limited) && ctx.Err() == nil {
+		if deadLetterer, ok := adapter.(DeadLetterer); ok {
+			deadLetterer.DeadLetter(msg, attempts, err)
+		}
+	}
+	return err
 }
 
 func (r *Registry) SendStream(ctx context.Context, stream agent.OutboundStream) error {
@@ -87,7 +138,9 @@ func (r *Registry) SendStream(ctx context.Context, stream agent.OutboundStream)
 		return fmt.Errorf("channel %q is not configured", id)
 	}
 	if streamingAdapter, ok := adapter.(StreamingAdapter); ok {
-		return streamingAdapter.SendStream(ctx, stream)
+		return r.limiters[id].do(ctx, func() error {
+			return streamingAdapter.SendStream(ctx, stream)
+		})
 	}
 	finalContent := ""
 	if len(stream.Events) > 0 {
@@ -104,7 +157,7 @@ func (r *Registry) SendStream(ctx context.Context, stream agent.OutboundStream)
 	if strings.TrimSpace(finalContent) == "" {
 		return nil
 	}
-	return adapter.Send(ctx, agent.OutboundMessage{
+	return r.Send(ctx, agent.OutboundMessage{
 		Channel:  stream.Channel,
 		ChatID:   stream.ChatID,
 		ReplyTo:  stream.ReplyTo,
@@ -95,7 +95,7 @@ func (a *SlackAdapter) Send(ctx context.Context, msg agent.OutboundMessage) erro
 	defer resp.Body.Close()
 	if resp.StatusCode >= 300 {
 		body, _ := io.ReadAll(io.LimitReader(resp.Body, 2048))
-		return fmt.Errorf("slack send failed status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(body)))
+		return rateLimitedResponse(resp, fmt.Errorf("slack send failed status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(body))))
 	}
 	var envelope struct {
 		OK    bool   `json:"ok"`
@@ -105,7 +105,11 @@ func (a *SlackAdapter) Send(ctx context.Context, msg agent.OutboundMessage) erro
 		return nil
 	}
 	if !envelope.OK {
-		return fmt.Errorf("slack send failed: %s", strings.TrimSpace(envelope.Error))
+		err := fmt.Errorf("slack send failed: %s", strings.TrimSpace(envelope.Error))
+		if strings.TrimSpace(envelope.Error) == "ratelimited" {
+			return &RateLimitError{RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")), Err: err}
+		}
+		return err
 	}
 	return nil
 }
@@ -104,6 +104,9 @@ func (c *Channel) toInbound(update tgbotapi.Update) agent.InboundMessage {
 		"telegram_message_id": m.MessageID,
 		"is_group":            m.Chat.IsGroup() || m.Chat.IsSuperGroup(),
 	}
+	if m.From != nil && m.From.UserName != "" {
+		metadata["sender_username"] = m.From.UserName
+	}
 	media := []string{}
 	if len(m.Photo) > 0 {
 		metadata["photo_file_id"] = m.Photo[len(m.Photo)-1].FileID
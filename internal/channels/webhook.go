@@ -4,21 +4,46 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/grixate/squidbot/internal/agent"
 	"github.com/grixate/squidbot/internal/config"
 )
 
+// WebhookAdapter posts outbound messages as JSON to cfg.Endpoint. A 5xx
+// reply or a transport error is retried with doubling backoff, using the
+// channel's sendRateLimit.maxRetries and retryBackoffMs; a 429 is left to the
+// registry's rate-limit retries, and the registry dead-letters it once those
+// run out. A message that still fails is logged as event=webhook_dead_letter
+// and, when metadata.dead_letter_path is set, appended there as a JSON line.
 type WebhookAdapter struct {
 	id     string
 	cfg    config.GenericChannelConfig
 	client *http.Client
 	log    *log.Logger
+	sleep  func(ctx context.Context, wait time.Duration) error
+
+	deadLetterMu sync.Mutex
+}
+
+// webhookDeadLetter is one line of the dead-letter file.
+type webhookDeadLetter struct {
+	FailedAt time.Time      `json:"failed_at"`
+	Channel  string         `json:"channel"`
+	ChatID   string         `json:"chat_id"`
+	Content  string         `json:"content"`
+	Metadata map[string]any `json:"metadata,omitempty"`
+	Attempts int            `json:"attempts"`
+	Error    string         `json:"error"`
 }
 
 func NewWebhookAdapter(id string, cfg config.GenericChannelConfig, logger *log.Logger) *WebhookAdapter {
@@ -31,7 +56,8 @@ func NewWebhookAdapter(id string, cfg config.GenericChannelConfig, logger *log.L
 		client: &http.Client{
 			Timeout: 15 * time.Second,
 		},
-		log: logger,
+		log:   logger,
+		sleep: sleepContext,
 	}
 }
 
@@ -45,7 +71,9 @@ func (a *WebhookAdapter) Send(ctx context.Context, msg agent.OutboundMessage) er
 		return fmt.Errorf("channel %q endpoint is empty", a.id)
 	}
 	payload := map[string]any{
-		"channel":  a.id,
+		"channel": a.id,
+		"chatId":  msg.ChatID,
+		// chat_id predates chatId and is kept for existing receivers.
 		"chat_id":  msg.ChatID,
 		"content":  msg.Content,
 		"reply_to": msg.ReplyTo,
@@ -55,9 +83,41 @@ func (a *WebhookAdapter) Send(ctx context.Context, msg agent.OutboundMessage) er
 	if err != nil {
 		return err
 	}
+	retries := a.cfg.SendRateLimit.MaxRetries
+	if retries == 0 {
+		retries = defaultSendRetries
+	}
+	retries = max(retries, 0)
+	backoff := time.Duration(a.cfg.SendRateLimit.RetryBackoffMs) * time.Millisecond
+	if backoff <= 0 {
+		backoff = defaultSendRetryBackoff
+	}
+	attempts := 0
+	for {
+		attempts++
+		err = a.post(ctx, endpoint, data)
+		var limited *RateLimitError
+		if err == nil || errors.As(err, &limited) {
+			return err
+		}
+		if isWebhookClientError(err) || attempts > retries || ctx.Err() != nil {
+			break
+		}
+		if sleepErr := a.sleep(ctx, backoff); sleepErr != nil {
+			break
+		}
+		backoff *= 2
+	}
+	if ctx.Err() == nil {
+		a.DeadLetter(msg, attempts, err)
+	}
+	return err
+}
+
+func (a *WebhookAdapter) post(ctx context.Context, endpoint string, data []byte) error {
 	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
 	if err != nil {
-		return err
+		return &webhookClientError{err: err}
 	}
 	request.Header.Set("Content-Type", "application/json")
 	if token := strings.TrimSpace(a.cfg.AuthToken); token != "" {
@@ -73,8 +133,67 @@ func (a *WebhookAdapter) Send(ctx context.Context, msg agent.OutboundMessage) er
 		return err
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode >= 300 {
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode >= 500 {
 		return fmt.Errorf("channel %q webhook returned %d", a.id, resp.StatusCode)
 	}
+	if resp.StatusCode >= 300 {
+		return rateLimitedResponse(resp, &webhookClientError{err: fmt.Errorf("channel %q webhook returned %d", a.id, resp.StatusCode)})
+	}
 	return nil
 }
+
+// webhookClientError marks a failure that retrying cannot fix: a bad request
+// or a 3xx or 4xx reply. Anything else, a 5xx or a transport error, is
+// retried.
+type webhookClientError struct {
+	err error
+}
+
+func (e *webhookClientError) Error() string { return e.err.Error() }
+
+func (e *webhookClientError) Unwrap() error { return e.err }
+
+func isWebhookClientError(err error) bool {
+	var clientErr *webhookClientError
+	return errors.As(err, &clientErr)
+}
+
+// DeadLetter records a message that could not be delivered after attempts
+// tries.
+func (a *WebhookAdapter) DeadLetter(msg agent.OutboundMessage, attempts int, sendErr error) {
+	traceID, _ := msg.Metadata["trace_id"].(string)
+	a.log.Printf("event=webhook_dead_letter channel=%s chat_id=%s trace_id=%s attempts=%d err=%v", a.id, msg.ChatID, traceID, attempts, sendErr)
+	path := metadataString(a.cfg, "dead_letter_path")
+	if path == "" {
+		return
+	}
+	line, err := json.Marshal(webhookDeadLetter{
+		FailedAt: time.Now().UTC(),
+		Channel:  a.id,
+		ChatID:   msg.ChatID,
+		Content:  msg.Content,
+		Metadata: msg.Metadata,
+		Attempts: attempts,
+		Error:    sendErr.Error(),
+	})
+	if err != nil {
+		a.log.Printf("channel %s dead letter encode failed: %v", a.id, err)
+		return
+	}
+	a.deadLetterMu.Lock()
+	defer a.deadLetterMu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		a.log.Printf("channel %s dead letter write failed: %v", a.id, err)
+		return
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		a.log.Printf("channel %s dead letter write failed: %v", a.id, err)
+		return
+	}
+	defer file.Close()
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		a.log.Printf("channel %s dead letter write failed: %v", a.id, err)
+	}
+}
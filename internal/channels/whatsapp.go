@@ -97,7 +97,7 @@ func (a *WhatsAppAdapter) Send(ctx context.Context, msg agent.OutboundMessage) e
 	defer resp.Body.Close()
 	if resp.StatusCode >= 300 {
 		body, _ := io.ReadAll(io.LimitReader(resp.Body, 2048))
-		return fmt.Errorf("whatsapp send failed status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(body)))
+		return rateLimitedResponse(resp, fmt.Errorf("whatsapp send failed status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(body))))
 	}
 	return nil
 }
@@ -14,6 +14,8 @@ import (
 )
 
 type Config struct {
+	// Version is the config schema version; see CurrentVersion.
+	Version   int             `json:"version"`
 	Agents    AgentsConfig    `json:"agents"`
 	Providers ProvidersConfig `json:"providers"`
 	Channels  ChannelsConfig  `json:"channels"`
@@ -28,7 +30,25 @@ type Config struct {
 }
 
 type AgentsConfig struct {
-	Defaults AgentDefaults `json:"defaults"`
+	Defaults       AgentDefaults        `json:"defaults"`
+	PostProcessors PostProcessorsConfig `json:"postProcessors"`
+}
+
+// PostProcessorsConfig lists deterministic transforms applied to the final
+// assistant response. A channel entry replaces the default pipeline.
+type PostProcessorsConfig struct {
+	Default  []PostProcessorConfig            `json:"default,omitempty"`
+	Channels map[string][]PostProcessorConfig `json:"channels,omitempty"`
+}
+
+// PostProcessorConfig is one pipeline step. Type is append, prepend,
+// rewrite_links (Template with a {url} placeholder), or max_length.
+type PostProcessorConfig struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	Template string `json:"template,omitempty"`
+	MaxChars int    `json:"maxChars,omitempty"`
+	Suffix   string `json:"suffix,omitempty"`
 }
 
 type AgentDefaults struct {
@@ -39,6 +59,29 @@ type AgentDefaults struct {
 	MaxToolIterations int     `json:"maxToolIterations"`
 	TurnTimeoutSec    int     `json:"turnTimeoutSec"`
 	ToolTimeoutSec    int     `json:"toolTimeoutSec"`
+	PromptCaching     bool    `json:"promptCaching"`
+	// FallbackToProviderDefaultModel retries with the active provider's
+	// catalog default model when the configured model is reported missing.
+	FallbackToProviderDefaultModel bool `json:"fallbackToProviderDefaultModel"`
+	// RetryMax is how many times a provider call that failed with a timeout,
+	// 429, or 5xx is retried; 0 disables retries. The wait starts at
+	// RetryBackoffMs and doubles per attempt.
+	RetryMax       int `json:"retryMax"`
+	RetryBackoffMs int `json:"retryBackoffMs"`
+	// Timezone is the IANA zone used for the prompt's current time and the
+	// {{.Date}} and {{.Time}} template variables; empty means the host zone.
+	Timezone string `json:"timezone,omitempty"`
+	// PromptVars are extra {{.Name}} variables expanded in the workspace
+	// bootstrap files. Built-in variables take precedence on a name clash.
+	PromptVars map[string]string `json:"promptVars,omitempty"`
+	// MaxResponseChars caps one outbound chat message; 0 means no cap. What
+	// happens to a longer reply is set by ResponseOverflow. Replies returned
+	// to the CLI are never shortened.
+	MaxResponseChars int `json:"maxResponseChars,omitempty"`
+	// ResponseOverflow is "split" (the default) to send an over-long reply as
+	// several messages broken on sentence boundaries, or "truncate" to cut it
+	// with a "[+N chars omitted]" marker.
+	ResponseOverflow string `json:"responseOverflow,omitempty"`
 }
 
 type ProvidersConfig struct {
@@ -50,25 +93,116 @@ type ProvidersConfig struct {
 	Gemini     ProviderConfig            `json:"gemini"`
 	Ollama     ProviderConfig            `json:"ollama"`
 	LMStudio   ProviderConfig            `json:"lmstudio"`
+	Mock       MockProviderConfig        `json:"mock,omitempty"`
+}
+
+// MockProviderConfig configures the offline mock provider. Each turn replays
+// Script one step per provider call; once the script is exhausted the
+// provider echoes the last user message, or returns Reply when set. Reply is
+// a Go template over .Message, .Model and .Step. ScriptFile names a JSON
+// array of steps that replaces Script, for scripts kept next to a CI job.
+type MockProviderConfig struct {
+	Model      string           `json:"model,omitempty"`
+	Reply      string           `json:"reply,omitempty"`
+	Script     []MockScriptStep `json:"script,omitempty"`
+	ScriptFile string           `json:"scriptFile,omitempty"`
+}
+
+// Steps returns the script to replay: the steps in ScriptFile when set,
+// otherwise Script.
+func (c MockProviderConfig) Steps() ([]MockScriptStep, error) {
+	path := strings.TrimSpace(c.ScriptFile)
+	if path == "" {
+		return c.Script, nil
+	}
+	path = expandPath(path)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("providers.mock.scriptFile: %w", err)
+	}
+	var steps []MockScriptStep
+	if err := json.Unmarshal(data, &steps); err != nil {
+		return nil, fmt.Errorf("providers.mock.scriptFile %s: %w", path, err)
+	}
+	return steps, nil
+}
+
+type MockScriptStep struct {
+	Content   string         `json:"content,omitempty"`
+	ToolCalls []MockToolCall `json:"toolCalls,omitempty"`
+}
+
+type MockToolCall struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
 }
 
 type ProviderConfig struct {
 	APIKey  string `json:"apiKey"`
 	APIBase string `json:"apiBase,omitempty"`
 	Model   string `json:"model,omitempty"`
+	// Headers are sent with every request to the provider, after the built-in
+	// auth and version headers, so they can replace Authorization, x-api-key,
+	// or anthropic-version for a proxy or a pinned API version.
+	Headers map[string]string `json:"headers,omitempty"`
+	// Transport overrides the catalog wire format. Set it to
+	// "openai_responses" to send an OpenAI-compatible provider through the
+	// Responses API instead of chat completions.
+	Transport string `json:"transport,omitempty"`
+	// ReasoningEffort (minimal|low|medium|high) is sent to reasoning models
+	// on the openai_responses transport.
+	ReasoningEffort string `json:"reasoningEffort,omitempty"`
+	// Streaming set to false makes streamed turns use one buffered request
+	// per reply, for endpoints that reject stream requests.
+	Streaming *bool `json:"streaming,omitempty"`
+}
+
+// StreamingEnabled reports whether streamed turns may call the provider's
+// streaming API. It is on unless Streaming is set to false.
+func (p ProviderConfig) StreamingEnabled() bool {
+	return p.Streaming == nil || *p.Streaming
 }
 
 type ChannelsConfig struct {
 	Telegram  TelegramConfig                  `json:"telegram"`
+	CLI       CLIChannelConfig                `json:"cli"`
 	Registry  map[string]GenericChannelConfig `json:"registry,omitempty"`
 	Plugins   map[string]PluginChannelConfig  `json:"plugins,omitempty"`
 	Scaffolds map[string]GenericChannelConfig `json:"scaffolds,omitempty"`
 }
 
 type TelegramConfig struct {
-	Enabled   bool     `json:"enabled"`
-	Token     string   `json:"token"`
-	AllowFrom []string `json:"allowFrom"`
+	Enabled       bool                       `json:"enabled"`
+	Token         string                     `json:"token"`
+	AllowFrom     []string                   `json:"allowFrom"`
+	SendRateLimit ChannelSendRateLimitConfig `json:"sendRateLimit"`
+	// SuppressSubagentNotifications keeps async subagent completion and
+	// progress messages out of Telegram chats; runs are still recorded.
+	SuppressSubagentNotifications bool `json:"suppressSubagentNotifications,omitempty"`
+}
+
+// CLIChannelConfig holds settings for the interactive CLI, which is always
+// available and has no adapter entry of its own.
+type CLIChannelConfig struct {
+	// SuppressSubagentNotifications stops async subagent completion and
+	// progress notices from printing in the REPL; runs are still recorded.
+	SuppressSubagentNotifications bool `json:"suppressSubagentNotifications,omitempty"`
+}
+
+// ChannelSendRateLimitConfig paces outbound sends on one channel. A send the
+// channel rejects as rate limited is retried after the channel's retry hint,
+// or with doubling backoff when it gives none.
+type ChannelSendRateLimitConfig struct {
+	// PerSecond is the sustained send rate; zero disables pacing.
+	PerSecond float64 `json:"perSecond,omitempty"`
+	// Burst is how many sends may go out back to back; zero means one.
+	Burst int `json:"burst,omitempty"`
+	// MaxRetries bounds retries of a rate-limited send. Zero uses the
+	// default of 3 and a negative value turns retries off.
+	MaxRetries int `json:"maxRetries,omitempty"`
+	// RetryBackoffMs is the first retry delay when the channel gives no
+	// hint. Zero uses 1000.
+	RetryBackoffMs int `json:"retryBackoffMs,omitempty"`
 }
 
 type GenericChannelConfig struct {
@@ -81,24 +215,38 @@ type GenericChannelConfig struct {
 	AuthToken string            `json:"authToken,omitempty"`
 	Headers   map[string]string `json:"headers,omitempty"`
 	Metadata  map[string]string `json:"metadata,omitempty"`
+	// SuppressSubagentNotifications keeps async subagent completion and
+	// progress messages off this channel; runs are still recorded.
+	SuppressSubagentNotifications bool                       `json:"suppressSubagentNotifications,omitempty"`
+	SendRateLimit                 ChannelSendRateLimitConfig `json:"sendRateLimit"`
 }
 
 type PluginChannelConfig struct {
-	Enabled   bool              `json:"enabled"`
-	Endpoint  string            `json:"endpoint,omitempty"`
-	AuthToken string            `json:"authToken,omitempty"`
-	Headers   map[string]string `json:"headers,omitempty"`
-	Metadata  map[string]string `json:"metadata,omitempty"`
+	Enabled                       bool              `json:"enabled"`
+	Endpoint                      string            `json:"endpoint,omitempty"`
+	AuthToken                     string            `json:"authToken,omitempty"`
+	Headers                       map[string]string `json:"headers,omitempty"`
+	Metadata                      map[string]string `json:"metadata,omitempty"`
+	SuppressSubagentNotifications bool              `json:"suppressSubagentNotifications,omitempty"`
 }
 
 type ToolsConfig struct {
 	Web        WebToolsConfig        `json:"web"`
 	Exec       ExecToolsConfig       `json:"exec"`
 	Filesystem FilesystemToolsConfig `json:"fs"`
+	Message    MessageToolsConfig    `json:"message"`
+	// Timeouts overrides agents.defaults.toolTimeoutSec per tool name, in seconds.
+	Timeouts map[string]int `json:"timeouts,omitempty"`
+	// DailyLimits caps how many times a tool may run per UTC day. Zero or a
+	// missing entry means unlimited.
+	DailyLimits map[string]int `json:"dailyLimits,omitempty"`
 }
 
 type ExecToolsConfig struct {
-	Enabled         bool     `json:"enabled"`
+	Enabled bool `json:"enabled"`
+	// AllowedCommands and BlockedCommands hold command names ("rm"), globs
+	// over the whole command line ("git push*"), or regular expressions
+	// starting with "^". Blocked entries win over allowed ones.
 	AllowedCommands []string `json:"allowedCommands,omitempty"`
 	BlockedCommands []string `json:"blockedCommands,omitempty"`
 }
@@ -108,6 +256,13 @@ type FilesystemToolsConfig struct {
 	SubagentWriteEnabled bool `json:"subagentWriteEnabled"`
 }
 
+// MessageToolsConfig limits where the message tool may send. AllowTargets
+// holds "channel:chat_id" entries, with "channel:*" for any chat on a
+// channel; the current conversation is always allowed.
+type MessageToolsConfig struct {
+	AllowTargets []string `json:"allowTargets,omitempty"`
+}
+
 type FeaturesConfig struct {
 	Streaming      bool `json:"streaming"`
 	ChannelsWave1  bool `json:"channelsWave1"`
@@ -118,11 +273,38 @@ type FeaturesConfig struct {
 
 type WebToolsConfig struct {
 	Search WebSearchConfig `json:"search"`
+	Fetch  WebFetchConfig  `json:"fetch"`
+}
+
+// WebFetchConfig limits which hosts web_fetch may reach. DenyHosts wins over
+// AllowHosts; an empty AllowHosts allows every host that is not denied. A
+// host entry also matches its subdomains.
+type WebFetchConfig struct {
+	AllowHosts []string `json:"allowHosts,omitempty"`
+	DenyHosts  []string `json:"denyHosts,omitempty"`
+	// BlockPrivateIPs refuses hosts that resolve to loopback, private, or
+	// link-local addresses.
+	BlockPrivateIPs bool `json:"blockPrivateIPs"`
 }
 
 type WebSearchConfig struct {
+	Provider   string `json:"provider,omitempty"`
 	APIKey     string `json:"apiKey"`
+	BaseURL    string `json:"baseUrl,omitempty"`
 	MaxResults int    `json:"maxResults"`
+	// DailyLimit caps web_search calls per UTC day; zero means unlimited.
+	DailyLimit int `json:"dailyLimit,omitempty"`
+	// Providers holds per-backend settings keyed by provider name (brave,
+	// serpapi, searxng, tavily). The selected provider's entry wins over the
+	// top-level apiKey and baseUrl.
+	Providers map[string]WebSearchProviderConfig `json:"providers,omitempty"`
+}
+
+// WebSearchProviderConfig is the credentials and endpoint of one web_search
+// backend.
+type WebSearchProviderConfig struct {
+	APIKey  string `json:"apiKey,omitempty"`
+	BaseURL string `json:"baseUrl,omitempty"`
 }
 
 type GatewayConfig struct {
@@ -144,11 +326,75 @@ type RuntimeConfig struct {
 	MailboxSize          int                      `json:"mailboxSize"`
 	ActorIdleTTL         DurationValue            `json:"actorIdleTtl"`
 	HeartbeatIntervalSec int                      `json:"heartbeatIntervalSec"`
+	MaxConcurrentTurns   int                      `json:"maxConcurrentTurns"`
 	Subagents            SubagentRuntimeConfig    `json:"subagents"`
 	Federation           FederationRuntimeConfig  `json:"federation"`
 	Plugins              PluginsRuntimeConfig     `json:"plugins"`
 	MetricsHTTP          MetricsHTTPRuntimeConfig `json:"metricsHttp"`
+	AgentAPI             AgentAPIRuntimeConfig    `json:"agentApi"`
 	TokenSafety          TokenSafetyRuntimeConfig `json:"tokenSafety"`
+	SessionTitles        SessionTitlesConfig      `json:"sessionTitles"`
+	// ArchiveOnIdle writes each session's transcript to ArchiveDir when its
+	// actor is evicted after ActorIdleTTL or stopped at shutdown.
+	ArchiveOnIdle bool   `json:"archiveOnIdle"`
+	ArchiveDir    string `json:"archiveDir,omitempty"`
+	ArchiveFormat string `json:"archiveFormat,omitempty"`
+	// GlobalAllowFrom and GlobalDenyFrom hold "channel:sender" entries checked
+	// for every inbound message before it reaches the engine. A sender of "*"
+	// matches everyone on that channel. Deny entries always win.
+	GlobalAllowFrom []string         `json:"globalAllowFrom,omitempty"`
+	GlobalDenyFrom  []string         `json:"globalDenyFrom,omitempty"`
+	QuietHours      QuietHoursConfig `json:"quietHours"`
+	// ShutdownGraceSec is how long the gateway waits at shutdown for running
+	// turns and subagent runs before cancelling them. Zero cancels at once.
+	ShutdownGraceSec int `json:"shutdownGraceSec"`
+	// HeartbeatProfiles are named heartbeats beside the default one, which
+	// runs HEARTBEAT.md every HeartbeatIntervalSec. A "default" entry
+	// replaces it.
+	HeartbeatProfiles map[string]HeartbeatProfileConfig `json:"heartbeatProfiles,omitempty"`
+	// HistorySummary folds the oldest turns of a long session into a stored
+	// summary so prompts stay bounded.
+	HistorySummary HistorySummaryConfig `json:"historySummary"`
+}
+
+// HeartbeatProfileConfig is one named heartbeat. File is a markdown file of
+// instructions relative to the workspace; IntervalSec is how often it runs,
+// and 0 runs it only when triggered.
+type HeartbeatProfileConfig struct {
+	File        string `json:"file"`
+	IntervalSec int    `json:"intervalSec"`
+}
+
+// QuietHoursConfig holds back proactive outbound messages (cron, subagent and
+// federation notices) between Start and End, given as HH:MM in Timezone (an
+// IANA name; empty means the host's local time). The window may wrap past
+// midnight. Held messages are delivered once it ends; direct replies to user
+// messages are never held.
+type QuietHoursConfig struct {
+	Enabled  bool   `json:"enabled"`
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// SessionTitlesConfig controls the short human-readable title stored in
+// session metadata after the first exchange. UseProvider asks the active
+// model for the title; otherwise the first user message is truncated.
+type SessionTitlesConfig struct {
+	Enabled     bool `json:"enabled"`
+	UseProvider bool `json:"useProvider"`
+	MaxChars    int  `json:"maxChars"`
+}
+
+// HistorySummaryConfig controls conversation summaries. When the turns after
+// a session's last summary pass MaxTurns turns or MaxChars characters, all
+// but the last KeepRecent are summarized by the active provider, and the
+// summary stands in for them in later prompts.
+type HistorySummaryConfig struct {
+	Enabled    bool `json:"enabled"`
+	MaxTurns   int  `json:"maxTurns"`
+	MaxChars   int  `json:"maxChars"`
+	KeepRecent int  `json:"keepRecent"`
 }
 
 type PluginsRuntimeConfig struct {
@@ -164,6 +410,20 @@ type MetricsHTTPRuntimeConfig struct {
 	ListenAddr    string `json:"listenAddr"`
 	AuthToken     string `json:"authToken,omitempty"`
 	LocalhostOnly bool   `json:"localhostOnly"`
+	// ManageToken is the bearer token for /api/manage. Without it the manage
+	// routes are read-only, and are not served at all on a listener that
+	// accepts remote clients.
+	ManageToken string `json:"manageToken,omitempty"`
+	// ManageMaxConcurrent caps in-flight /api/manage requests; extra requests
+	// get 429. Zero removes the cap.
+	ManageMaxConcurrent int `json:"manageMaxConcurrent"`
+}
+
+type AgentAPIRuntimeConfig struct {
+	Enabled           bool   `json:"enabled"`
+	ListenAddr        string `json:"listenAddr"`
+	AuthToken         string `json:"authToken,omitempty"`
+	RequestTimeoutSec int    `json:"requestTimeoutSec"`
 }
 
 type SubagentRuntimeConfig struct {
@@ -177,6 +437,29 @@ type SubagentRuntimeConfig struct {
 	AllowWrites        bool `json:"allowWrites"`
 	NotifyOnComplete   bool `json:"notifyOnComplete"`
 	ReinjectCompletion bool `json:"reinjectCompletion"`
+	StreamProgress     bool `json:"streamProgress"`
+	// ProgressIntervalSec throttles progress updates per run.
+	ProgressIntervalSec int `json:"progressIntervalSec"`
+	// ArtifactRetentionDays controls how long run artifact directories are
+	// kept. Zero disables artifact writes; a negative value, the default,
+	// keeps them forever.
+	ArtifactRetentionDays int `json:"artifactRetentionDays"`
+	// DefaultContextMode is used when spawn omits context_mode: minimal,
+	// session, or session_memory. Empty means minimal.
+	DefaultContextMode string `json:"defaultContextMode,omitempty"`
+	// ResumeOnStartup re-queues runs a restart interrupted while they still
+	// have attempts left. When false they are marked failed instead.
+	ResumeOnStartup bool `json:"resumeOnStartup"`
+	// LoopThreshold refuses a spawn once a session already holds this many
+	// runs at the same depth with an identical context packet, spawned in the
+	// last LoopWindowSec seconds. Zero disables the guard.
+	LoopThreshold int `json:"loopThreshold"`
+	LoopWindowSec int `json:"loopWindowSec"`
+	// InlineAttachmentMaxBytes inlines the content of spawn attachments up to
+	// this size into the context packet, so the subagent needs no file tool
+	// to read them. Larger or binary files are passed by path. Zero disables
+	// inlining.
+	InlineAttachmentMaxBytes int `json:"inlineAttachmentMaxBytes"`
 }
 
 type TokenSafetyRuntimeConfig struct {
@@ -191,6 +474,14 @@ type TokenSafetyRuntimeConfig struct {
 	EstimateOnMissingUsage      bool     `json:"estimateOnMissingUsage"`
 	EstimateCharsPerToken       int      `json:"estimateCharsPerToken"`
 	TrustedWriters              []string `json:"trustedWriters"`
+	// Pricing maps model names to USD per million tokens, overriding the
+	// built-in table used for cost estimates.
+	Pricing map[string]TokenPriceConfig `json:"pricing,omitempty"`
+}
+
+type TokenPriceConfig struct {
+	InputPerMillion  float64 `json:"inputPerMillion"`
+	OutputPerMillion float64 `json:"outputPerMillion"`
 }
 
 type FederationPeerConfig struct {
@@ -219,19 +510,44 @@ type FederationRuntimeConfig struct {
 }
 
 type MemoryConfig struct {
-	Enabled            bool                 `json:"enabled"`
-	IndexPath          string               `json:"indexPath"`
-	TopK               int                  `json:"topK"`
-	RecencyDays        int                  `json:"recencyDays"`
-	EmbeddingsProvider string               `json:"embeddingsProvider"`
-	EmbeddingsModel    string               `json:"embeddingsModel"`
-	Semantic           MemorySemanticConfig `json:"semantic"`
+	Enabled            bool                    `json:"enabled"`
+	IndexPath          string                  `json:"indexPath"`
+	TopK               int                     `json:"topK"`
+	RecencyDays        int                     `json:"recencyDays"`
+	EmbeddingsProvider string                  `json:"embeddingsProvider"`
+	EmbeddingsModel    string                  `json:"embeddingsModel"`
+	Embeddings         MemoryEmbeddingsConfig  `json:"embeddings"`
+	Semantic           MemorySemanticConfig    `json:"semantic"`
+	DailyRollup        MemoryDailyRollupConfig `json:"dailyRollup"`
+	// MaxOpenConns caps the pooled SQLite connections used by the memory index.
+	MaxOpenConns int `json:"maxOpenConns"`
+	// InjectRecentDays adds daily log entries from the last N days to the
+	// system prompt; 0 turns the section off. InjectMaxChars caps its size.
+	InjectRecentDays int `json:"injectRecentDays"`
+	InjectMaxChars   int `json:"injectMaxChars"`
+}
+
+// MemoryDailyRollupConfig controls the scheduled digest of completed daily
+// logs into MEMORY.md. Time is HH:MM in UTC, matching daily log days.
+type MemoryDailyRollupConfig struct {
+	Enabled       bool   `json:"enabled"`
+	Time          string `json:"time"`
+	MaxDaysPerRun int    `json:"maxDaysPerRun"`
+}
+
+type MemoryEmbeddingsConfig struct {
+	BatchSize   int `json:"batchSize"`
+	Concurrency int `json:"concurrency"`
+	TimeoutSec  int `json:"timeoutSec"`
 }
 
 type MemorySemanticConfig struct {
 	Enabled        bool `json:"enabled"`
 	TopKCandidates int  `json:"topKCandidates"`
 	RerankTopK     int  `json:"rerankTopK"`
+	// Reranker picks how candidates are rescored: "cosine" (embedding
+	// similarity) or "none" (lexical order).
+	Reranker string `json:"reranker"`
 }
 
 type SkillsConfig struct {
@@ -269,6 +585,15 @@ const (
 	ProviderGemini     = "gemini"
 	ProviderOllama     = "ollama"
 	ProviderLMStudio   = "lmstudio"
+	// ProviderMock is an offline provider for tests and demos; it never
+	// touches the network and needs no API key.
+	ProviderMock = "mock"
+)
+
+const (
+	TransportAnthropic       = "anthropic"
+	TransportOpenAICompat    = "openai_compat"
+	TransportOpenAIResponses = "openai_responses"
 )
 
 var supportedProviders = []string{
@@ -278,6 +603,7 @@ var supportedProviders = []string{
 	ProviderGemini,
 	ProviderOllama,
 	ProviderLMStudio,
+	ProviderMock,
 }
 
 func init() {
@@ -337,6 +663,7 @@ func Default() Config {
 	home := HomeDir()
 	workspace := filepath.Join(home, "workspace")
 	return Config{
+		Version: CurrentVersion,
 		Agents: AgentsConfig{
 			Defaults: AgentDefaults{
 				Workspace:         workspace,
@@ -346,6 +673,9 @@ func Default() Config {
 				MaxToolIterations: 20,
 				TurnTimeoutSec:    120,
 				ToolTimeoutSec:    60,
+				PromptCaching:     true,
+				RetryMax:          2,
+				RetryBackoffMs:    500,
 			},
 		},
 		Providers: ProvidersConfig{},
@@ -361,6 +691,7 @@ func Default() Config {
 		Tools: ToolsConfig{
 			Web: WebToolsConfig{
 				Search: WebSearchConfig{
+					Provider:   "brave",
 					MaxResults: 5,
 				},
 			},
@@ -394,17 +725,25 @@ func Default() Config {
 			MailboxSize:          64,
 			ActorIdleTTL:         DurationValue{Duration: 15 * time.Minute},
 			HeartbeatIntervalSec: 1800,
+			ShutdownGraceSec:     30,
 			Subagents: SubagentRuntimeConfig{
-				Enabled:            true,
-				MaxConcurrent:      4,
-				MaxQueue:           64,
-				DefaultTimeoutSec:  300,
-				MaxAttempts:        2,
-				RetryBackoffSec:    8,
-				MaxDepth:           1,
-				AllowWrites:        false,
-				NotifyOnComplete:   true,
-				ReinjectCompletion: false,
+				Enabled:               true,
+				MaxConcurrent:         4,
+				MaxQueue:              64,
+				DefaultTimeoutSec:     300,
+				MaxAttempts:           2,
+				RetryBackoffSec:       8,
+				MaxDepth:              1,
+				AllowWrites:           false,
+				NotifyOnComplete:      true,
+				ReinjectCompletion:    false,
+				StreamProgress:        false,
+				ProgressIntervalSec:   15,
+				ArtifactRetentionDays: -1,
+				DefaultContextMode:    "minimal",
+				ResumeOnStartup:       true,
+				LoopThreshold:         3,
+				LoopWindowSec:         600,
 			},
 			Federation: FederationRuntimeConfig{
 				Enabled:           false,
@@ -425,10 +764,16 @@ func Default() Config {
 				MaxProcesses:      8,
 			},
 			MetricsHTTP: MetricsHTTPRuntimeConfig{
-				Enabled:       false,
-				ListenAddr:    "127.0.0.1:19090",
-				AuthToken:     "",
-				LocalhostOnly: true,
+				Enabled:             false,
+				ListenAddr:          "127.0.0.1:19090",
+				AuthToken:           "",
+				LocalhostOnly:       true,
+				ManageMaxConcurrent: 2,
+			},
+			AgentAPI: AgentAPIRuntimeConfig{
+				Enabled:           false,
+				ListenAddr:        "127.0.0.1:19091",
+				RequestTimeoutSec: 300,
 			},
 			TokenSafety: TokenSafetyRuntimeConfig{
 				Enabled:                     true,
@@ -443,6 +788,24 @@ func Default() Config {
 				EstimateCharsPerToken:       4,
 				TrustedWriters:              []string{"cli:user"},
 			},
+			SessionTitles: SessionTitlesConfig{
+				Enabled:     true,
+				UseProvider: false,
+				MaxChars:    60,
+			},
+			HistorySummary: HistorySummaryConfig{
+				Enabled:    false,
+				MaxTurns:   40,
+				MaxChars:   40000,
+				KeepRecent: 10,
+			},
+			ArchiveOnIdle: false,
+			ArchiveFormat: "json",
+			QuietHours: QuietHoursConfig{
+				Enabled: false,
+				Start:   "22:00",
+				End:     "07:00",
+			},
 		},
 		Memory: MemoryConfig{
 			Enabled:            true,
@@ -451,10 +814,24 @@ func Default() Config {
 			RecencyDays:        30,
 			EmbeddingsProvider: "none",
 			EmbeddingsModel:    "",
+			Embeddings: MemoryEmbeddingsConfig{
+				BatchSize:   32,
+				Concurrency: 2,
+				TimeoutSec:  30,
+			},
+			DailyRollup: MemoryDailyRollupConfig{
+				Enabled:       false,
+				Time:          "00:30",
+				MaxDaysPerRun: 7,
+			},
+			MaxOpenConns:     4,
+			InjectRecentDays: 2,
+			InjectMaxChars:   1200,
 			Semantic: MemorySemanticConfig{
 				Enabled:        false,
 				TopKCandidates: 24,
 				RerankTopK:     8,
+				Reranker:       "cosine",
 			},
 		},
 		Skills: SkillsConfig{
@@ -476,7 +853,35 @@ func Default() Config {
 	}
 }
 
+// HomeDir resolves the squidbot data home. SQUIDBOT_HOME wins, an install
+// with a ~/.squidbot/config.json is kept for compatibility, and
+// $XDG_DATA_HOME/squidbot (default ~/.local/share/squidbot) is used otherwise.
 func HomeDir() string {
+	if override := strings.TrimSpace(os.Getenv("SQUIDBOT_HOME")); override != "" {
+		return expandPath(override)
+	}
+	if useLegacyHome() {
+		return legacyHomeDir()
+	}
+	return filepath.Join(xdgDir("XDG_DATA_HOME", ".local", "share"), "squidbot")
+}
+
+// ConfigPath resolves the config file the same way HomeDir resolves the data
+// home, so both follow one choice between ~/.squidbot and the XDG locations.
+func ConfigPath() string {
+	if explicit := strings.TrimSpace(os.Getenv("SQUIDBOT_CONFIG")); explicit != "" {
+		return expandPath(explicit)
+	}
+	if override := strings.TrimSpace(os.Getenv("SQUIDBOT_HOME")); override != "" {
+		return filepath.Join(expandPath(override), "config.json")
+	}
+	if useLegacyHome() {
+		return filepath.Join(legacyHomeDir(), "config.json")
+	}
+	return filepath.Join(xdgDir("XDG_CONFIG_HOME", ".config"), "squidbot", "config.json")
+}
+
+func legacyHomeDir() string {
 	h, err := os.UserHomeDir()
 	if err != nil {
 		return ".squidbot"
@@ -484,8 +889,46 @@ func HomeDir() string {
 	return filepath.Join(h, ".squidbot")
 }
 
-func ConfigPath() string {
-	return filepath.Join(HomeDir(), "config.json")
+// useLegacyHome reports whether the install lives in ~/.squidbot. Only its
+// config file decides, so a fresh XDG install that later gains a stray
+// ~/.squidbot directory keeps its paths. A ~/.squidbot that cannot be
+// resolved, such as a symlink to an unmounted volume, is kept as well, since
+// the config behind it cannot be ruled out.
+func useLegacyHome() bool {
+	legacy := legacyHomeDir()
+	if pathPresent(filepath.Join(legacy, "config.json")) {
+		return true
+	}
+	info, err := os.Lstat(legacy)
+	if err != nil {
+		return !os.IsNotExist(err)
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		return false
+	}
+	_, err = os.Stat(legacy)
+	return err != nil
+}
+
+// xdgDir returns the directory named by the XDG variable env, or the spec's
+// fallback under the user's home when it is unset.
+func xdgDir(env string, fallback ...string) string {
+	if dir := strings.TrimSpace(os.Getenv(env)); dir != "" {
+		return expandPath(dir)
+	}
+	h, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(fallback...)
+	}
+	return filepath.Join(append([]string{h}, fallback...)...)
+}
+
+// pathPresent reports whether anything is at path. A symlink counts even when
+// its target is missing, and so does an entry that cannot be inspected, so an
+// existing install is never moved to the XDG paths.
+func pathPresent(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil || !os.IsNotExist(err)
 }
 
 func DataRoot() string {
@@ -499,6 +942,15 @@ func WorkspacePath(cfg Config) string {
 	return expandPath(cfg.Agents.Defaults.Workspace)
 }
 
+// ArchivePath is where session transcripts are archived, defaulting to
+// <data>/archive.
+func ArchivePath(cfg Config) string {
+	if strings.TrimSpace(cfg.Runtime.ArchiveDir) == "" {
+		return filepath.Join(DataRoot(), "archive")
+	}
+	return expandPath(cfg.Runtime.ArchiveDir)
+}
+
 func expandPath(path string) string {
 	if strings.HasPrefix(path, "~/") {
 		h, err := os.UserHomeDir()
@@ -509,36 +961,90 @@ func expandPath(path string) string {
 	return path
 }
 
+// envMode selects how load treats SQUIDBOT_* environment overrides.
+type envMode int
+
+const (
+	envApply envMode = iota
+	envTrack
+	envSkip
+)
+
 func Load(path string) (Config, error) {
+	cfg, _, err := load(path, envApply)
+	return cfg, err
+}
+
+// LoadWithEnvSources is Load that also reports the settings, as dotted JSON
+// paths, whose values were set by SQUIDBOT_* environment variables.
+func LoadWithEnvSources(path string) (Config, []string, error) {
+	return load(path, envTrack)
+}
+
+// LoadFile is Load without the SQUIDBOT_* environment overrides. Commands
+// that edit the config and Save it back use it so environment-only secrets
+// are never written into config.json.
+func LoadFile(path string) (Config, error) {
+	cfg, _, err := load(path, envSkip)
+	return cfg, err
+}
+
+func load(path string, env envMode) (Config, []string, error) {
 	cfg := Default()
 	if path == "" {
 		path = ConfigPath()
 	}
 	path = expandPath(path)
 	bytes, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return cfg, nil, err
+	}
+	baseMissing := err != nil
+	dropIns, err := readDropIns(DropInDir(path))
 	if err != nil {
-		if os.IsNotExist(err) {
-			normalizeDefaultChannels(&cfg)
-			applyEnvOverrides(&cfg)
-			normalizeSkillsConfig(&cfg)
-			return cfg, nil
+		return cfg, nil, err
+	}
+	if baseMissing && len(dropIns) == 0 {
+		normalizeDefaultChannels(&cfg)
+		envPaths, err := applyEnvMode(&cfg, env)
+		normalizeSkillsConfig(&cfg)
+		return cfg, envPaths, err
+	}
+	if len(dropIns) > 0 {
+		bytes, err = mergeDropIns(bytes, dropIns)
+		if err != nil {
+			return cfg, nil, err
 		}
-		return cfg, err
 	}
 	var raw map[string]any
 	_ = json.Unmarshal(bytes, &raw)
 	if err := json.Unmarshal(bytes, &cfg); err != nil {
-		return cfg, err
+		return cfg, nil, err
+	}
+	if _, err := migrateSchema(&cfg, raw); err != nil {
+		return cfg, nil, err
 	}
-	applyLegacyCompatibilityDefaults(&cfg, raw)
-	migrateLegacyProviders(&cfg)
-	migrateLegacyChannels(&cfg)
 	normalizeDefaultChannels(&cfg)
-	applyEnvOverrides(&cfg)
+	envPaths, err := applyEnvMode(&cfg, env)
 	normalizeSkillsConfig(&cfg)
-	return cfg, nil
+	return cfg, envPaths, err
+}
+
+// applyEnvMode applies the environment to cfg as env asks. Skipping it still
+// folds the legacy provider and channel fields into the registries, which
+// applyEnvOverrides does otherwise.
+func applyEnvMode(cfg *Config, env envMode) ([]string, error) {
+	if env == envSkip {
+		migrateLegacyProviders(cfg)
+		migrateLegacyChannels(cfg)
+		return nil, nil
+	}
+	return applyTrackedEnvOverrides(cfg, env == envTrack)
 }
 
+// Save writes cfg to the base config file. Values that came from config.d
+// drop-ins and were left unchanged are kept out of it, so secrets split into a
+// drop-in are not copied into config.json.
 func Save(path string, cfg Config) error {
 	if path == "" {
 		path = ConfigPath()
@@ -551,10 +1057,61 @@ func Save(path string, cfg Config) error {
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(path, data, 0o644)
+	dropIns, err := readDropIns(DropInDir(path))
+	if err != nil {
+		return err
+	}
+	if len(dropIns) > 0 {
+		base, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		data, err = stripDropIns(data, base, dropIns)
+		if err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, data, 0o600)
 }
 
-func applyEnvOverrides(cfg *Config) {
+// applyTrackedEnvOverrides applies the environment to cfg and, when track is
+// set, returns the sorted paths the environment sets. Diffing cfg alone would
+// miss variables that repeat a value cfg already holds, so a blank config is
+// probed as well.
+func applyTrackedEnvOverrides(cfg *Config, track bool) ([]string, error) {
+	if !track {
+		applyEnvOverrides(cfg)
+		return nil, nil
+	}
+	set := map[string]struct{}{}
+	var probe Config
+	for _, target := range []*Config{cfg, &probe} {
+		ensureEnvTargets(target)
+		before, err := flattenConfig(*target)
+		if err != nil {
+			return nil, err
+		}
+		applyEnvOverrides(target)
+		after, err := flattenConfig(*target)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range after {
+			if old, ok := before[key]; !ok || old != value {
+				set[key] = struct{}{}
+			}
+		}
+	}
+	paths := make([]string, 0, len(set))
+	for key := range set {
+		paths = append(paths, key)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// ensureEnvTargets allocates the maps environment overrides write into.
+func ensureEnvTargets(cfg *Config) {
 	if cfg.Providers.Registry == nil {
 		cfg.Providers.Registry = map[string]ProviderConfig{}
 	}
@@ -567,6 +1124,10 @@ func applyEnvOverrides(cfg *Config) {
 	if cfg.Skills.Policy.Channels == nil {
 		cfg.Skills.Policy.Channels = map[string]SkillsChannelPolicyConfig{}
 	}
+}
+
+func applyEnvOverrides(cfg *Config) {
+	ensureEnvTargets(cfg)
 	env := map[string]*string{
 		"SQUIDBOT_PROVIDER_ACTIVE":            &cfg.Providers.Active,
 		"SQUIDBOT_OPENROUTER_API_KEY":         &cfg.Providers.OpenRouter.APIKey,
@@ -577,6 +1138,7 @@ func applyEnvOverrides(cfg *Config) {
 		"SQUIDBOT_OPENAI_API_KEY":             &cfg.Providers.OpenAI.APIKey,
 		"SQUIDBOT_OPENAI_API_BASE":            &cfg.Providers.OpenAI.APIBase,
 		"SQUIDBOT_OPENAI_MODEL":               &cfg.Providers.OpenAI.Model,
+		"SQUIDBOT_OPENAI_TRANSPORT":           &cfg.Providers.OpenAI.Transport,
 		"SQUIDBOT_GEMINI_API_KEY":             &cfg.Providers.Gemini.APIKey,
 		"SQUIDBOT_GEMINI_API_BASE":            &cfg.Providers.Gemini.APIBase,
 		"SQUIDBOT_GEMINI_MODEL":               &cfg.Providers.Gemini.Model,
@@ -588,17 +1150,35 @@ func applyEnvOverrides(cfg *Config) {
 		"SQUIDBOT_LMSTUDIO_MODEL":             &cfg.Providers.LMStudio.Model,
 		"SQUIDBOT_TELEGRAM_TOKEN":             &cfg.Channels.Telegram.Token,
 		"SQUIDBOT_BRAVE_API_KEY":              &cfg.Tools.Web.Search.APIKey,
+		"SQUIDBOT_WEB_SEARCH_PROVIDER":        &cfg.Tools.Web.Search.Provider,
+		"SQUIDBOT_WEB_SEARCH_BASE_URL":        &cfg.Tools.Web.Search.BaseURL,
 		"SQUIDBOT_MEMORY_INDEX_PATH":          &cfg.Memory.IndexPath,
 		"SQUIDBOT_MEMORY_EMBEDDINGS_PROVIDER": &cfg.Memory.EmbeddingsProvider,
 		"SQUIDBOT_MEMORY_EMBEDDINGS_MODEL":    &cfg.Memory.EmbeddingsModel,
 		"SQUIDBOT_METRICS_HTTP_LISTEN_ADDR":   &cfg.Runtime.MetricsHTTP.ListenAddr,
 		"SQUIDBOT_METRICS_HTTP_AUTH_TOKEN":    &cfg.Runtime.MetricsHTTP.AuthToken,
+		"SQUIDBOT_MANAGE_TOKEN":               &cfg.Runtime.MetricsHTTP.ManageToken,
+		"SQUIDBOT_AGENT_API_LISTEN_ADDR":      &cfg.Runtime.AgentAPI.ListenAddr,
+		"SQUIDBOT_AGENT_API_AUTH_TOKEN":       &cfg.Runtime.AgentAPI.AuthToken,
 	}
 	for key, target := range env {
 		if value := strings.TrimSpace(os.Getenv(key)); value != "" {
 			*target = value
 		}
 	}
+	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_WEB_SEARCH_API_KEY")); value != "" {
+		cfg.Tools.Web.Search.APIKey = value
+	}
+	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_WEB_SEARCH_DAILY_LIMIT")); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed >= 0 {
+			cfg.Tools.Web.Search.DailyLimit = parsed
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_WEB_FETCH_BLOCK_PRIVATE_IPS")); value != "" {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			cfg.Tools.Web.Fetch.BlockPrivateIPs = parsed
+		}
+	}
 
 	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_TELEGRAM_ENABLED")); value != "" {
 		parsed, err := strconv.ParseBool(value)
@@ -652,6 +1232,16 @@ func applyEnvOverrides(cfg *Config) {
 			cfg.Tools.Filesystem.SubagentWriteEnabled = parsed
 		}
 	}
+	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_RUNTIME_MAX_CONCURRENT_TURNS")); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed >= 0 {
+			cfg.Runtime.MaxConcurrentTurns = parsed
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_SHUTDOWN_GRACE_SEC")); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed >= 0 {
+			cfg.Runtime.ShutdownGraceSec = parsed
+		}
+	}
 	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_RUNTIME_PLUGINS_ENABLED")); value != "" {
 		if parsed, err := strconv.ParseBool(value); err == nil {
 			cfg.Runtime.Plugins.Enabled = parsed
@@ -675,6 +1265,11 @@ func applyEnvOverrides(cfg *Config) {
 			cfg.Runtime.Plugins.MaxProcesses = parsed
 		}
 	}
+	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_AGENT_API_ENABLED")); value != "" {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			cfg.Runtime.AgentAPI.Enabled = parsed
+		}
+	}
 	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_METRICS_HTTP_ENABLED")); value != "" {
 		if parsed, err := strconv.ParseBool(value); err == nil {
 			cfg.Runtime.MetricsHTTP.Enabled = parsed
@@ -685,6 +1280,11 @@ func applyEnvOverrides(cfg *Config) {
 			cfg.Runtime.MetricsHTTP.LocalhostOnly = parsed
 		}
 	}
+	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_MANAGE_MAX_CONCURRENT")); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			cfg.Runtime.MetricsHTTP.ManageMaxConcurrent = parsed
+		}
+	}
 	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_MEMORY_ENABLED")); value != "" {
 		parsed, err := strconv.ParseBool(value)
 		if err == nil {
@@ -697,6 +1297,12 @@ func applyEnvOverrides(cfg *Config) {
 			cfg.Memory.TopK = parsed
 		}
 	}
+	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_MEMORY_INJECT_RECENT_DAYS")); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err == nil && parsed >= 0 {
+			cfg.Memory.InjectRecentDays = parsed
+		}
+	}
 	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_MEMORY_RECENCY_DAYS")); value != "" {
 		parsed, err := strconv.Atoi(value)
 		if err == nil && parsed > 0 {
@@ -718,6 +1324,32 @@ func applyEnvOverrides(cfg *Config) {
 			cfg.Memory.Semantic.RerankTopK = parsed
 		}
 	}
+	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_MEMORY_SEMANTIC_RERANKER")); value != "" {
+		cfg.Memory.Semantic.Reranker = strings.ToLower(value)
+	}
+	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_MEMORY_EMBEDDINGS_BATCH_SIZE")); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed > 0 {
+			cfg.Memory.Embeddings.BatchSize = parsed
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_MEMORY_EMBEDDINGS_CONCURRENCY")); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed > 0 {
+			cfg.Memory.Embeddings.Concurrency = parsed
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_MEMORY_EMBEDDINGS_TIMEOUT_SEC")); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed > 0 {
+			cfg.Memory.Embeddings.TimeoutSec = parsed
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_MEMORY_DAILY_ROLLUP_ENABLED")); value != "" {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			cfg.Memory.DailyRollup.Enabled = parsed
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_MEMORY_DAILY_ROLLUP_TIME")); value != "" {
+		cfg.Memory.DailyRollup.Time = value
+	}
 	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_SKILLS_PATHS")); value != "" {
 		paths := strings.Split(value, ",")
 		out := make([]string, 0, len(paths))
@@ -793,6 +1425,9 @@ func applyEnvOverrides(cfg *Config) {
 			cfg.Runtime.Subagents.DefaultTimeoutSec = parsed
 		}
 	}
+	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_SUBAGENTS_DEFAULT_CONTEXT_MODE")); value != "" {
+		cfg.Runtime.Subagents.DefaultContextMode = value
+	}
 	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_SUBAGENTS_MAX_ATTEMPTS")); value != "" {
 		parsed, err := strconv.Atoi(value)
 		if err == nil && parsed > 0 {
@@ -829,6 +1464,115 @@ func applyEnvOverrides(cfg *Config) {
 			cfg.Runtime.Subagents.ReinjectCompletion = parsed
 		}
 	}
+	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_SUBAGENTS_RESUME_ON_STARTUP")); value != "" {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			cfg.Runtime.Subagents.ResumeOnStartup = parsed
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_SUBAGENTS_LOOP_THRESHOLD")); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed >= 0 {
+			cfg.Runtime.Subagents.LoopThreshold = parsed
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_SUBAGENTS_LOOP_WINDOW_SEC")); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed >= 0 {
+			cfg.Runtime.Subagents.LoopWindowSec = parsed
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_SUBAGENTS_INLINE_ATTACHMENT_MAX_BYTES")); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed >= 0 {
+			cfg.Runtime.Subagents.InlineAttachmentMaxBytes = parsed
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_SUBAGENTS_ARTIFACT_RETENTION_DAYS")); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			cfg.Runtime.Subagents.ArtifactRetentionDays = parsed
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_FALLBACK_TO_PROVIDER_DEFAULT_MODEL")); value != "" {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			cfg.Agents.Defaults.FallbackToProviderDefaultModel = parsed
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_AGENT_TIMEZONE")); value != "" {
+		cfg.Agents.Defaults.Timezone = value
+	}
+	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_AGENT_MAX_RESPONSE_CHARS")); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			cfg.Agents.Defaults.MaxResponseChars = parsed
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_AGENT_RESPONSE_OVERFLOW")); value != "" {
+		cfg.Agents.Defaults.ResponseOverflow = value
+	}
+	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_PROVIDER_RETRY_MAX")); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			cfg.Agents.Defaults.RetryMax = parsed
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_PROVIDER_RETRY_BACKOFF_MS")); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			cfg.Agents.Defaults.RetryBackoffMs = parsed
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_ARCHIVE_ON_IDLE")); value != "" {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			cfg.Runtime.ArchiveOnIdle = parsed
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_ARCHIVE_DIR")); value != "" {
+		cfg.Runtime.ArchiveDir = value
+	}
+	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_GLOBAL_ALLOW_FROM")); value != "" {
+		cfg.Runtime.GlobalAllowFrom = splitCSV(value)
+	}
+	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_GLOBAL_DENY_FROM")); value != "" {
+		cfg.Runtime.GlobalDenyFrom = splitCSV(value)
+	}
+	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_QUIET_HOURS_ENABLED")); value != "" {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			cfg.Runtime.QuietHours.Enabled = parsed
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_QUIET_HOURS_START")); value != "" {
+		cfg.Runtime.QuietHours.Start = value
+	}
+	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_QUIET_HOURS_END")); value != "" {
+		cfg.Runtime.QuietHours.End = value
+	}
+	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_QUIET_HOURS_TIMEZONE")); value != "" {
+		cfg.Runtime.QuietHours.Timezone = value
+	}
+	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_SESSION_TITLES_ENABLED")); value != "" {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			cfg.Runtime.SessionTitles.Enabled = parsed
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_SESSION_TITLES_USE_PROVIDER")); value != "" {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			cfg.Runtime.SessionTitles.UseProvider = parsed
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_HISTORY_SUMMARY_ENABLED")); value != "" {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			cfg.Runtime.HistorySummary.Enabled = parsed
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_HISTORY_SUMMARY_MAX_TURNS")); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			cfg.Runtime.HistorySummary.MaxTurns = parsed
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_HISTORY_SUMMARY_MAX_CHARS")); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			cfg.Runtime.HistorySummary.MaxChars = parsed
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_HISTORY_SUMMARY_KEEP_RECENT")); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			cfg.Runtime.HistorySummary.KeepRecent = parsed
+		}
+	}
 	if value := strings.TrimSpace(os.Getenv("SQUIDBOT_FEDERATION_ENABLED")); value != "" {
 		if parsed, err := strconv.ParseBool(value); err == nil {
 			cfg.Runtime.Federation.Enabled = parsed
@@ -1042,6 +1786,63 @@ func NormalizeProviderName(name string) (string, bool) {
 	return "", false
 }
 
+// SuppressesSubagentNotifications reports whether async subagent messages
+// should be withheld from the named channel.
+func (c Config) SuppressesSubagentNotifications(channel string) bool {
+	channel = strings.ToLower(strings.TrimSpace(channel))
+	switch channel {
+	case "":
+		return false
+	case "cli":
+		if c.Channels.CLI.SuppressSubagentNotifications {
+			return true
+		}
+	case "telegram":
+		if c.Channels.Telegram.SuppressSubagentNotifications {
+			return true
+		}
+	}
+	for id, item := range c.Channels.Registry {
+		if strings.ToLower(strings.TrimSpace(id)) == channel && item.SuppressSubagentNotifications {
+			return true
+		}
+	}
+	for id, item := range c.Channels.Plugins {
+		if strings.ToLower(strings.TrimSpace(id)) == channel && item.SuppressSubagentNotifications {
+			return true
+		}
+	}
+	return false
+}
+
+// ToolDailyLimit returns the per-day call limit for a tool, or zero when the
+// tool is unlimited. tools.dailyLimits takes precedence over the
+// tools.web.search.dailyLimit shorthand.
+func (c Config) ToolDailyLimit(name string) int {
+	if limit, ok := c.Tools.DailyLimits[name]; ok {
+		return max(limit, 0)
+	}
+	if name == "web_search" {
+		return max(c.Tools.Web.Search.DailyLimit, 0)
+	}
+	return 0
+}
+
+// ChannelAllowFrom returns the sender allow-list configured on a channel,
+// falling back to the legacy telegram block for telegram.
+func (c Config) ChannelAllowFrom(channel string) []string {
+	channel = strings.ToLower(strings.TrimSpace(channel))
+	for id, item := range c.Channels.Registry {
+		if strings.ToLower(strings.TrimSpace(id)) == channel && len(item.AllowFrom) > 0 {
+			return item.AllowFrom
+		}
+	}
+	if channel == "telegram" {
+		return c.Channels.Telegram.AllowFrom
+	}
+	return nil
+}
+
 func (c Config) ProviderByName(name string) (ProviderConfig, bool) {
 	normalized, ok := NormalizeProviderName(name)
 	if !ok {
@@ -1063,6 +1864,8 @@ func (c Config) ProviderByName(name string) (ProviderConfig, bool) {
 		return c.Providers.Ollama, true
 	case ProviderLMStudio:
 		return c.Providers.LMStudio, true
+	case ProviderMock:
+		return ProviderConfig{Model: c.Providers.Mock.Model}, true
 	default:
 		return ProviderConfig{}, false
 	}
@@ -1107,6 +1910,22 @@ func ProviderDefaultAPIBase(name string) string {
 	return ""
 }
 
+// ProviderTransport returns the wire format for a provider: its transport
+// override when set, otherwise the catalog transport.
+func ProviderTransport(name string, provider ProviderConfig) string {
+	if override := normalizeTransport(provider.Transport); override != "" {
+		return override
+	}
+	if profile, exists := catalog.ProviderByID(name); exists && strings.TrimSpace(profile.Transport) != "" {
+		return strings.TrimSpace(profile.Transport)
+	}
+	return TransportOpenAICompat
+}
+
+func normalizeTransport(value string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(value)), "-", "_")
+}
+
 func ProviderDefaultModel(name string) string {
 	normalized, ok := NormalizeProviderName(name)
 	if !ok {
@@ -1156,6 +1975,9 @@ func ProviderRequirements(name string) (requiresAPIKey, requiresModel bool, ok b
 	if profile, exists := catalog.ProviderByID(normalized); exists {
 		return profile.RequiresAPIKey, profile.RequiresModel, true
 	}
+	if normalized == ProviderMock {
+		return false, false, true
+	}
 	if strings.HasPrefix(normalized, "custom-") || strings.HasPrefix(normalized, "custom:") {
 		return false, false, true
 	}
@@ -1173,9 +1995,43 @@ func validateProviderConfig(name string, provider ProviderConfig) error {
 	if requiresModel && strings.TrimSpace(provider.Model) == "" {
 		return fmt.Errorf("provider %q requires model", name)
 	}
+	for key := range provider.Headers {
+		if !validHeaderName(key) {
+			return fmt.Errorf("provider %q has invalid header name %q", name, key)
+		}
+	}
+	if override := normalizeTransport(provider.Transport); override != "" {
+		base := ProviderTransport(name, ProviderConfig{})
+		switch {
+		case override != TransportOpenAICompat && override != TransportOpenAIResponses:
+			return fmt.Errorf("provider %q has unsupported transport %q (use %s or %s)", name, provider.Transport, TransportOpenAICompat, TransportOpenAIResponses)
+		case name == ProviderMock || base != TransportOpenAICompat:
+			return fmt.Errorf("provider %q does not support transport %q", name, provider.Transport)
+		}
+	}
+	switch strings.ToLower(strings.TrimSpace(provider.ReasoningEffort)) {
+	case "", "minimal", "low", "medium", "high":
+	default:
+		return fmt.Errorf("provider %q has invalid reasoningEffort %q (use minimal, low, medium, or high)", name, provider.ReasoningEffort)
+	}
 	return nil
 }
 
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case strings.ContainsRune("!#$%&'*+-.^_`|~", r):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 func hasProviderCredentials(providerID string, provider ProviderConfig) bool {
 	requiresAPIKey, requiresModel, ok := ProviderRequirements(providerID)
 	if !ok {
@@ -1222,11 +2078,13 @@ func migrateLegacyChannels(cfg *Config) {
 		cfg.Channels.Plugins = map[string]PluginChannelConfig{}
 	}
 	legacy := GenericChannelConfig{
-		Label:     "Telegram",
-		Kind:      "core",
-		Enabled:   cfg.Channels.Telegram.Enabled,
-		Token:     strings.TrimSpace(cfg.Channels.Telegram.Token),
-		AllowFrom: normalizeAllowFrom(cfg.Channels.Telegram.AllowFrom),
+		Label:                         "Telegram",
+		Kind:                          "core",
+		Enabled:                       cfg.Channels.Telegram.Enabled,
+		Token:                         strings.TrimSpace(cfg.Channels.Telegram.Token),
+		AllowFrom:                     normalizeAllowFrom(cfg.Channels.Telegram.AllowFrom),
+		SuppressSubagentNotifications: cfg.Channels.Telegram.SuppressSubagentNotifications,
+		SendRateLimit:                 cfg.Channels.Telegram.SendRateLimit,
 	}
 	current := cfg.Channels.Registry["telegram"]
 	if strings.TrimSpace(current.Token) == "" && strings.TrimSpace(legacy.Token) != "" {
@@ -1238,13 +2096,19 @@ func migrateLegacyChannels(cfg *Config) {
 	current.Label = defaultString(current.Label, legacy.Label)
 	current.Kind = defaultString(current.Kind, legacy.Kind)
 	current.Enabled = current.Enabled || legacy.Enabled
+	current.SuppressSubagentNotifications = current.SuppressSubagentNotifications || legacy.SuppressSubagentNotifications
+	if current.SendRateLimit == (ChannelSendRateLimitConfig{}) {
+		current.SendRateLimit = legacy.SendRateLimit
+	}
 	cfg.Channels.Registry["telegram"] = current
 
 	telegram := cfg.Channels.Registry["telegram"]
 	cfg.Channels.Telegram = TelegramConfig{
-		Enabled:   telegram.Enabled,
-		Token:     strings.TrimSpace(telegram.Token),
-		AllowFrom: normalizeAllowFrom(telegram.AllowFrom),
+		Enabled:                       telegram.Enabled,
+		Token:                         strings.TrimSpace(telegram.Token),
+		AllowFrom:                     normalizeAllowFrom(telegram.AllowFrom),
+		SendRateLimit:                 telegram.SendRateLimit,
+		SuppressSubagentNotifications: telegram.SuppressSubagentNotifications,
 	}
 }
 
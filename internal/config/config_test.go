@@ -45,6 +45,35 @@ func TestValidateActiveProvider(t *testing.T) {
 		}
 	})
 
+	t.Run("transport override", func(t *testing.T) {
+		cfg := Default()
+		cfg.Providers.Active = ProviderOllama
+		cfg.Providers.Ollama.Model = "llama3.1:8b"
+		cfg.Providers.Ollama.Transport = "openai_responses"
+		if err := ValidateActiveProvider(cfg); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		cfg.Providers.Ollama.ReasoningEffort = "extreme"
+		if err := ValidateActiveProvider(cfg); err == nil {
+			t.Fatal("expected error for invalid reasoning effort")
+		}
+		cfg.Providers.Active = ProviderAnthropic
+		cfg.Providers.Anthropic = ProviderConfig{APIKey: "key", Model: "claude", Transport: "openai_responses"}
+		if err := ValidateActiveProvider(cfg); err == nil {
+			t.Fatal("expected error for responses transport on anthropic")
+		}
+	})
+
+	t.Run("invalid header name", func(t *testing.T) {
+		cfg := Default()
+		cfg.Providers.Active = ProviderOllama
+		cfg.Providers.Ollama.Model = "llama3.1:8b"
+		cfg.Providers.Ollama.Headers = map[string]string{"X Bad Header": "1"}
+		if err := ValidateActiveProvider(cfg); err == nil {
+			t.Fatal("expected error for invalid header name")
+		}
+	})
+
 	t.Run("legacy fallback openai key", func(t *testing.T) {
 		cfg := Default()
 		cfg.Providers.Active = ""
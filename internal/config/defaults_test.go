@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -231,3 +232,361 @@ func TestLoadAppliesSkillsEnvOverrides(t *testing.T) {
 		t.Fatalf("unexpected skills cacheDir from env: %s", cfg.Skills.CacheDir)
 	}
 }
+
+func TestHomeDirResolution(t *testing.T) {
+	t.Run("xdg locations used for fresh installs", func(t *testing.T) {
+		home := t.TempDir()
+		t.Setenv("HOME", home)
+		t.Setenv("SQUIDBOT_HOME", "")
+		t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "cfg"))
+		t.Setenv("XDG_DATA_HOME", filepath.Join(home, "share"))
+		if got := ConfigPath(); got != filepath.Join(home, "cfg", "squidbot", "config.json") {
+			t.Fatalf("unexpected config path: %s", got)
+		}
+		if got := DataRoot(); got != filepath.Join(home, "share", "squidbot", "data") {
+			t.Fatalf("unexpected data root: %s", got)
+		}
+	})
+
+	t.Run("existing legacy home is kept", func(t *testing.T) {
+		home := t.TempDir()
+		t.Setenv("HOME", home)
+		t.Setenv("SQUIDBOT_HOME", "")
+		t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "cfg"))
+		t.Setenv("XDG_DATA_HOME", filepath.Join(home, "share"))
+		if err := os.MkdirAll(filepath.Join(home, ".squidbot"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(home, ".squidbot", "config.json"), []byte("{}"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if got := ConfigPath(); got != filepath.Join(home, ".squidbot", "config.json") {
+			t.Fatalf("unexpected config path: %s", got)
+		}
+		if got := HomeDir(); got != filepath.Join(home, ".squidbot") {
+			t.Fatalf("unexpected home dir: %s", got)
+		}
+		if got := DataRoot(); got != filepath.Join(home, ".squidbot", "data") {
+			t.Fatalf("unexpected data root: %s", got)
+		}
+	})
+
+	t.Run("mixed xdg variables keep one choice across runs", func(t *testing.T) {
+		home := t.TempDir()
+		t.Setenv("HOME", home)
+		t.Setenv("SQUIDBOT_HOME", "")
+		t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "cfg"))
+		t.Setenv("XDG_DATA_HOME", "")
+		wantConfig := filepath.Join(home, "cfg", "squidbot", "config.json")
+		wantData := filepath.Join(home, ".local", "share", "squidbot", "data")
+		if got := ConfigPath(); got != wantConfig {
+			t.Fatalf("unexpected config path: %s", got)
+		}
+		if got := DataRoot(); got != wantData {
+			t.Fatalf("unexpected data root: %s", got)
+		}
+		// Simulate onboarding, plus a stray legacy directory without a config.
+		if err := Save(ConfigPath(), Default()); err != nil {
+			t.Fatal(err)
+		}
+		for _, dir := range []string{DataRoot(), filepath.Join(home, ".squidbot")} {
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got := ConfigPath(); got != wantConfig {
+			t.Fatalf("expected the config path to stay put after onboarding, got %s", got)
+		}
+		if got := DataRoot(); got != wantData {
+			t.Fatalf("expected the data root to stay put after onboarding, got %s", got)
+		}
+	})
+
+	t.Run("unset xdg variables use the spec defaults", func(t *testing.T) {
+		home := t.TempDir()
+		t.Setenv("HOME", home)
+		t.Setenv("SQUIDBOT_HOME", "")
+		t.Setenv("XDG_CONFIG_HOME", "")
+		t.Setenv("XDG_DATA_HOME", "")
+		if got := ConfigPath(); got != filepath.Join(home, ".config", "squidbot", "config.json") {
+			t.Fatalf("unexpected config path: %s", got)
+		}
+		if got := HomeDir(); got != filepath.Join(home, ".local", "share", "squidbot") {
+			t.Fatalf("unexpected home dir: %s", got)
+		}
+	})
+
+	t.Run("legacy home behind an unavailable symlink is kept", func(t *testing.T) {
+		home := t.TempDir()
+		t.Setenv("HOME", home)
+		t.Setenv("SQUIDBOT_HOME", "")
+		t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "cfg"))
+		t.Setenv("XDG_DATA_HOME", filepath.Join(home, "share"))
+		if err := os.Symlink(filepath.Join(home, "unmounted", "squidbot"), filepath.Join(home, ".squidbot")); err != nil {
+			t.Skipf("symlinks unavailable: %v", err)
+		}
+		if got := ConfigPath(); got != filepath.Join(home, ".squidbot", "config.json") {
+			t.Fatalf("unexpected config path: %s", got)
+		}
+		if got := DataRoot(); got != filepath.Join(home, ".squidbot", "data") {
+			t.Fatalf("unexpected data root: %s", got)
+		}
+	})
+
+	t.Run("squidbot home overrides everything", func(t *testing.T) {
+		home := t.TempDir()
+		custom := filepath.Join(home, "custom")
+		t.Setenv("HOME", home)
+		t.Setenv("SQUIDBOT_HOME", custom)
+		t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "cfg"))
+		if got := ConfigPath(); got != filepath.Join(custom, "config.json") {
+			t.Fatalf("unexpected config path: %s", got)
+		}
+		if got := DataRoot(); got != filepath.Join(custom, "data") {
+			t.Fatalf("unexpected data root: %s", got)
+		}
+	})
+}
+
+func TestProfilesRoundTrip(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("SQUIDBOT_HOME", "")
+	path := filepath.Join(t.TempDir(), "profiles.json")
+	profiles, err := LoadProfiles(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := profiles.Add("bad name", "x.json"); err == nil {
+		t.Fatal("expected invalid name to be rejected")
+	}
+	if err := profiles.Add("work", "~/work/config.json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveProfiles(path, profiles); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadProfiles(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolved, err := loaded.Resolve("work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !filepath.IsAbs(resolved) || filepath.Base(resolved) != "config.json" {
+		t.Fatalf("unexpected resolved path: %s", resolved)
+	}
+	if _, err := loaded.Resolve("home"); err == nil {
+		t.Fatal("expected unknown profile error")
+	}
+}
+
+func TestLoadMergesConfigDropIns(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	base := `{"agents":{"defaults":{"model":"base-model","maxTokens":4096}},"providers":{"registry":{"openai":{"apiKey":"","model":"gpt-4o"}}},"runtime":{"tokenSafety":{"globalHardLimitTokens":9007199254740993}}}`
+	if err := os.WriteFile(path, []byte(base), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dropInDir := DropInDir(path)
+	if err := os.MkdirAll(dropInDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"10-secrets.json":   `{"providers":{"registry":{"openai":{"apiKey":"sk-secret"}}}}`,
+		"20-structure.json": `{"agents":{"defaults":{"model":"dropin-model"}}}`,
+		"30-override.json":  `{"agents":{"defaults":{"model":"last-wins"}}}`,
+		"notes.txt":         `{"agents":{"defaults":{"model":"ignored"}}}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dropInDir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Agents.Defaults.Model != "last-wins" {
+		t.Fatalf("expected later drop-in to win, got %q", cfg.Agents.Defaults.Model)
+	}
+	if cfg.Agents.Defaults.MaxTokens != 4096 {
+		t.Fatalf("expected sibling keys from base to survive merge, got %d", cfg.Agents.Defaults.MaxTokens)
+	}
+	openai := cfg.Providers.Registry["openai"]
+	if openai.APIKey != "sk-secret" || openai.Model != "gpt-4o" {
+		t.Fatalf("expected provider maps merged, got %#v", openai)
+	}
+	if cfg.Runtime.TokenSafety.GlobalHardLimitTokens != 9007199254740993 {
+		t.Fatalf("expected large numbers preserved, got %d", cfg.Runtime.TokenSafety.GlobalHardLimitTokens)
+	}
+
+	if err := os.WriteFile(filepath.Join(dropInDir, "40-broken.json"), []byte(`{"agents":`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Fatal("expected invalid drop-in to fail loading")
+	}
+}
+
+func TestSaveKeepsDropInValuesOutOfBaseConfig(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	base := `{"agents":{"defaults":{"model":"base-model"}},"providers":{"registry":{"openai":{"model":"gpt-4o"}}}}`
+	if err := os.WriteFile(path, []byte(base), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dropInDir := DropInDir(path)
+	if err := os.MkdirAll(dropInDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	secrets := `{"providers":{"registry":{"openai":{"apiKey":"sk-secret"}}},"agents":{"defaults":{"model":"dropin-model"}}}`
+	if err := os.WriteFile(filepath.Join(dropInDir, "10-secrets.json"), []byte(secrets), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Agents.Defaults.MaxTokens = 2048
+	if err := Save(path, cfg); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(saved), "sk-secret") || strings.Contains(string(saved), "dropin-model") {
+		t.Fatalf("expected drop-in values kept out of base config, got %s", saved)
+	}
+	if !strings.Contains(string(saved), `"base-model"`) {
+		t.Fatalf("expected base value restored, got %s", saved)
+	}
+
+	reloaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.Agents.Defaults.MaxTokens != 2048 {
+		t.Fatalf("expected edit to persist, got %d", reloaded.Agents.Defaults.MaxTokens)
+	}
+	if reloaded.Providers.Registry["openai"].APIKey != "sk-secret" || reloaded.Agents.Defaults.Model != "dropin-model" {
+		t.Fatalf("expected drop-in values after reload, got %#v", reloaded.Providers.Registry["openai"])
+	}
+}
+
+func TestMergeDropInsReplacesArraysAndScalars(t *testing.T) {
+	merged, err := mergeDropIns([]byte(`{"a":{"list":[1,2],"keep":true,"n":1}}`), []dropIn{{data: map[string]any{"a": map[string]any{"list": []any{3}, "n": "x"}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(merged) != `{"a":{"keep":true,"list":[3],"n":"x"}}` {
+		t.Fatalf("unexpected merge result: %s", merged)
+	}
+}
+
+func TestMigrateRewritesLegacyConfigAtCurrentVersion(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "config.json")
+	legacy := `{"providers":{"active":"openrouter","openrouter":{"apiKey":"sk-legacy"}},"channels":{"telegram":{"enabled":true,"token":"tg-token"}}}`
+	if err := os.WriteFile(path, []byte(legacy), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Migrate(path, false)
+	if err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	if report.FromVersion != 0 || report.ToVersion != CurrentVersion || len(report.Steps) != 1 || !report.Rewritten {
+		t.Fatalf("unexpected report: %#v", report)
+	}
+	changed := strings.Join(report.Changed, "\n")
+	for _, want := range []string{"tools.exec.enabled: false -> true", `providers.registry.openrouter.apiKey: unset -> "sk-legacy"`, "version: 0 -> 1"} {
+		if !strings.Contains(changed, want) {
+			t.Fatalf("expected change %q in report:\n%s", want, changed)
+		}
+	}
+	if backup, err := os.ReadFile(path + ".bak"); err != nil || string(backup) != legacy {
+		t.Fatalf("expected original file kept as backup, got %q (%v)", backup, err)
+	}
+
+	migrated, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if migrated.Version != CurrentVersion || !migrated.Tools.Exec.Enabled || migrated.Providers.Registry[ProviderOpenRouter].APIKey != "sk-legacy" || migrated.Channels.Registry["telegram"].Token != "tg-token" {
+		t.Fatalf("migrated config lost settings: %#v", migrated)
+	}
+	if loaded.Tools.Exec.Enabled != migrated.Tools.Exec.Enabled {
+		t.Fatal("expected migrated file to load the same as the legacy file")
+	}
+
+	again, err := Migrate(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again.Steps) != 0 || len(again.Changed) != 0 || again.Rewritten {
+		t.Fatalf("expected second migration to be a no-op, got %#v", again)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"version":99}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Fatalf("expected newer config version to be rejected, got %v", err)
+	}
+}
+
+func TestSuppressesSubagentNotificationsOnTelegramAndCLI(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "config.json")
+	raw := `{"channels":{"telegram":{"enabled":true,"token":"tg","suppressSubagentNotifications":true},"cli":{"suppressSubagentNotifications":true}}}`
+	if err := os.WriteFile(path, []byte(raw), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, channel := range []string{"telegram", "CLI"} {
+		if !cfg.SuppressesSubagentNotifications(channel) {
+			t.Fatalf("expected %s to suppress subagent notifications", channel)
+		}
+	}
+	if cfg.SuppressesSubagentNotifications("slack") {
+		t.Fatal("expected other channels to keep notifications")
+	}
+	if !cfg.Channels.Registry["telegram"].SuppressSubagentNotifications {
+		t.Fatal("expected the telegram flag carried into the channel registry")
+	}
+}
+
+func TestLoadKeepsTelegramSendRateLimit(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	want := ChannelSendRateLimitConfig{PerSecond: 1, Burst: 3, MaxRetries: 2}
+	for _, version := range []string{``, `"version":1,`} {
+		path := filepath.Join(t.TempDir(), "config.json")
+		raw := `{` + version + `"channels":{"telegram":{"enabled":true,"token":"tg","sendRateLimit":{"perSecond":1,"burst":3,"maxRetries":2}}}}`
+		if err := os.WriteFile(path, []byte(raw), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := Load(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Channels.Telegram.SendRateLimit != want {
+			t.Fatalf("expected channels.telegram.sendRateLimit to survive load (%q), got %+v", version, cfg.Channels.Telegram.SendRateLimit)
+		}
+		if cfg.Channels.Registry["telegram"].SendRateLimit != want {
+			t.Fatalf("expected the limit carried into the channel registry (%q), got %+v", version, cfg.Channels.Registry["telegram"].SendRateLimit)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+type dropIn struct {
+	path string
+	data map[string]any
+}
+
+// DropInDir returns the config.d directory that sits next to a config file.
+// Every *.json file in it is merged over the base config at load time.
+func DropInDir(configPath string) string {
+	if configPath == "" {
+		configPath = ConfigPath()
+	}
+	return filepath.Join(filepath.Dir(expandPath(configPath)), "config.d")
+}
+
+// readDropIns loads config.d/*.json in lexical file-name order. A missing
+// directory is not an error.
+func readDropIns(dir string) ([]dropIn, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(matches)
+	out := make([]dropIn, 0, len(matches))
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(match)
+		if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(data)) == 0 {
+			continue
+		}
+		parsed, err := decodeObject(data)
+		if err != nil {
+			return nil, fmt.Errorf("config drop-in %s: %w", filepath.Base(match), err)
+		}
+		out = append(out, dropIn{path: match, data: parsed})
+	}
+	return out, nil
+}
+
+// mergeDropIns deep-merges drop-ins over the base config document. Objects
+// are merged key by key; scalars, arrays, and null replace the base value.
+func mergeDropIns(base []byte, dropIns []dropIn) ([]byte, error) {
+	merged := map[string]any{}
+	if len(bytes.TrimSpace(base)) > 0 {
+		parsed, err := decodeObject(base)
+		if err != nil {
+			return nil, err
+		}
+		merged = parsed
+	}
+	for _, item := range dropIns {
+		merged = mergeObjects(merged, item.data)
+	}
+	return json.Marshal(merged)
+}
+
+func mergeObjects(base, overlay map[string]any) map[string]any {
+	if base == nil {
+		base = map[string]any{}
+	}
+	for key, value := range overlay {
+		overlayMap, overlayIsMap := value.(map[string]any)
+		baseMap, baseIsMap := base[key].(map[string]any)
+		if overlayIsMap && baseIsMap {
+			base[key] = mergeObjects(baseMap, overlayMap)
+			continue
+		}
+		base[key] = value
+	}
+	return base
+}
+
+// stripDropIns removes the values the drop-ins supply from a marshaled
+// config. A value still equal to its drop-in value goes back to what the base
+// document held, or is dropped when the base did not set it; a value the
+// caller changed is kept.
+func stripDropIns(doc, base []byte, dropIns []dropIn) ([]byte, error) {
+	out, err := decodeObject(doc)
+	if err != nil {
+		return nil, err
+	}
+	baseDoc := map[string]any{}
+	if len(bytes.TrimSpace(base)) > 0 {
+		if baseDoc, err = decodeObject(base); err != nil {
+			return nil, err
+		}
+	}
+	overlay := map[string]any{}
+	for _, item := range dropIns {
+		overlay = mergeObjects(overlay, item.data)
+	}
+	stripObject(out, baseDoc, overlay)
+	return json.MarshalIndent(out, "", "  ")
+}
+
+func stripObject(out, base, overlay map[string]any) {
+	for key, value := range overlay {
+		current, ok := out[key]
+		if !ok {
+			continue
+		}
+		overlayMap, overlayIsMap := value.(map[string]any)
+		currentMap, currentIsMap := current.(map[string]any)
+		if overlayIsMap && currentIsMap {
+			baseMap, _ := base[key].(map[string]any)
+			stripObject(currentMap, baseMap, overlayMap)
+			continue
+		}
+		if !sameJSON(current, value) {
+			continue
+		}
+		if baseValue, ok := base[key]; ok {
+			out[key] = baseValue
+		} else {
+			delete(out, key)
+		}
+	}
+}
+
+func sameJSON(a, b any) bool {
+	left, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	right, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(left, right)
+}
+
+// decodeObject parses a JSON object while keeping numbers exact, so large
+// token limits survive the round trip through the merged document.
+func decodeObject(data []byte) (map[string]any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var out map[string]any
+	if err := decoder.Decode(&out); err != nil {
+		return nil, err
+	}
+	if out == nil {
+		return nil, fmt.Errorf("expected a JSON object")
+	}
+	return out, nil
+}
@@ -0,0 +1,194 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// CurrentVersion is the config schema version written by Save and Migrate.
+// Files without a version field are version 0.
+const CurrentVersion = 1
+
+// schemaMigration upgrades a config from the previous version to version to.
+// raw is the decoded file, for steps that need to tell a missing key from a
+// zero value.
+type schemaMigration struct {
+	to          int
+	description string
+	apply       func(cfg *Config, raw map[string]any)
+}
+
+var schemaMigrations = []schemaMigration{
+	{
+		to:          1,
+		description: "keep pre-hardening exec and filesystem defaults, move legacy provider blocks into providers.registry, and copy channels.telegram into channels.registry",
+		apply: func(cfg *Config, raw map[string]any) {
+			applyLegacyCompatibilityDefaults(cfg, raw)
+			migrateLegacyProviders(cfg)
+			migrateLegacyChannels(cfg)
+		},
+	},
+}
+
+// MigrationReport describes what Migrate did to a config file.
+type MigrationReport struct {
+	Path        string   `json:"path"`
+	FromVersion int      `json:"from_version"`
+	ToVersion   int      `json:"to_version"`
+	Steps       []string `json:"steps,omitempty"`
+	// Changed lists settings whose effective value differs after migration,
+	// as "path: old -> new".
+	Changed   []string `json:"changed,omitempty"`
+	Rewritten bool     `json:"rewritten"`
+}
+
+// configVersion returns the version recorded in a decoded config file.
+func configVersion(raw map[string]any) int {
+	switch value := raw["version"].(type) {
+	case float64:
+		return int(value)
+	case json.Number:
+		parsed, _ := value.Int64()
+		return int(parsed)
+	}
+	return 0
+}
+
+// migrateSchema runs every migration newer than the file's version and
+// stamps cfg with CurrentVersion. It returns the applied step descriptions.
+func migrateSchema(cfg *Config, raw map[string]any) ([]string, error) {
+	from := configVersion(raw)
+	if from > CurrentVersion {
+		return nil, fmt.Errorf("config version %d is newer than this squidbot supports (%d)", from, CurrentVersion)
+	}
+	steps := make([]string, 0, len(schemaMigrations))
+	for _, step := range schemaMigrations {
+		if step.to <= from {
+			continue
+		}
+		step.apply(cfg, raw)
+		steps = append(steps, fmt.Sprintf("v%d: %s", step.to, step.description))
+	}
+	cfg.Version = CurrentVersion
+	return steps, nil
+}
+
+// Migrate upgrades the config file at path to CurrentVersion and, unless
+// dryRun is set, rewrites it in the current schema. Drop-ins and environment
+// overrides are left out so the file keeps only its own settings.
+func Migrate(path string, dryRun bool) (MigrationReport, error) {
+	if path == "" {
+		path = ConfigPath()
+	}
+	path = expandPath(path)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return MigrationReport{}, err
+	}
+	raw := map[string]any{}
+	if len(bytes.TrimSpace(data)) > 0 {
+		raw, err = decodeObject(data)
+		if err != nil {
+			return MigrationReport{}, fmt.Errorf("config %s: %w", path, err)
+		}
+	}
+	before := Default()
+	after := Default()
+	if len(raw) > 0 {
+		if err := json.Unmarshal(data, &before); err != nil {
+			return MigrationReport{}, fmt.Errorf("config %s: %w", path, err)
+		}
+		// Decoded twice rather than copied: migrations mutate maps in place.
+		_ = json.Unmarshal(data, &after)
+	}
+	before.Version = configVersion(raw)
+	steps, err := migrateSchema(&after, raw)
+	if err != nil {
+		return MigrationReport{}, err
+	}
+	report := MigrationReport{Path: path, FromVersion: before.Version, ToVersion: CurrentVersion, Steps: steps}
+	report.Changed, err = diffConfigs(before, after)
+	if err != nil {
+		return MigrationReport{}, err
+	}
+	if dryRun {
+		return report, nil
+	}
+	if err := os.WriteFile(path+".bak", data, 0o600); err != nil {
+		return report, err
+	}
+	if err := Save(path, after); err != nil {
+		return report, err
+	}
+	report.Rewritten = true
+	return report, nil
+}
+
+func diffConfigs(before, after Config) ([]string, error) {
+	flatBefore, err := flattenConfig(before)
+	if err != nil {
+		return nil, err
+	}
+	flatAfter, err := flattenConfig(after)
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[string]struct{}, len(flatAfter))
+	for key := range flatBefore {
+		keys[key] = struct{}{}
+	}
+	for key := range flatAfter {
+		keys[key] = struct{}{}
+	}
+	changed := make([]string, 0)
+	for key := range keys {
+		old, hadOld := flatBefore[key]
+		current, hasCurrent := flatAfter[key]
+		if hadOld && hasCurrent && old == current {
+			continue
+		}
+		if !hadOld {
+			old = "unset"
+		}
+		if !hasCurrent {
+			current = "unset"
+		}
+		changed = append(changed, fmt.Sprintf("%s: %s -> %s", key, old, current))
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// flattenConfig maps dotted JSON paths to JSON-encoded leaf values. Arrays
+// are treated as leaves.
+func flattenConfig(cfg Config) (map[string]string, error) {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	out := map[string]string{}
+	var walk func(prefix string, value any)
+	walk = func(prefix string, value any) {
+		if object, ok := value.(map[string]any); ok && len(object) > 0 {
+			for key, child := range object {
+				next := key
+				if prefix != "" {
+					next = prefix + "." + key
+				}
+				walk(next, child)
+			}
+			return
+		}
+		encoded, _ := json.Marshal(value)
+		out[prefix] = string(encoded)
+	}
+	walk("", doc)
+	return out, nil
+}
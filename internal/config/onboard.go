@@ -41,9 +41,9 @@ I am squidbot.
 
 - read_file(path)
 - write_file(path, content)
-- edit_file(path, old_text, new_text)
+- edit_file(path, old_text | start_line + end_line?, new_text)
 - list_dir(path)
-- exec(command, working_dir?)
+- exec(command, cwd?)
 - web_search(query, count?)
 - web_fetch(url, extractMode?, maxChars?)
 - message(content, channel?, chat_id?)
@@ -57,6 +57,7 @@ I am squidbot.
 - budget_set_mode(mode)
 - budget_set_enabled(enabled)
 - budget_set_estimation(estimate_on_missing_usage?, estimate_chars_per_token?)
+- budget_reset(scope, session_id?, run_id?)
 `,
 	"HEARTBEAT.md": `# Heartbeat Tasks
 
@@ -78,7 +79,8 @@ skills/
 `,
 }
 
-const memoryTemplate = `# Long-term Memory
+// MemoryTemplate is the curated memory file written into a new workspace.
+const MemoryTemplate = `# Long-term Memory
 
 ## User Information
 
@@ -123,7 +125,7 @@ func EnsureFilesystem(cfg Config) error {
 
 	memoryPath := filepath.Join(memoryDir, "MEMORY.md")
 	if _, err := os.Stat(memoryPath); os.IsNotExist(err) {
-		if err := os.WriteFile(memoryPath, []byte(memoryTemplate), 0o644); err != nil {
+		if err := os.WriteFile(memoryPath, []byte(MemoryTemplate), 0o644); err != nil {
 			return err
 		}
 	}
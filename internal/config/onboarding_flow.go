@@ -3,12 +3,14 @@ package config
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/grixate/squidbot/internal/catalog"
 )
@@ -33,6 +35,10 @@ type OnboardingOptions struct {
 	In  io.Reader
 	Out io.Writer
 
+	GeminiVerifyAttempts int
+	GeminiVerifyTimeout  time.Duration
+	GeminiVerifyBackoff  time.Duration
+
 	LookPath   func(file string) (string, error)
 	RunCommand func(ctx context.Context, name string, args []string, env map[string]string) (string, error)
 }
@@ -43,8 +49,58 @@ type OnboardingResult struct {
 	Warnings           []string
 	GeminiCLIVerified  bool
 	GeminiCLIVerifyRan bool
+	// GeminiCLIFailureReason is one of the GeminiVerifyReason* values when
+	// verification ran and failed.
+	GeminiCLIFailureReason string
+}
+
+const (
+	GeminiVerifyReasonMissingKey = "missing_api_key"
+	GeminiVerifyReasonPath       = "path"
+	GeminiVerifyReasonAuth       = "auth"
+	GeminiVerifyReasonNetwork    = "network"
+	GeminiVerifyReasonTimeout    = "timeout"
+	GeminiVerifyReasonUnknown    = "unknown"
+)
+
+const (
+	defaultGeminiVerifyAttempts = 3
+	defaultGeminiVerifyTimeout  = 30 * time.Second
+	defaultGeminiVerifyBackoff  = 2 * time.Second
+)
+
+type GeminiVerifyError struct {
+	Reason   string
+	Attempts int
+	Err      error
+}
+
+func (e *GeminiVerifyError) Error() string {
+	hint := ""
+	switch e.Reason {
+	case GeminiVerifyReasonMissingKey:
+		hint = "set a Gemini API key before verifying"
+	case GeminiVerifyReasonPath:
+		hint = "install the gemini CLI or add it to PATH"
+	case GeminiVerifyReasonAuth:
+		hint = "check that the Gemini API key is valid and has access to the model"
+	case GeminiVerifyReasonNetwork:
+		hint = "check network connectivity to the Gemini API"
+	case GeminiVerifyReasonTimeout:
+		hint = "the gemini CLI did not respond in time; retry or raise the verification timeout"
+	}
+	message := e.Err.Error()
+	if e.Attempts > 1 {
+		message = fmt.Sprintf("%s (after %d attempts)", message, e.Attempts)
+	}
+	if hint == "" {
+		return message
+	}
+	return message + ": " + hint
 }
 
+func (e *GeminiVerifyError) Unwrap() error { return e.Err }
+
 func RunOnboarding(ctx context.Context, cfg Config, opts OnboardingOptions) (OnboardingResult, error) {
 	if ctx == nil {
 		ctx = context.Background()
@@ -90,6 +146,11 @@ func RunOnboarding(ctx context.Context, cfg Config, opts OnboardingOptions) (Onb
 	if shouldVerify {
 		result.GeminiCLIVerifyRan = true
 		if err := verifyGeminiCLI(ctx, providerCfg, opts); err != nil {
+			result.GeminiCLIFailureReason = GeminiVerifyReasonUnknown
+			var verifyErr *GeminiVerifyError
+			if errors.As(err, &verifyErr) {
+				result.GeminiCLIFailureReason = verifyErr.Reason
+			}
 			if opts.NonInteractive && opts.VerifyGeminiCLI {
 				return OnboardingResult{}, err
 			}
@@ -331,7 +392,7 @@ func shouldRunGeminiVerification(providerName string, opts OnboardingOptions, re
 
 func verifyGeminiCLI(ctx context.Context, providerCfg ProviderConfig, opts OnboardingOptions) error {
 	if strings.TrimSpace(providerCfg.APIKey) == "" {
-		return fmt.Errorf("cannot verify Gemini CLI without api key")
+		return &GeminiVerifyError{Reason: GeminiVerifyReasonMissingKey, Attempts: 1, Err: fmt.Errorf("cannot verify Gemini CLI without api key")}
 	}
 
 	lookPath := opts.LookPath
@@ -339,25 +400,82 @@ func verifyGeminiCLI(ctx context.Context, providerCfg ProviderConfig, opts Onboa
 		lookPath = exec.LookPath
 	}
 	if _, err := lookPath("gemini"); err != nil {
-		return fmt.Errorf("gemini CLI not found in PATH")
+		return &GeminiVerifyError{Reason: GeminiVerifyReasonPath, Attempts: 1, Err: fmt.Errorf("gemini CLI not found in PATH")}
 	}
 
 	runCommand := opts.RunCommand
 	if runCommand == nil {
 		runCommand = defaultRunCommand
 	}
+	attempts := opts.GeminiVerifyAttempts
+	if attempts <= 0 {
+		attempts = defaultGeminiVerifyAttempts
+	}
+	timeout := opts.GeminiVerifyTimeout
+	if timeout <= 0 {
+		timeout = defaultGeminiVerifyTimeout
+	}
+	backoff := opts.GeminiVerifyBackoff
+	if backoff <= 0 {
+		backoff = defaultGeminiVerifyBackoff
+	}
 
 	model := strings.TrimSpace(providerCfg.Model)
 	if model == "" {
 		model = ProviderDefaultModel(ProviderGemini)
 	}
-	_, err := runCommand(ctx, "gemini", []string{"-p", "Reply with OK", "--model", model, "--output-format", "json"}, map[string]string{
-		"GEMINI_API_KEY": providerCfg.APIKey,
-	})
-	if err != nil {
-		return err
+	var lastErr error
+	reason := GeminiVerifyReasonUnknown
+	for attempt := 1; attempt <= attempts; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+		_, err := runCommand(attemptCtx, "gemini", []string{"-p", "Reply with OK", "--model", model, "--output-format", "json"}, map[string]string{
+			"GEMINI_API_KEY": providerCfg.APIKey,
+		})
+		timedOut := errors.Is(attemptCtx.Err(), context.DeadlineExceeded)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		reason = classifyGeminiVerifyError(err, timedOut)
+		retryable := reason == GeminiVerifyReasonNetwork || reason == GeminiVerifyReasonTimeout
+		if !retryable || attempt == attempts || ctx.Err() != nil {
+			return &GeminiVerifyError{Reason: reason, Attempts: attempt, Err: lastErr}
+		}
+		select {
+		case <-ctx.Done():
+			return &GeminiVerifyError{Reason: reason, Attempts: attempt, Err: lastErr}
+		case <-time.After(backoff * time.Duration(attempt)):
+		}
 	}
-	return nil
+	return &GeminiVerifyError{Reason: reason, Attempts: attempts, Err: lastErr}
+}
+
+func classifyGeminiVerifyError(err error, timedOut bool) string {
+	if timedOut || errors.Is(err, context.DeadlineExceeded) {
+		return GeminiVerifyReasonTimeout
+	}
+	var execErr *exec.Error
+	if errors.As(err, &execErr) {
+		return GeminiVerifyReasonPath
+	}
+	text := strings.ToLower(err.Error())
+	for _, marker := range []string{"api key not valid", "api_key_invalid", "invalid api key", "unauthorized", "unauthenticated", "permission denied", "permission_denied", "401", "403"} {
+		if strings.Contains(text, marker) {
+			return GeminiVerifyReasonAuth
+		}
+	}
+	for _, marker := range []string{"timeout", "timed out", "deadline exceeded"} {
+		if strings.Contains(text, marker) {
+			return GeminiVerifyReasonTimeout
+		}
+	}
+	for _, marker := range []string{"no such host", "connection refused", "connection reset", "network is unreachable", "fetch failed", "econnrefused", "econnreset", "enotfound", "etimedout", "tls handshake", "dial tcp"} {
+		if strings.Contains(text, marker) {
+			return GeminiVerifyReasonNetwork
+		}
+	}
+	return GeminiVerifyReasonUnknown
 }
 
 func defaultRunCommand(ctx context.Context, name string, args []string, env map[string]string) (string, error) {
@@ -528,6 +646,8 @@ func providerLabel(providerName string) string {
 		return "Ollama"
 	case ProviderLMStudio:
 		return "LM Studio"
+	case ProviderMock:
+		return "Mock (offline)"
 	default:
 		return providerName
 	}
@@ -6,6 +6,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestRunOnboardingInteractiveGeminiFlashWithVerification(t *testing.T) {
@@ -274,3 +275,67 @@ func TestRunOnboardingNonInteractiveTelegramExplicitOverrides(t *testing.T) {
 		t.Fatalf("unexpected allow list: %#v", result.Config.Channels.Telegram.AllowFrom)
 	}
 }
+
+func TestRunOnboardingGeminiVerificationRetriesAndReportsReason(t *testing.T) {
+	baseOpts := func(run func(context.Context, string, []string, map[string]string) (string, error)) OnboardingOptions {
+		return OnboardingOptions{
+			Provider:            ProviderGemini,
+			APIKey:              "sk-gemini",
+			Model:               "gemini-3.0-pro",
+			NonInteractive:      true,
+			VerifyGeminiCLI:     true,
+			GeminiVerifyBackoff: time.Millisecond,
+			LookPath: func(file string) (string, error) {
+				return "/usr/bin/gemini", nil
+			},
+			RunCommand: run,
+		}
+	}
+
+	t.Run("network failure is retried", func(t *testing.T) {
+		calls := 0
+		result, err := RunOnboarding(context.Background(), Default(), baseOpts(func(context.Context, string, []string, map[string]string) (string, error) {
+			calls++
+			if calls < 3 {
+				return "", errors.New("exit status 1: fetch failed: getaddrinfo ENOTFOUND")
+			}
+			return "OK", nil
+		}))
+		if err != nil {
+			t.Fatalf("expected verification to succeed after retries: %v", err)
+		}
+		if !result.GeminiCLIVerified || calls != 3 {
+			t.Fatalf("expected 3 attempts and success, got calls=%d verified=%v", calls, result.GeminiCLIVerified)
+		}
+	})
+
+	t.Run("auth failure is not retried", func(t *testing.T) {
+		calls := 0
+		_, err := RunOnboarding(context.Background(), Default(), baseOpts(func(context.Context, string, []string, map[string]string) (string, error) {
+			calls++
+			return "", errors.New("exit status 1: API key not valid. Please pass a valid API key.")
+		}))
+		var verifyErr *GeminiVerifyError
+		if !errors.As(err, &verifyErr) || verifyErr.Reason != GeminiVerifyReasonAuth {
+			t.Fatalf("expected auth failure, got %v", err)
+		}
+		if calls != 1 {
+			t.Fatalf("auth failures should not be retried, got %d calls", calls)
+		}
+	})
+
+	t.Run("missing binary reports path reason", func(t *testing.T) {
+		opts := baseOpts(nil)
+		opts.NonInteractive = false
+		opts.In = strings.NewReader("\nn\n")
+		opts.Out = &strings.Builder{}
+		opts.LookPath = func(file string) (string, error) { return "", errors.New("not found") }
+		result, err := RunOnboarding(context.Background(), Default(), opts)
+		if err != nil {
+			t.Fatalf("interactive onboarding should only warn: %v", err)
+		}
+		if result.GeminiCLIFailureReason != GeminiVerifyReasonPath {
+			t.Fatalf("unexpected failure reason: %q", result.GeminiCLIFailureReason)
+		}
+	})
+}
@@ -0,0 +1,28 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSetPasswordHashesAndVerifies(t *testing.T) {
+	cfg := Default()
+	if err := SetPassword(&cfg, "short", time.Time{}); err == nil {
+		t.Fatal("expected short password to be rejected")
+	}
+	if err := SetPassword(&cfg, "correct horse battery", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Auth.PasswordHash == "correct horse battery" {
+		t.Fatal("expected password to be hashed")
+	}
+	if cfg.Auth.PasswordUpdatedAt != "2026-03-01T00:00:00Z" {
+		t.Fatalf("unexpected updated at: %s", cfg.Auth.PasswordUpdatedAt)
+	}
+	if !VerifyPassword(cfg.Auth.PasswordHash, "correct horse battery") {
+		t.Fatal("expected password to verify")
+	}
+	if VerifyPassword(cfg.Auth.PasswordHash, "wrong password") {
+		t.Fatal("expected wrong password to fail")
+	}
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Profiles maps profile names to config file paths.
+type Profiles struct {
+	Profiles map[string]string `json:"profiles"`
+}
+
+func ProfilesPath() string {
+	return filepath.Join(HomeDir(), "profiles.json")
+}
+
+func LoadProfiles(path string) (Profiles, error) {
+	if path == "" {
+		path = ProfilesPath()
+	}
+	out := Profiles{Profiles: map[string]string{}}
+	bytes, err := os.ReadFile(expandPath(path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return out, nil
+		}
+		return out, err
+	}
+	if err := json.Unmarshal(bytes, &out); err != nil {
+		return out, fmt.Errorf("invalid profiles file %s: %w", path, err)
+	}
+	if out.Profiles == nil {
+		out.Profiles = map[string]string{}
+	}
+	return out, nil
+}
+
+func SaveProfiles(path string, profiles Profiles) error {
+	if path == "" {
+		path = ProfilesPath()
+	}
+	path = expandPath(path)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(profiles, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func (p Profiles) Names() []string {
+	out := make([]string, 0, len(p.Profiles))
+	for name := range p.Profiles {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (p Profiles) Resolve(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	path, ok := p.Profiles[name]
+	if !ok {
+		names := p.Names()
+		if len(names) == 0 {
+			return "", fmt.Errorf("unknown profile %q (no profiles configured; use `squidbot profile add`)", name)
+		}
+		return "", fmt.Errorf("unknown profile %q (available: %s)", name, strings.Join(names, ", "))
+	}
+	return expandPath(path), nil
+}
+
+func (p *Profiles) Add(name, configPath string) error {
+	name = strings.TrimSpace(name)
+	if name == "" || strings.ContainsAny(name, " \t/\\") {
+		return fmt.Errorf("invalid profile name %q", name)
+	}
+	configPath = strings.TrimSpace(configPath)
+	if configPath == "" {
+		return fmt.Errorf("config path is required")
+	}
+	abs, err := filepath.Abs(expandPath(configPath))
+	if err != nil {
+		return err
+	}
+	if p.Profiles == nil {
+		p.Profiles = map[string]string{}
+	}
+	p.Profiles[name] = abs
+	return nil
+}
+
+func (p *Profiles) Remove(name string) bool {
+	name = strings.TrimSpace(name)
+	if _, ok := p.Profiles[name]; !ok {
+		return false
+	}
+	delete(p.Profiles, name)
+	return true
+}
@@ -3,12 +3,12 @@ package config
 import "os"
 
 type Status struct {
-	ConfigPath  string
-	ConfigOK    bool
-	Workspace   string
-	WorkspaceOK bool
-	DataRoot    string
-	DataRootOK  bool
+	ConfigPath  string `json:"config_path"`
+	ConfigOK    bool   `json:"config_ok"`
+	Workspace   string `json:"workspace"`
+	WorkspaceOK bool   `json:"workspace_ok"`
+	DataRoot    string `json:"data_root"`
+	DataRootOK  bool   `json:"data_root_ok"`
 }
 
 func BuildStatus(cfg Config) Status {
@@ -0,0 +1,132 @@
+package cron
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	gocron "github.com/robfig/cron/v3"
+)
+
+const exportVersion = 1
+
+// ExportFile is the portable representation written by `cron export` and
+// read by `cron import`.
+type ExportFile struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	Jobs       []Job     `json:"jobs"`
+}
+
+type ImportResult struct {
+	Imported int
+	Removed  int
+	Warnings []string
+}
+
+// ValidateSchedule checks that a schedule can produce run times.
+func ValidateSchedule(schedule JobSchedule) error {
+	switch schedule.Kind {
+	case ScheduleAt:
+		if schedule.At == nil || schedule.At.IsZero() {
+			return fmt.Errorf("at schedule requires a time")
+		}
+	case ScheduleEvery:
+		if schedule.Every <= 0 {
+			return fmt.Errorf("every schedule requires a positive interval")
+		}
+	case ScheduleCron:
+		expr := strings.TrimSpace(schedule.Expr)
+		if expr == "" {
+			return fmt.Errorf("cron schedule requires an expression")
+		}
+		parser := gocron.NewParser(gocron.Minute | gocron.Hour | gocron.Dom | gocron.Month | gocron.Dow)
+		if _, err := parser.Parse(expr); err != nil {
+			return fmt.Errorf("invalid cron expression %q: %w", expr, err)
+		}
+	default:
+		return fmt.Errorf("unknown schedule kind %q", schedule.Kind)
+	}
+	return nil
+}
+
+// ValidatePayload checks that a payload has a known kind and, for subagent
+// jobs, a task to run.
+func ValidatePayload(payload JobPayload) error {
+	switch payload.Kind {
+	case "", PayloadMessage:
+	case PayloadSubagent:
+		if strings.TrimSpace(payload.Message) == "" {
+			return fmt.Errorf("subagent payload requires a task message")
+		}
+	default:
+		return fmt.Errorf("unknown payload kind %q", payload.Kind)
+	}
+	if payload.TimeoutSec < 0 {
+		return fmt.Errorf("payload timeout must not be negative")
+	}
+	return nil
+}
+
+func (s *Service) Export(ctx context.Context) (ExportFile, error) {
+	jobs, err := s.List(ctx, true)
+	if err != nil {
+		return ExportFile{}, err
+	}
+	return ExportFile{Version: exportVersion, ExportedAt: time.Now().UTC(), Jobs: jobs}, nil
+}
+
+// Import validates every job in file before writing any of them, then stores
+// each one. With replace, jobs missing from file are removed. One-shot jobs
+// scheduled in the past are imported with a warning.
+func (s *Service) Import(ctx context.Context, file ExportFile, replace bool) (ImportResult, error) {
+	if file.Version > exportVersion {
+		return ImportResult{}, fmt.Errorf("unsupported cron export version %d", file.Version)
+	}
+	result := ImportResult{}
+	now := time.Now().UTC()
+	seen := make(map[string]struct{}, len(file.Jobs))
+	for idx, job := range file.Jobs {
+		label := strings.TrimSpace(job.ID)
+		if label == "" {
+			return ImportResult{}, fmt.Errorf("job %d has no id", idx+1)
+		}
+		if _, dup := seen[label]; dup {
+			return ImportResult{}, fmt.Errorf("duplicate job id %s", label)
+		}
+		seen[label] = struct{}{}
+		if err := ValidateSchedule(job.Schedule); err != nil {
+			return ImportResult{}, fmt.Errorf("job %s: %w", label, err)
+		}
+		if err := ValidatePayload(job.Payload); err != nil {
+			return ImportResult{}, fmt.Errorf("job %s: %w", label, err)
+		}
+		if job.Schedule.Kind == ScheduleAt && !job.Schedule.At.After(now) {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("job %s (%s) is scheduled at %s, which is in the past; it will not run", label, job.Name, job.Schedule.At.UTC().Format(time.RFC3339)))
+		}
+	}
+	for _, job := range file.Jobs {
+		if err := s.Put(ctx, job); err != nil {
+			return result, fmt.Errorf("job %s: %w", job.ID, err)
+		}
+		result.Imported++
+	}
+	if !replace {
+		return result, nil
+	}
+	existing, err := s.List(ctx, true)
+	if err != nil {
+		return result, err
+	}
+	for _, job := range existing {
+		if _, keep := seen[job.ID]; keep {
+			continue
+		}
+		if err := s.Remove(ctx, job.ID); err != nil {
+			return result, err
+		}
+		result.Removed++
+	}
+	return result, nil
+}
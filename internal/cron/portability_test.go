@@ -0,0 +1,143 @@
+package cron
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type memoryJobStore struct {
+	mu   sync.Mutex
+	jobs map[string][]byte
+}
+
+func newMemoryJobStore() *memoryJobStore {
+	return &memoryJobStore{jobs: map[string][]byte{}}
+}
+
+func (m *memoryJobStore) PutJob(_ context.Context, job []byte, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.jobs[id] = append([]byte(nil), job...)
+	return nil
+}
+
+func (m *memoryJobStore) DeleteJob(_ context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.jobs, id)
+	return nil
+}
+
+func (m *memoryJobStore) ListJobs(context.Context) (map[string][]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make(map[string][]byte, len(m.jobs))
+	for id, job := range m.jobs {
+		out[id] = job
+	}
+	return out, nil
+}
+
+func (m *memoryJobStore) RecordJobRun(context.Context, string, []byte) error { return nil }
+
+func listSummary(t *testing.T, service *Service) []string {
+	t.Helper()
+	jobs, err := service.List(context.Background(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make([]string, 0, len(jobs))
+	for _, job := range jobs {
+		next := ""
+		if job.State.NextRunAt != nil {
+			next = job.State.NextRunAt.Format(time.RFC3339)
+		}
+		out = append(out, strings.Join([]string{job.ID, job.Name, job.Payload.Message, string(job.Schedule.Kind), next}, "|"))
+	}
+	return out
+}
+
+func TestExportImportRoundTripAndReplace(t *testing.T) {
+	ctx := context.Background()
+	source := NewService(newMemoryJobStore(), nil, nil)
+	past := time.Now().Add(-time.Hour).UTC()
+	for _, job := range []Job{
+		{ID: "job-every", Name: "every", Enabled: true, Schedule: JobSchedule{Kind: ScheduleEvery, Every: 60_000}, Payload: JobPayload{Message: "ping"}},
+		{ID: "job-cron", Name: "cron", Enabled: false, Schedule: JobSchedule{Kind: ScheduleCron, Expr: "0 9 * * 1"}, Payload: JobPayload{Message: "weekly", Deliver: true, Channel: "telegram", To: "42"}},
+		{ID: "job-at", Name: "at", Enabled: true, Schedule: JobSchedule{Kind: ScheduleAt, At: &past}, Payload: JobPayload{Message: "once"}},
+	} {
+		if err := source.Put(ctx, job); err != nil {
+			t.Fatal(err)
+		}
+	}
+	exported, err := source.Export(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := json.Marshal(exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded ExportFile
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	targetStore := newMemoryJobStore()
+	target := NewService(targetStore, nil, nil)
+	if err := target.Put(ctx, Job{ID: "job-stale", Name: "stale", Enabled: true, Schedule: JobSchedule{Kind: ScheduleEvery, Every: 1000}}); err != nil {
+		t.Fatal(err)
+	}
+	result, err := target.Import(ctx, decoded, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Imported != 3 || result.Removed != 1 {
+		t.Fatalf("unexpected import result %+v", result)
+	}
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "job-at") {
+		t.Fatalf("expected a past-schedule warning, got %v", result.Warnings)
+	}
+	if got, want := listSummary(t, target), listSummary(t, source); !reflect.DeepEqual(got, want) {
+		t.Fatalf("round trip changed jobs:\n got %v\nwant %v", got, want)
+	}
+}
+
+func TestImportRejectsInvalidScheduleBeforeWriting(t *testing.T) {
+	store := newMemoryJobStore()
+	service := NewService(store, nil, nil)
+	_, err := service.Import(context.Background(), ExportFile{Version: 1, Jobs: []Job{
+		{ID: "ok", Name: "ok", Enabled: true, Schedule: JobSchedule{Kind: ScheduleEvery, Every: 1000}},
+		{ID: "bad", Name: "bad", Enabled: true, Schedule: JobSchedule{Kind: ScheduleCron, Expr: "not a cron"}},
+	}}, false)
+	if err == nil || !strings.Contains(err.Error(), "bad") {
+		t.Fatalf("expected validation error for job bad, got %v", err)
+	}
+	if len(store.jobs) != 0 {
+		t.Fatalf("expected nothing written, got %d jobs", len(store.jobs))
+	}
+}
+
+func TestImportRejectsInvalidPayload(t *testing.T) {
+	store := newMemoryJobStore()
+	service := NewService(store, nil, nil)
+	every := JobSchedule{Kind: ScheduleEvery, Every: 1000}
+	for _, payload := range []JobPayload{
+		{Kind: "webhook", Message: "ping"},
+		{Kind: PayloadSubagent},
+		{Kind: PayloadSubagent, Message: "research", TimeoutSec: -1},
+	} {
+		_, err := service.Import(context.Background(), ExportFile{Version: 1, Jobs: []Job{{ID: "job", Schedule: every, Payload: payload}}}, false)
+		if err == nil {
+			t.Fatalf("expected payload %+v to be rejected", payload)
+		}
+	}
+	if _, err := service.Import(context.Background(), ExportFile{Version: 1, Jobs: []Job{{ID: "job", Schedule: every, Payload: JobPayload{Kind: PayloadSubagent, Message: "research", TimeoutSec: 600}}}}, false); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,101 @@
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	RecurDaily   = "daily"
+	RecurWeekly  = "weekly"
+	RecurMonthly = "monthly"
+)
+
+var weekdayNumbers = map[string]int{
+	"sun": 0, "sunday": 0,
+	"mon": 1, "monday": 1,
+	"tue": 2, "tues": 2, "tuesday": 2,
+	"wed": 3, "wednesday": 3,
+	"thu": 4, "thur": 4, "thurs": 4, "thursday": 4,
+	"fri": 5, "friday": 5,
+	"sat": 6, "saturday": 6,
+}
+
+// Recurrence is a daily, weekly, or monthly run at a wall-clock time, the
+// shorthand behind `cron add --daily|--weekly|--monthly`.
+type Recurrence struct {
+	Period string
+	// Time is the run time as HH:MM in 24-hour form.
+	Time string
+	// Weekday is a day name or abbreviation, or 0-7 with both 0 and 7 for
+	// Sunday. Weekly only; empty means Monday.
+	Weekday string
+	// DayOfMonth is 1-31. Monthly only; 0 means the 1st. Months without
+	// that day are skipped.
+	DayOfMonth int
+}
+
+// CronExpr translates r into a five-field cron expression.
+func (r Recurrence) CronExpr() (string, error) {
+	hour, minute, err := parseRecurrenceTime(r.Time)
+	if err != nil {
+		return "", err
+	}
+	period := strings.ToLower(strings.TrimSpace(r.Period))
+	if period != RecurWeekly && strings.TrimSpace(r.Weekday) != "" {
+		return "", fmt.Errorf("weekday only applies to weekly schedules")
+	}
+	if period != RecurMonthly && r.DayOfMonth != 0 {
+		return "", fmt.Errorf("day of month only applies to monthly schedules")
+	}
+	switch period {
+	case RecurDaily:
+		return fmt.Sprintf("%d %d * * *", minute, hour), nil
+	case RecurWeekly:
+		weekday, err := parseWeekday(r.Weekday)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d %d * * %d", minute, hour, weekday), nil
+	case RecurMonthly:
+		day := r.DayOfMonth
+		if day == 0 {
+			day = 1
+		}
+		if day < 1 || day > 31 {
+			return "", fmt.Errorf("day of month %d must be between 1 and 31", r.DayOfMonth)
+		}
+		return fmt.Sprintf("%d %d %d * *", minute, hour, day), nil
+	default:
+		return "", fmt.Errorf("unknown recurrence %q (use daily, weekly, or monthly)", r.Period)
+	}
+}
+
+func parseRecurrenceTime(value string) (int, int, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, 0, fmt.Errorf("a time of day (HH:MM) is required")
+	}
+	rawHour, rawMinute, ok := strings.Cut(value, ":")
+	hour, hourErr := strconv.Atoi(rawHour)
+	minute, minuteErr := strconv.Atoi(rawMinute)
+	if !ok || len(rawMinute) != 2 || hourErr != nil || minuteErr != nil || hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+		return 0, 0, fmt.Errorf("invalid time %q (use HH:MM, 24-hour)", value)
+	}
+	return hour, minute, nil
+}
+
+func parseWeekday(value string) (int, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return 1, nil
+	}
+	if day, ok := weekdayNumbers[value]; ok {
+		return day, nil
+	}
+	if day, err := strconv.Atoi(value); err == nil && day >= 0 && day <= 7 {
+		return day % 7, nil
+	}
+	return 0, fmt.Errorf("invalid weekday %q (use a name such as monday or mon, or 0-7)", value)
+}
@@ -0,0 +1,49 @@
+package cron
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRecurrenceCronExpr(t *testing.T) {
+	cases := []struct {
+		name    string
+		in      Recurrence
+		want    string
+		wantErr string
+	}{
+		{name: "daily", in: Recurrence{Period: RecurDaily, Time: "07:30"}, want: "30 7 * * *"},
+		{name: "weekly default monday", in: Recurrence{Period: RecurWeekly, Time: "09:00"}, want: "0 9 * * 1"},
+		{name: "weekly by name", in: Recurrence{Period: "Weekly", Time: "18:05", Weekday: "Fri"}, want: "5 18 * * 5"},
+		{name: "weekly sunday as 7", in: Recurrence{Period: RecurWeekly, Time: "00:00", Weekday: "7"}, want: "0 0 * * 0"},
+		{name: "monthly default first", in: Recurrence{Period: RecurMonthly, Time: "08:15"}, want: "15 8 1 * *"},
+		{name: "monthly day", in: Recurrence{Period: RecurMonthly, Time: "23:59", DayOfMonth: 15}, want: "59 23 15 * *"},
+		{name: "missing time", in: Recurrence{Period: RecurDaily}, wantErr: "HH:MM"},
+		{name: "bad time", in: Recurrence{Period: RecurDaily, Time: "24:00"}, wantErr: "invalid time"},
+		{name: "single digit minute", in: Recurrence{Period: RecurDaily, Time: "9:5"}, wantErr: "invalid time"},
+		{name: "bad weekday", in: Recurrence{Period: RecurWeekly, Time: "09:00", Weekday: "funday"}, wantErr: "invalid weekday"},
+		{name: "bad day of month", in: Recurrence{Period: RecurMonthly, Time: "09:00", DayOfMonth: 32}, wantErr: "between 1 and 31"},
+		{name: "weekday on daily", in: Recurrence{Period: RecurDaily, Time: "09:00", Weekday: "mon"}, wantErr: "only applies to weekly"},
+		{name: "day on weekly", in: Recurrence{Period: RecurWeekly, Time: "09:00", DayOfMonth: 3}, wantErr: "only applies to monthly"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.in.CronExpr()
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error containing %q, got %q, %v", tc.wantErr, got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Fatalf("expected %q, got %q", tc.want, got)
+			}
+			if err := ValidateSchedule(JobSchedule{Kind: ScheduleCron, Expr: got}); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
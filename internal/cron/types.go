@@ -30,11 +30,27 @@ type JobSchedule struct {
 	TZ    string       `json:"tz,omitempty"`
 }
 
+// PayloadKind selects what a job does when it fires. An empty kind is
+// PayloadMessage.
+type PayloadKind string
+
+const (
+	// PayloadMessage asks the agent with Message and optionally delivers the
+	// reply.
+	PayloadMessage PayloadKind = "message"
+	// PayloadSubagent enqueues Message as a background subagent task and
+	// returns without waiting for it.
+	PayloadSubagent PayloadKind = "subagent"
+)
+
 type JobPayload struct {
-	Message string `json:"message"`
-	Deliver bool   `json:"deliver"`
-	Channel string `json:"channel,omitempty"`
-	To      string `json:"to,omitempty"`
+	Kind    PayloadKind `json:"kind,omitempty"`
+	Message string      `json:"message"`
+	Deliver bool        `json:"deliver"`
+	Channel string      `json:"channel,omitempty"`
+	To      string      `json:"to,omitempty"`
+	// TimeoutSec bounds a subagent run. Zero uses the subagent default.
+	TimeoutSec int `json:"timeout_sec,omitempty"`
 }
 
 type JobState struct {
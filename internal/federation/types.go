@@ -33,8 +33,16 @@ type ContextPacket struct {
 	History        []provider.Message `json:"history,omitempty"`
 	MemorySnippets []string           `json:"memory_snippets,omitempty"`
 	Attachments    []string           `json:"attachments,omitempty"`
-	CreatedAt      time.Time          `json:"created_at"`
-	Checksum       string             `json:"checksum,omitempty"`
+	// InlineAttachments carries small attachment contents, since a peer
+	// cannot read the delegating node's files.
+	InlineAttachments []InlineAttachment `json:"inline_attachments,omitempty"`
+	CreatedAt         time.Time          `json:"created_at"`
+	Checksum          string             `json:"checksum,omitempty"`
+}
+
+type InlineAttachment struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
 }
 
 type DelegationRequest struct {
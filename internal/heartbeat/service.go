@@ -2,8 +2,11 @@ package heartbeat
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -15,9 +18,36 @@ const Prompt = `Read HEARTBEAT.md in your workspace (if it exists).
 Follow any instructions or tasks listed there.
 If nothing needs attention, reply with just: HEARTBEAT_OK`
 
-type Handler func(ctx context.Context, prompt string) (string, error)
+const (
+	// DefaultProfile is the profile that runs HEARTBEAT.md on
+	// runtime.heartbeatIntervalSec.
+	DefaultProfile  = "default"
+	DefaultFile     = "HEARTBEAT.md"
+	DefaultInterval = 30 * time.Minute
+)
+
+// ErrUnknownProfile is returned when triggering a profile that is not
+// configured.
+var ErrUnknownProfile = errors.New("unknown heartbeat profile")
+
+// Profile is one named heartbeat: a workspace markdown file of instructions
+// and how often it runs. A zero Interval runs the profile only on demand.
+type Profile struct {
+	Name     string
+	File     string
+	Interval time.Duration
+}
+
+// PromptFor returns the heartbeat prompt that points the agent at file.
+func PromptFor(file string) string {
+	return strings.Replace(Prompt, DefaultFile, file, 1)
+}
+
+// Handler runs one heartbeat: prompt points the agent at profile's file.
+type Handler func(ctx context.Context, profile Profile, prompt string) (string, error)
 
 type RunRecord struct {
+	Profile     string
 	TriggeredBy string
 	Status      string
 	Error       string
@@ -28,6 +58,12 @@ type RunRecord struct {
 
 type RunObserver func(record RunRecord)
 
+type profileState struct {
+	Profile
+	reset     chan time.Duration
+	nextRunAt time.Time
+}
+
 type Service struct {
 	workspace string
 	handler   Handler
@@ -35,18 +71,18 @@ type Service struct {
 	mu        sync.Mutex
 	running   bool
 	stop      chan struct{}
-	reset     chan time.Duration
-	interval  time.Duration
-	nextRunAt time.Time
+	profiles  map[string]*profileState
 	lastRun   RunRecord
 	hasLast   bool
 	observer  RunObserver
 	wg        sync.WaitGroup
 }
 
+// NewService returns a service with only the default profile, which runs
+// HEARTBEAT.md every interval.
 func NewService(workspace string, interval time.Duration, handler Handler, metrics *telemetry.Metrics) *Service {
 	if interval <= 0 {
-		interval = 30 * time.Minute
+		interval = DefaultInterval
 	}
 	if metrics == nil {
 		metrics = &telemetry.Metrics{}
@@ -54,11 +90,48 @@ func NewService(workspace string, interval time.Duration, handler Handler, metri
 	return &Service{
 		workspace: workspace,
 		handler:   handler,
-		interval:  interval,
 		metrics:   metrics,
 		stop:      make(chan struct{}),
-		reset:     make(chan time.Duration, 1),
+		profiles: map[string]*profileState{
+			DefaultProfile: newProfileState(Profile{Name: DefaultProfile, File: DefaultFile, Interval: interval}),
+		},
+	}
+}
+
+func newProfileState(profile Profile) *profileState {
+	return &profileState{Profile: profile, reset: make(chan time.Duration, 1)}
+}
+
+// SetProfile adds profile or replaces the one with the same name. Profiles
+// are fixed once the service has started.
+func (s *Service) SetProfile(profile Profile) error {
+	profile.Name = strings.ToLower(strings.TrimSpace(profile.Name))
+	profile.File = strings.TrimSpace(profile.File)
+	if profile.Name == "" {
+		return fmt.Errorf("heartbeat profile name is required")
 	}
+	if profile.File == "" {
+		return fmt.Errorf("heartbeat profile %q has no file", profile.Name)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		return fmt.Errorf("heartbeat profiles cannot change while the service runs")
+	}
+	s.profiles[profile.Name] = newProfileState(profile)
+	return nil
+}
+
+// Profiles lists the configured profiles by name.
+func (s *Service) Profiles() []Profile {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Profile, 0, len(s.profiles))
+	for _, state := range s.profiles {
+		out = append(out, state.Profile)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
 }
 
 func (s *Service) Start() {
@@ -68,11 +141,20 @@ func (s *Service) Start() {
 		return
 	}
 	s.running = true
-	s.nextRunAt = time.Now().UTC().Add(s.interval)
+	now := time.Now().UTC()
+	scheduled := make([]*profileState, 0, len(s.profiles))
+	for _, state := range s.profiles {
+		if state.Interval > 0 {
+			state.nextRunAt = now.Add(state.Interval)
+			scheduled = append(scheduled, state)
+		}
+	}
 	s.mu.Unlock()
 
-	s.wg.Add(1)
-	go s.loop()
+	for _, state := range scheduled {
+		s.wg.Add(1)
+		go s.loop(state)
+	}
 }
 
 func (s *Service) Stop() {
@@ -82,80 +164,123 @@ func (s *Service) Stop() {
 		return
 	}
 	s.running = false
-	s.nextRunAt = time.Time{}
+	for _, state := range s.profiles {
+		state.nextRunAt = time.Time{}
+	}
 	s.mu.Unlock()
 	close(s.stop)
 	s.wg.Wait()
 }
 
-func (s *Service) loop() {
+func (s *Service) loop(state *profileState) {
 	defer s.wg.Done()
-	ticker := time.NewTicker(s.Interval())
+	ticker := time.NewTicker(s.profileInterval(state))
 	defer ticker.Stop()
 	for {
 		select {
 		case <-s.stop:
 			return
-		case interval := <-s.reset:
+		case interval := <-state.reset:
 			if interval <= 0 {
 				continue
 			}
 			ticker.Reset(interval)
 			s.mu.Lock()
-			s.nextRunAt = time.Now().UTC().Add(interval)
+			state.nextRunAt = time.Now().UTC().Add(interval)
 			s.mu.Unlock()
 		case <-ticker.C:
-			interval := s.Interval()
+			interval := s.profileInterval(state)
 			s.mu.Lock()
-			s.nextRunAt = time.Now().UTC().Add(interval)
+			state.nextRunAt = time.Now().UTC().Add(interval)
 			s.mu.Unlock()
-			s.tick(context.Background())
+			s.tick(context.Background(), state.Profile)
 		}
 	}
 }
 
+func (s *Service) profileInterval(state *profileState) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return state.Interval
+}
+
+// TriggerNow runs the default profile at once.
 func (s *Service) TriggerNow(ctx context.Context) (string, error) {
+	return s.TriggerProfile(ctx, DefaultProfile)
+}
+
+// TriggerProfile runs the named profile at once, even when its file is empty.
+func (s *Service) TriggerProfile(ctx context.Context, name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		name = DefaultProfile
+	}
+	s.mu.Lock()
+	state, ok := s.profiles[name]
+	var profile Profile
+	if ok {
+		profile = state.Profile
+	}
+	s.mu.Unlock()
+	if !ok {
+		return "", fmt.Errorf("%w %q", ErrUnknownProfile, name)
+	}
 	if s.handler == nil {
 		return "", nil
 	}
-	return s.execute(ctx, "manual")
+	return s.execute(ctx, profile, "manual")
 }
 
+// Interval is the default profile's interval.
 func (s *Service) Interval() time.Duration {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	return s.interval
+	if state, ok := s.profiles[DefaultProfile]; ok {
+		return state.Interval
+	}
+	return 0
 }
 
+// SetInterval changes the default profile's interval, rescheduling it when
+// the service runs.
 func (s *Service) SetInterval(interval time.Duration) {
 	if interval <= 0 {
 		return
 	}
 	s.mu.Lock()
-	s.interval = interval
-	isRunning := s.running
+	state, ok := s.profiles[DefaultProfile]
+	if !ok {
+		s.mu.Unlock()
+		return
+	}
+	scheduled := state.Interval > 0
+	state.Interval = interval
+	isRunning := s.running && scheduled
 	if isRunning {
-		s.nextRunAt = time.Now().UTC().Add(interval)
+		state.nextRunAt = time.Now().UTC().Add(interval)
 	}
 	s.mu.Unlock()
 	if !isRunning {
 		return
 	}
 	select {
-	case s.reset <- interval:
+	case state.reset <- interval:
 	default:
 	}
 }
 
+// NextRunAt is when the default profile runs next.
 func (s *Service) NextRunAt() (time.Time, bool) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if s.nextRunAt.IsZero() {
+	state, ok := s.profiles[DefaultProfile]
+	if !ok || state.nextRunAt.IsZero() {
 		return time.Time{}, false
 	}
-	return s.nextRunAt, true
+	return state.nextRunAt, true
 }
 
+// LastRun is the most recent run of any profile.
 func (s *Service) LastRun() (RunRecord, bool) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -177,9 +302,8 @@ func (s *Service) SetRunObserver(observer RunObserver) {
 	s.observer = observer
 }
 
-func (s *Service) tick(ctx context.Context) {
-	heartbeatPath := filepath.Join(s.workspace, "HEARTBEAT.md")
-	bytes, err := os.ReadFile(heartbeatPath)
+func (s *Service) tick(ctx context.Context, profile Profile) {
+	bytes, err := os.ReadFile(filepath.Join(s.workspace, profile.File))
 	if err != nil {
 		return
 	}
@@ -189,16 +313,17 @@ func (s *Service) tick(ctx context.Context) {
 	if s.handler == nil {
 		return
 	}
-	_, _ = s.execute(ctx, "scheduled")
+	_, _ = s.execute(ctx, profile, "scheduled")
 }
 
-func (s *Service) execute(ctx context.Context, triggeredBy string) (string, error) {
+func (s *Service) execute(ctx context.Context, profile Profile, triggeredBy string) (string, error) {
 	started := time.Now().UTC()
 	s.metrics.HeartbeatExecutions.Add(1)
-	result, err := s.handler(ctx, Prompt)
+	result, err := s.handler(ctx, profile, PromptFor(profile.File))
 	finished := time.Now().UTC()
 
 	record := RunRecord{
+		Profile:     profile.Name,
 		TriggeredBy: triggeredBy,
 		Status:      "ok",
 		Error:       "",
@@ -2,6 +2,11 @@ package heartbeat
 
 import (
 	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -19,7 +24,7 @@ func TestIsEmpty(t *testing.T) {
 }
 
 func TestSetIntervalUpdatesValue(t *testing.T) {
-	service := NewService(t.TempDir(), time.Minute, func(ctx context.Context, prompt string) (string, error) {
+	service := NewService(t.TempDir(), time.Minute, func(ctx context.Context, profile Profile, prompt string) (string, error) {
 		return "ok", nil
 	}, nil)
 	if got := service.Interval(); got != time.Minute {
@@ -30,3 +35,77 @@ func TestSetIntervalUpdatesValue(t *testing.T) {
 		t.Fatalf("expected updated interval 45s, got %s", got)
 	}
 }
+
+func TestProfilesRunTheirOwnFile(t *testing.T) {
+	workspace := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workspace, "DIGEST.md"), []byte("- summarise overnight email\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	prompts := map[string]bool{}
+	service := NewService(workspace, time.Hour, func(ctx context.Context, profile Profile, prompt string) (string, error) {
+		mu.Lock()
+		prompts[prompt] = true
+		mu.Unlock()
+		return "done", nil
+	}, nil)
+	scheduled := make(chan RunRecord, 1)
+	var manual []RunRecord
+	service.SetRunObserver(func(record RunRecord) {
+		mu.Lock()
+		defer mu.Unlock()
+		if record.TriggeredBy == "manual" {
+			manual = append(manual, record)
+			return
+		}
+		select {
+		case scheduled <- record:
+		default:
+		}
+	})
+	if err := service.SetProfile(Profile{Name: "Morning", File: "DIGEST.md", Interval: 20 * time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.SetProfile(Profile{Name: "review", File: "REVIEW.md"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.SetProfile(Profile{Name: "broken"}); err == nil {
+		t.Fatal("expected a profile without a file to be rejected")
+	}
+
+	service.Start()
+	defer service.Stop()
+	if err := service.SetProfile(Profile{Name: "late", File: "LATE.md"}); err == nil {
+		t.Fatal("expected profiles to be fixed once started")
+	}
+	select {
+	case record := <-scheduled:
+		if record.Profile != "morning" {
+			t.Fatalf("unexpected scheduled run: %+v", record)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("scheduled profile did not run")
+	}
+
+	if _, err := service.TriggerProfile(context.Background(), "review"); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	if len(manual) != 1 || manual[0].Profile != "review" {
+		t.Fatalf("unexpected manual runs: %+v", manual)
+	}
+	if !prompts[PromptFor("DIGEST.md")] || !prompts[PromptFor("REVIEW.md")] || !strings.Contains(PromptFor("REVIEW.md"), "Read REVIEW.md") {
+		t.Fatalf("expected each profile's file in its prompt, got %v", prompts)
+	}
+	mu.Unlock()
+	if _, err := service.TriggerProfile(context.Background(), "evening"); !errors.Is(err, ErrUnknownProfile) {
+		t.Fatalf("expected ErrUnknownProfile, got %v", err)
+	}
+	names := make([]string, 0, 3)
+	for _, profile := range service.Profiles() {
+		names = append(names, profile.Name)
+	}
+	if strings.Join(names, ",") != "default,morning,review" {
+		t.Fatalf("unexpected profiles: %v", names)
+	}
+}
@@ -54,12 +54,16 @@ func NewEmbedder(cfg config.Config) Embedder {
 		if model == "" {
 			model = "text-embedding-3-small"
 		}
+		timeout := 30 * time.Second
+		if cfg.Memory.Embeddings.TimeoutSec > 0 {
+			timeout = time.Duration(cfg.Memory.Embeddings.TimeoutSec) * time.Second
+		}
 		return &openAIEmbedder{
 			provider: provider,
 			apiKey:   strings.TrimSpace(providerCfg.APIKey),
 			apiBase:  strings.TrimRight(apiBase, "/"),
 			model:    model,
-			client:   &http.Client{Timeout: 30 * time.Second},
+			client:   &http.Client{Timeout: timeout},
 		}
 	default:
 		return noopEmbedder{}
@@ -24,6 +24,8 @@ import (
 const (
 	defaultDailyRetentionDays = 90
 	maxChunkChars             = 900
+	defaultMaxOpenConns       = 4
+	sqliteBusyTimeoutMS       = 5000
 )
 
 type Manager struct {
@@ -38,7 +40,37 @@ type Manager struct {
 	embeddingsProvider string
 	embeddingsModel    string
 	embedder           Embedder
+	reranker           Reranker
+	embedBatchSize     int
+	embedConcurrency   int
+	embedTimeout       time.Duration
+	rollupEnabled      bool
+	rollupMaxDays      int
+	maxOpenConns       int
 	mu                 sync.Mutex
+
+	// embedMu guards the background embedding worker: whether a pass is
+	// running, whether another was requested meanwhile, and the last stats.
+	embedMu      sync.Mutex
+	embedRunning bool
+	embedAgain   bool
+	embedWG      sync.WaitGroup
+	lastEmbed    EmbedStats
+
+	// dbMu guards the pooled index connection, opened on first use and shared
+	// by all callers until Close.
+	dbMu       sync.Mutex
+	db         *sql.DB
+	ftsEnabled bool
+}
+
+// EmbedStats summarizes the most recent sync-time embedding pass. Chunks in
+// failed batches keep lexical-only scoring until a later sync succeeds.
+type EmbedStats struct {
+	Pending  int
+	Embedded int
+	Failed   int
+	Batches  int
 }
 
 type Chunk struct {
@@ -50,6 +82,20 @@ type Chunk struct {
 	Score   float64
 }
 
+// SearchResult is a Search hit with the parts of its score. Score is
+// Lexical + Recency + Semantic; Semantic stays zero unless the reranker
+// scored the chunk.
+type SearchResult struct {
+	Chunk
+	// Retrieval is "fts" for bm25-ranked hits or "like" for the substring
+	// fallback, which has no lexical score.
+	Retrieval string
+	Lexical   float64
+	Recency   float64
+	Semantic  float64
+	Reranked  bool
+}
+
 type DailyEntry struct {
 	Time      time.Time
 	Source    string
@@ -68,7 +114,9 @@ type sourceDoc struct {
 	updatedAt int64
 }
 
-func NewManager(cfg config.Config) *Manager {
+// NewManager builds a memory manager from cfg. Options such as WithReranker
+// wire components that config cannot name.
+func NewManager(cfg config.Config, opts ...Option) *Manager {
 	indexPath := strings.TrimSpace(cfg.Memory.IndexPath)
 	if indexPath == "" {
 		indexPath = filepath.Join(config.DataRoot(), "memory_index.db")
@@ -86,8 +134,24 @@ func NewManager(cfg config.Config) *Manager {
 	if recencyDays <= 0 {
 		recencyDays = 30
 	}
+	batchSize := cfg.Memory.Embeddings.BatchSize
+	if batchSize <= 0 {
+		batchSize = 32
+	}
+	concurrency := cfg.Memory.Embeddings.Concurrency
+	if concurrency <= 0 {
+		concurrency = 2
+	}
+	timeoutSec := cfg.Memory.Embeddings.TimeoutSec
+	if timeoutSec <= 0 {
+		timeoutSec = 30
+	}
+	maxOpenConns := cfg.Memory.MaxOpenConns
+	if maxOpenConns <= 0 {
+		maxOpenConns = defaultMaxOpenConns
+	}
 
-	return &Manager{
+	m := &Manager{
 		enabled:            cfg.Memory.Enabled,
 		workspace:          config.WorkspacePath(cfg),
 		indexPath:          filepath.Clean(indexPath),
@@ -99,7 +163,18 @@ func NewManager(cfg config.Config) *Manager {
 		embeddingsProvider: strings.TrimSpace(cfg.Memory.EmbeddingsProvider),
 		embeddingsModel:    strings.TrimSpace(cfg.Memory.EmbeddingsModel),
 		embedder:           NewEmbedder(cfg),
-	}
+		embedBatchSize:     batchSize,
+		embedConcurrency:   concurrency,
+		embedTimeout:       time.Duration(timeoutSec) * time.Second,
+		rollupEnabled:      cfg.Memory.DailyRollup.Enabled,
+		rollupMaxDays:      cfg.Memory.DailyRollup.MaxDaysPerRun,
+		maxOpenConns:       maxOpenConns,
+	}
+	m.reranker = rerankerByName(m, cfg.Memory.Semantic.Reranker)
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
 }
 
 func (m *Manager) Enabled() bool {
@@ -110,102 +185,37 @@ func (m *Manager) EnsureIndex(_ context.Context) error {
 	if !m.Enabled() {
 		return nil
 	}
-	db, _, err := m.openDB()
-	if err != nil {
-		return err
-	}
-	return db.Close()
+	_, _, err := m.openDB()
+	return err
 }
 
-func (m *Manager) Sync(_ context.Context) error {
+// Sync reindexes the workspace memory files. Embeddings for new or changed
+// chunks are computed afterwards on a background worker, so network calls
+// neither hold the manager lock nor delay the caller.
+func (m *Manager) Sync(ctx context.Context) error {
 	if !m.Enabled() {
 		return nil
 	}
 
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	return m.syncLocked(ctx)
+}
 
-	db, ftsEnabled, err := m.openDB()
-	if err != nil {
-		return err
-	}
-	defer db.Close()
-
-	sources, err := m.collectSources()
-	if err != nil {
-		return err
-	}
-
-	tx, err := db.Begin()
-	if err != nil {
-		return err
-	}
-	defer func() {
-		_ = tx.Rollback()
-	}()
-
-	seen := map[string]struct{}{}
-	for _, source := range sources {
-		chunks := chunkContent(source.content, maxChunkChars)
-		for idx, chunkText := range chunks {
-			chunkID := stableChunkID(source.relPath, idx, chunkText)
-			seen[chunkID] = struct{}{}
-			if _, err := tx.Exec(
-				`INSERT INTO chunks (id, path, kind, day, content, updated_at) VALUES (?, ?, ?, ?, ?, ?)
-				ON CONFLICT(id) DO UPDATE SET path=excluded.path, kind=excluded.kind, day=excluded.day, content=excluded.content, updated_at=excluded.updated_at`,
-				chunkID, source.path, source.kind, source.day, chunkText, source.updatedAt,
-			); err != nil {
-				return err
-			}
-			if ftsEnabled {
-				if _, err := tx.Exec(`DELETE FROM chunks_fts WHERE id = ?`, chunkID); err != nil {
-					return err
-				}
-				if _, err := tx.Exec(`INSERT INTO chunks_fts (id, path, kind, day, content) VALUES (?, ?, ?, ?, ?)`, chunkID, source.path, source.kind, source.day, chunkText); err != nil {
-					return err
-				}
-			}
-		}
-	}
-
-	rows, err := tx.Query(`SELECT id FROM chunks`)
-	if err != nil {
-		return err
-	}
-	defer rows.Close()
-
-	toDelete := make([]string, 0)
-	for rows.Next() {
-		var id string
-		if err := rows.Scan(&id); err != nil {
-			return err
-		}
-		if _, ok := seen[id]; !ok {
-			toDelete = append(toDelete, id)
-		}
-	}
-	if err := rows.Err(); err != nil {
-		return err
+func (m *Manager) Search(ctx context.Context, query string, limit int) ([]Chunk, error) {
+	results, err := m.SearchExplain(ctx, query, limit)
+	if err != nil || results == nil {
+		return nil, err
 	}
-
-	for _, id := range toDelete {
-		if _, err := tx.Exec(`DELETE FROM chunks WHERE id = ?`, id); err != nil {
-			return err
-		}
-		if ftsEnabled {
-			if _, err := tx.Exec(`DELETE FROM chunks_fts WHERE id = ?`, id); err != nil {
-				return err
-			}
-		}
-		if _, err := tx.Exec(`DELETE FROM embeddings WHERE chunk_id = ?`, id); err != nil {
-			return err
-		}
+	chunks := make([]Chunk, len(results))
+	for idx, result := range results {
+		chunks[idx] = result.Chunk
 	}
-
-	return tx.Commit()
+	return chunks, nil
 }
 
-func (m *Manager) Search(ctx context.Context, query string, limit int) ([]Chunk, error) {
+// SearchExplain runs Search and keeps each result's score components.
+func (m *Manager) SearchExplain(ctx context.Context, query string, limit int) ([]SearchResult, error) {
 	if !m.Enabled() {
 		return nil, nil
 	}
@@ -222,23 +232,28 @@ func (m *Manager) Search(ctx context.Context, query string, limit int) ([]Chunk,
 	if err != nil {
 		return nil, err
 	}
-	defer db.Close()
 
 	candidateLimit := limit * 3
 	if m.semanticEnabled && m.semanticCandidates > candidateLimit {
 		candidateLimit = m.semanticCandidates
 	}
-	results, err := m.searchFTS(db, query, candidateLimit)
-	if err != nil || len(results) == 0 {
-		results, err = m.searchLike(db, query, candidateLimit)
+	retrieval := "fts"
+	chunks, err := m.searchFTS(db, query, candidateLimit)
+	if err != nil || len(chunks) == 0 {
+		retrieval = "like"
+		chunks, err = m.searchLike(db, query, candidateLimit)
 		if err != nil {
 			return nil, err
 		}
 	}
+	results := make([]SearchResult, len(chunks))
+	for idx, chunk := range chunks {
+		results[idx] = SearchResult{Chunk: chunk, Retrieval: retrieval, Lexical: chunk.Score}
+	}
 
 	m.applyHybridScore(results)
 	if m.semanticEnabled {
-		if err := m.applySemanticRerank(ctx, db, query, results); err != nil {
+		if err := m.applySemanticRerank(ctx, query, results); err != nil {
 			// Semantic reranking is best-effort and must not block lexical retrieval.
 		}
 	}
@@ -258,21 +273,29 @@ func (m *Manager) Search(ctx context.Context, query string, limit int) ([]Chunk,
 	return results, nil
 }
 
-func (m *Manager) RecentDaily(_ context.Context, limit int) ([]Chunk, error) {
+func (m *Manager) RecentDaily(ctx context.Context, limit int) ([]Chunk, error) {
+	return m.RecentDailySince(ctx, m.recencyDays+1, limit)
+}
+
+// RecentDailySince returns up to limit daily log chunks from the last days
+// days (today counts as the first), newest first.
+func (m *Manager) RecentDailySince(_ context.Context, days, limit int) ([]Chunk, error) {
 	if !m.Enabled() {
 		return nil, nil
 	}
 	if limit <= 0 {
 		limit = 4
 	}
+	if days <= 0 {
+		days = 1
+	}
 
 	db, _, err := m.openDB()
 	if err != nil {
 		return nil, err
 	}
-	defer db.Close()
 
-	cutoff := time.Now().UTC().AddDate(0, 0, -m.recencyDays).Format("2006-01-02")
+	cutoff := time.Now().UTC().AddDate(0, 0, 1-days).Format("2006-01-02")
 	rows, err := db.Query(`SELECT id, path, kind, day, content FROM chunks WHERE kind = 'daily' AND day >= ? ORDER BY day DESC, updated_at DESC LIMIT ?`, cutoff, limit)
 	if err != nil {
 		return nil, err
@@ -369,12 +392,117 @@ func (m *Manager) PruneDaily(_ context.Context, keepDays int) error {
 	return m.pruneDailyLocked(keepDays)
 }
 
-func (m *Manager) syncLocked(_ context.Context) error {
+type IndexHealth struct {
+	Path      string
+	Exists    bool
+	Integrity string
+	Chunks    int
+	FTSRows   int
+	FTS       bool
+	Problems  []string
+}
+
+func (h IndexHealth) OK() bool {
+	return len(h.Problems) == 0
+}
+
+// CheckIndex runs SQLite and FTS integrity checks plus a probe query against
+// the index without modifying it.
+func (m *Manager) CheckIndex(ctx context.Context) (IndexHealth, error) {
+	health := IndexHealth{Path: m.indexPath}
+	if !m.Enabled() {
+		return health, nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := os.Stat(m.indexPath); err != nil {
+		if os.IsNotExist(err) {
+			health.Problems = append(health.Problems, "memory index not built yet")
+			return health, nil
+		}
+		return health, err
+	}
+	health.Exists = true
+	m.checkpointPool(ctx)
+
+	db, err := sql.Open("sqlite", m.indexPath)
+	if err != nil {
+		return health, err
+	}
+	defer db.Close()
+
+	rows, err := db.QueryContext(ctx, `PRAGMA integrity_check`)
+	if err != nil {
+		health.Problems = append(health.Problems, "integrity check failed: "+err.Error())
+		return health, nil
+	}
+	results := make([]string, 0, 1)
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			_ = rows.Close()
+			return health, err
+		}
+		results = append(results, line)
+	}
+	rowsErr := rows.Err()
+	_ = rows.Close()
+	if rowsErr != nil {
+		health.Problems = append(health.Problems, "integrity check failed: "+rowsErr.Error())
+		return health, nil
+	}
+	health.Integrity = strings.Join(results, "; ")
+	if health.Integrity != "ok" {
+		health.Problems = append(health.Problems, "integrity check reported: "+health.Integrity)
+	}
+
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM chunks`).Scan(&health.Chunks); err != nil {
+		health.Problems = append(health.Problems, "chunks table unreadable: "+err.Error())
+	}
+	var ftsTables int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE name = 'chunks_fts'`).Scan(&ftsTables); err == nil && ftsTables > 0 {
+		health.FTS = true
+		if _, err := db.ExecContext(ctx, `INSERT INTO chunks_fts(chunks_fts) VALUES('integrity-check')`); err != nil {
+			health.Problems = append(health.Problems, "fts integrity check failed: "+err.Error())
+		}
+		if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM chunks_fts`).Scan(&health.FTSRows); err != nil {
+			health.Problems = append(health.Problems, "fts table unreadable: "+err.Error())
+		} else if health.FTSRows != health.Chunks {
+			health.Problems = append(health.Problems, fmt.Sprintf("fts rows (%d) do not match chunks (%d)", health.FTSRows, health.Chunks))
+		}
+		var probe string
+		err := db.QueryRowContext(ctx, `SELECT id FROM chunks_fts WHERE chunks_fts MATCH ? LIMIT 1`, `"memory"`).Scan(&probe)
+		if err != nil && err != sql.ErrNoRows {
+			health.Problems = append(health.Problems, "fts probe query failed: "+err.Error())
+		}
+	}
+	return health, nil
+}
+
+// RebuildIndex drops the index database and re-indexes workspace sources.
+func (m *Manager) RebuildIndex(ctx context.Context) error {
+	if !m.Enabled() {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// The index is discarded, so a close error from a damaged file is moot.
+	_ = m.Close()
+	for _, path := range []string{m.indexPath, m.indexPath + "-wal", m.indexPath + "-shm"} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return m.syncLocked(ctx)
+}
+
+func (m *Manager) syncLocked(ctx context.Context) error {
 	db, ftsEnabled, err := m.openDB()
 	if err != nil {
 		return err
 	}
-	defer db.Close()
 
 	sources, err := m.collectSources()
 	if err != nil {
@@ -445,7 +573,11 @@ func (m *Manager) syncLocked(_ context.Context) error {
 		}
 	}
 
-	return tx.Commit()
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	m.scheduleEmbed()
+	return nil
 }
 
 func (m *Manager) pruneDailyLocked(keepDays int) error {
@@ -461,6 +593,10 @@ func (m *Manager) pruneDailyLocked(keepDays int) error {
 		return err
 	}
 	cutoff := time.Now().UTC().AddDate(0, 0, -keepDays)
+	digested := map[string]bool{}
+	if m.rollupEnabled {
+		digested = m.digestedDays()
+	}
 	for _, entry := range entries {
 		if entry.IsDir() {
 			continue
@@ -475,20 +611,35 @@ func (m *Manager) pruneDailyLocked(keepDays int) error {
 			continue
 		}
 		if parsed.Before(cutoff) {
-			_ = os.Remove(filepath.Join(dailyDir, name))
+			path := filepath.Join(dailyDir, name)
+			// Keep logs that still await a digest so rollup never loses a day.
+			if m.rollupEnabled && !digested[day] && dailyHasEntries(path) {
+				continue
+			}
+			_ = os.Remove(path)
 		}
 	}
 	return nil
 }
 
+// openDB returns the pooled index connection, opening it on first use.
+// Callers must not close it; Close releases it on shutdown.
 func (m *Manager) openDB() (*sql.DB, bool, error) {
+	m.dbMu.Lock()
+	defer m.dbMu.Unlock()
+	if m.db != nil {
+		return m.db, m.ftsEnabled, nil
+	}
 	if err := os.MkdirAll(filepath.Dir(m.indexPath), 0o755); err != nil {
 		return nil, false, err
 	}
-	db, err := sql.Open("sqlite", m.indexPath)
+	dsn := fmt.Sprintf("%s?_pragma=busy_timeout(%d)", m.indexPath, sqliteBusyTimeoutMS)
+	db, err := sql.Open("sqlite", dsn)
 	if err != nil {
 		return nil, false, err
 	}
+	db.SetMaxOpenConns(m.maxOpenConns)
+	db.SetMaxIdleConns(m.maxOpenConns)
 	if _, err := db.Exec(`PRAGMA journal_mode = WAL;`); err != nil {
 		_ = db.Close()
 		return nil, false, err
@@ -498,9 +649,39 @@ func (m *Manager) openDB() (*sql.DB, bool, error) {
 		_ = db.Close()
 		return nil, false, err
 	}
+	m.db = db
+	m.ftsEnabled = ftsEnabled
 	return db, ftsEnabled, nil
 }
 
+// checkpointPool flushes WAL frames held by the pooled connection into the
+// main database file so an independent connection sees current contents.
+func (m *Manager) checkpointPool(ctx context.Context) {
+	m.dbMu.Lock()
+	db := m.db
+	m.dbMu.Unlock()
+	if db != nil {
+		_, _ = db.ExecContext(ctx, `PRAGMA wal_checkpoint(TRUNCATE)`)
+	}
+}
+
+// Close releases the pooled index connection. The manager reopens it on the
+// next call, so Close is safe to call more than once.
+func (m *Manager) Close() error {
+	if m == nil {
+		return nil
+	}
+	m.embedWG.Wait()
+	m.dbMu.Lock()
+	defer m.dbMu.Unlock()
+	if m.db == nil {
+		return nil
+	}
+	err := m.db.Close()
+	m.db = nil
+	return err
+}
+
 func ensureSchema(db *sql.DB) (bool, error) {
 	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS chunks (
 		id TEXT PRIMARY KEY,
@@ -648,46 +829,217 @@ func (m *Manager) searchLike(db *sql.DB, query string, limit int) ([]Chunk, erro
 	return out, nil
 }
 
-func (m *Manager) applyHybridScore(chunks []Chunk) {
+func (m *Manager) applyHybridScore(results []SearchResult) {
 	now := time.Now().UTC()
-	for idx := range chunks {
-		lexical := chunks[idx].Score
+	for idx := range results {
 		recencyBoost := 0.0
-		if chunks[idx].Day != "" {
-			if day, err := time.Parse("2006-01-02", chunks[idx].Day); err == nil {
+		if results[idx].Day != "" {
+			if day, err := time.Parse("2006-01-02", results[idx].Day); err == nil {
 				ageDays := int(now.Sub(day).Hours() / 24)
 				if ageDays >= 0 && ageDays <= m.recencyDays {
 					recencyBoost = 0.35
 				}
 			}
 		}
-		chunks[idx].Score = lexical + recencyBoost
+		results[idx].Recency = recencyBoost
+		results[idx].Score = results[idx].Lexical + recencyBoost
 	}
 }
 
-func (m *Manager) applySemanticRerank(ctx context.Context, db *sql.DB, query string, chunks []Chunk) error {
-	if len(chunks) == 0 || m.embedder == nil {
+// applySemanticRerank adds the reranker's score to each chunk it scored. On
+// error nothing is applied and results keep their lexical order.
+func (m *Manager) applySemanticRerank(ctx context.Context, query string, results []SearchResult) error {
+	if len(results) == 0 || m.reranker == nil {
 		return nil
 	}
-	if strings.EqualFold(strings.TrimSpace(m.embedder.Provider()), "none") {
-		return nil
+	chunks := make([]Chunk, len(results))
+	for idx, result := range results {
+		chunks[idx] = result.Chunk
 	}
-	queryVectors, err := m.embedder.Embed(ctx, []string{query})
-	if err != nil || len(queryVectors) == 0 || len(queryVectors[0]) == 0 {
+	scored, err := m.reranker.Rerank(ctx, query, chunks)
+	if err != nil {
 		return err
 	}
-	queryVector := queryVectors[0]
-	for idx := range chunks {
-		chunkVector, vectorErr := m.getOrCreateEmbedding(ctx, db, chunks[idx].ID, chunks[idx].Content)
-		if vectorErr != nil || len(chunkVector) == 0 {
+	scores := make(map[string]float64, len(scored))
+	for _, chunk := range scored {
+		scores[chunk.ID] = chunk.Score
+	}
+	for idx := range results {
+		score, ok := scores[results[idx].ID]
+		if !ok {
 			continue
 		}
-		sim := cosineSimilarity(queryVector, chunkVector)
-		chunks[idx].Score += sim
+		results[idx].Semantic = score
+		results[idx].Reranked = true
+		results[idx].Score += score
 	}
 	return nil
 }
 
+func (m *Manager) LastEmbedStats() EmbedStats {
+	if m == nil {
+		return EmbedStats{}
+	}
+	m.embedMu.Lock()
+	defer m.embedMu.Unlock()
+	return m.lastEmbed
+}
+
+// scheduleEmbed starts a background embedding pass, or queues one more pass
+// when one is already running so chunks indexed meanwhile are not missed.
+func (m *Manager) scheduleEmbed() {
+	m.embedMu.Lock()
+	if m.embedRunning {
+		m.embedAgain = true
+		m.embedMu.Unlock()
+		return
+	}
+	m.embedRunning = true
+	m.embedWG.Add(1)
+	m.embedMu.Unlock()
+	go func() {
+		defer m.embedWG.Done()
+		for {
+			m.runEmbedPass()
+			m.embedMu.Lock()
+			if !m.embedAgain {
+				m.embedRunning = false
+				m.embedMu.Unlock()
+				return
+			}
+			m.embedAgain = false
+			m.embedMu.Unlock()
+		}
+	}()
+}
+
+func (m *Manager) runEmbedPass() {
+	db, _, err := m.openDB()
+	if err != nil {
+		return
+	}
+	stats, _ := m.embedPending(context.Background(), db)
+	m.embedMu.Lock()
+	m.lastEmbed = stats
+	m.embedMu.Unlock()
+}
+
+// waitEmbeddings blocks until no background embedding pass is running.
+func (m *Manager) waitEmbeddings() {
+	m.embedWG.Wait()
+}
+
+type pendingEmbedding struct {
+	chunkID  string
+	content  string
+	checksum string
+	vector   []float32
+}
+
+// embedPending embeds chunks whose stored vector is missing or stale, in
+// bounded-concurrency batches with a per-batch timeout. Batch failures are
+// counted and skipped rather than failing the pass.
+func (m *Manager) embedPending(ctx context.Context, db *sql.DB) (EmbedStats, error) {
+	if !m.semanticEnabled || m.embedder == nil || strings.EqualFold(strings.TrimSpace(m.embedder.Provider()), "none") {
+		return EmbedStats{}, nil
+	}
+	providerName := strings.TrimSpace(m.embedder.Provider())
+	modelName := strings.TrimSpace(m.embedder.Model())
+
+	rows, err := db.QueryContext(ctx, `SELECT c.id, c.content, COALESCE(e.provider, ''), COALESCE(e.model, ''), COALESCE(e.checksum, '')
+		FROM chunks c LEFT JOIN embeddings e ON e.chunk_id = c.id`)
+	if err != nil {
+		return EmbedStats{}, err
+	}
+	pending := make([]pendingEmbedding, 0)
+	for rows.Next() {
+		var item pendingEmbedding
+		var storedProvider, storedModel, storedChecksum string
+		if err := rows.Scan(&item.chunkID, &item.content, &storedProvider, &storedModel, &storedChecksum); err != nil {
+			_ = rows.Close()
+			return EmbedStats{}, err
+		}
+		item.checksum = checksumText(item.content)
+		if storedProvider == providerName && storedModel == modelName && storedChecksum == item.checksum {
+			continue
+		}
+		pending = append(pending, item)
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return EmbedStats{}, err
+	}
+	_ = rows.Close()
+
+	stats := EmbedStats{Pending: len(pending)}
+	if len(pending) == 0 {
+		return stats, nil
+	}
+
+	batches := make([][]pendingEmbedding, 0, len(pending)/m.embedBatchSize+1)
+	for start := 0; start < len(pending); start += m.embedBatchSize {
+		end := min(start+m.embedBatchSize, len(pending))
+		batches = append(batches, pending[start:end])
+	}
+	stats.Batches = len(batches)
+
+	var wg sync.WaitGroup
+	var statsMu sync.Mutex
+	sem := make(chan struct{}, m.embedConcurrency)
+	for _, batch := range batches {
+		if ctx.Err() != nil {
+			statsMu.Lock()
+			stats.Failed += len(batch)
+			statsMu.Unlock()
+			continue
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(batch []pendingEmbedding) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			batchCtx, cancel := context.WithTimeout(ctx, m.embedTimeout)
+			defer cancel()
+			texts := make([]string, len(batch))
+			for idx := range batch {
+				texts[idx] = batch[idx].content
+			}
+			vectors, err := m.embedder.Embed(batchCtx, texts)
+			statsMu.Lock()
+			defer statsMu.Unlock()
+			if err != nil {
+				stats.Failed += len(batch)
+				return
+			}
+			for idx := range batch {
+				if idx < len(vectors) && len(vectors[idx]) > 0 {
+					batch[idx].vector = vectors[idx]
+				} else {
+					stats.Failed++
+				}
+			}
+		}(batch)
+	}
+	wg.Wait()
+
+	now := time.Now().UTC().Unix()
+	for _, item := range pending {
+		if len(item.vector) == 0 {
+			continue
+		}
+		if _, err := db.ExecContext(
+			context.WithoutCancel(ctx),
+			`INSERT INTO embeddings (chunk_id, provider, model, checksum, vector, updated_at) VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT(chunk_id) DO UPDATE SET provider=excluded.provider, model=excluded.model, checksum=excluded.checksum, vector=excluded.vector, updated_at=excluded.updated_at`,
+			item.chunkID, providerName, modelName, item.checksum, encodeVector(item.vector), now,
+		); err != nil {
+			return stats, err
+		}
+		stats.Embedded++
+	}
+	return stats, nil
+}
+
 func (m *Manager) getOrCreateEmbedding(ctx context.Context, db *sql.DB, chunkID, content string) ([]float32, error) {
 	checksum := checksumText(content)
 	providerName := strings.TrimSpace(m.embedder.Provider())
@@ -1,11 +1,16 @@
 package memory
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
 	"context"
 	"database/sql"
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -94,6 +99,77 @@ func TestSearchFallbackWhenEmbeddingsDisabled(t *testing.T) {
 	}
 }
 
+type keywordReranker struct {
+	keyword string
+	err     error
+}
+
+func (r keywordReranker) Rerank(ctx context.Context, query string, chunks []Chunk) ([]Chunk, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	scored := make([]Chunk, 0, len(chunks))
+	for _, chunk := range chunks {
+		if strings.Contains(chunk.Content, r.keyword) {
+			chunk.Score = 10
+			scored = append(scored, chunk)
+		}
+	}
+	return scored, nil
+}
+
+func TestSearchUsesCustomRerankerAndFallsBackOnError(t *testing.T) {
+	workspace := t.TempDir()
+	memoryDir := filepath.Join(workspace, "memory")
+	if err := os.MkdirAll(memoryDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	content := strings.Repeat("alpha plans plans plans ", 30) + "\n\n" + strings.Repeat("beta plans ", 60)
+	if err := os.WriteFile(filepath.Join(memoryDir, "MEMORY.md"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.Default()
+	cfg.Agents.Defaults.Workspace = workspace
+	cfg.Memory.IndexPath = filepath.Join(t.TempDir(), "memory_index.db")
+	cfg.Memory.EmbeddingsProvider = "none"
+	cfg.Memory.Semantic.Enabled = true
+
+	search := func(reranker Reranker) []SearchResult {
+		t.Helper()
+		mgr := NewManager(cfg, WithReranker(reranker))
+		defer mgr.Close()
+		if err := mgr.Sync(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		results, err := mgr.SearchExplain(context.Background(), "plans", 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) < 2 {
+			t.Fatalf("expected both chunks, got %d", len(results))
+		}
+		return results
+	}
+
+	lexical := search(keywordReranker{err: errors.New("cross-encoder offline")})
+	if strings.Contains(lexical[0].Content, "beta") {
+		t.Fatalf("expected the alpha chunk to lead lexically, got %q", lexical[0].Content[:20])
+	}
+	for _, result := range lexical {
+		if result.Reranked || result.Semantic != 0 {
+			t.Fatalf("expected no rerank scores after an error, got %+v", result)
+		}
+	}
+
+	reranked := search(keywordReranker{keyword: "beta"})
+	if !strings.Contains(reranked[0].Content, "beta") || !reranked[0].Reranked || reranked[0].Semantic != 10 {
+		t.Fatalf("expected the reranker to promote the beta chunk, got %+v", reranked[0])
+	}
+	if reranked[1].Reranked {
+		t.Fatal("expected chunks the reranker skipped to keep lexical scoring")
+	}
+}
+
 func TestAppendDailyLogFormat(t *testing.T) {
 	workspace := t.TempDir()
 	if err := os.MkdirAll(filepath.Join(workspace, "memory"), 0o755); err != nil {
@@ -153,3 +229,355 @@ func countChunks(t *testing.T, indexPath string) int {
 	}
 	return count
 }
+
+func TestCheckIndexDetectsCorruptionAndRebuildRecovers(t *testing.T) {
+	workspace := t.TempDir()
+	memoryDir := filepath.Join(workspace, "memory")
+	if err := os.MkdirAll(memoryDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(memoryDir, "MEMORY.md"), []byte("# Memory\nThe squid keeps a tidy memory."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.Default()
+	cfg.Agents.Defaults.Workspace = workspace
+	cfg.Memory.IndexPath = filepath.Join(t.TempDir(), "memory_index.db")
+	cfg.Memory.EmbeddingsProvider = "none"
+
+	mgr := NewManager(cfg)
+	if err := mgr.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	health, err := mgr.CheckIndex(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !health.OK() || health.Integrity != "ok" || health.Chunks == 0 {
+		t.Fatalf("expected healthy index, got %+v", health)
+	}
+
+	if err := os.WriteFile(cfg.Memory.IndexPath, []byte(strings.Repeat("corrupt", 1024)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	health, err = mgr.CheckIndex(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if health.OK() {
+		t.Fatalf("expected corruption to be reported, got %+v", health)
+	}
+
+	if err := mgr.RebuildIndex(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	health, err = mgr.CheckIndex(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !health.OK() || health.Chunks == 0 {
+		t.Fatalf("expected rebuilt index to be healthy, got %+v", health)
+	}
+	results, err := mgr.Search(context.Background(), "tidy", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) == 0 {
+		t.Fatal("expected search results after rebuild")
+	}
+}
+
+type flakyEmbedder struct{}
+
+func (e *flakyEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, len(texts))
+	for idx, text := range texts {
+		if strings.Contains(text, "flaky") {
+			return nil, errors.New("backend unavailable")
+		}
+		out[idx] = []float32{1, float32(len(text))}
+	}
+	return out, nil
+}
+
+func (e *flakyEmbedder) Provider() string { return "fake" }
+func (e *flakyEmbedder) Model() string    { return "fake-embed" }
+
+func TestSyncEmbedsInBatchesAndToleratesFailures(t *testing.T) {
+	workspace := t.TempDir()
+	memoryDir := filepath.Join(workspace, "memory")
+	if err := os.MkdirAll(memoryDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	paragraphs := []string{}
+	for _, word := range []string{"alpha", "beta", "flaky", "gamma"} {
+		paragraphs = append(paragraphs, strings.Repeat(word+" notes ", 60))
+	}
+	content := strings.Join(paragraphs, "\n\n")
+	if err := os.WriteFile(filepath.Join(memoryDir, "MEMORY.md"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.Default()
+	cfg.Agents.Defaults.Workspace = workspace
+	cfg.Memory.IndexPath = filepath.Join(t.TempDir(), "memory_index.db")
+	cfg.Memory.Semantic.Enabled = true
+	cfg.Memory.Embeddings.BatchSize = 1
+	cfg.Memory.Embeddings.Concurrency = 2
+
+	mgr := NewManager(cfg)
+	mgr.embedder = &flakyEmbedder{}
+	if err := mgr.Sync(context.Background()); err != nil {
+		t.Fatalf("sync should tolerate embedding failures: %v", err)
+	}
+	mgr.waitEmbeddings()
+	stats := mgr.LastEmbedStats()
+	if stats.Batches != stats.Pending || stats.Failed == 0 || stats.Embedded == 0 {
+		t.Fatalf("unexpected embed stats: %+v", stats)
+	}
+	if stats.Embedded+stats.Failed != stats.Pending {
+		t.Fatalf("embedded+failed should cover pending: %+v", stats)
+	}
+
+	if err := mgr.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	mgr.waitEmbeddings()
+	if again := mgr.LastEmbedStats(); again.Pending != stats.Failed {
+		t.Fatalf("expected only failed chunks to be retried, got %+v", again)
+	}
+}
+
+// blockingEmbedder holds every Embed call until release is closed.
+type blockingEmbedder struct {
+	started chan struct{}
+	release chan struct{}
+	once    sync.Once
+}
+
+func (e *blockingEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	e.once.Do(func() { close(e.started) })
+	<-e.release
+	out := make([][]float32, len(texts))
+	for idx := range texts {
+		out[idx] = []float32{1, 0}
+	}
+	return out, nil
+}
+
+func (e *blockingEmbedder) Provider() string { return "fake" }
+func (e *blockingEmbedder) Model() string    { return "fake-embed" }
+
+func TestSyncEmbedsInBackgroundWithoutHoldingTheLock(t *testing.T) {
+	workspace := t.TempDir()
+	memoryDir := filepath.Join(workspace, "memory")
+	if err := os.MkdirAll(memoryDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(memoryDir, "MEMORY.md"), []byte("# Memory\n\nUser prefers tea."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.Default()
+	cfg.Agents.Defaults.Workspace = workspace
+	cfg.Memory.IndexPath = filepath.Join(t.TempDir(), "memory_index.db")
+	cfg.Memory.Semantic.Enabled = true
+
+	mgr := NewManager(cfg)
+	embedder := &blockingEmbedder{started: make(chan struct{}), release: make(chan struct{})}
+	mgr.embedder = embedder
+	if err := mgr.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-embedder.started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected a background embedding pass")
+	}
+	// The pass is still blocked, yet another sync goes through.
+	if err := mgr.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	close(embedder.release)
+	mgr.waitEmbeddings()
+	if stats := mgr.LastEmbedStats(); stats.Failed != 0 {
+		t.Fatalf("unexpected embed stats: %+v", stats)
+	}
+	if err := mgr.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRollupDailyDigestsCompletedDaysAndProtectsThemFromPruning(t *testing.T) {
+	workspace := t.TempDir()
+	dailyDir := filepath.Join(workspace, "memory", "daily")
+	if err := os.MkdirAll(dailyDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().UTC().AddDate(0, 0, -200).Format("2006-01-02")
+	yesterday := time.Now().UTC().AddDate(0, 0, -1).Format("2006-01-02")
+	today := time.Now().UTC().Format("2006-01-02")
+	for _, day := range []string{old, yesterday, today} {
+		content := "# " + day + "\n\n## 10:00:00Z [conversation]\n- Intent: plan release " + day + "\n"
+		if err := os.WriteFile(filepath.Join(dailyDir, day+".md"), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := config.Default()
+	cfg.Agents.Defaults.Workspace = workspace
+	cfg.Memory.IndexPath = filepath.Join(t.TempDir(), "memory_index.db")
+	cfg.Memory.EmbeddingsProvider = "none"
+	cfg.Memory.DailyRollup.Enabled = true
+	cfg.Memory.DailyRollup.MaxDaysPerRun = 1
+	mgr := NewManager(cfg)
+
+	if err := mgr.PruneDaily(context.Background(), 90); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dailyDir, old+".md")); err != nil {
+		t.Fatalf("expected undigested old log to survive pruning: %v", err)
+	}
+
+	var summarized []string
+	summarize := func(_ context.Context, day, content string) (string, error) {
+		if !strings.Contains(content, "plan release "+day) {
+			t.Fatalf("unexpected content for %s: %q", day, content)
+		}
+		summarized = append(summarized, day)
+		return "- Planned the release", nil
+	}
+	result, err := mgr.RollupDaily(context.Background(), summarize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Days) != 1 || result.Days[0] != old || result.Pending != 1 {
+		t.Fatalf("expected oldest day first with one pending, got %+v", result)
+	}
+	result, err = mgr.RollupDaily(context.Background(), summarize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Days) != 1 || result.Days[0] != yesterday || result.Pending != 0 {
+		t.Fatalf("expected yesterday digested next, got %+v", result)
+	}
+	if len(summarized) != 2 {
+		t.Fatalf("expected today to be skipped, summarized %v", summarized)
+	}
+
+	raw, err := os.ReadFile(filepath.Join(workspace, "memory", "MEMORY.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, day := range []string{old, yesterday} {
+		if !strings.Contains(string(raw), "## Daily digest "+day+"\n\n- Planned the release") {
+			t.Fatalf("missing digest for %s in MEMORY.md:\n%s", day, raw)
+		}
+	}
+
+	if err := mgr.PruneDaily(context.Background(), 90); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dailyDir, old+".md")); !os.IsNotExist(err) {
+		t.Fatalf("expected digested old log to be pruned, stat err=%v", err)
+	}
+}
+
+func TestSearchReusesPooledConnectionUntilClose(t *testing.T) {
+	workspace := t.TempDir()
+	memoryDir := filepath.Join(workspace, "memory")
+	if err := os.MkdirAll(memoryDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(memoryDir, "MEMORY.md"), []byte("# Memory\nsquid pool notes"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.Default()
+	cfg.Agents.Defaults.Workspace = workspace
+	cfg.Memory.IndexPath = filepath.Join(t.TempDir(), "memory_index.db")
+	cfg.Memory.EmbeddingsProvider = "none"
+	cfg.Memory.MaxOpenConns = 2
+
+	mgr := NewManager(cfg)
+	defer mgr.Close()
+	if err := mgr.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	pooled := mgr.db
+	if pooled == nil {
+		t.Fatal("expected sync to open the pooled connection")
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results, err := mgr.Search(context.Background(), "squid", 3)
+			if err == nil && len(results) == 0 {
+				err = errors.New("expected search results")
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if mgr.db != pooled {
+		t.Fatal("expected concurrent searches to reuse the pooled connection")
+	}
+	if got := pooled.Stats().MaxOpenConnections; got != 2 {
+		t.Fatalf("expected max open conns 2, got %d", got)
+	}
+
+	if err := mgr.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := mgr.Close(); err != nil {
+		t.Fatalf("expected repeated Close to be a no-op, got %v", err)
+	}
+	if _, err := mgr.Search(context.Background(), "squid", 3); err != nil {
+		t.Fatalf("expected search to reopen after Close: %v", err)
+	}
+	if err := mgr.RebuildIndex(context.Background()); err != nil {
+		t.Fatalf("rebuild with pooled connection failed: %v", err)
+	}
+}
+
+func TestImportRejectsEntriesOutsideMemoryTree(t *testing.T) {
+	workspace := t.TempDir()
+	cfg := config.Default()
+	cfg.Agents.Defaults.Workspace = workspace
+	cfg.Memory.IndexPath = filepath.Join(t.TempDir(), "memory_index.db")
+	mgr := NewManager(cfg)
+	defer mgr.Close()
+
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gz)
+	for name, content := range map[string]string{"manifest.json": `{"version":1}`, "memory/../escape.md": "pwned"} {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := mgr.Import(context.Background(), &archive, false); err == nil || !strings.Contains(err.Error(), "unexpected memory archive entry") {
+		t.Fatalf("expected escaping entry to be rejected, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(workspace, "escape.md")); !os.IsNotExist(err) {
+		t.Fatalf("expected no file written outside memory/, got %v", err)
+	}
+}
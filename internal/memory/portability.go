@@ -0,0 +1,358 @@
+package memory
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grixate/squidbot/internal/config"
+)
+
+const (
+	exportVersion       = 1
+	exportManifestName  = "manifest.json"
+	exportChunksName    = "chunks.jsonl"
+	exportMemoryDirName = "memory"
+)
+
+// ErrWorkspaceNotEmpty is returned by Import when the workspace already holds
+// memory and force was not set.
+var ErrWorkspaceNotEmpty = errors.New("workspace memory is not empty")
+
+// ExportManifest describes a memory archive written by Export.
+type ExportManifest struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	Files      int       `json:"files"`
+	Chunks     int       `json:"chunks"`
+}
+
+// ExportedChunk is one row of the chunks table. Path is relative to the
+// workspace so an archive restores into a workspace at any location.
+type ExportedChunk struct {
+	ID        string `json:"id"`
+	Path      string `json:"path"`
+	Kind      string `json:"kind"`
+	Day       string `json:"day,omitempty"`
+	Content   string `json:"content"`
+	UpdatedAt int64  `json:"updated_at"`
+}
+
+type ImportResult struct {
+	Files  int
+	Chunks int
+}
+
+// HasMemory reports whether the workspace holds memory worth protecting: any
+// daily log, or a curated MEMORY.md that differs from the onboarding template.
+func (m *Manager) HasMemory() (bool, error) {
+	memoryDir := filepath.Join(m.workspace, exportMemoryDirName)
+	found := false
+	err := filepath.WalkDir(memoryDir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == memoryDir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		raw, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		content := strings.TrimSpace(string(raw))
+		if content == "" {
+			return nil
+		}
+		if p == filepath.Join(memoryDir, "MEMORY.md") && content == strings.TrimSpace(config.MemoryTemplate) {
+			return nil
+		}
+		found = true
+		return filepath.SkipAll
+	})
+	return found, err
+}
+
+// Export writes a gzipped tar of the workspace memory/ tree plus a dump of
+// the chunks table to w.
+func (m *Manager) Export(ctx context.Context, w io.Writer) (ExportManifest, error) {
+	files, err := m.memoryFiles()
+	if err != nil {
+		return ExportManifest{}, err
+	}
+	chunks, err := m.dumpChunks(ctx)
+	if err != nil {
+		return ExportManifest{}, err
+	}
+	manifest := ExportManifest{Version: exportVersion, ExportedAt: time.Now().UTC(), Files: len(files), Chunks: len(chunks)}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	writeEntry := func(name string, mode int64, modTime time.Time, content []byte) error {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: mode, Size: int64(len(content)), ModTime: modTime}); err != nil {
+			return err
+		}
+		_, err := tw.Write(content)
+		return err
+	}
+	rawManifest, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return ExportManifest{}, err
+	}
+	if err := writeEntry(exportManifestName, 0o644, manifest.ExportedAt, append(rawManifest, '\n')); err != nil {
+		return ExportManifest{}, err
+	}
+	for _, rel := range files {
+		full := filepath.Join(m.workspace, filepath.FromSlash(rel))
+		info, err := os.Stat(full)
+		if err != nil {
+			return ExportManifest{}, err
+		}
+		content, err := os.ReadFile(full)
+		if err != nil {
+			return ExportManifest{}, err
+		}
+		if err := writeEntry(rel, int64(info.Mode().Perm()), info.ModTime(), content); err != nil {
+			return ExportManifest{}, err
+		}
+	}
+	var dump strings.Builder
+	for _, chunk := range chunks {
+		raw, err := json.Marshal(chunk)
+		if err != nil {
+			return ExportManifest{}, err
+		}
+		dump.Write(raw)
+		dump.WriteByte('\n')
+	}
+	if err := writeEntry(exportChunksName, 0o644, manifest.ExportedAt, []byte(dump.String())); err != nil {
+		return ExportManifest{}, err
+	}
+	if err := tw.Close(); err != nil {
+		return ExportManifest{}, err
+	}
+	if err := gz.Close(); err != nil {
+		return ExportManifest{}, err
+	}
+	return manifest, nil
+}
+
+// Import restores an archive written by Export: memory files replace
+// same-named files in the workspace, the chunk dump is loaded into the index,
+// and the index is then synced against the restored files. Without force it
+// refuses to touch a workspace that already holds memory.
+func (m *Manager) Import(ctx context.Context, r io.Reader, force bool) (ImportResult, error) {
+	if !force {
+		hasMemory, err := m.HasMemory()
+		if err != nil {
+			return ImportResult{}, err
+		}
+		if hasMemory {
+			return ImportResult{}, ErrWorkspaceNotEmpty
+		}
+	}
+	manifest, files, chunks, err := readMemoryArchive(r)
+	if err != nil {
+		return ImportResult{}, err
+	}
+	if manifest.Version > exportVersion {
+		return ImportResult{}, fmt.Errorf("unsupported memory export version %d", manifest.Version)
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		full := filepath.Join(m.workspace, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			return ImportResult{}, err
+		}
+		if err := os.WriteFile(full, files[name], 0o644); err != nil {
+			return ImportResult{}, err
+		}
+	}
+	result := ImportResult{Files: len(names)}
+	if !m.Enabled() {
+		return result, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.restoreChunksLocked(ctx, chunks); err != nil {
+		return result, err
+	}
+	result.Chunks = len(chunks)
+	return result, m.syncLocked(ctx)
+}
+
+// memoryFiles lists regular files under memory/, as slash-separated paths
+// relative to the workspace.
+func (m *Manager) memoryFiles() ([]string, error) {
+	memoryDir := filepath.Join(m.workspace, exportMemoryDirName)
+	files := make([]string, 0, 16)
+	err := filepath.WalkDir(memoryDir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == memoryDir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(m.workspace, p)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+func (m *Manager) dumpChunks(ctx context.Context) ([]ExportedChunk, error) {
+	if !m.Enabled() {
+		return nil, nil
+	}
+	db, _, err := m.openDB()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, `SELECT id, path, kind, COALESCE(day, ''), content, updated_at FROM chunks ORDER BY path, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	chunks := make([]ExportedChunk, 0, 64)
+	for rows.Next() {
+		var chunk ExportedChunk
+		if err := rows.Scan(&chunk.ID, &chunk.Path, &chunk.Kind, &chunk.Day, &chunk.Content, &chunk.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if rel, err := filepath.Rel(m.workspace, chunk.Path); err == nil && !strings.HasPrefix(rel, "..") {
+			chunk.Path = filepath.ToSlash(rel)
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks, rows.Err()
+}
+
+func (m *Manager) restoreChunksLocked(ctx context.Context, chunks []ExportedChunk) error {
+	db, ftsEnabled, err := m.openDB()
+	if err != nil {
+		return err
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	for _, chunk := range chunks {
+		chunkPath := chunk.Path
+		if !filepath.IsAbs(chunkPath) {
+			chunkPath = filepath.Join(m.workspace, filepath.FromSlash(chunkPath))
+		}
+		if _, err := tx.Exec(
+			`INSERT INTO chunks (id, path, kind, day, content, updated_at) VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT(id) DO UPDATE SET path=excluded.path, kind=excluded.kind, day=excluded.day, content=excluded.content, updated_at=excluded.updated_at`,
+			chunk.ID, chunkPath, chunk.Kind, chunk.Day, chunk.Content, chunk.UpdatedAt,
+		); err != nil {
+			return err
+		}
+		if ftsEnabled {
+			if _, err := tx.Exec(`DELETE FROM chunks_fts WHERE id = ?`, chunk.ID); err != nil {
+				return err
+			}
+			if _, err := tx.Exec(`INSERT INTO chunks_fts (id, path, kind, day, content) VALUES (?, ?, ?, ?, ?)`, chunk.ID, chunkPath, chunk.Kind, chunk.Day, chunk.Content); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// readMemoryArchive reads the manifest, memory files, and chunk dump from an
+// Export archive. Entries outside memory/ other than the manifest and dump,
+// and any path that escapes the workspace, are rejected.
+func readMemoryArchive(r io.Reader) (ExportManifest, map[string][]byte, []ExportedChunk, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return ExportManifest{}, nil, nil, fmt.Errorf("read memory archive: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	var manifest ExportManifest
+	hasManifest := false
+	files := map[string][]byte{}
+	var chunks []ExportedChunk
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return ExportManifest{}, nil, nil, fmt.Errorf("read memory archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := path.Clean(strings.TrimPrefix(header.Name, "./"))
+		switch {
+		case name == exportManifestName:
+			if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+				return ExportManifest{}, nil, nil, fmt.Errorf("invalid memory archive manifest: %w", err)
+			}
+			hasManifest = true
+		case name == exportChunksName:
+			scanner := bufio.NewScanner(tr)
+			scanner.Buffer(make([]byte, 0, 64*1024), 16<<20)
+			for scanner.Scan() {
+				line := strings.TrimSpace(scanner.Text())
+				if line == "" {
+					continue
+				}
+				var chunk ExportedChunk
+				if err := json.Unmarshal([]byte(line), &chunk); err != nil {
+					return ExportManifest{}, nil, nil, fmt.Errorf("invalid memory archive chunk: %w", err)
+				}
+				if strings.TrimSpace(chunk.ID) == "" || filepath.IsAbs(chunk.Path) || strings.HasPrefix(path.Clean(chunk.Path), "..") {
+					return ExportManifest{}, nil, nil, fmt.Errorf("invalid memory archive chunk %q", chunk.ID)
+				}
+				chunks = append(chunks, chunk)
+			}
+			if err := scanner.Err(); err != nil {
+				return ExportManifest{}, nil, nil, fmt.Errorf("read memory archive chunks: %w", err)
+			}
+		case strings.HasPrefix(name, exportMemoryDirName+"/") && !path.IsAbs(name):
+			content, err := io.ReadAll(tr)
+			if err != nil {
+				return ExportManifest{}, nil, nil, fmt.Errorf("read memory archive: %w", err)
+			}
+			files[name] = content
+		default:
+			return ExportManifest{}, nil, nil, fmt.Errorf("unexpected memory archive entry %q", header.Name)
+		}
+	}
+	if !hasManifest {
+		return ExportManifest{}, nil, nil, fmt.Errorf("memory archive has no %s", exportManifestName)
+	}
+	return manifest, files, chunks, nil
+}
@@ -0,0 +1,80 @@
+package memory
+
+import (
+	"context"
+	"strings"
+)
+
+const (
+	// RerankerCosine scores candidates by embedding cosine similarity to the
+	// query. It is the default.
+	RerankerCosine = "cosine"
+	// RerankerNone keeps lexical and recency order.
+	RerankerNone = "none"
+)
+
+// Reranker rescores search candidates after lexical retrieval. It returns the
+// chunks it scored with Score set to its relevance for query; the manager
+// adds that score to the chunk's lexical and recency score. Chunks left out
+// of the result keep their lexical order. An error discards every score, so
+// a failing reranker never blocks retrieval.
+type Reranker interface {
+	Rerank(ctx context.Context, query string, chunks []Chunk) ([]Chunk, error)
+}
+
+// Option customises a Manager at construction.
+type Option func(*Manager)
+
+// WithReranker wires a custom reranker, such as a local cross-encoder, in
+// place of the one named by memory.semantic.reranker.
+func WithReranker(reranker Reranker) Option {
+	return func(m *Manager) {
+		m.reranker = reranker
+	}
+}
+
+// rerankerByName returns the built-in reranker for name. Unknown names fall
+// back to cosine so a typo keeps the default ordering.
+func rerankerByName(m *Manager, name string) Reranker {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case RerankerNone:
+		return nil
+	default:
+		return cosineReranker{m: m}
+	}
+}
+
+// cosineReranker compares the query embedding with each chunk's cached
+// embedding, computing and storing missing ones on the way.
+type cosineReranker struct {
+	m *Manager
+}
+
+func (r cosineReranker) Rerank(ctx context.Context, query string, chunks []Chunk) ([]Chunk, error) {
+	m := r.m
+	if len(chunks) == 0 || m.embedder == nil {
+		return nil, nil
+	}
+	if strings.EqualFold(strings.TrimSpace(m.embedder.Provider()), "none") {
+		return nil, nil
+	}
+	db, _, err := m.openDB()
+	if err != nil {
+		return nil, err
+	}
+	queryVectors, err := m.embedder.Embed(ctx, []string{query})
+	if err != nil || len(queryVectors) == 0 || len(queryVectors[0]) == 0 {
+		return nil, err
+	}
+	queryVector := queryVectors[0]
+	scored := make([]Chunk, 0, len(chunks))
+	for _, chunk := range chunks {
+		chunkVector, vectorErr := m.getOrCreateEmbedding(ctx, db, chunk.ID, chunk.Content)
+		if vectorErr != nil || len(chunkVector) == 0 {
+			continue
+		}
+		chunk.Score = cosineSimilarity(queryVector, chunkVector)
+		scored = append(scored, chunk)
+	}
+	return scored, nil
+}
@@ -0,0 +1,173 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	dailyDigestHeading    = "## Daily digest "
+	dailyRollupInputChars = 12000
+	dailyDigestMaxChars   = 1200
+)
+
+// DailySummarizer condenses one day's log into a short digest.
+type DailySummarizer func(ctx context.Context, day, content string) (string, error)
+
+// RollupResult reports which days were digested into MEMORY.md.
+type RollupResult struct {
+	Days    []string
+	Pending int
+}
+
+// RollupDaily appends a digest of each completed daily log that is not yet in
+// MEMORY.md, oldest first. Summaries run without holding the manager lock so
+// concurrent daily log appends are not blocked on the provider.
+func (m *Manager) RollupDaily(ctx context.Context, summarize DailySummarizer) (RollupResult, error) {
+	if !m.Enabled() || summarize == nil {
+		return RollupResult{}, nil
+	}
+
+	m.mu.Lock()
+	pending, err := m.pendingRollupDays(time.Now().UTC())
+	m.mu.Unlock()
+	if err != nil {
+		return RollupResult{}, err
+	}
+	maxDays := m.rollupMaxDays
+	if maxDays <= 0 {
+		maxDays = 7
+	}
+	result := RollupResult{Pending: len(pending)}
+	if len(pending) > maxDays {
+		pending = pending[:maxDays]
+	}
+
+	for _, day := range pending {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		raw, err := os.ReadFile(m.dailyPath(day))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return result, err
+		}
+		content := string(raw)
+		if len(content) > dailyRollupInputChars {
+			content = content[:dailyRollupInputChars]
+		}
+		summary, err := summarize(ctx, day, content)
+		if err != nil {
+			return result, fmt.Errorf("summarize %s: %w", day, err)
+		}
+		summary = strings.TrimSpace(summary)
+		if len(summary) > dailyDigestMaxChars {
+			summary = strings.TrimSpace(summary[:dailyDigestMaxChars-3]) + "..."
+		}
+		if summary == "" {
+			summary = "No notable activity."
+		}
+
+		m.mu.Lock()
+		err = m.appendDigestLocked(day, summary)
+		m.mu.Unlock()
+		if err != nil {
+			return result, err
+		}
+		result.Days = append(result.Days, day)
+	}
+	result.Pending -= len(result.Days)
+
+	if len(result.Days) > 0 {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if err := m.syncLocked(ctx); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// pendingRollupDays lists completed days that have log entries but no digest.
+func (m *Manager) pendingRollupDays(now time.Time) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(m.workspace, "memory", "daily", "*.md"))
+	if err != nil {
+		return nil, err
+	}
+	today := now.Format("2006-01-02")
+	digested := m.digestedDays()
+	days := make([]string, 0, len(matches))
+	for _, path := range matches {
+		day := strings.TrimSuffix(filepath.Base(path), ".md")
+		if _, err := time.Parse("2006-01-02", day); err != nil {
+			continue
+		}
+		if day >= today || digested[day] || !dailyHasEntries(path) {
+			continue
+		}
+		days = append(days, day)
+	}
+	sort.Strings(days)
+	return days, nil
+}
+
+func (m *Manager) appendDigestLocked(day, summary string) error {
+	if m.digestedDays()[day] {
+		return nil
+	}
+	memoryDir := filepath.Join(m.workspace, "memory")
+	if err := os.MkdirAll(memoryDir, 0o755); err != nil {
+		return err
+	}
+	path := filepath.Join(memoryDir, "MEMORY.md")
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	prefix := ""
+	if len(existing) > 0 && !strings.HasSuffix(string(existing), "\n") {
+		prefix = "\n"
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = fmt.Fprintf(f, "%s\n%s%s\n\n%s\n", prefix, dailyDigestHeading, day, summary)
+	return err
+}
+
+// digestedDays returns the days that already have a digest in MEMORY.md.
+func (m *Manager) digestedDays() map[string]bool {
+	out := map[string]bool{}
+	raw, err := os.ReadFile(filepath.Join(m.workspace, "memory", "MEMORY.md"))
+	if err != nil {
+		return out
+	}
+	for _, line := range strings.Split(string(raw), "\n") {
+		if day, ok := strings.CutPrefix(strings.TrimSpace(line), dailyDigestHeading); ok {
+			out[strings.TrimSpace(day)] = true
+		}
+	}
+	return out
+}
+
+func (m *Manager) dailyPath(day string) string {
+	return filepath.Join(m.workspace, "memory", "daily", day+".md")
+}
+
+// dailyHasEntries reports whether a daily log holds more than its header.
+func dailyHasEntries(path string) bool {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	return strings.Contains(string(raw), "\n## ")
+}
@@ -1,6 +1,7 @@
 package mission
 
 import (
+	"fmt"
 	"strings"
 	"time"
 	"unicode"
@@ -92,15 +93,42 @@ func DefaultColumns(now time.Time) []Column {
 }
 
 type UsageDay struct {
-	Day              string    `json:"day"`
-	PromptTokens     uint64    `json:"prompt_tokens"`
-	CompletionTokens uint64    `json:"completion_tokens"`
-	TotalTokens      uint64    `json:"total_tokens"`
-	UpdatedAt        time.Time `json:"updated_at"`
+	Day              string `json:"day"`
+	PromptTokens     uint64 `json:"prompt_tokens"`
+	CompletionTokens uint64 `json:"completion_tokens"`
+	TotalTokens      uint64 `json:"total_tokens"`
+	CacheReadTokens  uint64 `json:"cache_read_tokens,omitempty"`
+	CacheWriteTokens uint64 `json:"cache_write_tokens,omitempty"`
+	// ByProvider splits the day's tokens by "provider/model" label. Days
+	// recorded before attribution existed have no entries.
+	ByProvider map[string]ProviderUsage `json:"by_provider,omitempty"`
+	UpdatedAt  time.Time                `json:"updated_at"`
+}
+
+type ProviderUsage struct {
+	PromptTokens     uint64 `json:"prompt_tokens"`
+	CompletionTokens uint64 `json:"completion_tokens"`
+	TotalTokens      uint64 `json:"total_tokens"`
+	// Calls, TimedTokens and LatencyMS cover the timed provider calls that
+	// returned completion tokens; MaxTokensPerSec is the fastest of them.
+	Calls           uint64  `json:"calls,omitempty"`
+	TimedTokens     uint64  `json:"timed_tokens,omitempty"`
+	LatencyMS       uint64  `json:"latency_ms,omitempty"`
+	MaxTokensPerSec float64 `json:"max_tokens_per_sec,omitempty"`
+}
+
+// TokensPerSec is the average completion throughput over the timed calls,
+// or zero when none were timed.
+func (u ProviderUsage) TokensPerSec() float64 {
+	if u.LatencyMS == 0 {
+		return 0
+	}
+	return float64(u.TimedTokens) * 1000 / float64(u.LatencyMS)
 }
 
 type HeartbeatRun struct {
 	ID          string    `json:"id"`
+	Profile     string    `json:"profile,omitempty"`
 	TriggeredBy string    `json:"triggered_by"`
 	Status      string    `json:"status"`
 	Error       string    `json:"error,omitempty"`
@@ -111,27 +139,47 @@ type HeartbeatRun struct {
 }
 
 type TaskAutomationPolicy struct {
-	EnableChat      bool      `json:"enable_chat"`
-	EnableHeartbeat bool      `json:"enable_heartbeat"`
-	EnableCron      bool      `json:"enable_cron"`
-	EnableSubagent  bool      `json:"enable_subagent"`
-	DedupeWindowSec int       `json:"dedupe_window_sec"`
-	DefaultColumnID string    `json:"default_column_id"`
-	UpdatedAt       time.Time `json:"updated_at"`
+	EnableChat          bool      `json:"enable_chat"`
+	EnableHeartbeat     bool      `json:"enable_heartbeat"`
+	EnableCron          bool      `json:"enable_cron"`
+	EnableSubagent      bool      `json:"enable_subagent"`
+	DedupeWindowSec     int       `json:"dedupe_window_sec"`
+	DedupeFields        string    `json:"dedupe_fields,omitempty"`
+	DedupeNormalization string    `json:"dedupe_normalization,omitempty"`
+	DedupeNotes         string    `json:"dedupe_notes,omitempty"`
+	DefaultColumnID     string    `json:"default_column_id"`
+	UpdatedAt           time.Time `json:"updated_at"`
 }
 
+const (
+	DedupeFieldsTitle         = "title"
+	DedupeFieldsTitleAssignee = "title_assignee"
+	DedupeFieldsTitleColumn   = "title_column"
+
+	DedupeNormalizeStrict = "strict"
+	DedupeNormalizeBasic  = "basic"
+	DedupeNormalizeLoose  = "loose"
+
+	DedupeNotesAppend  = "append"
+	DedupeNotesReplace = "replace"
+	DedupeNotesIgnore  = "ignore"
+)
+
 func DefaultTaskAutomationPolicy(now time.Time) TaskAutomationPolicy {
 	if now.IsZero() {
 		now = time.Now().UTC()
 	}
 	return TaskAutomationPolicy{
-		EnableChat:      true,
-		EnableHeartbeat: true,
-		EnableCron:      true,
-		EnableSubagent:  true,
-		DedupeWindowSec: int((6 * time.Hour).Seconds()),
-		DefaultColumnID: ColumnBacklog,
-		UpdatedAt:       now,
+		EnableChat:          true,
+		EnableHeartbeat:     true,
+		EnableCron:          true,
+		EnableSubagent:      true,
+		DedupeWindowSec:     int((6 * time.Hour).Seconds()),
+		DedupeFields:        DedupeFieldsTitle,
+		DedupeNormalization: DedupeNormalizeLoose,
+		DedupeNotes:         DedupeNotesAppend,
+		DefaultColumnID:     ColumnBacklog,
+		UpdatedAt:           now,
 	}
 }
 
@@ -158,6 +206,109 @@ func (p TaskAutomationPolicy) EnabledForSource(source TaskSourceType) bool {
 	}
 }
 
+// NormalizeDedupe fills unset dedupe settings with their defaults and rejects
+// unknown values.
+func (p *TaskAutomationPolicy) NormalizeDedupe() error {
+	p.DedupeFields = strings.ToLower(strings.TrimSpace(p.DedupeFields))
+	switch p.DedupeFields {
+	case "":
+		p.DedupeFields = DedupeFieldsTitle
+	case DedupeFieldsTitle, DedupeFieldsTitleAssignee, DedupeFieldsTitleColumn:
+	default:
+		return fmt.Errorf("unsupported dedupe fields %q (use title|title_assignee|title_column)", p.DedupeFields)
+	}
+	p.DedupeNormalization = strings.ToLower(strings.TrimSpace(p.DedupeNormalization))
+	switch p.DedupeNormalization {
+	case "":
+		p.DedupeNormalization = DedupeNormalizeLoose
+	case DedupeNormalizeStrict, DedupeNormalizeBasic, DedupeNormalizeLoose:
+	default:
+		return fmt.Errorf("unsupported dedupe normalization %q (use strict|basic|loose)", p.DedupeNormalization)
+	}
+	p.DedupeNotes = strings.ToLower(strings.TrimSpace(p.DedupeNotes))
+	switch p.DedupeNotes {
+	case "":
+		p.DedupeNotes = DedupeNotesAppend
+	case DedupeNotesAppend, DedupeNotesReplace, DedupeNotesIgnore:
+	default:
+		return fmt.Errorf("unsupported dedupe notes mode %q (use append|replace|ignore)", p.DedupeNotes)
+	}
+	return nil
+}
+
+// DedupeTitleKey normalizes a title according to the policy's normalization
+// strength: strict trims only, basic folds case and whitespace, loose also
+// drops punctuation.
+func (p TaskAutomationPolicy) DedupeTitleKey(title string) string {
+	switch p.DedupeNormalization {
+	case DedupeNormalizeStrict:
+		return strings.TrimSpace(title)
+	case DedupeNormalizeBasic:
+		return strings.Join(strings.Fields(strings.ToLower(title)), " ")
+	default:
+		return NormalizeTaskTitle(title)
+	}
+}
+
+// DedupeMatches reports whether an existing task should absorb a new request
+// with the given title, assignee, and target column.
+func (p TaskAutomationPolicy) DedupeMatches(candidate Task, title, assignee, columnID string) bool {
+	if p.DedupeTitleKey(candidate.Title) != p.DedupeTitleKey(title) {
+		return false
+	}
+	switch p.DedupeFields {
+	case DedupeFieldsTitleAssignee:
+		return strings.EqualFold(strings.TrimSpace(candidate.Assignee), strings.TrimSpace(assignee))
+	case DedupeFieldsTitleColumn:
+		return candidate.ColumnID == columnID
+	default:
+		return true
+	}
+}
+
+// MergeDedupeNotes combines existing and incoming notes per the policy.
+func (p TaskAutomationPolicy) MergeDedupeNotes(existing, incoming string) string {
+	existing = strings.TrimSpace(existing)
+	incoming = strings.TrimSpace(incoming)
+	if incoming == "" {
+		return existing
+	}
+	switch p.DedupeNotes {
+	case DedupeNotesIgnore:
+		return existing
+	case DedupeNotesReplace:
+		return incoming
+	default:
+		if existing == "" {
+			return incoming
+		}
+		return existing + "\n" + incoming
+	}
+}
+
+// AutomationSources lists the task sources governed by TaskAutomationPolicy.
+func AutomationSources() []TaskSourceType {
+	return []TaskSourceType{TaskSourceChat, TaskSourceHeartbeat, TaskSourceCron, TaskSourceSubagent}
+}
+
+// SetEnabledSources enables exactly the given automation sources and disables the rest.
+func (p *TaskAutomationPolicy) SetEnabledSources(sources []TaskSourceType) error {
+	enabled := map[TaskSourceType]bool{}
+	for _, source := range sources {
+		switch source {
+		case TaskSourceChat, TaskSourceHeartbeat, TaskSourceCron, TaskSourceSubagent:
+			enabled[source] = true
+		default:
+			return fmt.Errorf("unsupported task source %q (use chat|heartbeat|cron|subagent)", source)
+		}
+	}
+	p.EnableChat = enabled[TaskSourceChat]
+	p.EnableHeartbeat = enabled[TaskSourceHeartbeat]
+	p.EnableCron = enabled[TaskSourceCron]
+	p.EnableSubagent = enabled[TaskSourceSubagent]
+	return nil
+}
+
 func NormalizeTaskTitle(in string) string {
 	trimmed := strings.TrimSpace(strings.ToLower(in))
 	if trimmed == "" {
@@ -194,3 +345,37 @@ func NormalizePriority(in string) string {
 		return ""
 	}
 }
+
+const (
+	// DefaultTaskPageSize and MaxTaskPageSize bound a TaskPageQuery limit.
+	DefaultTaskPageSize = 100
+	MaxTaskPageSize     = 500
+	// DueSoonWindow is how far ahead an open task's due date counts as due
+	// soon in TaskCounts.
+	DueSoonWindow = 24 * time.Hour
+)
+
+// TaskPageQuery selects one page of tasks in ID order, which is creation
+// order. Cursor is the NextCursor of the previous page, or empty for the
+// first page; ColumnID, when set, keeps only that column's tasks.
+type TaskPageQuery struct {
+	ColumnID string
+	Limit    int
+	Cursor   string
+}
+
+// TaskPage is one page of tasks. NextCursor is empty on the last page.
+type TaskPage struct {
+	Tasks      []Task `json:"tasks"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// TaskCounts summarises the board without loading whole tasks. Open counts
+// tasks outside the done column; DueSoon and Overdue only count open tasks.
+type TaskCounts struct {
+	Total    int            `json:"total"`
+	Open     int            `json:"open"`
+	DueSoon  int            `json:"dueSoon"`
+	Overdue  int            `json:"overdue"`
+	ByColumn map[string]int `json:"byColumn"`
+}
@@ -0,0 +1,66 @@
+package mission
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTaskAutomationPolicyDedupeSettings(t *testing.T) {
+	candidate := Task{Title: "Fix the Login page!", Assignee: "Ana", ColumnID: ColumnBacklog}
+
+	t.Run("normalization strength", func(t *testing.T) {
+		policy := DefaultTaskAutomationPolicy(time.Time{})
+		if !policy.DedupeMatches(candidate, "fix the login page", "", ColumnBacklog) {
+			t.Fatal("loose normalization should ignore case and punctuation")
+		}
+		policy.DedupeNormalization = DedupeNormalizeBasic
+		if policy.DedupeMatches(candidate, "fix the login page", "", ColumnBacklog) {
+			t.Fatal("basic normalization should keep punctuation")
+		}
+		if !policy.DedupeMatches(candidate, "  fix  the LOGIN page! ", "", ColumnBacklog) {
+			t.Fatal("basic normalization should fold case and whitespace")
+		}
+		policy.DedupeNormalization = DedupeNormalizeStrict
+		if policy.DedupeMatches(candidate, "fix the login page!", "", ColumnBacklog) {
+			t.Fatal("strict normalization should be case sensitive")
+		}
+	})
+
+	t.Run("matching fields", func(t *testing.T) {
+		policy := DefaultTaskAutomationPolicy(time.Time{})
+		policy.DedupeFields = DedupeFieldsTitleAssignee
+		if policy.DedupeMatches(candidate, candidate.Title, "bo", ColumnBacklog) {
+			t.Fatal("different assignee should not match")
+		}
+		if !policy.DedupeMatches(candidate, candidate.Title, "ana", ColumnBacklog) {
+			t.Fatal("assignee comparison should be case insensitive")
+		}
+		policy.DedupeFields = DedupeFieldsTitleColumn
+		if policy.DedupeMatches(candidate, candidate.Title, "", ColumnDone) {
+			t.Fatal("different column should not match")
+		}
+	})
+
+	t.Run("notes merging and validation", func(t *testing.T) {
+		policy := DefaultTaskAutomationPolicy(time.Time{})
+		if got := policy.MergeDedupeNotes("a", "b"); got != "a\nb" {
+			t.Fatalf("unexpected appended notes: %q", got)
+		}
+		policy.DedupeNotes = DedupeNotesReplace
+		if got := policy.MergeDedupeNotes("a", "b"); got != "b" {
+			t.Fatalf("unexpected replaced notes: %q", got)
+		}
+		policy.DedupeNotes = DedupeNotesIgnore
+		if got := policy.MergeDedupeNotes("a", "b"); got != "a" {
+			t.Fatalf("unexpected ignored notes: %q", got)
+		}
+		policy.DedupeFields = "everything"
+		if err := policy.NormalizeDedupe(); err == nil {
+			t.Fatal("expected unknown dedupe fields to be rejected")
+		}
+		empty := TaskAutomationPolicy{}
+		if err := empty.NormalizeDedupe(); err != nil || empty.DedupeFields != DedupeFieldsTitle || empty.DedupeNotes != DedupeNotesAppend {
+			t.Fatalf("expected defaults for unset dedupe settings, got %+v (%v)", empty, err)
+		}
+	})
+}
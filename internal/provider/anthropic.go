@@ -4,31 +4,40 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"fmt"
 	"net/http"
 	"strings"
 	"time"
 )
 
+const anthropicMessagesURL = "https://api.anthropic.com/v1/messages"
+
 type AnthropicProvider struct {
-	apiKey string
-	model  string
-	client *http.Client
+	apiKey   string
+	model    string
+	headers  map[string]string
+	endpoint string
+	client   *http.Client
 }
 
 func NewAnthropicProvider(apiKey, model string) *AnthropicProvider {
+	return NewAnthropicProviderWithOptions(apiKey, model, nil)
+}
+
+func NewAnthropicProviderWithOptions(apiKey, model string, headers map[string]string) *AnthropicProvider {
 	if strings.TrimSpace(model) == "" {
 		model = "claude-3-5-sonnet-20241022"
 	}
 	return &AnthropicProvider{
-		apiKey: apiKey,
-		model:  model,
-		client: &http.Client{Timeout: 120 * time.Second},
+		apiKey:   apiKey,
+		model:    model,
+		headers:  cloneHeaders(headers),
+		endpoint: anthropicMessagesURL,
+		client:   &http.Client{Timeout: 120 * time.Second},
 	}
 }
 
 func (p *AnthropicProvider) Capabilities() ProviderCapabilities {
-	return ProviderCapabilities{SupportsTools: true, SupportsStream: true, SupportsJSONOut: false}
+	return ProviderCapabilities{SupportsTools: true, SupportsStream: true, SupportsJSONOut: false, SupportsCaching: true}
 }
 
 func (p *AnthropicProvider) Stream(ctx context.Context, req ChatRequest) (<-chan StreamEvent, <-chan error) {
@@ -56,11 +65,18 @@ func (p *AnthropicProvider) Stream(ctx context.Context, req ChatRequest) (<-chan
 
 func (p *AnthropicProvider) Chat(ctx context.Context, req ChatRequest) (ChatResponse, error) {
 	system := ""
+	var systemBlocks []string
 	messages := make([]map[string]any, 0, len(req.Messages))
 	for _, m := range req.Messages {
 		if m.Role == "system" {
-			if system == "" {
-				system = m.Content
+			// Later system messages, such as the per-turn prompt or a history
+			// summary, are appended to the first so none is dropped.
+			if strings.TrimSpace(m.Content) != "" {
+				if system != "" {
+					system += "\n\n"
+				}
+				system += m.Content
+				systemBlocks = append(systemBlocks, m.Content)
 			}
 			continue
 		}
@@ -77,6 +93,20 @@ func (p *AnthropicProvider) Chat(ctx context.Context, req ChatRequest) (ChatResp
 		"system":      system,
 		"messages":    messages,
 	}
+	if req.PromptCaching && system != "" {
+		// The breakpoint goes on the first system message only, which the
+		// engine keeps stable across turns; later ones change every turn
+		// and would otherwise keep the cached prefix from ever matching.
+		blocks := make([]map[string]any, 0, len(systemBlocks))
+		for idx, text := range systemBlocks {
+			block := map[string]any{"type": "text", "text": text}
+			if idx == 0 {
+				block["cache_control"] = anthropicEphemeralCache()
+			}
+			blocks = append(blocks, block)
+		}
+		payload["system"] = blocks
+	}
 
 	if len(req.Tools) > 0 {
 		tools := make([]map[string]any, 0, len(req.Tools))
@@ -87,6 +117,10 @@ func (p *AnthropicProvider) Chat(ctx context.Context, req ChatRequest) (ChatResp
 				"input_schema": t.Schema,
 			})
 		}
+		if req.PromptCaching {
+			// A breakpoint on the last tool caches the whole tool block.
+			tools[len(tools)-1]["cache_control"] = anthropicEphemeralCache()
+		}
 		payload["tools"] = tools
 	}
 
@@ -99,12 +133,13 @@ func (p *AnthropicProvider) Chat(ctx context.Context, req ChatRequest) (ChatResp
 		return ChatResponse{}, err
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.anthropic.com/v1/messages", bytes.NewReader(data))
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(data))
 	if err != nil {
 		return ChatResponse{}, err
 	}
 	httpReq.Header.Set("x-api-key", p.apiKey)
 	httpReq.Header.Set("anthropic-version", "2023-06-01")
+	applyHeaders(httpReq, p.headers)
 	httpReq.Header.Set("content-type", "application/json")
 
 	resp, err := p.client.Do(httpReq)
@@ -116,7 +151,7 @@ func (p *AnthropicProvider) Chat(ctx context.Context, req ChatRequest) (ChatResp
 	if resp.StatusCode >= 300 {
 		var body map[string]any
 		_ = json.NewDecoder(resp.Body).Decode(&body)
-		return ChatResponse{}, fmt.Errorf("provider http %d: %v", resp.StatusCode, body)
+		return ChatResponse{}, &HTTPError{StatusCode: resp.StatusCode, Body: body}
 	}
 
 	var parsed anthropicResponse
@@ -146,19 +181,30 @@ func (p *AnthropicProvider) Chat(ctx context.Context, req ChatRequest) (ChatResp
 			})
 		}
 	}
+	// input_tokens excludes cached tokens, so fold cache reads and writes back
+	// into the prompt total to keep budget accounting comparable.
+	promptTokens := parsed.Usage.InputTokens + parsed.Usage.CacheReadInputTokens + parsed.Usage.CacheCreationInputTokens
 	out.Usage = Usage{
-		PromptTokens:     parsed.Usage.InputTokens,
+		PromptTokens:     promptTokens,
 		CompletionTokens: parsed.Usage.OutputTokens,
-		TotalTokens:      parsed.Usage.InputTokens + parsed.Usage.OutputTokens,
+		TotalTokens:      promptTokens + parsed.Usage.OutputTokens,
+		CacheReadTokens:  parsed.Usage.CacheReadInputTokens,
+		CacheWriteTokens: parsed.Usage.CacheCreationInputTokens,
 	}
 	return out, nil
 }
 
+func anthropicEphemeralCache() map[string]any {
+	return map[string]any{"type": "ephemeral"}
+}
+
 type anthropicResponse struct {
 	StopReason string `json:"stop_reason"`
 	Usage      struct {
-		InputTokens  int `json:"input_tokens"`
-		OutputTokens int `json:"output_tokens"`
+		InputTokens              int `json:"input_tokens"`
+		OutputTokens             int `json:"output_tokens"`
+		CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+		CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
 	} `json:"usage"`
 	Content []struct {
 		Type  string         `json:"type"`
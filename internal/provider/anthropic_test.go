@@ -0,0 +1,112 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAnthropicPromptCachingMarksSystemAndTools(t *testing.T) {
+	var payload map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"stop_reason":"end_turn","content":[{"type":"text","text":"ok"}],"usage":{"input_tokens":10,"output_tokens":5,"cache_read_input_tokens":900,"cache_creation_input_tokens":100}}`))
+	}))
+	defer server.Close()
+
+	p := NewAnthropicProvider("key", "claude-test")
+	p.endpoint = server.URL
+	resp, err := p.Chat(context.Background(), ChatRequest{
+		Messages: []Message{
+			{Role: "system", Content: "stable system prompt"},
+			{Role: "system", Content: "## Current Time\n2026-03-01 09:00:00 (Sunday)"},
+			{Role: "user", Content: "hello"},
+		},
+		Tools: []ToolDefinition{
+			{Name: "read_file", Schema: map[string]any{"type": "object"}},
+			{Name: "list_dir", Schema: map[string]any{"type": "object"}},
+		},
+		MaxTokens:     100,
+		PromptCaching: true,
+	})
+	if err != nil {
+		t.Fatalf("chat: %v", err)
+	}
+
+	system, ok := payload["system"].([]any)
+	if !ok || len(system) != 2 {
+		t.Fatalf("expected system content blocks, got %#v", payload["system"])
+	}
+	block := system[0].(map[string]any)
+	if block["text"] != "stable system prompt" || block["cache_control"] == nil {
+		t.Fatalf("expected cacheable system block, got %#v", block)
+	}
+	if turn := system[1].(map[string]any); turn["cache_control"] != nil {
+		t.Fatalf("expected the per-turn block after the breakpoint, got %#v", turn)
+	}
+	tools := payload["tools"].([]any)
+	if tools[0].(map[string]any)["cache_control"] != nil {
+		t.Fatalf("expected breakpoint only on the last tool")
+	}
+	if tools[1].(map[string]any)["cache_control"] == nil {
+		t.Fatalf("expected breakpoint on the last tool")
+	}
+
+	if resp.Usage.CacheReadTokens != 900 || resp.Usage.CacheWriteTokens != 100 {
+		t.Fatalf("unexpected cache usage: %+v", resp.Usage)
+	}
+	if resp.Usage.PromptTokens != 1010 || resp.Usage.TotalTokens != 1015 {
+		t.Fatalf("expected cached tokens folded into prompt totals, got %+v", resp.Usage)
+	}
+}
+
+func TestAnthropicWithoutPromptCachingSendsPlainSystem(t *testing.T) {
+	var payload map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"stop_reason":"end_turn","content":[{"type":"text","text":"ok"}],"usage":{"input_tokens":10,"output_tokens":5}}`))
+	}))
+	defer server.Close()
+
+	p := NewAnthropicProvider("key", "claude-test")
+	p.endpoint = server.URL
+	if _, err := p.Chat(context.Background(), ChatRequest{
+		Messages: []Message{{Role: "system", Content: "sys"}, {Role: "user", Content: "hi"}},
+	}); err != nil {
+		t.Fatalf("chat: %v", err)
+	}
+	if payload["system"] != "sys" {
+		t.Fatalf("expected plain system string, got %#v", payload["system"])
+	}
+}
+
+func TestAnthropicCustomHeadersOverrideDefaults(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"stop_reason":"end_turn","content":[{"type":"text","text":"ok"}],"usage":{"input_tokens":1,"output_tokens":1}}`))
+	}))
+	defer server.Close()
+
+	p := NewAnthropicProviderWithOptions("key", "claude-test", map[string]string{
+		"anthropic-version": "2024-10-22",
+		"X-Org-Id":          "org-7",
+		"Content-Type":      "text/plain",
+		"X-Blank":           " ",
+	})
+	p.endpoint = server.URL
+	if _, err := p.Chat(context.Background(), ChatRequest{Messages: []Message{{Role: "user", Content: "hello"}}, MaxTokens: 10}); err != nil {
+		t.Fatalf("chat: %v", err)
+	}
+	if got.Get("anthropic-version") != "2024-10-22" || got.Get("X-Org-Id") != "org-7" || got.Get("x-api-key") != "key" {
+		t.Fatalf("expected custom headers over the defaults, got %v", got)
+	}
+	if got.Get("Content-Type") != "application/json" || got.Get("X-Blank") != "" {
+		t.Fatalf("expected content type kept and blank header skipped, got %v", got)
+	}
+}
@@ -0,0 +1,57 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// HTTPError is returned when a provider responds with a non-success status.
+type HTTPError struct {
+	StatusCode int
+	Body       map[string]any
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("provider http %d: %v", e.StatusCode, e.Body)
+}
+
+// IsModelNotFound reports whether err means the requested model is not
+// served by the provider, as opposed to an auth, quota, or transport error.
+// Only structured provider errors count: an OpenAI-style error code of
+// model_not_found on a 400 or 404, or a 404 whose error type is
+// not_found_error, which chat endpoints such as Anthropic's and Ollama's
+// return for an unknown model.
+func IsModelNotFound(err error) bool {
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		return false
+	}
+	if httpErr.StatusCode != 400 && httpErr.StatusCode != 404 {
+		return false
+	}
+	detail, _ := httpErr.Body["error"].(map[string]any)
+	if code, _ := detail["code"].(string); code == "model_not_found" {
+		return true
+	}
+	kind, _ := detail["type"].(string)
+	return httpErr.StatusCode == 404 && kind == "not_found_error"
+}
+
+// IsRetryable reports whether err is transient: a timeout, a 408 or 429, or
+// a 5xx response. Cancellation and other HTTP errors are permanent.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == 408 || httpErr.StatusCode == 429 || httpErr.StatusCode >= 500
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -18,6 +18,21 @@ func FromConfig(cfg config.Config) (LLMProvider, string, error) {
 		model = p.Model
 	}
 
+	if name == config.ProviderMock {
+		// The agent default model names a real provider's model, so the mock
+		// only honours a model set on its own config.
+		if strings.TrimSpace(p.Model) == "" {
+			model = mockDefaultModel
+		}
+		mockCfg := cfg.Providers.Mock
+		steps, err := mockCfg.Steps()
+		if err != nil {
+			return nil, "", err
+		}
+		mockCfg.Script, mockCfg.ScriptFile = steps, ""
+		return NewMockProvider(mockCfg), model, nil
+	}
+
 	profile, hasProfile := catalog.ProviderByID(name)
 	if !hasProfile {
 		profile = catalog.ProviderProfile{
@@ -27,16 +42,18 @@ func FromConfig(cfg config.Config) (LLMProvider, string, error) {
 			APIKeyPrefix: "Bearer ",
 		}
 	}
-	switch strings.TrimSpace(profile.Transport) {
-	case "anthropic":
-		return NewAnthropicProvider(p.APIKey, model), model, nil
-	case "openai_compat", "":
-		base := p.APIBase
-		if strings.TrimSpace(base) == "" {
-			base = config.ProviderDefaultAPIBase(name)
-		}
-		return NewOpenAICompatProviderWithOptions(p.APIKey, base, profile.APIKeyHeader, profile.APIKeyPrefix, nil), model, nil
+	base := p.APIBase
+	if strings.TrimSpace(base) == "" {
+		base = config.ProviderDefaultAPIBase(name)
+	}
+	switch transport := config.ProviderTransport(name, p); transport {
+	case config.TransportAnthropic:
+		return NewAnthropicProviderWithOptions(p.APIKey, model, p.Headers), model, nil
+	case config.TransportOpenAICompat:
+		return NewOpenAICompatProviderWithOptions(p.APIKey, base, profile.APIKeyHeader, profile.APIKeyPrefix, p.Headers), model, nil
+	case config.TransportOpenAIResponses:
+		return NewOpenAIResponsesProviderWithOptions(p.APIKey, base, profile.APIKeyHeader, profile.APIKeyPrefix, p.Headers, p.ReasoningEffort), model, nil
 	default:
-		return nil, "", fmt.Errorf("unsupported provider transport %q for %q", profile.Transport, name)
+		return nil, "", fmt.Errorf("unsupported provider transport %q for %q", transport, name)
 	}
 }
@@ -32,6 +32,22 @@ func TestFromConfig(t *testing.T) {
 		}
 	})
 
+	t.Run("mock needs no api key and ignores the agent default model", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Providers.Active = config.ProviderMock
+
+		client, model, err := FromConfig(cfg)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, ok := client.(*MockProvider); !ok {
+			t.Fatalf("expected MockProvider, got %T", client)
+		}
+		if model != mockDefaultModel {
+			t.Fatalf("unexpected model: %s", model)
+		}
+	})
+
 	t.Run("ollama supports empty api key", func(t *testing.T) {
 		cfg := config.Default()
 		cfg.Providers.Active = config.ProviderOllama
@@ -99,6 +115,51 @@ func TestFromConfig(t *testing.T) {
 		}
 	})
 
+	t.Run("provider headers are passed to the client", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Providers.Active = config.ProviderOpenRouter
+		cfg.Providers.OpenRouter = config.ProviderConfig{
+			APIKey:  "router-key",
+			Model:   "openai/gpt-4.1",
+			Headers: map[string]string{"HTTP-Referer": "https://example.com", "X-Title": "squidbot"},
+		}
+
+		client, _, err := FromConfig(cfg)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		openaiCompat, ok := client.(*OpenAICompatProvider)
+		if !ok {
+			t.Fatalf("expected OpenAICompatProvider, got %T", client)
+		}
+		if openaiCompat.headers["X-Title"] != "squidbot" || openaiCompat.headers["HTTP-Referer"] != "https://example.com" {
+			t.Fatalf("unexpected headers: %+v", openaiCompat.headers)
+		}
+	})
+
+	t.Run("openai responses transport is selectable per provider", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Providers.Active = config.ProviderOpenAI
+		cfg.Providers.OpenAI = config.ProviderConfig{
+			APIKey:          "openai-key",
+			Model:           "o4-mini",
+			Transport:       "openai-responses",
+			ReasoningEffort: "low",
+		}
+
+		client, _, err := FromConfig(cfg)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		responses, ok := client.(*OpenAIResponsesProvider)
+		if !ok {
+			t.Fatalf("expected OpenAIResponsesProvider, got %T", client)
+		}
+		if responses.baseURL != config.ProviderDefaultAPIBase(config.ProviderOpenAI) || responses.reasoningEffort != "low" {
+			t.Fatalf("unexpected client settings: %+v", responses)
+		}
+	})
+
 	t.Run("invalid config returns validation error", func(t *testing.T) {
 		cfg := config.Default()
 		cfg.Providers.Active = config.ProviderGemini
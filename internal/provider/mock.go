@@ -0,0 +1,161 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+
+	"github.com/grixate/squidbot/internal/config"
+)
+
+const mockDefaultModel = "mock"
+
+// mockStreamChunkChars is roughly how much reply text one streamed chunk
+// carries; chunks end on a space where one is near.
+const mockStreamChunkChars = 24
+
+// MockProvider is a deterministic, offline LLMProvider. Within a turn it
+// replays the configured script one step per call, counting the assistant
+// messages after the last user message, then echoes the user. Usage is
+// synthesised at roughly four characters per token.
+type MockProvider struct {
+	cfg   config.MockProviderConfig
+	reply *template.Template
+}
+
+// NewMockProvider replays cfg.Script; a ScriptFile must already be loaded
+// into it, as FromConfig does. A Reply that is not a valid template is
+// returned as written.
+func NewMockProvider(cfg config.MockProviderConfig) *MockProvider {
+	p := &MockProvider{cfg: cfg}
+	if strings.Contains(cfg.Reply, "{{") {
+		if tmpl, err := template.New("mock_reply").Option("missingkey=zero").Parse(cfg.Reply); err == nil {
+			p.reply = tmpl
+		}
+	}
+	return p
+}
+
+func (p *MockProvider) Capabilities() ProviderCapabilities {
+	return ProviderCapabilities{SupportsTools: true, SupportsStream: true}
+}
+
+func (p *MockProvider) Chat(ctx context.Context, req ChatRequest) (ChatResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return ChatResponse{}, err
+	}
+	step, lastUser := mockTurnState(req.Messages)
+	out := ChatResponse{FinishReason: "stop"}
+	if step < len(p.cfg.Script) {
+		scripted := p.cfg.Script[step]
+		out.Content = scripted.Content
+		for idx, call := range scripted.ToolCalls {
+			args := call.Arguments
+			if len(args) == 0 {
+				args = json.RawMessage("{}")
+			}
+			out.ToolCalls = append(out.ToolCalls, ToolCall{ID: fmt.Sprintf("mock-%d-%d", step, idx), Name: call.Name, Arguments: args})
+		}
+		if len(out.ToolCalls) > 0 {
+			out.FinishReason = "tool_calls"
+		}
+	} else if strings.TrimSpace(p.cfg.Reply) != "" {
+		out.Content = p.renderReply(req.Model, step, lastUser)
+	} else {
+		out.Content = "echo: " + lastUser
+	}
+	out.Usage = mockUsage(req.Messages, out)
+	return out, nil
+}
+
+// Stream sends the Chat reply in word-aligned chunks, followed by its tool
+// calls and a final Done event.
+func (p *MockProvider) Stream(ctx context.Context, req ChatRequest) (<-chan StreamEvent, <-chan error) {
+	resp, err := p.Chat(ctx, req)
+	chunks := mockChunks(resp.Content)
+	events := make(chan StreamEvent, len(chunks)+len(resp.ToolCalls)+1)
+	errs := make(chan error, 1)
+	if err != nil {
+		errs <- err
+	} else {
+		for _, chunk := range chunks {
+			events <- StreamEvent{DeltaContent: chunk}
+		}
+		for _, call := range resp.ToolCalls {
+			toolCall := call
+			events <- StreamEvent{ToolCall: &toolCall}
+		}
+		events <- StreamEvent{Done: true}
+	}
+	close(events)
+	close(errs)
+	return events, errs
+}
+
+func (p *MockProvider) renderReply(model string, step int, lastUser string) string {
+	if p.reply == nil {
+		return p.cfg.Reply
+	}
+	if strings.TrimSpace(model) == "" {
+		model = mockDefaultModel
+	}
+	var out strings.Builder
+	data := map[string]any{"Message": lastUser, "Model": model, "Step": step}
+	if err := p.reply.Execute(&out, data); err != nil {
+		return p.cfg.Reply
+	}
+	return out.String()
+}
+
+// mockChunks splits content into pieces of about mockStreamChunkChars,
+// breaking after a space when one falls in the second half of a piece.
+func mockChunks(content string) []string {
+	chunks := make([]string, 0, len(content)/mockStreamChunkChars+1)
+	for len(content) > mockStreamChunkChars {
+		cut := mockStreamChunkChars
+		if idx := strings.LastIndexByte(content[:cut], ' '); idx >= cut/2 {
+			cut = idx + 1
+		}
+		for cut < len(content) && !utf8.RuneStart(content[cut]) {
+			cut++
+		}
+		chunks = append(chunks, content[:cut])
+		content = content[cut:]
+	}
+	if content != "" {
+		chunks = append(chunks, content)
+	}
+	return chunks
+}
+
+// mockTurnState returns how many assistant replies the current turn already
+// has and the content of the last user message.
+func mockTurnState(messages []Message) (int, string) {
+	step := 0
+	for i := len(messages) - 1; i >= 0; i-- {
+		switch messages[i].Role {
+		case "user":
+			return step, messages[i].Content
+		case "assistant":
+			step++
+		}
+	}
+	return step, ""
+}
+
+func mockUsage(messages []Message, resp ChatResponse) Usage {
+	promptChars := 0
+	for _, message := range messages {
+		promptChars += len(message.Content)
+	}
+	completionChars := len(resp.Content)
+	for _, call := range resp.ToolCalls {
+		completionChars += len(call.Name) + len(call.Arguments)
+	}
+	prompt := max(promptChars/4, 1)
+	completion := max(completionChars/4, 1)
+	return Usage{PromptTokens: prompt, CompletionTokens: completion, TotalTokens: prompt + completion}
+}
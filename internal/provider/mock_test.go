@@ -0,0 +1,124 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/grixate/squidbot/internal/config"
+)
+
+func TestMockProviderReplaysScriptPerTurn(t *testing.T) {
+	p := NewMockProvider(config.MockProviderConfig{Script: []config.MockScriptStep{
+		{ToolCalls: []config.MockToolCall{{Name: "list_dir", Arguments: json.RawMessage(`{"path":"."}`)}}},
+		{Content: "listed"},
+	}})
+	turn := []Message{{Role: "system", Content: "sys"}, {Role: "user", Content: "show files"}}
+
+	first, err := p.Chat(context.Background(), ChatRequest{Messages: turn})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first.ToolCalls) != 1 || first.ToolCalls[0].Name != "list_dir" {
+		t.Fatalf("expected scripted tool call, got %+v", first)
+	}
+	if first.Usage.TotalTokens == 0 || first.Usage.TotalTokens != first.Usage.PromptTokens+first.Usage.CompletionTokens {
+		t.Fatalf("expected synthetic usage, got %+v", first.Usage)
+	}
+
+	turn = append(turn,
+		Message{Role: "assistant", ToolCalls: first.ToolCalls},
+		Message{Role: "tool", ToolCallID: first.ToolCalls[0].ID, Content: "a.txt"},
+	)
+	second, err := p.Chat(context.Background(), ChatRequest{Messages: turn})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Content != "listed" {
+		t.Fatalf("expected second script step, got %+v", second)
+	}
+
+	turn = append(turn, Message{Role: "assistant", Content: "listed"})
+	third, err := p.Chat(context.Background(), ChatRequest{Messages: turn})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third.Content != "echo: show files" {
+		t.Fatalf("expected echo after script, got %q", third.Content)
+	}
+
+	nextTurn := append(turn, Message{Role: "user", Content: "again"})
+	restarted, err := p.Chat(context.Background(), ChatRequest{Messages: nextTurn})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(restarted.ToolCalls) != 1 {
+		t.Fatalf("expected script to restart on a new user message, got %+v", restarted)
+	}
+}
+
+func TestMockProviderTemplatedReplyAndChunkedStream(t *testing.T) {
+	p := NewMockProvider(config.MockProviderConfig{Reply: "{{.Model}} heard: {{.Message}}, which is a fairly long message to stream"})
+	req := ChatRequest{Model: "demo", Messages: []Message{{Role: "user", Content: "ping"}}}
+	resp, err := p.Chat(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "demo heard: ping, which is a fairly long message to stream"
+	if resp.Content != want {
+		t.Fatalf("expected templated reply %q, got %q", want, resp.Content)
+	}
+
+	events, errs := p.Stream(context.Background(), req)
+	var chunks []string
+	done := false
+	for event := range events {
+		if event.Done {
+			done = true
+			continue
+		}
+		chunks = append(chunks, event.DeltaContent)
+	}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+	if !done || len(chunks) < 2 || strings.Join(chunks, "") != want {
+		t.Fatalf("expected the reply in several chunks then done, got %q (done=%v)", chunks, done)
+	}
+
+	broken := NewMockProvider(config.MockProviderConfig{Reply: "{{.Message"})
+	resp, _ = broken.Chat(context.Background(), req)
+	if resp.Content != "{{.Message" {
+		t.Fatalf("expected an invalid template to be returned as written, got %q", resp.Content)
+	}
+}
+
+func TestMockProviderLoadsScriptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "script.json")
+	if err := os.WriteFile(path, []byte(`[{"content":"from file"}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.Default()
+	cfg.Providers.Active = config.ProviderMock
+	cfg.Providers.Mock.Script = []config.MockScriptStep{{Content: "inline"}}
+	cfg.Providers.Mock.ScriptFile = path
+	client, model, err := FromConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Chat(context.Background(), ChatRequest{Model: model, Messages: []Message{{Role: "user", Content: "hi"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Content != "from file" {
+		t.Fatalf("expected script file to replace inline script, got %q", resp.Content)
+	}
+
+	cfg.Providers.Mock.ScriptFile = filepath.Join(t.TempDir(), "missing.json")
+	if _, _, err := FromConfig(cfg); err == nil || !strings.Contains(err.Error(), "scriptFile") {
+		t.Fatalf("expected missing script file error, got %v", err)
+	}
+}
@@ -98,12 +98,7 @@ func (p *OpenAICompatProvider) Chat(ctx context.Context, req ChatRequest) (ChatR
 	if strings.TrimSpace(p.apiKey) != "" {
 		httpReq.Header.Set(p.apiKeyHeader, p.apiKeyPrefix+p.apiKey)
 	}
-	for key, value := range p.headers {
-		if strings.TrimSpace(key) == "" || strings.TrimSpace(value) == "" {
-			continue
-		}
-		httpReq.Header.Set(key, value)
-	}
+	applyHeaders(httpReq, p.headers)
 	httpReq.Header.Set("Content-Type", "application/json")
 
 	resp, err := p.client.Do(httpReq)
@@ -115,7 +110,7 @@ func (p *OpenAICompatProvider) Chat(ctx context.Context, req ChatRequest) (ChatR
 	if resp.StatusCode >= 300 {
 		var body map[string]any
 		_ = json.NewDecoder(resp.Body).Decode(&body)
-		return ChatResponse{}, fmt.Errorf("provider http %d: %v", resp.StatusCode, body)
+		return ChatResponse{}, &HTTPError{StatusCode: resp.StatusCode, Body: body}
 	}
 
 	var parsed openAIResponse
@@ -133,6 +128,7 @@ func (p *OpenAICompatProvider) Chat(ctx context.Context, req ChatRequest) (ChatR
 			PromptTokens:     parsed.Usage.PromptTokens,
 			CompletionTokens: parsed.Usage.CompletionTokens,
 			TotalTokens:      parsed.Usage.TotalTokens,
+			CacheReadTokens:  parsed.Usage.PromptTokensDetails.CachedTokens,
 		},
 	}
 
@@ -146,6 +142,20 @@ func (p *OpenAICompatProvider) Chat(ctx context.Context, req ChatRequest) (ChatR
 	return out, nil
 }
 
+// applyHeaders sets the configured provider headers on req. They are applied
+// after the built-in auth and version headers, so an entry such as
+// Authorization, x-api-key, or anthropic-version replaces the default; only
+// Content-Type is always set by the client. Entries with a blank name or
+// value are skipped.
+func applyHeaders(req *http.Request, headers map[string]string) {
+	for key, value := range headers {
+		if strings.TrimSpace(key) == "" || strings.TrimSpace(value) == "" {
+			continue
+		}
+		req.Header.Set(key, value)
+	}
+}
+
 func cloneHeaders(headers map[string]string) map[string]string {
 	if len(headers) == 0 {
 		return nil
@@ -219,5 +229,9 @@ type openAIResponse struct {
 		PromptTokens     int `json:"prompt_tokens"`
 		CompletionTokens int `json:"completion_tokens"`
 		TotalTokens      int `json:"total_tokens"`
+		// Providers that cache prompts automatically report hits here.
+		PromptTokensDetails struct {
+			CachedTokens int `json:"cached_tokens"`
+		} `json:"prompt_tokens_details"`
 	} `json:"usage"`
 }
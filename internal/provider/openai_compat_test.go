@@ -51,4 +51,76 @@ func TestOpenAICompatAuthorizationHeader(t *testing.T) {
 			t.Fatalf("expected bearer auth header, got %q", authHeader)
 		}
 	})
+
+	t.Run("custom authorization header replaces the key", func(t *testing.T) {
+		var authHeader, orgHeader string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authHeader = r.Header.Get("Authorization")
+			orgHeader = r.Header.Get("OpenAI-Organization")
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"choices":[{"finish_reason":"stop","message":{"content":"ok"}}],"usage":{"prompt_tokens":1,"completion_tokens":1,"total_tokens":2}}`))
+		}))
+		defer server.Close()
+
+		p := NewOpenAICompatProviderWithOptions("secret-key", server.URL+"/v1", "Authorization", "Bearer ", map[string]string{
+			"Authorization":       "Basic cHJveHk6cGFzcw==",
+			"OpenAI-Organization": "org-7",
+		})
+		_, err := p.Chat(context.Background(), ChatRequest{
+			Model:    "test-model",
+			Messages: []Message{{Role: "user", Content: "hello"}},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if authHeader != "Basic cHJveHk6cGFzcw==" || orgHeader != "org-7" {
+			t.Fatalf("expected custom headers to win, got auth %q org %q", authHeader, orgHeader)
+		}
+	})
+}
+
+func TestOpenAICompatModelNotFoundError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Header.Get("X-Quota") != "" {
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"error":{"message":"rate limited for model gpt-x"}}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":{"code":"model_not_found","message":"The model gpt-retired does not exist"}}`))
+	}))
+	defer server.Close()
+
+	p := NewOpenAICompatProvider("key", server.URL+"/v1")
+	_, err := p.Chat(context.Background(), ChatRequest{Model: "gpt-retired", Messages: []Message{{Role: "user", Content: "hi"}}})
+	if !IsModelNotFound(err) {
+		t.Fatalf("expected model not found error, got %v", err)
+	}
+
+	limited := NewOpenAICompatProviderWithOptions("key", server.URL+"/v1", "", "", map[string]string{"X-Quota": "1"})
+	_, err = limited.Chat(context.Background(), ChatRequest{Model: "gpt-x", Messages: []Message{{Role: "user", Content: "hi"}}})
+	if err == nil || IsModelNotFound(err) {
+		t.Fatalf("expected a non-model error, got %v", err)
+	}
+}
+
+func TestIsModelNotFoundNeedsStructuredCode(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"openai code", &HTTPError{StatusCode: 400, Body: map[string]any{"error": map[string]any{"code": "model_not_found"}}}, true},
+		{"anthropic type", &HTTPError{StatusCode: 404, Body: map[string]any{"type": "error", "error": map[string]any{"type": "not_found_error", "message": "model: claude-retired"}}}, true},
+		{"not_found type on 400", &HTTPError{StatusCode: 400, Body: map[string]any{"error": map[string]any{"type": "not_found_error"}}}, false},
+		{"message only", &HTTPError{StatusCode: 404, Body: map[string]any{"error": map[string]any{"message": "The model gpt-x does not exist"}}}, false},
+		{"unrelated 400", &HTTPError{StatusCode: 400, Body: map[string]any{"error": map[string]any{"code": "invalid_request_error", "message": "file not found for model input"}}}, false},
+		{"code on 500", &HTTPError{StatusCode: 500, Body: map[string]any{"error": map[string]any{"code": "model_not_found"}}}, false},
+	}
+	for _, tc := range cases {
+		if got := IsModelNotFound(tc.err); got != tc.want {
+			t.Fatalf("%s: IsModelNotFound = %v, want %v", tc.name, got, tc.want)
+		}
+	}
 }
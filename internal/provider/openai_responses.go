@@ -0,0 +1,340 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OpenAIResponsesProvider talks to the OpenAI Responses API (/responses).
+// Messages map to input items, tool calls and results to function_call and
+// function_call_output items, and Stream reads the server-sent events of a
+// streamed response.
+type OpenAIResponsesProvider struct {
+	apiKey          string
+	baseURL         string
+	apiKeyHeader    string
+	apiKeyPrefix    string
+	headers         map[string]string
+	reasoningEffort string
+	client          *http.Client
+}
+
+func NewOpenAIResponsesProvider(apiKey, baseURL string) *OpenAIResponsesProvider {
+	return NewOpenAIResponsesProviderWithOptions(apiKey, baseURL, "Authorization", "Bearer ", nil, "")
+}
+
+func NewOpenAIResponsesProviderWithOptions(apiKey, baseURL, apiKeyHeader, apiKeyPrefix string, headers map[string]string, reasoningEffort string) *OpenAIResponsesProvider {
+	trimmed := strings.TrimRight(strings.TrimSpace(baseURL), "/")
+	if trimmed == "" {
+		trimmed = "https://api.openai.com/v1"
+	}
+	if strings.TrimSpace(apiKeyHeader) == "" {
+		apiKeyHeader = "Authorization"
+	}
+	return &OpenAIResponsesProvider{
+		apiKey:          apiKey,
+		baseURL:         trimmed,
+		apiKeyHeader:    apiKeyHeader,
+		apiKeyPrefix:    apiKeyPrefix,
+		headers:         cloneHeaders(headers),
+		reasoningEffort: strings.ToLower(strings.TrimSpace(reasoningEffort)),
+		client: &http.Client{
+			Timeout: 120 * time.Second,
+		},
+	}
+}
+
+func (p *OpenAIResponsesProvider) Capabilities() ProviderCapabilities {
+	return ProviderCapabilities{SupportsTools: true, SupportsStream: true, SupportsJSONOut: true}
+}
+
+// Stream sends the request with stream set and forwards output text deltas
+// and completed function calls as the server-sent events arrive.
+func (p *OpenAIResponsesProvider) Stream(ctx context.Context, req ChatRequest) (<-chan StreamEvent, <-chan error) {
+	events := make(chan StreamEvent, 8)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(events)
+		defer close(errs)
+		payload := p.payload(req)
+		payload["stream"] = true
+		resp, err := p.post(ctx, payload)
+		if err != nil {
+			errs <- err
+			return
+		}
+		defer resp.Body.Close()
+		if err := readResponsesStream(resp.Body, func(event StreamEvent) bool {
+			select {
+			case events <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}); err != nil {
+			errs <- err
+			return
+		}
+		if ctx.Err() != nil {
+			errs <- ctx.Err()
+			return
+		}
+		events <- StreamEvent{Done: true}
+	}()
+	return events, errs
+}
+
+// readResponsesStream parses the server-sent events of a streamed response
+// and hands text deltas and finished function calls to emit until the
+// response completes, emit returns false, or the stream ends.
+func readResponsesStream(body io.Reader, emit func(StreamEvent) bool) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64<<10), 4<<20)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		data = strings.TrimSpace(data)
+		if !ok || data == "" || data == "[DONE]" {
+			continue
+		}
+		var event responsesStreamEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return fmt.Errorf("provider stream: %w", err)
+		}
+		switch event.Type {
+		case "response.output_text.delta":
+			if event.Delta != "" && !emit(StreamEvent{DeltaContent: event.Delta}) {
+				return nil
+			}
+		case "response.output_item.done":
+			if event.Item.Type != "function_call" {
+				continue
+			}
+			args := json.RawMessage(event.Item.Arguments)
+			if len(args) == 0 {
+				args = json.RawMessage("{}")
+			}
+			if !emit(StreamEvent{ToolCall: &ToolCall{ID: event.Item.CallID, Name: event.Item.Name, Arguments: args}}) {
+				return nil
+			}
+		case "response.completed", "response.incomplete":
+			return nil
+		case "response.failed":
+			if event.Response != nil && event.Response.Error != nil {
+				return fmt.Errorf("provider response failed: %s", event.Response.Error.Message)
+			}
+			return fmt.Errorf("provider response failed")
+		case "error":
+			return fmt.Errorf("provider stream error: %s", event.Message)
+		}
+	}
+	return scanner.Err()
+}
+
+func (p *OpenAIResponsesProvider) Chat(ctx context.Context, req ChatRequest) (ChatResponse, error) {
+	resp, err := p.post(ctx, p.payload(req))
+	if err != nil {
+		return ChatResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	var parsed responsesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return ChatResponse{}, err
+	}
+	if parsed.Status == "failed" && parsed.Error != nil {
+		return ChatResponse{}, fmt.Errorf("provider response failed: %s", parsed.Error.Message)
+	}
+	if len(parsed.Output) == 0 {
+		return ChatResponse{}, fmt.Errorf("provider returned no output")
+	}
+	out := ChatResponse{
+		FinishReason: "stop",
+		Usage: Usage{
+			PromptTokens:     parsed.Usage.InputTokens,
+			CompletionTokens: parsed.Usage.OutputTokens,
+			TotalTokens:      parsed.Usage.TotalTokens,
+			CacheReadTokens:  parsed.Usage.InputTokensDetails.CachedTokens,
+			ReasoningTokens:  parsed.Usage.OutputTokensDetails.ReasoningTokens,
+		},
+	}
+	var content []string
+	for _, item := range parsed.Output {
+		switch item.Type {
+		case "message":
+			for _, part := range item.Content {
+				if part.Type == "output_text" {
+					content = append(content, part.Text)
+				}
+			}
+		case "function_call":
+			args := json.RawMessage(item.Arguments)
+			if len(args) == 0 {
+				args = json.RawMessage("{}")
+			}
+			out.ToolCalls = append(out.ToolCalls, ToolCall{ID: item.CallID, Name: item.Name, Arguments: args})
+		}
+	}
+	out.Content = strings.Join(content, "")
+	switch {
+	case len(out.ToolCalls) > 0:
+		out.FinishReason = "tool_calls"
+	case parsed.Status == "incomplete" && parsed.IncompleteDetails != nil && parsed.IncompleteDetails.Reason == "max_output_tokens":
+		out.FinishReason = "length"
+	}
+	return out, nil
+}
+
+func (p *OpenAIResponsesProvider) payload(req ChatRequest) map[string]any {
+	instructions, input := toResponsesInput(req.Messages)
+	payload := map[string]any{
+		"model": req.Model,
+		"input": input,
+		// squidbot keeps its own history, so nothing needs to live server-side.
+		"store": false,
+	}
+	if instructions != "" {
+		payload["instructions"] = instructions
+	}
+	if req.MaxTokens > 0 {
+		payload["max_output_tokens"] = req.MaxTokens
+	}
+	if p.reasoningEffort != "" {
+		// Reasoning models reject sampling parameters such as temperature.
+		payload["reasoning"] = map[string]any{"effort": p.reasoningEffort}
+	} else {
+		payload["temperature"] = req.Temperature
+	}
+	if len(req.Tools) > 0 {
+		payload["tools"] = toResponsesTools(req.Tools)
+		payload["tool_choice"] = "auto"
+	}
+	return payload
+}
+
+// post sends payload to /responses and returns the response once it has a
+// success status; other statuses become an HTTPError.
+func (p *OpenAIResponsesProvider) post(ctx context.Context, payload map[string]any) (*http.Response, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/responses", bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(p.apiKey) != "" {
+		httpReq.Header.Set(p.apiKeyHeader, p.apiKeyPrefix+p.apiKey)
+	}
+	applyHeaders(httpReq, p.headers)
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		var body map[string]any
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		return nil, &HTTPError{StatusCode: resp.StatusCode, Body: body}
+	}
+	return resp, nil
+}
+
+// toResponsesInput splits system messages into instructions and maps the
+// rest of the conversation to Responses input items.
+func toResponsesInput(in []Message) (string, []map[string]any) {
+	var instructions []string
+	out := make([]map[string]any, 0, len(in))
+	for _, msg := range in {
+		switch msg.Role {
+		case "system":
+			if strings.TrimSpace(msg.Content) != "" {
+				instructions = append(instructions, msg.Content)
+			}
+		case "tool":
+			out = append(out, map[string]any{
+				"type":    "function_call_output",
+				"call_id": msg.ToolCallID,
+				"output":  msg.Content,
+			})
+		default:
+			if msg.Content != "" || len(msg.ToolCalls) == 0 {
+				out = append(out, map[string]any{"role": msg.Role, "content": msg.Content})
+			}
+			for _, tc := range msg.ToolCalls {
+				out = append(out, map[string]any{
+					"type":      "function_call",
+					"call_id":   tc.ID,
+					"name":      tc.Name,
+					"arguments": string(tc.Arguments),
+				})
+			}
+		}
+	}
+	return strings.Join(instructions, "\n\n"), out
+}
+
+func toResponsesTools(in []ToolDefinition) []map[string]any {
+	out := make([]map[string]any, 0, len(in))
+	for _, td := range in {
+		out = append(out, map[string]any{
+			"type":        "function",
+			"name":        td.Name,
+			"description": td.Description,
+			"parameters":  td.Schema,
+		})
+	}
+	return out
+}
+
+type responsesResponse struct {
+	Status string `json:"status"`
+	Error  *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+	IncompleteDetails *struct {
+		Reason string `json:"reason"`
+	} `json:"incomplete_details"`
+	Output []struct {
+		Type    string `json:"type"`
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+		CallID    string `json:"call_id"`
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"output"`
+	Usage struct {
+		InputTokens        int `json:"input_tokens"`
+		OutputTokens       int `json:"output_tokens"`
+		TotalTokens        int `json:"total_tokens"`
+		InputTokensDetails struct {
+			CachedTokens int `json:"cached_tokens"`
+		} `json:"input_tokens_details"`
+		OutputTokensDetails struct {
+			ReasoningTokens int `json:"reasoning_tokens"`
+		} `json:"output_tokens_details"`
+	} `json:"usage"`
+}
+
+type responsesStreamEvent struct {
+	Type    string `json:"type"`
+	Delta   string `json:"delta"`
+	Message string `json:"message"`
+	Item    struct {
+		Type      string `json:"type"`
+		CallID    string `json:"call_id"`
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"item"`
+	Response *responsesResponse `json:"response"`
+}
@@ -0,0 +1,154 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOpenAIResponsesMapsRequestAndParsesOutput(t *testing.T) {
+	var path string
+	var payload map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"status": "completed",
+			"output": [
+				{"type": "reasoning", "summary": [{"type": "summary_text", "text": "Need the file list first."}]},
+				{"type": "message", "content": [{"type": "output_text", "text": "Checking."}]},
+				{"type": "function_call", "call_id": "call_2", "name": "list_dir", "arguments": "{\"path\":\".\"}"}
+			],
+			"usage": {"input_tokens": 20, "output_tokens": 12, "total_tokens": 32, "input_tokens_details": {"cached_tokens": 8}, "output_tokens_details": {"reasoning_tokens": 5}}
+		}`))
+	}))
+	defer server.Close()
+
+	p := NewOpenAIResponsesProviderWithOptions("key", server.URL+"/v1", "", "Bearer ", nil, "medium")
+	resp, err := p.Chat(context.Background(), ChatRequest{
+		Model:       "o4-mini",
+		MaxTokens:   256,
+		Temperature: 0.7,
+		Messages: []Message{
+			{Role: "system", Content: "Be brief."},
+			{Role: "user", Content: "what is here?"},
+			{Role: "assistant", ToolCalls: []ToolCall{{ID: "call_1", Name: "read_file", Arguments: json.RawMessage(`{"path":"a"}`)}}},
+			{Role: "tool", ToolCallID: "call_1", Content: "contents"},
+		},
+		Tools: []ToolDefinition{{Name: "list_dir", Description: "List a directory", Schema: map[string]any{"type": "object"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != "/v1/responses" {
+		t.Fatalf("unexpected path: %s", path)
+	}
+	if payload["instructions"] != "Be brief." || payload["max_output_tokens"] != float64(256) {
+		t.Fatalf("unexpected payload: %+v", payload)
+	}
+	if _, ok := payload["temperature"]; ok {
+		t.Fatal("expected temperature to be omitted when reasoning effort is set")
+	}
+	input, _ := payload["input"].([]any)
+	if len(input) != 3 {
+		t.Fatalf("expected user, function_call, and function_call_output items, got %+v", input)
+	}
+	call, _ := input[1].(map[string]any)
+	output, _ := input[2].(map[string]any)
+	if call["type"] != "function_call" || call["call_id"] != "call_1" || output["type"] != "function_call_output" || output["output"] != "contents" {
+		t.Fatalf("unexpected tool items: %+v / %+v", call, output)
+	}
+	tools, _ := payload["tools"].([]any)
+	tool, _ := tools[0].(map[string]any)
+	if tool["name"] != "list_dir" || tool["type"] != "function" {
+		t.Fatalf("unexpected tool definition: %+v", tool)
+	}
+
+	if resp.Content != "Checking." || resp.FinishReason != "tool_calls" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].ID != "call_2" || string(resp.ToolCalls[0].Arguments) != `{"path":"."}` {
+		t.Fatalf("unexpected tool calls: %+v", resp.ToolCalls)
+	}
+	if resp.Usage.PromptTokens != 20 || resp.Usage.CompletionTokens != 12 || resp.Usage.CacheReadTokens != 8 || resp.Usage.ReasoningTokens != 5 {
+		t.Fatalf("unexpected usage: %+v", resp.Usage)
+	}
+}
+
+func TestOpenAIResponsesStreamForwardsServerSentEvents(t *testing.T) {
+	var payload map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("event: response.output_text.delta\n" +
+			`data: {"type":"response.output_text.delta","delta":"Hello"}` + "\n\n" +
+			`data: {"type":"response.output_text.delta","delta":" "}` + "\n\n" +
+			`data: {"type":"response.output_text.delta","delta":"world"}` + "\n\n" +
+			`data: {"type":"response.output_item.done","item":{"type":"function_call","call_id":"call_1","name":"list_dir","arguments":""}}` + "\n\n" +
+			`data: {"type":"response.completed","response":{"status":"completed"}}` + "\n\n" +
+			`data: {"type":"response.output_text.delta","delta":"ignored"}` + "\n\n"))
+	}))
+	defer server.Close()
+
+	p := NewOpenAIResponsesProvider("key", server.URL)
+	events, errs := p.Stream(context.Background(), ChatRequest{Model: "gpt-4.1", Messages: []Message{{Role: "user", Content: "hi"}}})
+	var content string
+	var calls []ToolCall
+	done := false
+	for event := range events {
+		content += event.DeltaContent
+		if event.ToolCall != nil {
+			calls = append(calls, *event.ToolCall)
+		}
+		done = done || event.Done
+	}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+	if payload["stream"] != true {
+		t.Fatalf("expected stream to be requested, got %+v", payload)
+	}
+	if content != "Hello world" || !done {
+		t.Fatalf("unexpected stream: content=%q done=%v", content, done)
+	}
+	if len(calls) != 1 || calls[0].ID != "call_1" || string(calls[0].Arguments) != "{}" {
+		t.Fatalf("unexpected tool calls: %+v", calls)
+	}
+}
+
+func TestOpenAIResponsesStreamReportsFailedResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte(`data: {"type":"response.failed","response":{"status":"failed","error":{"message":"overloaded"}}}` + "\n\n"))
+	}))
+	defer server.Close()
+
+	p := NewOpenAIResponsesProvider("key", server.URL)
+	events, errs := p.Stream(context.Background(), ChatRequest{Model: "gpt-4.1", Messages: []Message{{Role: "user", Content: "hi"}}})
+	for range events {
+	}
+	if err := <-errs; err == nil || !strings.Contains(err.Error(), "overloaded") {
+		t.Fatalf("expected failed response error, got %v", err)
+	}
+}
+
+func TestOpenAIResponsesReportsTruncation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"incomplete","incomplete_details":{"reason":"max_output_tokens"},"output":[{"type":"message","content":[{"type":"output_text","text":"partial"}]}]}`))
+	}))
+	defer server.Close()
+
+	p := NewOpenAIResponsesProvider("key", server.URL)
+	resp, err := p.Chat(context.Background(), ChatRequest{Model: "gpt-x", Messages: []Message{{Role: "user", Content: "hi"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Content != "partial" || resp.FinishReason != "length" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
@@ -31,12 +31,21 @@ type ChatRequest struct {
 	Model       string
 	MaxTokens   int
 	Temperature float64
+	// PromptCaching asks providers that support it to mark the first system
+	// message and tool definitions as cacheable across turns. Later system
+	// messages are left outside the cached prefix.
+	PromptCaching bool
 }
 
 type Usage struct {
 	PromptTokens     int `json:"prompt_tokens"`
 	CompletionTokens int `json:"completion_tokens"`
 	TotalTokens      int `json:"total_tokens"`
+	CacheReadTokens  int `json:"cache_read_tokens,omitempty"`
+	CacheWriteTokens int `json:"cache_write_tokens,omitempty"`
+	// ReasoningTokens is the part of CompletionTokens spent on hidden
+	// reasoning, for providers that report it.
+	ReasoningTokens int `json:"reasoning_tokens,omitempty"`
 }
 
 type ChatResponse struct {
@@ -44,6 +53,9 @@ type ChatResponse struct {
 	ToolCalls    []ToolCall
 	FinishReason string
 	Usage        Usage
+	// Model is the model that served the call, which differs from the
+	// configured one when a model fallback answered it.
+	Model string
 }
 
 func (r ChatResponse) HasToolCalls() bool {
@@ -60,6 +72,7 @@ type ProviderCapabilities struct {
 	SupportsTools   bool
 	SupportsStream  bool
 	SupportsJSONOut bool
+	SupportsCaching bool
 }
 
 type LLMProvider interface {
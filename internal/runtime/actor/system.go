@@ -46,6 +46,7 @@ type System struct {
 	wg         sync.WaitGroup
 	onStart    func()
 	onStop     func()
+	onEvict    func(sessionID string)
 }
 
 func NewSystem(factory SessionFactory, mailboxCap int, idleTTL time.Duration) *System {
@@ -72,6 +73,12 @@ func (s *System) SetActorHooks(onStart, onStop func()) {
 	s.onStop = onStop
 }
 
+// SetEvictHook registers fn to run for each actor the reaper evicts after
+// the idle TTL. Actors stopped by Stop are not reported.
+func (s *System) SetEvictHook(fn func(sessionID string)) {
+	s.onEvict = fn
+}
+
 func (s *System) Stop() error {
 	close(s.stop)
 	s.wg.Wait()
@@ -128,6 +135,18 @@ func (s *System) ActorCount() int {
 	return len(s.actors)
 }
 
+// Live returns the sessions that currently have an actor, with the time each
+// was last active.
+func (s *System) Live() map[string]time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	live := make(map[string]time.Time, len(s.actors))
+	for id, actor := range s.actors {
+		live[id] = actor.lastSeen
+	}
+	return live
+}
+
 func (s *System) getOrCreate(sessionID string) (*actorState, error) {
 	s.mu.Lock()
 	if actor, ok := s.actors[sessionID]; ok {
@@ -216,5 +235,8 @@ func (s *System) evictIdle() {
 	for _, actor := range candidates {
 		close(actor.mailbox)
 		<-actor.closed
+		if s.onEvict != nil {
+			s.onEvict(actor.sessionID)
+		}
 	}
 }
@@ -48,3 +48,34 @@ func TestSystemIsolation(t *testing.T) {
 		t.Fatalf("expected actors > 0")
 	}
 }
+
+func TestSystemReportsLiveAndEvictedSessions(t *testing.T) {
+	sys := NewSystem(func(sessionID string) (SessionHandler, error) {
+		return &testHandler{}, nil
+	}, 4, time.Minute)
+	defer sys.Stop()
+	var evicted []string
+	sys.SetEvictHook(func(sessionID string) { evicted = append(evicted, sessionID) })
+
+	for _, session := range []string{"s:old", "s:new"} {
+		if _, err := sys.Submit(context.Background(), session, 1, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	live := sys.Live()
+	if len(live) != 2 || live["s:old"].IsZero() {
+		t.Fatalf("expected both sessions live, got %v", live)
+	}
+
+	sys.mu.Lock()
+	sys.actors["s:old"].lastSeen = time.Now().Add(-2 * time.Minute)
+	sys.mu.Unlock()
+	sys.evictIdle()
+
+	if live := sys.Live(); len(live) != 1 || live["s:new"].IsZero() {
+		t.Fatalf("expected only s:new live after eviction, got %v", live)
+	}
+	if len(evicted) != 1 || evicted[0] != "s:old" {
+		t.Fatalf("expected eviction hook for s:old, got %v", evicted)
+	}
+}
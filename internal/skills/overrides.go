@@ -0,0 +1,108 @@
+package skills
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// OverrideNamespace is the KV namespace holding skill enable/disable
+// overrides set at runtime with `squidbot skills enable|disable`.
+const OverrideNamespace = "skill_overrides"
+
+const overridesKey = "policy"
+
+// Override forces a skill on or off, globally or for one channel. Overrides
+// are checked before the config allow and deny lists.
+type Override struct {
+	SkillID   string    `json:"skill_id"`
+	Channel   string    `json:"channel,omitempty"`
+	Enabled   bool      `json:"enabled"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type OverrideSet struct {
+	Overrides []Override `json:"overrides"`
+}
+
+type OverrideStore interface {
+	PutKV(ctx context.Context, namespace, key string, value []byte) error
+	GetKV(ctx context.Context, namespace, key string) ([]byte, error)
+}
+
+// LoadOverrides reads the stored overrides. A missing or unreadable record
+// yields an empty set so config policy applies unchanged.
+func LoadOverrides(ctx context.Context, store OverrideStore) OverrideSet {
+	if store == nil {
+		return OverrideSet{}
+	}
+	raw, err := store.GetKV(ctx, OverrideNamespace, overridesKey)
+	if err != nil || len(raw) == 0 {
+		return OverrideSet{}
+	}
+	var set OverrideSet
+	if err := json.Unmarshal(raw, &set); err != nil {
+		return OverrideSet{}
+	}
+	return set
+}
+
+func SaveOverrides(ctx context.Context, store OverrideStore, set OverrideSet) error {
+	raw, err := json.Marshal(set)
+	if err != nil {
+		return err
+	}
+	return store.PutKV(ctx, OverrideNamespace, overridesKey, raw)
+}
+
+// Set stores an override for skillID, replacing any existing one with the
+// same channel scope.
+func (s *OverrideSet) Set(skillID, channel string, enabled bool) {
+	skillID = strings.ToLower(strings.TrimSpace(skillID))
+	channel = strings.ToLower(strings.TrimSpace(channel))
+	s.Clear(skillID, channel)
+	s.Overrides = append(s.Overrides, Override{SkillID: skillID, Channel: channel, Enabled: enabled, UpdatedAt: time.Now().UTC()})
+}
+
+// Clear removes the override for skillID in the given channel scope and
+// reports whether one existed.
+func (s *OverrideSet) Clear(skillID, channel string) bool {
+	skillID = strings.ToLower(strings.TrimSpace(skillID))
+	channel = strings.ToLower(strings.TrimSpace(channel))
+	kept := s.Overrides[:0]
+	removed := false
+	for _, item := range s.Overrides {
+		if item.SkillID == skillID && item.Channel == channel {
+			removed = true
+			continue
+		}
+		kept = append(kept, item)
+	}
+	s.Overrides = kept
+	return removed
+}
+
+// Lookup returns the override that applies to skill on channel. A
+// channel-scoped override wins over a global one.
+func (s OverrideSet) Lookup(channel string, skill SkillDescriptor) (Override, bool) {
+	channel = strings.ToLower(strings.TrimSpace(channel))
+	var global *Override
+	for i := range s.Overrides {
+		item := s.Overrides[i]
+		if !matchesPolicyTokenSet(map[string]struct{}{item.SkillID: {}}, skill) {
+			continue
+		}
+		if item.Channel == "" {
+			global = &s.Overrides[i]
+			continue
+		}
+		if channel != "" && item.Channel == channel {
+			return item, true
+		}
+	}
+	if global != nil {
+		return *global, true
+	}
+	return Override{}, false
+}
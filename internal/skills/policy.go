@@ -6,16 +6,19 @@ import (
 	"github.com/grixate/squidbot/internal/config"
 )
 
-type policyDecision struct {
+// PolicyDecision is the outcome of skill policy for one channel. Source is
+// "override" when a stored override decided it and "config" otherwise.
+type PolicyDecision struct {
 	Allowed bool
 	Reason  string
+	Source  string
 }
 
-func applyPolicyFilter(cfg config.Config, channel string, skills []SkillDescriptor) (allowed []SkillDescriptor, denied map[string]string) {
+func applyPolicyFilter(cfg config.Config, channel string, skills []SkillDescriptor, overrides OverrideSet) (allowed []SkillDescriptor, denied map[string]string) {
 	allowed = make([]SkillDescriptor, 0, len(skills))
 	denied = map[string]string{}
 	for _, skill := range skills {
-		decision := evaluatePolicy(cfg, channel, skill)
+		decision := EvaluatePolicy(cfg, channel, skill, overrides)
 		if decision.Allowed {
 			allowed = append(allowed, skill)
 			continue
@@ -26,8 +29,16 @@ func applyPolicyFilter(cfg config.Config, channel string, skills []SkillDescript
 	return allowed, denied
 }
 
-func evaluatePolicy(cfg config.Config, channel string, skill SkillDescriptor) policyDecision {
+// EvaluatePolicy applies stored overrides first, then the config global and
+// channel allow and deny lists.
+func EvaluatePolicy(cfg config.Config, channel string, skill SkillDescriptor, overrides OverrideSet) PolicyDecision {
 	channel = strings.ToLower(strings.TrimSpace(channel))
+	if override, ok := overrides.Lookup(channel, skill); ok {
+		if override.Enabled {
+			return PolicyDecision{Allowed: true, Reason: "enabled_by_override", Source: "override"}
+		}
+		return PolicyDecision{Allowed: false, Reason: "disabled_by_override", Source: "override"}
+	}
 	globalAllow := normalizePolicyList(cfg.Skills.Policy.Allow)
 	globalDeny := normalizePolicyList(cfg.Skills.Policy.Deny)
 	channelPolicy := cfg.Skills.Policy.Channels[channel]
@@ -35,18 +46,18 @@ func evaluatePolicy(cfg config.Config, channel string, skill SkillDescriptor) po
 	channelDeny := normalizePolicyList(channelPolicy.Deny)
 
 	if len(globalAllow) > 0 && !matchesPolicyTokenSet(globalAllow, skill) {
-		return policyDecision{Allowed: false, Reason: "denied_by_global_allowlist"}
+		return PolicyDecision{Allowed: false, Reason: "denied_by_global_allowlist", Source: "config"}
 	}
 	if len(globalDeny) > 0 && matchesPolicyTokenSet(globalDeny, skill) {
-		return policyDecision{Allowed: false, Reason: "denied_by_global"}
+		return PolicyDecision{Allowed: false, Reason: "denied_by_global", Source: "config"}
 	}
 	if len(channelAllow) > 0 && !matchesPolicyTokenSet(channelAllow, skill) {
-		return policyDecision{Allowed: false, Reason: "denied_by_channel_allowlist"}
+		return PolicyDecision{Allowed: false, Reason: "denied_by_channel_allowlist", Source: "config"}
 	}
 	if len(channelDeny) > 0 && matchesPolicyTokenSet(channelDeny, skill) {
-		return policyDecision{Allowed: false, Reason: "denied_by_channel"}
+		return PolicyDecision{Allowed: false, Reason: "denied_by_channel", Source: "config"}
 	}
-	return policyDecision{Allowed: true}
+	return PolicyDecision{Allowed: true, Reason: "allowed", Source: "config"}
 }
 
 func normalizePolicyList(values []string) map[string]struct{} {
@@ -0,0 +1,134 @@
+package skills
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grixate/squidbot/internal/config"
+)
+
+const policyExportVersion = 1
+
+// PolicyExport is the effective skills policy of one instance: the config
+// allow and deny lists plus the overrides stored with `skills enable|disable`.
+type PolicyExport struct {
+	Version    int                       `json:"version"`
+	ExportedAt time.Time                 `json:"exported_at"`
+	Policy     config.SkillsPolicyConfig `json:"policy"`
+	Overrides  []Override                `json:"overrides"`
+}
+
+// ExportPolicy captures cfg's skills policy and the stored overrides.
+func ExportPolicy(cfg config.Config, overrides OverrideSet) PolicyExport {
+	policy := cfg.Skills.Policy
+	if policy.Allow == nil {
+		policy.Allow = []string{}
+	}
+	if policy.Deny == nil {
+		policy.Deny = []string{}
+	}
+	if policy.Channels == nil {
+		policy.Channels = map[string]config.SkillsChannelPolicyConfig{}
+	}
+	items := append([]Override{}, overrides.Overrides...)
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].SkillID != items[j].SkillID {
+			return items[i].SkillID < items[j].SkillID
+		}
+		return items[i].Channel < items[j].Channel
+	})
+	return PolicyExport{Version: policyExportVersion, ExportedAt: time.Now().UTC(), Policy: policy, Overrides: items}
+}
+
+// Normalize checks the export version and returns the policy and overrides
+// with skill IDs and channel names lowercased and trimmed, as
+// `skills enable|disable` stores them.
+func (p PolicyExport) Normalize() (config.SkillsPolicyConfig, OverrideSet, error) {
+	if p.Version > policyExportVersion {
+		return config.SkillsPolicyConfig{}, OverrideSet{}, fmt.Errorf("unsupported skills policy export version %d", p.Version)
+	}
+	policy := config.SkillsPolicyConfig{
+		Allow:    normalizePolicyEntries(p.Policy.Allow),
+		Deny:     normalizePolicyEntries(p.Policy.Deny),
+		Channels: map[string]config.SkillsChannelPolicyConfig{},
+	}
+	for channel, rules := range p.Policy.Channels {
+		channel = strings.ToLower(strings.TrimSpace(channel))
+		if channel == "" {
+			return config.SkillsPolicyConfig{}, OverrideSet{}, fmt.Errorf("channel policy has an empty channel name")
+		}
+		policy.Channels[channel] = config.SkillsChannelPolicyConfig{
+			Allow: normalizePolicyEntries(rules.Allow),
+			Deny:  normalizePolicyEntries(rules.Deny),
+		}
+	}
+	var overrides OverrideSet
+	for idx, item := range p.Overrides {
+		if strings.TrimSpace(item.SkillID) == "" {
+			return config.SkillsPolicyConfig{}, OverrideSet{}, fmt.Errorf("override %d has no skill_id", idx+1)
+		}
+		overrides.Set(item.SkillID, item.Channel, item.Enabled)
+	}
+	return policy, overrides, nil
+}
+
+// UnknownSkills lists policy entries that match no skill in known by ID,
+// name, or alias, described by where they appear.
+func (p PolicyExport) UnknownSkills(known []SkillDescriptor) []string {
+	matches := func(token string) bool {
+		set := map[string]struct{}{strings.ToLower(strings.TrimSpace(token)): {}}
+		for _, skill := range known {
+			if matchesPolicyTokenSet(set, skill) {
+				return true
+			}
+		}
+		return false
+	}
+	unknown := make([]string, 0)
+	check := func(where string, tokens []string) {
+		for _, token := range tokens {
+			if strings.TrimSpace(token) != "" && !matches(token) {
+				unknown = append(unknown, fmt.Sprintf("%s: %s", where, strings.TrimSpace(token)))
+			}
+		}
+	}
+	check("policy.allow", p.Policy.Allow)
+	check("policy.deny", p.Policy.Deny)
+	channels := make([]string, 0, len(p.Policy.Channels))
+	for channel := range p.Policy.Channels {
+		channels = append(channels, channel)
+	}
+	sort.Strings(channels)
+	for _, channel := range channels {
+		rules := p.Policy.Channels[channel]
+		check("policy.channels."+channel+".allow", rules.Allow)
+		check("policy.channels."+channel+".deny", rules.Deny)
+	}
+	for _, item := range p.Overrides {
+		where := "override"
+		if strings.TrimSpace(item.Channel) != "" {
+			where += " (channel " + strings.TrimSpace(item.Channel) + ")"
+		}
+		check(where, []string{item.SkillID})
+	}
+	return unknown
+}
+
+func normalizePolicyEntries(values []string) []string {
+	out := make([]string, 0, len(values))
+	seen := map[string]struct{}{}
+	for _, value := range values {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" {
+			continue
+		}
+		if _, dup := seen[value]; dup {
+			continue
+		}
+		seen[value] = struct{}{}
+		out = append(out, value)
+	}
+	return out
+}
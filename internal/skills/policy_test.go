@@ -17,7 +17,7 @@ func TestApplyPolicyFilterGlobalAndChannel(t *testing.T) {
 		{ID: "ui-audit", Name: "UI Audit", Valid: true},
 		{ID: "docker-maint", Name: "Docker", Valid: true},
 	}
-	allowed, denied := applyPolicyFilter(cfg, "telegram", input)
+	allowed, denied := applyPolicyFilter(cfg, "telegram", input, OverrideSet{})
 	if len(allowed) != 1 || allowed[0].ID != "aws-guard" {
 		t.Fatalf("unexpected allowed skills: %#v", allowed)
 	}
@@ -25,3 +25,32 @@ func TestApplyPolicyFilterGlobalAndChannel(t *testing.T) {
 		t.Fatalf("expected deny reason for ui-audit, got %#v", denied)
 	}
 }
+
+func TestEvaluatePolicyAppliesOverridesBeforeConfig(t *testing.T) {
+	cfg := config.Default()
+	cfg.Skills.Policy.Deny = []string{"ui-audit"}
+	uiAudit := SkillDescriptor{ID: "ui-audit", Name: "UI Audit", Valid: true}
+	planner := SkillDescriptor{ID: "planner", Name: "Planner", Valid: true}
+
+	var overrides OverrideSet
+	overrides.Set("UI-Audit", "", true)
+	overrides.Set("planner", "", false)
+	overrides.Set("planner", "Slack", true)
+
+	if got := EvaluatePolicy(cfg, "telegram", uiAudit, overrides); !got.Allowed || got.Source != "override" {
+		t.Fatalf("expected override to re-enable a config-denied skill, got %+v", got)
+	}
+	if got := EvaluatePolicy(cfg, "telegram", planner, overrides); got.Allowed || got.Reason != "disabled_by_override" {
+		t.Fatalf("expected global disable override, got %+v", got)
+	}
+	if got := EvaluatePolicy(cfg, "slack", planner, overrides); !got.Allowed {
+		t.Fatalf("expected channel override to win over the global one, got %+v", got)
+	}
+
+	if !overrides.Clear("ui-audit", "") {
+		t.Fatal("expected override to be cleared")
+	}
+	if got := EvaluatePolicy(cfg, "telegram", uiAudit, overrides); got.Allowed || got.Source != "config" {
+		t.Fatalf("expected config deny after clearing override, got %+v", got)
+	}
+}
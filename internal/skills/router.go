@@ -33,6 +33,10 @@ func routeSkills(req ActivationRequest, snapshot IndexSnapshot, cfg config.Confi
 	}
 	query := strings.TrimSpace(req.Query)
 	explicit := collectExplicitMentions(query, req.ExplicitMentions)
+	focus := strings.TrimSpace(req.Focus)
+	if focus != "" {
+		explicit = []string{focus}
+	}
 	result.Diagnostics.Explicit = append(result.Diagnostics.Explicit, explicit...)
 
 	validSkills := make([]SkillDescriptor, 0, len(snapshot.Skills))
@@ -45,7 +49,7 @@ func routeSkills(req ActivationRequest, snapshot IndexSnapshot, cfg config.Confi
 		}
 		validSkills = append(validSkills, skill)
 	}
-	allowedSkills, deniedReasons := applyPolicyFilter(cfg, req.Channel, validSkills)
+	allowedSkills, deniedReasons := applyPolicyFilter(cfg, req.Channel, validSkills, req.Overrides)
 
 	deniedByName := map[string]string{}
 	for _, skill := range validSkills {
@@ -78,6 +82,10 @@ func routeSkills(req ActivationRequest, snapshot IndexSnapshot, cfg config.Confi
 		if score == 0 && !isExplicit {
 			continue
 		}
+		if focus != "" && !isExplicit {
+			result.Skipped = append(result.Skipped, SkillSkip{ID: skill.ID, Name: skill.Name, Reason: "focus", Score: score})
+			continue
+		}
 		scored = append(scored, scoredSkill{Skill: skill, Score: score, Explicit: isExplicit, MatchedBy: matchedBy, Breakdown: breakdown})
 	}
 
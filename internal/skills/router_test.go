@@ -28,6 +28,34 @@ func TestRouteSkillsExplicitTakesPriorityAndCaps(t *testing.T) {
 	}
 }
 
+func TestRouteSkillsFocusActivatesOnlyFocusedSkill(t *testing.T) {
+	cfg := config.Default()
+	cfg.Skills.MatchThreshold = 1
+	snapshot := IndexSnapshot{Skills: []SkillDescriptor{
+		{ID: "intake", Name: "Intake", Description: "Client intake interview", Valid: true},
+		{ID: "aws-guard", Name: "AWS Guard", Description: "Secure aws changes", Tags: []string{"aws"}, Valid: true},
+		{ID: "ui-audit", Name: "UI Audit", Description: "Inspect frontend UX", Tags: []string{"ui"}, Valid: true},
+	}}
+
+	result := routeSkills(ActivationRequest{Query: "use $ui-audit on the aws changes", Focus: "intake"}, snapshot, cfg)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected activation errors: %v", result.Errors)
+	}
+	if len(result.Activated) != 1 || result.Activated[0].Skill.Descriptor.ID != "intake" || !result.Activated[0].Explicit {
+		t.Fatalf("expected only the focused skill, got %+v", result.Activated)
+	}
+	for _, skip := range result.Skipped {
+		if skip.Reason != "focus" {
+			t.Fatalf("expected other skills skipped for focus, got %+v", skip)
+		}
+	}
+
+	missing := routeSkills(ActivationRequest{Query: "hello", Focus: "gone"}, snapshot, cfg)
+	if len(missing.Errors) == 0 {
+		t.Fatal("expected an error when the focused skill no longer exists")
+	}
+}
+
 func TestRouteSkillsExplicitMissingFails(t *testing.T) {
 	cfg := config.Default()
 	snapshot := IndexSnapshot{Skills: []SkillDescriptor{{ID: "known", Name: "Known", Valid: true}}}
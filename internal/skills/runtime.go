@@ -28,6 +28,12 @@ type ActivationRequest struct {
 	SessionID        string
 	ExplicitMentions []string
 	IsSubagent       bool
+	// Overrides are stored enable/disable decisions applied before config
+	// policy; see LoadOverrides.
+	Overrides OverrideSet
+	// Focus pins activation to one skill: it is activated as if mentioned
+	// explicitly, other mentions are ignored, and every other skill is skipped.
+	Focus string
 }
 
 type ActivationDiagnostics struct {
@@ -139,7 +145,7 @@ func NewManager(cfg config.Config, logger *log.Logger) *Manager {
 		logger = log.Default()
 	}
 	workspace := config.WorkspacePath(cfg)
-	refresh := time.Duration(maxInt(cfg.Skills.RefreshIntervalSec, 30)) * time.Second
+	refresh := RefreshPeriod(cfg)
 	cacheDir := strings.TrimSpace(cfg.Skills.CacheDir)
 	if cacheDir == "" {
 		cacheDir = filepath.Join(workspace, ".squidbot", "skills-cache")
@@ -157,6 +163,24 @@ func NewManager(cfg config.Config, logger *log.Logger) *Manager {
 	}
 }
 
+// RefreshPeriod is how long a discovered skill index stays fresh:
+// skills.refreshIntervalSec, at least 30 seconds.
+func RefreshPeriod(cfg config.Config) time.Duration {
+	return time.Duration(maxInt(cfg.Skills.RefreshIntervalSec, 30)) * time.Second
+}
+
+// Counts returns how many discovered skills are valid and invalid.
+func (s IndexSnapshot) Counts() (valid, invalid int) {
+	for _, skill := range s.Skills {
+		if skill.Valid {
+			valid++
+		} else {
+			invalid++
+		}
+	}
+	return valid, invalid
+}
+
 func (m *Manager) Discover(ctx context.Context) error {
 	if m == nil {
 		return nil
@@ -1,6 +1,7 @@
 package bbolt
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -31,6 +32,10 @@ func budgetReservationKey(id string) string {
 	return "reservation:" + strings.TrimSpace(id)
 }
 
+func toolUsageKey(day, tool string) string {
+	return strings.TrimSpace(day) + ":" + strings.TrimSpace(tool)
+}
+
 func budgetEventKey(id string) string {
 	return "event:" + strings.TrimSpace(id)
 }
@@ -277,6 +282,71 @@ func (s *Store) CancelBudgetReservation(ctx context.Context, reservationID strin
 	})
 }
 
+// ResetBudgetCounter zeroes the counter for scope and cancels its open
+// reservations, so a scope blocked by runaway usage or stale reservations
+// passes preflight again at once.
+func (s *Store) ResetBudgetCounter(ctx context.Context, scope string) (budget.ResetResult, error) {
+	scope = strings.TrimSpace(scope)
+	if scope == "" {
+		return budget.ResetResult{}, fmt.Errorf("scope is required")
+	}
+	now := time.Now().UTC()
+	result := budget.ResetResult{Scope: scope}
+	err := s.runWrite(ctx, func(tx *bbolt.Tx) error {
+		previous, err := s.getBudgetCounterTx(tx, scope)
+		if err != nil {
+			return err
+		}
+		result.Previous = previous
+		reservations := tx.Bucket(bucketBudgetReservations)
+		cleared := make(map[string][]byte)
+		err = reservations.ForEach(func(key, value []byte) error {
+			var reservation budget.Reservation
+			if err := json.Unmarshal(value, &reservation); err != nil {
+				return nil
+			}
+			if reservation.Scope != scope || reservation.Finalized || reservation.Cancelled {
+				return nil
+			}
+			reservation.Cancelled = true
+			reservation.FinalizedAt = now
+			raw, err := json.Marshal(reservation)
+			if err != nil {
+				return err
+			}
+			cleared[string(key)] = raw
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for key, raw := range cleared {
+			if err := reservations.Put([]byte(key), raw); err != nil {
+				return err
+			}
+		}
+		result.ClearedReservations = len(cleared)
+		if err := s.putBudgetCounterTx(tx, budget.Counter{Scope: scope, UpdatedAt: now}); err != nil {
+			return err
+		}
+		return s.putBudgetEventTx(tx, budgetEvent{
+			ID:        s.nextULID(),
+			Kind:      "budget_counter_reset",
+			Scope:     scope,
+			CreatedAt: now,
+			Metadata: map[string]any{
+				"previous_total_tokens":    previous.TotalTokens,
+				"previous_reserved_tokens": previous.ReservedTokens,
+				"cleared_reservations":     len(cleared),
+			},
+		})
+	})
+	if err != nil {
+		return budget.ResetResult{}, err
+	}
+	return result, nil
+}
+
 func (s *Store) ListBudgetReservations(_ context.Context, scope string, limit int) ([]budget.Reservation, error) {
 	scope = strings.TrimSpace(scope)
 	if limit <= 0 {
@@ -308,6 +378,76 @@ func (s *Store) ListBudgetReservations(_ context.Context, scope string, limit in
 	return out, nil
 }
 
+// ConsumeToolQuota counts one call of tool on day and reports whether it is
+// within limit. A limit of zero or less never blocks. Blocked calls are
+// counted separately and do not use up the quota.
+func (s *Store) ConsumeToolQuota(ctx context.Context, day, tool string, limit int) (budget.ToolUsage, bool, error) {
+	day = strings.TrimSpace(day)
+	if day == "" {
+		day = time.Now().UTC().Format("2006-01-02")
+	}
+	tool = strings.TrimSpace(tool)
+	if tool == "" {
+		return budget.ToolUsage{}, false, fmt.Errorf("tool is required")
+	}
+	var (
+		usage   budget.ToolUsage
+		allowed bool
+	)
+	err := s.runWrite(ctx, func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketToolUsageDaily)
+		key := []byte(toolUsageKey(day, tool))
+		usage = budget.ToolUsage{Day: day, Tool: tool}
+		if existing := bucket.Get(key); existing != nil {
+			if err := json.Unmarshal(existing, &usage); err != nil {
+				return err
+			}
+		}
+		allowed = limit <= 0 || usage.Calls < limit
+		if allowed {
+			usage.Calls++
+		} else {
+			usage.Blocked++
+		}
+		usage.UpdatedAt = time.Now().UTC()
+		raw, err := json.Marshal(usage)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(key, raw)
+	})
+	if err != nil {
+		return budget.ToolUsage{}, false, err
+	}
+	return usage, allowed, nil
+}
+
+// ListToolUsage returns the per-tool counters for day, sorted by tool name.
+func (s *Store) ListToolUsage(_ context.Context, day string) ([]budget.ToolUsage, error) {
+	day = strings.TrimSpace(day)
+	if day == "" {
+		day = time.Now().UTC().Format("2006-01-02")
+	}
+	prefix := []byte(day + ":")
+	out := make([]budget.ToolUsage, 0)
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		cursor := tx.Bucket(bucketToolUsageDaily).Cursor()
+		for key, value := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, value = cursor.Next() {
+			var usage budget.ToolUsage
+			if err := json.Unmarshal(value, &usage); err != nil {
+				continue
+			}
+			out = append(out, usage)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Tool < out[j].Tool })
+	return out, nil
+}
+
 func (s *Store) getBudgetCounterTx(tx *bbolt.Tx, scope string) (budget.Counter, error) {
 	scope = strings.TrimSpace(scope)
 	counter := budget.Counter{Scope: scope}
@@ -128,3 +128,95 @@ func TestBudgetReserveFinalizeAndCancel(t *testing.T) {
 		t.Fatalf("expected total usage persisted across restart, got %d", counter.TotalTokens)
 	}
 }
+
+func TestConsumeToolQuotaStopsAtLimit(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "tool-quota.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, allowed, err := store.ConsumeToolQuota(ctx, "2026-01-02", "web_search", 2); err != nil || !allowed {
+			t.Fatalf("call %d: allowed=%v err=%v", i+1, allowed, err)
+		}
+	}
+	usage, allowed, err := store.ConsumeToolQuota(ctx, "2026-01-02", "web_search", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if allowed || usage.Calls != 2 || usage.Blocked != 1 {
+		t.Fatalf("expected third call blocked, got allowed=%v usage=%+v", allowed, usage)
+	}
+	if _, allowed, _ := store.ConsumeToolQuota(ctx, "2026-01-03", "web_search", 2); !allowed {
+		t.Fatal("expected quota to reset on a new day")
+	}
+
+	usages, err := store.ListToolUsage(ctx, "2026-01-02")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(usages) != 1 || usages[0].Calls != 2 {
+		t.Fatalf("unexpected usage list: %+v", usages)
+	}
+}
+
+func TestResetBudgetCounterClearsUsageAndOpenReservations(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "budget-reset.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+	scope := "session:cli:loop"
+
+	if err := store.AddBudgetUsage(ctx, scope, 80, 20, 100); err != nil {
+		t.Fatal(err)
+	}
+	stale, err := store.ReserveBudget(ctx, scope, 50, 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := store.ReserveBudget(ctx, "global", 30, 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := store.ResetBudgetCounter(ctx, scope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Previous.TotalTokens != 100 || result.Previous.ReservedTokens != 50 || result.ClearedReservations != 1 {
+		t.Fatalf("unexpected reset result: %+v", result)
+	}
+	counter, err := store.GetBudgetCounter(ctx, scope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counter.TotalTokens != 0 || counter.ReservedTokens != 0 || counter.PromptTokens != 0 {
+		t.Fatalf("expected zeroed counter, got %+v", counter)
+	}
+
+	// Finalizing the cleared reservation must not bring its tokens back.
+	if err := store.FinalizeBudgetReservation(ctx, stale, 40); err != nil {
+		t.Fatal(err)
+	}
+	counter, err = store.GetBudgetCounter(ctx, scope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counter.TotalTokens != 0 {
+		t.Fatalf("expected cleared reservation to stay cleared, got %+v", counter)
+	}
+	global, err := store.GetBudgetCounter(ctx, "global")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if global.ReservedTokens != 30 {
+		t.Fatalf("expected other scopes untouched, got %+v", global)
+	}
+	if err := store.CancelBudgetReservation(ctx, other); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,8 +1,10 @@
 package bbolt
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"sort"
 	"strings"
 	"time"
@@ -74,6 +76,84 @@ func (s *Store) ListMissionTasks(_ context.Context) ([]mission.Task, error) {
 	return out, nil
 }
 
+// ListMissionTasksPage returns tasks in ID order starting after
+// query.Cursor, so a page costs a seek plus the tasks it returns rather than
+// a load and sort of the whole board.
+func (s *Store) ListMissionTasksPage(_ context.Context, query mission.TaskPageQuery) (mission.TaskPage, error) {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = mission.DefaultTaskPageSize
+	}
+	limit = min(limit, mission.MaxTaskPageSize)
+	columnID := strings.TrimSpace(query.ColumnID)
+	page := mission.TaskPage{Tasks: make([]mission.Task, 0, min(limit, 32))}
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		prefix := []byte(missionTaskKey(""))
+		cursor := tx.Bucket(bucketMissionTasks).Cursor()
+		k, v := cursor.Seek(prefix)
+		if after := strings.TrimSpace(query.Cursor); after != "" {
+			k, v = cursor.Seek([]byte(missionTaskKey(after)))
+			if k != nil && string(k) == missionTaskKey(after) {
+				k, v = cursor.Next()
+			}
+		}
+		for ; k != nil && bytes.HasPrefix(k, prefix); k, v = cursor.Next() {
+			var task mission.Task
+			if err := json.Unmarshal(v, &task); err != nil {
+				continue
+			}
+			if columnID != "" && task.ColumnID != columnID {
+				continue
+			}
+			if len(page.Tasks) == limit {
+				page.NextCursor = page.Tasks[limit-1].ID
+				return nil
+			}
+			page.Tasks = append(page.Tasks, task)
+		}
+		return nil
+	})
+	if err != nil {
+		return mission.TaskPage{}, err
+	}
+	return page, nil
+}
+
+// MissionTaskCounts counts tasks per column and open tasks that are overdue
+// or due within dueSoon of now, decoding only the fields it needs.
+func (s *Store) MissionTaskCounts(_ context.Context, now time.Time, dueSoon time.Duration) (mission.TaskCounts, error) {
+	counts := mission.TaskCounts{ByColumn: map[string]int{}}
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucketMissionTasks).ForEach(func(_, v []byte) error {
+			var task struct {
+				ColumnID string     `json:"column_id"`
+				DueAt    *time.Time `json:"due_at"`
+			}
+			if err := json.Unmarshal(v, &task); err != nil {
+				return nil
+			}
+			counts.Total++
+			counts.ByColumn[task.ColumnID]++
+			if task.ColumnID == mission.ColumnDone {
+				return nil
+			}
+			counts.Open++
+			switch {
+			case task.DueAt == nil:
+			case task.DueAt.Before(now):
+				counts.Overdue++
+			case !task.DueAt.After(now.Add(dueSoon)):
+				counts.DueSoon++
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return mission.TaskCounts{}, err
+	}
+	return counts, nil
+}
+
 func (s *Store) ReplaceMissionColumns(ctx context.Context, columns []mission.Column) error {
 	return s.runWrite(ctx, func(tx *bbolt.Tx) error {
 		if err := tx.DeleteBucket(bucketMissionColumns); err != nil && err != bbolt.ErrBucketNotFound {
@@ -115,7 +195,9 @@ func (s *Store) ListMissionColumns(_ context.Context) ([]mission.Column, error)
 	return out, nil
 }
 
-func (s *Store) RecordUsageDay(ctx context.Context, day string, promptTokens, completionTokens, totalTokens uint64) error {
+// RecordUsageDay adds tokens to the day's totals and, when label is set, to
+// that provider's share of the day.
+func (s *Store) RecordUsageDay(ctx context.Context, day, label string, promptTokens, completionTokens, totalTokens, cacheReadTokens, cacheWriteTokens uint64) error {
 	day = strings.TrimSpace(day)
 	if day == "" {
 		day = time.Now().UTC().Format("2006-01-02")
@@ -130,6 +212,61 @@ func (s *Store) RecordUsageDay(ctx context.Context, day string, promptTokens, co
 		current.PromptTokens += promptTokens
 		current.CompletionTokens += completionTokens
 		current.TotalTokens += totalTokens
+		current.CacheReadTokens += cacheReadTokens
+		current.CacheWriteTokens += cacheWriteTokens
+		if label = strings.TrimSpace(label); label != "" {
+			if current.ByProvider == nil {
+				current.ByProvider = map[string]mission.ProviderUsage{}
+			}
+			share := current.ByProvider[label]
+			share.PromptTokens += promptTokens
+			share.CompletionTokens += completionTokens
+			share.TotalTokens += totalTokens
+			current.ByProvider[label] = share
+		}
+		current.UpdatedAt = time.Now().UTC()
+		bytes, err := json.Marshal(current)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(key, bytes)
+	})
+}
+
+// RecordProviderThroughput adds one timed provider call to the label's
+// throughput totals for the day.
+func (s *Store) RecordProviderThroughput(ctx context.Context, day, label string, completionTokens uint64, latency time.Duration) error {
+	label = strings.TrimSpace(label)
+	if label == "" {
+		return fmt.Errorf("label is required")
+	}
+	if completionTokens == 0 || latency <= 0 {
+		return nil
+	}
+	day = strings.TrimSpace(day)
+	if day == "" {
+		day = time.Now().UTC().Format("2006-01-02")
+	}
+	latencyMS := max(uint64(latency.Milliseconds()), 1)
+	rate := float64(completionTokens) / latency.Seconds()
+	return s.runWrite(ctx, func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketUsageDaily)
+		key := []byte(usageDayKey(day))
+		current := mission.UsageDay{Day: day}
+		if existing := bucket.Get(key); existing != nil {
+			_ = json.Unmarshal(existing, &current)
+		}
+		if current.ByProvider == nil {
+			current.ByProvider = map[string]mission.ProviderUsage{}
+		}
+		share := current.ByProvider[label]
+		share.Calls++
+		share.TimedTokens += completionTokens
+		share.LatencyMS += latencyMS
+		if rate > share.MaxTokensPerSec {
+			share.MaxTokensPerSec = rate
+		}
+		current.ByProvider[label] = share
 		current.UpdatedAt = time.Now().UTC()
 		bytes, err := json.Marshal(current)
 		if err != nil {
@@ -221,6 +358,7 @@ func (s *Store) GetTaskAutomationPolicy(_ context.Context) (mission.TaskAutomati
 	if policy.DedupeWindowSec <= 0 {
 		policy.DedupeWindowSec = int((6 * time.Hour).Seconds())
 	}
+	_ = policy.NormalizeDedupe()
 	return policy, nil
 }
 
@@ -248,10 +386,18 @@ func (s *Store) ListHeartbeatRuns(_ context.Context, limit int) ([]mission.Heart
 	return out, nil
 }
 
-func (s *Store) ListToolEvents(_ context.Context, limit int) ([]agent.ToolEvent, error) {
+func (s *Store) ListToolEvents(ctx context.Context, limit int) ([]agent.ToolEvent, error) {
+	return s.QueryToolEvents(ctx, "", "", limit)
+}
+
+// QueryToolEvents returns up to limit of the newest tool events, keeping only
+// those for sessionID and toolName when they are set.
+func (s *Store) QueryToolEvents(_ context.Context, sessionID, toolName string, limit int) ([]agent.ToolEvent, error) {
 	if limit <= 0 {
 		limit = 100
 	}
+	sessionID = strings.TrimSpace(sessionID)
+	toolName = strings.TrimSpace(toolName)
 	out := make([]agent.ToolEvent, 0, limit)
 	err := s.db.View(func(tx *bbolt.Tx) error {
 		bucket := tx.Bucket(bucketToolEvents)
@@ -261,6 +407,12 @@ func (s *Store) ListToolEvents(_ context.Context, limit int) ([]agent.ToolEvent,
 			if err := json.Unmarshal(v, &event); err != nil {
 				continue
 			}
+			if sessionID != "" && event.SessionID != sessionID {
+				continue
+			}
+			if toolName != "" && event.ToolName != toolName {
+				continue
+			}
 			out = append(out, event)
 		}
 		return nil
@@ -44,6 +44,7 @@ var (
 	bucketBudgetCounters      = []byte("budget_counters")
 	bucketBudgetReservations  = []byte("budget_reservations")
 	bucketBudgetEvents        = []byte("budget_events")
+	bucketToolUsageDaily      = []byte("tool_usage_daily")
 )
 
 type writeTask struct {
@@ -122,6 +123,7 @@ func (s *Store) initSchema() error {
 			bucketBudgetCounters,
 			bucketBudgetReservations,
 			bucketBudgetEvents,
+			bucketToolUsageDaily,
 		}
 		for _, b := range buckets {
 			if _, err := tx.CreateBucketIfNotExists(b); err != nil {
@@ -257,15 +259,150 @@ func (s *Store) Window(ctx context.Context, sessionID string, limit int) ([]prov
 	return messages, nil
 }
 
-func (s *Store) SaveSessionMeta(ctx context.Context, sessionID string, meta map[string]any) error {
-	record := map[string]any{"session_id": sessionID, "meta": meta, "updated_at": time.Now().UTC(), "version": 1}
-	bytes, err := json.Marshal(record)
+// SessionTurns returns every stored turn for a session in chronological order.
+func (s *Store) SessionTurns(_ context.Context, sessionID string) ([]agent.Turn, error) {
+	prefix := []byte("turn:" + sessionID + ":")
+	turns := make([]agent.Turn, 0)
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		cursor := tx.Bucket(bucketTurns).Cursor()
+		for key, value := cursor.Seek(prefix); key != nil && strings.HasPrefix(string(key), string(prefix)); key, value = cursor.Next() {
+			var turn agent.Turn
+			if err := json.Unmarshal(value, &turn); err != nil {
+				continue
+			}
+			if turn.SessionID != sessionID {
+				continue
+			}
+			turns = append(turns, turn)
+		}
+		return nil
+	})
 	if err != nil {
-		return err
+		return nil, err
 	}
+	sort.SliceStable(turns, func(i, j int) bool {
+		return turns[i].CreatedAt.Before(turns[j].CreatedAt)
+	})
+	return turns, nil
+}
+
+// SearchTurns scans a session's user and assistant turns for content that
+// contains every whitespace-separated term of query, case-insensitively.
+// Matches are returned newest first.
+func (s *Store) SearchTurns(_ context.Context, sessionID, query string, limit int) ([]agent.Turn, error) {
+	terms := strings.Fields(strings.ToLower(query))
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+	prefix := []byte("turn:" + sessionID + ":")
+	matches := make([]agent.Turn, 0, limit)
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		cursor := tx.Bucket(bucketTurns).Cursor()
+		for key, value := cursor.Seek(prefix); key != nil && strings.HasPrefix(string(key), string(prefix)); key, value = cursor.Next() {
+			var turn agent.Turn
+			if err := json.Unmarshal(value, &turn); err != nil {
+				continue
+			}
+			if turn.SessionID != sessionID || (turn.Role != "user" && turn.Role != "assistant") {
+				continue
+			}
+			content := strings.ToLower(turn.Content)
+			matched := true
+			for _, term := range terms {
+				if !strings.Contains(content, term) {
+					matched = false
+					break
+				}
+			}
+			if matched {
+				matches = append(matches, turn)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].CreatedAt.After(matches[j].CreatedAt)
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
+// SaveSessionMeta merges meta into the session's stored metadata, so keys
+// written by other callers (such as the session title) are preserved.
+func (s *Store) SaveSessionMeta(ctx context.Context, sessionID string, meta map[string]any) error {
 	return s.runWrite(ctx, func(tx *bbolt.Tx) error {
-		return tx.Bucket(bucketSessions).Put([]byte(sessionKey(sessionID)), bytes)
+		bucket := tx.Bucket(bucketSessions)
+		key := []byte(sessionKey(sessionID))
+		record := agent.SessionRecord{SessionID: sessionID, Meta: map[string]any{}}
+		if existing := bucket.Get(key); existing != nil {
+			_ = json.Unmarshal(existing, &record)
+			if record.Meta == nil {
+				record.Meta = map[string]any{}
+			}
+		}
+		for k, v := range meta {
+			record.Meta[k] = v
+		}
+		record.SessionID = sessionID
+		record.UpdatedAt = time.Now().UTC()
+		record.Version = 1
+		bytes, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(key, bytes)
+	})
+}
+
+func (s *Store) GetSessionMeta(_ context.Context, sessionID string) (map[string]any, error) {
+	var record agent.SessionRecord
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		value := tx.Bucket(bucketSessions).Get([]byte(sessionKey(sessionID)))
+		if value == nil {
+			return nil
+		}
+		return json.Unmarshal(value, &record)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if record.Meta == nil {
+		record.Meta = map[string]any{}
+	}
+	return record.Meta, nil
+}
+
+// ListSessions returns every session with stored metadata, most recently
+// updated first.
+func (s *Store) ListSessions(_ context.Context) ([]agent.SessionRecord, error) {
+	records := make([]agent.SessionRecord, 0)
+	prefix := []byte("sess:")
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		cursor := tx.Bucket(bucketSessions).Cursor()
+		for key, value := cursor.Seek(prefix); key != nil && strings.HasPrefix(string(key), string(prefix)); key, value = cursor.Next() {
+			var record agent.SessionRecord
+			if err := json.Unmarshal(value, &record); err != nil {
+				continue
+			}
+			records = append(records, record)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].UpdatedAt.After(records[j].UpdatedAt)
 	})
+	return records, nil
 }
 
 func (s *Store) AppendToolEvent(ctx context.Context, event agent.ToolEvent) error {
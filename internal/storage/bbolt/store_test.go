@@ -3,9 +3,12 @@ package bbolt
 import (
 	"context"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/grixate/squidbot/internal/agent"
+	"github.com/grixate/squidbot/internal/mission"
 )
 
 func TestAppendAndWindow(t *testing.T) {
@@ -35,3 +38,181 @@ func TestAppendAndWindow(t *testing.T) {
 		t.Fatalf("unexpected second message: %s", window[1].Content)
 	}
 }
+
+func TestSearchTurnsMatchesAllTermsWithinSession(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	turns := []agent.Turn{
+		{SessionID: "s1", Role: "user", Content: "The deploy key lives in the vault"},
+		{SessionID: "s1", Role: "tool", Content: "vault deploy output"},
+		{SessionID: "s1", Role: "assistant", Content: "Noted: DEPLOY uses the Vault key"},
+		{SessionID: "s1", Role: "user", Content: "unrelated chatter"},
+		{SessionID: "s10", Role: "user", Content: "deploy vault in another session"},
+	}
+	for _, turn := range turns {
+		if err := store.AppendTurn(ctx, turn); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	matches, err := store.SearchTurns(ctx, "s1", "vault deploy", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 2 {
+		t.Fatalf("expected 2 matches, got %+v", matches)
+	}
+	if matches[0].Role != "assistant" || matches[1].Role != "user" {
+		t.Fatalf("expected newest first, got %+v", matches)
+	}
+
+	limited, err := store.SearchTurns(ctx, "s1", "vault", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(limited) != 1 {
+		t.Fatalf("expected limit to apply, got %d", len(limited))
+	}
+}
+
+func TestRecordUsageDayAttributesProviders(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "usage.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+
+	// A day written before provider attribution has no by_provider field.
+	if err := store.PutUsageDay(ctx, mission.UsageDay{Day: "2026-05-01", PromptTokens: 7, TotalTokens: 7}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RecordUsageDay(ctx, "2026-05-01", "anthropic/claude-sonnet-4", 10, 5, 15, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RecordUsageDay(ctx, "2026-05-01", "ollama/llama3", 3, 1, 4, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RecordUsageDay(ctx, "2026-05-01", "", 1, 0, 1, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	days, err := store.ListUsageDays(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(days) != 1 || days[0].TotalTokens != 27 {
+		t.Fatalf("expected aggregate totals to include every call, got %+v", days)
+	}
+	if len(days[0].ByProvider) != 2 || days[0].ByProvider["anthropic/claude-sonnet-4"].TotalTokens != 15 || days[0].ByProvider["ollama/llama3"].PromptTokens != 3 {
+		t.Fatalf("unexpected provider split: %+v", days[0].ByProvider)
+	}
+}
+
+func TestRecordProviderThroughputTracksAverageAndMax(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "throughput.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+
+	if err := store.RecordUsageDay(ctx, "2026-05-01", "openai/gpt-4o", 10, 100, 110, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RecordProviderThroughput(ctx, "2026-05-01", "openai/gpt-4o", 100, 2*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RecordProviderThroughput(ctx, "2026-05-01", "openai/gpt-4o", 200, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	// Calls without completion tokens are not timed.
+	if err := store.RecordProviderThroughput(ctx, "2026-05-01", "openai/gpt-4o", 0, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	days, err := store.ListUsageDays(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	share := days[0].ByProvider["openai/gpt-4o"]
+	if share.TotalTokens != 110 || share.Calls != 2 || share.LatencyMS != 3000 || share.TimedTokens != 300 {
+		t.Fatalf("unexpected throughput totals: %+v", share)
+	}
+	if share.TokensPerSec() != 100 || share.MaxTokensPerSec != 200 {
+		t.Fatalf("expected avg 100 and max 200 tok/s, got %v and %v", share.TokensPerSec(), share.MaxTokensPerSec)
+	}
+}
+
+func TestMissionTaskPagesAndCounts(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+	overdue := now.Add(-time.Hour)
+	soon := now.Add(2 * time.Hour)
+	later := now.Add(72 * time.Hour)
+	tasks := []mission.Task{
+		{ID: "t01", ColumnID: mission.ColumnBacklog, DueAt: &overdue},
+		{ID: "t02", ColumnID: mission.ColumnDone, DueAt: &overdue},
+		{ID: "t03", ColumnID: mission.ColumnBacklog, DueAt: &soon},
+		{ID: "t04", ColumnID: mission.ColumnInProgress, DueAt: &later},
+		{ID: "t05", ColumnID: mission.ColumnBacklog},
+	}
+	for _, task := range tasks {
+		if err := store.PutMissionTask(ctx, task); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var ids []string
+	cursor := ""
+	pages := 0
+	for {
+		page, err := store.ListMissionTasksPage(ctx, mission.TaskPageQuery{Limit: 2, Cursor: cursor})
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages++
+		for _, task := range page.Tasks {
+			ids = append(ids, task.ID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	if pages != 3 || strings.Join(ids, ",") != "t01,t02,t03,t04,t05" {
+		t.Fatalf("unexpected paging: %d pages, %v", pages, ids)
+	}
+
+	page, err := store.ListMissionTasksPage(ctx, mission.TaskPageQuery{ColumnID: mission.ColumnBacklog, Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Tasks) != 2 || page.Tasks[1].ID != "t03" || page.NextCursor != "t03" {
+		t.Fatalf("unexpected backlog page: %+v", page)
+	}
+	page, err = store.ListMissionTasksPage(ctx, mission.TaskPageQuery{ColumnID: mission.ColumnBacklog, Limit: 2, Cursor: page.NextCursor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Tasks) != 1 || page.Tasks[0].ID != "t05" || page.NextCursor != "" {
+		t.Fatalf("unexpected last backlog page: %+v", page)
+	}
+
+	counts, err := store.MissionTaskCounts(ctx, now, mission.DueSoonWindow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts.Total != 5 || counts.Open != 4 || counts.Overdue != 1 || counts.DueSoon != 1 || counts.ByColumn[mission.ColumnBacklog] != 3 {
+		t.Fatalf("unexpected counts: %+v", counts)
+	}
+}
@@ -1,6 +1,7 @@
 package bbolt
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -118,3 +119,64 @@ func (s *Store) AppendSubagentEvent(ctx context.Context, event subagent.Event) e
 		return tx.Bucket(bucketSubagentEvents).Put([]byte(subagentEventKey(event)), bytes)
 	})
 }
+
+// PruneSubagentRuns deletes terminal runs that finished before cutoff, along
+// with their events. An empty statuses list matches every terminal status;
+// queued and running runs are never removed. The deleted runs are returned so
+// callers can clean up artifacts.
+func (s *Store) PruneSubagentRuns(ctx context.Context, cutoff time.Time, statuses []subagent.Status) ([]subagent.Run, error) {
+	allowed := map[subagent.Status]bool{}
+	for _, status := range statuses {
+		allowed[subagent.Status(strings.TrimSpace(strings.ToLower(string(status))))] = true
+	}
+	pruned := make([]subagent.Run, 0, 16)
+	err := s.runWrite(ctx, func(tx *bbolt.Tx) error {
+		runs := tx.Bucket(bucketSubagentRuns)
+		var keys [][]byte
+		if err := runs.ForEach(func(k, v []byte) error {
+			var run subagent.Run
+			if err := json.Unmarshal(v, &run); err != nil {
+				return nil
+			}
+			if !run.Status.Terminal() || (len(allowed) > 0 && !allowed[run.Status]) {
+				return nil
+			}
+			finished := run.CreatedAt
+			if run.FinishedAt != nil {
+				finished = *run.FinishedAt
+			}
+			if !finished.Before(cutoff) {
+				return nil
+			}
+			keys = append(keys, append([]byte(nil), k...))
+			pruned = append(pruned, run)
+			return nil
+		}); err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if err := runs.Delete(key); err != nil {
+				return err
+			}
+		}
+		events := tx.Bucket(bucketSubagentEvents)
+		for _, run := range pruned {
+			prefix := []byte("event:" + strings.TrimSpace(run.ID) + ":")
+			var eventKeys [][]byte
+			cursor := events.Cursor()
+			for k, _ := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = cursor.Next() {
+				eventKeys = append(eventKeys, append([]byte(nil), k...))
+			}
+			for _, key := range eventKeys {
+				if err := events.Delete(key); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pruned, nil
+}
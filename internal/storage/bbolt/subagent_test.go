@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/grixate/squidbot/internal/subagent"
+	"go.etcd.io/bbolt"
 )
 
 func TestSubagentRunCRUDAndFiltering(t *testing.T) {
@@ -65,3 +66,63 @@ func TestSubagentRunCRUDAndFiltering(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestPruneSubagentRunsOnlyRemovesOldTerminalRuns(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "subagent.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+	now := time.Now().UTC()
+	old := now.Add(-60 * 24 * time.Hour)
+	recent := now.Add(-time.Hour)
+	runs := []subagent.Run{
+		{ID: "old-failed", Status: subagent.StatusFailed, CreatedAt: old, FinishedAt: &old},
+		{ID: "old-succeeded", Status: subagent.StatusSucceeded, CreatedAt: old, FinishedAt: &old},
+		{ID: "old-running", Status: subagent.StatusRunning, CreatedAt: old},
+		{ID: "recent-failed", Status: subagent.StatusFailed, CreatedAt: recent, FinishedAt: &recent},
+	}
+	for _, run := range runs {
+		if err := store.PutSubagentRun(ctx, run); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.AppendSubagentEvent(ctx, subagent.Event{RunID: run.ID, Status: run.Status, CreatedAt: run.CreatedAt}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pruned, err := store.PruneSubagentRuns(ctx, now.Add(-30*24*time.Hour), []subagent.Status{subagent.StatusFailed, subagent.StatusTimedOut})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pruned) != 1 || pruned[0].ID != "old-failed" {
+		t.Fatalf("expected only old-failed pruned, got %#v", pruned)
+	}
+	if _, err := store.GetSubagentRun(ctx, "old-failed"); err == nil {
+		t.Fatal("expected pruned run to be deleted")
+	}
+
+	pruned, err = store.PruneSubagentRuns(ctx, now.Add(-30*24*time.Hour), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pruned) != 1 || pruned[0].ID != "old-succeeded" {
+		t.Fatalf("expected remaining old terminal run pruned, got %#v", pruned)
+	}
+	for _, id := range []string{"old-running", "recent-failed"} {
+		if _, err := store.GetSubagentRun(ctx, id); err != nil {
+			t.Fatalf("expected %s to be kept: %v", id, err)
+		}
+	}
+	eventCount := 0
+	_ = store.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucketSubagentEvents).ForEach(func(_, _ []byte) error {
+			eventCount++
+			return nil
+		})
+	})
+	if eventCount != 2 {
+		t.Fatalf("expected events of pruned runs removed, %d left", eventCount)
+	}
+}
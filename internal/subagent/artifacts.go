@@ -0,0 +1,64 @@
+package subagent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// TranscriptFile is the JSON-lines log of every message a run exchanged with
+// the provider, written to the run's artifact dir.
+const TranscriptFile = "transcript.jsonl"
+
+// TranscriptPath returns where a run's transcript is written, or "" when the
+// run keeps no artifacts.
+func TranscriptPath(run Run) string {
+	if strings.TrimSpace(run.ArtifactDir) == "" {
+		return ""
+	}
+	return filepath.Join(run.ArtifactDir, TranscriptFile)
+}
+
+// ReapArtifacts removes the artifact directories of terminal runs that
+// finished before cutoff and records the reap time on each run. Directories
+// outside root are never touched.
+func (m *Manager) ReapArtifacts(ctx context.Context, root string, cutoff time.Time) ([]Run, error) {
+	if m == nil || m.store == nil {
+		return nil, fmt.Errorf("subagent manager not configured")
+	}
+	root = filepath.Clean(strings.TrimSpace(root))
+	runs, err := m.store.ListSubagentRunsBySession(ctx, "", 0)
+	if err != nil {
+		return nil, err
+	}
+	reaped := make([]Run, 0)
+	for _, run := range runs {
+		if !run.Status.Terminal() || run.ArtifactsReapedAt != nil || strings.TrimSpace(run.ArtifactDir) == "" {
+			continue
+		}
+		finished := run.CreatedAt
+		if run.FinishedAt != nil {
+			finished = *run.FinishedAt
+		}
+		if !finished.Before(cutoff) {
+			continue
+		}
+		dir := filepath.Clean(run.ArtifactDir)
+		if rel, err := filepath.Rel(root, dir); err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			return reaped, err
+		}
+		reapedAt := m.opts.Clock().UTC()
+		run.ArtifactsReapedAt = &reapedAt
+		if err := m.store.PutSubagentRun(ctx, run); err != nil {
+			return reaped, err
+		}
+		reaped = append(reaped, run)
+	}
+	return reaped, nil
+}
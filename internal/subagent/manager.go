@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/grixate/squidbot/internal/telemetry"
@@ -15,8 +16,50 @@ var (
 	ErrDisabled      = errors.New("subagents are disabled")
 	ErrQueueFull     = errors.New("subagent queue is full")
 	ErrDepthExceeded = errors.New("subagent depth exceeded")
+	ErrNotRetryable  = errors.New("only failed or timed out runs can be retried")
+	ErrLoopDetected  = errors.New("possible loop detected")
 )
 
+// loopScanLimit bounds how many recent session runs the loop guard inspects.
+const loopScanLimit = 256
+
+// defaultLoopWindow is the loop guard's window when Options.LoopWindow is
+// unset.
+const defaultLoopWindow = 10 * time.Minute
+
+// QueueFullError reports an enqueue rejected because every queue slot was
+// taken. It matches ErrQueueFull with errors.Is.
+type QueueFullError struct {
+	Depth    int
+	Capacity int
+}
+
+func (e *QueueFullError) Error() string {
+	return fmt.Sprintf("%s (%d/%d queued)", ErrQueueFull, e.Depth, e.Capacity)
+}
+
+func (e *QueueFullError) Unwrap() error { return ErrQueueFull }
+
+// LoopDetectedError reports a spawn refused because the session already ran
+// Count subagents with the same context checksum at the same depth. It
+// matches ErrLoopDetected with errors.Is.
+type LoopDetectedError struct {
+	Checksum string
+	Depth    int
+	MaxDepth int
+	Count    int
+}
+
+func (e *LoopDetectedError) Error() string {
+	checksum := e.Checksum
+	if len(checksum) > 12 {
+		checksum = checksum[:12]
+	}
+	return fmt.Sprintf("%s: %d runs at depth %d/%d share context %s", ErrLoopDetected, e.Count, e.Depth, e.MaxDepth, checksum)
+}
+
+func (e *LoopDetectedError) Unwrap() error { return ErrLoopDetected }
+
 type Store interface {
 	PutSubagentRun(ctx context.Context, run Run) error
 	GetSubagentRun(ctx context.Context, id string) (Run, error)
@@ -31,6 +74,8 @@ type Executor func(ctx context.Context, run Run) (Result, error)
 
 type NotifyFunc func(run Run)
 
+type ProgressFunc func(run Run, progress Progress)
+
 type IDFunc func() string
 
 type ClockFunc func() time.Time
@@ -44,6 +89,19 @@ type Options struct {
 	RetryBackoff     time.Duration
 	MaxDepth         int
 	NotifyOnComplete bool
+	// FailInterrupted marks runs left running by a restart as failed instead
+	// of resuming them. Runs that already used every attempt fail either way.
+	FailInterrupted bool
+	// LoopThreshold is how many runs in one session may share a context
+	// checksum at the same depth within LoopWindow before further spawns are
+	// refused. Zero disables the guard.
+	LoopThreshold int
+	// LoopWindow is how far back the loop guard counts runs. Zero means ten
+	// minutes.
+	LoopWindow       time.Duration
+	StreamProgress   bool
+	ProgressInterval time.Duration
+	Progress         ProgressFunc
 	NextID           IDFunc
 	Clock            ClockFunc
 }
@@ -59,11 +117,19 @@ type Manager struct {
 	stop  chan struct{}
 	wg    sync.WaitGroup
 
+	// active counts runs a worker is executing; draining stops workers from
+	// taking further queued runs.
+	active   atomic.Int64
+	draining atomic.Bool
+
 	startOnce sync.Once
 	stopOnce  sync.Once
 
 	cancelMu sync.Mutex
 	cancels  map[string]context.CancelFunc
+
+	progressMu   sync.Mutex
+	progressLast map[string]time.Time
 }
 
 func NewManager(opts Options, store Store, exec Executor, notify NotifyFunc, metrics *telemetry.Metrics) *Manager {
@@ -85,6 +151,12 @@ func NewManager(opts Options, store Store, exec Executor, notify NotifyFunc, met
 	if opts.MaxDepth < 0 {
 		opts.MaxDepth = 0
 	}
+	if opts.LoopThreshold < 0 {
+		opts.LoopThreshold = 0
+	}
+	if opts.LoopWindow <= 0 {
+		opts.LoopWindow = defaultLoopWindow
+	}
 	if opts.NextID == nil {
 		opts.NextID = func() string {
 			return fmt.Sprintf("subagent-%d", time.Now().UTC().UnixNano())
@@ -93,6 +165,9 @@ func NewManager(opts Options, store Store, exec Executor, notify NotifyFunc, met
 	if opts.Clock == nil {
 		opts.Clock = func() time.Time { return time.Now().UTC() }
 	}
+	if opts.ProgressInterval <= 0 {
+		opts.ProgressInterval = 15 * time.Second
+	}
 	return &Manager{
 		opts:    opts,
 		store:   store,
@@ -102,6 +177,8 @@ func NewManager(opts Options, store Store, exec Executor, notify NotifyFunc, met
 		queue:   make(chan string, opts.MaxQueue),
 		stop:    make(chan struct{}),
 		cancels: map[string]context.CancelFunc{},
+
+		progressLast: map[string]time.Time{},
 	}
 }
 
@@ -130,6 +207,23 @@ func (m *Manager) Stop() {
 	})
 }
 
+// Drain stops workers from starting queued runs so the runs already
+// executing can finish before Stop.
+func (m *Manager) Drain() {
+	if m == nil {
+		return
+	}
+	m.draining.Store(true)
+}
+
+// ActiveRuns reports how many runs are executing.
+func (m *Manager) ActiveRuns() int {
+	if m == nil {
+		return 0
+	}
+	return int(m.active.Load())
+}
+
 func (m *Manager) Enqueue(ctx context.Context, req Request) (Run, error) {
 	if m == nil || !m.opts.Enabled {
 		return Run{}, ErrDisabled
@@ -144,6 +238,10 @@ func (m *Manager) Enqueue(ctx context.Context, req Request) (Run, error) {
 	if req.Depth > m.opts.MaxDepth {
 		return Run{}, ErrDepthExceeded
 	}
+	// Check before storing so a rejected spawn leaves no queued run behind.
+	if len(m.queue) >= cap(m.queue) {
+		return Run{}, m.rejectQueueFull()
+	}
 	timeoutSec := req.TimeoutSec
 	if timeoutSec <= 0 {
 		timeoutSec = int(m.opts.DefaultTimeout.Seconds())
@@ -173,14 +271,34 @@ func (m *Manager) Enqueue(ctx context.Context, req Request) (Run, error) {
 		NotifyOnComplete: req.NotifyOnComplete,
 		ArtifactDir:      strings.TrimSpace(req.ArtifactDir),
 		Context:          req.Context,
+		RetryOf:          strings.TrimSpace(req.RetryOf),
+	}
+	if !req.Recurring {
+		if loopErr, err := m.checkLoop(ctx, run); err != nil {
+			return Run{}, err
+		} else if loopErr != nil {
+			m.rejectLoop(ctx, run, loopErr)
+			return Run{}, loopErr
+		}
 	}
 	if err := m.store.PutSubagentRun(ctx, run); err != nil {
 		return Run{}, err
 	}
-	if err := m.recordEvent(ctx, run.ID, StatusQueued, "run queued", 0); err != nil {
+	message := "run queued"
+	if run.RetryOf != "" {
+		message = "run queued as retry of " + run.RetryOf
+	}
+	if err := m.recordEvent(ctx, run.ID, StatusQueued, message, 0); err != nil {
 		return Run{}, err
 	}
 	if err := m.enqueueRunID(run.ID); err != nil {
+		// Another caller took the last slot after the check above.
+		finishedAt := m.opts.Clock().UTC()
+		run.Status = StatusFailed
+		run.Error = err.Error()
+		run.FinishedAt = &finishedAt
+		_ = m.store.PutSubagentRun(ctx, run)
+		_ = m.recordEvent(ctx, run.ID, StatusFailed, "rejected: "+run.Error, 0)
 		return Run{}, err
 	}
 	if m.metrics != nil {
@@ -189,6 +307,14 @@ func (m *Manager) Enqueue(ctx context.Context, req Request) (Run, error) {
 	return run, nil
 }
 
+// QueueDepth returns how many runs wait for a worker and how many may wait.
+func (m *Manager) QueueDepth() (depth, capacity int) {
+	if m == nil {
+		return 0, 0
+	}
+	return len(m.queue), cap(m.queue)
+}
+
 func (m *Manager) Recover(ctx context.Context) error {
 	if m == nil || !m.opts.Enabled || m.store == nil {
 		return nil
@@ -218,6 +344,12 @@ func (m *Manager) Recover(ctx context.Context) error {
 		if _, ok := seen[run.ID]; ok {
 			continue
 		}
+		if m.opts.FailInterrupted || run.Attempt >= m.maxAttempts(run) {
+			if err := m.failInterrupted(ctx, run); err != nil {
+				return err
+			}
+			continue
+		}
 		run.Status = StatusQueued
 		run.Error = "recovered after restart"
 		if err := m.store.PutSubagentRun(ctx, run); err != nil {
@@ -233,6 +365,33 @@ func (m *Manager) Recover(ctx context.Context) error {
 	return nil
 }
 
+// failInterrupted closes out a run that was executing when the process
+// stopped and will not be resumed.
+func (m *Manager) failInterrupted(ctx context.Context, run Run) error {
+	finishedAt := m.opts.Clock().UTC()
+	run.Status = StatusFailed
+	run.Error = "interrupted by restart"
+	run.FinishedAt = &finishedAt
+	if err := m.store.PutSubagentRun(ctx, run); err != nil {
+		return err
+	}
+	if m.metrics != nil {
+		m.metrics.SubagentFailed.Add(1)
+	}
+	return m.recordEvent(ctx, run.ID, StatusFailed, run.Error, run.Attempt)
+}
+
+func (m *Manager) maxAttempts(run Run) int {
+	maxAttempts := run.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = m.opts.MaxAttempts
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+	return maxAttempts
+}
+
 func (m *Manager) Wait(ctx context.Context, runIDs []string, timeout time.Duration) ([]Run, error) {
 	if m == nil {
 		return nil, fmt.Errorf("subagent manager not configured")
@@ -343,6 +502,40 @@ func (m *Manager) Cancel(ctx context.Context, runID string) (Run, error) {
 	return run, nil
 }
 
+// Retry enqueues a new run with the task, context packet, and session
+// linkage of a failed or timed out run, and records the link on both runs.
+// newID and artifactDir are used for the new run when set.
+func (m *Manager) Retry(ctx context.Context, original Run, newID, artifactDir string) (Run, error) {
+	if original.Status != StatusFailed && original.Status != StatusTimedOut {
+		return Run{}, fmt.Errorf("run %s is %s: %w", original.ID, original.Status, ErrNotRetryable)
+	}
+	run, err := m.Enqueue(ctx, Request{
+		ID:               newID,
+		SessionID:        original.SessionID,
+		Channel:          original.Channel,
+		ChatID:           original.ChatID,
+		SenderID:         original.SenderID,
+		Task:             original.Task,
+		Label:            original.Label,
+		ContextMode:      original.Context.Mode,
+		Attachments:      original.Context.Attachments,
+		TimeoutSec:       original.TimeoutSec,
+		MaxAttempts:      original.MaxAttempts,
+		Depth:            original.Depth,
+		NotifyOnComplete: original.NotifyOnComplete,
+		ArtifactDir:      artifactDir,
+		Context:          original.Context,
+		RetryOf:          original.ID,
+	})
+	if err != nil {
+		return Run{}, err
+	}
+	if err := m.recordEvent(ctx, original.ID, original.Status, "retried as "+run.ID, original.Attempt); err != nil {
+		return run, err
+	}
+	return run, nil
+}
+
 func (m *Manager) ListSessionRuns(ctx context.Context, sessionID string, limit int) ([]Run, error) {
 	if m == nil || m.store == nil {
 		return nil, fmt.Errorf("subagent manager not configured")
@@ -358,7 +551,13 @@ func (m *Manager) worker() {
 			return
 		case runID := <-m.queue:
 			m.updateQueueDepth()
-			m.executeRun(runID)
+			m.active.Add(1)
+			// A run left queued while draining stays queued in the store and
+			// resumes on the next start.
+			if !m.draining.Load() {
+				m.executeRun(runID)
+			}
+			m.active.Add(-1)
 		}
 	}
 }
@@ -376,13 +575,7 @@ func (m *Manager) executeRun(runID string) {
 		m.finalizeCancelled(ctx, run, "run cancelled by external signal")
 		return
 	}
-	maxAttempts := run.MaxAttempts
-	if maxAttempts <= 0 {
-		maxAttempts = m.opts.MaxAttempts
-	}
-	if maxAttempts <= 0 {
-		maxAttempts = 1
-	}
+	maxAttempts := m.maxAttempts(run)
 
 	for {
 		if m.hasCancelSignal(ctx, run.ID) {
@@ -432,6 +625,7 @@ func (m *Manager) executeRun(runID string) {
 		result, runErr := m.exec(runCtx, run)
 		cancel()
 		m.unregisterCancel(run.ID)
+		m.clearProgress(run.ID)
 
 		if runErr == nil {
 			finishedAt := m.opts.Clock().UTC()
@@ -597,7 +791,62 @@ func (m *Manager) enqueueRunID(runID string) error {
 		m.updateQueueDepth()
 		return nil
 	default:
-		return ErrQueueFull
+		return m.rejectQueueFull()
+	}
+}
+
+func (m *Manager) rejectQueueFull() error {
+	if m.metrics != nil {
+		m.metrics.SubagentRejected.Add(1)
+	}
+	return &QueueFullError{Depth: len(m.queue), Capacity: cap(m.queue)}
+}
+
+// checkLoop counts earlier runs in the run's session that were spawned at
+// the same depth with an identical context packet within LoopWindow. Retries
+// are exempt since they reuse the packet on purpose, and runs the guard
+// itself refused do not count, so a refusal does not extend the loop.
+func (m *Manager) checkLoop(ctx context.Context, run Run) (*LoopDetectedError, error) {
+	if m.opts.LoopThreshold <= 0 || run.RetryOf != "" || run.SessionID == "" || run.Context.Checksum == "" {
+		return nil, nil
+	}
+	runs, err := m.store.ListSubagentRunsBySession(ctx, run.SessionID, loopScanLimit)
+	if err != nil {
+		return nil, err
+	}
+	since := m.opts.Clock().UTC().Add(-m.opts.LoopWindow)
+	count := 0
+	for _, prior := range runs {
+		if prior.Depth != run.Depth || prior.Context.Checksum != run.Context.Checksum || prior.RetryOf != "" {
+			continue
+		}
+		if prior.CreatedAt.Before(since) || refusedByLoopGuard(prior) {
+			continue
+		}
+		count++
+	}
+	if count < m.opts.LoopThreshold {
+		return nil, nil
+	}
+	return &LoopDetectedError{Checksum: run.Context.Checksum, Depth: run.Depth, MaxDepth: m.opts.MaxDepth, Count: count}, nil
+}
+
+// refusedByLoopGuard reports whether run was stored by rejectLoop.
+func refusedByLoopGuard(run Run) bool {
+	return run.Status == StatusFailed && strings.HasPrefix(run.Error, ErrLoopDetected.Error())
+}
+
+// rejectLoop stores the refused run as failed so the loop break shows up in
+// the session's run list and event log.
+func (m *Manager) rejectLoop(ctx context.Context, run Run, loopErr *LoopDetectedError) {
+	finishedAt := m.opts.Clock().UTC()
+	run.Status = StatusFailed
+	run.Error = loopErr.Error()
+	run.FinishedAt = &finishedAt
+	_ = m.store.PutSubagentRun(ctx, run)
+	_ = m.recordEvent(ctx, run.ID, StatusFailed, "loop break: "+run.Error, 0)
+	if m.metrics != nil {
+		m.metrics.SubagentLoopBreaks.Add(1)
 	}
 }
 
@@ -620,6 +869,36 @@ func (m *Manager) loadRuns(ctx context.Context, ids []string) ([]Run, error) {
 	return runs, nil
 }
 
+// ReportProgress forwards a progress update for a running run, throttled to
+// one update per ProgressInterval. The first report only starts the clock so
+// short runs stay silent.
+func (m *Manager) ReportProgress(run Run, progress Progress) {
+	if m == nil || !m.opts.StreamProgress || m.opts.Progress == nil || strings.TrimSpace(run.ID) == "" {
+		return
+	}
+	now := m.opts.Clock().UTC()
+	m.progressMu.Lock()
+	last, seen := m.progressLast[run.ID]
+	if !seen {
+		m.progressLast[run.ID] = now
+		m.progressMu.Unlock()
+		return
+	}
+	if now.Sub(last) < m.opts.ProgressInterval {
+		m.progressMu.Unlock()
+		return
+	}
+	m.progressLast[run.ID] = now
+	m.progressMu.Unlock()
+	m.opts.Progress(run, progress)
+}
+
+func (m *Manager) clearProgress(runID string) {
+	m.progressMu.Lock()
+	delete(m.progressLast, runID)
+	m.progressMu.Unlock()
+}
+
 func (m *Manager) recordEvent(ctx context.Context, runID string, status Status, message string, attempt int) error {
 	if m.store == nil {
 		return nil
@@ -4,11 +4,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"runtime"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/grixate/squidbot/internal/telemetry"
 )
 
 type memoryStore struct {
@@ -98,16 +103,114 @@ func (s *memoryStore) GetKV(ctx context.Context, namespace, key string) ([]byte,
 
 func TestManagerQueueLimit(t *testing.T) {
 	store := newMemoryStore()
+	metrics := &telemetry.Metrics{}
 	m := NewManager(Options{Enabled: true, MaxConcurrent: 1, MaxQueue: 1, DefaultTimeout: time.Second, MaxAttempts: 1, NextID: func() string { return "id" + time.Now().Format("150405.000000") }}, store, func(ctx context.Context, run Run) (Result, error) {
 		return Result{Summary: "ok"}, nil
-	}, nil, nil)
+	}, nil, metrics)
 	_, err := m.Enqueue(context.Background(), Request{Task: "one"})
 	if err != nil {
 		t.Fatal(err)
 	}
-	if _, err := m.Enqueue(context.Background(), Request{Task: "two"}); !errors.Is(err, ErrQueueFull) {
+	_, err = m.Enqueue(context.Background(), Request{ID: "rejected", Task: "two"})
+	if !errors.Is(err, ErrQueueFull) {
 		t.Fatalf("expected ErrQueueFull, got %v", err)
 	}
+	var full *QueueFullError
+	if !errors.As(err, &full) || full.Depth != 1 || full.Capacity != 1 {
+		t.Fatalf("expected queue depth 1/1 in error, got %v", err)
+	}
+	if _, err := store.GetSubagentRun(context.Background(), "rejected"); err == nil {
+		t.Fatal("expected a rejected spawn to leave no stored run")
+	}
+	if metrics.SubagentRejected.Load() != 1 {
+		t.Fatalf("expected one rejected enqueue, got %d", metrics.SubagentRejected.Load())
+	}
+}
+
+func TestManagerLoopGuardRefusesRepeatedContext(t *testing.T) {
+	store := newMemoryStore()
+	metrics := &telemetry.Metrics{}
+	m := NewManager(Options{Enabled: true, MaxConcurrent: 1, MaxQueue: 8, DefaultTimeout: time.Second, MaxAttempts: 1, MaxDepth: 2, LoopThreshold: 2}, store, func(ctx context.Context, run Run) (Result, error) {
+		return Result{Summary: "ok"}, nil
+	}, nil, metrics)
+	packet := ContextPacket{Mode: ContextModeMinimal, Checksum: "0123456789abcdef"}
+	for _, id := range []string{"a", "b"} {
+		if _, err := m.Enqueue(context.Background(), Request{ID: id, SessionID: "s1", Task: "same", Depth: 1, Context: packet}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Other sessions and depths keep their own counts.
+	if _, err := m.Enqueue(context.Background(), Request{ID: "other-session", SessionID: "s2", Task: "same", Depth: 1, Context: packet}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Enqueue(context.Background(), Request{ID: "other-depth", SessionID: "s1", Task: "same", Depth: 2, Context: packet}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := m.Enqueue(context.Background(), Request{ID: "c", SessionID: "s1", Task: "same", Depth: 1, Context: packet})
+	var loop *LoopDetectedError
+	if !errors.Is(err, ErrLoopDetected) || !errors.As(err, &loop) {
+		t.Fatalf("expected loop detection, got %v", err)
+	}
+	if loop.Count != 2 || loop.Depth != 1 || loop.MaxDepth != 2 {
+		t.Fatalf("unexpected loop details: %+v", loop)
+	}
+	if !strings.Contains(err.Error(), "depth 1/2") {
+		t.Fatalf("expected depth in error, got %q", err.Error())
+	}
+	run, err := store.GetSubagentRun(context.Background(), "c")
+	if err != nil || run.Status != StatusFailed {
+		t.Fatalf("expected refused run recorded as failed, got %+v (%v)", run, err)
+	}
+	found := false
+	for _, event := range store.events {
+		if event.RunID == "c" && strings.HasPrefix(event.Message, "loop break:") {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("expected a loop break event")
+	}
+	if metrics.SubagentLoopBreaks.Load() != 1 {
+		t.Fatalf("expected one loop break, got %d", metrics.SubagentLoopBreaks.Load())
+	}
+
+	if _, err := m.Enqueue(context.Background(), Request{ID: "retry", SessionID: "s1", Task: "same", Depth: 1, Context: packet, RetryOf: "a"}); err != nil {
+		t.Fatalf("expected retries to bypass the loop guard, got %v", err)
+	}
+}
+
+func TestManagerLoopGuardCountsOnlyRecentAcceptedRuns(t *testing.T) {
+	store := newMemoryStore()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	m := NewManager(Options{Enabled: true, MaxConcurrent: 1, MaxQueue: 8, DefaultTimeout: time.Second, MaxAttempts: 1, MaxDepth: 2, LoopThreshold: 2, LoopWindow: time.Minute, Clock: func() time.Time { return now }}, store, func(ctx context.Context, run Run) (Result, error) {
+		return Result{Summary: "ok"}, nil
+	}, nil, nil)
+	packet := ContextPacket{Mode: ContextModeMinimal, Checksum: "0123456789abcdef"}
+	enqueue := func(id string) error {
+		_, err := m.Enqueue(context.Background(), Request{ID: id, SessionID: "s1", Task: "same", Depth: 1, Context: packet})
+		return err
+	}
+	for _, id := range []string{"a", "b"} {
+		if err := enqueue(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enqueue("refused"); !errors.Is(err, ErrLoopDetected) {
+		t.Fatalf("expected loop detection, got %v", err)
+	}
+
+	now = now.Add(45 * time.Second)
+	if err := enqueue("refused-again"); !errors.Is(err, ErrLoopDetected) {
+		t.Fatalf("expected loop detection inside the window, got %v", err)
+	}
+
+	// a and b have left the window; the refused runs must not keep the
+	// session blocked.
+	now = now.Add(30 * time.Second)
+	if err := enqueue("c"); err != nil {
+		t.Fatalf("expected a spawn once earlier runs left the window, got %v", err)
+	}
 }
 
 func TestManagerRetryThenSuccess(t *testing.T) {
@@ -150,6 +253,64 @@ func TestManagerRetryThenSuccess(t *testing.T) {
 	}
 }
 
+func TestManagerRetryFailedRunLinksRuns(t *testing.T) {
+	store := newMemoryStore()
+	m := NewManager(Options{
+		Enabled:        true,
+		MaxConcurrent:  1,
+		MaxQueue:       8,
+		DefaultTimeout: time.Second,
+		MaxAttempts:    1,
+		NextID:         func() string { return fmt.Sprintf("evt-%d", time.Now().UnixNano()) },
+	}, store, func(ctx context.Context, run Run) (Result, error) {
+		return Result{Summary: "ok"}, nil
+	}, nil, nil)
+	original := Run{
+		ID:          "run-failed",
+		SessionID:   "cli:default",
+		Channel:     "cli",
+		ChatID:      "direct",
+		Task:        "flaky task",
+		Status:      StatusFailed,
+		TimeoutSec:  30,
+		MaxAttempts: 2,
+		Attempt:     2,
+		Context:     ContextPacket{Mode: ContextModeMinimal, Attachments: []string{"notes.md"}},
+	}
+	if err := store.PutSubagentRun(context.Background(), original); err != nil {
+		t.Fatal(err)
+	}
+
+	run, err := m.Retry(context.Background(), original, "run-retried", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.ID != "run-retried" || run.RetryOf != "run-failed" || run.Status != StatusQueued {
+		t.Fatalf("unexpected retry run: %+v", run)
+	}
+	if run.Task != original.Task || run.SessionID != original.SessionID || run.TimeoutSec != 30 || run.MaxAttempts != 2 {
+		t.Fatalf("retry did not keep the original run settings: %+v", run)
+	}
+	if run.Context.Mode != ContextModeMinimal || len(run.Context.Attachments) != 1 {
+		t.Fatalf("retry did not keep the context packet: %+v", run.Context)
+	}
+	linked := map[string]string{}
+	for _, event := range store.events {
+		linked[event.RunID] = event.Message
+	}
+	if linked["run-failed"] != "retried as run-retried" {
+		t.Fatalf("expected link event on original run, got %q", linked["run-failed"])
+	}
+	if linked["run-retried"] != "run queued as retry of run-failed" {
+		t.Fatalf("expected link event on new run, got %q", linked["run-retried"])
+	}
+
+	original.Status = StatusRunning
+	if _, err := m.Retry(context.Background(), original, "run-again", ""); !errors.Is(err, ErrNotRetryable) {
+		t.Fatalf("expected ErrNotRetryable for running run, got %v", err)
+	}
+}
+
 func TestManagerCancelRunningRun(t *testing.T) {
 	store := newMemoryStore()
 	m := NewManager(Options{
@@ -332,3 +493,105 @@ func TestManagerLongRunKeepsGoroutinesBounded(t *testing.T) {
 		t.Fatalf("goroutines grew unexpectedly: before=%d after=%d", before, after)
 	}
 }
+
+func TestManagerReportProgressThrottlesPerRun(t *testing.T) {
+	store := newMemoryStore()
+	var mu sync.Mutex
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	advance := func(d time.Duration) {
+		mu.Lock()
+		now = now.Add(d)
+		mu.Unlock()
+	}
+	var relayed []Progress
+	m := NewManager(Options{
+		Enabled:          true,
+		StreamProgress:   true,
+		ProgressInterval: 10 * time.Second,
+		Progress: func(run Run, progress Progress) {
+			relayed = append(relayed, progress)
+		},
+		Clock: func() time.Time {
+			mu.Lock()
+			defer mu.Unlock()
+			return now
+		},
+	}, store, func(ctx context.Context, run Run) (Result, error) {
+		return Result{}, nil
+	}, nil, nil)
+
+	run := Run{ID: "run-progress"}
+	m.ReportProgress(run, Progress{Hop: 1, Status: "thinking"})
+	advance(5 * time.Second)
+	m.ReportProgress(run, Progress{Hop: 1, Tool: "web_search", Status: "running tool"})
+	if len(relayed) != 0 {
+		t.Fatalf("expected updates inside the interval to be dropped, got %+v", relayed)
+	}
+	advance(6 * time.Second)
+	m.ReportProgress(run, Progress{Hop: 2, Status: "thinking"})
+	advance(time.Second)
+	m.ReportProgress(run, Progress{Hop: 2, Tool: "web_fetch", Status: "running tool"})
+	if len(relayed) != 1 || relayed[0].Hop != 2 || relayed[0].Tool != "" {
+		t.Fatalf("expected exactly one throttled update, got %+v", relayed)
+	}
+
+	m.clearProgress(run.ID)
+	m.ReportProgress(run, Progress{Hop: 3, Status: "thinking"})
+	if len(relayed) != 1 {
+		t.Fatalf("expected cleared run to restart its clock, got %+v", relayed)
+	}
+
+	disabled := NewManager(Options{Enabled: true, Progress: func(Run, Progress) { t.Fatal("progress must not relay when disabled") }}, store, nil, nil, nil)
+	disabled.ReportProgress(run, Progress{Hop: 1})
+	disabled.ReportProgress(run, Progress{Hop: 2})
+}
+
+func TestManagerReapArtifactsRemovesExpiredDirs(t *testing.T) {
+	store := newMemoryStore()
+	root := filepath.Join(t.TempDir(), "subagents")
+	outside := filepath.Join(t.TempDir(), "outside")
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	old := now.Add(-40 * 24 * time.Hour)
+	recent := now.Add(-time.Hour)
+	runs := []Run{
+		{ID: "old", Status: StatusSucceeded, CreatedAt: old, FinishedAt: &old, ArtifactDir: filepath.Join(root, "old")},
+		{ID: "recent", Status: StatusSucceeded, CreatedAt: recent, FinishedAt: &recent, ArtifactDir: filepath.Join(root, "recent")},
+		{ID: "running", Status: StatusRunning, CreatedAt: old, ArtifactDir: filepath.Join(root, "running")},
+		{ID: "outside", Status: StatusFailed, CreatedAt: old, FinishedAt: &old, ArtifactDir: outside},
+	}
+	for _, run := range runs {
+		if err := os.MkdirAll(run.ArtifactDir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		_ = store.PutSubagentRun(context.Background(), run)
+	}
+	m := NewManager(Options{Enabled: true, Clock: func() time.Time { return now }}, store, nil, nil, nil)
+
+	reaped, err := m.ReapArtifacts(context.Background(), root, now.Add(-30*24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reaped) != 1 || reaped[0].ID != "old" {
+		t.Fatalf("expected only the old run reaped, got %+v", reaped)
+	}
+	if _, err := os.Stat(runs[0].ArtifactDir); !os.IsNotExist(err) {
+		t.Fatalf("expected old artifact dir removed, err=%v", err)
+	}
+	for _, run := range runs[1:] {
+		if _, err := os.Stat(run.ArtifactDir); err != nil {
+			t.Fatalf("expected %s artifacts kept: %v", run.ID, err)
+		}
+	}
+	stored, _ := store.GetSubagentRun(context.Background(), "old")
+	if stored.ArtifactsReapedAt == nil || !stored.ArtifactsReapedAt.Equal(now) {
+		t.Fatalf("expected reap time recorded, got %+v", stored.ArtifactsReapedAt)
+	}
+
+	again, err := m.ReapArtifacts(context.Background(), root, now.Add(-30*24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again) != 0 {
+		t.Fatalf("expected reaped runs to be skipped, got %+v", again)
+	}
+}
@@ -73,3 +73,69 @@ func TestManagerRecoveryRequeuesRunningAndQueuedRuns(t *testing.T) {
 		}
 	}
 }
+
+func TestManagerRecoveryFailsInterruptedRunsThatCannotResume(t *testing.T) {
+	store, err := storepkg.Open(filepath.Join(t.TempDir(), "recovery.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	now := time.Now().UTC()
+	put := func(id string, attempt, maxAttempts int, status subagent.Status) {
+		t.Helper()
+		run := subagent.Run{ID: id, SessionID: "s1", Task: id, Status: status, CreatedAt: now, TimeoutSec: 10, Attempt: attempt, MaxAttempts: maxAttempts}
+		if status == subagent.StatusRunning {
+			run.StartedAt = &now
+		}
+		if err := store.PutSubagentRun(context.Background(), run); err != nil {
+			t.Fatal(err)
+		}
+	}
+	put("run-exhausted", 2, 2, subagent.StatusRunning)
+	put("run-resumable", 1, 2, subagent.StatusRunning)
+	put("run-queued", 0, 2, subagent.StatusQueued)
+
+	start := func(failInterrupted bool) *subagent.Manager {
+		mgr := subagent.NewManager(subagent.Options{
+			Enabled:         true,
+			MaxConcurrent:   2,
+			MaxQueue:        8,
+			DefaultTimeout:  2 * time.Second,
+			FailInterrupted: failInterrupted,
+		}, store, func(ctx context.Context, run subagent.Run) (subagent.Result, error) {
+			return subagent.Result{Summary: "ok"}, nil
+		}, nil, nil)
+		if err := mgr.Start(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		return mgr
+	}
+
+	mgr := start(false)
+	runs, err := mgr.Wait(context.Background(), []string{"run-exhausted", "run-resumable", "run-queued"}, 3*time.Second)
+	mgr.Stop()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]subagent.Status{"run-exhausted": subagent.StatusFailed, "run-resumable": subagent.StatusSucceeded, "run-queued": subagent.StatusSucceeded}
+	for _, run := range runs {
+		if run.Status != want[run.ID] {
+			t.Fatalf("expected %s for %s, got %s (%s)", want[run.ID], run.ID, run.Status, run.Error)
+		}
+		if run.ID == "run-exhausted" && run.Error != "interrupted by restart" {
+			t.Fatalf("expected interrupted error, got %q", run.Error)
+		}
+	}
+
+	put("run-no-resume", 0, 2, subagent.StatusRunning)
+	mgr = start(true)
+	defer mgr.Stop()
+	run, err := mgr.Status(context.Background(), "run-no-resume")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != subagent.StatusFailed || run.Error != "interrupted by restart" || run.FinishedAt == nil {
+		t.Fatalf("expected run failed by restart when resume is off, got %+v", run)
+	}
+}
@@ -54,8 +54,18 @@ type ContextPacket struct {
 	History        []provider.Message `json:"history,omitempty"`
 	MemorySnippets []string           `json:"memory_snippets,omitempty"`
 	Attachments    []string           `json:"attachments,omitempty"`
-	CreatedAt      time.Time          `json:"created_at"`
-	Checksum       string             `json:"checksum,omitempty"`
+	// InlineAttachments carries the content of the attachments small enough
+	// for runtime.subagents.inlineAttachmentMaxBytes. Their paths stay in
+	// Attachments.
+	InlineAttachments []InlineAttachment `json:"inline_attachments,omitempty"`
+	CreatedAt         time.Time          `json:"created_at"`
+	Checksum          string             `json:"checksum,omitempty"`
+}
+
+// InlineAttachment is an attachment's resolved path and its content.
+type InlineAttachment struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
 }
 
 type Result struct {
@@ -85,6 +95,18 @@ type Run struct {
 	ArtifactDir      string        `json:"artifact_dir,omitempty"`
 	Context          ContextPacket `json:"context"`
 	Result           *Result       `json:"result,omitempty"`
+	// RetryOf is the ID of the failed run this run retries.
+	RetryOf string `json:"retry_of,omitempty"`
+	// ArtifactsReapedAt records when retention cleanup removed ArtifactDir.
+	ArtifactsReapedAt *time.Time `json:"artifacts_reaped_at,omitempty"`
+}
+
+// Progress is a lightweight status update published while a run executes.
+type Progress struct {
+	Hop     int    `json:"hop"`
+	MaxHops int    `json:"max_hops"`
+	Tool    string `json:"tool,omitempty"`
+	Status  string `json:"status"`
 }
 
 type Event struct {
@@ -112,4 +134,8 @@ type Request struct {
 	NotifyOnComplete bool
 	ArtifactDir      string
 	Context          ContextPacket
+	RetryOf          string
+	// Recurring marks a run expected to repeat with the same context, such
+	// as a scheduled job, so the loop guard lets it through.
+	Recurring bool
 }
@@ -0,0 +1,78 @@
+package telemetry
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// latencyBucketsMS are the upper bounds of the latency histogram buckets.
+// Calls slower than the last bound land in an overflow bucket.
+var latencyBucketsMS = [...]uint64{50, 100, 250, 500, 1000, 2000, 5000, 10000, 20000, 30000, 60000, 120000}
+
+// LatencyHistogram counts call durations in fixed buckets with atomic adds,
+// so recording never takes a lock. Quantiles are reported as the upper bound
+// of the bucket holding them, or the slowest call seen for the overflow
+// bucket. The zero value is ready to use.
+type LatencyHistogram struct {
+	buckets [len(latencyBucketsMS) + 1]atomic.Uint64
+	maxMS   atomic.Uint64
+}
+
+// Observe records one call of duration d.
+func (h *LatencyHistogram) Observe(d time.Duration) {
+	ms := uint64(max(d.Milliseconds(), 0))
+	idx := len(latencyBucketsMS)
+	for i, bound := range latencyBucketsMS {
+		if ms <= bound {
+			idx = i
+			break
+		}
+	}
+	h.buckets[idx].Add(1)
+	for {
+		current := h.maxMS.Load()
+		if ms <= current || h.maxMS.CompareAndSwap(current, ms) {
+			return
+		}
+	}
+}
+
+// Count returns the number of recorded calls.
+func (h *LatencyHistogram) Count() uint64 {
+	var total uint64
+	for i := range h.buckets {
+		total += h.buckets[i].Load()
+	}
+	return total
+}
+
+// QuantileMS estimates the q quantile (0 < q <= 1) in milliseconds. It is
+// zero before any call is recorded.
+func (h *LatencyHistogram) QuantileMS(q float64) uint64 {
+	var counts [len(latencyBucketsMS) + 1]uint64
+	var total uint64
+	for i := range h.buckets {
+		counts[i] = h.buckets[i].Load()
+		total += counts[i]
+	}
+	if total == 0 {
+		return 0
+	}
+	rank := uint64(q * float64(total))
+	if float64(rank) < q*float64(total) {
+		rank++
+	}
+	rank = min(max(rank, 1), total)
+	var seen uint64
+	for i, count := range counts {
+		seen += count
+		if seen < rank {
+			continue
+		}
+		if i < len(latencyBucketsMS) {
+			return min(latencyBucketsMS[i], h.maxMS.Load())
+		}
+		break
+	}
+	return h.maxMS.Load()
+}
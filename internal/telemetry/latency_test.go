@@ -0,0 +1,40 @@
+package telemetry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatencyHistogramQuantiles(t *testing.T) {
+	var h LatencyHistogram
+	if h.QuantileMS(0.5) != 0 {
+		t.Fatalf("expected zero quantile before any call")
+	}
+	for i := 0; i < 90; i++ {
+		h.Observe(80 * time.Millisecond)
+	}
+	for i := 0; i < 10; i++ {
+		h.Observe(3 * time.Second)
+	}
+	if h.Count() != 100 {
+		t.Fatalf("expected 100 calls, got %d", h.Count())
+	}
+	if p50 := h.QuantileMS(0.5); p50 != 100 {
+		t.Fatalf("expected p50 in the 100ms bucket, got %d", p50)
+	}
+	if p95 := h.QuantileMS(0.95); p95 != 3000 {
+		t.Fatalf("expected p95 capped at the slowest call, got %d", p95)
+	}
+
+	h.Observe(5 * time.Minute)
+	if p100 := h.QuantileMS(1); p100 != 300000 {
+		t.Fatalf("expected overflow bucket to report the slowest call, got %d", p100)
+	}
+
+	var m Metrics
+	m.ProviderLatency.Observe(40 * time.Millisecond)
+	snapshot := m.Snapshot()
+	if snapshot["provider_latency_p50_ms"] != 40 || snapshot["provider_latency_p95_ms"] != 40 {
+		t.Fatalf("expected latency quantiles in snapshot, got p50=%d p95=%d", snapshot["provider_latency_p50_ms"], snapshot["provider_latency_p95_ms"])
+	}
+}
@@ -2,17 +2,37 @@ package telemetry
 
 import (
 	"sync/atomic"
+	"time"
 )
 
 type Metrics struct {
 	InboundCount                atomic.Uint64
+	InboundRejected             atomic.Uint64
 	OutboundCount               atomic.Uint64
+	OutboundDropped             atomic.Uint64
+	OutboundDeferred            atomic.Uint64
+	ManageRejected              atomic.Uint64
 	ActiveActors                atomic.Int64
 	ActiveTurns                 atomic.Int64
+	SessionsArchived            atomic.Uint64
+	SessionsEvicted             atomic.Uint64
+	SessionsResurrected         atomic.Uint64
+	TurnsWaiting                atomic.Int64
+	TurnsQueued                 atomic.Uint64
 	ProviderCalls               atomic.Uint64
 	ProviderErrors              atomic.Uint64
+	ProviderRetries             atomic.Uint64
+	ModelFallbacks              atomic.Uint64
+	ProviderTimedTokens         atomic.Uint64
+	ProviderLatencyMS           atomic.Uint64
+	ProviderTokensPerSecMax     atomic.Uint64
+	PromptCacheReadTokens       atomic.Uint64
+	PromptCacheWriteTokens      atomic.Uint64
+	ReasoningTokens             atomic.Uint64
 	ToolCalls                   atomic.Uint64
 	ToolErrors                  atomic.Uint64
+	ToolArgumentErrors          atomic.Uint64
+	ToolQuotaBlocked            atomic.Uint64
 	CronExecutions              atomic.Uint64
 	HeartbeatExecutions         atomic.Uint64
 	SubagentQueued              atomic.Uint64
@@ -23,6 +43,9 @@ type Metrics struct {
 	SubagentCancelled           atomic.Uint64
 	SubagentRetries             atomic.Uint64
 	SubagentQueueDepth          atomic.Uint64
+	SubagentRejected            atomic.Uint64
+	SubagentLoopBreaks          atomic.Uint64
+	SubagentNotifySuppressed    atomic.Uint64
 	DelegationsSubmitted        atomic.Uint64
 	DelegationsSucceeded        atomic.Uint64
 	DelegationsFailed           atomic.Uint64
@@ -40,6 +63,9 @@ type Metrics struct {
 	SkillsExplicitFailures      atomic.Uint64
 	SkillsInvalidSkipped        atomic.Uint64
 	SkillsReloadTotal           atomic.Uint64
+	// ProviderLatency is the wall time of every provider chat call,
+	// including failed ones.
+	ProviderLatency LatencyHistogram
 }
 
 func (m *Metrics) Snapshot() map[string]uint64 {
@@ -51,15 +77,43 @@ func (m *Metrics) Snapshot() map[string]uint64 {
 	if turns < 0 {
 		turns = 0
 	}
+	waiting := m.TurnsWaiting.Load()
+	if waiting < 0 {
+		waiting = 0
+	}
+	avgTokensPerSec := uint64(0)
+	if latency := m.ProviderLatencyMS.Load(); latency > 0 {
+		avgTokensPerSec = m.ProviderTimedTokens.Load() * 1000 / latency
+	}
 	return map[string]uint64{
 		"inbound_count":                  m.InboundCount.Load(),
+		"inbound_rejected":               m.InboundRejected.Load(),
 		"outbound_count":                 m.OutboundCount.Load(),
+		"outbound_dropped":               m.OutboundDropped.Load(),
+		"outbound_deferred":              m.OutboundDeferred.Load(),
+		"manage_rejected":                m.ManageRejected.Load(),
 		"active_actors":                  uint64(active),
 		"active_turns":                   uint64(turns),
+		"sessions_archived":              m.SessionsArchived.Load(),
+		"sessions_evicted":               m.SessionsEvicted.Load(),
+		"sessions_resurrected":           m.SessionsResurrected.Load(),
+		"turns_waiting":                  uint64(waiting),
+		"turns_queued_total":             m.TurnsQueued.Load(),
 		"provider_calls":                 m.ProviderCalls.Load(),
 		"provider_errors":                m.ProviderErrors.Load(),
+		"provider_retries":               m.ProviderRetries.Load(),
+		"model_fallbacks":                m.ModelFallbacks.Load(),
+		"provider_tokens_per_sec_avg":    avgTokensPerSec,
+		"provider_tokens_per_sec_max":    m.ProviderTokensPerSecMax.Load(),
+		"provider_latency_p50_ms":        m.ProviderLatency.QuantileMS(0.5),
+		"provider_latency_p95_ms":        m.ProviderLatency.QuantileMS(0.95),
+		"prompt_cache_read_tokens":       m.PromptCacheReadTokens.Load(),
+		"prompt_cache_write_tokens":      m.PromptCacheWriteTokens.Load(),
+		"reasoning_tokens":               m.ReasoningTokens.Load(),
 		"tool_calls":                     m.ToolCalls.Load(),
 		"tool_errors":                    m.ToolErrors.Load(),
+		"tool_argument_errors":           m.ToolArgumentErrors.Load(),
+		"tool_quota_blocked":             m.ToolQuotaBlocked.Load(),
 		"cron_executions":                m.CronExecutions.Load(),
 		"heartbeat_executions":           m.HeartbeatExecutions.Load(),
 		"subagent_queued":                m.SubagentQueued.Load(),
@@ -70,6 +124,9 @@ func (m *Metrics) Snapshot() map[string]uint64 {
 		"subagent_cancelled":             m.SubagentCancelled.Load(),
 		"subagent_retries":               m.SubagentRetries.Load(),
 		"subagent_queue_depth":           m.SubagentQueueDepth.Load(),
+		"subagent_rejected":              m.SubagentRejected.Load(),
+		"subagent_loop_breaks":           m.SubagentLoopBreaks.Load(),
+		"subagent_notify_suppressed":     m.SubagentNotifySuppressed.Load(),
 		"delegations_submitted_total":    m.DelegationsSubmitted.Load(),
 		"delegations_succeeded_total":    m.DelegationsSucceeded.Load(),
 		"delegations_failed_total":       m.DelegationsFailed.Load(),
@@ -89,3 +146,20 @@ func (m *Metrics) Snapshot() map[string]uint64 {
 		"skills_reload_total":            m.SkillsReloadTotal.Load(),
 	}
 }
+
+// RecordProviderThroughput folds one timed provider call into the
+// throughput gauges. Calls without completion tokens or latency are ignored.
+func (m *Metrics) RecordProviderThroughput(completionTokens uint64, latency time.Duration) {
+	if completionTokens == 0 || latency <= 0 {
+		return
+	}
+	m.ProviderTimedTokens.Add(completionTokens)
+	m.ProviderLatencyMS.Add(max(uint64(latency.Milliseconds()), 1))
+	rate := uint64(float64(completionTokens) / latency.Seconds())
+	for {
+		current := m.ProviderTokensPerSecMax.Load()
+		if rate <= current || m.ProviderTokensPerSecMax.CompareAndSwap(current, rate) {
+			return
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/grixate/squidbot/internal/budget"
+)
+
+type BudgetResetRequest struct {
+	SessionID string
+	Channel   string
+	SenderID  string
+	// Scope is a counter key such as "global", "session:<id>", or
+	// "subagent:<run>".
+	Scope string
+}
+
+type BudgetResetResponse struct {
+	Result budget.ResetResult
+}
+
+type BudgetResetFunc func(ctx context.Context, req BudgetResetRequest) (BudgetResetResponse, error)
+
+type BudgetResetTool struct {
+	reset     BudgetResetFunc
+	sessionID string
+	channel   string
+	senderID  string
+}
+
+func NewBudgetResetTool(reset BudgetResetFunc) *BudgetResetTool {
+	return &BudgetResetTool{reset: reset}
+}
+
+func (t *BudgetResetTool) SetContext(sessionID, channel, senderID string) {
+	t.sessionID = sessionID
+	t.channel = channel
+	t.senderID = senderID
+}
+
+func (t *BudgetResetTool) Name() string { return "budget_reset" }
+
+func (t *BudgetResetTool) Description() string {
+	return "Zero a token usage counter and clear its stale reservations. Scope session defaults to the current session."
+}
+
+func (t *BudgetResetTool) Schema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"scope":      map[string]any{"type": "string", "enum": []string{"global", "session", "subagent"}},
+			"session_id": map[string]any{"type": "string"},
+			"run_id":     map[string]any{"type": "string"},
+		},
+		"required": []string{"scope"},
+	}
+}
+
+func (t *BudgetResetTool) Execute(ctx context.Context, args json.RawMessage) (ToolResult, error) {
+	if t.reset == nil {
+		return ToolResult{}, fmt.Errorf("budget manager is not configured")
+	}
+	var in struct {
+		Scope     string `json:"scope"`
+		SessionID string `json:"session_id"`
+		RunID     string `json:"run_id"`
+	}
+	if err := json.Unmarshal(args, &in); err != nil {
+		return ToolResult{}, fmt.Errorf("invalid arguments: %w", err)
+	}
+	id := strings.TrimSpace(in.RunID)
+	if strings.EqualFold(strings.TrimSpace(in.Scope), "session") {
+		id = strings.TrimSpace(in.SessionID)
+		if id == "" {
+			id = t.sessionID
+		}
+	}
+	scope, err := budget.ScopeKey(in.Scope, id)
+	if err != nil {
+		return ToolResult{}, err
+	}
+	out, err := t.reset(ctx, BudgetResetRequest{
+		SessionID: t.sessionID,
+		Channel:   t.channel,
+		SenderID:  t.senderID,
+		Scope:     scope,
+	})
+	if err != nil {
+		return ToolResult{}, err
+	}
+	return ToolResult{
+		Text: fmt.Sprintf("Budget counter %s reset (was used=%d reserved=%d; cleared %d reservations).",
+			out.Result.Scope, out.Result.Previous.TotalTokens, out.Result.Previous.ReservedTokens, out.Result.ClearedReservations),
+		Metadata: map[string]any{
+			"result": out.Result,
+		},
+	}, nil
+}
@@ -0,0 +1,167 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	diffContextLines = 3
+	// maxDiffCells caps the line-matching table; larger changes are shown as
+	// one replaced block instead of a minimal diff.
+	maxDiffCells = 4 << 20
+)
+
+type diffLine struct {
+	op   byte // ' ', '-', or '+'
+	text string
+}
+
+// unifiedDiff renders the change from before to after as a unified diff with
+// three lines of context, or "" when they are equal. created marks a file
+// that does not exist yet.
+func unifiedDiff(path, before, after string, created bool) string {
+	if before == after && !created {
+		return ""
+	}
+	lines := diffLines(splitDiffLines(before), splitDiffLines(after))
+	var b strings.Builder
+	if created {
+		b.WriteString("--- /dev/null\n")
+	} else {
+		b.WriteString("--- a/" + path + "\n")
+	}
+	b.WriteString("+++ b/" + path + "\n")
+	oldLine, newLine := 1, 1
+	for i := 0; i < len(lines); {
+		if lines[i].op == ' ' {
+			oldLine++
+			newLine++
+			i++
+			continue
+		}
+		start := max(i-diffContextLines, 0)
+		end := i
+		for end < len(lines) {
+			if lines[end].op != ' ' {
+				end++
+				continue
+			}
+			next := end
+			for next < len(lines) && lines[next].op == ' ' {
+				next++
+			}
+			if next == len(lines) || next-end > 2*diffContextLines {
+				end = min(end+diffContextLines, next)
+				break
+			}
+			end = next
+		}
+		oldStart, newStart := oldLine-(i-start), newLine-(i-start)
+		oldCount, newCount := 0, 0
+		for _, line := range lines[start:end] {
+			if line.op != '+' {
+				oldCount++
+			}
+			if line.op != '-' {
+				newCount++
+			}
+		}
+		if oldCount == 0 {
+			oldStart--
+		}
+		if newCount == 0 {
+			newStart--
+		}
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+		for _, line := range lines[start:end] {
+			b.WriteByte(line.op)
+			b.WriteString(line.text)
+			if !strings.HasSuffix(line.text, "\n") {
+				b.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		for _, line := range lines[i:end] {
+			if line.op != '+' {
+				oldLine++
+			}
+			if line.op != '-' {
+				newLine++
+			}
+		}
+		i = end
+	}
+	return b.String()
+}
+
+// splitDiffLines splits text into lines that keep their trailing newline.
+func splitDiffLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines aligns a and b by their longest common subsequence after
+// trimming the shared prefix and suffix.
+func diffLines(a, b []string) []diffLine {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	out := make([]diffLine, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		out = append(out, diffLine{op: ' ', text: line})
+	}
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if len(midA)*len(midB) > maxDiffCells {
+		for _, line := range midA {
+			out = append(out, diffLine{op: '-', text: line})
+		}
+		for _, line := range midB {
+			out = append(out, diffLine{op: '+', text: line})
+		}
+	} else {
+		// lcs[i][j] is the common subsequence length of midA[i:] and midB[j:].
+		lcs := make([][]int, len(midA)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(midB)+1)
+		}
+		for i := len(midA) - 1; i >= 0; i-- {
+			for j := len(midB) - 1; j >= 0; j-- {
+				if midA[i] == midB[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+		i, j := 0, 0
+		for i < len(midA) || j < len(midB) {
+			switch {
+			case i < len(midA) && j < len(midB) && midA[i] == midB[j]:
+				out = append(out, diffLine{op: ' ', text: midA[i]})
+				i++
+				j++
+			case j < len(midB) && (i == len(midA) || lcs[i][j+1] > lcs[i+1][j]):
+				out = append(out, diffLine{op: '+', text: midB[j]})
+				j++
+			default:
+				out = append(out, diffLine{op: '-', text: midA[i]})
+				i++
+			}
+		}
+	}
+	for _, line := range a[len(a)-suffix:] {
+		out = append(out, diffLine{op: ' ', text: line})
+	}
+	return out
+}
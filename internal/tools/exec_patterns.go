@@ -0,0 +1,204 @@
+package tools
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Exec allow and block lists take three kinds of entry:
+//
+//   - a plain command name such as "rm", matched against the command itself
+//     (and, for blocking, against every word of the line);
+//   - a glob such as "git *" or "git push", matched against the leading words
+//     of each command in the line, with "*" spanning spaces;
+//   - a regular expression starting with "^", such as `^git\s+push`, matched
+//     case-sensitively against each command in the line.
+//
+// Commands are compared after quotes are removed and words are joined by one
+// space, with the program name reduced to its base name. Block entries are
+// also tried from every word of a command, so wrappers such as
+// `sudo git push` or `sh -c 'git push'` are caught.
+
+// isExecPattern reports whether entry is a glob or regex rather than a plain
+// command name.
+func isExecPattern(entry string) bool {
+	return strings.HasPrefix(entry, "^") || strings.ContainsAny(entry, "*?[ \t")
+}
+
+// compileExecPattern turns a glob or "^" regex entry into a regexp. Globs
+// match a leading run of whole words, case-insensitively.
+func compileExecPattern(entry string) (*regexp.Regexp, error) {
+	if strings.HasPrefix(entry, "^") {
+		return regexp.Compile(entry)
+	}
+	var b strings.Builder
+	b.WriteString(`(?i)^(?:`)
+	runes := []rune(strings.Join(strings.Fields(entry), " "))
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; r {
+		case '*':
+			b.WriteString(`.*`)
+		case '?':
+			b.WriteString(`.`)
+		case '[':
+			end := strings.IndexRune(string(runes[i+1:]), ']')
+			if end < 0 {
+				b.WriteString(regexp.QuoteMeta(string(r)))
+				continue
+			}
+			class := string(runes[i+1 : i+1+end])
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString(`)(?: |$)`)
+	return regexp.Compile(b.String())
+}
+
+// ValidateExecPatterns reports entries that are not valid globs or regular
+// expressions.
+func ValidateExecPatterns(entries []string) []string {
+	problems := make([]string, 0)
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || !isExecPattern(entry) {
+			continue
+		}
+		if _, err := compileExecPattern(entry); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid pattern %q: %v", entry, err))
+		}
+	}
+	return problems
+}
+
+// matchExecPatterns returns the first pattern entry that matches line, or ""
+// when none does. Invalid patterns never match.
+func matchExecPatterns(entries []string, line string) string {
+	for _, entry := range entries {
+		if !isExecPattern(entry) {
+			continue
+		}
+		re, err := compileExecPattern(entry)
+		if err == nil && re.MatchString(line) {
+			return entry
+		}
+	}
+	return ""
+}
+
+// commandLine joins words with single spaces, reducing the program name to
+// its base name.
+func commandLine(words []string) string {
+	if len(words) == 0 {
+		return ""
+	}
+	out := append([]string{filepath.Base(words[0])}, words[1:]...)
+	return strings.Join(out, " ")
+}
+
+// splitCommandLine splits command into the simple commands the shell would
+// run, each as its unquoted words. Commands are separated by ;, &, |,
+// newlines, parentheses, backticks, and $(...), including substitutions inside
+// double quotes. control reports whether any of those or a redirection
+// appeared outside single quotes. ok is false for unterminated quotes or a
+// trailing backslash.
+func splitCommandLine(command string) (commands [][]string, control bool, ok bool) {
+	var (
+		words    []string
+		word     strings.Builder
+		hasWord  bool
+		inSingle bool
+		inDouble bool
+	)
+	endWord := func() {
+		if hasWord {
+			words = append(words, word.String())
+		}
+		word.Reset()
+		hasWord = false
+	}
+	endCommand := func() {
+		endWord()
+		if len(words) > 0 {
+			commands = append(commands, words)
+		}
+		words = nil
+	}
+	runes := []rune(command)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case inSingle:
+			if r == '\'' {
+				inSingle = false
+				continue
+			}
+			word.WriteRune(r)
+			hasWord = true
+		case inDouble:
+			switch {
+			case r == '"':
+				inDouble = false
+			case r == '\\' && i+1 < len(runes) && strings.ContainsRune("\"\\$`", runes[i+1]):
+				i++
+				word.WriteRune(runes[i])
+				hasWord = true
+			case r == '`' || (r == '$' && i+1 < len(runes) && runes[i+1] == '('):
+				control = true
+				if r == '$' {
+					i++
+				}
+				endCommand()
+			case r == ')':
+				control = true
+				endCommand()
+			default:
+				word.WriteRune(r)
+				hasWord = true
+			}
+		default:
+			switch {
+			case r == '\\':
+				if i+1 >= len(runes) {
+					return nil, control, false
+				}
+				i++
+				word.WriteRune(runes[i])
+				hasWord = true
+			case r == '\'':
+				inSingle = true
+				hasWord = true
+			case r == '"':
+				inDouble = true
+				hasWord = true
+			case r == ' ' || r == '\t':
+				endWord()
+			case strings.ContainsRune(";&|\n()`", r):
+				control = true
+				endCommand()
+			case r == '$' && i+1 < len(runes) && runes[i+1] == '(':
+				control = true
+				i++
+				endCommand()
+			case r == '<' || r == '>':
+				control = true
+				endWord()
+			default:
+				word.WriteRune(r)
+				hasWord = true
+			}
+		}
+	}
+	if inSingle || inDouble {
+		return nil, control, false
+	}
+	endCommand()
+	return commands, control, true
+}
@@ -53,15 +53,16 @@ func NewWriteFileTool(policy *PathPolicy) *WriteFileTool {
 
 func (t *WriteFileTool) Name() string { return "write_file" }
 func (t *WriteFileTool) Description() string {
-	return "Write content to a file at the given path. Creates parent directories if needed."
+	return "Write content to a file at the given path. Creates parent directories if needed. Set dry_run to preview a unified diff without writing."
 }
 func (t *WriteFileTool) Schema() map[string]any {
-	return map[string]any{"type": "object", "properties": map[string]any{"path": map[string]any{"type": "string"}, "content": map[string]any{"type": "string"}}, "required": []string{"path", "content"}}
+	return map[string]any{"type": "object", "properties": map[string]any{"path": map[string]any{"type": "string"}, "content": map[string]any{"type": "string"}, "dry_run": dryRunSchema}, "required": []string{"path", "content"}}
 }
 func (t *WriteFileTool) Execute(_ context.Context, args json.RawMessage) (ToolResult, error) {
 	var in struct {
 		Path    string `json:"path"`
 		Content string `json:"content"`
+		DryRun  bool   `json:"dry_run"`
 	}
 	if err := json.Unmarshal(args, &in); err != nil {
 		return ToolResult{}, fmt.Errorf("invalid arguments: %w", err)
@@ -70,6 +71,13 @@ func (t *WriteFileTool) Execute(_ context.Context, args json.RawMessage) (ToolRe
 	if err != nil {
 		return ToolResult{}, err
 	}
+	if in.DryRun {
+		current, err := os.ReadFile(resolved)
+		if err != nil && !os.IsNotExist(err) {
+			return ToolResult{}, err
+		}
+		return dryRunResult(in.Path, string(current), in.Content, os.IsNotExist(err)), nil
+	}
 	if err := os.MkdirAll(filepath.Dir(resolved), 0o755); err != nil {
 		return ToolResult{}, err
 	}
@@ -89,16 +97,26 @@ func NewEditFileTool(policy *PathPolicy) *EditFileTool {
 
 func (t *EditFileTool) Name() string { return "edit_file" }
 func (t *EditFileTool) Description() string {
-	return "Edit a file by replacing old_text with new_text. The old_text must exist exactly in the file."
+	return "Edit a file by replacing old_text with new_text. The old_text must exist exactly in the file. Alternatively, set start_line and end_line (1-based, inclusive) instead of old_text to replace those lines with new_text; an empty new_text deletes them. Set dry_run to preview a unified diff without writing."
 }
 func (t *EditFileTool) Schema() map[string]any {
-	return map[string]any{"type": "object", "properties": map[string]any{"path": map[string]any{"type": "string"}, "old_text": map[string]any{"type": "string"}, "new_text": map[string]any{"type": "string"}}, "required": []string{"path", "old_text", "new_text"}}
+	return map[string]any{"type": "object", "properties": map[string]any{
+		"path":       map[string]any{"type": "string"},
+		"old_text":   map[string]any{"type": "string"},
+		"new_text":   map[string]any{"type": "string"},
+		"start_line": map[string]any{"type": "integer", "description": "First line to replace (1-based); use instead of old_text"},
+		"end_line":   map[string]any{"type": "integer", "description": "Last line to replace (inclusive, default start_line)"},
+		"dry_run":    dryRunSchema,
+	}, "required": []string{"path", "new_text"}}
 }
 func (t *EditFileTool) Execute(_ context.Context, args json.RawMessage) (ToolResult, error) {
 	var in struct {
-		Path    string `json:"path"`
-		OldText string `json:"old_text"`
-		NewText string `json:"new_text"`
+		Path      string `json:"path"`
+		OldText   string `json:"old_text"`
+		NewText   string `json:"new_text"`
+		StartLine *int   `json:"start_line"`
+		EndLine   *int   `json:"end_line"`
+		DryRun    bool   `json:"dry_run"`
 	}
 	if err := json.Unmarshal(args, &in); err != nil {
 		return ToolResult{}, fmt.Errorf("invalid arguments: %w", err)
@@ -112,6 +130,15 @@ func (t *EditFileTool) Execute(_ context.Context, args json.RawMessage) (ToolRes
 		return ToolResult{}, err
 	}
 	text := string(content)
+	if in.StartLine != nil || in.EndLine != nil {
+		if in.OldText != "" {
+			return ToolResult{Text: "Error: use either old_text or start_line/end_line, not both."}, nil
+		}
+		return t.replaceLines(resolved, in.Path, text, in.StartLine, in.EndLine, in.NewText, in.DryRun)
+	}
+	if in.OldText == "" {
+		return ToolResult{Text: "Error: old_text is empty. Provide the text to replace, or start_line and end_line."}, nil
+	}
 	count := strings.Count(text, in.OldText)
 	if count == 0 {
 		return ToolResult{Text: "Error: old_text not found in file. Make sure it matches exactly."}, nil
@@ -120,12 +147,67 @@ func (t *EditFileTool) Execute(_ context.Context, args json.RawMessage) (ToolRes
 		return ToolResult{Text: fmt.Sprintf("Warning: old_text appears %d times. Please provide more context to make it unique.", count)}, nil
 	}
 	updated := strings.Replace(text, in.OldText, in.NewText, 1)
+	if in.DryRun {
+		return dryRunResult(in.Path, text, updated, false), nil
+	}
 	if err := os.WriteFile(resolved, []byte(updated), 0o644); err != nil {
 		return ToolResult{}, err
 	}
 	return ToolResult{Text: fmt.Sprintf("Successfully edited %s", in.Path)}, nil
 }
 
+// replaceLines swaps lines start..end of text for newText and reports the
+// change as a unified diff. newText gains a trailing newline when the lines
+// it replaces had one.
+func (t *EditFileTool) replaceLines(resolved, path, text string, startLine, endLine *int, newText string, dryRun bool) (ToolResult, error) {
+	lines := splitDiffLines(text)
+	if startLine == nil {
+		return ToolResult{Text: "Error: end_line needs start_line."}, nil
+	}
+	start := *startLine
+	end := start
+	if endLine != nil {
+		end = *endLine
+	}
+	switch {
+	case start < 1:
+		return ToolResult{Text: fmt.Sprintf("Error: start_line %d is out of range; lines start at 1.", start)}, nil
+	case end < start:
+		return ToolResult{Text: fmt.Sprintf("Error: end_line %d is before start_line %d.", end, start)}, nil
+	case end > len(lines):
+		return ToolResult{Text: fmt.Sprintf("Error: line range %d-%d is out of range; %s has %d lines.", start, end, path, len(lines))}, nil
+	}
+	if newText != "" && !strings.HasSuffix(newText, "\n") && strings.HasSuffix(lines[end-1], "\n") {
+		newText += "\n"
+	}
+	updated := strings.Join(lines[:start-1], "") + newText + strings.Join(lines[end:], "")
+	if dryRun {
+		return dryRunResult(path, text, updated, false), nil
+	}
+	if err := os.WriteFile(resolved, []byte(updated), 0o644); err != nil {
+		return ToolResult{}, err
+	}
+	diff := unifiedDiff(path, text, updated, false)
+	if diff == "" {
+		return ToolResult{Text: fmt.Sprintf("Lines %d-%d of %s already match; nothing changed.", start, end, path)}, nil
+	}
+	return ToolResult{Text: fmt.Sprintf("Successfully edited lines %d-%d of %s.\n\n%s", start, end, path, diff)}, nil
+}
+
+var dryRunSchema = map[string]any{"type": "boolean", "description": "Return a unified diff of the change without writing the file"}
+
+// dryRunResult reports the diff a write would make to path.
+func dryRunResult(path, before, after string, created bool) ToolResult {
+	meta := map[string]any{"dry_run": true, "path": path}
+	diff := unifiedDiff(path, before, after, created)
+	if diff == "" {
+		meta["changed"] = false
+		return ToolResult{Text: fmt.Sprintf("Dry run: %s would be unchanged.", path), Metadata: meta}
+	}
+	meta["changed"] = true
+	return ToolResult{Text: fmt.Sprintf("Dry run: no changes written to %s.\n\n%s", path, diff), Metadata: meta}
+}
+
 type ListDirTool struct {
 	policy *PathPolicy
 }
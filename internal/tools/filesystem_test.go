@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -32,3 +33,119 @@ func TestEditFileMultipleOccurrences(t *testing.T) {
 		t.Fatalf("expected warning result, got: %s", result.Text)
 	}
 }
+
+func TestFileToolsDryRunReturnsDiffWithoutWriting(t *testing.T) {
+	workspace := t.TempDir()
+	path := filepath.Join(workspace, "notes.md")
+	original := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\n"
+	if err := os.WriteFile(path, []byte(original), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	policy, err := NewPathPolicy(workspace)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	args, _ := json.Marshal(map[string]any{"path": "notes.md", "old_text": "seven\n", "new_text": "SEVEN\n", "dry_run": true})
+	result, err := NewEditFileTool(policy).Execute(context.Background(), args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantDiff := "--- a/notes.md\n+++ b/notes.md\n@@ -4,5 +4,5 @@\n four\n five\n six\n-seven\n+SEVEN\n eight\n"
+	if !strings.HasSuffix(result.Text, wantDiff) || result.Metadata["dry_run"] != true {
+		t.Fatalf("unexpected edit dry run result: %q %+v", result.Text, result.Metadata)
+	}
+
+	args, _ = json.Marshal(map[string]any{"path": "new/plan.md", "content": "a\nb", "dry_run": true})
+	result, err = NewWriteFileTool(policy).Execute(context.Background(), args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantDiff = "--- /dev/null\n+++ b/new/plan.md\n@@ -0,0 +1,2 @@\n+a\n+b\n\\ No newline at end of file\n"
+	if !strings.HasSuffix(result.Text, wantDiff) {
+		t.Fatalf("unexpected write dry run diff: %q", result.Text)
+	}
+
+	current, err := os.ReadFile(path)
+	if err != nil || string(current) != original {
+		t.Fatalf("dry run modified the file: %q %v", current, err)
+	}
+	if _, err := os.Stat(filepath.Join(workspace, "new")); !os.IsNotExist(err) {
+		t.Fatalf("dry run created directories: %v", err)
+	}
+
+	args, _ = json.Marshal(map[string]any{"path": "../outside.md", "content": "x", "dry_run": true})
+	if _, err := NewWriteFileTool(policy).Execute(context.Background(), args); err == nil {
+		t.Fatal("expected dry run outside the workspace to be rejected")
+	}
+}
+
+func TestEditFileReplacesLineRange(t *testing.T) {
+	workspace := t.TempDir()
+	path := filepath.Join(workspace, "list.md")
+	if err := os.WriteFile(path, []byte("a\nb\nc\nd\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	policy, err := NewPathPolicy(workspace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tool := NewEditFileTool(policy)
+	edit := func(fields map[string]any) string {
+		t.Helper()
+		fields["path"] = "list.md"
+		args, _ := json.Marshal(fields)
+		result, err := tool.Execute(context.Background(), args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result.Text
+	}
+
+	text := edit(map[string]any{"start_line": 2, "end_line": 3, "new_text": "B\nC\nC2"})
+	wantDiff := "--- a/list.md\n+++ b/list.md\n@@ -1,4 +1,5 @@\n a\n-b\n-c\n+B\n+C\n+C2\n d\n"
+	if !strings.HasPrefix(text, "Successfully edited lines 2-3") || !strings.HasSuffix(text, wantDiff) {
+		t.Fatalf("unexpected line edit result: %q", text)
+	}
+	current, _ := os.ReadFile(path)
+	if string(current) != "a\nB\nC\nC2\nd\n" {
+		t.Fatalf("unexpected file after line edit: %q", current)
+	}
+
+	edit(map[string]any{"start_line": 5, "new_text": ""})
+	current, _ = os.ReadFile(path)
+	if string(current) != "a\nB\nC\nC2\n" {
+		t.Fatalf("expected an empty new_text to delete the line, got %q", current)
+	}
+
+	for _, tc := range []struct {
+		fields map[string]any
+		want   string
+	}{
+		{map[string]any{"start_line": 3, "end_line": 9, "new_text": "x"}, "out of range; list.md has 4 lines"},
+		{map[string]any{"start_line": 0, "new_text": "x"}, "lines start at 1"},
+		{map[string]any{"start_line": 3, "end_line": 2, "new_text": "x"}, "before start_line"},
+		{map[string]any{"end_line": 2, "new_text": "x"}, "needs start_line"},
+		{map[string]any{"start_line": 1, "old_text": "a", "new_text": "x"}, "not both"},
+	} {
+		if text := edit(tc.fields); !strings.HasPrefix(text, "Error:") || !strings.Contains(text, tc.want) {
+			t.Fatalf("expected error containing %q for %v, got %q", tc.want, tc.fields, text)
+		}
+	}
+	current, _ = os.ReadFile(path)
+	if string(current) != "a\nB\nC\nC2\n" {
+		t.Fatalf("rejected edits modified the file: %q", current)
+	}
+}
+
+func TestUnifiedDiffSplitsDistantHunks(t *testing.T) {
+	before := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+	after := "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n"
+	want := "--- a/f\n+++ b/f\n@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n@@ -9,4 +9,3 @@\n 9\n 10\n 11\n-12\n"
+	if got := unifiedDiff("f", before, after, false); got != want {
+		t.Fatalf("unexpected diff:\n%s", got)
+	}
+	if got := unifiedDiff("f", before, before, false); got != "" {
+		t.Fatalf("expected no diff for equal content, got %q", got)
+	}
+}
@@ -11,16 +11,24 @@ import (
 type SendMessageFunc func(ctx context.Context, channel, chatID, content string) error
 
 type MessageTool struct {
-	send      SendMessageFunc
-	channel   string
-	chatID    string
-	sessionID string
+	send         SendMessageFunc
+	allowTargets []string
+	channel      string
+	chatID       string
+	sessionID    string
 }
 
 func NewMessageTool(send SendMessageFunc) *MessageTool {
 	return &MessageTool{send: send}
 }
 
+// NewMessageToolWithTargets lets the tool send outside the current
+// conversation to the "channel:chat_id" entries in allowTargets, where
+// "channel:*" admits every chat on that channel.
+func NewMessageToolWithTargets(send SendMessageFunc, allowTargets []string) *MessageTool {
+	return &MessageTool{send: send, allowTargets: allowTargets}
+}
+
 func (t *MessageTool) SetContext(channel, chatID, sessionID string) {
 	t.channel = channel
 	t.chatID = chatID
@@ -29,7 +37,7 @@ func (t *MessageTool) SetContext(channel, chatID, sessionID string) {
 
 func (t *MessageTool) Name() string { return "message" }
 func (t *MessageTool) Description() string {
-	return "Send a message to a user on a target channel."
+	return "Send a message to the current conversation, or to another channel and chat_id allowed by tools.message.allowTargets."
 }
 func (t *MessageTool) Schema() map[string]any {
 	return map[string]any{"type": "object", "properties": map[string]any{
@@ -55,17 +63,39 @@ func (t *MessageTool) Execute(ctx context.Context, args json.RawMessage) (ToolRe
 	}
 	channel := t.channel
 	if strings.TrimSpace(in.Channel) != "" {
-		channel = in.Channel
+		channel = strings.TrimSpace(in.Channel)
 	}
 	chatID := t.chatID
 	if strings.TrimSpace(in.ChatID) != "" {
-		chatID = in.ChatID
+		chatID = strings.TrimSpace(in.ChatID)
 	}
 	if strings.TrimSpace(channel) == "" || strings.TrimSpace(chatID) == "" {
 		return ToolResult{Text: "Error: No target channel/chat specified"}, nil
 	}
+	if !t.targetAllowed(channel, chatID) {
+		return ToolResult{Text: fmt.Sprintf("Error: target %s:%s is not in tools.message.allowTargets", channel, chatID)}, nil
+	}
 	if err := t.send(ctx, channel, chatID, in.Content); err != nil {
 		return ToolResult{}, err
 	}
 	return ToolResult{Text: fmt.Sprintf("Message sent to %s:%s", channel, chatID)}, nil
 }
+
+// targetAllowed reports whether channel and chatID name the current
+// conversation or match an allowTargets entry.
+func (t *MessageTool) targetAllowed(channel, chatID string) bool {
+	if strings.EqualFold(channel, t.channel) && chatID == t.chatID {
+		return true
+	}
+	for _, entry := range t.allowTargets {
+		entryChannel, entryChat, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || !strings.EqualFold(strings.TrimSpace(entryChannel), channel) {
+			continue
+		}
+		entryChat = strings.TrimSpace(entryChat)
+		if entryChat == "*" || entryChat == chatID {
+			return true
+		}
+	}
+	return false
+}
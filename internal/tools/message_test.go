@@ -0,0 +1,35 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestMessageToolTargetsAllowList(t *testing.T) {
+	var sent []string
+	tool := NewMessageToolWithTargets(func(ctx context.Context, channel, chatID, content string) error {
+		sent = append(sent, channel+":"+chatID)
+		return nil
+	}, []string{"telegram:42", "slack:*"})
+	tool.SetContext("cli", "direct", "cli:direct")
+
+	for _, args := range []string{
+		`{"content":"hi"}`,
+		`{"content":"hi","channel":"telegram","chat_id":"42"}`,
+		`{"content":"hi","channel":"Slack","chat_id":"C9"}`,
+	} {
+		result, err := tool.Execute(context.Background(), json.RawMessage(args))
+		if err != nil || strings.HasPrefix(result.Text, "Error") {
+			t.Fatalf("expected %s to send, got %q err=%v", args, result.Text, err)
+		}
+	}
+	result, err := tool.Execute(context.Background(), json.RawMessage(`{"content":"hi","channel":"telegram","chat_id":"7"}`))
+	if err != nil || !strings.Contains(result.Text, "not in tools.message.allowTargets") {
+		t.Fatalf("expected unlisted target to be refused, got %q err=%v", result.Text, err)
+	}
+	if got := strings.Join(sent, ","); got != "cli:direct,telegram:42,Slack:C9" {
+		t.Fatalf("unexpected deliveries %q", got)
+	}
+}
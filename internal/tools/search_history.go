@@ -0,0 +1,146 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	searchHistoryDefaultLimit = 5
+	searchHistoryMaxLimit     = 20
+	searchHistorySnippetChars = 240
+)
+
+type SearchHistoryRequest struct {
+	SessionID string
+	Query     string
+	Limit     int
+}
+
+type HistoryMatch struct {
+	Role      string
+	Content   string
+	CreatedAt time.Time
+}
+
+type SearchHistoryFunc func(ctx context.Context, req SearchHistoryRequest) ([]HistoryMatch, error)
+
+type SearchHistoryTool struct {
+	search    SearchHistoryFunc
+	sessionID string
+}
+
+func NewSearchHistoryTool(search SearchHistoryFunc) *SearchHistoryTool {
+	return &SearchHistoryTool{search: search}
+}
+
+func (t *SearchHistoryTool) SetContext(sessionID string) {
+	t.sessionID = sessionID
+}
+
+func (t *SearchHistoryTool) Name() string { return "search_history" }
+
+func (t *SearchHistoryTool) Description() string {
+	return "Search earlier messages in this conversation, including ones outside the current context window. Returns matching snippets, newest first."
+}
+
+func (t *SearchHistoryTool) Schema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"query": map[string]any{"type": "string", "description": "Words that must all appear in the message"},
+			"limit": map[string]any{"type": "integer", "minimum": 1, "maximum": searchHistoryMaxLimit},
+		},
+		"required": []string{"query"},
+	}
+}
+
+func (t *SearchHistoryTool) Execute(ctx context.Context, args json.RawMessage) (ToolResult, error) {
+	if t.search == nil {
+		return ToolResult{}, fmt.Errorf("conversation history is not available")
+	}
+	var in struct {
+		Query string `json:"query"`
+		Limit int    `json:"limit"`
+	}
+	if err := json.Unmarshal(args, &in); err != nil {
+		return ToolResult{}, fmt.Errorf("invalid arguments: %w", err)
+	}
+	query := strings.TrimSpace(in.Query)
+	if query == "" {
+		return ToolResult{}, fmt.Errorf("query is required")
+	}
+	limit := in.Limit
+	if limit <= 0 {
+		limit = searchHistoryDefaultLimit
+	}
+	if limit > searchHistoryMaxLimit {
+		limit = searchHistoryMaxLimit
+	}
+	matches, err := t.search(ctx, SearchHistoryRequest{SessionID: t.sessionID, Query: query, Limit: limit})
+	if err != nil {
+		return ToolResult{}, err
+	}
+	if len(matches) == 0 {
+		return ToolResult{Text: fmt.Sprintf("No earlier messages match %q.", query), Metadata: map[string]any{"matches": 0}}, nil
+	}
+	lines := make([]string, 0, len(matches))
+	for _, match := range matches {
+		lines = append(lines, fmt.Sprintf("[%s] %s: %s", match.CreatedAt.UTC().Format(time.RFC3339), match.Role, historySnippet(match.Content, query, searchHistorySnippetChars)))
+	}
+	return ToolResult{Text: strings.Join(lines, "\n"), Metadata: map[string]any{"matches": len(matches)}}, nil
+}
+
+// historySnippet returns up to width runes of content centred on the first
+// occurrence of any query term, with ellipses marking trimmed ends.
+func historySnippet(content, query string, width int) string {
+	content = strings.Join(strings.Fields(content), " ")
+	runes := []rune(content)
+	if len(runes) <= width {
+		return content
+	}
+	lower := []rune(strings.ToLower(content))
+	hit := -1
+	for _, term := range strings.Fields(strings.ToLower(query)) {
+		if idx := runeIndex(lower, []rune(term)); idx >= 0 && (hit < 0 || idx < hit) {
+			hit = idx
+		}
+	}
+	start := 0
+	if hit > width/3 {
+		start = hit - width/3
+	}
+	if start+width > len(runes) {
+		start = len(runes) - width
+	}
+	snippet := string(runes[start : start+width])
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if start+width < len(runes) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+func runeIndex(haystack, needle []rune) int {
+	if len(needle) == 0 {
+		return -1
+	}
+	for i := 0; i+len(needle) <= len(haystack); i++ {
+		match := true
+		for j := range needle {
+			if haystack[i+j] != needle[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return i
+		}
+	}
+	return -1
+}
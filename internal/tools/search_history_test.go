@@ -0,0 +1,49 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSearchHistoryToolScopesToSessionAndSnippets(t *testing.T) {
+	var got SearchHistoryRequest
+	long := strings.Repeat("filler ", 80) + "the release checklist is pinned" + strings.Repeat(" trailing", 80)
+	tool := NewSearchHistoryTool(func(_ context.Context, req SearchHistoryRequest) ([]HistoryMatch, error) {
+		got = req
+		return []HistoryMatch{{Role: "user", Content: long, CreatedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)}}, nil
+	})
+	tool.SetContext("session-1")
+
+	result, err := tool.Execute(context.Background(), json.RawMessage(`{"query":"  checklist ","limit":99}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.SessionID != "session-1" || got.Query != "checklist" || got.Limit != searchHistoryMaxLimit {
+		t.Fatalf("unexpected request %+v", got)
+	}
+	if !strings.HasPrefix(result.Text, "[2026-01-02T03:04:05Z] user: …") {
+		t.Fatalf("unexpected result prefix: %q", result.Text)
+	}
+	if !strings.Contains(result.Text, "release checklist is pinned") || !strings.HasSuffix(result.Text, "…") {
+		t.Fatalf("expected snippet around match, got %q", result.Text)
+	}
+}
+
+func TestSearchHistoryToolReportsNoMatches(t *testing.T) {
+	tool := NewSearchHistoryTool(func(context.Context, SearchHistoryRequest) ([]HistoryMatch, error) {
+		return nil, nil
+	})
+	result, err := tool.Execute(context.Background(), json.RawMessage(`{"query":"missing"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.Text, "No earlier messages") {
+		t.Fatalf("unexpected result %q", result.Text)
+	}
+	if _, err := tool.Execute(context.Background(), json.RawMessage(`{"query":" "}`)); err == nil {
+		t.Fatal("expected empty query to fail")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
@@ -18,8 +19,6 @@ var (
 	errExecDisabled = errors.New("policy_denied: exec tool is disabled")
 )
 
-var shellControlPattern = regexp.MustCompile(`[;&|><` + "`" + `]|\$\(`)
-
 type ExecPolicy struct {
 	Enabled         bool
 	AllowedCommands []string
@@ -52,12 +51,14 @@ func (t *ExecTool) Description() string {
 func (t *ExecTool) Schema() map[string]any {
 	return map[string]any{"type": "object", "properties": map[string]any{
 		"command":     map[string]any{"type": "string", "description": "The shell command to execute"},
-		"working_dir": map[string]any{"type": "string", "description": "Optional working directory"},
+		"cwd":         map[string]any{"type": "string", "description": "Optional working directory inside the workspace; relative paths resolve against the workspace root"},
+		"working_dir": map[string]any{"type": "string", "description": "Deprecated alias for cwd"},
 	}, "required": []string{"command"}}
 }
 func (t *ExecTool) Execute(ctx context.Context, args json.RawMessage) (ToolResult, error) {
 	var in struct {
 		Command    string `json:"command"`
+		Cwd        string `json:"cwd"`
 		WorkingDir string `json:"working_dir"`
 	}
 	if err := json.Unmarshal(args, &in); err != nil {
@@ -67,19 +68,23 @@ func (t *ExecTool) Execute(ctx context.Context, args json.RawMessage) (ToolResul
 		return ToolResult{}, fmt.Errorf("command required")
 	}
 	if !t.config.Enabled {
-		return ToolResult{Text: errExecDisabled.Error(), Metadata: map[string]any{"policy_denied": true, "reason": "disabled"}}, nil
+		return execRefusal(in.Command, "disabled", errExecDisabled, t.config), nil
 	}
 	decision, policyErr := evaluateExecPolicy(in.Command, t.config)
 	if policyErr != nil {
-		return ToolResult{Text: policyErr.Error(), Metadata: map[string]any{"policy_denied": true, "reason": decision}}, nil
+		return execRefusal(in.Command, decision, policyErr, t.config), nil
 	}
 
 	cwd, err := t.policy.Resolve(".")
 	if err != nil {
 		return ToolResult{}, err
 	}
-	if strings.TrimSpace(in.WorkingDir) != "" {
-		cwd, err = t.policy.Resolve(in.WorkingDir)
+	requested := strings.TrimSpace(in.Cwd)
+	if requested == "" {
+		requested = strings.TrimSpace(in.WorkingDir)
+	}
+	if requested != "" {
+		cwd, err = t.resolveCwd(requested)
 		if err != nil {
 			return ToolResult{}, err
 		}
@@ -120,6 +125,90 @@ func (t *ExecTool) Execute(ctx context.Context, args json.RawMessage) (ToolResul
 	return ToolResult{Text: result, Metadata: map[string]any{"policy_denied": false, "policy_reason": decision}}, nil
 }
 
+// resolveCwd maps a requested working directory through the path policy and
+// checks that it is an existing directory.
+func (t *ExecTool) resolveCwd(requested string) (string, error) {
+	cwd, err := t.policy.Resolve(requested)
+	if err != nil {
+		return "", fmt.Errorf("cwd %q rejected: %w", requested, err)
+	}
+	info, err := os.Stat(cwd)
+	if err != nil {
+		return "", fmt.Errorf("cwd %q: %w", requested, err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("cwd %q is not a directory", requested)
+	}
+	return cwd, nil
+}
+
+// execAlternatives maps commonly refused commands to ones that often do the
+// same job, so a refusal can point the model at an allowlisted substitute.
+var execAlternatives = map[string][]string{
+	"cat":     {"head", "tail", "less", "sed"},
+	"curl":    {"wget"},
+	"wget":    {"curl"},
+	"find":    {"ls", "tree", "fd"},
+	"grep":    {"rg", "ag"},
+	"rg":      {"grep"},
+	"ls":      {"find", "tree"},
+	"python":  {"python3"},
+	"python3": {"python"},
+	"pip":     {"pip3"},
+	"pip3":    {"pip"},
+	"vim":     {"sed"},
+	"nano":    {"sed"},
+}
+
+// execRefusal explains a policy denial to the model: why the command was
+// refused, what it may run instead, and that retrying the same command will
+// not help. The text keeps the policy_denied prefix.
+func execRefusal(command, reason string, cause error, cfg ExecPolicy) ToolResult {
+	lines := []string{cause.Error()}
+	meta := map[string]any{"policy_denied": true, "reason": reason}
+	root := ""
+	if tokens := extractCommandTokens(command); len(tokens) > 0 {
+		root = strings.ToLower(filepath.Base(tokens[0]))
+	}
+	switch reason {
+	case "disabled":
+		lines = append(lines, "Shell access is turned off for this agent. Do not call exec again; do the task with the other tools or tell the user it needs a shell.")
+	case "blocked_command":
+		lines = append(lines, "Blocked commands: "+strings.Join(cfg.BlockedCommands, ", ")+".",
+			"Do not retry it or hide it behind another command or wrapper; choose a different approach or ask the user to run it.")
+		meta["blocked_commands"] = cfg.BlockedCommands
+	case "not_allowlisted":
+		lines = append(lines, "Allowed commands: "+strings.Join(cfg.AllowedCommands, ", ")+".")
+		if suggestion := suggestExecAlternative(root, cfg.AllowedCommands); suggestion != "" {
+			lines = append(lines, fmt.Sprintf("Try %q instead of %q.", suggestion, root))
+			meta["suggestion"] = suggestion
+		} else {
+			lines = append(lines, "Rewrite the command with one of the allowed commands, or ask the user to run it.")
+		}
+		meta["allowed_commands"] = cfg.AllowedCommands
+	case "shell_control_operator":
+		lines = append(lines, "With an allowlist, run a single command per call without ;, &, |, <, >, backticks, or $(...); chain steps with separate exec calls.")
+		meta["allowed_commands"] = cfg.AllowedCommands
+	}
+	if root != "" {
+		meta["command"] = root
+	}
+	return ToolResult{Text: strings.Join(lines, "\n"), Metadata: meta}
+}
+
+// suggestExecAlternative returns an allowlisted command that commonly stands
+// in for root, or "" when none is known.
+func suggestExecAlternative(root string, allowed []string) string {
+	for _, candidate := range execAlternatives[root] {
+		if slices.Contains(allowed, candidate) {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// normalizeCommandList trims entries and lowercases command names and globs.
+// Regex entries keep their case so character classes such as \S survive.
 func normalizeCommandList(values []string) []string {
 	out := make([]string, 0, len(values))
 	for _, value := range values {
@@ -127,7 +216,10 @@ func normalizeCommandList(values []string) []string {
 		if value == "" {
 			continue
 		}
-		out = append(out, strings.ToLower(value))
+		if !strings.HasPrefix(value, "^") {
+			value = strings.ToLower(value)
+		}
+		out = append(out, value)
 	}
 	return out
 }
@@ -138,7 +230,8 @@ func evaluateExecPolicy(command string, cfg ExecPolicy) (string, error) {
 		return "empty", fmt.Errorf("policy_denied: command is empty")
 	}
 	tokens := extractCommandTokens(command)
-	if len(tokens) == 0 {
+	commands, control, ok := splitCommandLine(command)
+	if len(tokens) == 0 || !ok || len(commands) == 0 {
 		return "unparseable", fmt.Errorf("policy_denied: command could not be parsed safely")
 	}
 
@@ -148,13 +241,24 @@ func evaluateExecPolicy(command string, cfg ExecPolicy) (string, error) {
 			return "blocked_command", fmt.Errorf("policy_denied: blocked command %q", base)
 		}
 	}
+	for _, words := range commands {
+		for start := range words {
+			if entry := matchExecPatterns(cfg.BlockedCommands, commandLine(words[start:])); entry != "" {
+				return "blocked_command", fmt.Errorf("policy_denied: command matches blocked pattern %q", entry)
+			}
+		}
+	}
 
 	if len(cfg.AllowedCommands) > 0 {
-		root := strings.ToLower(filepath.Base(tokens[0]))
-		if !slices.Contains(cfg.AllowedCommands, root) && !slices.Contains(cfg.AllowedCommands, strings.ToLower(tokens[0])) {
+		// Only the first command is checked: any further one means a control
+		// operator, which an allowlist refuses outright.
+		words := commands[0]
+		root := strings.ToLower(filepath.Base(words[0]))
+		if !slices.Contains(cfg.AllowedCommands, root) && !slices.Contains(cfg.AllowedCommands, strings.ToLower(words[0])) &&
+			matchExecPatterns(cfg.AllowedCommands, commandLine(words)) == "" {
 			return "not_allowlisted", fmt.Errorf("policy_denied: command %q is not allowlisted", root)
 		}
-		if shellControlPattern.MatchString(command) {
+		if control {
 			return "shell_control_operator", fmt.Errorf("policy_denied: control operators are not allowed with allowlisted exec")
 		}
 	}
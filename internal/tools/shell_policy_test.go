@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -73,3 +74,103 @@ func TestExecToolPolicyAllowedCommandRuns(t *testing.T) {
 		t.Fatalf("expected pwd output to include workspace path, got %q", result.Text)
 	}
 }
+
+func TestExecToolCwdResolvesInsideWorkspace(t *testing.T) {
+	workspace := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(workspace, "repo", "src"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	policy, err := NewPathPolicy(workspace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tool := NewExecToolWithPolicy(policy, 0, ExecPolicy{Enabled: true, AllowedCommands: []string{"pwd"}})
+	args, _ := json.Marshal(map[string]any{"command": "pwd", "cwd": "repo/src"})
+	result, err := tool.Execute(context.Background(), args)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.TrimSpace(result.Text) != filepath.Join(filepath.Clean(workspace), "repo", "src") {
+		t.Fatalf("expected pwd to report the requested cwd, got %q", result.Text)
+	}
+
+	for _, cwd := range []string{"../", "/", "missing"} {
+		args, _ := json.Marshal(map[string]any{"command": "pwd", "cwd": cwd})
+		if _, err := tool.Execute(context.Background(), args); err == nil || !strings.Contains(err.Error(), "cwd") {
+			t.Fatalf("expected cwd %q to be rejected, got %v", cwd, err)
+		}
+	}
+}
+
+func TestExecToolRefusalExplainsPolicyToModel(t *testing.T) {
+	policy, err := NewPathPolicy(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tool := NewExecToolWithPolicy(policy, 0, ExecPolicy{Enabled: true, AllowedCommands: []string{"head", "ls"}})
+	args, _ := json.Marshal(map[string]any{"command": "cat notes.txt"})
+	result, err := tool.Execute(context.Background(), args)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(result.Text, "policy_denied") || !strings.Contains(result.Text, "Allowed commands: head, ls") || !strings.Contains(result.Text, `Try "head" instead of "cat"`) {
+		t.Fatalf("expected refusal with allowed commands and a suggestion, got %q", result.Text)
+	}
+	if result.Metadata["reason"] != "not_allowlisted" || result.Metadata["suggestion"] != "head" || result.Metadata["command"] != "cat" {
+		t.Fatalf("unexpected refusal metadata: %+v", result.Metadata)
+	}
+
+	blocked := NewExecToolWithPolicy(policy, 0, ExecPolicy{Enabled: true, BlockedCommands: []string{"rm"}})
+	args, _ = json.Marshal(map[string]any{"command": "rm -rf build"})
+	result, err = blocked.Execute(context.Background(), args)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(result.Text, "Blocked commands: rm") || !strings.Contains(result.Text, "Do not retry") {
+		t.Fatalf("expected blocked refusal guidance, got %q", result.Text)
+	}
+}
+
+func TestEvaluateExecPolicyPatterns(t *testing.T) {
+	cases := []struct {
+		name    string
+		command string
+		policy  ExecPolicy
+		want    string
+	}{
+		{"rm -rf regex", "rm -rf /tmp/a", ExecPolicy{BlockedCommands: []string{`^rm\s+-(rf|fr)\b`}}, "blocked_command"},
+		{"rm without -rf", "rm notes.txt", ExecPolicy{BlockedCommands: []string{`^rm\s+-(rf|fr)\b`}}, "allowed"},
+		{"quoted program name", `'rm' -rf "/tmp/a b"`, ExecPolicy{BlockedCommands: []string{"rm -rf *"}}, "blocked_command"},
+		{"absolute program path", "/bin/rm -rf build", ExecPolicy{BlockedCommands: []string{"rm -rf*"}}, "blocked_command"},
+		{"wrapped in sh -c", `sh -c 'git push --force'`, ExecPolicy{BlockedCommands: []string{"git push"}}, "blocked_command"},
+		{"glob prefix matches whole words", "git pushall", ExecPolicy{BlockedCommands: []string{"git push"}}, "allowed"},
+		{"pipeline stage", "cat notes.txt | xargs rm -rf", ExecPolicy{BlockedCommands: []string{"rm -rf*"}}, "blocked_command"},
+		{"command substitution in quotes", `echo "$(git push)"`, ExecPolicy{BlockedCommands: []string{"git push"}}, "blocked_command"},
+		{"blocklist beats allowlist", "git push origin main", ExecPolicy{AllowedCommands: []string{"git *"}, BlockedCommands: []string{"git push*"}}, "blocked_command"},
+		{"allowlist glob", "git log --oneline -5", ExecPolicy{AllowedCommands: []string{"git *"}, BlockedCommands: []string{"git push*"}}, "allowed"},
+		{"allowlist glob needs its words", "git", ExecPolicy{AllowedCommands: []string{"git *"}}, "not_allowlisted"},
+		{"allowlist regex", "go test ./...", ExecPolicy{AllowedCommands: []string{`^go (build|test|vet)\b`}}, "allowed"},
+		{"allowlist regex miss", "go run main.go", ExecPolicy{AllowedCommands: []string{`^go (build|test|vet)\b`}}, "not_allowlisted"},
+		{"quoted separator is an argument", `git commit -m "fix; tidy | trim"`, ExecPolicy{AllowedCommands: []string{"git commit *"}}, "allowed"},
+		{"allowlisted pipeline refused", "git log | head", ExecPolicy{AllowedCommands: []string{"git *", "head"}}, "shell_control_operator"},
+		{"unterminated quote", `git commit -m "oops`, ExecPolicy{AllowedCommands: []string{"git *"}}, "unparseable"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			policy := tc.policy
+			policy.AllowedCommands = normalizeCommandList(policy.AllowedCommands)
+			policy.BlockedCommands = normalizeCommandList(policy.BlockedCommands)
+			got, _ := evaluateExecPolicy(tc.command, policy)
+			if got != tc.want {
+				t.Fatalf("evaluateExecPolicy(%q) = %q, want %q", tc.command, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidateExecPatterns(t *testing.T) {
+	problems := ValidateExecPatterns([]string{"rm", "git *", `^rm\s+(`, "[abc"})
+	if len(problems) != 1 || !strings.Contains(problems[0], `^rm\s+(`) {
+		t.Fatalf("expected one invalid regex, got %v", problems)
+	}
+}
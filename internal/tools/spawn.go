@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -103,11 +104,17 @@ func (t *SpawnTool) Execute(ctx context.Context, args json.RawMessage) (ToolResu
 	if strings.TrimSpace(in.Task) == "" {
 		return ToolResult{}, fmt.Errorf("task is required")
 	}
+	// An omitted context_mode stays empty so the engine can apply
+	// runtime.subagents.defaultContextMode.
+	var contextMode subagent.ContextMode
+	if strings.TrimSpace(in.ContextMode) != "" {
+		contextMode = subagent.NormalizeContextMode(in.ContextMode)
+	}
 	message, err := t.spawn(ctx, SpawnRequest{
 		Task:        in.Task,
 		Label:       in.Label,
 		Target:      strings.ToLower(strings.TrimSpace(in.Target)),
-		ContextMode: subagent.NormalizeContextMode(in.ContextMode),
+		ContextMode: contextMode,
 		Attachments: in.Attachments,
 		TimeoutSec:  in.TimeoutSec,
 		MaxAttempts: in.MaxAttempts,
@@ -122,6 +129,19 @@ func (t *SpawnTool) Execute(ctx context.Context, args json.RawMessage) (ToolResu
 		SenderID:    t.senderID,
 		Depth:       t.depth,
 	})
+	var queueFull *subagent.QueueFullError
+	if errors.As(err, &queueFull) {
+		// Saturation is transient, so hand the model a result it can act on
+		// instead of failing the call.
+		return ToolResult{
+			Text: fmt.Sprintf("Subagent capacity reached (%d/%d runs queued). Retry later or run the task inline.", queueFull.Depth, queueFull.Capacity),
+			Metadata: map[string]any{
+				"status":         "rejected",
+				"queue_depth":    queueFull.Depth,
+				"queue_capacity": queueFull.Capacity,
+			},
+		}, nil
+	}
 	if err != nil {
 		return ToolResult{}, err
 	}
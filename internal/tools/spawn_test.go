@@ -3,6 +3,8 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/grixate/squidbot/internal/subagent"
@@ -71,3 +73,20 @@ func TestSpawnToolParsesExtendedFields(t *testing.T) {
 		t.Fatalf("unexpected depth: %d", got.Depth)
 	}
 }
+
+func TestSpawnToolReportsQueueFullAsResult(t *testing.T) {
+	tool := NewSpawnTool(func(ctx context.Context, req SpawnRequest) (SpawnResponse, error) {
+		return SpawnResponse{}, fmt.Errorf("enqueue: %w", &subagent.QueueFullError{Depth: 8, Capacity: 8})
+	})
+	args, _ := json.Marshal(map[string]any{"task": "crawl"})
+	result, err := tool.Execute(context.Background(), args)
+	if err != nil {
+		t.Fatalf("expected queue saturation to be a tool result, got error %v", err)
+	}
+	if !strings.Contains(result.Text, "capacity reached (8/8") || !strings.Contains(result.Text, "run the task inline") {
+		t.Fatalf("unexpected text: %q", result.Text)
+	}
+	if result.Metadata["status"] != "rejected" || result.Metadata["queue_depth"] != 8 {
+		t.Fatalf("unexpected metadata: %#v", result.Metadata)
+	}
+}
@@ -43,6 +43,9 @@ func (r *Registry) Execute(ctx context.Context, name string, args json.RawMessag
 	if !ok {
 		return ToolResult{}, fmt.Errorf("Error: Tool '%s' not found", name)
 	}
+	if err := ValidateArgs(name, tool.Schema(), args); err != nil {
+		return ToolResult{}, err
+	}
 	result, err := tool.Execute(ctx, args)
 	if err != nil {
 		return ToolResult{}, fmt.Errorf("Error executing %s: %w", name, err)
@@ -65,3 +68,16 @@ func (r *Registry) Names() []string {
 	}
 	return out
 }
+
+// BuiltinToolNames lists the tools compiled into this build. Plugin tools are
+// discovered at runtime and are not included.
+func BuiltinToolNames() []string {
+	return []string{
+		"read_file", "write_file", "edit_file", "list_dir", "exec",
+		"web_search", "web_fetch", "message", "search_history",
+		"spawn", "subagent_wait", "subagent_status", "subagent_result", "subagent_cancel",
+		"federation_peers",
+		"budget_status", "budget_set_limits", "budget_set_mode", "budget_set_enabled", "budget_set_estimation", "budget_reset",
+		"create_task", "update_task",
+	}
+}
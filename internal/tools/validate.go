@@ -0,0 +1,227 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// ArgumentProblem describes one field that does not match a tool's schema.
+type ArgumentProblem struct {
+	Field   string `json:"field"`
+	Problem string `json:"problem"`
+}
+
+// ArgumentError is returned when tool arguments fail schema validation. Its
+// message is JSON so the model can see exactly which fields to correct.
+type ArgumentError struct {
+	Tool     string            `json:"tool"`
+	Problems []ArgumentProblem `json:"problems"`
+}
+
+func (e *ArgumentError) Error() string {
+	payload, _ := json.Marshal(map[string]any{
+		"error":    "invalid_arguments",
+		"tool":     e.Tool,
+		"problems": e.Problems,
+		"hint":     "Correct the arguments to match the tool schema and call the tool again.",
+	})
+	return string(payload)
+}
+
+// ValidateArgs checks raw tool arguments against the subset of JSON Schema
+// used by tool definitions: type, properties, required, enum, minimum,
+// maximum, items, and additionalProperties=false.
+func ValidateArgs(tool string, schema map[string]any, args json.RawMessage) error {
+	if len(schema) == 0 {
+		return nil
+	}
+	trimmed := bytes.TrimSpace(args)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		trimmed = []byte("{}")
+	}
+	decoder := json.NewDecoder(bytes.NewReader(trimmed))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return &ArgumentError{Tool: tool, Problems: []ArgumentProblem{{Field: "$", Problem: "arguments are not valid JSON: " + err.Error()}}}
+	}
+	if decoder.More() {
+		return &ArgumentError{Tool: tool, Problems: []ArgumentProblem{{Field: "$", Problem: "arguments contain trailing data after the JSON object"}}}
+	}
+	var problems []ArgumentProblem
+	validateValue("", schema, value, &problems)
+	if len(problems) == 0 {
+		return nil
+	}
+	return &ArgumentError{Tool: tool, Problems: problems}
+}
+
+func validateValue(path string, schema map[string]any, value any, problems *[]ArgumentProblem) {
+	field := path
+	if field == "" {
+		field = "$"
+	}
+	add := func(format string, args ...any) {
+		*problems = append(*problems, ArgumentProblem{Field: field, Problem: fmt.Sprintf(format, args...)})
+	}
+
+	expected, _ := schema["type"].(string)
+	if expected != "" && !matchesType(expected, value) {
+		add("expected %s, got %s", expected, jsonTypeName(value))
+		return
+	}
+	if allowed := schemaList(schema["enum"]); len(allowed) > 0 {
+		if !containsValue(allowed, value) {
+			add("must be one of %s", strings.Join(formatValues(allowed), ", "))
+		}
+	}
+	if number, ok := value.(json.Number); ok {
+		parsed, _ := number.Float64()
+		if minimum, ok := schemaNumber(schema["minimum"]); ok && parsed < minimum {
+			add("must be >= %v", minimum)
+		}
+		if maximum, ok := schemaNumber(schema["maximum"]); ok && parsed > maximum {
+			add("must be <= %v", maximum)
+		}
+	}
+
+	switch typed := value.(type) {
+	case map[string]any:
+		properties, _ := schema["properties"].(map[string]any)
+		for _, name := range schemaList(schema["required"]) {
+			key, _ := name.(string)
+			if _, ok := typed[key]; !ok {
+				*problems = append(*problems, ArgumentProblem{Field: joinField(path, key), Problem: "required field is missing"})
+			}
+		}
+		keys := make([]string, 0, len(typed))
+		for key := range typed {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			propSchema, known := properties[key].(map[string]any)
+			if !known {
+				if additional, ok := schema["additionalProperties"].(bool); ok && !additional {
+					*problems = append(*problems, ArgumentProblem{Field: joinField(path, key), Problem: "unknown field"})
+				}
+				continue
+			}
+			validateValue(joinField(path, key), propSchema, typed[key], problems)
+		}
+	case []any:
+		items, _ := schema["items"].(map[string]any)
+		if items == nil {
+			return
+		}
+		for idx, item := range typed {
+			validateValue(fmt.Sprintf("%s[%d]", field, idx), items, item, problems)
+		}
+	}
+}
+
+func matchesType(expected string, value any) bool {
+	switch expected {
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number":
+		_, ok := value.(json.Number)
+		return ok
+	case "integer":
+		number, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		parsed, err := number.Float64()
+		return err == nil && parsed == math.Trunc(parsed)
+	case "null":
+		return value == nil
+	}
+	return true
+}
+
+func jsonTypeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// schemaList accepts the []string and []any forms tool schemas use.
+func schemaList(raw any) []any {
+	switch typed := raw.(type) {
+	case []any:
+		return typed
+	case []string:
+		out := make([]any, 0, len(typed))
+		for _, item := range typed {
+			out = append(out, item)
+		}
+		return out
+	}
+	return nil
+}
+
+func schemaNumber(raw any) (float64, bool) {
+	switch typed := raw.(type) {
+	case int:
+		return float64(typed), true
+	case int64:
+		return float64(typed), true
+	case float64:
+		return typed, true
+	case json.Number:
+		parsed, err := typed.Float64()
+		return parsed, err == nil
+	}
+	return 0, false
+}
+
+func containsValue(allowed []any, value any) bool {
+	for _, candidate := range allowed {
+		if fmt.Sprint(candidate) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatValues(values []any) []string {
+	out := make([]string, 0, len(values))
+	for _, value := range values {
+		out = append(out, fmt.Sprintf("%q", fmt.Sprint(value)))
+	}
+	return out
+}
+
+func joinField(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
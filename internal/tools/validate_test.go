@@ -0,0 +1,76 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type recordingTool struct {
+	calls int
+}
+
+func (t *recordingTool) Name() string        { return "search" }
+func (t *recordingTool) Description() string { return "test tool" }
+func (t *recordingTool) Schema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"query": map[string]any{"type": "string"},
+			"count": map[string]any{"type": "integer", "minimum": 1, "maximum": 10},
+			"mode":  map[string]any{"type": "string", "enum": []string{"fast", "deep"}},
+			"tags":  map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		},
+		"required": []string{"query"},
+	}
+}
+func (t *recordingTool) Execute(context.Context, json.RawMessage) (ToolResult, error) {
+	t.calls++
+	return ToolResult{Text: "ok"}, nil
+}
+
+func TestRegistryExecuteRejectsArgumentsThatDoNotMatchSchema(t *testing.T) {
+	tool := &recordingTool{}
+	registry := NewRegistry()
+	registry.Register(tool)
+
+	_, err := registry.Execute(context.Background(), "search", json.RawMessage(`{"count":2.5,"mode":"slow","tags":["a",3]}`))
+	var argErr *ArgumentError
+	if !errors.As(err, &argErr) {
+		t.Fatalf("expected ArgumentError, got %v", err)
+	}
+	if tool.calls != 0 {
+		t.Fatal("tool must not run with invalid arguments")
+	}
+	got := map[string]string{}
+	for _, problem := range argErr.Problems {
+		got[problem.Field] = problem.Problem
+	}
+	for field, want := range map[string]string{
+		"query":   "required field is missing",
+		"count":   "expected integer",
+		"mode":    "must be one of",
+		"tags[1]": "expected string",
+	} {
+		if !strings.Contains(got[field], want) {
+			t.Fatalf("field %s: expected %q, got %q (all: %+v)", field, want, got[field], argErr.Problems)
+		}
+	}
+	var payload map[string]any
+	if err := json.Unmarshal([]byte(argErr.Error()), &payload); err != nil || payload["error"] != "invalid_arguments" {
+		t.Fatalf("expected structured JSON error, got %q", argErr.Error())
+	}
+
+	if _, err := registry.Execute(context.Background(), "search", json.RawMessage(`{"query":`)); !errors.As(err, &argErr) || argErr.Problems[0].Field != "$" {
+		t.Fatalf("expected malformed JSON to be reported, got %v", err)
+	}
+
+	if _, err := registry.Execute(context.Background(), "search", json.RawMessage(`{"query":"go","count":3,"mode":"deep","tags":["x"]}`)); err != nil {
+		t.Fatalf("valid arguments rejected: %v", err)
+	}
+	if tool.calls != 1 {
+		t.Fatalf("expected tool to run once, ran %d times", tool.calls)
+	}
+}
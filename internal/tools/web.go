@@ -5,25 +5,30 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"html"
 	"io"
 	"net/http"
-	"net/url"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 )
 
 type WebSearchTool struct {
-	apiKey     string
-	maxResults int
-	client     *http.Client
+	opts   WebSearchOptions
+	client *http.Client
 }
 
 func NewWebSearchTool(apiKey string, maxResults int) *WebSearchTool {
-	if maxResults <= 0 {
-		maxResults = 5
+	return NewWebSearchToolWithOptions(WebSearchOptions{Provider: "brave", APIKey: apiKey, MaxResults: maxResults})
+}
+
+func NewWebSearchToolWithOptions(opts WebSearchOptions) *WebSearchTool {
+	if opts.MaxResults <= 0 {
+		opts.MaxResults = 5
 	}
-	return &WebSearchTool{apiKey: strings.TrimSpace(apiKey), maxResults: maxResults, client: &http.Client{Timeout: 15 * time.Second}}
+	opts.Provider = NormalizeSearchProvider(opts.Provider)
+	return &WebSearchTool{opts: opts, client: &http.Client{Timeout: 15 * time.Second}}
 }
 
 func (t *WebSearchTool) Name() string { return "web_search" }
@@ -41,86 +46,91 @@ func (t *WebSearchTool) Execute(ctx context.Context, args json.RawMessage) (Tool
 	if err := json.Unmarshal(args, &in); err != nil {
 		return ToolResult{}, fmt.Errorf("invalid arguments: %w", err)
 	}
-	if strings.TrimSpace(t.apiKey) == "" {
-		return ToolResult{Text: "Error: BRAVE_API_KEY not configured"}, nil
+	backend, err := NewSearchBackend(t.opts, t.client)
+	if err != nil {
+		return ToolResult{Text: "Error: " + err.Error()}, nil
 	}
 	count := in.Count
 	if count <= 0 {
-		count = t.maxResults
+		count = t.opts.MaxResults
 	}
 	if count > 10 {
 		count = 10
 	}
 
-	endpoint := "https://api.search.brave.com/res/v1/web/search?q=" + url.QueryEscape(in.Query) + fmt.Sprintf("&count=%d", count)
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
-	if err != nil {
-		return ToolResult{}, err
-	}
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("X-Subscription-Token", t.apiKey)
-
-	resp, err := t.client.Do(req)
+	results, err := backend.Search(ctx, in.Query, count)
 	if err != nil {
-		return ToolResult{}, err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode >= 300 {
-		body, _ := io.ReadAll(io.LimitReader(resp.Body, 2048))
-		return ToolResult{Text: fmt.Sprintf("Error: search request failed (%d): %s", resp.StatusCode, string(body))}, nil
-	}
-
-	var parsed struct {
-		Web struct {
-			Results []struct {
-				Title       string `json:"title"`
-				URL         string `json:"url"`
-				Description string `json:"description"`
-			} `json:"results"`
-		} `json:"web"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
-		return ToolResult{}, err
+		return ToolResult{Text: "Error: " + err.Error()}, nil
 	}
-	if len(parsed.Web.Results) == 0 {
+	if len(results) == 0 {
 		return ToolResult{Text: "No results found."}, nil
 	}
+	if len(results) > count {
+		results = results[:count]
+	}
 
 	lines := []string{fmt.Sprintf("Results for: %s", in.Query), ""}
-	for idx, item := range parsed.Web.Results {
+	for idx, item := range results {
 		lines = append(lines, fmt.Sprintf("%d. %s", idx+1, item.Title))
 		lines = append(lines, "   "+item.URL)
-		if strings.TrimSpace(item.Description) != "" {
-			lines = append(lines, "   "+item.Description)
+		if strings.TrimSpace(item.Snippet) != "" {
+			lines = append(lines, "   "+item.Snippet)
 		}
 	}
-	return ToolResult{Text: strings.Join(lines, "\n")}, nil
+	return ToolResult{Text: strings.Join(lines, "\n"), Metadata: map[string]any{"search_provider": backend.Name()}}, nil
 }
 
 type WebFetchTool struct {
 	client   *http.Client
 	maxChars int
+	policy   WebFetchPolicy
 }
 
+const (
+	webFetchMaxBytes        = 4 << 20
+	webFetchCacheTTL        = 10 * time.Minute
+	webFetchCacheMaxEntries = 32
+)
+
+type webFetchDocument struct {
+	status      int
+	contentType string
+	extractor   string
+	text        string
+	fetchedAt   time.Time
+}
+
+var webFetchCache = struct {
+	sync.Mutex
+	entries map[string]webFetchDocument
+}{entries: map[string]webFetchDocument{}}
+
 func NewWebFetchTool(maxChars int) *WebFetchTool {
+	return NewWebFetchToolWithPolicy(maxChars, WebFetchPolicy{})
+}
+
+func NewWebFetchToolWithPolicy(maxChars int, policy WebFetchPolicy) *WebFetchTool {
 	if maxChars <= 0 {
 		maxChars = 50000
 	}
-	return &WebFetchTool{client: &http.Client{Timeout: 30 * time.Second}, maxChars: maxChars}
+	return &WebFetchTool{client: policy.client(30 * time.Second), maxChars: maxChars, policy: policy}
 }
 
 func (t *WebFetchTool) Name() string { return "web_fetch" }
 func (t *WebFetchTool) Description() string {
-	return "Fetch URL and extract readable content (HTML to text/markdown)."
+	return "Fetch URL and extract readable content (HTML to text/markdown). Set raw=true to skip extraction. Long pages are returned in windows; pass page or offset to read further sections."
 }
 func (t *WebFetchTool) Schema() map[string]any {
-	return map[string]any{"type": "object", "properties": map[string]any{"url": map[string]any{"type": "string"}, "extractMode": map[string]any{"type": "string", "enum": []string{"markdown", "text"}}, "maxChars": map[string]any{"type": "integer", "minimum": 100}}, "required": []string{"url"}}
+	return map[string]any{"type": "object", "properties": map[string]any{"url": map[string]any{"type": "string"}, "extractMode": map[string]any{"type": "string", "enum": []string{"markdown", "text"}}, "maxChars": map[string]any{"type": "integer", "minimum": 100}, "raw": map[string]any{"type": "boolean"}, "page": map[string]any{"type": "integer", "minimum": 1}, "offset": map[string]any{"type": "integer", "minimum": 0}}, "required": []string{"url"}}
 }
 func (t *WebFetchTool) Execute(ctx context.Context, args json.RawMessage) (ToolResult, error) {
 	var in struct {
 		URL         string `json:"url"`
 		ExtractMode string `json:"extractMode"`
 		MaxChars    int    `json:"maxChars"`
+		Raw         bool   `json:"raw"`
+		Page        int    `json:"page"`
+		Offset      int    `json:"offset"`
 	}
 	if err := json.Unmarshal(args, &in); err != nil {
 		return ToolResult{}, fmt.Errorf("invalid arguments: %w", err)
@@ -132,52 +142,141 @@ func (t *WebFetchTool) Execute(ctx context.Context, args json.RawMessage) (ToolR
 	if maxChars <= 0 {
 		maxChars = t.maxChars
 	}
+	offset := in.Offset
+	if in.Page > 0 {
+		offset = (in.Page - 1) * maxChars
+	}
+	if offset < 0 {
+		offset = 0
+	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, in.URL, nil)
-	if err != nil {
+	if err := t.policy.checkURL(ctx, in.URL); err != nil {
 		return ToolResult{}, err
 	}
+
+	extractMode := strings.TrimSpace(strings.ToLower(in.ExtractMode))
+	cacheKey := fmt.Sprintf("%s|%s|%v", in.URL, extractMode, in.Raw)
+	doc, cached := cachedWebFetch(cacheKey)
+	if !cached {
+		fetched, err := t.fetch(ctx, in.URL, extractMode, in.Raw)
+		if err != nil {
+			return ToolResult{}, err
+		}
+		doc = fetched
+		storeWebFetch(cacheKey, doc)
+	}
+
+	// Windows are counted in runes so a page never splits a character.
+	runes := []rune(doc.text)
+	var window []rune
+	if offset < len(runes) {
+		window = runes[offset:]
+	}
+	truncated := false
+	if len(window) > maxChars {
+		window = window[:maxChars]
+		truncated = true
+	}
+	payload := map[string]any{
+		"url":         in.URL,
+		"status":      doc.status,
+		"extractor":   doc.extractor,
+		"contentType": doc.contentType,
+		"truncated":   truncated,
+		"length":      len(window),
+		"totalLength": len(runes),
+		"offset":      offset,
+		"cached":      cached,
+		"text":        string(window),
+	}
+	if truncated {
+		payload["nextOffset"] = offset + len(window)
+		payload["moreAvailable"] = true
+	}
+	result, _ := json.Marshal(payload)
+	return ToolResult{Text: string(result)}, nil
+}
+
+func (t *WebFetchTool) fetch(ctx context.Context, target, extractMode string, raw bool) (webFetchDocument, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return webFetchDocument{}, err
+	}
 	req.Header.Set("User-Agent", "squidbot/1.0")
 	resp, err := t.client.Do(req)
 	if err != nil {
-		return ToolResult{}, err
+		return webFetchDocument{}, err
 	}
 	defer resp.Body.Close()
 
-	bodyBytes, err := io.ReadAll(io.LimitReader(resp.Body, int64(maxChars*4)))
+	bodyBytes, err := io.ReadAll(io.LimitReader(resp.Body, webFetchMaxBytes))
 	if err != nil {
-		return ToolResult{}, err
+		return webFetchDocument{}, err
 	}
 	body := string(bodyBytes)
 	contentType := resp.Header.Get("Content-Type")
 
 	text := body
 	extractor := "raw"
-	if strings.Contains(contentType, "application/json") {
+	switch {
+	case raw:
+	case isJSONContentType(contentType):
 		extractor = "json"
 		var pretty bytes.Buffer
 		if err := json.Indent(&pretty, bodyBytes, "", "  "); err == nil {
 			text = pretty.String()
 		}
-	} else if strings.Contains(contentType, "text/html") || looksLikeHTML(body) {
+	case strings.Contains(contentType, "text/html") || looksLikeHTML(body):
 		extractor = "html"
-		text = htmlToText(body)
+		text = htmlToText(body, extractMode != "text")
 	}
+	return webFetchDocument{
+		status:      resp.StatusCode,
+		contentType: contentType,
+		extractor:   extractor,
+		text:        text,
+		fetchedAt:   time.Now().UTC(),
+	}, nil
+}
 
-	truncated := false
-	if len(text) > maxChars {
-		text = text[:maxChars]
-		truncated = true
+func cachedWebFetch(key string) (webFetchDocument, bool) {
+	webFetchCache.Lock()
+	defer webFetchCache.Unlock()
+	doc, ok := webFetchCache.entries[key]
+	if !ok {
+		return webFetchDocument{}, false
 	}
-	result, _ := json.Marshal(map[string]any{
-		"url":       in.URL,
-		"status":    resp.StatusCode,
-		"extractor": extractor,
-		"truncated": truncated,
-		"length":    len(text),
-		"text":      text,
-	})
-	return ToolResult{Text: string(result)}, nil
+	if time.Since(doc.fetchedAt) > webFetchCacheTTL {
+		delete(webFetchCache.entries, key)
+		return webFetchDocument{}, false
+	}
+	return doc, true
+}
+
+func storeWebFetch(key string, doc webFetchDocument) {
+	if doc.status >= 300 {
+		return
+	}
+	webFetchCache.Lock()
+	defer webFetchCache.Unlock()
+	now := time.Now().UTC()
+	for existing, entry := range webFetchCache.entries {
+		if now.Sub(entry.fetchedAt) > webFetchCacheTTL {
+			delete(webFetchCache.entries, existing)
+		}
+	}
+	if len(webFetchCache.entries) >= webFetchCacheMaxEntries {
+		oldestKey := ""
+		var oldest time.Time
+		for existing, entry := range webFetchCache.entries {
+			if oldestKey == "" || entry.fetchedAt.Before(oldest) {
+				oldestKey = existing
+				oldest = entry.fetchedAt
+			}
+		}
+		delete(webFetchCache.entries, oldestKey)
+	}
+	webFetchCache.entries[key] = doc
 }
 
 func looksLikeHTML(content string) bool {
@@ -188,17 +287,38 @@ func looksLikeHTML(content string) bool {
 	return strings.Contains(head, "<html") || strings.Contains(head, "<!doctype")
 }
 
-func htmlToText(content string) string {
-	re := regexp.MustCompile(`(?is)<(script|style)[^>]*>.*?</(script|style)>`)
+func isJSONContentType(contentType string) bool {
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+func htmlToText(content string, keepLinks bool) string {
+	re := regexp.MustCompile(`(?is)<(script|style|noscript|head|svg)[^>]*>.*?</(script|style|noscript|head|svg)>`)
+	content = re.ReplaceAllString(content, "")
+	re = regexp.MustCompile(`(?is)<!--.*?-->`)
 	content = re.ReplaceAllString(content, "")
+	tags := regexp.MustCompile(`(?is)<[^>]+>`)
+	if keepLinks {
+		re = regexp.MustCompile(`(?is)<a\s[^>]*href\s*=\s*["']([^"'#][^"']*)["'][^>]*>(.*?)</a>`)
+		content = re.ReplaceAllStringFunc(content, func(match string) string {
+			parts := re.FindStringSubmatch(match)
+			label := strings.TrimSpace(tags.ReplaceAllString(parts[2], ""))
+			if label == "" {
+				return ""
+			}
+			return "[" + label + "](" + parts[1] + ")"
+		})
+	}
 	re = regexp.MustCompile(`(?is)<br\s*/?>`)
 	content = re.ReplaceAllString(content, "\n")
 	re = regexp.MustCompile(`(?is)</(p|div|section|article|li|h1|h2|h3|h4|h5|h6)>`)
 	content = re.ReplaceAllString(content, "\n")
-	re = regexp.MustCompile(`(?is)<[^>]+>`)
-	content = re.ReplaceAllString(content, "")
+	content = tags.ReplaceAllString(content, "")
+	content = html.UnescapeString(content)
 	re = regexp.MustCompile(`[ \t]+`)
 	content = re.ReplaceAllString(content, " ")
+	re = regexp.MustCompile(`[ \t]*\n[ \t]*`)
+	content = re.ReplaceAllString(content, "\n")
 	re = regexp.MustCompile(`\n{3,}`)
 	content = re.ReplaceAllString(content, "\n\n")
 	return strings.TrimSpace(content)
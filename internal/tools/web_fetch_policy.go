@@ -0,0 +1,141 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// WebFetchPolicy limits which hosts web_fetch may reach. DenyHosts wins over
+// AllowHosts, an empty AllowHosts allows any host that is not denied, and an
+// entry such as "example.com" also covers its subdomains.
+type WebFetchPolicy struct {
+	AllowHosts      []string
+	DenyHosts       []string
+	BlockPrivateIPs bool
+}
+
+// BlockedHostError reports a fetch refused by WebFetchPolicy.
+type BlockedHostError struct {
+	Host   string
+	Reason string
+}
+
+func (e *BlockedHostError) Error() string {
+	return fmt.Sprintf("web_fetch blocked host %q: %s", e.Host, e.Reason)
+}
+
+func (p WebFetchPolicy) active() bool {
+	return len(p.AllowHosts) > 0 || len(p.DenyHosts) > 0 || p.BlockPrivateIPs
+}
+
+// checkHost applies the allow and deny lists to host, then, when private
+// addresses are blocked, resolves it and rejects any private answer.
+func (p WebFetchPolicy) checkHost(ctx context.Context, host string) error {
+	host = normalizeFetchHost(host)
+	if host == "" {
+		return &BlockedHostError{Host: host, Reason: "missing host"}
+	}
+	if matchesFetchHost(host, p.DenyHosts) {
+		return &BlockedHostError{Host: host, Reason: "host is denied"}
+	}
+	if len(p.AllowHosts) > 0 && !matchesFetchHost(host, p.AllowHosts) {
+		return &BlockedHostError{Host: host, Reason: "host is not in the allow list"}
+	}
+	if !p.BlockPrivateIPs {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if isPrivateFetchIP(ip) {
+			return &BlockedHostError{Host: host, Reason: "private address " + ip.String()}
+		}
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("resolve %s: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if isPrivateFetchIP(addr.IP) {
+			return &BlockedHostError{Host: host, Reason: "resolves to private address " + addr.IP.String()}
+		}
+	}
+	return nil
+}
+
+// client returns an HTTP client that enforces the policy on redirects and,
+// when private addresses are blocked, on the address actually dialed, so a
+// DNS answer that changes after checkHost cannot reach a private network.
+func (p WebFetchPolicy) client(timeout time.Duration) *http.Client {
+	client := &http.Client{Timeout: timeout}
+	if !p.active() {
+		return client
+	}
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return p.checkHost(req.Context(), req.URL.Hostname())
+	}
+	if p.BlockPrivateIPs {
+		dialer := &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				if ip := net.ParseIP(host); ip != nil && isPrivateFetchIP(ip) {
+					return &BlockedHostError{Host: host, Reason: "private address " + ip.String()}
+				}
+				return nil
+			},
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.DialContext = dialer.DialContext
+		client.Transport = transport
+	}
+	return client
+}
+
+func (p WebFetchPolicy) checkURL(ctx context.Context, target string) error {
+	if !p.active() {
+		return nil
+	}
+	parsed, err := url.Parse(strings.TrimSpace(target))
+	if err != nil {
+		return err
+	}
+	return p.checkHost(ctx, parsed.Hostname())
+}
+
+func normalizeFetchHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	host = strings.TrimPrefix(host, "*.")
+	host = strings.TrimSuffix(host, ".")
+	return strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+}
+
+func matchesFetchHost(host string, entries []string) bool {
+	for _, entry := range entries {
+		entry = normalizeFetchHost(entry)
+		if entry == "" {
+			continue
+		}
+		if host == entry || strings.HasSuffix(host, "."+entry) {
+			return true
+		}
+	}
+	return false
+}
+
+func isPrivateFetchIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified()
+}
@@ -0,0 +1,251 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type SearchResult struct {
+	Title   string
+	URL     string
+	Snippet string
+}
+
+type SearchBackend interface {
+	Name() string
+	Search(ctx context.Context, query string, count int) ([]SearchResult, error)
+}
+
+type WebSearchOptions struct {
+	Provider   string
+	APIKey     string
+	BaseURL    string
+	MaxResults int
+}
+
+// SearchProviders lists the web_search backends NewSearchBackend supports.
+var SearchProviders = []string{"brave", "serpapi", "searxng", "tavily"}
+
+// KnownSearchProvider reports whether name selects a supported backend. An
+// empty name selects brave.
+func KnownSearchProvider(name string) bool {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "brave", "serpapi", "searxng", "searx", "tavily":
+		return true
+	}
+	return false
+}
+
+// NormalizeSearchProvider maps name to a supported backend; unknown names
+// fall back to brave.
+func NormalizeSearchProvider(name string) string {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "serpapi":
+		return "serpapi"
+	case "searxng", "searx":
+		return "searxng"
+	case "tavily":
+		return "tavily"
+	default:
+		return "brave"
+	}
+}
+
+func NewSearchBackend(opts WebSearchOptions, client *http.Client) (SearchBackend, error) {
+	apiKey := strings.TrimSpace(opts.APIKey)
+	baseURL := strings.TrimRight(strings.TrimSpace(opts.BaseURL), "/")
+	switch NormalizeSearchProvider(opts.Provider) {
+	case "serpapi":
+		if apiKey == "" {
+			return nil, fmt.Errorf("serpapi api key not configured")
+		}
+		if baseURL == "" {
+			baseURL = "https://serpapi.com"
+		}
+		return &serpAPIBackend{apiKey: apiKey, baseURL: baseURL, client: client}, nil
+	case "searxng":
+		if baseURL == "" {
+			return nil, fmt.Errorf("searxng baseUrl not configured")
+		}
+		return &searxngBackend{apiKey: apiKey, baseURL: baseURL, client: client}, nil
+	case "tavily":
+		if apiKey == "" {
+			return nil, fmt.Errorf("tavily api key not configured")
+		}
+		if baseURL == "" {
+			baseURL = "https://api.tavily.com"
+		}
+		return &tavilyBackend{apiKey: apiKey, baseURL: baseURL, client: client}, nil
+	default:
+		if apiKey == "" {
+			return nil, fmt.Errorf("BRAVE_API_KEY not configured")
+		}
+		if baseURL == "" {
+			baseURL = "https://api.search.brave.com"
+		}
+		return &braveBackend{apiKey: apiKey, baseURL: baseURL, client: client}, nil
+	}
+}
+
+type braveBackend struct {
+	apiKey  string
+	baseURL string
+	client  *http.Client
+}
+
+func (b *braveBackend) Name() string { return "brave" }
+
+func (b *braveBackend) Search(ctx context.Context, query string, count int) ([]SearchResult, error) {
+	endpoint := b.baseURL + "/res/v1/web/search?q=" + url.QueryEscape(query) + fmt.Sprintf("&count=%d", count)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Subscription-Token", b.apiKey)
+	var parsed struct {
+		Web struct {
+			Results []struct {
+				Title       string `json:"title"`
+				URL         string `json:"url"`
+				Description string `json:"description"`
+			} `json:"results"`
+		} `json:"web"`
+	}
+	if err := doSearchRequest(b.client, req, &parsed); err != nil {
+		return nil, err
+	}
+	out := make([]SearchResult, 0, len(parsed.Web.Results))
+	for _, item := range parsed.Web.Results {
+		out = append(out, SearchResult{Title: item.Title, URL: item.URL, Snippet: item.Description})
+	}
+	return out, nil
+}
+
+type serpAPIBackend struct {
+	apiKey  string
+	baseURL string
+	client  *http.Client
+}
+
+func (b *serpAPIBackend) Name() string { return "serpapi" }
+
+func (b *serpAPIBackend) Search(ctx context.Context, query string, count int) ([]SearchResult, error) {
+	params := url.Values{}
+	params.Set("engine", "google")
+	params.Set("q", query)
+	params.Set("num", fmt.Sprintf("%d", count))
+	params.Set("api_key", b.apiKey)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.baseURL+"/search.json?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	var parsed struct {
+		OrganicResults []struct {
+			Title   string `json:"title"`
+			Link    string `json:"link"`
+			Snippet string `json:"snippet"`
+		} `json:"organic_results"`
+	}
+	if err := doSearchRequest(b.client, req, &parsed); err != nil {
+		return nil, err
+	}
+	out := make([]SearchResult, 0, len(parsed.OrganicResults))
+	for _, item := range parsed.OrganicResults {
+		out = append(out, SearchResult{Title: item.Title, URL: item.Link, Snippet: item.Snippet})
+	}
+	return out, nil
+}
+
+type searxngBackend struct {
+	apiKey  string
+	baseURL string
+	client  *http.Client
+}
+
+func (b *searxngBackend) Name() string { return "searxng" }
+
+func (b *searxngBackend) Search(ctx context.Context, query string, count int) ([]SearchResult, error) {
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("format", "json")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.baseURL+"/search?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if b.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+b.apiKey)
+	}
+	var parsed struct {
+		Results []struct {
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Content string `json:"content"`
+		} `json:"results"`
+	}
+	if err := doSearchRequest(b.client, req, &parsed); err != nil {
+		return nil, err
+	}
+	out := make([]SearchResult, 0, len(parsed.Results))
+	for _, item := range parsed.Results {
+		out = append(out, SearchResult{Title: item.Title, URL: item.URL, Snippet: item.Content})
+	}
+	return out, nil
+}
+
+type tavilyBackend struct {
+	apiKey  string
+	baseURL string
+	client  *http.Client
+}
+
+func (b *tavilyBackend) Name() string { return "tavily" }
+
+func (b *tavilyBackend) Search(ctx context.Context, query string, count int) ([]SearchResult, error) {
+	body, err := json.Marshal(map[string]any{"api_key": b.apiKey, "query": query, "max_results": count})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.baseURL+"/search", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	var parsed struct {
+		Results []struct {
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Content string `json:"content"`
+		} `json:"results"`
+	}
+	if err := doSearchRequest(b.client, req, &parsed); err != nil {
+		return nil, err
+	}
+	out := make([]SearchResult, 0, len(parsed.Results))
+	for _, item := range parsed.Results {
+		out = append(out, SearchResult{Title: item.Title, URL: item.URL, Snippet: item.Content})
+	}
+	return out, nil
+}
+
+func doSearchRequest(client *http.Client, req *http.Request, out any) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 2048))
+		return fmt.Errorf("search request failed (%d): %s", resp.StatusCode, string(body))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
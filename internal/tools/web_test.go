@@ -0,0 +1,238 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWebFetchExtractsHTMLAndHonorsRaw(t *testing.T) {
+	page := `<!doctype html><html><head><title>t</title><style>.x{}</style></head><body>
+<script>alert(1)</script><h1>Docs &amp; Guides</h1><p>Read the <a href="https://example.com/start">getting started</a> page.</p></body></html>`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(page))
+	}))
+	defer server.Close()
+
+	tool := NewWebFetchTool(5000)
+	fetch := func(extra map[string]any) map[string]any {
+		t.Helper()
+		in := map[string]any{"url": server.URL}
+		for k, v := range extra {
+			in[k] = v
+		}
+		args, _ := json.Marshal(in)
+		result, err := tool.Execute(context.Background(), args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := map[string]any{}
+		if err := json.Unmarshal([]byte(result.Text), &out); err != nil {
+			t.Fatalf("invalid result: %v", err)
+		}
+		return out
+	}
+
+	extracted := fetch(nil)
+	text, _ := extracted["text"].(string)
+	if extracted["extractor"] != "html" {
+		t.Fatalf("expected html extractor, got %v", extracted["extractor"])
+	}
+	if strings.Contains(text, "alert(1)") || strings.Contains(text, ".x{}") || strings.Contains(text, "<p>") {
+		t.Fatalf("expected scripts, styles, and tags to be stripped, got %q", text)
+	}
+	if !strings.Contains(text, "Docs & Guides") || !strings.Contains(text, "[getting started](https://example.com/start)") {
+		t.Fatalf("expected readable text with links, got %q", text)
+	}
+
+	plain := fetch(map[string]any{"extractMode": "text"})
+	if plainText, _ := plain["text"].(string); strings.Contains(plainText, "https://example.com/start") {
+		t.Fatalf("expected text mode to drop link targets, got %q", plainText)
+	}
+
+	raw := fetch(map[string]any{"raw": true})
+	if raw["extractor"] != "raw" || !strings.Contains(raw["text"].(string), "<script>") {
+		t.Fatalf("expected raw body, got %v", raw)
+	}
+}
+
+func TestWebFetchPaginatesFromCache(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte(strings.Repeat("a", 150) + strings.Repeat("b", 150) + strings.Repeat("c", 50)))
+	}))
+	defer server.Close()
+
+	tool := NewWebFetchTool(150)
+	fetchPage := func(page int) map[string]any {
+		t.Helper()
+		args, _ := json.Marshal(map[string]any{"url": server.URL, "page": page})
+		result, err := tool.Execute(context.Background(), args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := map[string]any{}
+		if err := json.Unmarshal([]byte(result.Text), &out); err != nil {
+			t.Fatalf("invalid result: %v", err)
+		}
+		return out
+	}
+
+	first := fetchPage(1)
+	if first["moreAvailable"] != true || first["nextOffset"] != float64(150) {
+		t.Fatalf("expected more content after first page, got %v", first)
+	}
+	second := fetchPage(2)
+	if second["text"] != strings.Repeat("b", 150) || second["moreAvailable"] != true {
+		t.Fatalf("unexpected second page: %v", second)
+	}
+	last := fetchPage(3)
+	if last["text"] != strings.Repeat("c", 50) || last["truncated"] != false {
+		t.Fatalf("unexpected last page: %v", last)
+	}
+	if _, ok := last["moreAvailable"]; ok {
+		t.Fatalf("did not expect more content on last page: %v", last)
+	}
+	if hits != 1 {
+		t.Fatalf("expected later pages to be served from cache, got %d fetches", hits)
+	}
+}
+
+func TestWebFetchPagesByRune(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte("<html><body><p>" + strings.Repeat("&mdash;", 150) + strings.Repeat("&eacute;", 10) + "</p></body></html>"))
+	}))
+	defer server.Close()
+
+	tool := NewWebFetchTool(100)
+	fetch := func(offset int) map[string]any {
+		t.Helper()
+		args, _ := json.Marshal(map[string]any{"url": server.URL, "offset": offset})
+		result, err := tool.Execute(context.Background(), args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := map[string]any{}
+		if err := json.Unmarshal([]byte(result.Text), &out); err != nil {
+			t.Fatalf("invalid result: %v", err)
+		}
+		return out
+	}
+
+	first := fetch(0)
+	if first["text"] != strings.Repeat("—", 100) || first["totalLength"] != float64(160) || first["nextOffset"] != float64(100) {
+		t.Fatalf("unexpected first window: %v", first)
+	}
+	second := fetch(100)
+	if second["text"] != strings.Repeat("—", 50)+strings.Repeat("é", 10) || second["truncated"] != false {
+		t.Fatalf("unexpected second window: %v", second)
+	}
+}
+
+func TestWebFetchPolicyBlocksHosts(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "http://denied.test/landing", http.StatusFound)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	localhostURL := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+
+	cases := []struct {
+		name   string
+		policy WebFetchPolicy
+		url    string
+		want   string
+	}{
+		{name: "loopback literal", policy: WebFetchPolicy{BlockPrivateIPs: true}, url: server.URL + "/private", want: `blocked host "127.0.0.1"`},
+		{name: "resolved loopback", policy: WebFetchPolicy{BlockPrivateIPs: true}, url: localhostURL + "/resolved", want: `blocked host "localhost"`},
+		{name: "denied subdomain", policy: WebFetchPolicy{DenyHosts: []string{"example.com"}}, url: "https://docs.example.com/page", want: `blocked host "docs.example.com"`},
+		{name: "not allowed", policy: WebFetchPolicy{AllowHosts: []string{"example.org"}}, url: "https://other.test/page", want: "not in the allow list"},
+		{name: "denied redirect", policy: WebFetchPolicy{DenyHosts: []string{"denied.test"}}, url: server.URL + "/redirect", want: `blocked host "denied.test"`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tool := NewWebFetchToolWithPolicy(1000, tc.policy)
+			args, _ := json.Marshal(map[string]any{"url": tc.url})
+			_, err := tool.Execute(context.Background(), args)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("expected error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+	if hits != 1 {
+		t.Fatalf("expected only the redirecting request to reach the server, got %d", hits)
+	}
+
+	allowed := NewWebFetchToolWithPolicy(1000, WebFetchPolicy{AllowHosts: []string{"127.0.0.1"}, DenyHosts: []string{"example.com"}})
+	args, _ := json.Marshal(map[string]any{"url": server.URL + "/allowed"})
+	if _, err := allowed.Execute(context.Background(), args); err != nil {
+		t.Fatalf("expected allowed host to be fetched, got %v", err)
+	}
+}
+
+func TestWebSearchBackendsNormalizeResults(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/search":
+			if r.Method == http.MethodPost {
+				_, _ = w.Write([]byte(`{"results":[{"title":"Tavily Hit","url":"https://t.example","content":"from tavily"}]}`))
+				return
+			}
+			if r.URL.Query().Get("format") != "json" {
+				t.Errorf("expected searxng json format, got %q", r.URL.RawQuery)
+			}
+			_, _ = w.Write([]byte(`{"results":[{"title":"Searx Hit","url":"https://s.example","content":"from searxng"}]}`))
+		case "/search.json":
+			_, _ = w.Write([]byte(`{"organic_results":[{"title":"Serp Hit","link":"https://g.example","snippet":"from serpapi"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cases := []struct {
+		provider string
+		apiKey   string
+		want     string
+	}{
+		{provider: "searxng", want: "Searx Hit"},
+		{provider: "tavily", apiKey: "tvly-key", want: "Tavily Hit"},
+		{provider: "serpapi", apiKey: "serp-key", want: "Serp Hit"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.provider, func(t *testing.T) {
+			tool := NewWebSearchToolWithOptions(WebSearchOptions{Provider: tc.provider, APIKey: tc.apiKey, BaseURL: server.URL})
+			result, err := tool.Execute(context.Background(), json.RawMessage(`{"query":"squid"}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(result.Text, tc.want) || result.Metadata["search_provider"] != tc.provider {
+				t.Fatalf("unexpected result: %s %v", result.Text, result.Metadata)
+			}
+		})
+	}
+
+	t.Run("missing configuration is reported", func(t *testing.T) {
+		tool := NewWebSearchToolWithOptions(WebSearchOptions{Provider: "searxng"})
+		result, err := tool.Execute(context.Background(), json.RawMessage(`{"query":"squid"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(result.Text, "Error:") {
+			t.Fatalf("expected configuration error, got %s", result.Text)
+		}
+	})
+}
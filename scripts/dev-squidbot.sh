@@ -61,6 +61,12 @@ if [[ -x "${REPO_ROOT}/squidbot" ]]; then
   HOME="${DEV_HOME}" "${REPO_ROOT}/squidbot" "$@"
 else
   mkdir -p "${BIN_DIR}"
-  HOME="${HOST_HOME}" go build -o "${LOCAL_BIN}" ./cmd/squidbot
+  BUILDINFO_PKG="github.com/grixate/squidbot/internal/buildinfo"
+  VERSION="$(git describe --tags --always --dirty 2>/dev/null || echo dev)"
+  COMMIT="$(git rev-parse --short HEAD 2>/dev/null || true)"
+  BUILD_DATE="$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+  HOME="${HOST_HOME}" go build \
+    -ldflags "-X ${BUILDINFO_PKG}.Version=${VERSION} -X ${BUILDINFO_PKG}.Commit=${COMMIT} -X ${BUILDINFO_PKG}.Date=${BUILD_DATE}" \
+    -o "${LOCAL_BIN}" ./cmd/squidbot
   HOME="${DEV_HOME}" "${LOCAL_BIN}" "$@"
 fi